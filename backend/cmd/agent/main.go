@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/etswifi/ets-noc/internal/logging"
+	"github.com/etswifi/ets-noc/internal/monitor"
+)
+
+func main() {
+	logging.Setup("agent", os.Getenv("LOG_LEVEL"))
+	slog.Info("Starting ETS NOC Probe Agent")
+
+	// Get environment variables
+	nocURL := os.Getenv("NOC_URL")
+	if nocURL == "" {
+		logging.Fatal("NOC_URL environment variable is required")
+	}
+
+	token := os.Getenv("PROBE_TOKEN")
+	if token == "" {
+		logging.Fatal("PROBE_TOKEN environment variable is required")
+	}
+
+	maxConcurrentPings := 50
+	if v := os.Getenv("MAX_CONCURRENT_PINGS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			logging.Fatal("Invalid MAX_CONCURRENT_PINGS", "value", v)
+		}
+		maxConcurrentPings = n
+	}
+
+	ctx := context.Background()
+	agent := monitor.NewProbeAgent(nocURL, token, maxConcurrentPings)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- agent.Start(ctx)
+	}()
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-quit:
+		slog.Info("Received shutdown signal")
+		agent.Stop()
+		// Let the agent report its last results
+		<-errChan
+	case err := <-errChan:
+		slog.Error("Probe agent error", "error", err)
+	}
+
+	slog.Info("Probe agent stopped")
+}
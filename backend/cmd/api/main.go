@@ -2,76 +2,211 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/etswifi/ets-noc/internal/api"
-	"github.com/etswifi/ets-noc/internal/gcs"
+	"github.com/etswifi/ets-noc/internal/config"
+	"github.com/etswifi/ets-noc/internal/geocode"
+	"github.com/etswifi/ets-noc/internal/logging"
+	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/s3"
 	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/worker"
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	log.Println("Starting ETS Properties API server...")
-
-	// Get environment variables
-	postgresURL := os.Getenv("POSTGRES_URL")
-	if postgresURL == "" {
-		log.Fatal("POSTGRES_URL environment variable is required")
+	// -migrate-only applies schema migrations and exits, for CI and deploy jobs
+	migrateOnly := flag.Bool("migrate-only", false, "apply database migrations and exit")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		logging.Setup("api", "")
+		logging.Fatal("Invalid configuration", "error", err)
 	}
 
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379"
+	logging.Setup("api", cfg.LogLevel)
+	slog.Info("Starting ETS Properties API server")
+	slog.Info("Loaded configuration", "config", cfg)
+
+	// SQLITE_PATH keeps everything in a SQLite file instead, for lab and demo setups
+	if cfg.PostgresURL == "" && cfg.SQLitePath == "" {
+		logging.Fatal("POSTGRES_URL or SQLITE_PATH is required")
+	}
+	if cfg.GinMode != "" {
+		gin.SetMode(cfg.GinMode)
 	}
 
-	redisPassword := os.Getenv("REDIS_PASSWORD")
+	// STATUS_STORE=memory keeps live state in process instead of Redis; that state
+	// can't be shared, so the worker runs inside the API too
+	runWorker := cfg.RunWorker || cfg.StatusStore == "memory"
 
-	gcsBucket := os.Getenv("GCS_BUCKET")
-	if gcsBucket == "" {
-		log.Fatal("GCS_BUCKET environment variable is required")
+	// QUERY_TIMEOUT has Postgres cancel any single statement that runs longer. It
+	// isn't applied to -migrate-only runs, whose migrations may take a while.
+	queryTimeout := cfg.QueryTimeout
+	if *migrateOnly {
+		queryTimeout = 0
 	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Session tokens are signed with the first of JWT_KEYS; the others are still
+	// accepted while a rotation rolls out
+	if cfg.JWTKeys != "" {
+		if err := api.SetJWTKeys(cfg.JWTKeys); err != nil {
+			logging.Fatal("Invalid JWT_KEYS", "error", err)
+		}
+	} else if gin.Mode() == gin.ReleaseMode && !*migrateOnly {
+		logging.Fatal("JWT_KEYS is required when GIN_MODE=release")
+	} else {
+		slog.Warn("JWT_KEYS not set; session tokens are signed with the built-in development key")
 	}
 
 	// Initialize storage
-	postgres, err := storage.NewPostgresStore(postgresURL)
-	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	var postgres storage.Store
+	if cfg.SQLitePath != "" {
+		postgres, err = storage.NewSQLiteStore(cfg.SQLitePath)
+		if err != nil {
+			logging.Fatal("Failed to open SQLite database", "error", err)
+		}
+		slog.Info("Opened SQLite database", "path", cfg.SQLitePath)
+	} else {
+		postgres, err = storage.NewPostgresStore(cfg.PostgresURL, cfg.PostgresOptions(queryTimeout))
+		if err != nil {
+			logging.Fatal("Failed to connect to PostgreSQL", "error", err)
+		}
+		slog.Info("Connected to PostgreSQL")
 	}
 	defer postgres.Close()
-	log.Println("Connected to PostgreSQL")
 
-	redis, err := storage.NewRedisStore(redisAddr, redisPassword, 0)
-	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+	if err := postgres.Migrate(context.Background()); err != nil {
+		logging.Fatal("Failed to migrate database", "error", err)
+	}
+	if *migrateOnly {
+		return
+	}
+
+	// Encrypt pfSense passwords and notification channel configs at rest
+	if cfg.SecretsKey != "" {
+		if err := postgres.EnableSecretEncryption(cfg.SecretsKey); err != nil {
+			logging.Fatal("Invalid SECRETS_KEY", "error", err)
+		}
+		sealed, err := postgres.EncryptExistingSecrets(context.Background())
+		if err != nil {
+			logging.Fatal("Failed to encrypt existing secrets", "error", err)
+		}
+		if sealed > 0 {
+			slog.Info("Encrypted plaintext secrets", "count", sealed)
+		}
+	} else {
+		slog.Warn("SECRETS_KEY not set; credentials are stored in plaintext")
+	}
+
+	var redis storage.StatusStore
+	if cfg.StatusStore == "memory" {
+		redis = storage.NewMemoryStore()
+		slog.Warn("STATUS_STORE=memory; live state is kept in process and lost on restart")
+	} else {
+		redis, err = storage.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword, 0)
+		if err != nil {
+			logging.Fatal("Failed to connect to Redis", "error", err)
+		}
+		slog.Info("Connected to Redis")
 	}
 	defer redis.Close()
-	log.Println("Connected to Redis")
 
-	// Initialize GCS client
+	// Attachments and config backups go to GCS or a local directory; without either
+	// the API runs with them turned off
 	ctx := context.Background()
-	gcsClient, err := gcs.NewClient(ctx, gcsBucket)
+	blobs, err := storage.OpenBlobStore(ctx, storage.BlobOptions{
+		Kind:      cfg.BlobStore,
+		GCSBucket: cfg.GCSBucket,
+		S3: s3.Config{
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			Endpoint:        cfg.S3Endpoint,
+			AccessKeyID:     cfg.S3AccessKey,
+			SecretAccessKey: cfg.S3SecretKey,
+		},
+		Dir: cfg.BlobDir,
+	})
 	if err != nil {
-		log.Fatalf("Failed to create GCS client: %v", err)
+		logging.Fatal("Failed to open file storage", "error", err)
+	}
+	if blobs != nil {
+		defer blobs.Close()
+		slog.Info("Opened file storage", "blob_store", blobs.Kind())
+	} else {
+		slog.Warn("Neither GCS_BUCKET nor BLOB_STORE set; attachments and config backups disabled")
+	}
+
+	// GEOCODER places properties on the map from their address when they are saved
+	geocoder, err := geocode.New(cfg.Geocoder, cfg.GeocoderURL, cfg.GeocoderAPIKey)
+	if err != nil {
+		logging.Fatal("Invalid GEOCODER settings", "error", err)
+	}
+	if geocoder != nil {
+		slog.Info("Geocoding property addresses", "geocoder", cfg.Geocoder)
 	}
-	defer gcsClient.Close()
-	log.Println("Connected to GCS")
 
 	// Create server and setup routes
-	server := api.NewServer(postgres, redis, gcsClient)
+	server := api.NewServer(postgres, redis, blobs,
+		api.WithWorkerStaleAfter(cfg.WorkerHeartbeatTimeout),
+		api.WithRequestTimeout(cfg.RequestTimeout),
+		api.WithUploadTimeout(cfg.UploadTimeout),
+		api.WithRateLimits(api.RateLimits{
+			Requests: cfg.RateLimit,
+			Auth:     cfg.AuthRateLimit,
+			Uploads:  cfg.UploadRateLimit,
+			Syncs:    cfg.SyncRateLimit,
+		}),
+		api.WithTrustedProxies(cfg.TrustedProxyList()),
+		api.WithGoogleOAuth(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL),
+		api.WithGeocoder(geocoder),
+	)
 	router := server.SetupRouter()
 
+	notify := notifier.NewNotifier(postgres, redis)
+
+	// Alert when every worker stops sending heartbeats; this has to run outside the
+	// workers to notice them dying
+	watchdog := monitor.NewWorkerWatchdog(redis, notify, cfg.WorkerHeartbeatTimeout)
+	go func() {
+		if err := watchdog.Start(ctx); err != nil {
+			slog.Error("Worker watchdog error", "error", err)
+		}
+	}()
+
+	// Single-binary mode runs device checks and the leader jobs in this process
+	var w *worker.Worker
+	if runWorker {
+		workerID := cfg.WorkerID
+		if workerID == "" {
+			workerID, _ = os.Hostname()
+		}
+		if workerID == "" {
+			workerID = "api"
+		}
+		w = worker.New(ctx, postgres, redis, notify, blobs, workerID)
+		go func() {
+			if err := w.Start(ctx); err != nil {
+				slog.Error("Worker error", "error", err)
+			}
+		}()
+		slog.Info("Running worker in process", "worker_id", workerID)
+	}
+
 	// Start HTTP server
+	httpServer := &http.Server{Addr: ":" + cfg.Port, Handler: router}
+	httpServer.RegisterOnShutdown(server.CloseStreams)
 	go func() {
-		log.Printf("API server listening on port %s", port)
-		if err := router.Run(":" + port); err != nil {
-			log.Fatalf("Failed to start server: %v", err)
+		slog.Info("API server listening", "port", cfg.Port)
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Failed to start server", "error", err)
 		}
 	}()
 
@@ -80,7 +215,20 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
-	time.Sleep(2 * time.Second)
-	log.Println("Server stopped")
+	// Stop accepting connections and let in-flight requests such as uploads finish
+	slog.Info("Shutting down server", "drain_timeout", cfg.ShutdownTimeout.String())
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(drainCtx); err != nil {
+		slog.Warn("Requests still running after the drain timeout were cut off", "error", err)
+		httpServer.Close()
+	}
+
+	server.CloseWebhooks()
+	watchdog.Stop()
+	if w != nil {
+		w.Stop()
+	}
+	// Returning runs the deferred closes: GCS, then Redis, then Postgres
+	slog.Info("Server stopped")
 }
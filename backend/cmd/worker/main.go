@@ -2,62 +2,122 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
-	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/config"
+	"github.com/etswifi/ets-noc/internal/logging"
+	"github.com/etswifi/ets-noc/internal/metrics"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/s3"
 	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/worker"
 )
 
 func main() {
-	log.Println("Starting ETS Properties Worker...")
-
-	// Get environment variables
-	postgresURL := os.Getenv("POSTGRES_URL")
-	if postgresURL == "" {
-		log.Fatal("POSTGRES_URL environment variable is required")
-	}
-
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379"
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		logging.Setup("worker", "")
+		logging.Fatal("Invalid configuration", "error", err)
 	}
 
-	redisPassword := os.Getenv("REDIS_PASSWORD")
+	logging.Setup("worker", cfg.LogLevel)
+	slog.Info("Starting ETS Properties Worker")
+	slog.Info("Loaded configuration", "config", cfg)
 
-	maxConcurrentPings := 150 // Default from plan
+	// SQLITE_PATH keeps everything in a SQLite file instead, for lab and demo setups
+	if cfg.PostgresURL == "" && cfg.SQLitePath == "" {
+		logging.Fatal("POSTGRES_URL or SQLITE_PATH is required")
+	}
 
 	// Initialize storage
-	postgres, err := storage.NewPostgresStore(postgresURL)
-	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	var postgres storage.Store
+	if cfg.SQLitePath != "" {
+		postgres, err = storage.NewSQLiteStore(cfg.SQLitePath)
+		if err != nil {
+			logging.Fatal("Failed to open SQLite database", "error", err)
+		}
+		slog.Info("Opened SQLite database", "path", cfg.SQLitePath)
+	} else {
+		// Retention and rollup jobs run long statements, so the worker has its own
+		// statement timeout, off by default
+		postgres, err = storage.NewPostgresStore(cfg.PostgresURL, cfg.PostgresOptions(cfg.WorkerQueryTimeout))
+		if err != nil {
+			logging.Fatal("Failed to connect to PostgreSQL", "error", err)
+		}
+		slog.Info("Connected to PostgreSQL")
 	}
 	defer postgres.Close()
-	log.Println("Connected to PostgreSQL")
 
-	redis, err := storage.NewRedisStore(redisAddr, redisPassword, 0)
+	// Must match the API's key to read pfSense passwords and channel configs
+	if cfg.SecretsKey != "" {
+		if err := postgres.EnableSecretEncryption(cfg.SecretsKey); err != nil {
+			logging.Fatal("Invalid SECRETS_KEY", "error", err)
+		}
+	}
+
+	redis, err := storage.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword, 0)
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		logging.Fatal("Failed to connect to Redis", "error", err)
 	}
 	defer redis.Close()
-	log.Println("Connected to Redis")
+	slog.Info("Connected to Redis")
 
-	// Get settings from database
 	ctx := context.Background()
-	settings, err := postgres.GetSettings(ctx)
-	if err == nil && settings.MaxConcurrentPings > 0 {
-		maxConcurrentPings = settings.MaxConcurrentPings
+
+	// Serve Prometheus metrics for check timings, queue depth and store errors
+	go func() {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		if err := http.ListenAndServe(":"+cfg.MetricsPort, mux); err != nil {
+			slog.Error("Metrics server error", "error", err)
+		}
+	}()
+
+	// Create notifier for property down/recovery alerts
+	notify := notifier.NewNotifier(postgres, redis)
+
+	// Optional; pfSense config backups are disabled without it
+	blobs, err := storage.OpenBlobStore(ctx, storage.BlobOptions{
+		Kind:      cfg.BlobStore,
+		GCSBucket: cfg.GCSBucket,
+		S3: s3.Config{
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			Endpoint:        cfg.S3Endpoint,
+			AccessKeyID:     cfg.S3AccessKey,
+			SecretAccessKey: cfg.S3SecretKey,
+		},
+		Dir: cfg.BlobDir,
+	})
+	if err != nil {
+		logging.Fatal("Failed to open file storage", "error", err)
+	}
+	if blobs != nil {
+		defer blobs.Close()
+		slog.Info("Opened file storage", "blob_store", blobs.Kind())
+	} else {
+		slog.Warn("Neither GCS_BUCKET nor BLOB_STORE set; pfSense config backups disabled")
 	}
 
-	// Create and start pinger
-	pinger := monitor.NewPinger(postgres, redis, maxConcurrentPings)
+	// Workers split properties between them through Redis; the pod name keeps the
+	// ID stable across restarts
+	workerID := cfg.WorkerID
+	if workerID == "" {
+		workerID, _ = os.Hostname()
+	}
+	if workerID == "" {
+		logging.Fatal("WORKER_ID environment variable is required when the hostname is unknown")
+	}
+	w := worker.New(ctx, postgres, redis, notify, blobs, workerID)
 
-	// Start pinger in goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		if err := pinger.Start(ctx); err != nil {
+		if err := w.Start(ctx); err != nil {
 			errChan <- err
 		}
 	}()
@@ -68,11 +128,11 @@ func main() {
 
 	select {
 	case <-quit:
-		log.Println("Received shutdown signal")
-		pinger.Stop()
+		slog.Info("Received shutdown signal")
+		w.Stop()
 	case err := <-errChan:
-		log.Printf("Pinger error: %v", err)
+		slog.Error("Pinger error", "error", err)
 	}
 
-	log.Println("Worker stopped")
+	slog.Info("Worker stopped")
 }
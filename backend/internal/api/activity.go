@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Activity feed
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// handleListActivity returns the activity feed: status changes, incidents,
+// acknowledgements, notification deliveries and pfSense syncs, newest first. It takes
+// since, property_id, kind (comma separated), limit and the cursor of the previous page.
+func (s *Server) handleListActivity(c *gin.Context) {
+	filter := storage.ActivityFilter{Limit: defaultActivityLimit}
+
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid since time, expected RFC3339"})
+			return
+		}
+		filter.Since = t.UTC()
+	}
+
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		filter.PropertyID = id
+	}
+
+	if v := c.Query("kind"); v != "" {
+		for _, kind := range strings.Split(v, ",") {
+			kind = strings.TrimSpace(kind)
+			if !slices.Contains(storage.ActivityKinds, kind) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "kind must be one of " + strings.Join(storage.ActivityKinds, ", ")})
+				return
+			}
+			filter.Kinds = append(filter.Kinds, kind)
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			filter.Limit = l
+		}
+	}
+	if filter.Limit > maxActivityLimit {
+		filter.Limit = maxActivityLimit
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := decodeActivityCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid cursor"})
+			return
+		}
+		filter.Before = cursor
+	}
+
+	events, err := s.postgres.ListActivity(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	feed := models.ActivityFeed{Events: events}
+	if len(events) == filter.Limit {
+		last := events[len(events)-1]
+		feed.NextCursor = encodeActivityCursor(storage.ActivityCursor{OccurredAt: last.OccurredAt, Source: last.Source, ID: last.SourceID})
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// encodeActivityCursor makes an opaque page cursor from the position of an entry
+func encodeActivityCursor(cursor storage.ActivityCursor) string {
+	raw := fmt.Sprintf("%s|%s|%d", cursor.OccurredAt.UTC().Format(time.RFC3339Nano), cursor.Source, cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeActivityCursor reverses encodeActivityCursor
+func decodeActivityCursor(v string) (*storage.ActivityCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	at, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if !storage.IsActivitySource(parts[1]) {
+		return nil, fmt.Errorf("unknown activity source %q", parts[1])
+	}
+	return &storage.ActivityCursor{OccurredAt: at, Source: parts[1], ID: id}, nil
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+type acknowledgeRequest struct {
+	Comment string `json:"comment"`
+}
+
+// Acknowledgements
+func (s *Server) handleListAcknowledgements(c *gin.Context) {
+	acks, err := s.postgres.ListActiveAcknowledgements(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, acks)
+}
+
+func (s *Server) handleAcknowledgeProperty(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	var req acknowledgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	status, err := s.redis.GetPropertyStatus(c.Request.Context(), id)
+	if err != nil || status.Status != "red" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Property has no active alert"})
+		return
+	}
+
+	ack := &models.Acknowledgement{
+		EntityType: "property",
+		PropertyID: id,
+		Comment:    req.Comment,
+	}
+	s.createAcknowledgement(c, ack)
+}
+
+func (s *Server) handleAcknowledgeDevice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device ID"})
+		return
+	}
+
+	var req acknowledgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	device, err := s.postgres.GetDevice(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device not found"})
+		return
+	}
+
+	// Soft failures haven't been confirmed yet, so there is nothing to acknowledge
+	status, err := s.redis.GetDeviceStatus(c.Request.Context(), id)
+	if err != nil || status.Status == "online" || status.StateType == "soft" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Device has no active alert"})
+		return
+	}
+
+	ack := &models.Acknowledgement{
+		EntityType: "device",
+		PropertyID: device.PropertyID,
+		DeviceID:   &device.ID,
+		Comment:    req.Comment,
+	}
+	s.createAcknowledgement(c, ack)
+}
+
+func (s *Server) createAcknowledgement(c *gin.Context, ack *models.Acknowledgement) {
+	existing, err := s.postgres.ListActiveAcknowledgements(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	for _, a := range existing {
+		if a.EntityType != ack.EntityType {
+			continue
+		}
+		if (ack.EntityType == "property" && a.PropertyID == ack.PropertyID) ||
+			(ack.EntityType == "device" && a.DeviceID != nil && *a.DeviceID == *ack.DeviceID) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{Error: fmt.Sprintf("Already acknowledged by %s", a.AcknowledgedBy)})
+			return
+		}
+	}
+
+	username, _ := c.Get("username")
+	ack.AcknowledgedBy, _ = username.(string)
+
+	if err := s.postgres.CreateAcknowledgement(c.Request.Context(), ack); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ack)
+}
+
+func (s *Server) handleUnacknowledgeProperty(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	if err := s.postgres.ClearPropertyAcknowledgement(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Acknowledgement cleared"})
+}
+
+func (s *Server) handleUnacknowledgeDevice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device ID"})
+		return
+	}
+
+	if err := s.postgres.ClearDeviceAcknowledgement(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Acknowledgement cleared"})
+}
+
+// Silences
+type silenceRequest struct {
+	ScopeType  string    `json:"scope_type"`
+	PropertyID *int64    `json:"property_id"`
+	DeviceID   *int64    `json:"device_id"`
+	Tag        string    `json:"tag"`
+	Reason     string    `json:"reason"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Duration   int       `json:"duration_minutes"` // alternative to expires_at
+}
+
+func (s *Server) handleListSilences(c *gin.Context) {
+	silences, err := s.postgres.ListActiveSilences(c.Request.Context(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, silences)
+}
+
+func (s *Server) handleCreateSilence(c *gin.Context) {
+	var req silenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	silence := &models.Silence{
+		ScopeType:  req.ScopeType,
+		PropertyID: req.PropertyID,
+		DeviceID:   req.DeviceID,
+		Tag:        req.Tag,
+		Reason:     req.Reason,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if req.Duration > 0 {
+		silence.ExpiresAt = time.Now().Add(time.Duration(req.Duration) * time.Minute)
+	}
+
+	if err := validateSilence(silence); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	username, _ := c.Get("username")
+	silence.CreatedBy, _ = username.(string)
+
+	if err := s.postgres.CreateSilence(c.Request.Context(), silence); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, silence)
+}
+
+func (s *Server) handleDeleteSilence(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid silence ID"})
+		return
+	}
+
+	if err := s.postgres.ExpireSilence(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Silence expired"})
+}
+
+func validateSilence(silence *models.Silence) error {
+	switch silence.ScopeType {
+	case "device":
+		if silence.DeviceID == nil {
+			return fmt.Errorf("device_id is required for device silences")
+		}
+	case "property":
+		if silence.PropertyID == nil {
+			return fmt.Errorf("property_id is required for property silences")
+		}
+	case "tag":
+		if silence.Tag == "" {
+			return fmt.Errorf("tag is required for tag silences")
+		}
+	default:
+		return fmt.Errorf("scope_type must be device, property or tag")
+	}
+	if !silence.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at or duration_minutes must be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/orphans"
+)
+
+// handleCleanupOrphanedAttachments deletes the files under properties/ in blob
+// storage that no attachment or chunked upload refers to, left by failed uploads and
+// deleted properties. Files from the last day are spared. ?dry_run=true only lists
+// them.
+func (s *Server) handleCleanupOrphanedAttachments(c *gin.Context) {
+	if s.blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage is not configured"})
+		return
+	}
+
+	sweep, err := orphans.Sweep(c.Request.Context(), s.postgres, s.blobs, c.Query("dry_run") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sweep)
+}
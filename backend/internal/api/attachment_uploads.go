@@ -0,0 +1,265 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Chunked attachment uploads. A client starts an upload, sends the file in parts of
+// chunk_size bytes with PUT /attachment-uploads/:id/parts?offset=N, and completes it
+// into an attachment.
+// After a dropped connection it reads the upload back and carries on from received.
+
+// maxSingleUploadSize caps attachments sent in one multipart request; larger files
+// need a chunked upload
+const maxSingleUploadSize = 50 * 1024 * 1024
+
+// maxAttachmentSize is the largest attachment the settings allow, in bytes
+func (s *Server) maxAttachmentSize(c *gin.Context) (int64, bool) {
+	settings, err := s.postgres.GetSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return 0, false
+	}
+	return int64(settings.MaxAttachmentSizeMB) * 1024 * 1024, true
+}
+
+// chunkedBlobs returns the blob store if it takes chunked uploads, or responds with why
+// not
+func (s *Server) chunkedBlobs(c *gin.Context) (storage.ChunkedBlobStore, bool) {
+	if s.blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage is not configured"})
+		return nil, false
+	}
+	chunked, ok := s.blobs.(storage.ChunkedBlobStore)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: fmt.Sprintf("Chunked uploads are not supported with %s file storage", s.blobs.Kind())})
+		return nil, false
+	}
+	return chunked, true
+}
+
+func (s *Server) handleStartAttachmentUpload(c *gin.Context) {
+	ctx := c.Request.Context()
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	var req models.StartAttachmentUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.Size < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "size must be at least 1"})
+		return
+	}
+	// The name becomes part of the object path, so only its last element is kept
+	req.Filename = path.Base(req.Filename)
+	if req.Filename == "." || req.Filename == ".." || req.Filename == "/" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid filename"})
+		return
+	}
+
+	blobs, ok := s.chunkedBlobs(c)
+	if !ok {
+		return
+	}
+	if _, err := s.postgres.GetProperty(ctx, propertyID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+	maxSize, ok := s.maxAttachmentSize(c)
+	if !ok {
+		return
+	}
+	if req.Size > maxSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("File too large (max %dMB)", maxSize/1024/1024)})
+		return
+	}
+
+	objectName := fmt.Sprintf("properties/%d/%d-%s", propertyID, time.Now().Unix(), req.Filename)
+	session, err := blobs.StartUpload(ctx, objectName, req.MimeType, req.Size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: fmt.Sprintf("Failed to start upload: %v", err)})
+		return
+	}
+
+	upload := &models.AttachmentUpload{
+		PropertyID:  propertyID,
+		Filename:    req.Filename,
+		Description: req.Description,
+		MimeType:    req.MimeType,
+		Tags:        normalizeTags(req.Tags),
+		FileSize:    req.Size,
+		StorageType: blobs.Kind(),
+		StoragePath: objectName,
+		Session:     session,
+		UploadedBy:  c.GetString("username"),
+	}
+	if err := s.postgres.CreateAttachmentUpload(ctx, upload); err != nil {
+		blobs.AbortUpload(ctx, session)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	upload.ChunkSize = storage.UploadChunkSize
+	c.JSON(http.StatusCreated, upload)
+}
+
+// loadAttachmentUpload looks up the upload in the path along with the blob store
+// holding it. Uploads belong to whoever started them.
+func (s *Server) loadAttachmentUpload(c *gin.Context) (*models.AttachmentUpload, storage.ChunkedBlobStore, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid upload ID"})
+		return nil, nil, false
+	}
+	upload, err := s.postgres.GetAttachmentUpload(c.Request.Context(), id)
+	if err != nil || upload.UploadedBy != c.GetString("username") {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Upload not found"})
+		return nil, nil, false
+	}
+	upload.ChunkSize = storage.UploadChunkSize
+
+	blobs, ok := s.chunkedBlobs(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if blobs.Kind() != upload.StorageType {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage for this upload is not configured"})
+		return nil, nil, false
+	}
+	return upload, blobs, true
+}
+
+// handleGetAttachmentUpload returns an upload, whose received count is where a
+// resumed upload carries on from
+func (s *Server) handleGetAttachmentUpload(c *gin.Context) {
+	upload, _, ok := s.loadAttachmentUpload(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, upload)
+}
+
+// handleUploadAttachmentPart takes the part of the file starting at the offset query
+// parameter as the raw request body. The blob store may keep less than it was sent;
+// the response's received is where the next part starts.
+func (s *Server) handleUploadAttachmentPart(c *gin.Context) {
+	ctx := c.Request.Context()
+	upload, blobs, ok := s.loadAttachmentUpload(c)
+	if !ok {
+		return
+	}
+	if time.Since(upload.CreatedAt) > storage.UploadSessionLifetime {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Upload has expired"})
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid offset"})
+		return
+	}
+	if offset != upload.Received {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: fmt.Sprintf("Part must start at offset %d", upload.Received)})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, storage.UploadChunkSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to read part"})
+		return
+	}
+	end := offset + int64(len(data))
+	switch {
+	case len(data) == 0:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "No data provided"})
+		return
+	case len(data) > storage.UploadChunkSize || end > upload.FileSize:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Part too large"})
+		return
+	case end < upload.FileSize && len(data) != storage.UploadChunkSize:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Parts other than the last must be %d bytes", storage.UploadChunkSize)})
+		return
+	}
+
+	received, err := blobs.UploadPart(ctx, upload.Session, offset, data, upload.FileSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: fmt.Sprintf("Failed to upload: %v", err)})
+		return
+	}
+	if err := s.postgres.UpdateAttachmentUploadReceived(ctx, upload.ID, received); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	upload.Received = received
+	c.JSON(http.StatusOK, upload)
+}
+
+// handleCompleteAttachmentUpload turns a fully received upload into an attachment
+func (s *Server) handleCompleteAttachmentUpload(c *gin.Context) {
+	upload, _, ok := s.loadAttachmentUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Received < upload.FileSize {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: fmt.Sprintf("Upload is incomplete: received %d of %d bytes", upload.Received, upload.FileSize)})
+		return
+	}
+
+	attachment := &models.Attachment{
+		PropertyID:  upload.PropertyID,
+		Filename:    upload.Filename,
+		Description: upload.Description,
+		StorageType: upload.StorageType,
+		StoragePath: upload.StoragePath,
+		FileSize:    upload.FileSize,
+		MimeType:    upload.MimeType,
+		Tags:        upload.Tags,
+		UploadedBy:  upload.UploadedBy,
+	}
+	if err := s.postgres.CompleteAttachmentUpload(c.Request.Context(), upload.ID, attachment); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// handleAbortAttachmentUpload cancels an upload and discards what was sent
+func (s *Server) handleAbortAttachmentUpload(c *gin.Context) {
+	ctx := c.Request.Context()
+	upload, blobs, ok := s.loadAttachmentUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Received < upload.FileSize {
+		if err := blobs.AbortUpload(ctx, upload.Session); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to cancel upload"})
+			return
+		}
+	} else if err := blobs.DeleteFile(ctx, upload.StoragePath); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete file"})
+		return
+	}
+
+	if err := s.postgres.DeleteAttachmentUpload(ctx, upload.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload cancelled"})
+}
@@ -0,0 +1,43 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/etswifi/ets-noc/internal/apitest"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+func TestStartAttachmentUploadRejectsPathFilenames(t *testing.T) {
+	ctx := context.Background()
+	h, err := apitest.New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if _, err := h.CreateUser(ctx, "admin", "admin-password", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := h.Login("admin", "admin-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	property := &models.Property{Name: "Harbor Inn"}
+	if err := h.Store.CreateProperty(ctx, property); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"..", "../..", "/", "./"} {
+		status, err := h.Do(http.MethodPost, fmt.Sprintf("/api/v1/properties/%d/attachment-uploads", property.ID), admin,
+			models.StartAttachmentUploadRequest{Filename: name, Size: 10}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != http.StatusBadRequest {
+			t.Fatalf("start upload of %q returned %d, want %d", name, status, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/audit"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// handleGetDeviceAudit returns the latest device audit from the worker, or runs one when
+// there is none or ?refresh=true
+func (s *Server) handleGetDeviceAudit(c *gin.Context) {
+	refresh, ok := boolQuery(c, "refresh")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if refresh == nil || !*refresh {
+		if result, err := s.redis.GetDeviceAudit(ctx); err == nil {
+			c.JSON(http.StatusOK, result)
+			return
+		}
+	}
+
+	result, err := audit.Devices(ctx, s.postgres)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := s.redis.SetDeviceAudit(ctx, result, audit.ResultTTL); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// validateDeviceConflicts rejects a device that checks the same target as another device
+// at its property, or whose MAC address is on another device anywhere. An update is only
+// checked for the target or MAC it changes, so devices that already conflict can still
+// be edited.
+func (s *Server) validateDeviceConflicts(ctx context.Context, device *models.Device) error {
+	device.MACAddress = strings.ToLower(strings.TrimSpace(device.MACAddress))
+	var current *models.Device
+	if device.ID != 0 {
+		current, _ = s.postgres.GetDevice(ctx, device.ID)
+	}
+
+	if current == nil || current.PropertyID != device.PropertyID || audit.TargetKey(current) != audit.TargetKey(device) {
+		devices, err := s.postgres.ListDevicesForProperty(ctx, device.PropertyID)
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			if d.ID != device.ID && audit.TargetKey(&d) == audit.TargetKey(device) {
+				return fmt.Errorf("device %q at this property already checks %s", d.Name, audit.Target(device))
+			}
+		}
+	}
+
+	if device.MACAddress != "" && (current == nil || current.MACAddress != device.MACAddress) {
+		devices, err := s.postgres.ListDevicesByMAC(ctx, device.MACAddress)
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			if d.ID != device.ID {
+				return fmt.Errorf("MAC address %s is already on device %q", device.MACAddress, d.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -1,9 +1,9 @@
 package api
 
 import (
-	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +14,57 @@ import (
 	"github.com/etswifi/ets-noc/internal/storage"
 )
 
-var jwtSecret = []byte("your-secret-key-change-in-production")
+// defaultJWTSecret signs session tokens when no keys are configured, for development.
+// The API refuses to start with it in release mode.
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
+// minJWTSecretLength is the shortest signing secret accepted, the HS256 key size
+const minJWTSecretLength = 32
+
+// jwtKey is an HMAC key for session tokens, named in their kid header
+type jwtKey struct {
+	id     string
+	secret []byte
+}
+
+// jwtKeys are the keys session tokens are checked against. The first one signs new
+// tokens.
+var jwtKeys = []jwtKey{{id: "default", secret: []byte(defaultJWTSecret)}}
+
+// SetJWTKeys configures the session token keys from a comma-separated list of id:secret
+// pairs. The first key signs new tokens and the others are still accepted, so a key
+// can be rotated out once the tokens it signed have expired instead of signing
+// everyone out at once.
+func SetJWTKeys(spec string) error {
+	var keys []jwtKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return fmt.Errorf("each key must be id:secret")
+		}
+		if len(secret) < minJWTSecretLength {
+			return fmt.Errorf("key %q: secret must be at least %d characters", id, minJWTSecretLength)
+		}
+		if secret == defaultJWTSecret {
+			return fmt.Errorf("key %q: the built-in development secret can't be used", id)
+		}
+		for _, key := range keys {
+			if key.id == id {
+				return fmt.Errorf("key %q appears more than once", id)
+			}
+		}
+		keys = append(keys, jwtKey{id: id, secret: []byte(secret)})
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one key is required")
+	}
+	jwtKeys = keys
+	return nil
+}
 
 type Claims struct {
 	UserID   int64  `json:"user_id"`
@@ -23,19 +73,23 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func generateToken(user *models.User) (string, error) {
+// generateToken returns the token for a user's session, which it names as its ID
+func generateToken(user *models.User, session *models.UserSession) (string, error) {
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        strconv.FormatInt(session.ID, 10),
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	key := jwtKeys[0]
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.secret)
 }
 
 func parseToken(tokenString string) (*Claims, error) {
@@ -43,7 +97,18 @@ func parseToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
+		// Tokens from before key IDs were added carry none; they can only match the
+		// signing key
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return jwtKeys[0].secret, nil
+		}
+		for _, key := range jwtKeys {
+			if key.id == kid {
+				return key.secret, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
 	})
 
 	if err != nil {
@@ -68,7 +133,7 @@ func checkPassword(password, hash string) bool {
 }
 
 // Middleware
-func AuthMiddleware(postgres *storage.PostgresStore) gin.HandlerFunc {
+func AuthMiddleware(postgres storage.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -90,11 +155,60 @@ func AuthMiddleware(postgres *storage.PostgresStore) gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		sessionID, err := checkSession(c, postgres, claims)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Session expired or revoked"})
+			c.Abort()
+			return
+		}
 
 		// Store claims in context
+		c.Set("session_id", sessionID)
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		addLogFields(c, "user_id", claims.UserID, "username", claims.Username)
+
+		c.Next()
+	}
+}
+
+// StreamAuthMiddleware authenticates long-lived streaming connections. Browsers can't
+// set headers on WebSocket or EventSource requests, so the token may also be passed
+// as a "token" query parameter.
+func StreamAuthMiddleware(postgres storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				token = parts[1]
+			}
+		}
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Authorization required"})
+			c.Abort()
+			return
+		}
+
+		claims, err := parseToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid token"})
+			c.Abort()
+			return
+		}
+		sessionID, err := checkSession(c, postgres, claims)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		c.Set("session_id", sessionID)
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		addLogFields(c, "user_id", claims.UserID, "username", claims.Username)
 
 		c.Next()
 	}
@@ -112,6 +226,29 @@ func AdminOnlyMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ViewerReadOnlyMiddleware gives viewers, such as wall-board displays and property
+// owners, read-only access: anything but a read gets 403
+func ViewerReadOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if role == "viewer" {
+				c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Viewers have read-only access"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// validRole reports whether role is one a user can have
+func validRole(role string) bool {
+	return role == "admin" || role == "user" || role == "viewer"
+}
+
 // Handlers
 func (s *Server) handleLogin(c *gin.Context) {
 	var req models.LoginRequest
@@ -120,7 +257,8 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
-	user, err := s.postgres.GetUserByUsername(context.Background(), req.Username)
+	ctx := c.Request.Context()
+	user, err := s.postgres.GetUserByUsername(ctx, req.Username)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid credentials"})
 		return
@@ -136,7 +274,29 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := generateToken(user)
+	// With two-factor on, the client retries with totp_code once it sees totp_required
+	if user.TOTPEnabled {
+		if req.TOTPCode == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor code required", "totp_required": true})
+			return
+		}
+		state, err := s.postgres.GetUserTOTP(ctx, user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		ok, err := s.checkSecondFactor(ctx, state, req.TOTPCode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code", "totp_required": true})
+			return
+		}
+	}
+
+	token, err := s.startSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
 		return
@@ -148,9 +308,60 @@ func (s *Server) handleLogin(c *gin.Context) {
 	})
 }
 
+// minPasswordLength is the shortest password a user can set for themselves
+const minPasswordLength = 8
+
+// handleChangePassword lets users rotate their own password, given the current one
+func (s *Server) handleChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("new_password must be at least %d characters", minPasswordLength)})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "new_password must differ from the current password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := s.postgres.GetUser(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if !checkPassword(req.CurrentPassword, user.Password) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to hash password"})
+		return
+	}
+	if err := s.postgres.UpdateUserPassword(ctx, user.ID, hashedPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Anyone else signed in with the old password is signed out
+	revoked, err := s.postgres.RevokeUserSessions(ctx, user.ID, c.GetInt64("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("Password changed", "revoked_sessions", revoked)
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
 func (s *Server) handleGetMe(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	user, err := s.postgres.GetUser(context.Background(), userID.(int64))
+	user, err := s.postgres.GetUser(c.Request.Context(), userID.(int64))
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
 		return
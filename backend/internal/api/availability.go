@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Availability calendars
+
+// availabilityRange is the bucketing an availability calendar request asks for
+type availabilityRange struct {
+	resolution string
+	location   *time.Location
+	start      time.Time // start of the first bucket
+	end        time.Time
+}
+
+// next returns the start of the bucket after the one starting at t
+func (r availabilityRange) next(t time.Time) time.Time {
+	if r.resolution == "hour" {
+		return t.Add(time.Hour)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// availabilityParams reads ?resolution= (day or hour, default day), ?tz= (IANA name,
+// default UTC) and start/end, defaulting to the last 90 days or, by hour, 7 days.
+// The start is moved back to the beginning of its day or hour.
+func availabilityParams(c *gin.Context) (availabilityRange, bool) {
+	r := availabilityRange{resolution: c.DefaultQuery("resolution", "day")}
+	window, maxWindow := 90*24*time.Hour, 366*24*time.Hour
+	switch r.resolution {
+	case "day":
+	case "hour":
+		window, maxWindow = 7*24*time.Hour, 31*24*time.Hour
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "resolution must be day or hour"})
+		return r, false
+	}
+
+	tz := c.DefaultQuery("tz", "UTC")
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("unknown timezone %q", tz)})
+		return r, false
+	}
+	r.location = location
+
+	start, end := timeRange(c, window)
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end must be after start"})
+		return r, false
+	}
+	if end.Sub(start) > maxWindow {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("range may not exceed %d days by %s", int(maxWindow.Hours()/24), r.resolution)})
+		return r, false
+	}
+
+	start = start.In(location)
+	if r.resolution == "hour" {
+		r.start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, location)
+	} else {
+		r.start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+	}
+	r.end = end.In(location)
+	return r, true
+}
+
+// handleGetDeviceAvailability returns a device's uptime per day or hour, where the
+// device is down while it is offline or unreachable
+func (s *Server) handleGetDeviceAvailability(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device ID"})
+		return
+	}
+	r, ok := availabilityParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	device, err := s.postgres.GetDevice(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device not found"})
+		return
+	}
+
+	// Without any status change the device has been in its current state throughout
+	current := ""
+	if status, err := s.redis.GetDeviceStatus(ctx, id); err == nil {
+		current = status.Status
+		if status.StateType == "soft" {
+			current = "online"
+		}
+	}
+
+	filter := storage.StatusEventFilter{EntityType: "device", DeviceID: id}
+	calendar, err := s.availabilityCalendar(ctx, filter, r, device.CreatedAt, current, func(status string) bool {
+		return status != "" && status != "online"
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	calendar.EntityID = id
+	c.JSON(http.StatusOK, calendar)
+}
+
+// handleGetPropertyAvailability returns a property's uptime per day or hour, where the
+// property is down while it is red
+func (s *Server) handleGetPropertyAvailability(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+	r, ok := availabilityParams(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	property, err := s.postgres.GetProperty(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	current := ""
+	if status, err := s.redis.GetPropertyStatus(ctx, id); err == nil {
+		current = status.Status
+	}
+
+	filter := storage.StatusEventFilter{EntityType: "property", PropertyID: id}
+	calendar, err := s.availabilityCalendar(ctx, filter, r, property.CreatedAt, current, func(status string) bool {
+		return status == "red"
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	calendar.EntityID = id
+	c.JSON(http.StatusOK, calendar)
+}
+
+// availabilityCalendar replays an entity's status events over the range's buckets.
+// Time before monitoredFrom or after now isn't counted; current is the entity's status
+// now, used when no event says what it was at the start.
+func (s *Server) availabilityCalendar(ctx context.Context, filter storage.StatusEventFilter, r availabilityRange, monitoredFrom time.Time, current string, down func(string) bool) (*models.AvailabilityCalendar, error) {
+	now := time.Now()
+	end := earlier(r.end, now)
+
+	// The state the range starts in is set by the last change before it
+	before := filter
+	before.End, before.Limit = r.start.UTC().Add(-time.Microsecond), 1
+	previous, err := s.postgres.ListStatusEvents(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	inRange := filter
+	inRange.Start, inRange.End = r.start.UTC(), end.UTC()
+	events, err := s.postgres.ListStatusEvents(ctx, inRange)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(events)
+
+	state := current
+	switch {
+	case len(previous) > 0:
+		state = previous[0].ToStatus
+	case len(events) > 0:
+		state = events[0].FromStatus
+	}
+
+	calendar := &models.AvailabilityCalendar{
+		EntityType: filter.EntityType,
+		Resolution: r.resolution,
+		Timezone:   r.location.String(),
+		Start:      r.start,
+		End:        r.end,
+		Buckets:    make([]models.AvailabilityBucket, 0),
+	}
+
+	var monitoredTotal, downTotal time.Duration
+	at := r.start // how far the events have been replayed
+	next := 0
+	for bucketStart := r.start; bucketStart.Before(r.end); bucketStart = r.next(bucketStart) {
+		bucketEnd := r.next(bucketStart)
+		bucket := models.AvailabilityBucket{Start: bucketStart, Date: bucketStart.Format("2006-01-02")}
+
+		// The part of the bucket that was monitored
+		from, to := later(bucketStart, monitoredFrom), earlier(bucketEnd, now)
+		var monitored, downtime time.Duration
+		for at.Before(bucketEnd) {
+			segmentEnd := bucketEnd
+			if next < len(events) && events[next].OccurredAt.Before(bucketEnd) {
+				segmentEnd = events[next].OccurredAt
+			}
+			if overlap := earlier(segmentEnd, to).Sub(later(at, from)); overlap > 0 {
+				monitored += overlap
+				if down(state) {
+					downtime += overlap
+				}
+			}
+			at = segmentEnd
+			if segmentEnd == bucketEnd {
+				break
+			}
+			e := events[next]
+			if down(e.ToStatus) && !down(state) && !e.OccurredAt.Before(from) {
+				bucket.OutageCount++
+			}
+			state = e.ToStatus
+			next++
+		}
+
+		if monitored > 0 {
+			uptime := 100 * float64(monitored-downtime) / float64(monitored)
+			bucket.UptimePercent = &uptime
+		}
+		bucket.MonitoredMinutes = monitored.Minutes()
+		bucket.DowntimeMinutes = downtime.Minutes()
+		monitoredTotal += monitored
+		downTotal += downtime
+		calendar.Buckets = append(calendar.Buckets, bucket)
+	}
+
+	if monitoredTotal > 0 {
+		uptime := 100 * float64(monitoredTotal-downTotal) / float64(monitoredTotal)
+		calendar.UptimePercent = &uptime
+	}
+	return calendar, nil
+}
+
+// earlier returns whichever of a and b comes first
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// later returns whichever of a and b comes last
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Embeddable status badges
+
+// maxBadgeLabelLength caps the ?label= text of a badge
+const maxBadgeLabelLength = 40
+
+func (s *Server) handleGetPropertyBadge(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	badge, err := s.postgres.GetPropertyBadge(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property badge not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, badge)
+}
+
+// handleCreatePropertyBadge turns on a property's badge, or replaces its token if it has
+// one so links with the old token stop working, and returns the new token
+func (s *Server) handleCreatePropertyBadge(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.postgres.GetProperty(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	token, err := newPublicToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+
+	if badge, err := s.postgres.GetPropertyBadge(ctx, id); err == nil {
+		if err := s.postgres.UpdatePropertyBadgeToken(ctx, badge, hashPublicToken(token)); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		requestLog(c).Info("Rotated property badge token")
+		badge.Token = token
+		c.JSON(http.StatusOK, badge)
+		return
+	}
+
+	badge := models.PropertyBadge{PropertyID: id}
+	if err := s.postgres.CreatePropertyBadge(ctx, &badge, hashPublicToken(token)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	badge.Token = token
+	c.JSON(http.StatusCreated, badge)
+}
+
+func (s *Server) handleDeletePropertyBadge(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	badge, err := s.postgres.GetPropertyBadge(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property badge not found"})
+		return
+	}
+	if err := s.postgres.DeletePropertyBadge(ctx, badge.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Property badge deleted"})
+}
+
+// handlePublicPropertyBadge returns a badge of the current status of the property a
+// badge token belongs to as an SVG image, without sign-in. ?label= replaces the
+// "status" text on the left.
+func (s *Server) handlePublicPropertyBadge(c *gin.Context) {
+	ctx := c.Request.Context()
+	badge, err := s.postgres.GetPropertyBadgeByTokenHash(ctx, hashPublicToken(c.Param("token")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property badge not found"})
+		return
+	}
+	property, err := s.postgres.GetProperty(ctx, badge.PropertyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property badge not found"})
+		return
+	}
+
+	// A property that hasn't been checked yet has no status and shows as unknown
+	status, _ := s.redis.GetPropertyStatus(ctx, property.ID)
+	entry, _ := publicPropertyStatus(property, status)
+
+	label := strings.TrimSpace(c.Query("label"))
+	if label == "" {
+		label = "status"
+	}
+	if utf8.RuneCountInString(label) > maxBadgeLabelLength {
+		label = string([]rune(label)[:maxBadgeLabelLength])
+	}
+	message, color := badgeMessage(entry)
+
+	c.Header("Cache-Control", "private, max-age=30")
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(renderBadge(label, message, color)))
+}
+
+// badgeMessage returns the right-hand text and color of a property's badge, in the
+// same customer-safe wording as status pages
+func badgeMessage(entry models.PublicPropertyStatus) (string, string) {
+	switch {
+	case entry.Maintenance && entry.Status != "green":
+		return "maintenance", "#007ec6"
+	case entry.Status == "red":
+		return "disruption", "#e05d44"
+	case entry.Status == "yellow":
+		return "degraded", "#dfb317"
+	case entry.Status == "green":
+		return "operational", "#4c1"
+	}
+	return "unknown", "#9f9f9f"
+}
+
+// renderBadge draws a flat two-part badge in the style of shields.io
+func renderBadge(label, message, color string) string {
+	labelWidth := badgeTextWidth(label) + 10
+	messageWidth := badgeTextWidth(message) + 10
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, width, labelWidth, messageWidth, label, message, color, labelWidth/2, labelWidth+messageWidth/2)
+}
+
+// badgeTextWidth estimates the width in pixels of text in 11px Verdana
+func badgeTextWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("iljI.,:;!|' ", r):
+			width += 4
+		case strings.ContainsRune("frt()[]-", r):
+			width += 5
+		case strings.ContainsRune("mwMW", r):
+			width += 11
+		case r >= 'A' && r <= 'Z':
+			width += 8
+		default:
+			width += 7
+		}
+	}
+	return width
+}
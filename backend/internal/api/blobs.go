@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// isBlobStorage reports whether an attachment of a storage type is kept in a BlobStore,
+// rather than linked from elsewhere like Google Drive
+func isBlobStorage(storageType string) bool {
+	return storageType == "gcs" || storageType == "s3" || storageType == "local"
+}
+
+// handleGetLocalBlob serves a file from local blob storage through a link made by
+// LocalBlobStore.GetSignedURL. It needs no sign-in, like a GCS signed URL, so the
+// link's signature and expiry are what protect the file.
+func (s *Server) handleGetLocalBlob(c *gin.Context) {
+	blobs, ok := s.blobs.(*storage.LocalBlobStore)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "File not found"})
+		return
+	}
+
+	objectName := strings.TrimPrefix(c.Param("name"), "/")
+	if !blobs.Verify(objectName, c.Query("expires"), c.Query("signature")) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Invalid or expired link"})
+		return
+	}
+
+	file := blobs.Path(objectName)
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "File not found"})
+		return
+	}
+	c.FileAttachment(file, path.Base(objectName))
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Response caching
+
+// dashboardCacheTTL is how long an assembled dashboard is reused. Wall boards polling
+// within it get the same response, built once across the API replicas.
+const dashboardCacheTTL = 5 * time.Second
+
+// cachedDashboard returns the dashboard for filter as gzipped JSON, from the response
+// cache when it was built within dashboardCacheTTL. A failing cache is bypassed.
+func (s *Server) cachedDashboard(c *gin.Context, filter storage.PropertyFilter) ([]byte, error) {
+	ctx := c.Request.Context()
+	key := fmt.Sprintf("dashboard:%d:%d:%s", filter.RegionID, filter.GroupID, filter.Tag)
+	body, err := s.redis.GetCachedResponse(ctx, key)
+	if err != nil {
+		requestLog(c).Warn("Failed to read cached dashboard", "error", err)
+	} else if body != nil {
+		return body, nil
+	}
+
+	body, err = s.buildGzippedDashboard(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.SetCachedResponse(ctx, key, body, dashboardCacheTTL); err != nil {
+		requestLog(c).Warn("Failed to cache dashboard", "error", err)
+	}
+	return body, nil
+}
+
+func (s *Server) buildGzippedDashboard(ctx context.Context, filter storage.PropertyFilter) ([]byte, error) {
+	response, err := s.buildDashboard(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeGzippedJSON sends a gzipped JSON body with an ETag, answering 304 when the
+// client already has it and decompressing it for clients that don't accept gzip
+func writeGzippedJSON(c *gin.Context, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Vary", "Accept-Encoding")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if acceptsGzip(c.GetHeader("Accept-Encoding")) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+		return
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err == nil {
+		var data []byte
+		if data, err = io.ReadAll(zr); err == nil {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+			return
+		}
+	}
+	requestLog(c).Error("Failed to decompress cached response", "error", err)
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+// etagMatches reports whether an If-None-Match header names etag. Matching is weak, as
+// If-None-Match requires, so W/ prefixes are ignored.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows a gzip response
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		// q=0 means the coding is not acceptable
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
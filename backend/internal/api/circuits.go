@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// ISP circuits. A circuit tied to a WAN device is named in down alerts while that
+// device is down.
+func (s *Server) handleListCircuitsForProperty(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	circuits, err := s.postgres.ListCircuitsForProperty(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, circuits)
+}
+
+func (s *Server) handleCreateCircuit(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	if _, err := s.postgres.GetProperty(c.Request.Context(), propertyID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	var circuit models.Circuit
+	if err := c.ShouldBindJSON(&circuit); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	circuit.PropertyID = propertyID
+	if err := s.validateCircuit(c.Request.Context(), &circuit); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreateCircuit(c.Request.Context(), &circuit); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, circuit)
+}
+
+func (s *Server) handleGetCircuit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid circuit ID"})
+		return
+	}
+
+	circuit, err := s.postgres.GetCircuit(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Circuit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, circuit)
+}
+
+func (s *Server) handleUpdateCircuit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid circuit ID"})
+		return
+	}
+
+	current, err := s.postgres.GetCircuit(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Circuit not found"})
+		return
+	}
+
+	var circuit models.Circuit
+	if err := c.ShouldBindJSON(&circuit); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	circuit.ID = id
+	circuit.PropertyID = current.PropertyID
+	if err := s.validateCircuit(c.Request.Context(), &circuit); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.UpdateCircuit(c.Request.Context(), &circuit); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, circuit)
+}
+
+func (s *Server) handleDeleteCircuit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid circuit ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteCircuit(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Circuit deleted"})
+}
+
+// validateCircuit normalizes a circuit and checks that its WAN device is at the
+// circuit's property
+func (s *Server) validateCircuit(ctx context.Context, circuit *models.Circuit) error {
+	if err := normalizeCircuit(circuit); err != nil {
+		return err
+	}
+	if circuit.DeviceID == nil {
+		return nil
+	}
+
+	device, err := s.postgres.GetDevice(ctx, *circuit.DeviceID)
+	if err != nil {
+		return fmt.Errorf("device %d not found", *circuit.DeviceID)
+	}
+	if device.PropertyID != circuit.PropertyID {
+		return fmt.Errorf("device %d belongs to a different property", device.ID)
+	}
+	return nil
+}
+
+// normalizeCircuit trims a circuit's fields and validates its bandwidth and static IPs
+func normalizeCircuit(circuit *models.Circuit) error {
+	circuit.Provider = strings.TrimSpace(circuit.Provider)
+	circuit.CircuitID = strings.TrimSpace(circuit.CircuitID)
+	circuit.SupportPhone = strings.TrimSpace(circuit.SupportPhone)
+	if circuit.Provider == "" {
+		return fmt.Errorf("provider is required")
+	}
+	if circuit.BandwidthDownMbps < 0 || circuit.BandwidthUpMbps < 0 {
+		return fmt.Errorf("bandwidth must not be negative")
+	}
+
+	ips := make([]string, 0, len(circuit.StaticIPs))
+	for _, ip := range circuit.StaticIPs {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if net.ParseIP(ip) == nil {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return fmt.Errorf("invalid static IP %q", ip)
+			}
+		}
+		ips = append(ips, ip)
+	}
+	circuit.StaticIPs = ips
+	return nil
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/backup"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// pfSense config backups
+
+func (s *Server) handleListConfigBackups(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	backups, err := s.postgres.ListConfigBackupsForProperty(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// handleCreateConfigBackup backs up the property's config.xml now. Returns 201 with the
+// new backup, or 200 with the latest one if the config hasn't changed since.
+func (s *Server) handleCreateConfigBackup(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	if s.blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage is not configured"})
+		return
+	}
+
+	property, err := s.postgres.GetProperty(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+	if !backup.HasCredentials(property) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "pfSense credentials not configured for this property",
+		})
+		return
+	}
+
+	username, _ := c.Get("username")
+	createdBy, _ := username.(string)
+
+	stored, created, err := backup.BackupProperty(c.Request.Context(), s.postgres, s.blobs, property, backup.TriggerManual, createdBy)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Error: fmt.Sprintf("Failed to back up config: %v", err)})
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, stored)
+		return
+	}
+	c.JSON(http.StatusCreated, stored)
+}
+
+func (s *Server) handleDownloadConfigBackup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid backup ID"})
+		return
+	}
+
+	stored, err := s.postgres.GetConfigBackup(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Config backup not found"})
+		return
+	}
+
+	if s.blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage is not configured"})
+		return
+	}
+
+	// Generate signed URL (valid for 15 minutes; config.xml holds credentials)
+	url, err := s.blobs.GetSignedURL(c.Request.Context(), stored.StoragePath, 15*time.Minute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate download URL"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": url})
+}
@@ -0,0 +1,735 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Full configuration export and import: properties with their devices, contacts and
+// notification links, notification channels and rules, and settings
+
+const configDocumentVersion = 1
+
+// maxConfigImportSize caps an uploaded configuration document
+const maxConfigImportSize = 50 << 20
+
+// Conflict strategies for entries of an import that already exist
+const (
+	conflictFail      = "fail"      // import nothing if anything exists
+	conflictSkip      = "skip"      // keep existing entries as they are
+	conflictOverwrite = "overwrite" // replace existing entries with the document's
+)
+
+func (s *Server) handleExportConfig(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "format must be json or yaml"})
+		return
+	}
+
+	doc, err := s.exportConfig(c.Request.Context(), c.Query("include_secrets") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err == nil && format == "yaml" {
+		data, err = jsonToYAML(data)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	contentType := "application/json"
+	if format == "yaml" {
+		contentType = "application/yaml"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="ets-noc-config-%s.%s"`, doc.ExportedAt.Format("20060102-150405"), format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// exportConfig collects the configuration document. pfSense passwords, the OIDC client
+// secret and the credentials in notification channel configs are left out unless
+// secrets is set.
+func (s *Server) exportConfig(ctx context.Context, secrets bool) (*models.ConfigDocument, error) {
+	doc := &models.ConfigDocument{Version: configDocumentVersion, ExportedAt: time.Now().UTC()}
+
+	var err error
+	if doc.Settings, err = s.postgres.GetSettings(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	if !secrets {
+		doc.Settings.OIDC.ClientSecret = ""
+	}
+	if doc.NotificationChannels, err = s.postgres.ListNotificationChannels(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	if !secrets {
+		for i := range doc.NotificationChannels {
+			nc := &doc.NotificationChannels[i]
+			if nc.Config, err = notifier.RedactChannelConfig(nc.Type, nc.Config); err != nil {
+				return nil, fmt.Errorf("notification channel %q: %w", nc.Name, err)
+			}
+		}
+	}
+	if doc.NotificationRules, err = s.postgres.ListNotificationRules(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load notification rules: %w", err)
+	}
+
+	properties, err := s.postgres.ListProperties(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load properties: %w", err)
+	}
+	doc.Properties = make([]models.ConfigProperty, len(properties))
+	for i, p := range properties {
+		if secrets {
+			p.PfSensePasswordSet = p.PfSensePassword != ""
+		} else {
+			redactProperty(&p)
+		}
+		entry := models.ConfigProperty{Property: p}
+		if entry.Devices, err = s.postgres.ListDevicesForProperty(ctx, p.ID); err != nil {
+			return nil, fmt.Errorf("failed to load devices for property %d: %w", p.ID, err)
+		}
+		if entry.Contacts, err = s.postgres.ListContactsForProperty(ctx, p.ID); err != nil {
+			return nil, fmt.Errorf("failed to load contacts for property %d: %w", p.ID, err)
+		}
+		if entry.Circuits, err = s.postgres.ListCircuitsForProperty(ctx, p.ID); err != nil {
+			return nil, fmt.Errorf("failed to load circuits for property %d: %w", p.ID, err)
+		}
+		if entry.Notifications, err = s.postgres.ListPropertyNotifications(ctx, p.ID); err != nil {
+			return nil, fmt.Errorf("failed to load notifications for property %d: %w", p.ID, err)
+		}
+		doc.Properties[i] = entry
+	}
+	return doc, nil
+}
+
+// handleImportConfig restores a configuration document, JSON or YAML by content type,
+// in one transaction. Existing entries are matched by name and handled by the
+// ?conflict= strategy; ?dry_run=true reports the outcome without keeping it.
+func (s *Server) handleImportConfig(c *gin.Context) {
+	strategy := c.DefaultQuery("conflict", conflictFail)
+	if strategy != conflictFail && strategy != conflictSkip && strategy != conflictOverwrite {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "conflict must be fail, skip or overwrite"})
+		return
+	}
+
+	doc, err := readConfigDocument(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateConfigDocument(doc); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	result := &models.ConfigImportResult{
+		DryRun:    c.Query("dry_run") == "true",
+		Strategy:  strategy,
+		Created:   map[string]int{},
+		Updated:   map[string]int{},
+		Skipped:   map[string]int{},
+		Conflicts: []string{},
+	}
+	conflicted, rolledBack := false, false
+	err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+		imp := &configImporter{
+			store:        tx,
+			strategy:     strategy,
+			result:       result,
+			channels:     make(map[int64]int64),
+			channelNames: make(map[int64]string),
+			properties:   make(map[int64]int64),
+			devices:      make(map[int64]int64),
+		}
+		if err := imp.run(ctx, doc); err != nil {
+			return err
+		}
+		// Roll back dry runs and refused imports; the result already describes them
+		conflicted = strategy == conflictFail && len(result.Conflicts) > 0
+		if result.DryRun || conflicted {
+			rolledBack = true
+			return fmt.Errorf("import rolled back")
+		}
+		return nil
+	})
+
+	switch {
+	case err != nil && !rolledBack:
+		result.Error = err.Error() + "; no changes were applied"
+		c.JSON(http.StatusInternalServerError, result)
+	case conflicted:
+		result.Error = fmt.Sprintf("%d entries already exist; no changes were applied", len(result.Conflicts))
+		c.JSON(http.StatusConflict, result)
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		result.Applied = true
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// readConfigDocument decodes the request body as YAML when its content type says so
+// and as JSON otherwise
+func readConfigDocument(c *gin.Context) (*models.ConfigDocument, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxConfigImportSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	if strings.Contains(c.ContentType(), "yaml") {
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+
+	var doc models.ConfigDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if doc.Version != configDocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %d (expected %d)", doc.Version, configDocumentVersion)
+	}
+	return &doc, nil
+}
+
+// jsonToYAML re-encodes a JSON document as block-style YAML, keeping its key order
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var clearStyle func(n *yaml.Node)
+	clearStyle = func(n *yaml.Node) {
+		n.Style = 0
+		for _, child := range n.Content {
+			clearStyle(child)
+		}
+	}
+	clearStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// validateConfigDocument checks a document before anything is imported, normalizing
+// entries the same way their own endpoints do. References between entries must stay
+// within the document.
+func validateConfigDocument(doc *models.ConfigDocument) error {
+	if doc.Settings != nil {
+		if err := validateSettings(doc.Settings); err != nil {
+			return fmt.Errorf("settings: %w", err)
+		}
+	}
+
+	channels := make(map[int64]bool)
+	channelNames := make(map[string]bool)
+	for _, nc := range doc.NotificationChannels {
+		if nc.Name == "" {
+			return fmt.Errorf("notification channel %d: name is required", nc.ID)
+		}
+		if channels[nc.ID] || channelNames[nc.Name] {
+			return fmt.Errorf("notification channel %q appears more than once", nc.Name)
+		}
+		channels[nc.ID] = true
+		channelNames[nc.Name] = true
+	}
+
+	properties := make(map[int64]bool)
+	propertyNames := make(map[string]bool)
+	devices := make(map[int64]bool)
+	for i := range doc.Properties {
+		p := &doc.Properties[i]
+		if p.Name == "" {
+			return fmt.Errorf("property %d: name is required", p.ID)
+		}
+		if properties[p.ID] || propertyNames[p.Name] {
+			return fmt.Errorf("property %q appears more than once", p.Name)
+		}
+		properties[p.ID] = true
+		propertyNames[p.Name] = true
+
+		propertyDevices := make(map[int64]bool)
+		for j := range p.Devices {
+			d := &p.Devices[j]
+			if d.Name == "" || d.Hostname == "" {
+				return fmt.Errorf("property %q: device %d needs a name and hostname", p.Name, d.ID)
+			}
+			if devices[d.ID] {
+				return fmt.Errorf("property %q: device %d appears more than once", p.Name, d.ID)
+			}
+			devices[d.ID] = true
+			propertyDevices[d.ID] = true
+			if err := validateDeviceCheck(d); err != nil {
+				return fmt.Errorf("property %q: device %q: %w", p.Name, d.Name, err)
+			}
+			if err := validateDeviceAsset(d); err != nil {
+				return fmt.Errorf("property %q: device %q: %w", p.Name, d.Name, err)
+			}
+			if d.Tags == nil {
+				d.Tags = []string{}
+			}
+		}
+		for _, contact := range p.Contacts {
+			if contact.Name == "" {
+				return fmt.Errorf("property %q: contact %d: name is required", p.Name, contact.ID)
+			}
+			if contact.EscalationOrder < 0 {
+				return fmt.Errorf("property %q: contact %q: escalation_order must not be negative", p.Name, contact.Name)
+			}
+		}
+		for j := range p.Circuits {
+			circuit := &p.Circuits[j]
+			if err := normalizeCircuit(circuit); err != nil {
+				return fmt.Errorf("property %q: circuit %d: %w", p.Name, circuit.ID, err)
+			}
+			if circuit.DeviceID != nil && !propertyDevices[*circuit.DeviceID] {
+				return fmt.Errorf("property %q: circuit %q: device %d is not one of the property's devices", p.Name, circuitLabel(circuit), *circuit.DeviceID)
+			}
+		}
+		for j := range p.Notifications {
+			pn := &p.Notifications[j]
+			if !channels[pn.NotificationChannelID] {
+				return fmt.Errorf("property %q: notification channel %d is not in the document", p.Name, pn.NotificationChannelID)
+			}
+			if err := notifier.ValidateActiveHours(pn.ActiveFrom, pn.ActiveTo, pn.Timezone); err != nil {
+				return fmt.Errorf("property %q: %w", p.Name, err)
+			}
+			if pn.Timezone == "" {
+				pn.Timezone = "UTC"
+			}
+		}
+	}
+
+	// Parents are checked once every device is known, since they may be listed later
+	for _, p := range doc.Properties {
+		for _, d := range p.Devices {
+			if d.ParentDeviceID != nil && !devices[*d.ParentDeviceID] {
+				return fmt.Errorf("property %q: device %q: parent device %d is not in the document", p.Name, d.Name, *d.ParentDeviceID)
+			}
+		}
+	}
+
+	ruleNames := make(map[string]bool)
+	for i := range doc.NotificationRules {
+		rule := &doc.NotificationRules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("notification rule %d: name is required", rule.ID)
+		}
+		if ruleNames[rule.Name] {
+			return fmt.Errorf("notification rule %q appears more than once", rule.Name)
+		}
+		ruleNames[rule.Name] = true
+		switch rule.Severity {
+		case "", notifier.SeverityCritical, notifier.SeverityWarning:
+		default:
+			return fmt.Errorf("notification rule %q: severity must be critical, warning or empty", rule.Name)
+		}
+		if !channels[rule.NotificationChannelID] {
+			return fmt.Errorf("notification rule %q: notification channel %d is not in the document", rule.Name, rule.NotificationChannelID)
+		}
+		if rule.PropertyID != nil && !properties[*rule.PropertyID] {
+			return fmt.Errorf("notification rule %q: property %d is not in the document", rule.Name, *rule.PropertyID)
+		}
+		if rule.Tags == nil {
+			rule.Tags = []string{}
+		}
+		if rule.DeviceTypes == nil {
+			rule.DeviceTypes = []string{}
+		}
+	}
+	return nil
+}
+
+// configImporter writes a validated document through a transaction's store, mapping
+// the document's IDs to the stored ones as entries are matched or created
+type configImporter struct {
+	store        storage.Store
+	strategy     string
+	result       *models.ConfigImportResult
+	channels     map[int64]int64
+	channelNames map[int64]string // stored ID to name
+	properties   map[int64]int64
+	devices      map[int64]int64
+}
+
+// pendingParent is a device whose parent is set once every device has an ID
+type pendingParent struct {
+	device   *models.Device
+	parentID int64 // document ID
+}
+
+func (imp *configImporter) run(ctx context.Context, doc *models.ConfigDocument) error {
+	if doc.Settings != nil {
+		if err := imp.importSettings(ctx, doc.Settings); err != nil {
+			return err
+		}
+	}
+	if err := imp.importChannels(ctx, doc.NotificationChannels); err != nil {
+		return err
+	}
+
+	properties, err := imp.store.ListProperties(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load properties: %w", err)
+	}
+	byName := make(map[string]*models.Property)
+	for i := range properties {
+		byName[properties[i].Name] = &properties[i]
+	}
+
+	var parents []pendingParent
+	for _, p := range doc.Properties {
+		pending, err := imp.importProperty(ctx, p, byName[p.Name])
+		if err != nil {
+			return err
+		}
+		parents = append(parents, pending...)
+	}
+	for _, pp := range parents {
+		parentID := imp.devices[pp.parentID]
+		pp.device.ParentDeviceID = &parentID
+		if err := imp.store.UpdateDevice(ctx, pp.device); err != nil {
+			return fmt.Errorf("failed to set parent of device %q: %w", pp.device.Name, err)
+		}
+	}
+
+	return imp.importRules(ctx, doc.NotificationRules)
+}
+
+// conflict records an entry that already exists and reports whether to overwrite it
+func (imp *configImporter) conflict(section, label string) bool {
+	imp.result.Conflicts = append(imp.result.Conflicts, section+": "+label)
+	if imp.strategy == conflictOverwrite {
+		imp.result.Updated[section]++
+		return true
+	}
+	imp.result.Skipped[section]++
+	return false
+}
+
+// importSettings replaces the settings unless existing entries are being kept
+func (imp *configImporter) importSettings(ctx context.Context, settings *models.Settings) error {
+	if imp.strategy == conflictSkip {
+		imp.result.Skipped["settings"]++
+		return nil
+	}
+	current, err := imp.store.GetSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+	updated := *settings
+	updated.ID = current.ID
+	keepUnsetSettings(&updated, current)
+	if err := imp.store.UpdateSettings(ctx, &updated); err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+	imp.result.Updated["settings"]++
+	return nil
+}
+
+func (imp *configImporter) importChannels(ctx context.Context, channels []models.NotificationChannel) error {
+	existing, err := imp.store.ListNotificationChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load notification channels: %w", err)
+	}
+	byName := make(map[string]models.NotificationChannel)
+	for _, nc := range existing {
+		byName[nc.Name] = nc
+	}
+
+	for _, nc := range channels {
+		docID := nc.ID
+		if current, ok := byName[nc.Name]; ok {
+			imp.channels[docID] = current.ID
+			imp.channelNames[current.ID] = nc.Name
+			if !imp.conflict("notification_channels", nc.Name) {
+				continue
+			}
+			// Credentials left out of an export keep their stored values
+			if nc.Type == current.Type {
+				if nc.Config, err = notifier.MergeChannelSecrets(nc.Type, nc.Config, current.Config); err != nil {
+					return fmt.Errorf("notification channel %q: %w", nc.Name, err)
+				}
+			}
+			nc.ID = current.ID
+			if err := imp.store.UpdateNotificationChannel(ctx, &nc); err != nil {
+				return fmt.Errorf("failed to update notification channel %q: %w", nc.Name, err)
+			}
+			continue
+		}
+		if err := imp.store.CreateNotificationChannel(ctx, &nc); err != nil {
+			return fmt.Errorf("failed to create notification channel %q: %w", nc.Name, err)
+		}
+		imp.channels[docID] = nc.ID
+		imp.channelNames[nc.ID] = nc.Name
+		imp.result.Created["notification_channels"]++
+	}
+	return nil
+}
+
+// importProperty imports a property and what belongs to it over current, the stored
+// property of the same name if any. It returns the devices whose parents still need
+// to be set.
+func (imp *configImporter) importProperty(ctx context.Context, entry models.ConfigProperty, current *models.Property) ([]pendingParent, error) {
+	p := entry.Property
+	created := current == nil
+	// Regions and groups aren't part of the document, so a property keeps the group it
+	// has here and a new one starts ungrouped
+	p.GroupID = nil
+	if !created {
+		p.GroupID = current.GroupID
+	}
+	// Subnets come from this install's scheme, so a new property gets its own and an
+	// existing one keeps what it has
+	p.Subnet = ""
+	if created {
+		if err := imp.store.CreateProperty(ctx, &p); err != nil {
+			return nil, fmt.Errorf("failed to create property %q: %w", p.Name, err)
+		}
+		// Creating a property only stores its details; pfSense access is an update
+		if p.PfSenseHost != "" || p.PfSenseUsername != "" || p.PfSensePassword != "" {
+			if err := imp.store.UpdateProperty(ctx, &p); err != nil {
+				return nil, fmt.Errorf("failed to update property %q: %w", p.Name, err)
+			}
+		}
+		imp.result.Created["properties"]++
+	} else {
+		p.ID = current.ID
+		if imp.conflict("properties", p.Name) {
+			if err := imp.store.UpdateProperty(ctx, &p); err != nil {
+				return nil, fmt.Errorf("failed to update property %q: %w", p.Name, err)
+			}
+		}
+	}
+	imp.properties[entry.ID] = p.ID
+
+	// A new property's own devices, such as its auto-created router, are replaced by the
+	// document's matching devices rather than treated as conflicts
+	pending, err := imp.importDevices(ctx, p, entry.Devices, created)
+	if err != nil {
+		return nil, err
+	}
+	if err := imp.importContacts(ctx, p, entry.Contacts); err != nil {
+		return nil, err
+	}
+	if err := imp.importCircuits(ctx, p, entry.Circuits); err != nil {
+		return nil, err
+	}
+	if err := imp.importPropertyNotifications(ctx, p, entry.Notifications); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (imp *configImporter) importDevices(ctx context.Context, p models.Property, devices []models.Device, created bool) ([]pendingParent, error) {
+	existing, err := imp.store.ListDevicesForProperty(ctx, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load devices for property %q: %w", p.Name, err)
+	}
+	byHostname := make(map[string]*models.Device)
+	byName := make(map[string]*models.Device)
+	for i := range existing {
+		d := &existing[i]
+		byHostname[strings.ToLower(d.Hostname)] = d
+		byName[strings.ToLower(d.Name)] = d
+	}
+
+	var pending []pendingParent
+	for _, d := range devices {
+		device := d
+		device.PropertyID = p.ID
+		device.ParentDeviceID = nil
+		device.ProbeID = nil // probes aren't exported; their devices go back to the worker
+
+		current := byHostname[strings.ToLower(d.Hostname)]
+		if current == nil {
+			current = byName[strings.ToLower(d.Name)]
+		}
+		switch {
+		case current == nil:
+			if err := imp.store.CreateDevice(ctx, &device); err != nil {
+				return nil, fmt.Errorf("failed to create device %q of property %q: %w", d.Name, p.Name, err)
+			}
+			imp.result.Created["devices"]++
+		case created || imp.conflict("devices", p.Name+"/"+d.Name):
+			device.ID = current.ID
+			device.ProbeID = current.ProbeID
+			if err := imp.store.UpdateDevice(ctx, &device); err != nil {
+				return nil, fmt.Errorf("failed to update device %q of property %q: %w", d.Name, p.Name, err)
+			}
+			if created {
+				imp.result.Created["devices"]++
+			}
+		default:
+			imp.devices[d.ID] = current.ID
+			continue
+		}
+		imp.devices[d.ID] = device.ID
+		if d.ParentDeviceID != nil {
+			pending = append(pending, pendingParent{device: &device, parentID: *d.ParentDeviceID})
+		}
+	}
+	return pending, nil
+}
+
+func (imp *configImporter) importContacts(ctx context.Context, p models.Property, contacts []models.Contact) error {
+	existing, err := imp.store.ListContactsForProperty(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load contacts for property %q: %w", p.Name, err)
+	}
+	byName := make(map[string]int64)
+	for _, contact := range existing {
+		byName[contact.Name] = contact.ID
+	}
+
+	for _, contact := range contacts {
+		contact.PropertyID = p.ID
+		if id, ok := byName[contact.Name]; ok {
+			if !imp.conflict("contacts", p.Name+"/"+contact.Name) {
+				continue
+			}
+			contact.ID = id
+			if err := imp.store.UpdateContact(ctx, &contact); err != nil {
+				return fmt.Errorf("failed to update contact %q of property %q: %w", contact.Name, p.Name, err)
+			}
+			continue
+		}
+		if err := imp.store.CreateContact(ctx, &contact); err != nil {
+			return fmt.Errorf("failed to create contact %q of property %q: %w", contact.Name, p.Name, err)
+		}
+		imp.result.Created["contacts"]++
+	}
+	return nil
+}
+
+// importCircuits matches circuits by provider and circuit ID
+func (imp *configImporter) importCircuits(ctx context.Context, p models.Property, circuits []models.Circuit) error {
+	existing, err := imp.store.ListCircuitsForProperty(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load circuits for property %q: %w", p.Name, err)
+	}
+	byLabel := make(map[string]int64)
+	for _, circuit := range existing {
+		byLabel[circuitLabel(&circuit)] = circuit.ID
+	}
+
+	for _, circuit := range circuits {
+		label := circuitLabel(&circuit)
+		circuit.PropertyID = p.ID
+		if circuit.DeviceID != nil {
+			deviceID := imp.devices[*circuit.DeviceID]
+			circuit.DeviceID = &deviceID
+		}
+		if id, ok := byLabel[label]; ok {
+			if !imp.conflict("circuits", p.Name+"/"+label) {
+				continue
+			}
+			circuit.ID = id
+			if err := imp.store.UpdateCircuit(ctx, &circuit); err != nil {
+				return fmt.Errorf("failed to update circuit %q of property %q: %w", label, p.Name, err)
+			}
+			continue
+		}
+		if err := imp.store.CreateCircuit(ctx, &circuit); err != nil {
+			return fmt.Errorf("failed to create circuit %q of property %q: %w", label, p.Name, err)
+		}
+		imp.result.Created["circuits"]++
+	}
+	return nil
+}
+
+// circuitLabel names a circuit by provider and circuit ID
+func circuitLabel(circuit *models.Circuit) string {
+	if circuit.CircuitID == "" {
+		return circuit.Provider
+	}
+	return circuit.Provider + " " + circuit.CircuitID
+}
+
+func (imp *configImporter) importPropertyNotifications(ctx context.Context, p models.Property, notifications []models.PropertyNotification) error {
+	existing, err := imp.store.ListPropertyNotifications(ctx, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load notifications for property %q: %w", p.Name, err)
+	}
+	byChannel := make(map[int64]int64)
+	for _, pn := range existing {
+		byChannel[pn.NotificationChannelID] = pn.ID
+	}
+
+	for _, pn := range notifications {
+		pn.PropertyID = p.ID
+		pn.NotificationChannelID = imp.channels[pn.NotificationChannelID]
+		if id, ok := byChannel[pn.NotificationChannelID]; ok {
+			if !imp.conflict("property_notifications", p.Name+"/"+imp.channelNames[pn.NotificationChannelID]) {
+				continue
+			}
+			pn.ID = id
+			if err := imp.store.UpdatePropertyNotification(ctx, &pn); err != nil {
+				return fmt.Errorf("failed to update a notification of property %q: %w", p.Name, err)
+			}
+			continue
+		}
+		if err := imp.store.CreatePropertyNotification(ctx, &pn); err != nil {
+			return fmt.Errorf("failed to create a notification of property %q: %w", p.Name, err)
+		}
+		imp.result.Created["property_notifications"]++
+	}
+	return nil
+}
+
+func (imp *configImporter) importRules(ctx context.Context, rules []models.NotificationRule) error {
+	existing, err := imp.store.ListNotificationRules(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load notification rules: %w", err)
+	}
+	byName := make(map[string]int64)
+	for _, rule := range existing {
+		byName[rule.Name] = rule.ID
+	}
+
+	for _, rule := range rules {
+		rule.NotificationChannelID = imp.channels[rule.NotificationChannelID]
+		// Documents exported before rules could match property tags leave them out
+		if rule.PropertyTags == nil {
+			rule.PropertyTags = []string{}
+		}
+		if rule.PropertyID != nil {
+			propertyID := imp.properties[*rule.PropertyID]
+			rule.PropertyID = &propertyID
+		}
+		if id, ok := byName[rule.Name]; ok {
+			if !imp.conflict("notification_rules", rule.Name) {
+				continue
+			}
+			rule.ID = id
+			if err := imp.store.UpdateNotificationRule(ctx, &rule); err != nil {
+				return fmt.Errorf("failed to update notification rule %q: %w", rule.Name, err)
+			}
+			continue
+		}
+		if err := imp.store.CreateNotificationRule(ctx, &rule); err != nil {
+			return fmt.Errorf("failed to create notification rule %q: %w", rule.Name, err)
+		}
+		imp.result.Created["notification_rules"]++
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const (
+	// defaultWarrantyDays is how far ahead the warranty report looks without ?days=
+	defaultWarrantyDays = 90
+	maxWarrantyDays     = 3650
+)
+
+// handleGetWarrantyReport lists the devices whose warranty lapses in the next ?days=
+// days (default 90), soonest first, optionally at one ?property_id=. ?expired=true also
+// lists devices whose warranty has already lapsed.
+func (s *Server) handleGetWarrantyReport(c *gin.Context) {
+	days := defaultWarrantyDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxWarrantyDays {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("days must be between 1 and %d", maxWarrantyDays)})
+			return
+		}
+		days = n
+	}
+
+	var propertyID int64
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		propertyID = id
+	}
+
+	expired, ok := boolQuery(c, "expired")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	today := assetDate(time.Now())
+	from := today
+	if expired != nil && *expired {
+		from = time.Time{}
+	}
+	devices, err := s.postgres.ListDevicesWarrantyExpiring(ctx, from, today.AddDate(0, 0, days+1), propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	names := make(map[int64]string)
+	report := make([]models.DeviceWarranty, len(devices))
+	for i, d := range devices {
+		name, ok := names[d.PropertyID]
+		if !ok {
+			if p, err := s.postgres.GetProperty(ctx, d.PropertyID); err == nil {
+				name = p.Name
+			}
+			names[d.PropertyID] = name
+		}
+		report[i] = models.DeviceWarranty{
+			Device:       d,
+			PropertyName: name,
+			DaysLeft:     int(assetDate(*d.WarrantyExpires).Sub(today).Hours() / 24),
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// validateDeviceAsset trims a device's asset details and keeps its dates to whole days
+func validateDeviceAsset(device *models.Device) error {
+	device.SerialNumber = strings.TrimSpace(device.SerialNumber)
+	device.Manufacturer = strings.TrimSpace(device.Manufacturer)
+	device.Model = strings.TrimSpace(device.Model)
+	device.FirmwareVersion = strings.TrimSpace(device.FirmwareVersion)
+	if device.PurchaseDate != nil {
+		date := assetDate(*device.PurchaseDate)
+		device.PurchaseDate = &date
+	}
+	if device.WarrantyExpires != nil {
+		date := assetDate(*device.WarrantyExpires)
+		device.WarrantyExpires = &date
+	}
+	if device.PurchaseDate != nil && device.WarrantyExpires != nil && device.WarrantyExpires.Before(*device.PurchaseDate) {
+		return fmt.Errorf("warranty_expires must not be before purchase_date")
+	}
+	return nil
+}
+
+// assetDate is the UTC day a time falls on
+func assetDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
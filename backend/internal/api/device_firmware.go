@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// firmwareNumber matches the numeric parts of a firmware version such as 6.6.55.15189
+// or 2.7.2-RELEASE
+var firmwareNumber = regexp.MustCompile(`\d+`)
+
+// handleGetFirmwareReport groups the devices with a known firmware version by model and
+// version, flagging versions below the model's minimum in settings, optionally at one
+// ?property_id=. ?below_minimum=true lists only the flagged groups.
+func (s *Server) handleGetFirmwareReport(c *gin.Context) {
+	var propertyID int64
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		propertyID = id
+	}
+
+	belowOnly, ok := boolQuery(c, "below_minimum")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	settings, err := s.postgres.GetSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	minimums := make(map[string]string, len(settings.FirmwareMinimums))
+	for model, version := range settings.FirmwareMinimums {
+		minimums[strings.ToLower(model)] = version
+	}
+
+	devices, err := s.postgres.ListDevicesWithFirmware(ctx, propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Devices come sorted by manufacturer, model and version, so each group is a run
+	names := make(map[int64]string)
+	report := make([]models.FirmwareGroup, 0)
+	for _, d := range devices {
+		if n := len(report); n == 0 || report[n-1].Manufacturer != d.Manufacturer ||
+			report[n-1].Model != d.Model || report[n-1].Version != d.FirmwareVersion {
+			group := models.FirmwareGroup{
+				Manufacturer:   d.Manufacturer,
+				Model:          d.Model,
+				Version:        d.FirmwareVersion,
+				MinimumVersion: minimums[strings.ToLower(d.Model)],
+				Devices:        []models.FirmwareDevice{},
+			}
+			group.BelowMinimum = group.MinimumVersion != "" && compareFirmware(group.Version, group.MinimumVersion) < 0
+			report = append(report, group)
+		}
+
+		name, ok := names[d.PropertyID]
+		if !ok {
+			if p, err := s.postgres.GetProperty(ctx, d.PropertyID); err == nil {
+				name = p.Name
+			}
+			names[d.PropertyID] = name
+		}
+		group := &report[len(report)-1]
+		group.Count++
+		group.Devices = append(group.Devices, models.FirmwareDevice{
+			ID:           d.ID,
+			Name:         d.Name,
+			PropertyID:   d.PropertyID,
+			PropertyName: name,
+		})
+	}
+
+	if belowOnly != nil && *belowOnly {
+		flagged := make([]models.FirmwareGroup, 0)
+		for _, group := range report {
+			if group.BelowMinimum {
+				flagged = append(flagged, group)
+			}
+		}
+		report = flagged
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// compareFirmware compares two firmware versions by their numeric parts, so 6.10.2 is
+// newer than 6.9 and 2.7.2-RELEASE equals 2.7.2. Missing parts count as zero.
+func compareFirmware(a, b string) int {
+	pa := firmwareNumber.FindAllString(a, -1)
+	pb := firmwareNumber.FindAllString(b, -1)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = strings.TrimLeft(pa[i], "0")
+		}
+		if i < len(pb) {
+			y = strings.TrimLeft(pb[i], "0")
+		}
+		// Digit strings without leading zeros order by length, then lexically
+		if len(x) != len(y) {
+			if len(x) < len(y) {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// normalizeFirmwareMinimums trims the models and versions of the firmware minimums and
+// checks each version has a number to compare
+func normalizeFirmwareMinimums(minimums map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string, len(minimums))
+	seen := make(map[string]bool, len(minimums))
+	for model, version := range minimums {
+		model = strings.TrimSpace(model)
+		version = strings.TrimSpace(version)
+		if model == "" {
+			return nil, fmt.Errorf("model is required")
+		}
+		if seen[strings.ToLower(model)] {
+			return nil, fmt.Errorf("model %q is listed twice", model)
+		}
+		seen[strings.ToLower(model)] = true
+		if !firmwareNumber.MatchString(version) {
+			return nil, fmt.Errorf("invalid minimum version %q for %s", version, model)
+		}
+		normalized[model] = version
+	}
+	return normalized, nil
+}
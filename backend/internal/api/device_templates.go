@@ -0,0 +1,202 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Device Templates
+func (s *Server) handleListDeviceTemplates(c *gin.Context) {
+	templates, err := s.postgres.ListDeviceTemplates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (s *Server) handleGetDeviceTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device template ID"})
+		return
+	}
+
+	template, err := s.postgres.GetDeviceTemplate(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (s *Server) handleCreateDeviceTemplate(c *gin.Context) {
+	var template models.DeviceTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateDeviceTemplate(&template); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreateDeviceTemplate(c.Request.Context(), &template); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (s *Server) handleUpdateDeviceTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device template ID"})
+		return
+	}
+
+	var template models.DeviceTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateDeviceTemplate(&template); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	template.ID = id
+	if err := s.postgres.UpdateDeviceTemplate(c.Request.Context(), &template); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (s *Server) handleDeleteDeviceTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device template ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteDeviceTemplate(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device template deleted"})
+}
+
+type applyTemplateRequest struct {
+	DeviceIDs []int64 `json:"device_ids" binding:"required"`
+}
+
+// handleApplyDeviceTemplate stamps a template onto a list of devices in one transaction
+func (s *Server) handleApplyDeviceTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device template ID"})
+		return
+	}
+
+	var req applyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if len(req.DeviceIDs) > maxBulkDeviceOperations {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("At most %d devices can be updated at once", maxBulkDeviceOperations)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	template, err := s.postgres.GetDeviceTemplate(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device template not found"})
+		return
+	}
+
+	devices := make([]models.Device, 0, len(req.DeviceIDs))
+	status := http.StatusInternalServerError
+	err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+		for _, deviceID := range req.DeviceIDs {
+			device, err := tx.GetDevice(ctx, deviceID)
+			if err != nil {
+				status = http.StatusNotFound
+				return fmt.Errorf("device %d not found", deviceID)
+			}
+			applyDeviceTemplate(device, template)
+			if err := tx.UpdateDevice(ctx, device); err != nil {
+				return fmt.Errorf("failed to update device %d: %w", deviceID, err)
+			}
+			devices = append(devices, *device)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, models.ErrorResponse{Error: err.Error() + "; no devices were changed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// validateDeviceTemplate checks a template, filling in the usual device check settings
+// for the ones left out
+func validateDeviceTemplate(template *models.DeviceTemplate) error {
+	if template.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if template.CheckInterval < 0 || template.Retries < 0 || template.Timeout < 0 {
+		return fmt.Errorf("check_interval, retries and timeout can't be negative")
+	}
+	if template.CheckInterval == 0 {
+		template.CheckInterval = 60
+	}
+	if template.Retries == 0 {
+		template.Retries = 3
+	}
+	if template.Timeout == 0 {
+		template.Timeout = 10000
+	}
+	if template.Tags == nil {
+		template.Tags = []string{}
+	}
+	return nil
+}
+
+// applyDeviceTemplate stamps a template's settings onto a device. The template's tags
+// are added to the device's own; a template without a device type leaves it alone.
+func applyDeviceTemplate(device *models.Device, template *models.DeviceTemplate) {
+	if template.DeviceType != "" {
+		device.DeviceType = template.DeviceType
+	}
+	device.IsCritical = template.IsCritical
+	device.CheckInterval = template.CheckInterval
+	device.Retries = template.Retries
+	device.Timeout = template.Timeout
+	device.Tags = mergeTags(device.Tags, template.Tags)
+}
+
+// mergeTags returns tags with extra appended, skipping ones it already has
+func mergeTags(tags, extra []string) []string {
+	merged := append([]string{}, tags...)
+	for _, tag := range extra {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
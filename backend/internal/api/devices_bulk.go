@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// maxBulkDeviceOperations caps how many operations one bulk request may carry
+const maxBulkDeviceOperations = 1000
+
+// handleBulkDevices applies a list of device creates, updates and deletes in one
+// transaction. Every operation is validated first; if any is invalid, or any fails
+// to apply, nothing is changed and the failing items carry their error.
+func (s *Server) handleBulkDevices(c *gin.Context) {
+	var ops []models.DeviceOperation
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if len(ops) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "At least one operation is required"})
+		return
+	}
+	if len(ops) > maxBulkDeviceOperations {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("At most %d operations are allowed per request", maxBulkDeviceOperations)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]models.DeviceOperationResult, len(ops))
+	invalid := 0
+	for i := range ops {
+		results[i] = models.DeviceOperationResult{Index: i, Op: ops[i].Op, ID: ops[i].ID}
+		if err := s.validateDeviceOperation(ctx, &ops[i]); err != nil {
+			results[i].Error = err.Error()
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		c.JSON(http.StatusBadRequest, models.BulkDeviceResponse{
+			Error:   fmt.Sprintf("%d of %d operations are invalid; no changes were applied", invalid, len(ops)),
+			Results: results,
+		})
+		return
+	}
+
+	failed := -1
+	err := s.postgres.InTx(ctx, func(tx storage.Store) error {
+		for i, op := range ops {
+			if err := applyDeviceOperation(ctx, tx, op); err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if failed < 0 {
+			c.JSON(http.StatusInternalServerError, models.BulkDeviceResponse{Error: err.Error(), Results: results})
+			return
+		}
+		results[failed].Error = err.Error()
+		c.JSON(http.StatusInternalServerError, models.BulkDeviceResponse{
+			Error:   fmt.Sprintf("Operation %d failed; no changes were applied", failed),
+			Results: results,
+		})
+		return
+	}
+
+	var deleted []int64
+	for i, op := range ops {
+		if op.Device != nil {
+			results[i].ID = op.Device.ID
+			results[i].Device = op.Device
+		} else {
+			deleted = append(deleted, op.ID)
+		}
+	}
+	s.clearDeviceStatuses(ctx, deleted)
+	c.JSON(http.StatusOK, models.BulkDeviceResponse{Applied: true, Results: results})
+}
+
+// validateDeviceOperation checks one bulk operation the same way the single-device
+// endpoints would, applying create defaults, the update ID and any template to its
+// device
+func (s *Server) validateDeviceOperation(ctx context.Context, op *models.DeviceOperation) error {
+	switch op.Op {
+	case "create":
+		if op.Device == nil {
+			return fmt.Errorf("create requires a device")
+		}
+		op.Device.ID = 0
+		applyNewDeviceDefaults(op.Device)
+	case "update":
+		if op.Device == nil {
+			return fmt.Errorf("update requires a device")
+		}
+		if op.ID <= 0 {
+			op.ID = op.Device.ID
+		}
+		if _, err := s.postgres.GetDevice(ctx, op.ID); err != nil {
+			return fmt.Errorf("device %d not found", op.ID)
+		}
+		op.Device.ID = op.ID
+	case "delete":
+		if op.Device != nil || op.TemplateID != 0 {
+			return fmt.Errorf("delete takes only an id")
+		}
+		if _, err := s.postgres.GetDevice(ctx, op.ID); err != nil {
+			return fmt.Errorf("device %d not found", op.ID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid op %q (must be create, update or delete)", op.Op)
+	}
+
+	if op.TemplateID != 0 {
+		template, err := s.postgres.GetDeviceTemplate(ctx, op.TemplateID)
+		if err != nil {
+			return fmt.Errorf("device template %d not found", op.TemplateID)
+		}
+		applyDeviceTemplate(op.Device, template)
+	}
+
+	if err := validateDeviceCheck(op.Device); err != nil {
+		return err
+	}
+	if err := validateDeviceAsset(op.Device); err != nil {
+		return err
+	}
+	if err := s.validateDeviceParent(ctx, op.Device); err != nil {
+		return err
+	}
+	if err := s.validateDeviceProbe(ctx, op.Device); err != nil {
+		return err
+	}
+	return s.validateDeviceConflicts(ctx, op.Device)
+}
+
+// applyDeviceOperation runs one validated bulk operation against the transaction's store
+func applyDeviceOperation(ctx context.Context, tx storage.Store, op models.DeviceOperation) error {
+	switch op.Op {
+	case "create":
+		return tx.CreateDevice(ctx, op.Device)
+	case "update":
+		return tx.UpdateDevice(ctx, op.Device)
+	default:
+		return tx.DeleteDevice(ctx, op.ID)
+	}
+}
@@ -0,0 +1,470 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Device CSV import and export. Files have a header row naming the columns below, in
+// any order; only name and hostname are required, and columns left out of an import
+// keep their current values. Tags are separated by semicolons and dates are
+// YYYY-MM-DD.
+
+var deviceCSVColumns = []string{"name", "hostname", "type", "critical", "tags",
+	"serial_number", "manufacturer", "model", "firmware_version", "purchase_date", "warranty_expires"}
+
+// deviceCSVAssetColumns are the asset detail columns, set as given and blank to clear
+var deviceCSVAssetColumns = []string{"serial_number", "manufacturer", "model", "firmware_version"}
+
+// deviceCSVDateFormat is how purchase_date and warranty_expires are written
+const deviceCSVDateFormat = "2006-01-02"
+
+// maxDeviceImportSize caps an uploaded device CSV
+const maxDeviceImportSize = 5 << 20
+
+// deviceCSVRow is one parsed data row of a device CSV. Columns lists the columns the
+// file has and filled the ones this row has a value in.
+type deviceCSVRow struct {
+	line     int
+	columns  map[string]int
+	filled   map[string]bool
+	name     string
+	hostname string
+	typ      string
+	critical bool
+	tags     []string
+	assets   map[string]string
+	purchase *time.Time
+	warranty *time.Time
+	err      error
+}
+
+func (s *Server) handleExportPropertyDevices(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	if _, err := s.postgres.GetProperty(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="property-%d-devices.csv"`, id))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(deviceCSVColumns)
+	for _, d := range devices {
+		w.Write([]string{d.Name, d.Hostname, d.DeviceType, strconv.FormatBool(d.IsCritical), strings.Join(d.Tags, ";"),
+			d.SerialNumber, d.Manufacturer, d.Model, d.FirmwareVersion, csvDate(d.PurchaseDate), csvDate(d.WarrantyExpires)})
+	}
+	w.Flush()
+}
+
+// handleImportPropertyDevices creates and updates a property's devices from a CSV, sent
+// as the request body or as the "file" field of a multipart form. Rows match existing
+// devices by hostname, then name. Nothing is applied if any row is invalid, and
+// ?dry_run=true only reports what would change.
+func (s *Server) handleImportPropertyDevices(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.postgres.GetProperty(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	body, err := deviceImportBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	defer body.Close()
+
+	rows, err := parseDeviceCSV(http.MaxBytesReader(c.Writer, body, maxDeviceImportSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if len(rows) > maxBulkDeviceOperations {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("At most %d devices can be imported at once", maxBulkDeviceOperations)})
+		return
+	}
+
+	existing, err := s.postgres.ListDevicesForProperty(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var template *models.DeviceTemplate
+	if v := c.Query("template_id"); v != "" {
+		templateID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device template ID"})
+			return
+		}
+		if template, err = s.postgres.GetDeviceTemplate(ctx, templateID); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device template not found"})
+			return
+		}
+	}
+
+	result, ops := planDeviceImport(id, rows, existing, template)
+	result.DryRun = c.Query("dry_run") == "true"
+
+	invalid := 0
+	for _, row := range result.Rows {
+		if row.Error != "" {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		result.Error = fmt.Sprintf("%d of %d rows are invalid; no changes were applied", invalid, len(result.Rows))
+		c.JSON(http.StatusBadRequest, result)
+		return
+	}
+	if result.DryRun || len(ops) == 0 {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+		for _, op := range ops {
+			if err := applyDeviceOperation(ctx, tx, op.DeviceOperation); err != nil {
+				return fmt.Errorf("line %d: %w", result.Rows[op.row].Line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		result.Error = err.Error() + "; no changes were applied"
+		c.JSON(http.StatusInternalServerError, result)
+		return
+	}
+
+	for _, op := range ops {
+		result.Rows[op.row].DeviceID = op.Device.ID
+	}
+	result.Applied = true
+	c.JSON(http.StatusOK, result)
+}
+
+// deviceImportBody returns the uploaded CSV from a multipart form or the raw body
+func deviceImportBody(c *gin.Context) (io.ReadCloser, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("no file provided")
+	}
+	if file.Size > maxDeviceImportSize {
+		return nil, fmt.Errorf("file too large (max 5MB)")
+	}
+	return file.Open()
+}
+
+// parseDeviceCSV reads a device CSV. Row problems are kept on the row so every bad
+// line can be reported at once; only an unreadable file or header is an error.
+func parseDeviceCSV(r io.Reader) ([]deviceCSVRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("CSV is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		// Spreadsheet exports often start with a UTF-8 byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(deviceCSVColumns, name) {
+			return nil, fmt.Errorf("unknown column %q (columns are %s)", name, strings.Join(deviceCSVColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "hostname"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+
+	var rows []deviceCSVRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := deviceCSVRow{
+			line:     line,
+			columns:  columns,
+			filled:   make(map[string]bool),
+			name:     field("name"),
+			hostname: field("hostname"),
+			typ:      field("type"),
+			tags:     []string{},
+			assets:   make(map[string]string),
+		}
+		for _, name := range deviceCSVAssetColumns {
+			row.assets[name] = field(name)
+		}
+		for name := range columns {
+			row.filled[name] = field(name) != ""
+		}
+		for _, tag := range strings.Split(field("tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(row.tags, tag) {
+				row.tags = append(row.tags, tag)
+			}
+		}
+		row.critical, row.err = parseCSVBool(field("critical"))
+		if row.err == nil {
+			row.purchase, row.err = parseCSVDate("purchase_date", field("purchase_date"))
+		}
+		if row.err == nil {
+			row.warranty, row.err = parseCSVDate("warranty_expires", field("warranty_expires"))
+		}
+		if row.err == nil {
+			switch {
+			case row.name == "":
+				row.err = fmt.Errorf("name is required")
+			case row.hostname == "":
+				row.err = fmt.Errorf("hostname is required")
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV has no device rows")
+	}
+	return rows, nil
+}
+
+// parseCSVBool reads the critical column; spreadsheets write booleans several ways
+func parseCSVBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "", "false", "no", "n", "0":
+		return false, nil
+	case "true", "yes", "y", "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("critical must be true or false, got %q", v)
+}
+
+// parseCSVDate reads a YYYY-MM-DD date column, nil when it is blank
+func parseCSVDate(column, v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(deviceCSVDateFormat, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a YYYY-MM-DD date, got %q", column, v)
+	}
+	return &t, nil
+}
+
+// csvDate writes an optional date column
+func csvDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(deviceCSVDateFormat)
+}
+
+// deviceImportOp is a create or update planned for a CSV row
+type deviceImportOp struct {
+	models.DeviceOperation
+	row int
+}
+
+// planDeviceImport matches rows to a property's devices by hostname, then name, and
+// returns the reported rows with the operations needed to apply them. With a template,
+// every row is stamped with it and the row's type, critical and tags only apply where
+// the row fills them in, its tags adding to the template's.
+func planDeviceImport(propertyID int64, rows []deviceCSVRow, existing []models.Device, template *models.DeviceTemplate) (*models.DeviceImportResult, []deviceImportOp) {
+	byHostname := make(map[string]*models.Device)
+	byName := make(map[string]*models.Device)
+	for i := range existing {
+		d := &existing[i]
+		byHostname[strings.ToLower(d.Hostname)] = d
+		byName[strings.ToLower(d.Name)] = d
+	}
+
+	result := &models.DeviceImportResult{Rows: make([]models.DeviceImportRow, len(rows))}
+	var ops []deviceImportOp
+	seen := make(map[string]int)
+	matched := make(map[int64]int)
+	for i, row := range rows {
+		out := &result.Rows[i]
+		*out = models.DeviceImportRow{Line: row.line, Name: row.name, Hostname: row.hostname}
+		if row.err != nil {
+			out.Error = row.err.Error()
+			continue
+		}
+
+		// Two rows for the same device would silently overwrite each other
+		key := strings.ToLower(row.hostname)
+		if line, ok := seen[key]; ok {
+			out.Error = fmt.Sprintf("hostname %s is already on line %d", row.hostname, line)
+			continue
+		}
+		seen[key] = row.line
+
+		current := byHostname[key]
+		if current == nil {
+			current = byName[strings.ToLower(row.name)]
+		}
+		if current != nil {
+			if line, ok := matched[current.ID]; ok {
+				out.Error = fmt.Sprintf("device %s is already imported by line %d", current.Name, line)
+				continue
+			}
+			matched[current.ID] = row.line
+		}
+
+		device := models.Device{PropertyID: propertyID, Tags: []string{}}
+		if current != nil {
+			device = *current
+		} else {
+			applyNewDeviceDefaults(&device)
+		}
+		device.Name = row.name
+		device.Hostname = row.hostname
+		set := func(column string) bool {
+			_, ok := row.columns[column]
+			return ok && (template == nil || row.filled[column])
+		}
+		if template != nil {
+			applyDeviceTemplate(&device, template)
+		}
+		if set("type") {
+			device.DeviceType = row.typ
+		}
+		if set("critical") {
+			device.IsCritical = row.critical
+		}
+		if set("tags") {
+			if template != nil {
+				device.Tags = mergeTags(device.Tags, row.tags)
+			} else {
+				device.Tags = row.tags
+			}
+		}
+		// Asset details describe the hardware, so a template doesn't stop the row's
+		// values, blank ones included, from applying
+		for _, column := range deviceCSVAssetColumns {
+			if _, ok := row.columns[column]; ok {
+				setDeviceAsset(&device, column, row.assets[column])
+			}
+		}
+		if _, ok := row.columns["purchase_date"]; ok {
+			device.PurchaseDate = row.purchase
+		}
+		if _, ok := row.columns["warranty_expires"]; ok {
+			device.WarrantyExpires = row.warranty
+		}
+		if err := validateDeviceCheck(&device); err != nil {
+			out.Error = err.Error()
+			continue
+		}
+		if err := validateDeviceAsset(&device); err != nil {
+			out.Error = err.Error()
+			continue
+		}
+
+		switch {
+		case current == nil:
+			out.Action = "create"
+			result.Created++
+			ops = append(ops, deviceImportOp{models.DeviceOperation{Op: "create", Device: &device}, i})
+		case deviceImportChanged(current, &device):
+			out.Action = "update"
+			out.DeviceID = current.ID
+			result.Updated++
+			ops = append(ops, deviceImportOp{models.DeviceOperation{Op: "update", ID: current.ID, Device: &device}, i})
+		default:
+			out.Action = "unchanged"
+			out.DeviceID = current.ID
+			result.Unchanged++
+		}
+	}
+	return result, ops
+}
+
+// deviceImportChanged reports whether an import changes any of the CSV's columns or
+// the check settings a template sets
+func deviceImportChanged(current, device *models.Device) bool {
+	return current.Name != device.Name ||
+		current.CheckInterval != device.CheckInterval ||
+		current.Retries != device.Retries ||
+		current.Timeout != device.Timeout ||
+		current.Hostname != device.Hostname ||
+		current.DeviceType != device.DeviceType ||
+		current.IsCritical != device.IsCritical ||
+		!slices.Equal(current.Tags, device.Tags) ||
+		current.SerialNumber != device.SerialNumber ||
+		current.Manufacturer != device.Manufacturer ||
+		current.Model != device.Model ||
+		current.FirmwareVersion != device.FirmwareVersion ||
+		csvDate(current.PurchaseDate) != csvDate(device.PurchaseDate) ||
+		csvDate(current.WarrantyExpires) != csvDate(device.WarrantyExpires)
+}
+
+// setDeviceAsset sets one of the asset detail columns on a device
+func setDeviceAsset(device *models.Device, column, value string) {
+	switch column {
+	case "serial_number":
+		device.SerialNumber = value
+	case "manufacturer":
+		device.Manufacturer = value
+	case "model":
+		device.Model = value
+	case "firmware_version":
+		device.FirmwareVersion = value
+	}
+}
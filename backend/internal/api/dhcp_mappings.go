@@ -0,0 +1,209 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/backup"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+)
+
+// DHCP static mappings pushed to pfSense
+
+var (
+	dhcpInterfacePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	dhcpHostnamePattern  = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+	hostnameInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// dhcpMappingRequest creates or updates a static mapping. With device_id set, empty
+// fields are filled from the device: its MAC, its address and a hostname from its name.
+type dhcpMappingRequest struct {
+	DeviceID    *int64 `json:"device_id"`
+	Interface   string `json:"interface"`
+	MACAddress  string `json:"mac_address"`
+	IPAddress   string `json:"ip_address"`
+	Hostname    string `json:"hostname"`
+	Description string `json:"description"`
+}
+
+// handlePushDHCPMapping writes a static mapping to the property's pfSense. The config is
+// backed up first, and every attempt is recorded in the mapping change history.
+func (s *Server) handlePushDHCPMapping(c *gin.Context) {
+	property, pfClient, ok := s.propertyPfSenseClient(c)
+	if !ok {
+		return
+	}
+
+	var req dhcpMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if req.DeviceID != nil {
+		device, err := s.postgres.GetDevice(c.Request.Context(), *req.DeviceID)
+		if err != nil || device.PropertyID != property.ID {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Device not found at this property"})
+			return
+		}
+		if req.MACAddress == "" {
+			req.MACAddress = device.MACAddress
+		}
+		if req.IPAddress == "" {
+			req.IPAddress = device.Hostname
+		}
+		if req.Hostname == "" {
+			req.Hostname = hostnameFromName(device.Name)
+		}
+	}
+
+	change, err := validateDHCPMappingRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	s.applyDHCPMapping(c, property, pfClient, req.DeviceID, change)
+}
+
+// handleDeleteDHCPMapping removes the static mapping for a MAC from a DHCP scope,
+// given by ?interface= (lan by default)
+func (s *Server) handleDeleteDHCPMapping(c *gin.Context) {
+	property, pfClient, ok := s.propertyPfSenseClient(c)
+	if !ok {
+		return
+	}
+
+	iface := c.DefaultQuery("interface", "lan")
+	if !dhcpInterfacePattern.MatchString(iface) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid interface"})
+		return
+	}
+	mac, err := net.ParseMAC(c.Param("mac"))
+	if err != nil || len(mac) != 6 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid MAC address"})
+		return
+	}
+
+	s.applyDHCPMapping(c, property, pfClient, nil, pfsense.StaticMappingChange{
+		Action:    pfsense.StaticMapDelete,
+		Interface: iface,
+		MAC:       mac.String(),
+	})
+}
+
+func (s *Server) handleListDHCPMappingChanges(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	changes, err := s.postgres.ListDHCPMappingChanges(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// applyDHCPMapping backs up the config, applies the change and records the outcome
+func (s *Server) applyDHCPMapping(c *gin.Context, property *models.Property, pfClient *pfsense.Client, deviceID *int64, change pfsense.StaticMappingChange) {
+	ctx := c.Request.Context()
+	username, _ := c.Get("username")
+	createdBy, _ := username.(string)
+
+	if _, _, err := backup.BackupProperty(ctx, s.postgres, s.blobs, property, backup.TriggerManual, createdBy); err != nil {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to back up config before change, nothing was pushed: %v", err),
+		})
+		return
+	}
+
+	change.Note = fmt.Sprintf("ETS NOC: %s static mapping %s on %s by %s", change.Action, change.MAC, change.Interface, createdBy)
+
+	record := &models.DHCPMappingChange{
+		PropertyID: property.ID,
+		DeviceID:   deviceID,
+		Action:     change.Action,
+		Interface:  change.Interface,
+		MACAddress: change.MAC,
+		IPAddress:  change.IPAddr,
+		Hostname:   change.Hostname,
+		CreatedBy:  createdBy,
+	}
+
+	previous, err := pfClient.ApplyStaticMapping(ctx, change)
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Success = true
+		if previous != nil {
+			record.PreviousIP = previous.IPAddr
+			record.PreviousHostname = previous.Hostname
+		}
+	}
+
+	if err := s.postgres.CreateDHCPMappingChange(ctx, record); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if !record.Success {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to push static mapping to pfSense: %s", record.Error),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+func validateDHCPMappingRequest(req *dhcpMappingRequest) (pfsense.StaticMappingChange, error) {
+	change := pfsense.StaticMappingChange{
+		Action:      pfsense.StaticMapSet,
+		Interface:   req.Interface,
+		Hostname:    req.Hostname,
+		Description: strings.TrimSpace(req.Description),
+	}
+	if change.Interface == "" {
+		change.Interface = "lan"
+	}
+	if !dhcpInterfacePattern.MatchString(change.Interface) {
+		return change, fmt.Errorf("invalid interface %q", req.Interface)
+	}
+
+	mac, err := net.ParseMAC(req.MACAddress)
+	if err != nil || len(mac) != 6 {
+		return change, fmt.Errorf("a valid MAC address is required")
+	}
+	change.MAC = mac.String()
+
+	ip := net.ParseIP(req.IPAddress)
+	if ip == nil || ip.To4() == nil {
+		return change, fmt.Errorf("a valid IPv4 address is required")
+	}
+	change.IPAddr = ip.To4().String()
+
+	if change.Hostname != "" && !dhcpHostnamePattern.MatchString(change.Hostname) {
+		return change, fmt.Errorf("hostname may only contain letters, digits and hyphens")
+	}
+	return change, nil
+}
+
+// hostnameFromName turns a device name into a DHCP hostname, e.g. "Lobby AP 1" -> "lobby-ap-1"
+func hostnameFromName(name string) string {
+	hostname := hostnameInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+	hostname = strings.Trim(hostname, "-")
+	if len(hostname) > 63 {
+		hostname = strings.TrimRight(hostname[:63], "-")
+	}
+	return hostname
+}
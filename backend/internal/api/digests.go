@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/digest"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Digest Subscriptions
+func (s *Server) handleListDigestSubscriptions(c *gin.Context) {
+	var subscriptions []models.DigestSubscription
+	var err error
+	if isAdmin(c) {
+		subscriptions, err = s.postgres.ListDigestSubscriptions(c.Request.Context())
+	} else {
+		userID, _ := c.Get("user_id")
+		subscriptions, err = s.postgres.ListDigestSubscriptionsForUser(c.Request.Context(), userID.(int64))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (s *Server) handleCreateDigestSubscription(c *gin.Context) {
+	sub := models.DigestSubscription{Enabled: true, SendHour: 8}
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Admins may subscribe other users; everyone else subscribes themselves
+	userID, _ := c.Get("user_id")
+	if sub.UserID == 0 || !isAdmin(c) {
+		sub.UserID = userID.(int64)
+	}
+
+	if err := s.validateDigestSubscription(c.Request.Context(), &sub); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreateDigestSubscription(c.Request.Context(), &sub); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+func (s *Server) handleUpdateDigestSubscription(c *gin.Context) {
+	existing, ok := s.ownedDigestSubscription(c)
+	if !ok {
+		return
+	}
+
+	var sub models.DigestSubscription
+	if err := c.ShouldBindJSON(&sub); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.validateDigestSubscription(c.Request.Context(), &sub); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	sub.ID = existing.ID
+	if err := s.postgres.UpdateDigestSubscription(c.Request.Context(), &sub); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+func (s *Server) handleDeleteDigestSubscription(c *gin.Context) {
+	existing, ok := s.ownedDigestSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.DeleteDigestSubscription(c.Request.Context(), existing.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Digest subscription deleted"})
+}
+
+// handlePreviewDigestSubscription builds the digest the subscription would receive for
+// the period ending now, without sending it
+func (s *Server) handlePreviewDigestSubscription(c *gin.Context) {
+	sub, ok := s.ownedDigestSubscription(c)
+	if !ok {
+		return
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -1)
+	if sub.Frequency == "weekly" {
+		start = end.AddDate(0, 0, -7)
+	}
+
+	d, err := digest.Build(c.Request.Context(), s.postgres, s.redis, sub.PropertyIDs, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// ownedDigestSubscription loads the subscription named by the :id parameter, writing
+// an error response unless it belongs to the current user or the user is an admin
+func (s *Server) ownedDigestSubscription(c *gin.Context) (*models.DigestSubscription, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid digest subscription ID"})
+		return nil, false
+	}
+
+	sub, err := s.postgres.GetDigestSubscription(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Digest subscription not found"})
+		return nil, false
+	}
+
+	userID, _ := c.Get("user_id")
+	if sub.UserID != userID.(int64) && !isAdmin(c) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Digest subscription not found"})
+		return nil, false
+	}
+	return sub, true
+}
+
+func (s *Server) validateDigestSubscription(ctx context.Context, sub *models.DigestSubscription) error {
+	if sub.Frequency != "daily" && sub.Frequency != "weekly" {
+		return fmt.Errorf("frequency must be daily or weekly")
+	}
+	if sub.SendHour < 0 || sub.SendHour > 23 {
+		return fmt.Errorf("send_hour must be between 0 and 23")
+	}
+	if sub.Timezone == "" {
+		sub.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(sub.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", sub.Timezone)
+	}
+	channel, err := s.postgres.GetNotificationChannel(ctx, sub.NotificationChannelID)
+	if err != nil {
+		return fmt.Errorf("notification channel %d not found", sub.NotificationChannelID)
+	}
+	if channel.Type != "email" {
+		return fmt.Errorf("digests must be sent through an email channel")
+	}
+	if sub.PropertyIDs == nil {
+		sub.PropertyIDs = []int64{}
+	}
+	return nil
+}
+
+func isAdmin(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == "admin"
+}
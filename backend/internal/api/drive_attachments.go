@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/drive"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// handleLinkDriveAttachment attaches a Google Drive file to a property by link. The
+// frontend's Drive picker sends the chosen file ID with the access token it signed in
+// with, which must come from our Google OAuth client; the file is looked up with it,
+// so users can only link files they can open.
+func (s *Server) handleLinkDriveAttachment(c *gin.Context) {
+	ctx := c.Request.Context()
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	var req models.DriveAttachmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if s.googleOAuth == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Google OAuth not configured"})
+		return
+	}
+	if _, err := s.postgres.GetProperty(ctx, propertyID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	err = drive.CheckToken(ctx, req.AccessToken, s.googleOAuth.ClientID)
+	switch {
+	case errors.Is(err, drive.ErrInvalidToken), errors.Is(err, drive.ErrForeignToken):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	file, err := drive.GetFile(ctx, req.AccessToken, req.FileID)
+	switch {
+	case errors.Is(err, drive.ErrNotFound), errors.Is(err, drive.ErrTrashed):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Drive file not found"})
+		return
+	case errors.Is(err, drive.ErrNoAccess):
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	attachment := &models.Attachment{
+		PropertyID:  propertyID,
+		Filename:    file.Name,
+		Description: req.Description,
+		StorageType: "google_drive",
+		StoragePath: file.Link,
+		FileSize:    file.Size,
+		MimeType:    file.MimeType,
+		Tags:        normalizeTags(req.Tags),
+		UploadedBy:  c.GetString("username"),
+	}
+	if err := s.postgres.CreateAttachment(ctx, attachment); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
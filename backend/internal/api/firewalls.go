@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+)
+
+// Firewalls (additional pfSense nodes, e.g. CARP HA pairs). Passwords are write-only.
+func (s *Server) handleListFirewallsForProperty(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	firewalls, err := s.postgres.ListFirewallsForProperty(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	for i := range firewalls {
+		firewalls[i].Password = ""
+	}
+	c.JSON(http.StatusOK, firewalls)
+}
+
+func (s *Server) handleCreateFirewall(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	if _, err := s.postgres.GetProperty(c.Request.Context(), propertyID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	var firewall models.Firewall
+	if err := c.ShouldBindJSON(&firewall); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateFirewall(&firewall); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	firewall.PropertyID = propertyID
+	if err := s.postgres.CreateFirewall(c.Request.Context(), &firewall); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	firewall.Password = ""
+	c.JSON(http.StatusCreated, firewall)
+}
+
+func (s *Server) handleGetFirewall(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid firewall ID"})
+		return
+	}
+
+	firewall, err := s.postgres.GetFirewall(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Firewall not found"})
+		return
+	}
+
+	firewall.Password = ""
+	c.JSON(http.StatusOK, firewall)
+}
+
+// handleUpdateFirewall saves a firewall; leaving password empty keeps the current one
+func (s *Server) handleUpdateFirewall(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid firewall ID"})
+		return
+	}
+
+	var firewall models.Firewall
+	if err := c.ShouldBindJSON(&firewall); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateFirewall(&firewall); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	firewall.ID = id
+	if err := s.postgres.UpdateFirewall(c.Request.Context(), &firewall); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	firewall.Password = ""
+	c.JSON(http.StatusOK, firewall)
+}
+
+func (s *Server) handleDeleteFirewall(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid firewall ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteFirewall(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Firewall deleted"})
+}
+
+// handleGetFirewallCARP reads the firewall's CARP VIPs live and evaluates them against
+// its expected state
+func (s *Server) handleGetFirewallCARP(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid firewall ID"})
+		return
+	}
+
+	firewall, err := s.postgres.GetFirewall(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Firewall not found"})
+		return
+	}
+
+	client := pfsense.NewClient(firewall.Host, firewall.Port, firewall.Username, firewall.Password)
+	vips, err := client.GetCARPStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to fetch CARP status from %s: %v", firewall.Name, err),
+		})
+		return
+	}
+
+	healthy, detail := pfsense.EvaluateCARP(vips, firewall.ExpectedCARPState)
+	c.JSON(http.StatusOK, gin.H{
+		"firewall_id":         firewall.ID,
+		"expected_carp_state": firewall.ExpectedCARPState,
+		"healthy":             healthy,
+		"detail":              detail,
+		"vips":                vips,
+	})
+}
+
+// validateFirewall normalizes and validates a firewall's connection settings
+func validateFirewall(firewall *models.Firewall) error {
+	if strings.TrimSpace(firewall.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(firewall.Host) == "" {
+		return fmt.Errorf("host is required")
+	}
+	if firewall.Port == 0 {
+		firewall.Port = 22
+	}
+	if firewall.Port < 1 || firewall.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	firewall.ExpectedCARPState = strings.ToLower(firewall.ExpectedCARPState)
+	switch firewall.ExpectedCARPState {
+	case "", "master", "backup":
+	default:
+		return fmt.Errorf("invalid expected_carp_state %q (must be master, backup or empty)", firewall.ExpectedCARPState)
+	}
+	return nil
+}
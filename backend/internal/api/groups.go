@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Regions
+func (s *Server) handleListRegions(c *gin.Context) {
+	regions, err := s.postgres.ListRegions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, regions)
+}
+
+func (s *Server) handleGetRegion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid region ID"})
+		return
+	}
+
+	region, err := s.postgres.GetRegion(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Region not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, region)
+}
+
+func (s *Server) handleCreateRegion(c *gin.Context) {
+	var region models.Region
+	if err := c.ShouldBindJSON(&region); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if region.Name == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name is required"})
+		return
+	}
+
+	if err := s.postgres.CreateRegion(c.Request.Context(), &region); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, region)
+}
+
+func (s *Server) handleUpdateRegion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid region ID"})
+		return
+	}
+
+	var region models.Region
+	if err := c.ShouldBindJSON(&region); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if region.Name == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "name is required"})
+		return
+	}
+
+	region.ID = id
+	if err := s.postgres.UpdateRegion(c.Request.Context(), &region); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, region)
+}
+
+func (s *Server) handleDeleteRegion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid region ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteRegion(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Region deleted"})
+}
+
+// Property Groups
+func (s *Server) handleListPropertyGroups(c *gin.Context) {
+	var regionID int64
+	if v := c.Query("region_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid region ID"})
+			return
+		}
+		regionID = id
+	}
+
+	groups, err := s.postgres.ListPropertyGroups(c.Request.Context(), regionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func (s *Server) handleGetPropertyGroup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	group, err := s.postgres.GetPropertyGroup(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property group not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (s *Server) handleCreatePropertyGroup(c *gin.Context) {
+	var group models.PropertyGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.validatePropertyGroup(ctx, &group); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreatePropertyGroup(ctx, &group); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func (s *Server) handleUpdatePropertyGroup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	var group models.PropertyGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := s.validatePropertyGroup(ctx, &group); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	group.ID = id
+	if err := s.postgres.UpdatePropertyGroup(ctx, &group); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (s *Server) handleDeletePropertyGroup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	if err := s.postgres.DeletePropertyGroup(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Property group deleted"})
+}
+
+func (s *Server) validatePropertyGroup(ctx context.Context, group *models.PropertyGroup) error {
+	if group.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := s.postgres.GetRegion(ctx, group.RegionID); err != nil {
+		return fmt.Errorf("region %d not found", group.RegionID)
+	}
+	return nil
+}
+
+// validatePropertyGroupID checks that the group a property is placed in exists
+func (s *Server) validatePropertyGroupID(ctx context.Context, property *models.Property) error {
+	if property.GroupID == nil {
+		return nil
+	}
+	if _, err := s.postgres.GetPropertyGroup(ctx, *property.GroupID); err != nil {
+		return fmt.Errorf("property group %d not found", *property.GroupID)
+	}
+	return nil
+}
+
+// propertyFilter reads the optional group_id, region_id and tag query parameters that
+// narrow the property listing and the dashboard
+func propertyFilter(c *gin.Context) (storage.PropertyFilter, bool) {
+	filter := storage.PropertyFilter{Tag: c.Query("tag")}
+	if v := c.Query("group_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+			return filter, false
+		}
+		filter.GroupID = id
+	}
+	if v := c.Query("region_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid region ID"})
+			return filter, false
+		}
+		filter.RegionID = id
+	}
+	return filter, true
+}
+
+// statusRank orders rollup statuses from best to worst
+var statusRank = map[string]int{"green": 0, "yellow": 1, "red": 2}
+
+// groupRollups rolls the dashboard's property statuses up per group, each group taking
+// its worst property's status. Every group in the filter's scope is listed, empty ones
+// as green, except that a tag filter leaves out groups with no tagged properties.
+// Properties outside any group are gathered under an "Ungrouped" entry with a zero
+// group ID.
+func (s *Server) groupRollups(ctx context.Context, filter storage.PropertyFilter, properties []models.PropertyWithStatus) ([]models.GroupStatus, error) {
+	regions, err := s.postgres.ListRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	regionNames := make(map[int64]string, len(regions))
+	for _, r := range regions {
+		regionNames[r.ID] = r.Name
+	}
+
+	groups, err := s.postgres.ListPropertyGroups(ctx, filter.RegionID)
+	if err != nil {
+		return nil, err
+	}
+	rollups := make([]models.GroupStatus, 0, len(groups)+1)
+	index := make(map[int64]int, len(groups))
+	for _, g := range groups {
+		if filter.GroupID != 0 && g.ID != filter.GroupID {
+			continue
+		}
+		index[g.ID] = len(rollups)
+		rollups = append(rollups, models.GroupStatus{
+			GroupID:    g.ID,
+			Name:       g.Name,
+			RegionID:   g.RegionID,
+			RegionName: regionNames[g.RegionID],
+			Status:     "green",
+		})
+	}
+
+	ungrouped := models.GroupStatus{Name: "Ungrouped", Status: "green"}
+	for _, p := range properties {
+		rollup := &ungrouped
+		if p.GroupID != nil {
+			i, ok := index[*p.GroupID]
+			if !ok {
+				continue
+			}
+			rollup = &rollups[i]
+		}
+		rollup.TotalProperties++
+		switch p.Status {
+		case "red":
+			rollup.RedCount++
+		case "yellow":
+			rollup.YellowCount++
+		default:
+			rollup.GreenCount++
+		}
+		if statusRank[p.Status] > statusRank[rollup.Status] {
+			rollup.Status = p.Status
+		}
+	}
+	if filter.Tag != "" {
+		rollups = slices.DeleteFunc(rollups, func(g models.GroupStatus) bool { return g.TotalProperties == 0 })
+	}
+	if ungrouped.TotalProperties > 0 {
+		rollups = append(rollups, ungrouped)
+	}
+	return rollups, nil
+}
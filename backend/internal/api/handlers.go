@@ -2,68 +2,228 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/etswifi/ets-noc/internal/gcs"
+	"github.com/etswifi/ets-noc/internal/geocode"
 	"github.com/etswifi/ets-noc/internal/models"
 	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/nut"
 	"github.com/etswifi/ets-noc/internal/pfsense"
 	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/webhook"
+	"golang.org/x/oauth2"
 )
 
 type Server struct {
-	postgres *storage.PostgresStore
-	redis    *storage.RedisStore
-	gcs      *gcs.Client
+	postgres storage.Store
+	redis    storage.StatusStore
+	// blobs keeps attachments and config backups; nil when no storage is configured
+	blobs    storage.BlobStore
+	notifier *notifier.Notifier
+	webhooks *webhook.Dispatcher
+	// workerStaleAfter is how old the newest worker heartbeat may be before /health
+	// and the dashboard report the workers as stale
+	workerStaleAfter time.Duration
+	// requestTimeout bounds each request's context; streams are exempt
+	requestTimeout time.Duration
+	// uploadTimeout replaces requestTimeout on the upload and import routes
+	uploadTimeout time.Duration
+	// googleOAuth is nil when Google sign-in isn't configured
+	googleOAuth *oauth2.Config
+	// geocoder locates property addresses; nil when geocoding isn't configured
+	geocoder   geocode.Geocoder
+	rateLimits RateLimits
+	// trustedProxies may set the client IP through X-Forwarded-For; with none, the
+	// client IP is always the connection's address
+	trustedProxies []string
+	// openAPISpec is the OpenAPI document for the routes, built by SetupRouter
+	openAPISpec []byte
+
+	// streamsClosed is closed on shutdown to end the live update streams
+	streamsClosed chan struct{}
+	closeStreams  sync.Once
 }
 
-func NewServer(postgres *storage.PostgresStore, redis *storage.RedisStore, gcsClient *gcs.Client) *Server {
-	return &Server{
+// ServerOption configures a Server built by NewServer
+type ServerOption func(*Server)
+
+// NewServer creates the API server over the given stores; blobs may be nil, which
+// turns attachments and config backups off. Settings not given as options keep their
+// defaults.
+func NewServer(postgres storage.Store, redis storage.StatusStore, blobs storage.BlobStore, opts ...ServerOption) *Server {
+	s := &Server{
 		postgres: postgres,
 		redis:    redis,
-		gcs:      gcsClient,
+		blobs:    blobs,
+		notifier: notifier.NewNotifier(postgres, redis),
+		webhooks: webhook.NewDispatcher(postgres),
+
+		workerStaleAfter: monitor.DefaultWorkerStaleAfter,
+		requestTimeout:   DefaultRequestTimeout,
+		uploadTimeout:    DefaultUploadTimeout,
+		rateLimits:       DefaultRateLimits,
+
+		streamsClosed: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithWorkerStaleAfter overrides how old the newest worker heartbeat may be before
+// the workers are reported stale
+func WithWorkerStaleAfter(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.workerStaleAfter = d
+	}
+}
+
+// WithRequestTimeout overrides how long a request may run before its context is
+// cancelled. Zero turns the limit off.
+func WithRequestTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.requestTimeout = d
+	}
+}
+
+// WithUploadTimeout overrides how long an upload or import may run before its
+// context is cancelled. Zero turns the limit off.
+func WithUploadTimeout(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.uploadTimeout = d
+	}
+}
+
+// WithGeocoder sets the service property addresses are geocoded with when saved
+func WithGeocoder(g geocode.Geocoder) ServerOption {
+	return func(s *Server) {
+		s.geocoder = g
 	}
 }
 
-// Health check
+// CloseStreams ends the open websocket and Server-Sent Events streams, which would
+// otherwise hold up a graceful shutdown until it timed out. Clients reconnect to
+// another replica.
+func (s *Server) CloseStreams() {
+	s.closeStreams.Do(func() { close(s.streamsClosed) })
+}
+
+// CloseWebhooks stops retrying webhook deliveries and waits for those in flight. Call
+// it once requests have drained, as they may still publish events.
+func (s *Server) CloseWebhooks() {
+	s.webhooks.Close()
+}
+
+// Health check. The status reflects the API itself so a stalled worker doesn't get
+// API pods restarted; worker health is reported alongside it.
 func (s *Server) handleHealth(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	response := gin.H{"status": "ok"}
+	if s.redis != nil {
+		workers, err := monitor.CheckWorkerHealth(c.Request.Context(), s.redis, s.workerStaleAfter)
+		if err != nil {
+			response["workers"] = gin.H{"status": "unknown", "error": err.Error()}
+		} else {
+			response["workers"] = workers
+		}
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // Dashboard
 func (s *Server) handleDashboard(c *gin.Context) {
-	properties, err := s.postgres.ListProperties(context.Background())
+	filter, ok := propertyFilter(c)
+	if !ok {
+		return
+	}
+
+	body, err := s.cachedDashboard(c, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Get all property statuses from Redis
-	propertyStatuses, err := s.redis.GetAllPropertyStatuses(context.Background())
+	writeGzippedJSON(c, body)
+}
+
+// handleGetWorkerHealth returns the worker heartbeats, so the dashboard can warn when
+// statuses have gone stale
+func (s *Server) handleGetWorkerHealth(c *gin.Context) {
+	workers, err := monitor.CheckWorkerHealth(c.Request.Context(), s.redis, s.workerStaleAfter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	c.JSON(http.StatusOK, workers)
+}
+
+// buildDashboard combines the properties matching filter with their rollup status
+// from Redis, and rolls those up per group
+func (s *Server) buildDashboard(ctx context.Context, filter storage.PropertyFilter) (*models.DashboardResponse, error) {
+	properties, _, err := s.postgres.ListPropertiesPage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get all property statuses from Redis
+	propertyStatuses, err := s.redis.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	acks, err := s.postgres.ListActiveAcknowledgements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	acknowledged := make(map[int64]bool)
+	for _, ack := range acks {
+		if ack.EntityType == "property" {
+			acknowledged[ack.PropertyID] = true
+		}
+	}
+
+	silences, err := s.postgres.ListActiveSilences(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	silenced := make(map[int64]bool)
+	for _, silence := range silences {
+		if silence.ScopeType == "property" && silence.PropertyID != nil {
+			silenced[*silence.PropertyID] = true
+		}
+	}
+
 	propertiesWithStatus := make([]models.PropertyWithStatus, 0)
 	redCount, yellowCount, greenCount := 0, 0, 0
 
 	for _, prop := range properties {
+		redactProperty(&prop)
 		pws := models.PropertyWithStatus{
-			Property: prop,
-			Status:   "green",
+			Property:     prop,
+			Status:       "green",
+			Acknowledged: acknowledged[prop.ID],
+			Silenced:     silenced[prop.ID],
 		}
 
 		if status, ok := propertyStatuses[prop.ID]; ok {
 			pws.Status = status.Status
 			pws.OnlineCount = status.OnlineCount
 			pws.OfflineCount = status.OfflineCount
+			pws.UnreachableCount = status.UnreachableCount
 			pws.TotalCount = status.TotalCount
 			pws.CriticalOffline = status.CriticalOffline
+			pws.Maintenance = status.Maintenance
+			pws.Flapping = status.Flapping
 			pws.LastCheck = status.LastCheck.Format(time.RFC3339)
 
 			switch status.Status {
@@ -81,25 +241,91 @@ func (s *Server) handleDashboard(c *gin.Context) {
 		propertiesWithStatus = append(propertiesWithStatus, pws)
 	}
 
-	response := models.DashboardResponse{
+	workers, err := monitor.CheckWorkerHealth(ctx, s.redis, s.workerStaleAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.groupRollups(ctx, filter, propertiesWithStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.DashboardResponse{
 		Properties: propertiesWithStatus,
+		Groups:     groups,
+		Workers:    workers,
 	}
 	response.Summary.TotalProperties = len(properties)
 	response.Summary.RedCount = redCount
 	response.Summary.YellowCount = yellowCount
 	response.Summary.GreenCount = greenCount
 
-	c.JSON(http.StatusOK, response)
+	return response, nil
 }
 
 // Properties
 func (s *Server) handleListProperties(c *gin.Context) {
-	properties, err := s.postgres.ListProperties(context.Background())
+	ctx := c.Request.Context()
+	opts, ok := listOptions(c, storage.PropertySorts)
+	if !ok {
+		return
+	}
+	filter, ok := propertyFilter(c)
+	if !ok {
+		return
+	}
+
+	// Rollup statuses live in Redis, so a status filter is applied to the whole
+	// listing before it is paged
+	status := c.Query("status")
+	if status != "" && status != "green" && status != "yellow" && status != "red" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "status must be green, yellow or red"})
+		return
+	}
+	filter.ListOptions = opts
+	if status != "" {
+		filter.ListOptions = storage.ListOptions{Sort: opts.Sort}
+	}
+
+	properties, total, err := s.postgres.ListPropertiesPage(ctx, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, properties)
+
+	if status != "" {
+		statuses, err := s.redis.GetAllPropertyStatuses(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		matching := properties[:0]
+		for _, p := range properties {
+			// Properties without a computed status show as green on the dashboard
+			current := "green"
+			if ps, ok := statuses[p.ID]; ok {
+				current = ps.Status
+			}
+			if current == status {
+				matching = append(matching, p)
+			}
+		}
+		total = len(matching)
+		properties = paginate(matching, opts)
+	}
+
+	for i := range properties {
+		redactProperty(&properties[i])
+	}
+	writeList(c, properties, total)
+}
+
+// redactProperty clears the pfSense password before a property is returned, recording
+// only whether one is set
+func redactProperty(property *models.Property) {
+	property.PfSensePasswordSet = property.PfSensePasswordSet || property.PfSensePassword != ""
+	property.PfSensePassword = ""
 }
 
 func (s *Server) handleGetProperty(c *gin.Context) {
@@ -109,12 +335,13 @@ func (s *Server) handleGetProperty(c *gin.Context) {
 		return
 	}
 
-	property, err := s.postgres.GetProperty(context.Background(), id)
+	property, err := s.postgres.GetProperty(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
 		return
 	}
 
+	redactProperty(property)
 	c.JSON(http.StatusOK, property)
 }
 
@@ -125,11 +352,33 @@ func (s *Server) handleCreateProperty(c *gin.Context) {
 		return
 	}
 
-	if err := s.postgres.CreateProperty(context.Background(), &property); err != nil {
+	ctx := c.Request.Context()
+	if err := s.validatePropertyGroupID(ctx, &property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateCoordinates(&property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateCheckSettings(property.CheckInterval, property.Retries, property.Timeout); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	property.ID = 0
+	if err := s.validatePropertySubnet(ctx, &property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	s.geocodeProperty(c, &property)
+
+	if err := s.postgres.CreateProperty(ctx, &property); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	redactProperty(&property)
+	s.webhooks.Publish(ctx, webhook.EventPropertyCreated, property)
 	c.JSON(http.StatusCreated, property)
 }
 
@@ -146,12 +395,46 @@ func (s *Server) handleUpdateProperty(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	existing, err := s.postgres.GetProperty(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+	if err := s.validatePropertyGroupID(ctx, &property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateCoordinates(&property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateCheckSettings(property.CheckInterval, property.Retries, property.Timeout); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	// Only a changed subnet is checked, so properties that already overlap can still be edited
 	property.ID = id
-	if err := s.postgres.UpdateProperty(context.Background(), &property); err != nil {
+	if property.Subnet == existing.Subnet {
+		property.Subnet = ""
+	}
+	if err := s.validatePropertySubnet(ctx, &property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// A new address is geocoded again unless the coordinates were moved with it
+	if property.Address != existing.Address && sameCoordinates(&property, existing) {
+		property.Latitude, property.Longitude = nil, nil
+	}
+	s.geocodeProperty(c, &property)
+
+	if err := s.postgres.UpdateProperty(ctx, &property); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	redactProperty(&property)
 	c.JSON(http.StatusOK, property)
 }
 
@@ -162,7 +445,7 @@ func (s *Server) handleDeleteProperty(c *gin.Context) {
 		return
 	}
 
-	if err := s.postgres.DeleteProperty(context.Background(), id); err != nil {
+	if err := s.deleteProperty(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -178,7 +461,7 @@ func (s *Server) handleGetPropertyStatus(c *gin.Context) {
 	}
 
 	// Get property devices
-	devices, err := s.postgres.ListDevicesForProperty(context.Background(), id)
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -186,7 +469,7 @@ func (s *Server) handleGetPropertyStatus(c *gin.Context) {
 
 	// Compute status
 	statusComputer := monitor.NewStatusComputer(s.postgres, s.redis)
-	status, err := statusComputer.ComputePropertyStatus(context.Background(), id, devices)
+	status, err := statusComputer.ComputePropertyStatus(c.Request.Context(), id, devices)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -202,13 +485,7 @@ func (s *Server) handleGetPropertyDevices(c *gin.Context) {
 		return
 	}
 
-	devices, err := s.postgres.ListDevicesForProperty(context.Background(), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, devices)
+	s.listDevices(c, id)
 }
 
 // Contacts
@@ -219,13 +496,18 @@ func (s *Server) handleListContactsForProperty(c *gin.Context) {
 		return
 	}
 
-	contacts, err := s.postgres.ListContactsForProperty(context.Background(), id)
+	opts, ok := listOptions(c, storage.ContactSorts)
+	if !ok {
+		return
+	}
+
+	contacts, total, err := s.postgres.ListContactsPage(c.Request.Context(), storage.ContactFilter{ListOptions: opts, PropertyID: id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, contacts)
+	writeList(c, contacts, total)
 }
 
 func (s *Server) handleCreateContact(c *gin.Context) {
@@ -241,8 +523,13 @@ func (s *Server) handleCreateContact(c *gin.Context) {
 		return
 	}
 
+	if contact.EscalationOrder < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "escalation_order must not be negative"})
+		return
+	}
+
 	contact.PropertyID = propertyID
-	if err := s.postgres.CreateContact(context.Background(), &contact); err != nil {
+	if err := s.postgres.CreateContact(c.Request.Context(), &contact); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -257,7 +544,7 @@ func (s *Server) handleGetContact(c *gin.Context) {
 		return
 	}
 
-	contact, err := s.postgres.GetContact(context.Background(), id)
+	contact, err := s.postgres.GetContact(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Contact not found"})
 		return
@@ -279,8 +566,13 @@ func (s *Server) handleUpdateContact(c *gin.Context) {
 		return
 	}
 
+	if contact.EscalationOrder < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "escalation_order must not be negative"})
+		return
+	}
+
 	contact.ID = id
-	if err := s.postgres.UpdateContact(context.Background(), &contact); err != nil {
+	if err := s.postgres.UpdateContact(c.Request.Context(), &contact); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -295,7 +587,7 @@ func (s *Server) handleDeleteContact(c *gin.Context) {
 		return
 	}
 
-	if err := s.postgres.DeleteContact(context.Background(), id); err != nil {
+	if err := s.postgres.DeleteContact(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -311,7 +603,7 @@ func (s *Server) handleListAttachmentsForProperty(c *gin.Context) {
 		return
 	}
 
-	attachments, err := s.postgres.ListAttachmentsForProperty(context.Background(), id)
+	attachments, err := s.postgres.ListAttachmentsForProperty(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -327,6 +619,11 @@ func (s *Server) handleUploadAttachment(c *gin.Context) {
 		return
 	}
 
+	if s.blobs == nil {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage is not configured"})
+		return
+	}
+
 	file, err := c.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "No file provided"})
@@ -334,11 +631,16 @@ func (s *Server) handleUploadAttachment(c *gin.Context) {
 	}
 
 	description := c.PostForm("description")
+	tags := normalizeTags(strings.Split(c.PostForm("tags"), ","))
 	username, _ := c.Get("username")
 
-	// Check file size (max 50MB)
-	if file.Size > 50*1024*1024 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "File too large (max 50MB)"})
+	// Check file size; larger files need a chunked upload
+	maxSize, ok := s.maxAttachmentSize(c)
+	if !ok {
+		return
+	}
+	if maxSize = min(maxSize, maxSingleUploadSize); file.Size > maxSize {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("File too large (max %dMB)", maxSize/1024/1024)})
 		return
 	}
 
@@ -353,8 +655,8 @@ func (s *Server) handleUploadAttachment(c *gin.Context) {
 	}
 	defer fileReader.Close()
 
-	// Upload to GCS
-	if err := s.gcs.UploadFile(context.Background(), objectName, fileReader, file.Header.Get("Content-Type")); err != nil {
+	// Upload to the blob store
+	if err := s.blobs.UploadFile(c.Request.Context(), objectName, fileReader, file.Header.Get("Content-Type")); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: fmt.Sprintf("Failed to upload: %v", err)})
 		return
 	}
@@ -364,14 +666,15 @@ func (s *Server) handleUploadAttachment(c *gin.Context) {
 		PropertyID:  propertyID,
 		Filename:    file.Filename,
 		Description: description,
-		StorageType: "gcs",
+		StorageType: s.blobs.Kind(),
 		StoragePath: objectName,
 		FileSize:    file.Size,
 		MimeType:    file.Header.Get("Content-Type"),
+		Tags:        tags,
 		UploadedBy:  username.(string),
 	}
 
-	if err := s.postgres.CreateAttachment(context.Background(), attachment); err != nil {
+	if err := s.postgres.CreateAttachment(c.Request.Context(), attachment); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -379,6 +682,75 @@ func (s *Server) handleUploadAttachment(c *gin.Context) {
 	c.JSON(http.StatusCreated, attachment)
 }
 
+// handleUpdateAttachment changes an attachment's description and tags
+func (s *Server) handleUpdateAttachment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid attachment ID"})
+		return
+	}
+
+	var update models.AttachmentUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	attachment, err := s.postgres.GetAttachment(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Attachment not found"})
+		return
+	}
+	attachment.Description = update.Description
+	attachment.Tags = normalizeTags(update.Tags)
+
+	if err := s.postgres.UpdateAttachment(c.Request.Context(), attachment); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// handleSearchAttachments finds attachments across all properties by the words in q,
+// matched against filenames, descriptions, tags and property names, and by tag and
+// property_id
+func (s *Server) handleSearchAttachments(c *gin.Context) {
+	opts, ok := listOptions(c, storage.AttachmentSorts)
+	if !ok {
+		return
+	}
+
+	filter := storage.AttachmentFilter{ListOptions: opts, Query: c.Query("q"), Tag: c.Query("tag")}
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		filter.PropertyID = id
+	}
+
+	attachments, total, err := s.postgres.SearchAttachments(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeList(c, attachments, total)
+}
+
+// normalizeTags trims tags and drops empty and repeated ones
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
 func (s *Server) handleDownloadAttachment(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -386,15 +758,19 @@ func (s *Server) handleDownloadAttachment(c *gin.Context) {
 		return
 	}
 
-	attachment, err := s.postgres.GetAttachment(context.Background(), id)
+	attachment, err := s.postgres.GetAttachment(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Attachment not found"})
 		return
 	}
 
-	if attachment.StorageType == "gcs" {
+	if isBlobStorage(attachment.StorageType) {
+		if s.blobs == nil || s.blobs.Kind() != attachment.StorageType {
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage for this attachment is not configured"})
+			return
+		}
 		// Generate signed URL (valid for 1 hour)
-		url, err := s.gcs.GetSignedURL(context.Background(), attachment.StoragePath, time.Hour)
+		url, err := s.blobs.GetSignedURL(c.Request.Context(), attachment.StoragePath, time.Hour)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate download URL"})
 			return
@@ -415,22 +791,26 @@ func (s *Server) handleDeleteAttachment(c *gin.Context) {
 		return
 	}
 
-	attachment, err := s.postgres.GetAttachment(context.Background(), id)
+	attachment, err := s.postgres.GetAttachment(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Attachment not found"})
 		return
 	}
 
-	// Delete from GCS if applicable
-	if attachment.StorageType == "gcs" {
-		if err := s.gcs.DeleteFile(context.Background(), attachment.StoragePath); err != nil {
+	// Delete the file if we store it
+	if isBlobStorage(attachment.StorageType) {
+		if s.blobs == nil || s.blobs.Kind() != attachment.StorageType {
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "File storage for this attachment is not configured"})
+			return
+		}
+		if err := s.blobs.DeleteFile(c.Request.Context(), attachment.StoragePath); err != nil {
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete file"})
 			return
 		}
 	}
 
 	// Delete database record
-	if err := s.postgres.DeleteAttachment(context.Background(), id); err != nil {
+	if err := s.postgres.DeleteAttachment(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -440,12 +820,85 @@ func (s *Server) handleDeleteAttachment(c *gin.Context) {
 
 // Devices
 func (s *Server) handleListDevices(c *gin.Context) {
-	devices, err := s.postgres.ListDevices(context.Background())
+	var propertyID int64
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		propertyID = id
+	}
+	s.listDevices(c, propertyID)
+}
+
+// listDevices responds with the devices matching the type, tag, active and status
+// query parameters, for one property or all when propertyID is zero
+func (s *Server) listDevices(c *gin.Context, propertyID int64) {
+	ctx := c.Request.Context()
+	opts, ok := listOptions(c, storage.DeviceSorts)
+	if !ok {
+		return
+	}
+	active, ok := boolQuery(c, "active")
+	if !ok {
+		return
+	}
+
+	// Current statuses live in Redis, so a status filter is applied to the whole
+	// listing before it is paged
+	status := c.Query("status")
+	switch status {
+	case "", monitor.StatusOnline, monitor.StatusOffline, monitor.StatusUnreachable, "unknown":
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "status must be online, offline, unreachable or unknown"})
+		return
+	}
+
+	filter := storage.DeviceFilter{
+		ListOptions:  opts,
+		PropertyID:   propertyID,
+		DeviceType:   c.Query("type"),
+		Tag:          c.Query("tag"),
+		Active:       active,
+		Manufacturer: c.Query("manufacturer"),
+		Model:        c.Query("model"),
+	}
+	if status != "" {
+		filter.ListOptions = storage.ListOptions{Sort: opts.Sort}
+	}
+
+	devices, total, err := s.postgres.ListDevicesPage(ctx, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, devices)
+
+	if status != "" {
+		ids := make([]int64, len(devices))
+		for i, d := range devices {
+			ids[i] = d.ID
+		}
+		statuses, err := s.redis.GetDeviceStatuses(ctx, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		matching := devices[:0]
+		for _, d := range devices {
+			current := "unknown"
+			if ds := statuses[d.ID]; ds != nil {
+				current = ds.Status
+			}
+			if current == status {
+				matching = append(matching, d)
+			}
+		}
+		total = len(matching)
+		devices = paginate(matching, opts)
+	}
+
+	writeList(c, devices, total)
 }
 
 func (s *Server) handleGetDevice(c *gin.Context) {
@@ -455,7 +908,7 @@ func (s *Server) handleGetDevice(c *gin.Context) {
 		return
 	}
 
-	device, err := s.postgres.GetDevice(context.Background(), id)
+	device, err := s.postgres.GetDevice(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device not found"})
 		return
@@ -471,20 +924,34 @@ func (s *Server) handleCreateDevice(c *gin.Context) {
 		return
 	}
 
-	// Set defaults if not provided
-	if device.CheckInterval <= 0 {
-		device.CheckInterval = 60
+	applyNewDeviceDefaults(&device)
+
+	if err := validateDeviceCheck(&device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
 	}
-	if device.Retries <= 0 {
-		device.Retries = 3
+
+	if err := validateDeviceAsset(&device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
 	}
-	if device.Timeout <= 0 {
-		device.Timeout = 10000
+
+	if err := s.validateDeviceParent(c.Request.Context(), &device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
 	}
-	// Default to active if not explicitly set
-	device.Active = true
 
-	if err := s.postgres.CreateDevice(context.Background(), &device); err != nil {
+	if err := s.validateDeviceProbe(c.Request.Context(), &device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.validateDeviceConflicts(c.Request.Context(), &device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreateDevice(c.Request.Context(), &device); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -505,8 +972,33 @@ func (s *Server) handleUpdateDevice(c *gin.Context) {
 		return
 	}
 
+	if err := validateDeviceCheck(&device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateDeviceAsset(&device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	device.ID = id
-	if err := s.postgres.UpdateDevice(context.Background(), &device); err != nil {
+	if err := s.validateDeviceParent(c.Request.Context(), &device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.validateDeviceProbe(c.Request.Context(), &device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.validateDeviceConflicts(c.Request.Context(), &device); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.UpdateDevice(c.Request.Context(), &device); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -514,6 +1006,98 @@ func (s *Server) handleUpdateDevice(c *gin.Context) {
 	c.JSON(http.StatusOK, device)
 }
 
+// validateCheckSettings checks a device's or property's check interval, retries and
+// timeout, where 0 inherits the next level's
+func validateCheckSettings(interval, retries, timeout int) error {
+	if interval < 0 || retries < 0 || timeout < 0 {
+		return fmt.Errorf("check_interval, retries and timeout must not be negative (0 inherits the default)")
+	}
+	return nil
+}
+
+// applyNewDeviceDefaults prepares a new device. Check settings it leaves out stay 0 and
+// are inherited from its property or the global defaults when it is checked.
+func applyNewDeviceDefaults(device *models.Device) {
+	// Default to active if not explicitly set
+	device.Active = true
+}
+
+// validateDeviceCheck normalizes and validates the device check type and its target
+func validateDeviceCheck(device *models.Device) error {
+	if err := validateCheckSettings(device.CheckInterval, device.Retries, device.Timeout); err != nil {
+		return err
+	}
+	switch device.CheckType {
+	case "", "icmp":
+		device.CheckType = "icmp"
+	case "tcp":
+		if device.Port <= 0 || device.Port > 65535 {
+			return fmt.Errorf("tcp checks require a port between 1 and 65535")
+		}
+	case "vpn":
+		if _, _, err := pfsense.ParseVPNKey(device.Hostname); err != nil {
+			return fmt.Errorf("vpn checks take the tunnel as hostname: %w", err)
+		}
+	case "carp":
+		if device.Hostname == "" {
+			return fmt.Errorf("carp checks take the firewall host as hostname")
+		}
+	case "ups":
+		if _, _, err := nut.ParseTarget(device.Hostname); err != nil {
+			return err
+		}
+		if device.Port < 0 || device.Port > 65535 {
+			return fmt.Errorf("ups checks take the upsd port, or 0 for the default %d", nut.DefaultPort)
+		}
+	default:
+		return fmt.Errorf("invalid check_type %q (must be icmp, tcp, vpn, carp or ups)", device.CheckType)
+	}
+	return nil
+}
+
+// validateDeviceParent ensures the parent device exists and doesn't create a cycle
+func (s *Server) validateDeviceParent(ctx context.Context, device *models.Device) error {
+	if device.ParentDeviceID == nil {
+		return nil
+	}
+
+	parentID := *device.ParentDeviceID
+	for depth := 0; depth < 32; depth++ {
+		if device.ID != 0 && parentID == device.ID {
+			return fmt.Errorf("parent_device_id would create a dependency cycle")
+		}
+		parent, err := s.postgres.GetDevice(ctx, parentID)
+		if err != nil {
+			return fmt.Errorf("parent device %d not found", parentID)
+		}
+		if parent.ParentDeviceID == nil {
+			return nil
+		}
+		parentID = *parent.ParentDeviceID
+	}
+	return fmt.Errorf("parent device chain is too deep")
+}
+
+// validateDeviceProbe ensures a device assigned to a remote probe belongs to the probe's
+// property and uses a check the probe can run
+func (s *Server) validateDeviceProbe(ctx context.Context, device *models.Device) error {
+	if device.ProbeID == nil {
+		return nil
+	}
+
+	probe, err := s.postgres.GetProbe(ctx, *device.ProbeID)
+	if err != nil {
+		return fmt.Errorf("probe %d not found", *device.ProbeID)
+	}
+	if probe.PropertyID != device.PropertyID {
+		return fmt.Errorf("probe %d belongs to a different property", probe.ID)
+	}
+	if !monitor.ProbeCheckTypes[device.CheckType] {
+		return fmt.Errorf("%s checks can't run on a remote probe", device.CheckType)
+	}
+	return nil
+}
+
 func (s *Server) handleDeleteDevice(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -521,10 +1105,11 @@ func (s *Server) handleDeleteDevice(c *gin.Context) {
 		return
 	}
 
-	if err := s.postgres.DeleteDevice(context.Background(), id); err != nil {
+	if err := s.postgres.DeleteDevice(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
+	s.clearDeviceStatuses(c.Request.Context(), []int64{id})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Device deleted"})
 }
@@ -536,7 +1121,7 @@ func (s *Server) handleGetDeviceStatus(c *gin.Context) {
 		return
 	}
 
-	status, err := s.redis.GetDeviceStatus(context.Background(), id)
+	status, err := s.redis.GetDeviceStatus(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device status not found"})
 		return
@@ -553,22 +1138,14 @@ func (s *Server) handleGetDeviceHistory(c *gin.Context) {
 	}
 
 	// Default to last 24 hours
-	endTime := time.Now()
-	startTime := endTime.Add(-24 * time.Hour)
-
-	if startStr := c.Query("start"); startStr != "" {
-		if t, err := time.Parse(time.RFC3339, startStr); err == nil {
-			startTime = t
-		}
-	}
+	startTime, endTime := timeRange(c, 24*time.Hour)
 
-	if endStr := c.Query("end"); endStr != "" {
-		if t, err := time.Parse(time.RFC3339, endStr); err == nil {
-			endTime = t
-		}
+	if c.Query("interval") != "" || c.Query("aggregate") != "" {
+		s.getDeviceHistoryBuckets(c, id, startTime, endTime)
+		return
 	}
 
-	history, err := s.redis.GetDeviceHistory(context.Background(), id, startTime, endTime)
+	history, err := s.postgres.GetDeviceHistory(c.Request.Context(), id, startTime, endTime)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -592,7 +1169,7 @@ func (s *Server) handleGetDeviceErrors(c *gin.Context) {
 		}
 	}
 
-	errors, err := s.redis.GetDeviceErrors(context.Background(), id, limit)
+	errors, err := s.postgres.GetDeviceErrors(c.Request.Context(), id, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -603,7 +1180,7 @@ func (s *Server) handleGetDeviceErrors(c *gin.Context) {
 
 // Users
 func (s *Server) handleListUsers(c *gin.Context) {
-	users, err := s.postgres.ListUsers(context.Background())
+	users, err := s.postgres.ListUsers(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
@@ -617,6 +1194,10 @@ func (s *Server) handleCreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
+	if !validRole(user.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "role must be admin, user or viewer"})
+		return
+	}
 
 	// Hash password
 	hashedPassword, err := hashPassword(user.Password)
@@ -626,7 +1207,7 @@ func (s *Server) handleCreateUser(c *gin.Context) {
 	}
 	user.Password = hashedPassword
 
-	if err := s.postgres.CreateUser(context.Background(), &user); err != nil {
+	if err := s.postgres.CreateUser(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -647,11 +1228,31 @@ func (s *Server) handleUpdateUser(c *gin.Context) {
 		return
 	}
 
+	if !validRole(user.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "role must be admin, user or viewer"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	current, err := s.postgres.GetUser(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+
 	user.ID = id
-	if err := s.postgres.UpdateUser(context.Background(), &user); err != nil {
+	if err := s.postgres.UpdateUser(ctx, &user); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
+	// Disabling an account signs it out everywhere, and so does a role change, since
+	// session tokens carry the role they were issued with
+	if !user.Active || user.Role != current.Role {
+		if _, err := s.postgres.RevokeUserSessions(ctx, id, 0); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, user)
 }
@@ -663,7 +1264,7 @@ func (s *Server) handleDeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := s.postgres.DeleteUser(context.Background(), id); err != nil {
+	if err := s.postgres.DeleteUser(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
@@ -673,11 +1274,12 @@ func (s *Server) handleDeleteUser(c *gin.Context) {
 
 // Settings
 func (s *Server) handleGetSettings(c *gin.Context) {
-	settings, err := s.postgres.GetSettings(context.Background())
+	settings, err := s.postgres.GetSettings(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
+	settings.OIDC.ClientSecret = ""
 	c.JSON(http.StatusOK, settings)
 }
 
@@ -688,116 +1290,406 @@ func (s *Server) handleUpdateSettings(c *gin.Context) {
 		return
 	}
 
-	if err := s.postgres.UpdateSettings(context.Background(), &settings); err != nil {
+	if err := validateSettings(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	current, err := s.postgres.GetSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	keepUnsetSettings(&settings, current)
+
+	if err := s.postgres.UpdateSettings(c.Request.Context(), &settings); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	settings.OIDC.ClientSecret = ""
 	c.JSON(http.StatusOK, settings)
 }
 
-// SyncDevicesFromPfSense syncs devices from pfSense DHCP static mappings
-func (s *Server) handleSyncDevicesFromPfSense(c *gin.Context) {
+// keepUnsetSettings fills in settings added since a client or export was written,
+// which it leaves unset, from the current ones
+func keepUnsetSettings(settings, current *models.Settings) {
+	keepOAuthSettings(settings, current)
+	if settings.MaxAttachmentSizeMB == 0 {
+		settings.MaxAttachmentSizeMB = current.MaxAttachmentSizeMB
+	}
+	if settings.FirmwareMinimums == nil {
+		settings.FirmwareMinimums = current.FirmwareMinimums
+	}
+	if settings.SubnetBase == "" {
+		settings.SubnetBase = current.SubnetBase
+	}
+}
+
+// Bounds on the global settings
+const (
+	minCheckInterval        = 5     // seconds
+	maxCheckInterval        = 86400 // seconds
+	maxRetries              = 10
+	minTimeout              = 100   // milliseconds
+	maxTimeout              = 60000 // milliseconds
+	maxRetentionDays        = 365
+	maxNotificationCooldown = 86400 // seconds
+)
+
+// validateSettings checks the settings the worker and Google sign-in apply. OAuth
+// settings, the attachment size, firmware minimums and subnet base left out keep their
+// current values; see keepUnsetSettings.
+func validateSettings(settings *models.Settings) error {
+	if settings.MaxConcurrentPings < 1 || settings.MaxConcurrentPings > monitor.MaxConcurrentChecks {
+		return fmt.Errorf("max_concurrent_pings must be between 1 and %d", monitor.MaxConcurrentChecks)
+	}
+	if settings.DefaultCheckInterval < minCheckInterval || settings.DefaultCheckInterval > maxCheckInterval {
+		return fmt.Errorf("default_check_interval must be between %d and %d seconds", minCheckInterval, maxCheckInterval)
+	}
+	if settings.DefaultRetries < 1 || settings.DefaultRetries > maxRetries {
+		return fmt.Errorf("default_retries must be between 1 and %d", maxRetries)
+	}
+	if settings.DefaultTimeout < minTimeout || settings.DefaultTimeout > maxTimeout {
+		return fmt.Errorf("default_timeout must be between %d and %d ms", minTimeout, maxTimeout)
+	}
+	if settings.HistoryRetentionDays < 1 || settings.HistoryRetentionDays > maxRetentionDays {
+		return fmt.Errorf("history_retention_days must be between 1 and %d", maxRetentionDays)
+	}
+	if settings.NotificationCooldown < 0 || settings.NotificationCooldown > maxNotificationCooldown {
+		return fmt.Errorf("notification_cooldown must be between 0 and %d seconds", maxNotificationCooldown)
+	}
+	if settings.MaxAttachmentSizeMB < 0 {
+		return fmt.Errorf("max_attachment_size_mb must be at least 1")
+	}
+	if settings.OAuthDefaultRole != "" && !validRole(settings.OAuthDefaultRole) {
+		return fmt.Errorf("oauth_default_role must be admin, user or viewer")
+	}
+	if settings.OAuthAllowedDomains != nil {
+		domains, err := normalizeEmailDomains(settings.OAuthAllowedDomains)
+		if err != nil {
+			return fmt.Errorf("oauth_allowed_domains: %w", err)
+		}
+		settings.OAuthAllowedDomains = domains
+	}
+	if settings.OIDC != nil {
+		if err := validateOIDCSettings(settings.OIDC); err != nil {
+			return fmt.Errorf("oidc: %w", err)
+		}
+	}
+	if settings.FirmwareMinimums != nil {
+		minimums, err := normalizeFirmwareMinimums(settings.FirmwareMinimums)
+		if err != nil {
+			return fmt.Errorf("firmware_minimums: %w", err)
+		}
+		settings.FirmwareMinimums = minimums
+	}
+	if settings.SubnetBase != "" {
+		base, err := normalizeSubnetBase(settings.SubnetBase)
+		if err != nil {
+			return fmt.Errorf("subnet_base %w", err)
+		}
+		settings.SubnetBase = base
+	}
+	return nil
+}
+
+// Notification Channels
+func (s *Server) handleListNotificationChannels(c *gin.Context) {
+	channels, err := s.postgres.ListNotificationChannels(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+func (s *Server) handleGetNotificationChannel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification channel ID"})
+		return
+	}
+
+	channel, err := s.postgres.GetNotificationChannel(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Notification channel not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+func (s *Server) handleCreateNotificationChannel(c *gin.Context) {
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if !json.Valid([]byte(channel.Config)) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Config must be valid JSON"})
+		return
+	}
+
+	if err := s.postgres.CreateNotificationChannel(c.Request.Context(), &channel); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+func (s *Server) handleUpdateNotificationChannel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification channel ID"})
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if !json.Valid([]byte(channel.Config)) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Config must be valid JSON"})
+		return
+	}
+
+	channel.ID = id
+	if err := s.postgres.UpdateNotificationChannel(c.Request.Context(), &channel); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+func (s *Server) handleDeleteNotificationChannel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification channel ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteNotificationChannel(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted"})
+}
+
+func (s *Server) handleTestNotificationChannel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification channel ID"})
+		return
+	}
+
+	channel, err := s.postgres.GetNotificationChannel(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Notification channel not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	result := models.NotificationTestResult{NotificationChannelID: channel.ID}
+	if err := s.notifier.SendTest(ctx, channel); err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Property Notifications
+func (s *Server) handleListPropertyNotifications(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	notifications, err := s.postgres.ListPropertyNotifications(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+func (s *Server) handleCreatePropertyNotification(c *gin.Context) {
 	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
 		return
 	}
 
-	property, err := s.postgres.GetProperty(context.Background(), propertyID)
+	var notification models.PropertyNotification
+	if err := c.ShouldBindJSON(&notification); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := notifier.ValidateActiveHours(notification.ActiveFrom, notification.ActiveTo, notification.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if notification.Timezone == "" {
+		notification.Timezone = "UTC"
+	}
+
+	notification.PropertyID = propertyID
+	if err := s.postgres.CreatePropertyNotification(c.Request.Context(), &notification); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, notification)
+}
+
+func (s *Server) handleUpdatePropertyNotification(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property notification ID"})
+		return
+	}
+
+	var notification models.PropertyNotification
+	if err := c.ShouldBindJSON(&notification); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := notifier.ValidateActiveHours(notification.ActiveFrom, notification.ActiveTo, notification.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if notification.Timezone == "" {
+		notification.Timezone = "UTC"
+	}
+
+	notification.ID = id
+	if err := s.postgres.UpdatePropertyNotification(c.Request.Context(), &notification); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+func (s *Server) handleDeletePropertyNotification(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property notification ID"})
 		return
 	}
 
-	if property.PfSenseHost == "" || property.PfSenseUsername == "" || property.PfSensePassword == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "pfSense credentials not configured for this property",
-		})
+	if err := s.postgres.DeletePropertyNotification(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	pfClient := pfsense.NewClient(property.PfSenseHost, property.PfSensePort, property.PfSenseUsername, property.PfSensePassword)
-	mappings, err := pfClient.GetDHCPStaticMappingsXML(context.Background())
+	c.JSON(http.StatusOK, gin.H{"message": "Property notification deleted"})
+}
+
+func (s *Server) handleListNotificationEvents(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: fmt.Sprintf("Failed to fetch devices from pfSense: %v", err),
-		})
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
 		return
 	}
 
-	created, updated := 0, 0
-	var errors []string
+	opts, ok := listOptions(c, storage.NotificationEventSorts)
+	if !ok {
+		return
+	}
+	// Events accumulate, so the listing is always paged, 50 at a time by default
+	if opts.Limit == 0 {
+		opts.Limit = 50
+	}
+	success, ok := boolQuery(c, "success")
+	if !ok {
+		return
+	}
 
-	for _, mapping := range mappings {
-		if mapping.Hostname == "" || mapping.IPAddr == "" {
-			continue
-		}
+	events, total, err := s.postgres.ListNotificationEventsPage(c.Request.Context(), storage.NotificationEventFilter{
+		ListOptions: opts,
+		PropertyID:  id,
+		EventType:   c.Query("event_type"),
+		Success:     success,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
 
-		deviceType := pfsense.DetermineDeviceType(mapping.IPAddr)
-		tags := []string{deviceType}
+	writeList(c, events, total)
+}
 
-		existingDevices, err := s.postgres.ListDevices(context.Background())
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Failed to list devices: %v", err))
-			continue
-		}
+// handleListAllNotificationEvents lists notification deliveries across every property,
+// for auditing failed deliveries fleet-wide. It takes the per-property listing's
+// filters plus property_id, channel_id, start and end.
+func (s *Server) handleListAllNotificationEvents(c *gin.Context) {
+	opts, ok := listOptions(c, storage.NotificationEventSorts)
+	if !ok {
+		return
+	}
+	if opts.Limit == 0 {
+		opts.Limit = 50
+	}
+	success, ok := boolQuery(c, "success")
+	if !ok {
+		return
+	}
 
-		var existingDevice *models.Device
-		for _, d := range existingDevices {
-			if d.PropertyID == propertyID && d.Hostname == mapping.IPAddr {
-				existingDevice = &d
-				break
+	filter := storage.NotificationEventFilter{
+		ListOptions: opts,
+		EventType:   c.Query("event_type"),
+		Success:     success,
+	}
+	for _, p := range []struct {
+		name  string
+		label string
+		dest  *int64
+	}{{"property_id", "property", &filter.PropertyID}, {"channel_id", "notification channel", &filter.ChannelID}} {
+		if v := c.Query(p.name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid " + p.label + " ID"})
+				return
 			}
+			*p.dest = id
 		}
-
-		if existingDevice != nil {
-			existingDevice.Name = mapping.Hostname
-			existingDevice.Tags = tags
-			// Fix monitoring settings if they're missing/invalid
-			if existingDevice.CheckInterval <= 0 {
-				existingDevice.CheckInterval = 60
-			}
-			if existingDevice.Retries <= 0 {
-				existingDevice.Retries = 3
-			}
-			if existingDevice.Timeout <= 0 {
-				existingDevice.Timeout = 10000
-			}
-			if err := s.postgres.UpdateDevice(context.Background(), existingDevice); err != nil {
-				errors = append(errors, fmt.Sprintf("Failed to update %s: %v", mapping.Hostname, err))
-				continue
-			}
-			updated++
-		} else {
-			device := &models.Device{
-				PropertyID:    propertyID,
-				Name:          mapping.Hostname,
-				Hostname:      mapping.IPAddr,
-				DeviceType:    deviceType,
-				Tags:          tags,
-				IsCritical:    deviceType == "Router",
-				Active:        true,
-				CheckInterval: 60,    // 60 seconds
-				Retries:       3,     // 3 retries
-				Timeout:       10000, // 10 seconds in milliseconds
-			}
-			if err := s.postgres.CreateDevice(context.Background(), device); err != nil {
-				errors = append(errors, fmt.Sprintf("Failed to create %s: %v", mapping.Hostname, err))
-				continue
+	}
+	for _, p := range []struct {
+		name string
+		dest *time.Time
+	}{{"start", &filter.Start}, {"end", &filter.End}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid " + p.name + " time, expected RFC3339"})
+				return
 			}
-			created++
+			*p.dest = t
 		}
 	}
 
-	response := map[string]interface{}{
-		"success": true,
-		"created": created,
-		"updated": updated,
-		"total":   len(mappings),
-	}
-	if len(errors) > 0 {
-		response["errors"] = errors
+	events, total, err := s.postgres.ListNotificationEventsPage(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
 	}
-	c.JSON(http.StatusOK, response)
+
+	writeList(c, events, total)
 }
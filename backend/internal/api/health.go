@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/monitor"
+)
+
+// readinessCheckTimeout bounds each dependency check, so a hung dependency fails the
+// probe instead of stalling it
+const readinessCheckTimeout = 2 * time.Second
+
+// handleLiveness answers as long as the process can serve requests. It checks no
+// dependencies, so an outage elsewhere doesn't get API pods restarted.
+func (s *Server) handleLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReadiness checks Postgres, Redis and file storage, returning 503 with the failing
+// dependency's error when any of them can't be reached. Worker heartbeats are
+// reported alongside but don't fail it: the API can serve without workers.
+func (s *Server) handleReadiness(c *gin.Context) {
+	checks := map[string]func(context.Context) error{
+		"postgres": s.postgres.Ping,
+		"redis":    s.redis.Ping,
+	}
+	if s.blobs != nil {
+		checks[s.blobs.Kind()] = s.blobs.Ping
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(gin.H, len(checks))
+	var failed []any
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			result := gin.H{"status": "ok", "duration_ms": time.Since(start).Milliseconds()}
+			if err != nil {
+				result["status"] = "error"
+				result["error"] = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			if err != nil {
+				failed = append(failed, name, err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	response := gin.H{"status": "ok", "checks": results}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+	defer cancel()
+	if workers, err := monitor.CheckWorkerHealth(ctx, s.redis, s.workerStaleAfter); err != nil {
+		response["workers"] = gin.H{"status": "unknown", "error": err.Error()}
+	} else {
+		response["workers"] = workers
+	}
+
+	status := http.StatusOK
+	if len(failed) > 0 {
+		response["status"] = "unavailable"
+		status = http.StatusServiceUnavailable
+		requestLog(c).Warn("Readiness check failed", failed...)
+	}
+	c.JSON(status, response)
+}
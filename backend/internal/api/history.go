@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// timeRange reads RFC3339 start/end query parameters, defaulting to the window of
+// length def ending now. Unparseable values fall back to the defaults.
+func timeRange(c *gin.Context, def time.Duration) (time.Time, time.Time) {
+	endTime := time.Now()
+	if endStr := c.Query("end"); endStr != "" {
+		if t, err := time.Parse(time.RFC3339, endStr); err == nil {
+			endTime = t
+		}
+	}
+
+	startTime := endTime.Add(-def)
+	if startStr := c.Query("start"); startStr != "" {
+		if t, err := time.Parse(time.RFC3339, startStr); err == nil {
+			startTime = t
+		}
+	}
+
+	return startTime, endTime
+}
+
+// rollupResolution picks hourly buckets for ranges up to two weeks and daily beyond
+func rollupResolution(startTime, endTime time.Time) string {
+	if endTime.Sub(startTime) <= 14*24*time.Hour {
+		return "hour"
+	}
+	return "day"
+}
+
+// maxHistoryBuckets caps how many buckets a downsampled history request may ask for
+const maxHistoryBuckets = 10000
+
+// getDeviceHistoryBuckets serves a device's history downsampled server-side:
+// ?interval= sets the bucket size (e.g. 5m, 1h or 1d, at least 1m) and ?aggregate= how
+// the response times in a bucket are combined (avg, min, max or p95; avg when unset)
+func (s *Server) getDeviceHistoryBuckets(c *gin.Context, id int64, startTime, endTime time.Time) {
+	interval, err := parseHistoryInterval(c.Query("interval"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if endTime.Sub(startTime)/interval > maxHistoryBuckets {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("interval is too short for the range (at most %d buckets)", maxHistoryBuckets)})
+		return
+	}
+
+	aggregate := c.DefaultQuery("aggregate", "avg")
+	if _, ok := storage.HistoryAggregates[aggregate]; !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "aggregate must be avg, min, max or p95"})
+		return
+	}
+
+	buckets, err := s.postgres.GetDeviceHistoryBuckets(c.Request.Context(), id, interval, aggregate, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, buckets)
+}
+
+// parseHistoryInterval parses a bucket size such as "30m", "1h" or "1d" into whole
+// seconds of at least a minute
+func parseHistoryInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("interval is required with aggregate")
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		if interval, err = parsePeriod(value); err != nil {
+			return 0, fmt.Errorf("invalid interval %q: use a duration such as 5m, 1h or 1d", value)
+		}
+	}
+	if interval < time.Minute || interval%time.Second != 0 {
+		return 0, fmt.Errorf("interval must be whole seconds and at least 1m")
+	}
+	return interval, nil
+}
+
+// handleGetDeviceHistoryRollups serves downsampled device history. Defaults to the
+// last 30 days; resolution is hour or day, chosen from the range when omitted.
+func (s *Server) handleGetDeviceHistoryRollups(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device ID"})
+		return
+	}
+
+	startTime, endTime := timeRange(c, 30*24*time.Hour)
+
+	resolution := c.Query("resolution")
+	switch resolution {
+	case "":
+		resolution = rollupResolution(startTime, endTime)
+	case "hour", "day":
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "resolution must be hour or day"})
+		return
+	}
+
+	rollups, err := s.postgres.GetDeviceHistoryRollups(c.Request.Context(), id, resolution, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rollups)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	// totalCountHeader reports how many items match a listing before limit and offset
+	totalCountHeader = "X-Total-Count"
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// listOptions reads the limit, offset and sort query parameters, checking sort against
+// the listing's sort fields. Without limit or offset the whole listing is returned.
+func listOptions(c *gin.Context, sorts map[string]string) (storage.ListOptions, bool) {
+	var opts storage.ListOptions
+
+	if v := c.Query("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "limit must be a positive integer"})
+			return opts, false
+		}
+		opts.Limit = min(l, maxPageLimit)
+	}
+
+	if v := c.Query("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "offset must be a non-negative integer"})
+			return opts, false
+		}
+		opts.Offset = o
+		if opts.Limit == 0 {
+			opts.Limit = defaultPageLimit
+		}
+	}
+
+	if v := c.Query("sort"); v != "" {
+		if _, ok := sorts[strings.TrimPrefix(v, "-")]; !ok {
+			fields := make([]string, 0, len(sorts))
+			for field := range sorts {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "sort must be one of " + strings.Join(fields, ", ") + ", optionally prefixed with -"})
+			return opts, false
+		}
+		opts.Sort = v
+	}
+
+	return opts, true
+}
+
+// boolQuery reads an optional true/false query parameter, nil when it is absent
+func boolQuery(c *gin.Context, name string) (*bool, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: name + " must be true or false"})
+		return nil, false
+	}
+	return &b, true
+}
+
+// paginate applies limit and offset to a listing filtered in memory
+func paginate[T any](items []T, opts storage.ListOptions) []T {
+	if opts.Limit == 0 {
+		return items
+	}
+	start := min(opts.Offset, len(items))
+	end := min(start+opts.Limit, len(items))
+	return items[start:end]
+}
+
+// writeList responds with one page of a listing and its total count
+func writeList[T any](c *gin.Context, items []T, total int) {
+	if items == nil {
+		items = []T{}
+	}
+	c.Header(totalCountHeader, strconv.Itoa(total))
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/logging"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestLogger gives every request a logger carrying its request ID, and logs the
+// request once it completes. The ID is taken from X-Request-ID when the client or
+// load balancer sets one and is echoed back in the response.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		args := []any{"request_id", requestID}
+		if strings.HasPrefix(c.FullPath(), "/api/v1/properties/:id") {
+			args = append(args, "property_id", c.Param("id"))
+		}
+		addLogFields(c, args...)
+
+		c.Next()
+
+		logger := requestLog(c)
+		level := slog.LevelInfo
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case isProbePath(c.FullPath()):
+			// Probes and scrapes would drown out everything else
+			level = slog.LevelDebug
+		}
+		logger.Log(c.Request.Context(), level, "Request completed",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// RecoveryLogger turns a panic in a handler into a 500 and logs it with the stack
+func RecoveryLogger() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		requestLog(c).Error("Panic handling request", "panic", fmt.Sprint(err), "stack", string(debug.Stack()))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// addLogFields adds fields to the request's logger for the rest of the request
+func addLogFields(c *gin.Context, args ...any) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(logging.WithLogger(ctx, logging.FromContext(ctx).With(args...)))
+}
+
+// requestLog returns the logger for the request
+func requestLog(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// isProbePath reports whether a route is polled by health probes or metric scrapes
+func isProbePath(route string) bool {
+	switch route {
+	case "/health", "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Maintenance Windows
+func (s *Server) handleListMaintenanceWindows(c *gin.Context) {
+	var windows []models.MaintenanceWindow
+	var err error
+	if c.Query("active") == "true" {
+		windows, err = s.postgres.ListCurrentMaintenanceWindows(c.Request.Context(), time.Now())
+	} else {
+		windows, err = s.postgres.ListMaintenanceWindows(c.Request.Context())
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, windows)
+}
+
+func (s *Server) handleListMaintenanceWindowsForProperty(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	windows, err := s.postgres.ListMaintenanceWindowsForProperty(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+func (s *Server) handleGetMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid maintenance window ID"})
+		return
+	}
+
+	window, err := s.postgres.GetMaintenanceWindow(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Maintenance window not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, window)
+}
+
+func (s *Server) handleCreateMaintenanceWindow(c *gin.Context) {
+	var window models.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateMaintenanceWindow(&window); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	username, _ := c.Get("username")
+	window.CreatedBy, _ = username.(string)
+
+	if err := s.postgres.CreateMaintenanceWindow(c.Request.Context(), &window); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+func (s *Server) handleUpdateMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid maintenance window ID"})
+		return
+	}
+
+	var window models.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateMaintenanceWindow(&window); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	window.ID = id
+	if err := s.postgres.UpdateMaintenanceWindow(c.Request.Context(), &window); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, window)
+}
+
+func (s *Server) handleDeleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid maintenance window ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteMaintenanceWindow(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window deleted"})
+}
+
+func validateMaintenanceWindow(w *models.MaintenanceWindow) error {
+	if w.PropertyID == nil && w.DeviceID == nil {
+		return fmt.Errorf("property_id or device_id is required")
+	}
+	if w.StartsAt.IsZero() || w.EndsAt.IsZero() {
+		return fmt.Errorf("starts_at and ends_at are required")
+	}
+	if !w.EndsAt.After(w.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	return nil
+}
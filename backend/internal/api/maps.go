@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/geocode"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Map view and geocoding
+
+// geocodeTimeout bounds the geocoding done while a property is saved, so a slow
+// geocoder only leaves the property off the map
+const geocodeTimeout = 5 * time.Second
+
+// handleGetMap returns the properties that have coordinates with their rollup status,
+// for a map of the sites. It takes the dashboard's region_id, group_id and tag filters.
+func (s *Server) handleGetMap(c *gin.Context) {
+	filter, ok := propertyFilter(c)
+	if !ok {
+		return
+	}
+
+	dashboard, err := s.buildDashboard(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result := models.PropertyMap{Properties: make([]models.PropertyWithStatus, 0, len(dashboard.Properties))}
+	for _, p := range dashboard.Properties {
+		if p.Latitude == nil || p.Longitude == nil {
+			result.Unlocated++
+			continue
+		}
+		result.Properties = append(result.Properties, p)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// validateCoordinates checks a property's coordinates are set together and in range
+func validateCoordinates(property *models.Property) error {
+	if (property.Latitude == nil) != (property.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+	if property.Latitude == nil {
+		return nil
+	}
+	if *property.Latitude < -90 || *property.Latitude > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if *property.Longitude < -180 || *property.Longitude > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// geocodeProperty fills in the coordinates of a property that has an address but no
+// coordinates. A property the geocoder can't place is saved without them.
+func (s *Server) geocodeProperty(c *gin.Context, property *models.Property) {
+	if s.geocoder == nil || property.Latitude != nil || property.Address == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), geocodeTimeout)
+	defer cancel()
+	location, err := s.geocoder.Geocode(ctx, property.Address)
+	if errors.Is(err, geocode.ErrNotFound) {
+		requestLog(c).Info("No coordinates found for property address", "address", property.Address)
+		return
+	}
+	if err != nil {
+		requestLog(c).Warn("Failed to geocode property address", "address", property.Address, "error", err)
+		return
+	}
+	property.Latitude, property.Longitude = &location.Latitude, &location.Longitude
+}
+
+// sameCoordinates reports whether two properties are at the same coordinates
+func sameCoordinates(a, b *models.Property) bool {
+	equal := func(x, y *float64) bool {
+		return x == nil && y == nil || x != nil && y != nil && *x == *y
+	}
+	return equal(a.Latitude, b.Latitude) && equal(a.Longitude, b.Longitude)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/metrics"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsMiddleware records the latency of every request by its route pattern
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+var (
+	devicesDesc = prometheus.NewDesc(
+		"ets_noc_devices",
+		"Devices by last check result, from the property rollups in Redis.",
+		[]string{"status"}, nil,
+	)
+	propertiesDesc = prometheus.NewDesc(
+		"ets_noc_properties",
+		"Properties by rollup status.",
+		[]string{"status"}, nil,
+	)
+)
+
+// fleetCollector reports device and property counts from Redis on each scrape, so
+// every API replica serves the same fleet-wide numbers
+type fleetCollector struct {
+	redis storage.StatusStore
+}
+
+func (f fleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- devicesDesc
+	ch <- propertiesDesc
+}
+
+func (f fleetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	statuses, err := f.redis.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		slog.Error("Failed to read property statuses for metrics", "error", err)
+		return
+	}
+
+	var online, offline, unreachable int
+	properties := map[string]int{"green": 0, "yellow": 0, "red": 0}
+	for _, status := range statuses {
+		online += status.OnlineCount
+		offline += status.OfflineCount
+		unreachable += status.UnreachableCount
+		properties[status.Status]++
+	}
+
+	ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(online), "online")
+	ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(offline), "offline")
+	ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(unreachable), "unreachable")
+	for status, count := range properties {
+		ch <- prometheus.MustNewConstMetric(propertiesDesc, prometheus.GaugeValue, float64(count), status)
+	}
+}
+
+// registerFleetMetrics adds the fleet gauges to /metrics; it is a no-op after the first call
+func (s *Server) registerFleetMetrics() {
+	if s.redis == nil {
+		return
+	}
+	err := prometheus.Register(fleetCollector{redis: s.redis})
+	var registered prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &registered) {
+		slog.Error("Failed to register fleet metrics", "error", err)
+	}
+}
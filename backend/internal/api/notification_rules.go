@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+)
+
+// Notification Rules
+func (s *Server) handleListNotificationRules(c *gin.Context) {
+	rules, err := s.postgres.ListNotificationRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func (s *Server) handleGetNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification rule ID"})
+		return
+	}
+
+	rule, err := s.postgres.GetNotificationRule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Notification rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) handleCreateNotificationRule(c *gin.Context) {
+	rule := models.NotificationRule{Enabled: true, NotifyOnRecovery: true, Priority: 100}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.validateNotificationRule(c.Request.Context(), &rule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreateNotificationRule(c.Request.Context(), &rule); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (s *Server) handleUpdateNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification rule ID"})
+		return
+	}
+
+	var rule models.NotificationRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.validateNotificationRule(c.Request.Context(), &rule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	rule.ID = id
+	if err := s.postgres.UpdateNotificationRule(c.Request.Context(), &rule); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) handleDeleteNotificationRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid notification rule ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteNotificationRule(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification rule deleted"})
+}
+
+func (s *Server) validateNotificationRule(ctx context.Context, rule *models.NotificationRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch rule.Severity {
+	case "", notifier.SeverityCritical, notifier.SeverityWarning:
+	default:
+		return fmt.Errorf("severity must be critical, warning or empty")
+	}
+	if _, err := s.postgres.GetNotificationChannel(ctx, rule.NotificationChannelID); err != nil {
+		return fmt.Errorf("notification channel %d not found", rule.NotificationChannelID)
+	}
+	if rule.Tags == nil {
+		rule.Tags = []string{}
+	}
+	if rule.DeviceTypes == nil {
+		rule.DeviceTypes = []string{}
+	}
+	if rule.PropertyTags == nil {
+		rule.PropertyTags = []string{}
+	}
+	return nil
+}
@@ -1,73 +1,96 @@
 package api
 
 import (
-	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
-var (
-	googleOauthConfig *oauth2.Config
-	oauthStateString  = "random-state-string" // In production, use a secure random state
+const (
+	// oauthStateCookie carries the state sent to Google so the callback can check it
+	// comes back unchanged, from the same browser
+	oauthStateCookie = "oauth_state"
+	// oauthStateMaxAge is how long a sign-in may take, in seconds
+	oauthStateMaxAge = 10 * 60
+	// googleStatePath scopes the state cookie to the Google sign-in routes
+	googleStatePath = "/api/v1/auth/google"
 )
 
-func initOAuthConfig() {
-	googleOauthConfig = &oauth2.Config{
-		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"), // e.g., https://status.etsusa.com/api/v1/auth/google/callback
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+// WithGoogleOAuth configures Google sign-in, which stays off without a client ID
+func WithGoogleOAuth(clientID, clientSecret, redirectURL string) ServerOption {
+	return func(s *Server) {
+		if clientID == "" {
+			s.googleOAuth = nil
+			return
+		}
+		s.googleOAuth = &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL, // e.g., https://status.etsusa.com/api/v1/auth/google/callback
+			Scopes: []string{
+				"https://www.googleapis.com/auth/userinfo.email",
+				"https://www.googleapis.com/auth/userinfo.profile",
+			},
+			Endpoint: google.Endpoint,
+		}
 	}
 }
 
 func (s *Server) handleGoogleLogin(c *gin.Context) {
-	if googleOauthConfig == nil {
-		initOAuthConfig()
+	if s.googleOAuth == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Google OAuth not configured"})
+		return
 	}
 
-	if googleOauthConfig.ClientID == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Google OAuth not configured"})
+	state, err := newOAuthState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
 		return
 	}
+	setOAuthStateCookie(c, googleStatePath, state, oauthStateMaxAge)
 
-	url := googleOauthConfig.AuthCodeURL(oauthStateString, oauth2.AccessTypeOffline)
+	url := s.googleOAuth.AuthCodeURL(state, oauth2.AccessTypeOffline)
 	c.Redirect(http.StatusTemporaryRedirect, url)
 }
 
 func (s *Server) handleGoogleCallback(c *gin.Context) {
-	if googleOauthConfig == nil {
-		initOAuthConfig()
+	if s.googleOAuth == nil {
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=settings_unavailable")
+		return
 	}
 
+	// The state is single use, so clear the cookie whatever happens next
+	expected, _ := c.Cookie(oauthStateCookie)
+	setOAuthStateCookie(c, googleStatePath, "", -1)
 	state := c.Query("state")
-	if state != oauthStateString {
-		fmt.Printf("OAuth callback error: Invalid state parameter\n")
+	if expected == "" || subtle.ConstantTimeCompare([]byte(state), []byte(expected)) != 1 {
+		requestLog(c).Warn("OAuth callback with invalid state parameter")
 		c.Redirect(http.StatusTemporaryRedirect, "/?error=invalid_state")
 		return
 	}
 
 	code := c.Query("code")
 	if code == "" {
-		fmt.Printf("OAuth callback error: Code not found\n")
+		requestLog(c).Warn("OAuth callback without a code")
 		c.Redirect(http.StatusTemporaryRedirect, "/?error=no_code")
 		return
 	}
 
-	token, err := googleOauthConfig.Exchange(context.Background(), code)
+	token, err := s.googleOAuth.Exchange(c.Request.Context(), code)
 	if err != nil {
-		fmt.Printf("OAuth callback error: Failed to exchange token: %v\n", err)
+		requestLog(c).Error("OAuth token exchange failed", "error", err)
 		c.Redirect(http.StatusTemporaryRedirect, "/?error=token_exchange_failed")
 		return
 	}
@@ -75,48 +98,109 @@ func (s *Server) handleGoogleCallback(c *gin.Context) {
 	// Get user info from Google
 	userInfo, err := getUserInfoFromGoogle(token.AccessToken)
 	if err != nil {
-		fmt.Printf("OAuth callback error: Failed to get user info: %v\n", err)
+		requestLog(c).Error("Failed to get Google user info", "error", err)
 		c.Redirect(http.StatusTemporaryRedirect, "/?error=userinfo_failed")
 		return
 	}
 
-	fmt.Printf("OAuth: Got user info for %s (%s)\n", userInfo.Email, userInfo.Name)
+	requestLog(c).Debug("Got Google user info", "email", userInfo.Email, "name", userInfo.Name)
+
+	settings, err := s.postgres.GetSettings(c.Request.Context())
+	if err != nil {
+		requestLog(c).Error("Failed to load OAuth settings", "error", err)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=settings_unavailable")
+		return
+	}
 
-	// Check if email domain is etsusa.com
-	if !strings.HasSuffix(userInfo.Email, "@etsusa.com") {
-		fmt.Printf("OAuth: Unauthorized domain for email: %s\n", userInfo.Email)
+	// Only verified addresses in an allowed domain may sign in
+	if !userInfo.VerifiedEmail || !emailDomainAllowed(userInfo.Email, settings.OAuthAllowedDomains) {
+		requestLog(c).Warn("OAuth login from unauthorized domain", "email", userInfo.Email)
 		c.Redirect(http.StatusTemporaryRedirect, "/?error=unauthorized_domain")
 		return
 	}
 
-	// Check if user exists, if not create them
-	user, err := s.postgres.GetUserByUsername(context.Background(), userInfo.Email)
+	identity := ssoIdentity{issuer: googleIssuer, subject: userInfo.ID}
+	s.completeSSOLogin(c, identity, userInfo.Email, userInfo.Name, settings.OAuthDefaultRole, false)
+}
+
+// googleIssuer is the issuer Google sign-in identities are linked under
+const googleIssuer = "https://accounts.google.com"
+
+// ssoIdentity is a user as a single sign-on provider knows them: the provider's issuer
+// and the stable subject it gives the user
+type ssoIdentity struct {
+	issuer  string
+	subject string
+}
+
+// completeSSOLogin signs in the user linked to a Google or OIDC identity, creating and
+// linking them with role on their first sign-in, and redirects to the frontend with a
+// token. With syncRole, an existing user's role is brought in line with role as well.
+// An unlinked account that already has the email as its username is refused rather
+// than taken over, since it signs in with its own password and two-factor codes.
+func (s *Server) completeSSOLogin(c *gin.Context, identity ssoIdentity, email, name, role string, syncRole bool) {
+	ctx := c.Request.Context()
+	if identity.subject == "" {
+		requestLog(c).Warn("SSO login without a subject", "issuer", identity.issuer, "email", email)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=userinfo_failed")
+		return
+	}
+
+	user, err := s.postgres.GetUserBySSOIdentity(ctx, identity.issuer, identity.subject)
 	if err != nil {
-		// User doesn't exist, create them
-		fmt.Printf("OAuth: Creating new user for %s\n", userInfo.Email)
-		user, err = s.postgres.CreateUserFromOAuth(context.Background(), userInfo.Email, userInfo.Name)
+		if _, err := s.postgres.GetUserByUsername(ctx, email); err == nil {
+			requestLog(c).Warn("SSO login for an existing local account", "issuer", identity.issuer, "email", email)
+			c.Redirect(http.StatusTemporaryRedirect, "/?error=account_exists")
+			return
+		}
+
+		requestLog(c).Info("Creating user from OAuth login", "email", email, "role", role)
+		err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+			var err error
+			if user, err = tx.CreateUserFromOAuth(ctx, email, name, role); err != nil {
+				return err
+			}
+			return tx.LinkSSOIdentity(ctx, user.ID, identity.issuer, identity.subject)
+		})
 		if err != nil {
-			fmt.Printf("OAuth callback error: Failed to create user: %v\n", err)
+			requestLog(c).Error("Failed to create OAuth user", "email", email, "error", err)
 			c.Redirect(http.StatusTemporaryRedirect, "/?error=user_creation_failed")
 			return
 		}
+	} else if !user.Active {
+		requestLog(c).Warn("OAuth login for disabled account", "user_id", user.ID, "email", email)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=account_disabled")
+		return
+	} else if syncRole && user.Role != role {
+		requestLog(c).Info("Updating role from OAuth login", "user_id", user.ID, "old_role", user.Role, "role", role)
+		user.Role = role
+		if err := s.postgres.UpdateUser(ctx, user); err != nil {
+			requestLog(c).Error("Failed to update OAuth user role", "user_id", user.ID, "error", err)
+			c.Redirect(http.StatusTemporaryRedirect, "/?error=user_update_failed")
+			return
+		}
+		// Sessions signed with the old role end with it
+		if _, err := s.postgres.RevokeUserSessions(ctx, user.ID, 0); err != nil {
+			requestLog(c).Error("Failed to revoke sessions after role change", "user_id", user.ID, "error", err)
+			c.Redirect(http.StatusTemporaryRedirect, "/?error=user_update_failed")
+			return
+		}
 	}
 
 	// Generate JWT token
-	jwtToken, err := generateToken(user)
+	jwtToken, err := s.startSession(c, user)
 	if err != nil {
-		fmt.Printf("OAuth callback error: Failed to generate token: %v\n", err)
+		requestLog(c).Error("Failed to generate token", "user_id", user.ID, "error", err)
 		c.Redirect(http.StatusTemporaryRedirect, "/?error=token_generation_failed")
 		return
 	}
 
-	fmt.Printf("OAuth: Successfully authenticated user %s, redirecting with token\n", userInfo.Email)
+	requestLog(c).Info("OAuth login succeeded", "user_id", user.ID, "email", email)
 
 	// Redirect to login page with token - login page will handle auth setup
 	host := c.Request.Host
 	redirectURL := fmt.Sprintf("https://%s/login?token=%s", host, jwtToken)
 
-	fmt.Printf("OAuth: Redirecting to: %s\n", redirectURL)
 	// Redirect to frontend with token
 	c.Redirect(http.StatusTemporaryRedirect, redirectURL)
 }
@@ -154,3 +238,66 @@ func getUserInfoFromGoogle(accessToken string) (*GoogleUserInfo, error) {
 
 	return &userInfo, nil
 }
+
+// newOAuthState returns a random state value for one sign-in
+func newOAuthState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// setOAuthStateCookie sets the state cookie for the sign-in routes under path, or
+// clears it when maxAge is negative. It has to be Lax rather than Strict so the browser
+// sends it on the redirect back from the provider.
+func setOAuthStateCookie(c *gin.Context, path, state string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// emailDomainAllowed reports whether email is in one of the allowed domains
+func emailDomainAllowed(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	return slices.Contains(domains, strings.ToLower(email[at+1:]))
+}
+
+// normalizeEmailDomains lowercases a list of email domains, dropping any leading @ and
+// duplicates
+func normalizeEmailDomains(domains []string) ([]string, error) {
+	normalized := []string{}
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain == "" || strings.ContainsAny(domain, "@/ \t") || !strings.Contains(domain, ".") {
+			return nil, fmt.Errorf("invalid domain %q", domain)
+		}
+		if !slices.Contains(normalized, domain) {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized, nil
+}
+
+// keepOAuthSettings fills in the Google and OIDC sign-in settings an update left out
+// from the current settings, so older clients and config exports don't reset them
+func keepOAuthSettings(settings, current *models.Settings) {
+	if settings.OIDC == nil {
+		settings.OIDC = current.OIDC
+	}
+	if settings.OAuthAllowedDomains == nil {
+		settings.OAuthAllowedDomains = current.OAuthAllowedDomains
+	}
+	if settings.OAuthDefaultRole == "" {
+		settings.OAuthDefaultRole = current.OAuthDefaultRole
+	}
+}
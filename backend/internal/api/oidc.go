@@ -0,0 +1,248 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Single sign-on through a generic OpenID Connect provider, configured in settings
+
+// oidcStatePath scopes the state cookie to the OIDC sign-in routes
+const oidcStatePath = "/api/v1/auth/oidc"
+
+// handleAuthProviders tells the login page which single sign-on buttons to show
+func (s *Server) handleAuthProviders(c *gin.Context) {
+	providers := gin.H{"google": s.googleOAuth != nil}
+	settings, err := s.postgres.GetSettings(c.Request.Context())
+	if err == nil && settings.OIDC.Enabled {
+		providers["oidc"] = gin.H{"name": settings.OIDC.Name}
+	}
+	c.JSON(http.StatusOK, providers)
+}
+
+// handleOIDCLogin sends the browser to the provider, keeping the state, nonce and PKCE
+// verifier for the callback in a cookie
+func (s *Server) handleOIDCLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+	settings, err := s.postgres.GetSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !settings.OIDC.Enabled {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "OIDC sign-in not configured"})
+		return
+	}
+
+	provider, err := oidc.Discover(ctx, settings.OIDC.Issuer)
+	if err != nil {
+		requestLog(c).Error("OIDC discovery failed", "issuer", settings.OIDC.Issuer, "error", err)
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	state, err := newOAuthState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	nonce, err := newOAuthState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+	setOAuthStateCookie(c, oidcStatePath, state+"."+nonce+"."+verifier, oauthStateMaxAge)
+
+	config := oidcConfig(c, settings.OIDC, provider)
+	url := config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce))
+	c.Redirect(http.StatusTemporaryRedirect, url)
+}
+
+// handleOIDCCallback finishes sign-in: it exchanges the code, verifies the ID token and
+// signs the user in with the role their claims map to
+func (s *Server) handleOIDCCallback(c *gin.Context) {
+	// The state is single use, so clear the cookie whatever happens next
+	cookie, _ := c.Cookie(oauthStateCookie)
+	setOAuthStateCookie(c, oidcStatePath, "", -1)
+	parts := strings.Split(cookie, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(parts[0])) != 1 {
+		requestLog(c).Warn("OIDC callback with invalid state parameter")
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=invalid_state")
+		return
+	}
+	nonce, verifier := parts[1], parts[2]
+
+	if providerError := c.Query("error"); providerError != "" {
+		requestLog(c).Warn("OIDC provider returned an error", "error", providerError, "description", c.Query("error_description"))
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=provider_error")
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		requestLog(c).Warn("OIDC callback without a code")
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=no_code")
+		return
+	}
+
+	ctx := c.Request.Context()
+	settings, err := s.postgres.GetSettings(ctx)
+	if err != nil || !settings.OIDC.Enabled {
+		requestLog(c).Error("OIDC callback while sign-in is not configured", "error", err)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=settings_unavailable")
+		return
+	}
+	provider, err := oidc.Discover(ctx, settings.OIDC.Issuer)
+	if err != nil {
+		requestLog(c).Error("OIDC discovery failed", "issuer", settings.OIDC.Issuer, "error", err)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=provider_unavailable")
+		return
+	}
+
+	config := oidcConfig(c, settings.OIDC, provider)
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		requestLog(c).Error("OIDC token exchange failed", "error", err)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=token_exchange_failed")
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		requestLog(c).Error("OIDC token response without an ID token")
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=token_exchange_failed")
+		return
+	}
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, settings.OIDC.ClientID, nonce)
+	if err != nil {
+		requestLog(c).Warn("OIDC ID token rejected", "error", err)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=invalid_token")
+		return
+	}
+
+	// Some providers only put the address in the userinfo response
+	email := claims.String("email")
+	if email == "" && provider.UserInfoURL != "" {
+		if info, err := provider.UserInfo(ctx, token); err == nil {
+			for name, value := range info {
+				if _, ok := claims[name]; !ok {
+					claims[name] = value
+				}
+			}
+			email = claims.String("email")
+		}
+	}
+	// Only verified addresses in an allowed domain may sign in, as with Google
+	if email == "" || claims["email_verified"] != true {
+		requestLog(c).Warn("OIDC login without a verified email", "subject", claims.String("sub"))
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=no_email")
+		return
+	}
+	if !emailDomainAllowed(email, settings.OAuthAllowedDomains) {
+		requestLog(c).Warn("OIDC login from unauthorized domain", "email", email)
+		c.Redirect(http.StatusTemporaryRedirect, "/?error=unauthorized_domain")
+		return
+	}
+	role, mapped := mapOIDCRole(claims, settings.OIDC)
+	if !mapped {
+		role = settings.OAuthDefaultRole
+	}
+	requestLog(c).Debug("Got OIDC claims", "email", email, "role", role, "mapped", mapped)
+	identity := ssoIdentity{issuer: settings.OIDC.Issuer, subject: claims.String("sub")}
+	s.completeSSOLogin(c, identity, strings.ToLower(email), claims.String("name"), role, mapped)
+}
+
+// oidcConfig returns the OAuth2 client config for the provider. The callback URL is
+// derived from the request host, as the Google callback's redirect is.
+func oidcConfig(c *gin.Context, settings *models.OIDCSettings, provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     settings.ClientID,
+		ClientSecret: settings.ClientSecret,
+		RedirectURL:  fmt.Sprintf("https://%s%s/callback", c.Request.Host, oidcStatePath),
+		Scopes:       settings.Scopes,
+		Endpoint:     provider.Endpoint(),
+	}
+}
+
+// mapOIDCRole picks the role for the values of the configured role claim, taking the
+// most privileged one when several match. It reports false when nothing matched.
+func mapOIDCRole(claims oidc.Claims, settings *models.OIDCSettings) (string, bool) {
+	if settings.RoleClaim == "" {
+		return "", false
+	}
+	best := ""
+	for _, value := range claims.Strings(settings.RoleClaim) {
+		role, ok := settings.RoleMapping[value]
+		if ok && roleRank(role) > roleRank(best) {
+			best = role
+		}
+	}
+	return best, best != ""
+}
+
+// roleRank orders roles by privilege
+func roleRank(role string) int {
+	switch role {
+	case "admin":
+		return 3
+	case "user":
+		return 2
+	case "viewer":
+		return 1
+	}
+	return 0
+}
+
+// validateOIDCSettings checks the OIDC provider settings, normalizing the scopes so
+// they always include openid
+func validateOIDCSettings(settings *models.OIDCSettings) error {
+	settings.Name = strings.TrimSpace(settings.Name)
+	settings.Issuer = strings.TrimSuffix(strings.TrimSpace(settings.Issuer), "/")
+	settings.ClientID = strings.TrimSpace(settings.ClientID)
+	settings.RoleClaim = strings.TrimSpace(settings.RoleClaim)
+
+	if settings.Enabled {
+		if settings.Name == "" {
+			settings.Name = "SSO"
+		}
+		if settings.Issuer == "" || settings.ClientID == "" {
+			return fmt.Errorf("issuer and client_id are required")
+		}
+	}
+	if settings.Issuer != "" {
+		u, err := url.Parse(settings.Issuer)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("issuer must be an https URL")
+		}
+	}
+
+	scopes := []string{"openid"}
+	for _, scope := range settings.Scopes {
+		scope = strings.TrimSpace(scope)
+		if scope != "" && !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	settings.Scopes = scopes
+
+	for value, role := range settings.RoleMapping {
+		if value == "" {
+			return fmt.Errorf("role_mapping keys must not be empty")
+		}
+		if !validRole(role) {
+			return fmt.Errorf("role_mapping %q: role must be admin, user or viewer", value)
+		}
+	}
+	if len(settings.RoleMapping) > 0 && settings.RoleClaim == "" {
+		return fmt.Errorf("role_claim is required with a role_mapping")
+	}
+	return nil
+}
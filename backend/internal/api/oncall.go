@@ -0,0 +1,224 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/oncall"
+)
+
+// On-Call
+func (s *Server) handleGetCurrentOnCall(c *gin.Context) {
+	now := time.Now()
+
+	if v := c.Query("schedule_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid schedule ID"})
+			return
+		}
+		shift, err := oncall.Resolve(c.Request.Context(), s.postgres, id, now)
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, []models.OnCallShift{*shift})
+		return
+	}
+
+	schedules, err := s.postgres.ListOnCallSchedules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	// Schedules without anyone to put on call are left out
+	shifts := make([]models.OnCallShift, 0, len(schedules))
+	for i := range schedules {
+		shift, err := oncall.ResolveSchedule(c.Request.Context(), s.postgres, &schedules[i], now)
+		if err != nil {
+			continue
+		}
+		shifts = append(shifts, *shift)
+	}
+
+	c.JSON(http.StatusOK, shifts)
+}
+
+func (s *Server) handleListOnCallSchedules(c *gin.Context) {
+	schedules, err := s.postgres.ListOnCallSchedules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (s *Server) handleGetOnCallSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	schedule, err := s.postgres.GetOnCallSchedule(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "On-call schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (s *Server) handleCreateOnCallSchedule(c *gin.Context) {
+	var schedule models.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateOnCallSchedule(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.CreateOnCallSchedule(c.Request.Context(), &schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (s *Server) handleUpdateOnCallSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	var schedule models.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateOnCallSchedule(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	schedule.ID = id
+	if err := s.postgres.UpdateOnCallSchedule(c.Request.Context(), &schedule); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (s *Server) handleDeleteOnCallSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteOnCallSchedule(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "On-call schedule deleted"})
+}
+
+func (s *Server) handleListOnCallOverrides(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	overrides, err := s.postgres.ListOnCallOverrides(c.Request.Context(), id, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+func (s *Server) handleCreateOnCallOverride(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid schedule ID"})
+		return
+	}
+
+	var override models.OnCallOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	override.ScheduleID = id
+
+	if override.UserID == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "user_id is required"})
+		return
+	}
+	if override.StartsAt.IsZero() || !override.EndsAt.After(override.StartsAt) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "starts_at and ends_at are required and ends_at must be after starts_at"})
+		return
+	}
+
+	if err := s.postgres.CreateOnCallOverride(c.Request.Context(), &override); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, override)
+}
+
+func (s *Server) handleDeleteOnCallOverride(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid override ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteOnCallOverride(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "On-call override deleted"})
+}
+
+func validateOnCallSchedule(schedule *models.OnCallSchedule) error {
+	if schedule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", schedule.Timezone)
+	}
+	if schedule.RotationStart.IsZero() {
+		return fmt.Errorf("rotation_start is required")
+	}
+	if schedule.RotationDays == 0 {
+		schedule.RotationDays = 7
+	}
+	if schedule.RotationDays < 0 {
+		return fmt.Errorf("rotation_days must be positive")
+	}
+	if schedule.MemberIDs == nil {
+		schedule.MemberIDs = []int64{}
+	}
+	return nil
+}
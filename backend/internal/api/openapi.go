@@ -0,0 +1,471 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const (
+	openAPIPath = "/api/openapi.json"
+	apiDocsPath = "/api/docs"
+
+	// swaggerUIVersion pins the Swagger UI assets loaded by the docs page
+	swaggerUIVersion = "5.17.14"
+)
+
+// The OpenAPI document is built from the registered routes, so every route appears in
+// it with its method, path and auth scheme. What the router can't know, the request
+// and response bodies and query parameters, comes from the routeDocs annotations
+// keyed by handler name; see openapi_routes.go.
+
+// routeDoc annotates the routes served by one handler, or by an anonymous handler
+// when keyed by method and path
+type routeDoc struct {
+	// Summary and OperationID override the ones derived from the handler name
+	Summary     string
+	OperationID string
+	Description string
+	// Request is a value of the JSON request body type, nil when there is none
+	Request any
+	// Upload marks a multipart/form-data body with a file field
+	Upload bool
+	// Binary marks a raw application/octet-stream body
+	Binary bool
+	// Response is a value of the JSON response body type
+	Response any
+	// ContentType of the response when it isn't JSON
+	ContentType string
+	// Status of a successful response, 200 when unset
+	Status int
+	Query  []queryParam
+	// List marks listings paged with limit and offset and counted in X-Total-Count
+	List  bool
+	Admin bool
+}
+
+// queryParam documents a query parameter; Type is an OpenAPI type, string when unset
+type queryParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// messageResponse is the body of responses that only confirm an action
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// urlResponse is the body of download responses: a short-lived signed URL
+type urlResponse struct {
+	URL string `json:"url"`
+}
+
+// revokedResponse reports how many sessions were revoked
+type revokedResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// handleOpenAPISpec serves the OpenAPI document built by SetupRouter
+func (s *Server) handleOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.openAPISpec)
+}
+
+// handleAPIDocs serves Swagger UI for the OpenAPI document
+func handleAPIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(apiDocsPage))
+}
+
+var apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>ETS NOC API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "` + openAPIPath + `", dom_id: "#swagger-ui", persistAuthorization: true });
+  </script>
+</body>
+</html>
+`
+
+// buildOpenAPISpec describes routes as an OpenAPI 3 document. Routes whose handler
+// has no annotation are still listed, and logged so they get one.
+func buildOpenAPISpec(routes gin.RoutesInfo) ([]byte, error) {
+	schemas := newSchemaRegistry()
+	paths := make(map[string]map[string]any)
+
+	for _, route := range routes {
+		if route.Path == openAPIPath || route.Path == apiDocsPath {
+			continue
+		}
+		handler := handlerName(route.Handler)
+		doc, ok := routeDocs[handler]
+		if !ok {
+			// Anonymous handlers, such as wrapped http.Handlers, are annotated by route
+			doc, ok = routeDocs[route.Method+" "+route.Path]
+		}
+		if !ok {
+			slog.Warn("Route has no OpenAPI annotation", "method", route.Method, "path", route.Path, "handler", handler)
+		}
+
+		path, params := openAPIPathParams(route.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(route.Method)] = buildOperation(route, handler, doc, params, schemas)
+	}
+
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "ETS NOC API",
+			"version": "1",
+			"description": "Monitoring API for properties, their devices and pfSense firewalls. " +
+				"Sign in with POST /api/v1/auth/login and send the token as a bearer token. " +
+				"Viewers may only read; routes marked admin need the admin role. " +
+				"Requests over the rate limits get 429 with a Retry-After header.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.defs,
+			"securitySchemes": map[string]any{
+				"session": map[string]any{
+					"type": "http", "scheme": "bearer", "bearerFormat": "JWT",
+					"description": "Session token from /api/v1/auth/login or a sign-in callback",
+				},
+				"streamToken": map[string]any{
+					"type": "apiKey", "in": "query", "name": "token",
+					"description": "Session token for browsers that can't set headers on websockets and event streams",
+				},
+				"probeToken": map[string]any{
+					"type": "http", "scheme": "bearer",
+					"description": "Token returned when the probe was created",
+				},
+			},
+		},
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+func buildOperation(route gin.RouteInfo, handler string, doc routeDoc, params []any, schemas *schemaRegistry) map[string]any {
+	security := routeSecurity(route.Path)
+	summary := doc.Summary
+	if summary == "" {
+		summary = handlerSummary(handler)
+	}
+
+	operationID := doc.OperationID
+	if operationID == "" {
+		operationID = strings.TrimPrefix(handler, "handle")
+		operationID = strings.ToLower(operationID[:1]) + operationID[1:]
+	}
+	op := map[string]any{
+		"operationId": operationID,
+		"summary":     summary,
+		"tags":        []string{routeTag(route.Path)},
+	}
+	description := doc.Description
+	if doc.Admin {
+		description = strings.TrimSpace("Admin only. " + description)
+	}
+	if description != "" {
+		op["description"] = description
+	}
+
+	if doc.List {
+		params = append(params, queryParameter(queryParam{"limit", "integer", "page size, at most 1000"}),
+			queryParameter(queryParam{"offset", "integer", "items to skip; implies limit=100 when limit is unset"}),
+			queryParameter(queryParam{"sort", "string", "field to sort by, prefixed with - for descending"}))
+	}
+	for _, q := range doc.Query {
+		params = append(params, queryParameter(q))
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	switch {
+	case doc.Upload:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{"multipart/form-data": map[string]any{"schema": map[string]any{
+				"type":     "object",
+				"required": []string{"file"},
+				"properties": map[string]any{
+					"file":        map[string]any{"type": "string", "format": "binary"},
+					"description": map[string]any{"type": "string"},
+				},
+			}}},
+		}
+	case doc.Binary:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{"application/octet-stream": map[string]any{"schema": map[string]any{
+				"type": "string", "format": "binary",
+			}}},
+		}
+	case doc.Request != nil:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(doc.Request))}},
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	switch {
+	case doc.ContentType != "":
+		success["content"] = map[string]any{doc.ContentType: map[string]any{"schema": map[string]any{"type": "string"}}}
+	case doc.Response != nil:
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(doc.Response))}}
+	}
+	if doc.List {
+		success["headers"] = map[string]any{totalCountHeader: map[string]any{
+			"description": "items matching the listing before limit and offset",
+			"schema":      map[string]any{"type": "integer"},
+		}}
+	}
+
+	errorBody := map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(models.ErrorResponse{}))}}
+	responses := map[string]any{
+		strconv.Itoa(status): success,
+		"default":            map[string]any{"description": "Error", "content": errorBody},
+	}
+	if security != nil {
+		responses["401"] = map[string]any{"description": "Missing, invalid or revoked token", "content": errorBody}
+		op["security"] = security
+	} else {
+		op["security"] = []any{}
+	}
+	if strings.HasPrefix(route.Path, "/api/v1/") && !strings.HasPrefix(route.Path, "/api/v1/probe/") {
+		responses["429"] = map[string]any{
+			"description": "Rate limit exceeded",
+			"headers":     map[string]any{"Retry-After": map[string]any{"schema": map[string]any{"type": "integer"}}},
+			"content":     errorBody,
+		}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// routeSecurity returns the auth schemes a path accepts, nil for public paths
+func routeSecurity(path string) []any {
+	switch {
+	case !strings.HasPrefix(path, "/api/v1/"):
+		return nil
+	case path == "/api/v1/auth/login", path == "/api/v1/auth/providers",
+		strings.HasPrefix(path, "/api/v1/auth/google"), strings.HasPrefix(path, "/api/v1/auth/oidc"),
+		strings.HasPrefix(path, "/api/v1/status-pages/"), strings.HasPrefix(path, "/api/v1/public/"):
+		return nil
+	case strings.HasPrefix(path, "/api/v1/probe/"):
+		return []any{map[string]any{"probeToken": []string{}}}
+	case strings.HasPrefix(path, "/api/v1/ws/"), strings.HasPrefix(path, "/api/v1/stream/"):
+		return []any{map[string]any{"session": []string{}}, map[string]any{"streamToken": []string{}}}
+	}
+	return []any{map[string]any{"session": []string{}}}
+}
+
+// routeTag groups a path by its first segment under /api/v1
+func routeTag(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/v1/")
+	if !ok {
+		return "health"
+	}
+	tag, _, _ := strings.Cut(rest, "/")
+	return tag
+}
+
+// openAPIPathParams turns gin's :name parameters into OpenAPI {name} ones
+func openAPIPathParams(path string) (string, []any) {
+	var params []any
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		schema := map[string]any{"type": "string"}
+		if name == "id" {
+			schema = map[string]any{"type": "integer", "format": "int64"}
+		}
+		params = append(params, map[string]any{"name": name, "in": "path", "required": true, "schema": schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func queryParameter(q queryParam) map[string]any {
+	typ := q.Type
+	if typ == "" {
+		typ = "string"
+	}
+	param := map[string]any{"name": q.Name, "in": "query", "schema": map[string]any{"type": typ}}
+	if q.Description != "" {
+		param["description"] = q.Description
+	}
+	return param
+}
+
+// handlerName trims a handler's function name, as gin reports it, to the method name
+func handlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// handlerSummary turns a handler name such as handleListDeviceTemplates into
+// "List device templates", keeping acronyms such as ARP in capitals
+func handlerSummary(handler string) string {
+	var words []string
+	runes := []rune(strings.TrimPrefix(handler, "handle"))
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !(unicode.IsUpper(runes[i]) && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1])))) {
+			continue
+		}
+		word := string(runes[start:i])
+		if len(words) > 0 && strings.ToUpper(word) != word {
+			word = strings.ToLower(word)
+		}
+		words = append(words, word)
+		start = i
+	}
+	return strings.Join(words, " ")
+}
+
+// schemaRegistry derives JSON schemas from Go types, following encoding/json's rules
+// for field names, and collects named struct types as components
+type schemaRegistry struct {
+	defs  map[string]any
+	types map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{defs: make(map[string]any), types: make(map[reflect.Type]string)}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (r *schemaRegistry) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := r.schema(t.Elem())
+		if _, isRef := s["$ref"]; isRef {
+			return map[string]any{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": r.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + r.define(t)}
+	}
+	return map[string]any{}
+}
+
+// define registers a named struct type, qualifying its name with its package when
+// another package has a type of the same name
+func (r *schemaRegistry) define(t reflect.Type) string {
+	if name, ok := r.types[t]; ok {
+		return name
+	}
+	name := exportedName(t.Name())
+	if _, taken := r.defs[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = exportedName(pkg) + name
+	}
+	r.types[t] = name
+	r.defs[name] = map[string]any{} // placeholder so recursive types terminate
+	r.defs[name] = r.structSchema(t)
+	return name
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	r.addFields(t, properties, &required)
+
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+// addFields adds t's JSON fields to properties, flattening embedded structs as
+// encoding/json does. Fields with binding:"required" are required.
+func (r *schemaRegistry) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			r.addFields(f.Type, properties, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = r.schema(f.Type)
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,412 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+)
+
+// Response bodies that handlers build with gin.H, spelled out for the OpenAPI document
+
+type healthResponse struct {
+	Status  string               `json:"status"`
+	Workers *models.WorkerHealth `json:"workers,omitempty"`
+}
+
+type readinessCheck struct {
+	Status     string `json:"status"` // ok or error
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status  string                    `json:"status"` // ok or unavailable
+	Checks  map[string]readinessCheck `json:"checks"`
+	Workers *models.WorkerHealth      `json:"workers"`
+}
+
+type authProvidersResponse struct {
+	Google bool `json:"google"`
+	OIDC   *struct {
+		Name string `json:"name"`
+	} `json:"oidc,omitempty"`
+}
+
+type totpStatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type totpEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type recoveryCodesResponse struct {
+	Enabled       bool     `json:"enabled,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type cloneResponse struct {
+	Property      models.Property               `json:"property"`
+	SourceID      int64                         `json:"source_id"`
+	Devices       []models.Device               `json:"devices"`
+	Contacts      []models.Contact              `json:"contacts"`
+	Notifications []models.PropertyNotification `json:"notifications"`
+}
+
+type carpStatusResponse struct {
+	FirewallID        int64             `json:"firewall_id"`
+	ExpectedCARPState string            `json:"expected_carp_state"`
+	Healthy           bool              `json:"healthy"`
+	Detail            string            `json:"detail"`
+	VIPs              []pfsense.CARPVIP `json:"vips"`
+}
+
+// syncResponse is a dry run's plan and confirm_token, or an applied sync's counts
+type syncResponse struct {
+	DryRun       bool           `json:"dry_run,omitempty"`
+	Plan         *syncPlan      `json:"plan,omitempty"`
+	ConfirmToken string         `json:"confirm_token,omitempty"`
+	Success      bool           `json:"success,omitempty"`
+	Created      int            `json:"created,omitempty"`
+	Updated      int            `json:"updated,omitempty"`
+	Deactivated  int            `json:"deactivated,omitempty"`
+	Unchanged    int            `json:"unchanged,omitempty"`
+	Total        int            `json:"total"`
+	Interfaces   map[string]int `json:"interfaces"`
+	Errors       []string       `json:"errors,omitempty"`
+}
+
+type probeResultsResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+var (
+	propertyFilterQuery = []queryParam{
+		{"group_id", "integer", "only properties in this group"},
+		{"region_id", "integer", "only properties in this region's groups"},
+		{"tag", "string", "only properties with this tag"},
+	}
+	deviceFilterQuery = []queryParam{
+		{"type", "string", "device type"},
+		{"tag", "string", "only devices with this tag"},
+		{"active", "boolean", ""},
+		{"status", "string", "online, offline, unreachable or unknown"},
+		{"manufacturer", "string", "case-insensitive"},
+		{"model", "string", "case-insensitive"},
+	}
+	timeRangeQuery = []queryParam{
+		{"start", "string", "RFC 3339 time"},
+		{"end", "string", "RFC 3339 time, now when unset"},
+	}
+	historyQuery = append([]queryParam{
+		{"interval", "string", "bucket size such as 5m, 1h or 1d, at least 1m; raw checks when unset"},
+		{"aggregate", "string", "avg, min, max or p95 of the response times of successful checks in a bucket; avg when unset"},
+	}, timeRangeQuery...)
+	availabilityQuery = append([]queryParam{
+		{"resolution", "string", "day or hour; day when unset"},
+		{"tz", "string", "IANA time zone the buckets are aligned to; UTC when unset"},
+	}, timeRangeQuery...)
+	uptimeQuery   = []queryParam{{"period", "string", "a number of hours, days or weeks such as 24h, 30d or 4w; 30d when unset"}}
+	dryRunQuery   = queryParam{"dry_run", "boolean", "report what would change without applying it"}
+	propertyQuery = queryParam{"property_id", "integer", ""}
+)
+
+// routeDocs annotates every handler registered by SetupRouter
+var routeDocs = map[string]routeDoc{
+	// Health
+	"handleHealth":    {Response: healthResponse{}, Description: "Kept for older probes; use /healthz and /readyz."},
+	"handleLiveness":  {Summary: "Liveness probe", Response: healthResponse{}},
+	"handleReadiness": {Summary: "Readiness probe", Response: readinessResponse{}, Description: "Checks Postgres, Redis and GCS; 503 when any is unreachable."},
+	"GET /metrics":    {Summary: "Prometheus metrics", OperationID: "metrics", ContentType: "text/plain"},
+
+	// Sign-in and account
+	"handleLogin": {Request: models.LoginRequest{}, Response: models.LoginResponse{},
+		Description: "Accounts with two-factor authentication also need totp_code; without it the 401 response has totp_required set."},
+	"handleGoogleLogin":         {Summary: "Start Google sign-in", Description: "Redirects to Google."},
+	"handleGoogleCallback":      {Summary: "Google sign-in callback", Description: "Redirects to the app with a session token."},
+	"handleOIDCLogin":           {Summary: "Start OIDC sign-in", Description: "Redirects to the OpenID Connect provider."},
+	"handleOIDCCallback":        {Summary: "OIDC sign-in callback", Description: "Redirects to the app with a session token."},
+	"handleAuthProviders":       {Summary: "List single sign-on providers", Response: authProvidersResponse{}},
+	"handleGetMe":               {Summary: "Get the signed-in user", Response: models.User{}},
+	"handleChangePassword":      {Request: models.ChangePasswordRequest{}, Response: messageResponse{}},
+	"handleLogout":              {Summary: "Sign out", Response: messageResponse{}, Description: "Revokes the session making the request."},
+	"handleListSessions":        {Response: []models.UserSession{}},
+	"handleRevokeOtherSessions": {Response: revokedResponse{}},
+	"handleRevokeSession":       {Response: messageResponse{}},
+	"handleGetTOTPStatus":       {Summary: "Get two-factor status", Response: totpStatusResponse{}},
+	"handleEnrollTOTP":          {Summary: "Start two-factor enrollment", Response: totpEnrollResponse{}},
+	"handleVerifyTOTP": {Summary: "Finish two-factor enrollment", Request: totpCodeRequest{}, Response: recoveryCodesResponse{},
+		Description: "Turns two-factor on and returns the recovery codes."},
+	"handleRegenerateRecoveryCodes": {Request: totpCodeRequest{}, Response: recoveryCodesResponse{}},
+	"handleDisableTOTP":             {Summary: "Turn off two-factor", Request: totpCodeRequest{}, Response: totpStatusResponse{}},
+
+	// Live updates
+	"handleDashboardWebSocket": {Summary: "Dashboard updates websocket", Status: http.StatusSwitchingProtocols, Query: propertyFilterQuery,
+		Description: "Sends a snapshot DashboardUpdate, then a property_status update on each change."},
+	"handleDeviceStatusStream": {Summary: "Stream device status changes", ContentType: "text/event-stream",
+		Query: []queryParam{propertyQuery}, Description: "Server-Sent Events: a device_status event with a DeviceStatusChange for each change."},
+
+	// Dashboard
+	"handleDashboard":       {Response: models.DashboardResponse{}, Query: propertyFilterQuery},
+	"handleGetWorkerHealth": {Response: models.WorkerHealth{}},
+	"handleGetStats":        {Summary: "Get fleet statistics", Response: models.FleetStats{}, Query: []queryParam{{"period", "string", "window for uptime, outages and latency such as 24h, 7d or 4w; 7d when unset"}, {"limit", "integer", "worst properties listed; 10 when unset, at most 100"}}},
+	"handleGetMap":          {Summary: "Get the property map", Response: models.PropertyMap{}, Query: propertyFilterQuery, Description: "Properties with coordinates and their status; those without are only counted."},
+
+	// Regions and property groups
+	"handleListRegions":         {Response: []models.Region{}},
+	"handleGetRegion":           {Response: models.Region{}},
+	"handleCreateRegion":        {Request: models.Region{}, Response: models.Region{}, Status: http.StatusCreated, Admin: true},
+	"handleUpdateRegion":        {Request: models.Region{}, Response: models.Region{}, Admin: true},
+	"handleDeleteRegion":        {Response: messageResponse{}, Admin: true},
+	"handleListPropertyGroups":  {Response: []models.PropertyGroup{}, Query: []queryParam{{"region_id", "integer", ""}}},
+	"handleGetPropertyGroup":    {Response: models.PropertyGroup{}},
+	"handleCreatePropertyGroup": {Request: models.PropertyGroup{}, Response: models.PropertyGroup{}, Status: http.StatusCreated, Admin: true},
+	"handleUpdatePropertyGroup": {Request: models.PropertyGroup{}, Response: models.PropertyGroup{}, Admin: true},
+	"handleDeletePropertyGroup": {Response: messageResponse{}, Admin: true},
+
+	// Status pages
+	"handleGetStatusPage":         {Response: models.StatusPage{}, Admin: true},
+	"handleSaveStatusPage":        {Summary: "Create or update a status page", Request: statusPageRequest{}, Response: models.StatusPage{}, Admin: true, Description: "201 with the page's token when the group had no status page; the token is only returned then."},
+	"handleDeleteStatusPage":      {Response: messageResponse{}, Admin: true},
+	"handleRotateStatusPageToken": {Summary: "Rotate a status page token", Response: models.StatusPage{}, Admin: true, Description: "Links with the old token stop working."},
+	"handlePublicStatusPage":      {Summary: "Get a public status page", Response: models.PublicStatusPage{}},
+	"handlePublicStatusPageHTML":  {Summary: "Get a public status page as HTML", ContentType: "text/html"},
+
+	// Status badges
+	"handleGetPropertyBadge":    {Response: models.PropertyBadge{}, Admin: true},
+	"handleCreatePropertyBadge": {Summary: "Create or rotate a property badge token", Response: models.PropertyBadge{}, Admin: true, Description: "201 when the property had no badge, otherwise 200 with a new token; links with the old token stop working. The token is only returned here."},
+	"handleDeletePropertyBadge": {Response: messageResponse{}, Admin: true},
+	"handlePublicPropertyBadge": {Summary: "Get a property status badge", ContentType: "image/svg+xml", Query: []queryParam{{"label", "string", "text on the left of the badge; status when unset"}}},
+
+	// Local file storage
+	"handleGetLocalBlob": {Summary: "Download a file from local storage", ContentType: "application/octet-stream",
+		Query:       []queryParam{{"expires", "integer", "Unix time the link expires"}, {"signature", "string", ""}},
+		Description: "Served only with BLOB_STORE=local, at the links returned by the attachment and config backup download endpoints."},
+
+	// Properties
+	"handleListProperties": {Response: []models.Property{}, List: true,
+		Query: append([]queryParam{{"status", "string", "green, yellow or red"}}, propertyFilterQuery...)},
+	"handleGetProperty":           {Response: models.Property{}},
+	"handleCreateProperty":        {Request: models.Property{}, Response: models.Property{}, Status: http.StatusCreated},
+	"handleUpdateProperty":        {Request: models.Property{}, Response: models.Property{}},
+	"handleDeleteProperty":        {Response: messageResponse{}},
+	"handleCloneProperty":         {Request: cloneRequest{}, Response: cloneResponse{}, Status: http.StatusCreated, Description: "Copies the property's devices, contacts and notifications."},
+	"handleGetPropertyStatus":     {Response: models.PropertyStatus{}},
+	"handleGetPropertyDevices":    {Response: []models.Device{}, List: true, Query: deviceFilterQuery},
+	"handleGetPropertyUptime":     {Response: models.PropertyUptime{}, Query: uptimeQuery},
+	"handleGetPropertyTopology":   {Response: models.PropertyTopology{}, Description: "Devices with their status, linked by parent device or, failing that, to the router on their subnet."},
+	"handleExportPropertyDevices": {Summary: "Export property devices as CSV", ContentType: "text/csv"},
+	"handleImportPropertyDevices": {Summary: "Import property devices from CSV", Upload: true, Response: models.DeviceImportResult{},
+		Query:       []queryParam{dryRunQuery, {"template_id", "integer", "device template applied to new devices"}},
+		Description: "The CSV may also be sent as a text/csv body. Nothing is applied if any row is invalid."},
+	"handleGetPropertyAvailability": {Response: models.AvailabilityCalendar{}, Query: availabilityQuery, Description: "Uptime per day or hour, for a calendar heatmap. The range defaults to the last 90 days by day or 7 days by hour."},
+	"handleSyncDevicesFromPfSense": {Summary: "Sync devices from pfSense", Response: syncResponse{},
+		Query: []queryParam{dryRunQuery,
+			{"interfaces", "string", "comma-separated DHCP interfaces to sync"},
+			{"confirm", "string", "confirm_token from the dry run; 409 if pfSense or the devices changed since"}}},
+	"handleGetPropertyLeases": {Summary: "List pfSense DHCP leases", Response: []propertyLease{},
+		Query: []queryParam{{"all", "boolean", "include expired and free leases"}}},
+	"handleGetPropertyARP": {Summary: "List pfSense ARP table", Response: []arpNeighbor{},
+		Query: []queryParam{{"unknown", "boolean", "only neighbors in the subnet that match no device"}}},
+	"handleGetPropertyVPN":     {Summary: "List pfSense VPN tunnels", Response: []vpnTunnelStatus{}},
+	"handleTestPfSense":        {Summary: "Test pfSense connection", Request: pfSenseTestRequest{}, Response: pfsense.ConnectionTest{}, Description: "Body fields default to the property's saved settings."},
+	"handleGetPropertyTraffic": {Response: []models.InterfaceTraffic{}, Query: append([]queryParam{{"interface", "string", ""}}, timeRangeQuery...)},
+
+	// Firewalls
+	"handleListFirewallsForProperty": {Response: []models.Firewall{}},
+	"handleCreateFirewall":           {Request: models.Firewall{}, Response: models.Firewall{}, Status: http.StatusCreated},
+	"handleGetFirewall":              {Response: models.Firewall{}},
+	"handleUpdateFirewall":           {Request: models.Firewall{}, Response: models.Firewall{}},
+	"handleDeleteFirewall":           {Response: messageResponse{}},
+	"handleGetFirewallCARP":          {Summary: "Get firewall CARP status", Response: carpStatusResponse{}},
+
+	// UniFi
+	"handleGetUniFiController":    {Summary: "Get UniFi controller", Response: models.UniFiController{}},
+	"handleSaveUniFiController":   {Summary: "Save UniFi controller", Request: models.UniFiController{}, Response: models.UniFiController{}},
+	"handleDeleteUniFiController": {Summary: "Delete UniFi controller", Response: messageResponse{}},
+	"handleGetPropertyWiFi":       {Summary: "Get property WiFi", Response: models.WiFiSnapshot{}},
+
+	// Contacts
+	"handleListContactsForProperty": {Response: []models.Contact{}, List: true},
+	"handleCreateContact":           {Request: models.Contact{}, Response: models.Contact{}, Status: http.StatusCreated},
+	"handleGetContact":              {Response: models.Contact{}},
+	"handleUpdateContact":           {Request: models.Contact{}, Response: models.Contact{}},
+	"handleDeleteContact":           {Response: messageResponse{}},
+
+	// ISP circuits
+	"handleListCircuitsForProperty": {Response: []models.Circuit{}},
+	"handleCreateCircuit":           {Request: models.Circuit{}, Response: models.Circuit{}, Status: http.StatusCreated},
+	"handleGetCircuit":              {Response: models.Circuit{}},
+	"handleUpdateCircuit":           {Request: models.Circuit{}, Response: models.Circuit{}},
+	"handleDeleteCircuit":           {Response: messageResponse{}},
+
+	// Attachments
+	"handleListAttachmentsForProperty": {Response: []models.Attachment{}},
+	"handleUploadAttachment":           {Upload: true, Response: models.Attachment{}, Status: http.StatusCreated},
+	"handleLinkDriveAttachment":        {Request: models.DriveAttachmentRequest{}, Response: models.Attachment{}, Status: http.StatusCreated},
+	"handleDownloadAttachment":         {Response: urlResponse{}},
+	"handleUpdateAttachment":           {Request: models.AttachmentUpdate{}, Response: models.Attachment{}},
+	"handleDeleteAttachment":           {Response: messageResponse{}},
+	"handleStartAttachmentUpload":      {Request: models.StartAttachmentUploadRequest{}, Response: models.AttachmentUpload{}, Status: http.StatusCreated},
+	"handleGetAttachmentUpload":        {Response: models.AttachmentUpload{}},
+	"handleCompleteAttachmentUpload":   {Response: models.Attachment{}, Status: http.StatusCreated},
+	"handleAbortAttachmentUpload":      {Response: messageResponse{}},
+	"handleSearchAttachments": {Response: []models.Attachment{}, List: true, Query: []queryParam{
+		{"q", "string", "words that must all appear in the filename, description, tags or property name"},
+		{"tag", "string", "only attachments with this tag"}, propertyQuery}},
+	"handleUploadAttachmentPart": {Binary: true, Response: models.AttachmentUpload{},
+		Query: []queryParam{{"offset", "integer", "where the part starts in the file; must equal the upload's received"}}},
+
+	// Devices
+	"handleListDevices":             {Response: []models.Device{}, List: true, Query: append([]queryParam{propertyQuery}, deviceFilterQuery...)},
+	"handleCreateDevice":            {Request: models.Device{}, Response: models.Device{}, Status: http.StatusCreated},
+	"handleGetWarrantyReport":       {Summary: "Get device warranty report", Response: []models.DeviceWarranty{},
+		Query: []queryParam{{"days", "integer", "look-ahead in days, default 90"}, propertyQuery, {"expired", "boolean", "also list lapsed warranties"}}},
+	"handleGetFirmwareReport":       {Summary: "Get firmware fleet report", Response: []models.FirmwareGroup{},
+		Query: []queryParam{propertyQuery, {"below_minimum", "boolean", "only versions below the model's minimum"}}},
+	"handleGetDeviceAudit":          {Summary: "Get device audit", Response: models.DeviceAudit{},
+		Query: []queryParam{{"refresh", "boolean", "run the audit now instead of returning the worker's latest"}}},
+	"handleBulkDevices":             {Summary: "Create, update and delete devices", Request: []models.DeviceOperation{}, Response: models.BulkDeviceResponse{}, Description: "Applied in one transaction: nothing changes if any operation fails."},
+	"handleGetDevice":               {Response: models.Device{}},
+	"handleUpdateDevice":            {Request: models.Device{}, Response: models.Device{}},
+	"handleDeleteDevice":            {Response: messageResponse{}},
+	"handleGetDeviceStatus":         {Response: models.DeviceStatus{}},
+	"handleGetDeviceHistory":        {Response: []models.DeviceHistory{}, Query: historyQuery, Description: "With interval, returns DeviceHistoryBucket items instead of raw checks: one per bucket that has checks, aligned to the Unix epoch."},
+	"handleGetDeviceHistoryRollups": {Response: []models.DeviceHistoryRollup{}, Query: append([]queryParam{{"resolution", "string", "hour or day, chosen from the range when unset"}}, timeRangeQuery...)},
+	"handleGetDeviceErrors":         {Response: []models.DeviceHistory{}, Query: []queryParam{{"limit", "integer", "10 when unset"}}},
+	"handleGetDeviceUptime":         {Response: models.DeviceUptime{}, Query: uptimeQuery},
+	"handleGetDeviceAvailability":   {Response: models.AvailabilityCalendar{}, Query: availabilityQuery, Description: "Uptime per day or hour, for a calendar heatmap. The range defaults to the last 90 days by day or 7 days by hour."},
+
+	// Device templates
+	"handleListDeviceTemplates":  {Response: []models.DeviceTemplate{}},
+	"handleCreateDeviceTemplate": {Request: models.DeviceTemplate{}, Response: models.DeviceTemplate{}, Status: http.StatusCreated},
+	"handleGetDeviceTemplate":    {Response: models.DeviceTemplate{}},
+	"handleUpdateDeviceTemplate": {Request: models.DeviceTemplate{}, Response: models.DeviceTemplate{}},
+	"handleDeleteDeviceTemplate": {Response: messageResponse{}},
+	"handleApplyDeviceTemplate":  {Request: applyTemplateRequest{}, Response: []models.Device{}},
+
+	// Property notifications
+	"handleListPropertyNotifications":  {Response: []models.PropertyNotification{}},
+	"handleCreatePropertyNotification": {Request: models.PropertyNotification{}, Response: models.PropertyNotification{}, Status: http.StatusCreated},
+	"handleUpdatePropertyNotification": {Request: models.PropertyNotification{}, Response: models.PropertyNotification{}},
+	"handleDeletePropertyNotification": {Response: messageResponse{}},
+	"handleListNotificationEvents": {Response: []models.NotificationEvent{}, List: true,
+		Query: []queryParam{{"event_type", "string", ""}, {"success", "boolean", ""}}},
+
+	// Status events
+	"handleListStatusEvents": {Response: []models.StatusEvent{},
+		Query: append([]queryParam{propertyQuery, {"device_id", "integer", ""}, {"entity_type", "string", "device or property"},
+			{"status", "string", "the status changed to"}, {"limit", "integer", ""}}, timeRangeQuery...)},
+	"handleListPropertyStatusEvents": {Response: []models.StatusEvent{},
+		Query: append([]queryParam{{"device_id", "integer", ""}, {"entity_type", "string", "device or property"},
+			{"status", "string", "the status changed to"}, {"limit", "integer", ""}}, timeRangeQuery...)},
+
+	// Activity feed
+	"handleListActivity": {Summary: "List recent activity", Response: models.ActivityFeed{},
+		Query: []queryParam{{"since", "string", "RFC 3339 time; only activity after it"}, propertyQuery,
+			{"kind", "string", "comma separated status_change, incident, acknowledgement, notification or sync"},
+			{"limit", "integer", "50 when unset, at most 200"}, {"cursor", "string", "next_cursor of the previous page"}},
+		Description: "Status changes, incidents, acknowledgements, notification deliveries and pfSense syncs, newest first."},
+
+	// Acknowledgements and silences
+	"handleListAcknowledgements":  {Response: []models.Acknowledgement{}},
+	"handleAcknowledgeProperty":   {Request: acknowledgeRequest{}, Response: models.Acknowledgement{}, Status: http.StatusCreated},
+	"handleUnacknowledgeProperty": {Response: messageResponse{}},
+	"handleAcknowledgeDevice":     {Request: acknowledgeRequest{}, Response: models.Acknowledgement{}, Status: http.StatusCreated},
+	"handleUnacknowledgeDevice":   {Response: messageResponse{}},
+	"handleListSilences":          {Response: []models.Silence{}},
+	"handleCreateSilence":         {Request: silenceRequest{}, Response: models.Silence{}, Status: http.StatusCreated},
+	"handleDeleteSilence":         {Summary: "Expire silence", Response: messageResponse{}},
+
+	// Digest subscriptions
+	"handleListDigestSubscriptions":   {Response: []models.DigestSubscription{}},
+	"handleCreateDigestSubscription":  {Request: models.DigestSubscription{}, Response: models.DigestSubscription{}, Status: http.StatusCreated},
+	"handleUpdateDigestSubscription":  {Request: models.DigestSubscription{}, Response: models.DigestSubscription{}},
+	"handleDeleteDigestSubscription":  {Response: messageResponse{}},
+	"handlePreviewDigestSubscription": {Response: models.Digest{}},
+
+	// On-call
+	"handleGetCurrentOnCall":     {Summary: "Get who is on call", Response: []models.OnCallShift{}, Query: []queryParam{{"schedule_id", "integer", ""}}},
+	"handleListOnCallSchedules":  {Response: []models.OnCallSchedule{}},
+	"handleGetOnCallSchedule":    {Response: models.OnCallSchedule{}},
+	"handleCreateOnCallSchedule": {Request: models.OnCallSchedule{}, Response: models.OnCallSchedule{}, Status: http.StatusCreated, Admin: true},
+	"handleUpdateOnCallSchedule": {Request: models.OnCallSchedule{}, Response: models.OnCallSchedule{}, Admin: true},
+	"handleDeleteOnCallSchedule": {Response: messageResponse{}, Admin: true},
+	"handleListOnCallOverrides":  {Response: []models.OnCallOverride{}},
+	"handleCreateOnCallOverride": {Request: models.OnCallOverride{}, Response: models.OnCallOverride{}, Status: http.StatusCreated, Admin: true},
+	"handleDeleteOnCallOverride": {Response: messageResponse{}, Admin: true},
+
+	// Maintenance windows
+	"handleListMaintenanceWindows":            {Response: []models.MaintenanceWindow{}, Query: []queryParam{{"active", "boolean", "only windows in effect now"}}},
+	"handleListMaintenanceWindowsForProperty": {Response: []models.MaintenanceWindow{}},
+	"handleCreateMaintenanceWindow":           {Request: models.MaintenanceWindow{}, Response: models.MaintenanceWindow{}, Status: http.StatusCreated},
+	"handleGetMaintenanceWindow":              {Response: models.MaintenanceWindow{}},
+	"handleUpdateMaintenanceWindow":           {Request: models.MaintenanceWindow{}, Response: models.MaintenanceWindow{}},
+	"handleDeleteMaintenanceWindow":           {Response: messageResponse{}},
+
+	// Users
+	"handleListUsers":          {Response: []models.User{}, Admin: true},
+	"handleCreateUser":         {Request: models.User{}, Response: models.User{}, Status: http.StatusCreated, Admin: true},
+	"handleUpdateUser":         {Request: models.User{}, Response: models.User{}, Admin: true, Description: "Deactivating a user revokes their sessions."},
+	"handleDeleteUser":         {Response: messageResponse{}, Admin: true},
+	"handleResetUserTOTP":      {Summary: "Reset user two-factor", Response: messageResponse{}, Admin: true},
+	"handleListUserSessions":   {Response: []models.UserSession{}, Admin: true},
+	"handleRevokeUserSessions": {Response: revokedResponse{}, Admin: true},
+
+	// Settings and configuration
+	"handleGetSettings":    {Response: models.Settings{}, Admin: true},
+	"handleUpdateSettings": {Request: models.Settings{}, Response: models.Settings{}, Admin: true},
+	"handleExportConfig": {Response: models.ConfigDocument{}, Admin: true,
+		Query: []queryParam{{"format", "string", "json or yaml"}, {"include_secrets", "boolean", ""}}},
+	"handleImportConfig": {Request: models.ConfigDocument{}, Response: models.ConfigImportResult{}, Admin: true,
+		Query:       []queryParam{dryRunQuery, {"conflict", "string", "fail, skip or overwrite existing entries with the same name"}},
+		Description: "The document may also be sent as application/yaml."},
+	"handleCleanupOrphanedAttachments": {Summary: "Clean up orphaned attachment files", Response: models.OrphanSweep{}, Admin: true,
+		Query: []queryParam{dryRunQuery}},
+
+	// Notification channels and rules
+	"handleListNotificationChannels":  {Response: []models.NotificationChannel{}, Admin: true},
+	"handleCreateNotificationChannel": {Request: models.NotificationChannel{}, Response: models.NotificationChannel{}, Status: http.StatusCreated, Admin: true},
+	"handleGetNotificationChannel":    {Response: models.NotificationChannel{}, Admin: true},
+	"handleUpdateNotificationChannel": {Request: models.NotificationChannel{}, Response: models.NotificationChannel{}, Admin: true},
+	"handleDeleteNotificationChannel": {Response: messageResponse{}, Admin: true},
+	"handleTestNotificationChannel":   {Response: models.NotificationTestResult{}, Admin: true},
+	"handleListNotificationRules":     {Response: []models.NotificationRule{}, Admin: true},
+	"handleCreateNotificationRule":    {Request: models.NotificationRule{}, Response: models.NotificationRule{}, Status: http.StatusCreated, Admin: true},
+	"handleGetNotificationRule":       {Response: models.NotificationRule{}, Admin: true},
+	"handleUpdateNotificationRule":    {Request: models.NotificationRule{}, Response: models.NotificationRule{}, Admin: true},
+	"handleDeleteNotificationRule":    {Response: messageResponse{}, Admin: true},
+	"handleListAllNotificationEvents": {Response: []models.NotificationEvent{}, List: true, Admin: true,
+		Query: append([]queryParam{propertyQuery, {"channel_id", "integer", ""}, {"event_type", "string", ""}, {"success", "boolean", ""}}, timeRangeQuery...)},
+
+	// pfSense config backups and DHCP mappings
+	"handleListConfigBackups":    {Response: []models.ConfigBackup{}, Admin: true},
+	"handleCreateConfigBackup":   {Summary: "Back up pfSense config", Response: models.ConfigBackup{}, Status: http.StatusCreated, Admin: true, Description: "Returns 200 with the latest backup when the config is unchanged."},
+	"handleDownloadConfigBackup": {Response: urlResponse{}, Admin: true},
+	"handlePushDHCPMapping":      {Summary: "Push DHCP static mapping to pfSense", Request: dhcpMappingRequest{}, Response: models.DHCPMappingChange{}, Admin: true},
+	"handleDeleteDHCPMapping": {Summary: "Delete DHCP static mapping from pfSense", Response: models.DHCPMappingChange{}, Admin: true,
+		Query: []queryParam{{"interface", "string", "lan when unset"}}},
+	"handleListDHCPMappingChanges": {Summary: "List DHCP mapping changes", Response: []models.DHCPMappingChange{}, Admin: true},
+
+	// Remote probes
+	"handleListProbes":   {Response: []models.Probe{}, Admin: true},
+	"handleCreateProbe":  {Request: probeRequest{}, Response: models.Probe{}, Status: http.StatusCreated, Admin: true, Description: "The probe's token is only returned here."},
+	"handleDeleteProbe":  {Response: messageResponse{}, Admin: true},
+	"handleProbeDevices": {Summary: "List the calling probe's devices", Response: []models.Device{}},
+	"handleProbeResults": {Summary: "Report probe check results", Request: []models.DeviceStatus{}, Response: probeResultsResponse{}},
+
+	// Outbound webhooks
+	"handleListWebhooks":  {Response: []models.Webhook{}, Admin: true},
+	"handleCreateWebhook": {Request: webhookRequest{}, Response: models.Webhook{}, Status: http.StatusCreated, Admin: true, Description: "The signing secret is only returned here."},
+	"handleGetWebhook":    {Response: models.Webhook{}, Admin: true},
+	"handleUpdateWebhook": {Request: webhookRequest{}, Response: models.Webhook{}, Admin: true},
+	"handleDeleteWebhook": {Response: messageResponse{}, Admin: true},
+	"handleTestWebhook":   {Summary: "Send a test event to a webhook", Response: models.WebhookDelivery{}, Admin: true},
+	"handleListWebhookDeliveries": {Response: []models.WebhookDelivery{}, Admin: true,
+		Query: []queryParam{{"limit", "integer", "50 when unset, at most 500"}}},
+	"handleReplayWebhookDelivery": {Summary: "Replay a webhook delivery", Response: models.WebhookDelivery{}, Admin: true,
+		Description: "Sends the delivery's payload again, with the same event ID, and logs it as a new delivery."},
+}
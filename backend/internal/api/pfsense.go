@@ -0,0 +1,563 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+	"github.com/etswifi/ets-noc/internal/webhook"
+)
+
+// propertyLease is a DHCP lease annotated with the registered device at its address
+type propertyLease struct {
+	pfsense.DHCPLease
+	DeviceID   *int64 `json:"device_id"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+// propertyPfSenseClient loads the property named by the :id param and returns a
+// pfSense client for it, writing the error response if that isn't possible
+func (s *Server) propertyPfSenseClient(c *gin.Context) (*models.Property, *pfsense.Client, bool) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return nil, nil, false
+	}
+
+	property, err := s.postgres.GetProperty(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return nil, nil, false
+	}
+
+	if property.PfSenseHost == "" || property.PfSenseUsername == "" || property.PfSensePassword == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "pfSense credentials not configured for this property",
+		})
+		return nil, nil, false
+	}
+
+	client := pfsense.NewClient(property.PfSenseHost, property.PfSensePort, property.PfSenseUsername, property.PfSensePassword)
+	return property, client, true
+}
+
+// handleGetPropertyLeases lists the DHCP leases currently handed out by the property's
+// pfSense. Expired and released leases are included with ?all=true.
+func (s *Server) handleGetPropertyLeases(c *gin.Context) {
+	property, pfClient, ok := s.propertyPfSenseClient(c)
+	if !ok {
+		return
+	}
+
+	leases, err := pfClient.GetDHCPLeases(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to fetch leases from pfSense: %v", err),
+		})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), property.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	byAddr := make(map[string]*models.Device, len(devices))
+	for i := range devices {
+		byAddr[devices[i].Hostname] = &devices[i]
+	}
+
+	all := c.Query("all") == "true"
+	now := time.Now()
+	result := make([]propertyLease, 0, len(leases))
+	for _, lease := range leases {
+		if !all && !lease.Active(now) {
+			continue
+		}
+		entry := propertyLease{DHCPLease: lease}
+		if device, ok := byAddr[lease.IPAddr]; ok {
+			entry.DeviceID = &device.ID
+			entry.DeviceName = device.Name
+		}
+		result = append(result, entry)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// arpNeighbor is an ARP entry cross-referenced against the property's devices.
+// Unknown entries are on the property subnet but match no registered device.
+type arpNeighbor struct {
+	pfsense.ARPEntry
+	DeviceID   *int64 `json:"device_id"`
+	DeviceName string `json:"device_name,omitempty"`
+	InSubnet   bool   `json:"in_subnet"`
+	Unknown    bool   `json:"unknown"`
+}
+
+// handleGetPropertyARP lists the neighbors in the property's pfSense ARP table, matched to
+// registered devices by MAC, then IP. ?unknown=true returns only unknown neighbors.
+func (s *Server) handleGetPropertyARP(c *gin.Context) {
+	property, pfClient, ok := s.propertyPfSenseClient(c)
+	if !ok {
+		return
+	}
+
+	entries, err := pfClient.GetARPTable(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to fetch ARP table from pfSense: %v", err),
+		})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), property.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	byMAC := make(map[string]*models.Device)
+	byAddr := make(map[string]*models.Device)
+	for i := range devices {
+		if devices[i].MACAddress != "" {
+			byMAC[devices[i].MACAddress] = &devices[i]
+		}
+		byAddr[devices[i].Hostname] = &devices[i]
+	}
+
+	// Without a parseable subnet every neighbor is treated as local
+	_, subnet, _ := net.ParseCIDR(property.Subnet)
+
+	onlyUnknown := c.Query("unknown") == "true"
+	result := make([]arpNeighbor, 0, len(entries))
+	for _, entry := range entries {
+		neighbor := arpNeighbor{ARPEntry: entry}
+		ip := net.ParseIP(entry.IPAddr)
+		neighbor.InSubnet = subnet == nil || (ip != nil && subnet.Contains(ip))
+
+		device, ok := byMAC[entry.MAC]
+		if !ok {
+			device, ok = byAddr[entry.IPAddr]
+		}
+		if ok {
+			neighbor.DeviceID = &device.ID
+			neighbor.DeviceName = device.Name
+		}
+		neighbor.Unknown = !ok && neighbor.InSubnet && !entry.Permanent
+
+		if onlyUnknown && !neighbor.Unknown {
+			continue
+		}
+		result = append(result, neighbor)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// vpnTunnelStatus is a VPN tunnel with the pseudo-device monitoring it, if any
+type vpnTunnelStatus struct {
+	pfsense.VPNTunnel
+	Key        string `json:"key"`
+	DeviceID   *int64 `json:"device_id"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+// handleGetPropertyVPN lists the IPsec and OpenVPN tunnels on the property's pfSense
+// and the vpn check devices that monitor them
+func (s *Server) handleGetPropertyVPN(c *gin.Context) {
+	property, pfClient, ok := s.propertyPfSenseClient(c)
+	if !ok {
+		return
+	}
+
+	tunnels, err := pfClient.GetVPNStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to fetch VPN status from pfSense: %v", err),
+		})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), property.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	byKey := make(map[string]*models.Device)
+	for i := range devices {
+		if devices[i].CheckType == "vpn" {
+			byKey[strings.ToLower(devices[i].Hostname)] = &devices[i]
+		}
+	}
+
+	result := make([]vpnTunnelStatus, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		entry := vpnTunnelStatus{VPNTunnel: tunnel, Key: tunnel.Key()}
+		if device, ok := byKey[strings.ToLower(tunnel.Key())]; ok {
+			entry.DeviceID = &device.ID
+			entry.DeviceName = device.Name
+		}
+		result = append(result, entry)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// pfSenseTestRequest optionally overrides the stored pfSense settings, so credentials
+// can be checked before they are saved
+type pfSenseTestRequest struct {
+	Host     string `json:"pfsense_host"`
+	Port     int    `json:"pfsense_port"`
+	Username string `json:"pfsense_username"`
+	Password string `json:"pfsense_password"`
+}
+
+// handleTestPfSense tries to reach and log in to the property's pfSense and reports
+// reachability, authentication and version. Fields in the request body take precedence
+// over the stored settings.
+func (s *Server) handleTestPfSense(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	property, err := s.postgres.GetProperty(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	var req pfSenseTestRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	if req.Host == "" {
+		req.Host = property.PfSenseHost
+	}
+	if req.Port == 0 {
+		req.Port = property.PfSensePort
+	}
+	if req.Username == "" {
+		req.Username = property.PfSenseUsername
+	}
+	if req.Password == "" {
+		req.Password = property.PfSensePassword
+	}
+
+	if req.Host == "" || req.Username == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "pfSense credentials not configured for this property",
+		})
+		return
+	}
+
+	client := pfsense.NewClient(req.Host, req.Port, req.Username, req.Password)
+	c.JSON(http.StatusOK, client.TestConnection(c.Request.Context()))
+}
+
+// syncChange is one device in a pfSense sync plan
+type syncChange struct {
+	DeviceID  *int64   `json:"device_id,omitempty"`
+	Name      string   `json:"name"`
+	IPAddr    string   `json:"ip_addr"`
+	MAC       string   `json:"mac,omitempty"`
+	Interface string   `json:"interface,omitempty"`
+	MatchedBy string   `json:"matched_by,omitempty"` // mac, ip or hostname
+	Changes   []string `json:"changes,omitempty"`
+
+	device *models.Device // device to create, or the updated copy to save
+}
+
+// syncPlan is what a pfSense sync would do to a property's devices
+type syncPlan struct {
+	Create     []syncChange `json:"create"`
+	Update     []syncChange `json:"update"`
+	Deactivate []syncChange `json:"deactivate"`
+	Unchanged  int          `json:"unchanged"`
+}
+
+// token fingerprints the plan so an apply can be tied to the preview it confirms
+func (p *syncPlan) token() string {
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// handleSyncDevicesFromPfSense imports DHCP static mappings from the property's pfSense
+// as devices. With ?dry_run=true it only returns the plan and a confirm_token; passing
+// that token back as ?confirm= applies the sync only if the plan hasn't changed since.
+func (s *Server) handleSyncDevicesFromPfSense(c *gin.Context) {
+	property, pfClient, ok := s.propertyPfSenseClient(c)
+	if !ok {
+		return
+	}
+
+	mappings, err := pfClient.GetDHCPStaticMappingsXML(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: fmt.Sprintf("Failed to fetch devices from pfSense: %v", err),
+		})
+		return
+	}
+
+	// Optionally limit the import to some DHCP scopes, by interface name or description
+	interfaceCounts := make(map[string]int)
+	for _, mapping := range mappings {
+		interfaceCounts[mapping.Interface]++
+	}
+	var scopes []string
+	if filter := c.Query("interfaces"); filter != "" {
+		scopes = strings.Split(filter, ",")
+		mappings = filterMappingsByInterface(mappings, scopes)
+		for _, mapping := range mappings {
+			scopes = append(scopes, mappingInterfaceLabel(mapping))
+		}
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), property.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	plan := buildSyncPlan(property.ID, mappings, devices, scopes)
+	token := plan.token()
+
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run":       true,
+			"plan":          plan,
+			"confirm_token": token,
+			"total":         len(mappings),
+			"interfaces":    interfaceCounts,
+		})
+		return
+	}
+
+	if confirm := c.Query("confirm"); confirm != "" && confirm != token {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "pfSense or device data changed since the preview; run the dry run again",
+		})
+		return
+	}
+
+	var errors []string
+	created, updated, deactivated := 0, 0, 0
+	for _, change := range plan.Create {
+		if err := s.postgres.CreateDevice(c.Request.Context(), change.device); err != nil {
+			errors = append(errors, fmt.Sprintf("Failed to create %s: %v", change.Name, err))
+			continue
+		}
+		created++
+	}
+	for _, change := range plan.Update {
+		if err := s.postgres.UpdateDevice(c.Request.Context(), change.device); err != nil {
+			errors = append(errors, fmt.Sprintf("Failed to update %s: %v", change.Name, err))
+			continue
+		}
+		updated++
+	}
+	for _, change := range plan.Deactivate {
+		if err := s.postgres.UpdateDevice(c.Request.Context(), change.device); err != nil {
+			errors = append(errors, fmt.Sprintf("Failed to deactivate %s: %v", change.Name, err))
+			continue
+		}
+		deactivated++
+	}
+
+	response := map[string]interface{}{
+		"success":     true,
+		"created":     created,
+		"updated":     updated,
+		"deactivated": deactivated,
+		"unchanged":   plan.Unchanged,
+		"total":       len(mappings),
+		"interfaces":  interfaceCounts,
+	}
+	if len(errors) > 0 {
+		response["errors"] = errors
+	}
+	s.webhooks.Publish(c.Request.Context(), webhook.EventSyncCompleted, webhook.SyncData{
+		PropertyID:  property.ID,
+		Created:     created,
+		Updated:     updated,
+		Deactivated: deactivated,
+		Unchanged:   plan.Unchanged,
+		Total:       len(mappings),
+		Errors:      errors,
+	})
+	c.JSON(http.StatusOK, response)
+}
+
+// buildSyncPlan matches static mappings to the property's devices by MAC, then IP,
+// then name. Active devices that were synced before (they have a MAC) but no longer
+// have a mapping are deactivated; with an interface filter only devices tagged with one
+// of the synced scopes (by name or label) are considered.
+func buildSyncPlan(propertyID int64, mappings []pfsense.DHCPStaticMapping, devices []models.Device, scopes []string) *syncPlan {
+	plan := &syncPlan{
+		Create:     []syncChange{},
+		Update:     []syncChange{},
+		Deactivate: []syncChange{},
+	}
+	matched := make(map[int64]bool)
+
+	for _, mapping := range mappings {
+		if mapping.Hostname == "" || mapping.IPAddr == "" {
+			continue
+		}
+		mac := strings.ToLower(mapping.MAC)
+
+		deviceType := pfsense.DetermineDeviceType(mapping.IPAddr)
+		tags := []string{deviceType}
+		if label := mappingInterfaceLabel(mapping); label != "" {
+			tags = append(tags, label)
+		}
+
+		change := syncChange{
+			Name:      mapping.Hostname,
+			IPAddr:    mapping.IPAddr,
+			MAC:       mac,
+			Interface: mapping.Interface,
+		}
+
+		existing, matchedBy := matchSyncDevice(devices, matched, mapping.Hostname, mapping.IPAddr, mac)
+		if existing == nil {
+			change.device = &models.Device{
+				PropertyID: propertyID,
+				Name:       mapping.Hostname,
+				Hostname:   mapping.IPAddr,
+				MACAddress: mac,
+				DeviceType: deviceType,
+				Tags:       tags,
+				IsCritical: deviceType == "Router",
+				Active:     true,
+			}
+			plan.Create = append(plan.Create, change)
+			continue
+		}
+		matched[existing.ID] = true
+
+		device := *existing
+		change.DeviceID = &existing.ID
+		change.MatchedBy = matchedBy
+		change.device = &device
+
+		if device.Name != mapping.Hostname {
+			change.Changes = append(change.Changes, fmt.Sprintf("name %q -> %q", device.Name, mapping.Hostname))
+			device.Name = mapping.Hostname
+		}
+		if device.Hostname != mapping.IPAddr {
+			change.Changes = append(change.Changes, fmt.Sprintf("ip %s -> %s", device.Hostname, mapping.IPAddr))
+			device.Hostname = mapping.IPAddr
+		}
+		if mac != "" && device.MACAddress != mac {
+			change.Changes = append(change.Changes, fmt.Sprintf("mac %q -> %q", device.MACAddress, mac))
+			device.MACAddress = mac
+		}
+		if strings.Join(device.Tags, ",") != strings.Join(tags, ",") {
+			change.Changes = append(change.Changes, fmt.Sprintf("tags %v -> %v", device.Tags, tags))
+			device.Tags = tags
+		}
+		if len(change.Changes) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		plan.Update = append(plan.Update, change)
+	}
+
+	for i := range devices {
+		d := devices[i]
+		if matched[d.ID] || !d.Active || d.MACAddress == "" {
+			continue
+		}
+		if len(scopes) > 0 && !anyString(d.Tags, scopes) {
+			continue
+		}
+		d.Active = false
+		plan.Deactivate = append(plan.Deactivate, syncChange{
+			DeviceID: &devices[i].ID,
+			Name:     d.Name,
+			IPAddr:   d.Hostname,
+			MAC:      d.MACAddress,
+			Changes:  []string{"no longer in pfSense"},
+			device:   &d,
+		})
+	}
+
+	return plan
+}
+
+// matchSyncDevice finds the device a mapping refers to, skipping devices already
+// claimed by another mapping
+func matchSyncDevice(devices []models.Device, matched map[int64]bool, name, ip, mac string) (*models.Device, string) {
+	if mac != "" {
+		for i := range devices {
+			if !matched[devices[i].ID] && devices[i].MACAddress == mac {
+				return &devices[i], "mac"
+			}
+		}
+	}
+	for i := range devices {
+		if !matched[devices[i].ID] && devices[i].Hostname == ip {
+			return &devices[i], "ip"
+		}
+	}
+	for i := range devices {
+		if !matched[devices[i].ID] && strings.EqualFold(devices[i].Name, name) {
+			return &devices[i], "hostname"
+		}
+	}
+	return nil, ""
+}
+
+// anyString reports whether any of the values appears in list, case-insensitively
+func anyString(list, values []string) bool {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		for _, item := range list {
+			if strings.EqualFold(item, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterMappingsByInterface keeps mappings whose interface name or description matches
+// one of the given names, case-insensitively
+func filterMappingsByInterface(mappings []pfsense.DHCPStaticMapping, names []string) []pfsense.DHCPStaticMapping {
+	var filtered []pfsense.DHCPStaticMapping
+	for _, mapping := range mappings {
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if strings.EqualFold(name, mapping.Interface) ||
+				(mapping.InterfaceDescr != "" && strings.EqualFold(name, mapping.InterfaceDescr)) {
+				filtered = append(filtered, mapping)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// mappingInterfaceLabel returns the tag used for a mapping's DHCP scope: the interface
+// description if set, otherwise its name
+func mappingInterfaceLabel(mapping pfsense.DHCPStaticMapping) string {
+	if mapping.InterfaceDescr != "" {
+		return strings.ToLower(mapping.InterfaceDescr)
+	}
+	return mapping.Interface
+}
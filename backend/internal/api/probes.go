@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Remote probes
+
+type probeRequest struct {
+	PropertyID int64  `json:"property_id" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+}
+
+// hashProbeToken returns the stored form of a probe token
+func hashProbeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ProbeAuthMiddleware authenticates a remote probe agent by its bearer token
+func ProbeAuthMiddleware(postgres storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Probe token required"})
+			c.Abort()
+			return
+		}
+
+		probe, err := postgres.GetProbeByTokenHash(c.Request.Context(), hashProbeToken(parts[1]))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid probe token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("probe", probe)
+		addLogFields(c, "probe_id", probe.ID, "property_id", probe.PropertyID)
+		c.Next()
+	}
+}
+
+func (s *Server) handleListProbes(c *gin.Context) {
+	probes, err := s.postgres.ListProbes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, probes)
+}
+
+// handleCreateProbe registers a probe for a property. The token is only returned here;
+// it can't be retrieved later.
+func (s *Server) handleCreateProbe(c *gin.Context) {
+	var req probeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if _, err := s.postgres.GetProperty(c.Request.Context(), req.PropertyID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	probe := models.Probe{PropertyID: req.PropertyID, Name: strings.TrimSpace(req.Name)}
+	if err := s.postgres.CreateProbe(c.Request.Context(), &probe, hashProbeToken(token)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	probe.Token = token
+	c.JSON(http.StatusCreated, probe)
+}
+
+// handleDeleteProbe removes a probe; its devices fall back to the central worker
+func (s *Server) handleDeleteProbe(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid probe ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteProbe(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Probe deleted"})
+}
+
+// handleProbeDevices returns the devices the calling probe should check, with the check
+// settings they inherit filled in
+func (s *Server) handleProbeDevices(c *gin.Context) {
+	probe := c.MustGet("probe").(*models.Probe)
+
+	ctx := c.Request.Context()
+	devices, err := s.postgres.ListDevicesForProbe(ctx, probe.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	settings, err := s.postgres.GetSettings(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	properties := make(map[int64]*models.Property)
+	for i := range devices {
+		property, ok := properties[devices[i].PropertyID]
+		if !ok {
+			property, _ = s.postgres.GetProperty(ctx, devices[i].PropertyID)
+			properties[devices[i].PropertyID] = property
+		}
+		monitor.ResolveCheckSettings(&devices[i], property, settings)
+	}
+
+	if err := s.postgres.TouchProbe(c.Request.Context(), probe.ID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// handleProbeResults queues check results from the calling probe for the worker.
+// Results for devices not assigned to the probe are ignored.
+func (s *Server) handleProbeResults(c *gin.Context) {
+	probe := c.MustGet("probe").(*models.Probe)
+
+	var statuses []models.DeviceStatus
+	if err := c.ShouldBindJSON(&statuses); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProbe(c.Request.Context(), probe.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	assigned := make(map[int64]bool, len(devices))
+	for _, d := range devices {
+		assigned[d.ID] = true
+	}
+
+	results := make([]models.ProbeResult, 0, len(statuses))
+	for _, status := range statuses {
+		if assigned[status.DeviceID] {
+			results = append(results, models.ProbeResult{ProbeID: probe.ID, Status: status})
+		}
+	}
+
+	if err := s.redis.PushProbeResults(c.Request.Context(), probe.PropertyID, results); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := s.postgres.TouchProbe(c.Request.Context(), probe.ID, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accepted": len(results)})
+}
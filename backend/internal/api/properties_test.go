@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/etswifi/ets-noc/internal/apitest"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+func TestPropertyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	h, err := apitest.New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if _, err := h.CreateUser(ctx, "admin", "admin-password", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.CreateUser(ctx, "viewer", "viewer-password", "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := h.Login("admin", "admin-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewer, err := h.Login("viewer", "viewer-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var created models.Property
+	status, err := h.Do(http.MethodPost, "/api/v1/properties", admin, models.Property{
+		Name:            "Harbor Inn",
+		Address:         "1 Harbor Way",
+		PfSensePassword: "router-secret",
+		Tags:            []string{"coastal"},
+	}, &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusCreated {
+		t.Fatalf("create returned %d, want %d", status, http.StatusCreated)
+	}
+	if created.ID == 0 || created.Subnet == "" {
+		t.Fatalf("created property has ID %d and subnet %q; want both assigned", created.ID, created.Subnet)
+	}
+	if created.PfSensePassword != "" || !created.PfSensePasswordSet {
+		t.Fatalf("create response exposed the password or lost that one is set: %+v", created)
+	}
+
+	var got models.Property
+	status, err = h.Do(http.MethodGet, fmt.Sprintf("/api/v1/properties/%d", created.ID), viewer, nil, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || got.Name != "Harbor Inn" || got.PfSensePassword != "" {
+		t.Fatalf("viewer get returned %d, %+v", status, got)
+	}
+
+	var listed []models.Property
+	status, err = h.Do(http.MethodGet, "/api/v1/properties?tag=coastal", viewer, nil, &listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || len(listed) != 1 || listed[0].ID != created.ID {
+		t.Fatalf("list by tag returned %d, %+v", status, listed)
+	}
+
+	// Viewers are read-only
+	status, err = h.Do(http.MethodDelete, fmt.Sprintf("/api/v1/properties/%d", created.ID), viewer, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusForbidden {
+		t.Fatalf("viewer delete returned %d, want %d", status, http.StatusForbidden)
+	}
+
+	status, err = h.Do(http.MethodDelete, fmt.Sprintf("/api/v1/properties/%d", created.ID), admin, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("admin delete returned %d, want %d", status, http.StatusOK)
+	}
+	status, err = h.Do(http.MethodGet, fmt.Sprintf("/api/v1/properties/%d", created.ID), admin, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNotFound {
+		t.Fatalf("get after delete returned %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/webhook"
+)
+
+// Property cloning, for onboarding a site that is laid out like an existing one
+
+type cloneRequest struct {
+	Name           string `json:"name" binding:"required"`
+	Address        string `json:"address"`
+	Notes          string `json:"notes"`
+	ISPCompanyName string `json:"isp_company_name"`
+	ISPAccountInfo string `json:"isp_account_info"`
+	// GroupID places the clone in a group and Tags replace its tags; both default to the
+	// source's own when left out
+	GroupID *int64   `json:"group_id"`
+	Tags    []string `json:"tags"`
+	// Subnet assigns the clone's subnet instead of taking the next automatic one
+	Subnet string `json:"subnet"`
+}
+
+// handleCloneProperty creates a property from the request and copies the source
+// property's devices, contacts and notification links into it, in one transaction.
+// Device IPs in the source subnet are moved to the same host in the new subnet, and
+// device names that start with the source property's name take the new one.
+func (s *Server) handleCloneProperty(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	var req cloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	source, err := s.postgres.GetProperty(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	property := models.Property{
+		Name:           req.Name,
+		Address:        req.Address,
+		Notes:          req.Notes,
+		ISPCompanyName: req.ISPCompanyName,
+		ISPAccountInfo: req.ISPAccountInfo,
+		Subnet:         req.Subnet,
+		CheckInterval:  source.CheckInterval,
+		Retries:        source.Retries,
+		Timeout:        source.Timeout,
+		GroupID:        source.GroupID,
+		Tags:           source.Tags,
+	}
+	if req.Tags != nil {
+		property.Tags = req.Tags
+	}
+	if req.GroupID != nil {
+		property.GroupID = req.GroupID
+		if err := s.validatePropertyGroupID(ctx, &property); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	if err := s.validatePropertySubnet(ctx, &property); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if property.Address == source.Address {
+		property.Latitude, property.Longitude = source.Latitude, source.Longitude
+	}
+	s.geocodeProperty(c, &property)
+
+	var devices, contacts, notifications int
+	err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+		if err := tx.CreateProperty(ctx, &property); err != nil {
+			return fmt.Errorf("failed to create property: %w", err)
+		}
+		if devices, err = cloneDevices(ctx, tx, source, &property); err != nil {
+			return err
+		}
+		if contacts, err = cloneContacts(ctx, tx, source.ID, property.ID); err != nil {
+			return err
+		}
+		notifications, err = clonePropertyNotifications(ctx, tx, source.ID, property.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	redactProperty(&property)
+	s.webhooks.Publish(ctx, webhook.EventPropertyCreated, property)
+	c.JSON(http.StatusCreated, gin.H{
+		"property":      property,
+		"source_id":     source.ID,
+		"devices":       devices,
+		"contacts":      contacts,
+		"notifications": notifications,
+	})
+}
+
+// cloneDevices copies the source's devices into the new property and returns how many
+// it copied. A copy whose hostname lands on one of the new property's own devices,
+// such as its auto-created router, updates that device instead. Probe assignments, MAC
+// addresses, serial numbers and purchase and warranty dates belong to the original
+// hardware and are not copied.
+func cloneDevices(ctx context.Context, tx storage.Store, source, property *models.Property) (int, error) {
+	sourceDevices, err := tx.ListDevicesForProperty(ctx, source.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load devices: %w", err)
+	}
+	existing, err := tx.ListDevicesForProperty(ctx, property.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load devices: %w", err)
+	}
+	byHostname := make(map[string]int64)
+	for _, d := range existing {
+		byHostname[strings.ToLower(d.Hostname)] = d.ID
+	}
+
+	rewrite := subnetRewriter(source.Subnet, property.Subnet)
+	ids := make(map[int64]int64) // source device ID to copy
+	var parented []*models.Device
+	for _, d := range sourceDevices {
+		device := d
+		device.PropertyID = property.ID
+		device.Hostname = rewriteDeviceHost(device.Hostname, rewrite)
+		if rest, ok := strings.CutPrefix(device.Name, source.Name); ok {
+			device.Name = property.Name + rest
+		}
+		device.ParentDeviceID = nil
+		device.ProbeID = nil
+		device.MACAddress = ""
+		device.SerialNumber = ""
+		device.PurchaseDate = nil
+		device.WarrantyExpires = nil
+
+		if existingID, ok := byHostname[strings.ToLower(device.Hostname)]; ok {
+			device.ID = existingID
+			err = tx.UpdateDevice(ctx, &device)
+		} else {
+			err = tx.CreateDevice(ctx, &device)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to copy device %q: %w", d.Name, err)
+		}
+		ids[d.ID] = device.ID
+		if d.ParentDeviceID != nil {
+			parentID := *d.ParentDeviceID
+			device.ParentDeviceID = &parentID
+			parented = append(parented, &device)
+		}
+	}
+
+	// Parents are linked once every copy has an ID
+	for _, device := range parented {
+		parentID, ok := ids[*device.ParentDeviceID]
+		if !ok {
+			continue
+		}
+		device.ParentDeviceID = &parentID
+		if err := tx.UpdateDevice(ctx, device); err != nil {
+			return 0, fmt.Errorf("failed to link device %q to its parent: %w", device.Name, err)
+		}
+	}
+	return len(sourceDevices), nil
+}
+
+// subnetRewriter returns a function that moves an address in the from subnet to the
+// same host in the to subnet, or nil if the subnets can't be mapped onto each other
+func subnetRewriter(from, to string) func(netip.Addr) (netip.Addr, bool) {
+	fromPrefix, err := netip.ParsePrefix(from)
+	if err != nil || !fromPrefix.Addr().Is4() {
+		return nil
+	}
+	toPrefix, err := netip.ParsePrefix(to)
+	if err != nil || !toPrefix.Addr().Is4() || toPrefix.Bits() != fromPrefix.Bits() {
+		return nil
+	}
+	fromPrefix, toPrefix = fromPrefix.Masked(), toPrefix.Masked()
+
+	return func(addr netip.Addr) (netip.Addr, bool) {
+		if !fromPrefix.Contains(addr) {
+			return addr, false
+		}
+		a, network := addr.As4(), toPrefix.Addr().As4()
+		hostBits := 32 - toPrefix.Bits()
+		for i := range a {
+			// Bits of this byte that belong to the host part
+			mask := byte(0)
+			if bits := hostBits - 8*(3-i); bits >= 8 {
+				mask = 0xff
+			} else if bits > 0 {
+				mask = byte(1<<bits - 1)
+			}
+			a[i] = network[i]&^mask | a[i]&mask
+		}
+		return netip.AddrFrom4(a), true
+	}
+}
+
+// rewriteDeviceHost applies rewrite to a device hostname that is an IP address, or to
+// the host of a ups@host target
+func rewriteDeviceHost(hostname string, rewrite func(netip.Addr) (netip.Addr, bool)) string {
+	if rewrite == nil {
+		return hostname
+	}
+	prefix, host := "", hostname
+	if ups, h, ok := strings.Cut(hostname, "@"); ok {
+		prefix, host = ups+"@", h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return hostname
+	}
+	if moved, ok := rewrite(addr); ok {
+		return prefix + moved.String()
+	}
+	return hostname
+}
+
+func cloneContacts(ctx context.Context, tx storage.Store, sourceID, propertyID int64) (int, error) {
+	contacts, err := tx.ListContactsForProperty(ctx, sourceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load contacts: %w", err)
+	}
+	for _, contact := range contacts {
+		contact.PropertyID = propertyID
+		if err := tx.CreateContact(ctx, &contact); err != nil {
+			return 0, fmt.Errorf("failed to copy contact %q: %w", contact.Name, err)
+		}
+	}
+	return len(contacts), nil
+}
+
+func clonePropertyNotifications(ctx context.Context, tx storage.Store, sourceID, propertyID int64) (int, error) {
+	notifications, err := tx.ListPropertyNotifications(ctx, sourceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load notifications: %w", err)
+	}
+	for _, pn := range notifications {
+		pn.PropertyID = propertyID
+		if err := tx.CreatePropertyNotification(ctx, &pn); err != nil {
+			return 0, fmt.Errorf("failed to copy notification link: %w", err)
+		}
+	}
+	return len(notifications), nil
+}
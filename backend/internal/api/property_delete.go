@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// deleteProperty removes a property and everything kept for it. The rows go in one
+// transaction, where the schema cascades to its devices, contacts, circuits,
+// attachments, uploads and notification rules, and digests drop it. Files in blob
+// storage and live status are cleaned up once it commits; a file that fails to
+// delete is logged and left for the orphan sweep.
+func (s *Server) deleteProperty(ctx context.Context, id int64) error {
+	var devices []models.Device
+	var attachments []models.Attachment
+	var uploads []models.AttachmentUpload
+	err := s.postgres.InTx(ctx, func(tx storage.Store) error {
+		var err error
+		if devices, err = tx.ListDevicesForProperty(ctx, id); err != nil {
+			return err
+		}
+		if attachments, err = tx.ListAttachmentsForProperty(ctx, id); err != nil {
+			return err
+		}
+		if uploads, err = tx.ListAttachmentUploadsForProperty(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.DeleteProperty(ctx, id); err != nil {
+			return err
+		}
+		return dropDigestProperty(ctx, tx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	deviceIDs := make([]int64, len(devices))
+	for i, d := range devices {
+		deviceIDs[i] = d.ID
+	}
+	s.clearDeviceStatuses(ctx, deviceIDs)
+	if err := s.redis.DeletePropertyState(ctx, id); err != nil {
+		slog.Error("Failed to clear deleted property's status", "property_id", id, "error", err)
+	}
+
+	for _, a := range attachments {
+		if !isBlobStorage(a.StorageType) {
+			continue
+		}
+		if s.blobs == nil || s.blobs.Kind() != a.StorageType {
+			slog.Warn("Left deleted property's attachment file; its storage is not configured",
+				"property_id", id, "path", a.StoragePath, "storage_type", a.StorageType)
+			continue
+		}
+		if err := s.blobs.DeleteFile(ctx, a.StoragePath); err != nil {
+			slog.Error("Failed to delete deleted property's attachment file", "property_id", id, "path", a.StoragePath, "error", err)
+		}
+	}
+
+	chunked, _ := s.blobs.(storage.ChunkedBlobStore)
+	for _, u := range uploads {
+		if chunked == nil || chunked.Kind() != u.StorageType {
+			continue
+		}
+		if u.Received < u.FileSize {
+			err = chunked.AbortUpload(ctx, u.Session)
+		} else {
+			err = chunked.DeleteFile(ctx, u.StoragePath)
+		}
+		if err != nil {
+			slog.Error("Failed to discard deleted property's upload", "property_id", id, "path", u.StoragePath, "error", err)
+		}
+	}
+	return nil
+}
+
+// dropDigestProperty takes a deleted property out of the digest subscriptions that list
+// it. A subscription left with no properties is disabled rather than widened to the
+// whole fleet.
+func dropDigestProperty(ctx context.Context, tx storage.Store, propertyID int64) error {
+	subscriptions, err := tx.ListDigestSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, d := range subscriptions {
+		kept := make([]int64, 0, len(d.PropertyIDs))
+		for _, id := range d.PropertyIDs {
+			if id != propertyID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(d.PropertyIDs) {
+			continue
+		}
+		d.PropertyIDs = kept
+		if len(kept) == 0 {
+			d.Enabled = false
+		}
+		if err := tx.UpdateDigestSubscription(ctx, &d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearDeviceStatuses drops deleted devices' live status, so they don't linger on
+// dashboards
+func (s *Server) clearDeviceStatuses(ctx context.Context, deviceIDs []int64) {
+	if err := s.redis.DeleteDeviceStatuses(ctx, deviceIDs); err != nil {
+		slog.Error("Failed to clear deleted devices' status", "devices", len(deviceIDs), "error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// RateLimits are in requests per minute, with up to a minute's worth allowed at once.
+// Zero turns a limit off.
+type RateLimits struct {
+	// Requests limits each user across the API
+	Requests int
+	// Auth limits sign-in attempts from each IP, and password and two-factor checks by
+	// each user
+	Auth int
+	// Uploads limits each user's attachment uploads and imports
+	Uploads int
+	// Syncs limits each user's pfSense syncs and config backups
+	Syncs int
+}
+
+// DefaultRateLimits are generous for people and tight where a request is expensive or
+// guesses a secret
+var DefaultRateLimits = RateLimits{Requests: 600, Auth: 10, Uploads: 30, Syncs: 6}
+
+// WithRateLimits overrides the request rate limits
+func WithRateLimits(limits RateLimits) ServerOption {
+	return func(s *Server) {
+		s.rateLimits = limits
+	}
+}
+
+// WithTrustedProxies sets the proxy IPs or CIDRs whose X-Forwarded-For header names
+// the client. Per-IP rate limits and session addresses use the client IP, so any
+// other caller's header is ignored.
+func WithTrustedProxies(proxies []string) ServerOption {
+	return func(s *Server) {
+		s.trustedProxies = proxies
+	}
+}
+
+// rateLimit returns middleware allowing perMinute requests a minute to the routes it
+// guards, counted per signed-in user, or per client IP before sign-in. The counts are
+// token buckets in the status store, so they hold across API replicas. A store error
+// lets the request through rather than locking everyone out.
+func (s *Server) rateLimit(name string, perMinute int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if perMinute <= 0 {
+			c.Next()
+			return
+		}
+
+		key := name + ":ip:" + c.ClientIP()
+		if userID := c.GetInt64("user_id"); userID != 0 {
+			key = name + ":user:" + strconv.FormatInt(userID, 10)
+		}
+		allowed, retryAfter, err := s.redis.TakeRateLimitToken(c.Request.Context(), key, perMinute, time.Minute)
+		if err != nil {
+			requestLog(c).Error("Failed to check rate limit", "limit", name, "error", err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			requestLog(c).Warn("Rate limit exceeded", "limit", name)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{Error: "Too many requests, try again later"})
+			return
+		}
+		c.Next()
+	}
+}
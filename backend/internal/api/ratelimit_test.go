@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/etswifi/ets-noc/internal/api"
+	"github.com/etswifi/ets-noc/internal/apitest"
+)
+
+// loginStatuses makes failed sign-ins, each claiming a different client in
+// X-Forwarded-For, and returns the status codes
+func loginStatuses(t *testing.T, h *apitest.Harness, attempts int) []int {
+	t.Helper()
+	var statuses []int
+	for i := range attempts {
+		req, err := http.NewRequest(http.MethodPost, h.HTTP.URL+"/api/v1/auth/login",
+			strings.NewReader(`{"username":"nobody","password":"wrong-password"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+		resp, err := h.HTTP.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		statuses = append(statuses, resp.StatusCode)
+	}
+	return statuses
+}
+
+func TestAuthRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	h, err := apitest.New(context.Background(),
+		apitest.WithServerOptions(api.WithRateLimits(api.RateLimits{Auth: 2})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	got := loginStatuses(t, h, 4)
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+}
+
+func TestAuthRateLimitUsesTrustedProxyForwardedFor(t *testing.T) {
+	h, err := apitest.New(context.Background(), apitest.WithServerOptions(
+		api.WithRateLimits(api.RateLimits{Auth: 2}),
+		api.WithTrustedProxies([]string{"127.0.0.1", "::1"}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// Each attempt comes from a different client behind the proxy, so none is limited
+	for i, status := range loginStatuses(t, h, 4) {
+		if status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d returned %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	wsPongTimeout  = 60 * time.Second
+	wsPingInterval = 30 * time.Second
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Auth is by token rather than cookie, and CORS already allows any origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// handleDashboardWebSocket sends a dashboard snapshot on connect, then pushes each
+// property status change published by the worker. With a group_id, region_id or tag
+// filter only changes to the properties in the snapshot are pushed.
+func (s *Server) handleDashboardWebSocket(c *gin.Context) {
+	filter, ok := propertyFilter(c)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Subscribe before taking the snapshot so no change is missed in between
+	updates, err := s.redis.SubscribePropertyStatus(ctx)
+	if err != nil {
+		requestLog(c).Error("Dashboard websocket failed to subscribe", "error", err)
+		return
+	}
+
+	snapshot, err := s.buildDashboard(ctx, filter)
+	if err != nil {
+		requestLog(c).Error("Dashboard websocket failed to build snapshot", "error", err)
+		return
+	}
+	var inScope map[int64]bool
+	if filter.GroupID != 0 || filter.RegionID != 0 || filter.Tag != "" {
+		inScope = make(map[int64]bool, len(snapshot.Properties))
+		for _, p := range snapshot.Properties {
+			inScope[p.ID] = true
+		}
+	}
+	if err := writeJSON(conn, &models.DashboardUpdate{Type: "snapshot", Dashboard: snapshot}); err != nil {
+		return
+	}
+
+	// The read loop only handles pongs and detects the client going away
+	go func() {
+		defer cancel()
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.streamsClosed:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(wsWriteTimeout))
+			return
+		case status, ok := <-updates:
+			if !ok {
+				return
+			}
+			if inScope != nil && !inScope[status.PropertyID] {
+				continue
+			}
+			if err := writeJSON(conn, &models.DashboardUpdate{Type: "property_status", Status: status}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+// handleDeviceStatusStream streams device status transitions as Server-Sent Events,
+// optionally limited to one property with ?property_id=
+func (s *Server) handleDeviceStatusStream(c *gin.Context) {
+	var propertyID int64
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		propertyID = id
+	}
+
+	ctx := c.Request.Context()
+	updates, err := s.redis.SubscribeDeviceStatusChanges(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.streamsClosed:
+			return false
+		case change, ok := <-updates:
+			if !ok {
+				return false
+			}
+			if propertyID == 0 || change.PropertyID == propertyID {
+				c.SSEvent("device_status", change)
+			}
+			return true
+		case <-ticker.C:
+			// Comment line keeps idle proxies from closing the connection
+			io.WriteString(w, ": keepalive\n\n")
+			return true
+		}
+	})
+}
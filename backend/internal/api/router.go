@@ -1,34 +1,127 @@
 package api
 
 import (
+	"log/slog"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/metrics"
+	"github.com/etswifi/ets-noc/internal/storage"
 )
 
 func (s *Server) SetupRouter() *gin.Engine {
-	router := gin.Default()
+	router := gin.New()
+	if err := router.SetTrustedProxies(s.trustedProxies); err != nil {
+		slog.Error("Invalid trusted proxies; trusting none", "error", err)
+		router.SetTrustedProxies(nil)
+	}
+	router.Use(RequestLogger(), RecoveryLogger())
+	s.registerFleetMetrics()
 
 	// CORS configuration
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"*"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", requestIDHeader}
+	config.ExposeHeaders = []string{requestIDHeader, totalCountHeader, "Retry-After"}
 	router.Use(cors.New(config))
+	router.Use(MetricsMiddleware())
+	router.Use(RequestTimeoutMiddleware(s.requestTimeout, s.uploadTimeout, "/api/v1/ws/", "/api/v1/stream/"))
+
+	// Rate limits for the routes that guess secrets or do expensive work
+	authLimit := s.rateLimit("auth", s.rateLimits.Auth)
+	uploadLimit := s.rateLimit("upload", s.rateLimits.Uploads)
+	syncLimit := s.rateLimit("sync", s.rateLimits.Syncs)
 
 	// Public routes
 	router.GET("/health", s.handleHealth)
-	router.POST("/api/v1/auth/login", s.handleLogin)
-	router.GET("/api/v1/auth/google", s.handleGoogleLogin)
-	router.GET("/api/v1/auth/google/callback", s.handleGoogleCallback)
+	router.GET("/healthz", s.handleLiveness)
+	router.GET("/readyz", s.handleReadiness)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.POST("/api/v1/auth/login", authLimit, s.handleLogin)
+	router.GET("/api/v1/auth/google", authLimit, s.handleGoogleLogin)
+	router.GET("/api/v1/auth/google/callback", authLimit, s.handleGoogleCallback)
+	router.GET("/api/v1/auth/oidc", authLimit, s.handleOIDCLogin)
+	router.GET("/api/v1/auth/oidc/callback", authLimit, s.handleOIDCCallback)
+	router.GET("/api/v1/auth/providers", s.handleAuthProviders)
+
+	// API reference
+	router.GET(openAPIPath, s.handleOpenAPISpec)
+	router.GET(apiDocsPath, handleAPIDocs)
+
+	// Public status pages (status page token in the path), limited per client IP
+	statusPages := router.Group("/api/v1/status-pages")
+	statusPages.Use(s.rateLimit("status-page", s.rateLimits.Requests))
+	{
+		statusPages.GET("/:token", s.handlePublicStatusPage)
+		statusPages.GET("/:token/html", s.handlePublicStatusPageHTML)
+	}
+
+	// Public status badges (badge token in the path), limited per client IP
+	public := router.Group("/api/v1/public")
+	public.Use(s.rateLimit("badge", s.rateLimits.Requests))
+	{
+		public.GET("/properties/:token/badge.svg", s.handlePublicPropertyBadge)
+	}
+
+	// Files in local blob storage (signed link in the query)
+	router.GET(storage.LocalBlobPath+"*name", s.handleGetLocalBlob)
+
+	// Live updates (token may be passed as a query parameter)
+	stream := router.Group("/api/v1/ws")
+	stream.Use(StreamAuthMiddleware(s.postgres))
+	{
+		stream.GET("/dashboard", s.handleDashboardWebSocket)
+	}
+
+	// Own account settings, open to viewers too since they only change the caller's account
+	account := router.Group("/api/v1/auth")
+	account.Use(AuthMiddleware(s.postgres), s.rateLimit("api", s.rateLimits.Requests))
+	{
+		account.PUT("/me/password", authLimit, s.handleChangePassword)
+		account.POST("/logout", s.handleLogout)
+		account.GET("/sessions", s.handleListSessions)
+		account.DELETE("/sessions", s.handleRevokeOtherSessions)
+		account.DELETE("/sessions/:id", s.handleRevokeSession)
+		account.GET("/totp", s.handleGetTOTPStatus)
+		account.POST("/totp/enroll", s.handleEnrollTOTP)
+		account.POST("/totp/verify", authLimit, s.handleVerifyTOTP)
+		account.POST("/totp/recovery-codes", authLimit, s.handleRegenerateRecoveryCodes)
+		account.POST("/totp/disable", authLimit, s.handleDisableTOTP)
+	}
+
+	// Server-Sent Events (token may be passed as a query parameter)
+	events := router.Group("/api/v1/stream")
+	events.Use(StreamAuthMiddleware(s.postgres))
+	{
+		events.GET("/devices", s.handleDeviceStatusStream)
+	}
+
+	// Remote probe agents (probe token)
+	probe := router.Group("/api/v1/probe")
+	probe.Use(ProbeAuthMiddleware(s.postgres))
+	{
+		probe.GET("/devices", s.handleProbeDevices)
+		probe.POST("/results", s.handleProbeResults)
+	}
 
 	// Protected routes
 	api := router.Group("/api/v1")
-	api.Use(AuthMiddleware(s.postgres))
+	api.Use(AuthMiddleware(s.postgres), ViewerReadOnlyMiddleware(), s.rateLimit("api", s.rateLimits.Requests))
 	{
 		// Auth
 		api.GET("/auth/me", s.handleGetMe)
 
 		// Dashboard
 		api.GET("/dashboard", s.handleDashboard)
+		api.GET("/workers", s.handleGetWorkerHealth)
+		api.GET("/stats", s.handleGetStats)
+		api.GET("/map", s.handleGetMap)
+
+		// Regions and property groups
+		api.GET("/regions", s.handleListRegions)
+		api.GET("/regions/:id", s.handleGetRegion)
+		api.GET("/property-groups", s.handleListPropertyGroups)
+		api.GET("/property-groups/:id", s.handleGetPropertyGroup)
 
 		// Properties
 		api.GET("/properties", s.handleListProperties)
@@ -36,9 +129,34 @@ func (s *Server) SetupRouter() *gin.Engine {
 		api.GET("/properties/:id", s.handleGetProperty)
 		api.PUT("/properties/:id", s.handleUpdateProperty)
 		api.DELETE("/properties/:id", s.handleDeleteProperty)
+		api.POST("/properties/:id/clone", s.handleCloneProperty)
 		api.GET("/properties/:id/status", s.handleGetPropertyStatus)
 		api.GET("/properties/:id/devices", s.handleGetPropertyDevices)
-		api.POST("/properties/:id/sync-devices", s.handleSyncDevicesFromPfSense)
+		api.GET("/properties/:id/uptime", s.handleGetPropertyUptime)
+		api.GET("/properties/:id/availability", s.handleGetPropertyAvailability)
+		api.GET("/properties/:id/topology", s.handleGetPropertyTopology)
+		api.GET("/properties/:id/devices/export", s.handleExportPropertyDevices)
+		api.POST("/properties/:id/devices/import", uploadLimit, s.handleImportPropertyDevices)
+		api.POST("/properties/:id/sync-devices", syncLimit, s.handleSyncDevicesFromPfSense)
+		api.GET("/properties/:id/leases", s.handleGetPropertyLeases)
+		api.GET("/properties/:id/arp", s.handleGetPropertyARP)
+		api.GET("/properties/:id/vpn", s.handleGetPropertyVPN)
+		api.POST("/properties/:id/pfsense/test", syncLimit, s.handleTestPfSense)
+		api.GET("/properties/:id/traffic", s.handleGetPropertyTraffic)
+
+		// Firewalls (HA pairs)
+		api.GET("/properties/:id/firewalls", s.handleListFirewallsForProperty)
+		api.POST("/properties/:id/firewalls", s.handleCreateFirewall)
+		api.GET("/firewalls/:id", s.handleGetFirewall)
+		api.PUT("/firewalls/:id", s.handleUpdateFirewall)
+		api.DELETE("/firewalls/:id", s.handleDeleteFirewall)
+		api.GET("/firewalls/:id/carp", s.handleGetFirewallCARP)
+
+		// UniFi controllers and WiFi telemetry
+		api.GET("/properties/:id/unifi", s.handleGetUniFiController)
+		api.PUT("/properties/:id/unifi", s.handleSaveUniFiController)
+		api.DELETE("/properties/:id/unifi", s.handleDeleteUniFiController)
+		api.GET("/properties/:id/wifi", s.handleGetPropertyWiFi)
 
 		// Contacts
 		api.GET("/properties/:id/contacts", s.handleListContactsForProperty)
@@ -47,37 +165,189 @@ func (s *Server) SetupRouter() *gin.Engine {
 		api.PUT("/contacts/:id", s.handleUpdateContact)
 		api.DELETE("/contacts/:id", s.handleDeleteContact)
 
+		// ISP circuits
+		api.GET("/properties/:id/circuits", s.handleListCircuitsForProperty)
+		api.POST("/properties/:id/circuits", s.handleCreateCircuit)
+		api.GET("/circuits/:id", s.handleGetCircuit)
+		api.PUT("/circuits/:id", s.handleUpdateCircuit)
+		api.DELETE("/circuits/:id", s.handleDeleteCircuit)
+
 		// Attachments
 		api.GET("/properties/:id/attachments", s.handleListAttachmentsForProperty)
-		api.POST("/properties/:id/attachments", s.handleUploadAttachment)
+		api.POST("/properties/:id/attachments", uploadLimit, s.handleUploadAttachment)
+		api.POST("/properties/:id/attachments/google-drive", s.handleLinkDriveAttachment)
+		api.GET("/attachments/search", s.handleSearchAttachments)
 		api.GET("/attachments/:id/download", s.handleDownloadAttachment)
+		api.PUT("/attachments/:id", s.handleUpdateAttachment)
 		api.DELETE("/attachments/:id", s.handleDeleteAttachment)
+		api.POST("/properties/:id/attachment-uploads", uploadLimit, s.handleStartAttachmentUpload)
+		api.GET("/attachment-uploads/:id", s.handleGetAttachmentUpload)
+		api.PUT("/attachment-uploads/:id/parts", s.handleUploadAttachmentPart)
+		api.POST("/attachment-uploads/:id/complete", s.handleCompleteAttachmentUpload)
+		api.DELETE("/attachment-uploads/:id", s.handleAbortAttachmentUpload)
 
 		// Devices
 		api.GET("/devices", s.handleListDevices)
 		api.POST("/devices", s.handleCreateDevice)
+		api.POST("/devices/bulk", s.handleBulkDevices)
+		api.GET("/devices/warranty", s.handleGetWarrantyReport)
+		api.GET("/devices/firmware", s.handleGetFirmwareReport)
+		api.GET("/audits/devices", s.handleGetDeviceAudit)
 		api.GET("/devices/:id", s.handleGetDevice)
 		api.PUT("/devices/:id", s.handleUpdateDevice)
 		api.DELETE("/devices/:id", s.handleDeleteDevice)
 		api.GET("/devices/:id/status", s.handleGetDeviceStatus)
 		api.GET("/devices/:id/history", s.handleGetDeviceHistory)
+		api.GET("/devices/:id/history/rollups", s.handleGetDeviceHistoryRollups)
 		api.GET("/devices/:id/errors", s.handleGetDeviceErrors)
+		api.GET("/devices/:id/uptime", s.handleGetDeviceUptime)
+		api.GET("/devices/:id/availability", s.handleGetDeviceAvailability)
+
+		// Device templates
+		api.GET("/device-templates", s.handleListDeviceTemplates)
+		api.POST("/device-templates", s.handleCreateDeviceTemplate)
+		api.GET("/device-templates/:id", s.handleGetDeviceTemplate)
+		api.PUT("/device-templates/:id", s.handleUpdateDeviceTemplate)
+		api.DELETE("/device-templates/:id", s.handleDeleteDeviceTemplate)
+		api.POST("/device-templates/:id/apply", s.handleApplyDeviceTemplate)
+
+		// Property notifications
+		api.GET("/properties/:id/notifications", s.handleListPropertyNotifications)
+		api.POST("/properties/:id/notifications", s.handleCreatePropertyNotification)
+		api.PUT("/property-notifications/:id", s.handleUpdatePropertyNotification)
+		api.DELETE("/property-notifications/:id", s.handleDeletePropertyNotification)
+		api.GET("/properties/:id/notification-events", s.handleListNotificationEvents)
+
+		// Status events
+		api.GET("/status-events", s.handleListStatusEvents)
+		api.GET("/properties/:id/status-events", s.handleListPropertyStatusEvents)
+
+		// Activity feed
+		api.GET("/events", s.handleListActivity)
+
+		// Acknowledgements and silences
+		api.GET("/acknowledgements", s.handleListAcknowledgements)
+		api.POST("/properties/:id/acknowledge", s.handleAcknowledgeProperty)
+		api.DELETE("/properties/:id/acknowledge", s.handleUnacknowledgeProperty)
+		api.POST("/devices/:id/acknowledge", s.handleAcknowledgeDevice)
+		api.DELETE("/devices/:id/acknowledge", s.handleUnacknowledgeDevice)
+		api.GET("/silences", s.handleListSilences)
+		api.POST("/silences", s.handleCreateSilence)
+		api.DELETE("/silences/:id", s.handleDeleteSilence)
+
+		// Digest subscriptions
+		api.GET("/digest-subscriptions", s.handleListDigestSubscriptions)
+		api.POST("/digest-subscriptions", s.handleCreateDigestSubscription)
+		api.PUT("/digest-subscriptions/:id", s.handleUpdateDigestSubscription)
+		api.DELETE("/digest-subscriptions/:id", s.handleDeleteDigestSubscription)
+		api.GET("/digest-subscriptions/:id/preview", s.handlePreviewDigestSubscription)
+
+		// On-call
+		api.GET("/oncall/current", s.handleGetCurrentOnCall)
+		api.GET("/oncall/schedules", s.handleListOnCallSchedules)
+		api.GET("/oncall/schedules/:id", s.handleGetOnCallSchedule)
+		api.GET("/oncall/schedules/:id/overrides", s.handleListOnCallOverrides)
+
+		// Maintenance windows
+		api.GET("/maintenance-windows", s.handleListMaintenanceWindows)
+		api.POST("/maintenance-windows", s.handleCreateMaintenanceWindow)
+		api.GET("/maintenance-windows/:id", s.handleGetMaintenanceWindow)
+		api.PUT("/maintenance-windows/:id", s.handleUpdateMaintenanceWindow)
+		api.DELETE("/maintenance-windows/:id", s.handleDeleteMaintenanceWindow)
+		api.GET("/properties/:id/maintenance-windows", s.handleListMaintenanceWindowsForProperty)
 
 		// Admin-only routes
 		admin := api.Group("")
 		admin.Use(AdminOnlyMiddleware())
 		{
+			// Regions and property groups
+			admin.POST("/regions", s.handleCreateRegion)
+			admin.PUT("/regions/:id", s.handleUpdateRegion)
+			admin.DELETE("/regions/:id", s.handleDeleteRegion)
+			admin.POST("/property-groups", s.handleCreatePropertyGroup)
+			admin.PUT("/property-groups/:id", s.handleUpdatePropertyGroup)
+			admin.DELETE("/property-groups/:id", s.handleDeletePropertyGroup)
+			admin.GET("/property-groups/:id/status-page", s.handleGetStatusPage)
+			admin.PUT("/property-groups/:id/status-page", s.handleSaveStatusPage)
+			admin.DELETE("/property-groups/:id/status-page", s.handleDeleteStatusPage)
+			admin.POST("/property-groups/:id/status-page/token", s.handleRotateStatusPageToken)
+			admin.GET("/properties/:id/badge", s.handleGetPropertyBadge)
+			admin.POST("/properties/:id/badge", s.handleCreatePropertyBadge)
+			admin.DELETE("/properties/:id/badge", s.handleDeletePropertyBadge)
+
 			// Users
 			admin.GET("/users", s.handleListUsers)
 			admin.POST("/users", s.handleCreateUser)
 			admin.PUT("/users/:id", s.handleUpdateUser)
 			admin.DELETE("/users/:id", s.handleDeleteUser)
+			admin.DELETE("/users/:id/totp", s.handleResetUserTOTP)
+			admin.GET("/users/:id/sessions", s.handleListUserSessions)
+			admin.DELETE("/users/:id/sessions", s.handleRevokeUserSessions)
 
 			// Settings
 			admin.GET("/settings", s.handleGetSettings)
 			admin.PUT("/settings", s.handleUpdateSettings)
+
+			// Configuration export and import
+			admin.GET("/config/export", s.handleExportConfig)
+			admin.POST("/config/import", uploadLimit, s.handleImportConfig)
+
+			// Attachment files left in blob storage with no attachment
+			admin.POST("/attachments/orphans/cleanup", s.handleCleanupOrphanedAttachments)
+
+			// Notification channels
+			admin.GET("/notification-channels", s.handleListNotificationChannels)
+			admin.POST("/notification-channels", s.handleCreateNotificationChannel)
+			admin.GET("/notification-channels/:id", s.handleGetNotificationChannel)
+			admin.PUT("/notification-channels/:id", s.handleUpdateNotificationChannel)
+			admin.DELETE("/notification-channels/:id", s.handleDeleteNotificationChannel)
+			admin.POST("/notification-channels/:id/test", s.handleTestNotificationChannel)
+			admin.GET("/notification-events", s.handleListAllNotificationEvents)
+			admin.GET("/notification-rules", s.handleListNotificationRules)
+			admin.POST("/notification-rules", s.handleCreateNotificationRule)
+			admin.GET("/notification-rules/:id", s.handleGetNotificationRule)
+			admin.PUT("/notification-rules/:id", s.handleUpdateNotificationRule)
+			admin.DELETE("/notification-rules/:id", s.handleDeleteNotificationRule)
+
+			// On-call schedules
+			admin.POST("/oncall/schedules", s.handleCreateOnCallSchedule)
+			admin.PUT("/oncall/schedules/:id", s.handleUpdateOnCallSchedule)
+			admin.DELETE("/oncall/schedules/:id", s.handleDeleteOnCallSchedule)
+			admin.POST("/oncall/schedules/:id/overrides", s.handleCreateOnCallOverride)
+			admin.DELETE("/oncall/overrides/:id", s.handleDeleteOnCallOverride)
+
+			// pfSense config backups
+			admin.GET("/properties/:id/config-backups", s.handleListConfigBackups)
+			admin.POST("/properties/:id/config-backups", syncLimit, s.handleCreateConfigBackup)
+			admin.GET("/config-backups/:id/download", s.handleDownloadConfigBackup)
+
+			// DHCP static mappings pushed to pfSense
+			admin.PUT("/properties/:id/dhcp-mappings", s.handlePushDHCPMapping)
+			admin.DELETE("/properties/:id/dhcp-mappings/:mac", s.handleDeleteDHCPMapping)
+			admin.GET("/properties/:id/dhcp-mappings/changes", s.handleListDHCPMappingChanges)
+
+			// Remote probes
+			admin.GET("/probes", s.handleListProbes)
+			admin.POST("/probes", s.handleCreateProbe)
+			admin.DELETE("/probes/:id", s.handleDeleteProbe)
+
+			// Outbound webhooks and their delivery log
+			admin.GET("/webhooks", s.handleListWebhooks)
+			admin.POST("/webhooks", s.handleCreateWebhook)
+			admin.GET("/webhooks/:id", s.handleGetWebhook)
+			admin.PUT("/webhooks/:id", s.handleUpdateWebhook)
+			admin.DELETE("/webhooks/:id", s.handleDeleteWebhook)
+			admin.POST("/webhooks/:id/test", s.handleTestWebhook)
+			admin.GET("/webhooks/:id/deliveries", s.handleListWebhookDeliveries)
+			admin.POST("/webhook-deliveries/:id/replay", s.handleReplayWebhookDelivery)
 		}
 	}
 
+	spec, err := buildOpenAPISpec(router.Routes())
+	if err != nil {
+		slog.Error("Failed to build OpenAPI document", "error", err)
+	}
+	s.openAPISpec = spec
+
 	return router
 }
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	// sessionLifetime is how long a sign-in lasts
+	sessionLifetime = 24 * time.Hour
+	// sessionTouchInterval limits how often a session's last seen time is written
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength caps the user agent kept with a session
+	maxUserAgentLength = 512
+)
+
+// startSession records a new session for a user signing in and returns its token
+func (s *Server) startSession(c *gin.Context, user *models.User) (string, error) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := &models.UserSession{
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		IPAddress: c.ClientIP(),
+		ExpiresAt: time.Now().Add(sessionLifetime),
+	}
+	if err := s.postgres.CreateSession(c.Request.Context(), session); err != nil {
+		return "", err
+	}
+	return generateToken(user, session)
+}
+
+// checkSession looks up the session a token belongs to, rejecting revoked and expired
+// ones, and records its use. Tokens from before sessions were recorded carry no
+// session and are rejected.
+func checkSession(c *gin.Context, postgres storage.Store, claims *Claims) (int64, error) {
+	id, err := strconv.ParseInt(claims.ID, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	ctx := c.Request.Context()
+	session, err := postgres.GetSession(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	if session.UserID != claims.UserID || session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return 0, fmt.Errorf("session is no longer active")
+	}
+
+	ip := c.ClientIP()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval || session.IPAddress != ip {
+		if err := postgres.TouchSession(ctx, id, ip, now); err != nil {
+			requestLog(c).Warn("Failed to update session", "session_id", id, "error", err)
+		}
+	}
+	return id, nil
+}
+
+// handleListSessions lists the current user's active sessions, flagging the one making
+// the request
+func (s *Server) handleListSessions(c *gin.Context) {
+	sessions, err := s.postgres.ListActiveSessions(c.Request.Context(), c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	current := c.GetInt64("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// handleRevokeSession signs out one of the current user's sessions
+func (s *Server) handleRevokeSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid session ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := s.postgres.GetSession(ctx, id)
+	if err != nil || session.UserID != c.GetInt64("user_id") {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Session not found"})
+		return
+	}
+	if err := s.postgres.RevokeSession(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Session not found"})
+		return
+	}
+
+	requestLog(c).Info("Session revoked", "revoked_session_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// handleRevokeOtherSessions signs out every session of the current user but the one
+// making the request
+func (s *Server) handleRevokeOtherSessions(c *gin.Context) {
+	revoked, err := s.postgres.RevokeUserSessions(c.Request.Context(), c.GetInt64("user_id"), c.GetInt64("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("Other sessions revoked", "count", revoked)
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// handleLogout signs out the session making the request
+func (s *Server) handleLogout(c *gin.Context) {
+	if err := s.postgres.RevokeSession(c.Request.Context(), c.GetInt64("session_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// handleListUserSessions lists a user's active sessions for an admin
+func (s *Server) handleListUserSessions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	sessions, err := s.postgres.ListActiveSessions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	current := c.GetInt64("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// handleRevokeUserSessions signs a user out everywhere, for an admin
+func (s *Server) handleRevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	revoked, err := s.postgres.RevokeUserSessions(c.Request.Context(), id, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("User sessions revoked", "target_user_id", id, "count", revoked)
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
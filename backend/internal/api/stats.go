@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Fleet statistics
+
+// defaultWorstPropertyLimit and maxWorstPropertyLimit bound the worst properties listed
+const (
+	defaultWorstPropertyLimit = 10
+	maxWorstPropertyLimit     = 100
+)
+
+// handleGetStats returns fleet-wide numbers for operations reviews: devices by type and
+// status, property statuses, incidents, the properties with the worst uptime and the
+// daily mean latency. ?period= (default 7d) sets the window for uptime, outages and
+// latency; ?limit= (default 10) how many worst properties are listed.
+func (s *Server) handleGetStats(c *gin.Context) {
+	period, err := parsePeriod(c.DefaultQuery("period", "7d"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	limit := defaultWorstPropertyLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if limit > maxWorstPropertyLimit {
+		limit = maxWorstPropertyLimit
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now()
+	stats := models.FleetStats{
+		PeriodStart:     now.Add(-period),
+		PeriodEnd:       now,
+		GeneratedAt:     now,
+		DevicesByType:   make(map[string]int),
+		WorstProperties: make([]models.PropertyUptimeSummary, 0),
+	}
+
+	properties, err := s.postgres.ListProperties(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	devices, err := s.postgres.ListDevices(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	deviceStatuses, err := s.redis.GetAllDeviceStatuses(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	propertyStatuses, err := s.redis.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	acks, err := s.postgres.ListActiveAcknowledgements(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	acknowledged := make(map[int64]bool)
+	for _, ack := range acks {
+		if ack.EntityType == "property" {
+			acknowledged[ack.PropertyID] = true
+		}
+	}
+
+	for _, d := range devices {
+		if !d.Active {
+			continue
+		}
+		stats.DeviceCount++
+		stats.DevicesByType[d.DeviceType]++
+
+		status, ok := deviceStatuses[d.ID]
+		switch {
+		case !ok:
+			stats.Devices.Unknown++
+		case status.Status == "online":
+			stats.Devices.Up++
+		case status.Status == "unreachable":
+			stats.Devices.Unreachable++
+		default:
+			stats.Devices.Down++
+		}
+	}
+
+	// Properties without a status yet count as green, as on the dashboard
+	stats.PropertyCount = len(properties)
+	for _, p := range properties {
+		status, ok := propertyStatuses[p.ID]
+		if !ok {
+			stats.Properties.Green++
+			continue
+		}
+		switch status.Status {
+		case "red":
+			stats.Properties.Red++
+			if !status.Maintenance && !status.Flapping {
+				stats.Incidents.Open++
+				if acknowledged[p.ID] {
+					stats.Incidents.Acknowledged++
+				}
+			}
+		case "yellow":
+			stats.Properties.Yellow++
+		default:
+			stats.Properties.Green++
+		}
+	}
+
+	outages, err := s.postgres.GetPropertyOutageSummaries(ctx, nil, stats.PeriodStart, stats.PeriodEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	periodMinutes := period.Minutes()
+	for _, o := range outages {
+		stats.Incidents.Opened += o.OutageCount
+		stats.WorstProperties = append(stats.WorstProperties, models.PropertyUptimeSummary{
+			PropertyID:      o.PropertyID,
+			PropertyName:    o.PropertyName,
+			UptimePercent:   max(0, 100*(periodMinutes-o.DowntimeMinutes)/periodMinutes),
+			DowntimeMinutes: o.DowntimeMinutes,
+			OutageCount:     o.OutageCount,
+		})
+	}
+	sort.SliceStable(stats.WorstProperties, func(i, j int) bool {
+		return stats.WorstProperties[i].DowntimeMinutes > stats.WorstProperties[j].DowntimeMinutes
+	})
+	if len(stats.WorstProperties) > limit {
+		stats.WorstProperties = stats.WorstProperties[:limit]
+	}
+
+	// Daily rollups start at midnight UTC, so the first day is the one the period starts in
+	y, m, d := stats.PeriodStart.UTC().Date()
+	stats.LatencyTrend, err = s.postgres.GetLatencyTrend(ctx, time.Date(y, m, d, 0, 0, 0, 0, time.UTC), stats.PeriodEnd)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
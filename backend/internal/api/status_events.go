@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const maxStatusEventLimit = 1000
+
+// statusEventFilter builds a filter from entity_type, device_id, status, start, end
+// and limit query parameters
+func statusEventFilter(c *gin.Context) (storage.StatusEventFilter, bool) {
+	filter := storage.StatusEventFilter{
+		EntityType: c.Query("entity_type"),
+		ToStatus:   c.Query("status"),
+		Limit:      100,
+	}
+
+	if filter.EntityType != "" && filter.EntityType != "device" && filter.EntityType != "property" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "entity_type must be device or property"})
+		return filter, false
+	}
+
+	if v := c.Query("device_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device ID"})
+			return filter, false
+		}
+		filter.DeviceID = id
+	}
+
+	for _, p := range []struct {
+		name string
+		dest *time.Time
+	}{{"start", &filter.Start}, {"end", &filter.End}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid " + p.name + " time, expected RFC3339"})
+				return filter, false
+			}
+			*p.dest = t
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			filter.Limit = l
+		}
+	}
+	if filter.Limit > maxStatusEventLimit {
+		filter.Limit = maxStatusEventLimit
+	}
+
+	return filter, true
+}
+
+func (s *Server) handleListStatusEvents(c *gin.Context) {
+	filter, ok := statusEventFilter(c)
+	if !ok {
+		return
+	}
+
+	if v := c.Query("property_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+			return
+		}
+		filter.PropertyID = id
+	}
+
+	events, err := s.postgres.ListStatusEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func (s *Server) handleListPropertyStatusEvents(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	filter, ok := statusEventFilter(c)
+	if !ok {
+		return
+	}
+	filter.PropertyID = id
+
+	events, err := s.postgres.ListStatusEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,370 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Public status pages
+
+type statusPageRequest struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// hashPublicToken returns the stored form of a status page or badge token
+func hashPublicToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newPublicToken returns a random token for a status page or badge link
+func newPublicToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *Server) handleGetStatusPage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	page, err := s.postgres.GetStatusPageForGroup(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Status page not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// handleSaveStatusPage sets a group's status page title and message, creating the page
+// with a new token if the group doesn't have one. The token is only returned then.
+func (s *Server) handleSaveStatusPage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	var req statusPageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	title, message := strings.TrimSpace(req.Title), strings.TrimSpace(req.Message)
+	if len(title) > 255 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "title must be at most 255 characters"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.postgres.GetPropertyGroup(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property group not found"})
+		return
+	}
+
+	if page, err := s.postgres.GetStatusPageForGroup(ctx, id); err == nil {
+		page.Title, page.Message = title, message
+		if err := s.postgres.UpdateStatusPage(ctx, page, ""); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	token, err := newPublicToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+	page := models.StatusPage{PropertyGroupID: id, Title: title, Message: message}
+	if err := s.postgres.CreateStatusPage(ctx, &page, hashPublicToken(token)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	page.Token = token
+	c.JSON(http.StatusCreated, page)
+}
+
+// handleRotateStatusPageToken replaces a status page's token, so links with the old
+// one stop working, and returns the new one
+func (s *Server) handleRotateStatusPageToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	page, err := s.postgres.GetStatusPageForGroup(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Status page not found"})
+		return
+	}
+
+	token, err := newPublicToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+	if err := s.postgres.UpdateStatusPage(ctx, page, hashPublicToken(token)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("Rotated status page token", "property_group_id", id)
+	page.Token = token
+	c.JSON(http.StatusOK, page)
+}
+
+func (s *Server) handleDeleteStatusPage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property group ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	page, err := s.postgres.GetStatusPageForGroup(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Status page not found"})
+		return
+	}
+	if err := s.postgres.DeleteStatusPage(ctx, page.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Status page deleted"})
+}
+
+// handlePublicStatusPage returns the status page for a token, without sign-in
+func (s *Server) handlePublicStatusPage(c *gin.Context) {
+	page, ok := s.publicStatusPage(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=30")
+	c.JSON(http.StatusOK, page)
+}
+
+// handlePublicStatusPageHTML renders the status page for a token as a web page that
+// refreshes itself every minute
+func (s *Server) handlePublicStatusPageHTML(c *gin.Context) {
+	page, ok := s.publicStatusPage(c)
+	if !ok {
+		return
+	}
+
+	var buf strings.Builder
+	if err := statusPageTemplate.Execute(&buf, page); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to render status page"})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=30")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(buf.String()))
+}
+
+// publicStatusPage builds the status page named by the :token param, writing the
+// error response if there isn't one
+func (s *Server) publicStatusPage(c *gin.Context) (*models.PublicStatusPage, bool) {
+	ctx := c.Request.Context()
+	page, err := s.postgres.GetStatusPageByTokenHash(ctx, hashPublicToken(c.Param("token")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Status page not found"})
+		return nil, false
+	}
+
+	group, err := s.postgres.GetPropertyGroup(ctx, page.PropertyGroupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Status page not found"})
+		return nil, false
+	}
+	properties, err := s.postgres.ListProperties(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to load status"})
+		return nil, false
+	}
+	statuses, err := s.redis.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to load status"})
+		return nil, false
+	}
+
+	public := &models.PublicStatusPage{
+		Title:       page.Title,
+		Message:     page.Message,
+		Status:      "green",
+		Properties:  []models.PublicPropertyStatus{},
+		Incidents:   []models.PublicIncident{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	if public.Title == "" {
+		public.Title = group.Name
+	}
+
+	for _, p := range properties {
+		if p.GroupID == nil || *p.GroupID != group.ID {
+			continue
+		}
+		entry, incident := publicPropertyStatus(&p, statuses[p.ID])
+		public.Properties = append(public.Properties, entry)
+		if incident != nil {
+			public.Incidents = append(public.Incidents, *incident)
+		}
+		// Planned maintenance doesn't count against the page's overall status
+		if !entry.Maintenance && statusSeverity(entry.Status) > statusSeverity(public.Status) {
+			public.Status = entry.Status
+		}
+	}
+	sort.Slice(public.Incidents, func(i, j int) bool {
+		return public.Incidents[i].StartedAt.Before(public.Incidents[j].StartedAt)
+	})
+
+	switch public.Status {
+	case "red":
+		public.Summary = "Some locations are experiencing a service disruption"
+	case "yellow":
+		public.Summary = "Some locations are experiencing degraded service"
+	default:
+		public.Summary = "All systems operational"
+	}
+	return public, true
+}
+
+// publicPropertyStatus describes a property's status in customer-safe wording, with
+// the incident to list when it is down or in maintenance
+func publicPropertyStatus(p *models.Property, status *models.PropertyStatus) (models.PublicPropertyStatus, *models.PublicIncident) {
+	entry := models.PublicPropertyStatus{Name: p.Name, Status: "unknown", Label: "Status unavailable"}
+	if status == nil {
+		return entry, nil
+	}
+
+	entry.Status = status.Status
+	entry.Maintenance = status.Maintenance
+	if !status.Since.IsZero() {
+		since := status.Since
+		entry.Since = &since
+	}
+	started := status.Since
+	if started.IsZero() {
+		started = status.LastCheck
+	}
+
+	switch {
+	case status.Maintenance && status.Status != "green":
+		entry.Label = "Scheduled maintenance"
+		return entry, &models.PublicIncident{
+			Property:  p.Name,
+			Status:    "maintenance",
+			Title:     "Scheduled maintenance",
+			Message:   "Planned maintenance is in progress. Service may be interrupted until it is complete.",
+			StartedAt: started,
+		}
+	case status.Status == "red":
+		entry.Label = "Service disruption"
+		return entry, &models.PublicIncident{
+			Property:  p.Name,
+			Status:    "red",
+			Title:     "Service disruption",
+			Message:   "We are aware of an issue affecting service at this location and are working to restore it.",
+			StartedAt: started,
+		}
+	case status.Status == "yellow":
+		entry.Label = "Degraded service"
+	default:
+		entry.Label = "Operational"
+	}
+	return entry, nil
+}
+
+// statusSeverity orders property statuses from best to worst
+func statusSeverity(status string) int {
+	switch status {
+	case "red":
+		return 2
+	case "yellow":
+		return 1
+	default:
+		return 0
+	}
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"since": func(t time.Time) string { return t.UTC().Format("Jan 2, 15:04 MST") },
+	"color": func(status string) string {
+		switch status {
+		case "red":
+			return "#d64545"
+		case "yellow":
+			return "#e0a526"
+		case "green":
+			return "#2f9e5b"
+		case "maintenance":
+			return "#4a7bd0"
+		}
+		return "#9aa0a6"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta http-equiv="refresh" content="60">
+  <title>{{.Title}} status</title>
+  <style>
+    body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 0; background: #f5f6f8; color: #222; }
+    main { max-width: 720px; margin: 0 auto; padding: 32px 16px; }
+    .banner { padding: 16px 20px; border-radius: 6px; color: #fff; font-size: 1.1em; }
+    .card { background: #fff; border-radius: 6px; margin-top: 16px; padding: 4px 20px; box-shadow: 0 1px 2px rgba(0,0,0,.08); }
+    .row { display: flex; justify-content: space-between; padding: 12px 0; border-bottom: 1px solid #eee; }
+    .row:last-child { border-bottom: none; }
+    .dot { display: inline-block; width: 10px; height: 10px; border-radius: 50%; margin-right: 8px; }
+    .muted { color: #6b7280; font-size: .9em; }
+    h2 { font-size: 1em; margin: 24px 0 0; }
+  </style>
+</head>
+<body>
+<main>
+  <h1>{{.Title}}</h1>
+  <div class="banner" style="background: {{color .Status}}">{{.Summary}}</div>
+  {{with .Message}}<div class="card"><p>{{.}}</p></div>{{end}}
+  {{if .Incidents}}
+  <h2>Current incidents</h2>
+  <div class="card">
+    {{range .Incidents}}
+    <div class="row"><div><span class="dot" style="background: {{color .Status}}"></span><strong>{{.Property}}</strong>: {{.Title}}<div class="muted">{{.Message}}</div></div><div class="muted">since {{since .StartedAt}}</div></div>
+    {{end}}
+  </div>
+  {{end}}
+  <h2>Locations</h2>
+  <div class="card">
+    {{range .Properties}}
+    <div class="row"><div><span class="dot" style="background: {{color .Status}}"></span>{{.Name}}</div><div class="muted">{{.Label}}</div></div>
+    {{else}}
+    <p class="muted">No locations</p>
+    {{end}}
+  </div>
+  <p class="muted">Updated {{since .GeneratedAt}}. This page refreshes every minute.</p>
+</main>
+</body>
+</html>
+`))
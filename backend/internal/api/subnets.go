@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Manually assigned property subnets must be between a /16 and a /30, so there is room
+// for the router at the first address
+const (
+	minSubnetBits = 16
+	maxSubnetBits = 30
+)
+
+// validatePropertySubnet normalizes a manually assigned subnet and checks it overlaps no
+// other property's. An empty subnet is assigned automatically on create and kept on
+// update.
+func (s *Server) validatePropertySubnet(ctx context.Context, property *models.Property) error {
+	if property.Subnet == "" {
+		return nil
+	}
+	subnet, err := netip.ParsePrefix(property.Subnet)
+	if err != nil || !subnet.Addr().Is4() {
+		return fmt.Errorf("subnet must be an IPv4 CIDR such as 10.120.4.0/24")
+	}
+	if subnet.Bits() < minSubnetBits || subnet.Bits() > maxSubnetBits {
+		return fmt.Errorf("subnet must be between a /%d and a /%d", minSubnetBits, maxSubnetBits)
+	}
+	subnet = subnet.Masked()
+	property.Subnet = subnet.String()
+
+	properties, err := s.postgres.ListProperties(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range properties {
+		if p.ID == property.ID || p.Subnet == "" {
+			continue
+		}
+		other, err := netip.ParsePrefix(p.Subnet)
+		if err == nil && other.Overlaps(subnet) {
+			return fmt.Errorf("subnet %s overlaps %s at %s", subnet, other, p.Name)
+		}
+	}
+	return nil
+}
+
+// normalizeSubnetBase checks the start of the automatic subnet scheme is the first
+// address of an IPv4 /24
+func normalizeSubnetBase(base string) (string, error) {
+	addr, err := netip.ParseAddr(base)
+	if err != nil || !addr.Is4() {
+		return "", fmt.Errorf("must be an IPv4 address such as 10.99.0.0")
+	}
+	if addr.As4()[3] != 0 {
+		return "", fmt.Errorf("must end in .0")
+	}
+	return addr.String(), nil
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultRequestTimeout bounds how long a request's queries and outbound calls may run
+const DefaultRequestTimeout = 60 * time.Second
+
+// DefaultUploadTimeout bounds the upload and import routes, which stream a body of up
+// to hundreds of megabytes to blob storage or the database
+const DefaultUploadTimeout = 30 * time.Minute
+
+// uploadRoutes run under the upload timeout instead of the request timeout, keyed by
+// method and route
+var uploadRoutes = map[string]bool{
+	"POST /api/v1/properties/:id/attachments":    true,
+	"PUT /api/v1/attachment-uploads/:id/parts":   true,
+	"POST /api/v1/properties/:id/devices/import": true,
+	"POST /api/v1/config/import":                 true,
+}
+
+// RequestTimeoutMiddleware gives each request a context that expires after timeout, so
+// the queries and calls made for a slow or abandoned request are cancelled. The
+// upload and import routes get uploadTimeout instead. Paths under skipPrefixes, which
+// hold their connection open, are left alone, as is any request whose limit is zero.
+func RequestTimeoutMiddleware(timeout, uploadTimeout time.Duration, skipPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := timeout
+		if uploadRoutes[c.Request.Method+" "+c.FullPath()] {
+			timeout = uploadTimeout
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			requestLog(c).Warn("Request timed out", "timeout", timeout.String())
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Site topology
+
+// handleGetPropertyTopology returns a property's devices and the links between them for
+// a site map. Parent devices give the links; a device without a parent on the property
+// is linked to the router on its subnet.
+func (s *Server) handleGetPropertyTopology(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	property, err := s.postgres.GetProperty(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	ids := make([]int64, len(devices))
+	for i, d := range devices {
+		ids[i] = d.ID
+	}
+	statuses, err := s.redis.GetDeviceStatuses(ctx, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildTopology(property, devices, statuses))
+}
+
+// buildTopology turns a property's devices into a graph. Only links between devices of
+// the property are drawn, so a parent at another property leaves its device unlinked.
+func buildTopology(property *models.Property, devices []models.Device, statuses map[int64]*models.DeviceStatus) models.PropertyTopology {
+	topology := models.PropertyTopology{
+		PropertyID: property.ID,
+		Nodes:      make([]models.TopologyNode, 0, len(devices)),
+		Edges:      make([]models.TopologyEdge, 0),
+	}
+
+	// Without a parseable subnet, addresses are grouped by their /24
+	subnet, err := netip.ParsePrefix(property.Subnet)
+	if err == nil {
+		subnet = subnet.Masked()
+	}
+
+	byID := make(map[int64]*models.Device, len(devices))
+	networks := make(map[int64]netip.Prefix, len(devices))
+	routers := make(map[netip.Prefix]*models.Device)
+	routerAddrs := make(map[netip.Prefix]netip.Addr)
+	for i := range devices {
+		d := &devices[i]
+		byID[d.ID] = d
+
+		node := models.TopologyNode{
+			DeviceID:   d.ID,
+			Name:       d.Name,
+			Hostname:   d.Hostname,
+			DeviceType: d.DeviceType,
+			IsCritical: d.IsCritical,
+			Active:     d.Active,
+			Status:     "unknown",
+		}
+		if status := statuses[d.ID]; status != nil {
+			node.Status = status.Status
+			node.Maintenance = status.Maintenance
+		}
+
+		if addr, ok := deviceAddr(d.Hostname); ok {
+			network := addressNetwork(addr, subnet)
+			networks[d.ID] = network
+			node.Network = network.String()
+
+			// The lowest addressed router on a subnet is taken as its gateway
+			if isRouter(d) {
+				if current, ok := routerAddrs[network]; !ok || addr.Less(current) {
+					routers[network], routerAddrs[network] = d, addr
+				}
+			}
+		}
+		topology.Nodes = append(topology.Nodes, node)
+	}
+
+	for i := range devices {
+		d := &devices[i]
+		if d.ParentDeviceID != nil {
+			if _, ok := byID[*d.ParentDeviceID]; ok {
+				topology.Edges = append(topology.Edges, models.TopologyEdge{From: *d.ParentDeviceID, To: d.ID, Kind: "dependency"})
+				continue
+			}
+		}
+
+		// Routers are the roots of their subnets
+		network, ok := networks[d.ID]
+		if !ok || isRouter(d) {
+			continue
+		}
+		router := routers[network]
+		if router == nil || dependsOn(router, d.ID, byID) {
+			continue
+		}
+		topology.Edges = append(topology.Edges, models.TopologyEdge{From: router.ID, To: d.ID, Kind: "subnet"})
+	}
+
+	return topology
+}
+
+// deviceAddr returns the IP address a device hostname, or the host of a ups@host
+// target, refers to
+func deviceAddr(hostname string) (netip.Addr, bool) {
+	if _, host, ok := strings.Cut(hostname, "@"); ok {
+		hostname = host
+	}
+	addr, err := netip.ParseAddr(hostname)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// addressNetwork returns subnet if it holds addr, or else the /24 (/64 for IPv6)
+// around addr
+func addressNetwork(addr netip.Addr, subnet netip.Prefix) netip.Prefix {
+	if subnet.IsValid() && subnet.Contains(addr) {
+		return subnet
+	}
+	bits := 24
+	if addr.Is6() {
+		bits = 64
+	}
+	network, _ := addr.Prefix(bits)
+	return network
+}
+
+// isRouter reports whether a device is a router. Synced devices use "Router" where
+// manually added ones use "router".
+func isRouter(d *models.Device) bool {
+	return strings.EqualFold(d.DeviceType, "router")
+}
+
+// dependsOn reports whether deviceID is among d's parents, so linking deviceID below d
+// would make a loop
+func dependsOn(d *models.Device, deviceID int64, byID map[int64]*models.Device) bool {
+	for range len(byID) {
+		if d.ParentDeviceID == nil {
+			return false
+		}
+		if *d.ParentDeviceID == deviceID {
+			return true
+		}
+		parent, ok := byID[*d.ParentDeviceID]
+		if !ok {
+			return false
+		}
+		d = parent
+	}
+	return false
+}
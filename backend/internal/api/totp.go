@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/totp"
+)
+
+// Two-factor authentication for local accounts
+
+const (
+	// totpIssuer names the NOC in authenticator apps
+	totpIssuer = "ETS NOC"
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+)
+
+type totpCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// handleGetTOTPStatus reports whether the current user has two-factor enabled and how
+// many recovery codes they have left
+func (s *Server) handleGetTOTPStatus(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	ctx := c.Request.Context()
+
+	state, err := s.postgres.GetUserTOTP(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	remaining, err := s.postgres.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": state.Enabled, "recovery_codes_remaining": remaining})
+}
+
+// handleEnrollTOTP starts enrollment with a new secret, returned with its provisioning
+// URI for the authenticator app's QR code. Two-factor stays off until a code from the
+// app is verified.
+func (s *Server) handleEnrollTOTP(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	ctx := c.Request.Context()
+
+	state, err := s.postgres.GetUserTOTP(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if state.Enabled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := s.postgres.SetUserTOTPSecret(ctx, userID, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(totpIssuer, c.GetString("username"), secret),
+	})
+}
+
+// handleVerifyTOTP completes enrollment with a code from the authenticator app, turning
+// two-factor on and returning the user's recovery codes
+func (s *Server) handleVerifyTOTP(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	ctx := c.Request.Context()
+	state, err := s.postgres.GetUserTOTP(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if state.Enabled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+	if state.Secret == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Start enrollment first"})
+		return
+	}
+
+	step, ok := totp.Validate(state.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid two-factor code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+		if err := tx.EnableUserTOTP(ctx, userID, step); err != nil {
+			return err
+		}
+		return tx.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("Two-factor authentication enabled")
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+}
+
+// handleRegenerateRecoveryCodes replaces the current user's recovery codes, given a
+// current authenticator code
+func (s *Server) handleRegenerateRecoveryCodes(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	ctx := c.Request.Context()
+	state, err := s.postgres.GetUserTOTP(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if !state.Enabled {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Two-factor authentication is not enabled"})
+		return
+	}
+	ok, err := s.checkTOTPCode(ctx, state, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid two-factor code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	err = s.postgres.InTx(ctx, func(tx storage.Store) error {
+		return tx.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// handleDisableTOTP turns off two-factor for the current user, given an authenticator
+// or recovery code
+func (s *Server) handleDisableTOTP(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("user_id")
+	ctx := c.Request.Context()
+	state, err := s.postgres.GetUserTOTP(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	if !state.Enabled {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Two-factor authentication is not enabled"})
+		return
+	}
+	ok, err := s.checkSecondFactor(ctx, state, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid two-factor code"})
+		return
+	}
+
+	if err := s.postgres.DisableUserTOTP(ctx, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("Two-factor authentication disabled")
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
+
+// handleResetUserTOTP lets an admin turn off two-factor for a user who has lost both
+// their authenticator and their recovery codes
+func (s *Server) handleResetUserTOTP(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	if err := s.postgres.DisableUserTOTP(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	requestLog(c).Info("Two-factor authentication reset", "target_user_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+// checkSecondFactor accepts an authenticator code or an unused recovery code for a
+// user with two-factor enabled. Either is used up by a successful check.
+func (s *Server) checkSecondFactor(ctx context.Context, state *models.UserTOTP, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if strings.Contains(code, "-") {
+		return s.postgres.UseRecoveryCode(ctx, state.UserID, hashRecoveryCode(code))
+	}
+	return s.checkTOTPCode(ctx, state, code)
+}
+
+// checkTOTPCode accepts an authenticator code that hasn't been used before
+func (s *Server) checkTOTPCode(ctx context.Context, state *models.UserTOTP, code string) (bool, error) {
+	step, ok := totp.Validate(state.Secret, code, time.Now())
+	if !ok || step <= state.LastStep {
+		return false, nil
+	}
+	return s.postgres.UseTOTPStep(ctx, state.UserID, step)
+}
+
+// newRecoveryCodes returns a fresh set of recovery codes, formatted xxxxx-xxxxx, and
+// the hashes to store for them
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range recoveryCodeCount {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage. The codes are random enough
+// that a plain SHA-256 can't be brute forced.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// handleGetPropertyTraffic returns per-interface throughput for a property's pfSense,
+// derived from the sampled counters. Defaults to the last 24 hours; ?interface= limits
+// it to one interface.
+func (s *Server) handleGetPropertyTraffic(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	startTime, endTime := timeRange(c, 24*time.Hour)
+	if !endTime.After(startTime) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end must be after start"})
+		return
+	}
+
+	// Fetch one extra sample interval so the first point in range has a predecessor
+	samples, err := s.postgres.ListTrafficSamples(c.Request.Context(), propertyID, c.Query("interface"),
+		startTime.Add(-10*time.Minute), endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trafficSeries(samples, startTime))
+}
+
+// trafficSeries turns consecutive counter samples (ordered by interface, then time) into
+// rates. Intervals where a counter went backwards, such as after a reboot, are skipped.
+func trafficSeries(samples []models.TrafficSample, start time.Time) []models.InterfaceTraffic {
+	series := make([]models.InterfaceTraffic, 0)
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		if prev.Interface != cur.Interface || cur.CollectedAt.Before(start) {
+			continue
+		}
+		seconds := cur.CollectedAt.Sub(prev.CollectedAt).Seconds()
+		if seconds <= 0 || cur.RxBytes < prev.RxBytes || cur.TxBytes < prev.TxBytes ||
+			cur.RxPackets < prev.RxPackets || cur.TxPackets < prev.TxPackets {
+			continue
+		}
+
+		if len(series) == 0 || series[len(series)-1].Interface != cur.Interface {
+			series = append(series, models.InterfaceTraffic{Interface: cur.Interface, Points: []models.TrafficPoint{}})
+		}
+		last := &series[len(series)-1]
+		last.Points = append(last.Points, models.TrafficPoint{
+			Time:  cur.CollectedAt,
+			RxBps: float64(cur.RxBytes-prev.RxBytes) * 8 / seconds,
+			TxBps: float64(cur.TxBytes-prev.TxBytes) * 8 / seconds,
+			RxPps: float64(cur.RxPackets-prev.RxPackets) / seconds,
+			TxPps: float64(cur.TxPackets-prev.TxPackets) / seconds,
+		})
+	}
+	return series
+}
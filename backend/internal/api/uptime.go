@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const maxUptimePeriod = 366 * 24 * time.Hour
+
+// parsePeriod parses a reporting period such as "30d", "12h" or "2w"
+func parsePeriod(period string) (time.Duration, error) {
+	if len(period) < 2 {
+		return 0, fmt.Errorf("invalid period %q", period)
+	}
+
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid period %q", period)
+	}
+
+	var unit time.Duration
+	switch strings.ToLower(period[len(period)-1:]) {
+	case "h":
+		unit = time.Hour
+	case "d":
+		unit = 24 * time.Hour
+	case "w":
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid period %q: use a number followed by h, d or w", period)
+	}
+
+	d := time.Duration(n) * unit
+	if d > maxUptimePeriod {
+		return 0, fmt.Errorf("period may not exceed 366d")
+	}
+	return d, nil
+}
+
+// uptimeRange returns the reporting window from ?period= (default 30d) ending now
+func uptimeRange(c *gin.Context) (time.Time, time.Time, error) {
+	period, err := parsePeriod(c.DefaultQuery("period", "30d"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endTime := time.Now()
+	return endTime.Add(-period), endTime, nil
+}
+
+func (s *Server) handleGetDeviceUptime(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid device ID"})
+		return
+	}
+
+	startTime, endTime, err := uptimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	device, err := s.postgres.GetDevice(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Device not found"})
+		return
+	}
+
+	reports, err := s.postgres.GetDeviceUptime(c.Request.Context(), []int64{id}, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deviceUptime(device, reports, startTime, endTime))
+}
+
+func (s *Server) handleGetPropertyUptime(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	startTime, endTime, err := uptimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if _, err := s.postgres.GetProperty(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	devices, err := s.postgres.ListDevicesForProperty(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	deviceIDs := make([]int64, len(devices))
+	for i, d := range devices {
+		deviceIDs[i] = d.ID
+	}
+
+	reports, err := s.postgres.GetDeviceUptime(c.Request.Context(), deviceIDs, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result := models.PropertyUptime{
+		PropertyID:   id,
+		UptimeReport: models.UptimeReport{PeriodStart: startTime, PeriodEnd: endTime},
+		Devices:      make([]models.DeviceUptime, 0, len(devices)),
+	}
+	for i := range devices {
+		du := deviceUptime(&devices[i], reports, startTime, endTime)
+		result.Devices = append(result.Devices, du)
+
+		result.Checks += du.Checks
+		result.OutageCount += du.OutageCount
+		result.DowntimeMinutes += du.DowntimeMinutes
+		result.MonitoredMinutes += du.MonitoredMinutes
+	}
+	if result.MonitoredMinutes > 0 {
+		uptime := 100 * (result.MonitoredMinutes - result.DowntimeMinutes) / result.MonitoredMinutes
+		result.UptimePercent = &uptime
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func deviceUptime(device *models.Device, reports map[int64]*models.UptimeReport, startTime, endTime time.Time) models.DeviceUptime {
+	du := models.DeviceUptime{
+		DeviceID:     device.ID,
+		DeviceName:   device.Name,
+		UptimeReport: models.UptimeReport{PeriodStart: startTime, PeriodEnd: endTime},
+	}
+	if r, ok := reports[device.ID]; ok {
+		du.UptimeReport = *r
+	}
+	return du
+}
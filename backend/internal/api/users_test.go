@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/etswifi/ets-noc/internal/apitest"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+func TestRoleChangeSignsUserOut(t *testing.T) {
+	ctx := context.Background()
+	h, err := apitest.New(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if _, err := h.CreateUser(ctx, "admin", "admin-password", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	editor, err := h.CreateUser(ctx, "editor", "editor-password", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := h.Login("admin", "admin-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := h.Login("editor", "editor-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Renaming keeps the editor signed in
+	editor.Email = "editor@example.org"
+	status, err := h.Do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d", editor.ID), admin, editor, nil)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("update returned %d, %v", status, err)
+	}
+	if status, _ := h.Do(http.MethodGet, "/api/v1/auth/me", token, nil, nil); status != http.StatusOK {
+		t.Fatalf("token after an email change returned %d, want %d", status, http.StatusOK)
+	}
+
+	// The token was issued to a user, so demoting them must end it
+	editor.Role = "viewer"
+	status, err = h.Do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d", editor.ID), admin, editor, nil)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("demotion returned %d, %v", status, err)
+	}
+	status, err = h.Do(http.MethodPost, "/api/v1/properties", token, models.Property{Name: "Harbor Inn"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusUnauthorized {
+		t.Fatalf("write with the pre-demotion token returned %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	status, err = h.Do(http.MethodPut, "/api/v1/users/999999", admin, editor, nil)
+	if err != nil || status != http.StatusNotFound {
+		t.Fatalf("update of a missing user returned %d, %v", status, err)
+	}
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/webhook"
+)
+
+// Outbound webhooks
+
+// maxWebhookDeliveryLimit caps the deliveries returned by one request
+const maxWebhookDeliveryLimit = 500
+
+type webhookRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"` // generated on create when empty; kept on update when empty
+	Events []string `json:"events"` // empty subscribes to every event
+	// Enabled defaults to true on create and is left as it was on update
+	Enabled *bool `json:"enabled"`
+}
+
+// apply validates the request and copies it onto a webhook
+func (r *webhookRequest) apply(w *models.Webhook) error {
+	w.Name = strings.TrimSpace(r.Name)
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	u, err := url.Parse(strings.TrimSpace(r.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	w.URL = u.String()
+
+	events := make([]string, 0, len(r.Events))
+	for _, event := range r.Events {
+		if !webhook.ValidEventType(event) {
+			return fmt.Errorf("unknown event %q (must be one of %s)", event, strings.Join(webhook.EventTypes, ", "))
+		}
+		events = append(events, event)
+	}
+	w.Events = events
+
+	w.Secret = r.Secret
+	if r.Enabled != nil {
+		w.Enabled = *r.Enabled
+	}
+	return nil
+}
+
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	webhooks, err := s.postgres.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// handleCreateWebhook adds a webhook. The signing secret is only returned here; one is
+// generated when the request doesn't set it.
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	hook := models.Webhook{Enabled: true}
+	if err := req.apply(&hook); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if hook.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate secret"})
+			return
+		}
+		hook.Secret = secret
+	}
+
+	if err := s.postgres.CreateWebhook(c.Request.Context(), &hook); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	s.webhooks.Invalidate()
+
+	hook.SecretSet = true
+	c.JSON(http.StatusCreated, hook)
+}
+
+func (s *Server) handleGetWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook ID"})
+		return
+	}
+
+	hook, err := s.postgres.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+// handleUpdateWebhook replaces a webhook's settings; an empty secret keeps the stored one
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook ID"})
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	hook, err := s.postgres.GetWebhook(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+	if err := req.apply(hook); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := s.postgres.UpdateWebhook(ctx, hook); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	s.webhooks.Invalidate()
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteWebhook(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+	s.webhooks.Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// handleTestWebhook sends a webhook.test event to a webhook, even a disabled one, and
+// returns the logged delivery
+func (s *Server) handleTestWebhook(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook ID"})
+		return
+	}
+
+	hook, err := s.postgres.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	delivery, err := s.webhooks.Test(ctx, hook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// handleListWebhookDeliveries returns a webhook's most recent deliveries, newest first.
+// ?limit= defaults to 50.
+func (s *Server) handleListWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook ID"})
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if limit > maxWebhookDeliveryLimit {
+		limit = maxWebhookDeliveryLimit
+	}
+
+	ctx := c.Request.Context()
+	if _, err := s.postgres.GetWebhook(ctx, id); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+
+	deliveries, err := s.postgres.ListWebhookDeliveries(ctx, id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// handleReplayWebhookDelivery sends a logged delivery's payload again, with the same
+// event ID, and returns the new delivery
+func (s *Server) handleReplayWebhookDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook delivery ID"})
+		return
+	}
+
+	delivery, err := s.postgres.GetWebhookDelivery(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Webhook delivery not found"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	replay, err := s.webhooks.Replay(ctx, delivery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	requestLog(c).Info("Replayed webhook delivery", "delivery_id", delivery.ID,
+		"webhook_id", delivery.WebhookID, "success", replay.Success)
+	c.JSON(http.StatusOK, replay)
+}
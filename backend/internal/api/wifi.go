@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// UniFi controllers and WiFi telemetry
+
+func (s *Server) handleGetUniFiController(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	controller, err := s.postgres.GetUniFiController(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "UniFi controller not configured for this property"})
+		return
+	}
+
+	controller.Password = ""
+	c.JSON(http.StatusOK, controller)
+}
+
+// handleSaveUniFiController creates or replaces the property's controller; leaving
+// password empty keeps the current one
+func (s *Server) handleSaveUniFiController(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	var controller models.UniFiController
+	if err := c.ShouldBindJSON(&controller); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := validateUniFiController(&controller); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if _, err := s.postgres.GetProperty(c.Request.Context(), propertyID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Property not found"})
+		return
+	}
+
+	controller.PropertyID = propertyID
+	if err := s.postgres.SaveUniFiController(c.Request.Context(), &controller); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	controller.Password = ""
+	c.JSON(http.StatusOK, controller)
+}
+
+func (s *Server) handleDeleteUniFiController(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	if err := s.postgres.DeleteUniFiController(c.Request.Context(), propertyID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "UniFi controller deleted"})
+}
+
+// handleGetPropertyWiFi returns the worker's latest poll of the property's UniFi
+// controller: every access point with its clients and per-radio channel utilization
+func (s *Server) handleGetPropertyWiFi(c *gin.Context) {
+	propertyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid property ID"})
+		return
+	}
+
+	snapshot, err := s.redis.GetWiFiSnapshot(c.Request.Context(), propertyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "No WiFi telemetry for this property"})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// validateUniFiController normalizes and validates a controller's connection settings
+func validateUniFiController(controller *models.UniFiController) error {
+	controller.URL = strings.TrimRight(strings.TrimSpace(controller.URL), "/")
+	parsed, err := url.Parse(controller.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("url must be an http(s) URL such as https://unifi.example.com:8443")
+	}
+	if strings.TrimSpace(controller.Username) == "" {
+		return fmt.Errorf("username is required")
+	}
+	controller.Site = strings.TrimSpace(controller.Site)
+	if controller.Site == "" {
+		controller.Site = "default"
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package apitest runs the API over httptest against throwaway stores, so handlers,
+// the scheduler and status computation can be exercised without Postgres, Redis or
+// cloud storage. The database is an in-memory storage.FakeStore unless WithStore
+// supplies another, such as a SQLite store for tests that need real SQL.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/etswifi/ets-noc/internal/api"
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Harness is an API server listening on a local port with its own database, status
+// store and blob directory, all removed by Close
+type Harness struct {
+	// Store is the migrated database behind the API
+	Store storage.Store
+	// Statuses is the in-memory status store behind the API, for seeding live state
+	Statuses *storage.MemoryStore
+	// Blobs keeps attachments and config backups in the temporary directory
+	Blobs *storage.LocalBlobStore
+	// API is the server the routes were built from
+	API *api.Server
+	// HTTP serves the routes; its URL is the base for requests
+	HTTP *httptest.Server
+
+	dir string
+}
+
+// Option configures a Harness
+type Option func(*options)
+
+type options struct {
+	store  storage.Store
+	server []api.ServerOption
+}
+
+// WithStore runs the API on store instead of a fresh FakeStore. The harness migrates
+// it and closes it with the rest.
+func WithStore(store storage.Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithServerOptions passes options to api.NewServer, for example to set a geocoder or
+// turn rate limits back on
+func WithServerOptions(opts ...api.ServerOption) Option {
+	return func(o *options) {
+		o.server = append(o.server, opts...)
+	}
+}
+
+// New migrates a fresh database and starts the API on it. Rate limits are off unless
+// a server option sets them.
+func New(ctx context.Context, opts ...Option) (*Harness, error) {
+	gin.SetMode(gin.TestMode)
+
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.store == nil {
+		o.store = storage.NewFakeStore()
+	}
+
+	dir, err := os.MkdirTemp("", "ets-noc-apitest-")
+	if err != nil {
+		o.store.Close()
+		return nil, fmt.Errorf("failed to create harness directory: %w", err)
+	}
+	h := &Harness{Store: o.store, dir: dir}
+	if err := h.Store.Migrate(ctx); err != nil {
+		h.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	h.Statuses = storage.NewMemoryStore()
+	h.Blobs, err = storage.NewLocalBlobStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+
+	serverOpts := append([]api.ServerOption{api.WithRateLimits(api.RateLimits{})}, o.server...)
+	h.API = api.NewServer(h.Store, h.Statuses, h.Blobs, serverOpts...)
+	h.HTTP = httptest.NewServer(h.API.SetupRouter())
+	return h, nil
+}
+
+// Close stops the server and removes the stores
+func (h *Harness) Close() {
+	if h.HTTP != nil {
+		h.API.CloseStreams()
+		h.HTTP.Close()
+		h.API.CloseWebhooks()
+	}
+	if h.Statuses != nil {
+		h.Statuses.Close()
+	}
+	if h.Store != nil {
+		h.Store.Close()
+	}
+	os.RemoveAll(h.dir)
+}
+
+// CreateUser adds an active user with the given password and role
+func (h *Harness) CreateUser(ctx context.Context, username, password, role string) (*models.User, error) {
+	// The lowest cost keeps fixtures fast; logins compare against any cost
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username: username,
+		Password: string(hash),
+		Email:    username + "@example.com",
+		Role:     role,
+		Active:   true,
+	}
+	if err := h.Store.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Login signs in through the API and returns the session token
+func (h *Harness) Login(username, password string) (string, error) {
+	var resp models.LoginResponse
+	status, err := h.Do(http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{
+		Username: username,
+		Password: password,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("login as %s returned %d", username, status)
+	}
+	return resp.Token, nil
+}
+
+// Do sends body as JSON to path, with token as the bearer token when set, and decodes
+// the response into out when it isn't nil. It returns the status code; responses
+// that aren't 2xx are decoded too, so out may be a models.ErrorResponse.
+func (h *Harness) Do(method, path, token string, body, out any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, h.HTTP.URL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := h.HTTP.Client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+			return resp.StatusCode, fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// StatusComputer returns a status computer over the harness stores, for rolling
+// seeded device statuses up into property statuses the API then reports
+func (h *Harness) StatusComputer(opts ...monitor.StatusComputerOption) *monitor.StatusComputer {
+	return monitor.NewStatusComputer(h.Store, h.Statuses, opts...)
+}
@@ -0,0 +1,184 @@
+// Package audit checks the device inventory for address conflicts: devices at a
+// property checking the same target, private addresses outside the property subnet and
+// MAC addresses registered more than once
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/nut"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Devices audits every device in the fleet
+func Devices(ctx context.Context, postgres storage.Store) (*models.DeviceAudit, error) {
+	properties, err := postgres.ListProperties(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list properties: %w", err)
+	}
+	devices, err := postgres.ListDevices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	return &models.DeviceAudit{
+		GeneratedAt: time.Now(),
+		Issues:      FindIssues(properties, devices),
+	}, nil
+}
+
+// FindIssues returns the conflicts among devices, per property in name order and then
+// the duplicate MACs
+func FindIssues(properties []models.Property, devices []models.Device) []models.DeviceAuditIssue {
+	byID := make(map[int64]*models.Property, len(properties))
+	for i := range properties {
+		byID[properties[i].ID] = &properties[i]
+	}
+	entry := func(d *models.Device) models.AuditDevice {
+		a := models.AuditDevice{
+			ID:         d.ID,
+			Name:       d.Name,
+			Hostname:   d.Hostname,
+			MACAddress: d.MACAddress,
+			PropertyID: d.PropertyID,
+		}
+		if p, ok := byID[d.PropertyID]; ok {
+			a.PropertyName = p.Name
+		}
+		return a
+	}
+
+	// Devices sharing a target or MAC, in the order the target or MAC was first seen
+	type group struct {
+		key     string
+		devices []models.AuditDevice
+	}
+	var targets, macs []*group
+	targetGroups := make(map[string]*group)
+	macGroups := make(map[string]*group)
+	var outside []models.DeviceAuditIssue
+
+	for i := range devices {
+		d := &devices[i]
+
+		key := fmt.Sprintf("%d|%s", d.PropertyID, TargetKey(d))
+		g, ok := targetGroups[key]
+		if !ok {
+			g = &group{key: Target(d)}
+			targetGroups[key] = g
+			targets = append(targets, g)
+		}
+		g.devices = append(g.devices, entry(d))
+
+		if mac := strings.ToLower(d.MACAddress); mac != "" {
+			g, ok := macGroups[mac]
+			if !ok {
+				g = &group{key: mac}
+				macGroups[mac] = g
+				macs = append(macs, g)
+			}
+			g.devices = append(g.devices, entry(d))
+		}
+
+		if p, ok := byID[d.PropertyID]; ok {
+			if addr, subnet, out := outsideSubnet(d, p); out {
+				propertyID := d.PropertyID
+				outside = append(outside, models.DeviceAuditIssue{
+					Kind:       models.AuditOutsideSubnet,
+					Value:      addr.String(),
+					PropertyID: &propertyID,
+					Detail:     fmt.Sprintf("%s is outside %s's subnet %s", addr, p.Name, subnet),
+					Devices:    []models.AuditDevice{entry(d)},
+				})
+			}
+		}
+	}
+
+	issues := make([]models.DeviceAuditIssue, 0)
+	for _, g := range targets {
+		if len(g.devices) < 2 {
+			continue
+		}
+		propertyID := g.devices[0].PropertyID
+		issues = append(issues, models.DeviceAuditIssue{
+			Kind:       models.AuditDuplicateTarget,
+			Value:      g.key,
+			PropertyID: &propertyID,
+			Detail:     fmt.Sprintf("%d devices at %s check %s", len(g.devices), g.devices[0].PropertyName, g.key),
+			Devices:    g.devices,
+		})
+	}
+	issues = append(issues, outside...)
+	// Property issues are listed by property name, keeping device order within one
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Devices[0].PropertyName < issues[j].Devices[0].PropertyName
+	})
+
+	for _, g := range macs {
+		if len(g.devices) < 2 {
+			continue
+		}
+		issues = append(issues, models.DeviceAuditIssue{
+			Kind:    models.AuditDuplicateMAC,
+			Value:   g.key,
+			Detail:  fmt.Sprintf("%d devices have MAC %s", len(g.devices), g.key),
+			Devices: g.devices,
+		})
+	}
+	return issues
+}
+
+// TargetKey identifies what a device checks, so two devices at a property with the same
+// key check the same thing. Hostnames compare case-insensitively, and the port counts
+// for the check types that take one.
+func TargetKey(d *models.Device) string {
+	port := 0
+	if d.CheckType == "tcp" || d.CheckType == "ups" {
+		port = d.Port
+	}
+	return d.CheckType + "|" + strings.ToLower(d.Hostname) + "|" + strconv.Itoa(port)
+}
+
+// Target describes what a device checks, e.g. 10.99.1.20, 10.99.1.20:443 (tcp) or
+// ups@10.99.1.5 (ups)
+func Target(d *models.Device) string {
+	target := d.Hostname
+	if d.CheckType == "tcp" && d.Port > 0 {
+		target = fmt.Sprintf("%s:%d", target, d.Port)
+	}
+	if d.CheckType != "" && d.CheckType != "icmp" {
+		target += " (" + d.CheckType + ")"
+	}
+	return target
+}
+
+// outsideSubnet reports whether a device's address is a private IPv4 address outside
+// its property's subnet. Devices checked by hostname or a public address, tunnels and
+// properties without a valid subnet are never flagged.
+func outsideSubnet(d *models.Device, p *models.Property) (netip.Addr, netip.Prefix, bool) {
+	subnet, err := netip.ParsePrefix(p.Subnet)
+	if err != nil {
+		return netip.Addr{}, netip.Prefix{}, false
+	}
+	host := d.Hostname
+	switch d.CheckType {
+	case "vpn":
+		return netip.Addr{}, netip.Prefix{}, false
+	case "ups":
+		if _, h, err := nut.ParseTarget(d.Hostname); err == nil {
+			host = h
+		}
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !addr.Is4() || !addr.IsPrivate() {
+		return netip.Addr{}, netip.Prefix{}, false
+	}
+	subnet = subnet.Masked()
+	return addr, subnet, !subnet.Contains(addr)
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	reportInterval = time.Hour
+	// ResultTTL keeps the latest audit through a missed run, then lets it expire so a
+	// stale result isn't served
+	ResultTTL = 3 * reportInterval
+)
+
+// Reporter audits the devices hourly, starting at launch, stores the result for
+// GET /api/v1/audits/devices and logs a warning when it finds conflicts
+type Reporter struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	stopChan chan struct{}
+}
+
+func NewReporter(postgres storage.Store, redis storage.StatusStore) *Reporter {
+	return &Reporter{
+		postgres: postgres,
+		redis:    redis,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (r *Reporter) Start(ctx context.Context) error {
+	slog.Info("Device auditor started")
+
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	r.report(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.stopChan:
+			slog.Info("Device auditor stopped")
+			return nil
+		case <-ticker.C:
+			r.report(ctx)
+		}
+	}
+}
+
+func (r *Reporter) Stop() {
+	close(r.stopChan)
+}
+
+func (r *Reporter) report(ctx context.Context) {
+	result, err := Devices(ctx, r.postgres)
+	if err != nil {
+		slog.Error("Failed to audit devices", "error", err)
+		return
+	}
+	if err := r.redis.SetDeviceAudit(ctx, result, ResultTTL); err != nil {
+		slog.Error("Failed to store device audit", "error", err)
+	}
+	if len(result.Issues) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, issue := range result.Issues {
+		counts[issue.Kind]++
+	}
+	slog.Warn("Device audit found conflicts; see GET /api/v1/audits/devices",
+		"duplicate_targets", counts[models.AuditDuplicateTarget],
+		"outside_subnet", counts[models.AuditOutsideSubnet],
+		"duplicate_macs", counts[models.AuditDuplicateMAC])
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// ErrNoBlobStore is returned when there is nowhere to keep backups
+var ErrNoBlobStore = errors.New("no file storage is configured for config backups")
+
+// Triggers recorded on a backup
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// HasCredentials reports whether a property's pfSense can be backed up
+func HasCredentials(property *models.Property) bool {
+	return property.PfSenseHost != "" && property.PfSenseUsername != "" && property.PfSensePassword != ""
+}
+
+// BackupProperty pulls a property's config.xml and stores it in blobs. If the config
+// is identical to the latest backup nothing is stored and that backup is returned with
+// created false.
+func BackupProperty(ctx context.Context, postgres storage.Store, blobs storage.BlobStore, property *models.Property, trigger, createdBy string) (*models.ConfigBackup, bool, error) {
+	if blobs == nil {
+		return nil, false, ErrNoBlobStore
+	}
+	if !HasCredentials(property) {
+		return nil, false, fmt.Errorf("pfSense credentials not configured for this property")
+	}
+
+	client := pfsense.NewClient(property.PfSenseHost, property.PfSensePort, property.PfSenseUsername, property.PfSensePassword)
+	config, err := client.GetConfigXML(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch config.xml: %w", err)
+	}
+
+	sum := sha256.Sum256(config)
+	checksum := hex.EncodeToString(sum[:])
+
+	latest, err := postgres.GetLatestConfigBackup(ctx, property.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if latest != nil && latest.SHA256 == checksum {
+		return latest, false, nil
+	}
+
+	now := time.Now().UTC()
+	objectName := fmt.Sprintf("config-backups/%d/%s-config.xml", property.ID, now.Format("20060102T150405Z"))
+	if err := blobs.UploadFile(ctx, objectName, bytes.NewReader(config), "application/xml"); err != nil {
+		return nil, false, err
+	}
+
+	backup := &models.ConfigBackup{
+		PropertyID:  property.ID,
+		StoragePath: objectName,
+		FileSize:    int64(len(config)),
+		SHA256:      checksum,
+		Trigger:     trigger,
+		CreatedBy:   createdBy,
+	}
+	if err := postgres.CreateConfigBackup(ctx, backup); err != nil {
+		return nil, false, err
+	}
+	return backup, true, nil
+}
@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const backupInterval = 24 * time.Hour
+
+// Scheduler backs up every property's pfSense config once a day, starting at launch
+type Scheduler struct {
+	postgres storage.Store
+	blobs    storage.BlobStore
+	stopChan chan struct{}
+}
+
+func NewScheduler(postgres storage.Store, blobs storage.BlobStore) *Scheduler {
+	return &Scheduler{
+		postgres: postgres,
+		blobs:    blobs,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start(ctx context.Context) error {
+	slog.Info("Config backup scheduler started")
+
+	ticker := time.NewTicker(backupInterval)
+	defer ticker.Stop()
+
+	s.backupAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stopChan:
+			slog.Info("Config backup scheduler stopped")
+			return nil
+		case <-ticker.C:
+			s.backupAll(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stopChan)
+}
+
+func (s *Scheduler) backupAll(ctx context.Context) {
+	properties, err := s.postgres.ListProperties(ctx)
+	if err != nil {
+		slog.Error("Failed to list properties for config backup", "error", err)
+		return
+	}
+
+	stored, unchanged, failed := 0, 0, 0
+	for i := range properties {
+		property := &properties[i]
+		if !HasCredentials(property) {
+			continue
+		}
+		_, created, err := BackupProperty(ctx, s.postgres, s.blobs, property, TriggerScheduled, "")
+		switch {
+		case err != nil:
+			slog.Error("Failed to back up config", "property_id", property.ID, "property", property.Name, "error", err)
+			failed++
+		case created:
+			stored++
+		default:
+			unchanged++
+		}
+	}
+	slog.Info("Config backups finished", "stored", stored, "unchanged", unchanged, "failed", failed)
+}
@@ -0,0 +1,396 @@
+// Package config loads the API and worker settings. Each setting comes from, in
+// increasing order of precedence, its default, an optional YAML or TOML file, its
+// environment variable and its command-line flag.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the API and the worker; each binary reads the ones it
+// needs. The key tag names the setting in a config file, and with underscores turned
+// into dashes, its flag. Secret settings are redacted when the config is logged.
+type Config struct {
+	// Storage
+	PostgresURL   string        `key:"postgres_url" env:"POSTGRES_URL" secret:"true" help:"PostgreSQL connection URL"`
+	SQLitePath    string        `key:"sqlite_path" env:"SQLITE_PATH" help:"SQLite database file, used instead of PostgreSQL"`
+	StatusStore   string        `key:"status_store" env:"STATUS_STORE" help:"where live status is kept: redis or memory"`
+	RedisAddr     string        `key:"redis_addr" env:"REDIS_ADDR" help:"Redis address"`
+	RedisPassword string        `key:"redis_password" env:"REDIS_PASSWORD" secret:"true" help:"Redis password"`
+	BlobStore     string        `key:"blob_store" env:"BLOB_STORE" help:"where attachments and config backups are kept: gcs, s3 or local; gcs when gcs_bucket is set"`
+	GCSBucket     string        `key:"gcs_bucket" env:"GCS_BUCKET" help:"GCS bucket for attachments and config backups"`
+	BlobDir       string        `key:"blob_dir" env:"BLOB_DIR" help:"directory for attachments and config backups when blob_store is local"`
+	S3Bucket      string        `key:"s3_bucket" env:"S3_BUCKET" help:"S3 bucket for attachments and config backups when blob_store is s3"`
+	S3Region      string        `key:"s3_region" env:"S3_REGION" help:"S3 region, us-east-1 when unset"`
+	S3Endpoint    string        `key:"s3_endpoint" env:"S3_ENDPOINT" help:"base URL of an S3-compatible server such as MinIO; AWS when unset"`
+	S3AccessKey   string        `key:"s3_access_key_id" env:"S3_ACCESS_KEY_ID" help:"S3 access key ID"`
+	S3SecretKey   string        `key:"s3_secret_access_key" env:"S3_SECRET_ACCESS_KEY" secret:"true" help:"S3 secret access key"`
+	SecretsKey    string        `key:"secrets_key" env:"SECRETS_KEY" secret:"true" help:"base64 AES-256 key for credentials stored in the database"`
+	QueryTimeout  time.Duration `key:"query_timeout" env:"QUERY_TIMEOUT" help:"longest a single Postgres statement run by the API may take; 0 for no limit"`
+
+	// Postgres pool and TLS
+	WorkerQueryTimeout      time.Duration `key:"worker_query_timeout" env:"WORKER_QUERY_TIMEOUT" help:"longest a single Postgres statement run by the worker may take; 0 for no limit"`
+	PostgresMaxOpenConns    int           `key:"postgres_max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" help:"most Postgres connections each process opens"`
+	PostgresMaxIdleConns    int           `key:"postgres_max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" help:"most idle Postgres connections each process keeps, up to postgres_max_open_conns; 0 keeps none"`
+	PostgresConnMaxLifetime time.Duration `key:"postgres_conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" help:"how long a Postgres connection is reused before it is replaced"`
+	PostgresConnMaxIdleTime time.Duration `key:"postgres_conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" help:"how long an idle Postgres connection is kept; 0 keeps it"`
+	PostgresSSLMode         string        `key:"postgres_sslmode" env:"POSTGRES_SSLMODE" help:"disable, allow, prefer, require, verify-ca or verify-full; overrides the URL's sslmode"`
+	PostgresSSLRootCert     string        `key:"postgres_sslrootcert" env:"POSTGRES_SSLROOTCERT" help:"CA certificate file the Postgres server certificate is verified against"`
+	PostgresSSLCert         string        `key:"postgres_sslcert" env:"POSTGRES_SSLCERT" help:"client certificate file for Postgres"`
+	PostgresSSLKey          string        `key:"postgres_sslkey" env:"POSTGRES_SSLKEY" help:"client key file for Postgres"`
+
+	// HTTP
+	Port            string        `key:"port" env:"PORT" help:"API server port"`
+	GinMode         string        `key:"gin_mode" env:"GIN_MODE" help:"release for production"`
+	RequestTimeout  time.Duration `key:"request_timeout" env:"REQUEST_TIMEOUT" help:"longest an API request may run; 0 for no limit"`
+	UploadTimeout   time.Duration `key:"upload_timeout" env:"UPLOAD_TIMEOUT" help:"longest an upload or import may run; 0 for no limit"`
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" help:"how long in-flight requests get to finish on shutdown"`
+	TrustedProxies  string        `key:"trusted_proxies" env:"TRUSTED_PROXIES" help:"comma-separated proxy IPs or CIDRs whose X-Forwarded-For is believed; empty trusts none"`
+	MetricsPort     string        `key:"metrics_port" env:"METRICS_PORT" help:"worker metrics port"`
+
+	// Rate limits, in requests per minute; 0 turns one off
+	RateLimit       int `key:"rate_limit" env:"RATE_LIMIT" help:"API requests per minute per user"`
+	AuthRateLimit   int `key:"auth_rate_limit" env:"AUTH_RATE_LIMIT" help:"sign-in attempts per minute per IP, and password and two-factor checks per user"`
+	UploadRateLimit int `key:"upload_rate_limit" env:"UPLOAD_RATE_LIMIT" help:"uploads and imports per minute per user"`
+	SyncRateLimit   int `key:"sync_rate_limit" env:"SYNC_RATE_LIMIT" help:"pfSense syncs and backups per minute per user"`
+
+	// Sign-in
+	JWTKeys            string `key:"jwt_keys" env:"JWT_KEYS" secret:"true" help:"session token signing keys as id:secret pairs"`
+	GoogleClientID     string `key:"google_client_id" env:"GOOGLE_CLIENT_ID" help:"Google OAuth client ID"`
+	GoogleClientSecret string `key:"google_client_secret" env:"GOOGLE_CLIENT_SECRET" secret:"true" help:"Google OAuth client secret"`
+	GoogleRedirectURL  string `key:"google_redirect_url" env:"GOOGLE_REDIRECT_URL" help:"Google OAuth callback URL"`
+
+	// Map view
+	Geocoder       string `key:"geocoder" env:"GEOCODER" help:"geocoding service for property addresses: nominatim or google; empty turns geocoding off"`
+	GeocoderURL    string `key:"geocoder_url" env:"GEOCODER_URL" help:"base URL of the geocoding service, such as a self-hosted Nominatim"`
+	GeocoderAPIKey string `key:"geocoder_api_key" env:"GEOCODER_API_KEY" secret:"true" help:"geocoding service API key, required by google"`
+
+	// Workers
+	RunWorker              bool          `key:"run_worker" env:"RUN_WORKER" help:"run device checks inside the API"`
+	WorkerID               string        `key:"worker_id" env:"WORKER_ID" help:"worker ID, defaulting to the hostname"`
+	WorkerHeartbeatTimeout time.Duration `key:"worker_heartbeat_timeout" env:"WORKER_HEARTBEAT_TIMEOUT" help:"how long without a heartbeat before workers are reported stale"`
+
+	LogLevel string `key:"log_level" env:"LOG_LEVEL" help:"debug, info, warn or error"`
+}
+
+// Default returns the settings used when nothing else sets them
+func Default() *Config {
+	return &Config{
+		StatusStore:             "redis",
+		RedisAddr:               "localhost:6379",
+		Port:                    "8080",
+		RequestTimeout:          60 * time.Second,
+		UploadTimeout:           30 * time.Minute,
+		ShutdownTimeout:         25 * time.Second,
+		MetricsPort:             "9090",
+		RateLimit:               600,
+		AuthRateLimit:           10,
+		UploadRateLimit:         30,
+		SyncRateLimit:           6,
+		PostgresMaxOpenConns:    storage.DefaultMaxOpenConns,
+		PostgresMaxIdleConns:    storage.DefaultMaxIdleConns,
+		PostgresConnMaxLifetime: storage.DefaultConnMaxLifetime,
+		WorkerHeartbeatTimeout:  monitor.DefaultWorkerStaleAfter,
+	}
+}
+
+// Load registers a flag for each setting, plus -config for the file, on fs, parses
+// args and builds the configuration. The file may also be named by CONFIG_FILE.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	fields := settingFields()
+
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file")
+	flagged := make(map[string]string)
+	for _, f := range fields {
+		fs.Var(&settingFlag{field: f, values: flagged}, f.flag(), f.help)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	if *file != "" {
+		if err := cfg.loadFile(*file, fields); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range fields {
+		if raw := os.Getenv(f.env); raw != "" {
+			if err := f.set(v, raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.env, err)
+			}
+		}
+	}
+	for _, f := range fields {
+		if raw, ok := flagged[f.key]; ok {
+			if err := f.set(v, raw); err != nil {
+				return nil, fmt.Errorf("-%s: %w", f.flag(), err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile applies the settings in a YAML or TOML file, chosen by its extension
+func (c *Config) loadFile(path string, fields []settingField) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	v := reflect.ValueOf(c).Elem()
+	for key, value := range values {
+		f, ok := findField(fields, key)
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		if err := f.set(v, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings that every binary shares
+func (c *Config) Validate() error {
+	if c.StatusStore != "redis" && c.StatusStore != "memory" {
+		return fmt.Errorf("status_store must be redis or memory")
+	}
+	switch c.BlobStore {
+	case "":
+	case "gcs":
+		if c.GCSBucket == "" {
+			return fmt.Errorf("blob_store gcs needs gcs_bucket")
+		}
+	case "s3":
+		if c.S3Bucket == "" || c.S3AccessKey == "" || c.S3SecretKey == "" {
+			return fmt.Errorf("blob_store s3 needs s3_bucket, s3_access_key_id and s3_secret_access_key")
+		}
+	case "local":
+		if c.BlobDir == "" {
+			return fmt.Errorf("blob_store local needs blob_dir")
+		}
+	default:
+		return fmt.Errorf("blob_store must be gcs, s3 or local")
+	}
+	switch c.GinMode {
+	case "", "debug", "release", "test":
+	default:
+		return fmt.Errorf("gin_mode must be debug, release or test")
+	}
+	for name, port := range map[string]string{"port": c.Port, "metrics_port": c.MetricsPort} {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("%s must be a port number", name)
+		}
+	}
+	if c.RequestTimeout < 0 || c.UploadTimeout < 0 || c.QueryTimeout < 0 || c.WorkerQueryTimeout < 0 {
+		return fmt.Errorf("request_timeout, upload_timeout, query_timeout and worker_query_timeout must not be negative")
+	}
+	if c.PostgresMaxOpenConns < 1 || c.PostgresMaxIdleConns < 0 {
+		return fmt.Errorf("postgres_max_open_conns must be at least 1 and postgres_max_idle_conns must not be negative")
+	}
+	if c.PostgresConnMaxLifetime < 0 || c.PostgresConnMaxIdleTime < 0 {
+		return fmt.Errorf("postgres_conn_max_lifetime and postgres_conn_max_idle_time must not be negative")
+	}
+	if c.PostgresSSLMode != "" && !slices.Contains(storage.SSLModes, c.PostgresSSLMode) {
+		return fmt.Errorf("postgres_sslmode must be one of %s", strings.Join(storage.SSLModes, ", "))
+	}
+	if (c.PostgresSSLCert == "") != (c.PostgresSSLKey == "") {
+		return fmt.Errorf("postgres_sslcert and postgres_sslkey must be set together")
+	}
+	for _, proxy := range c.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("trusted_proxies: %q is not an IP address or CIDR", proxy)
+		}
+	}
+	if c.RateLimit < 0 || c.AuthRateLimit < 0 || c.UploadRateLimit < 0 || c.SyncRateLimit < 0 {
+		return fmt.Errorf("rate limits must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 || c.WorkerHeartbeatTimeout <= 0 {
+		return fmt.Errorf("shutdown_timeout and worker_heartbeat_timeout must be positive")
+	}
+	return nil
+}
+
+// TrustedProxyList splits TrustedProxies into its addresses
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// PostgresOptions returns the pool and TLS settings, with the statement timeout the
+// binary uses. Idle connections above the open limit are lowered to it, so setting
+// only the open limit below the idle default works.
+func (c *Config) PostgresOptions(queryTimeout time.Duration) storage.PostgresOptions {
+	idle := min(c.PostgresMaxIdleConns, c.PostgresMaxOpenConns)
+	return storage.PostgresOptions{
+		QueryTimeout:    queryTimeout,
+		MaxOpenConns:    c.PostgresMaxOpenConns,
+		MaxIdleConns:    &idle,
+		ConnMaxLifetime: c.PostgresConnMaxLifetime,
+		ConnMaxIdleTime: c.PostgresConnMaxIdleTime,
+		SSLMode:         c.PostgresSSLMode,
+		SSLRootCert:     c.PostgresSSLRootCert,
+		SSLCert:         c.PostgresSSLCert,
+		SSLKey:          c.PostgresSSLKey,
+	}
+}
+
+// LogValue lists the effective settings with the secret ones redacted, so the config
+// can be logged at startup
+func (c *Config) LogValue() slog.Value {
+	v := reflect.ValueOf(c).Elem()
+	var attrs []slog.Attr
+	for _, f := range settingFields() {
+		value := v.Field(f.index).Interface()
+		switch {
+		case f.secret && value != "":
+			value = "[redacted]"
+		case f.kind == reflect.Int64:
+			value = value.(time.Duration).String()
+		}
+		attrs = append(attrs, slog.Any(f.key, value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// settingField describes one field of Config
+type settingField struct {
+	index  int
+	kind   reflect.Kind
+	key    string
+	env    string
+	help   string
+	secret bool
+}
+
+func settingFields() []settingField {
+	t := reflect.TypeOf(Config{})
+	fields := make([]settingField, 0, t.NumField())
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		fields = append(fields, settingField{
+			index:  i,
+			kind:   sf.Type.Kind(),
+			key:    sf.Tag.Get("key"),
+			env:    sf.Tag.Get("env"),
+			help:   sf.Tag.Get("help"),
+			secret: sf.Tag.Get("secret") == "true",
+		})
+	}
+	return fields
+}
+
+func findField(fields []settingField, key string) (settingField, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return settingField{}, false
+}
+
+func (f settingField) flag() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// set parses raw into the field of cfg. Durations are whole seconds, as the
+// environment variables have always been, or a Go duration such as 90s.
+func (f settingField) set(cfg reflect.Value, raw string) error {
+	field := cfg.Field(f.index)
+	switch f.kind {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Int64:
+		d, err := parseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	}
+	return nil
+}
+
+func parseDuration(raw string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", raw)
+	}
+	return d, nil
+}
+
+// settingFlag records a flag's value to be applied after the file and environment
+type settingFlag struct {
+	field  settingField
+	values map[string]string
+}
+
+func (s *settingFlag) String() string {
+	if s == nil || s.values == nil {
+		return ""
+	}
+	return s.values[s.field.key]
+}
+
+func (s *settingFlag) Set(raw string) error {
+	s.values[s.field.key] = raw
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as a bare -flag
+func (s *settingFlag) IsBoolFlag() bool {
+	return s.field.kind == reflect.Bool
+}
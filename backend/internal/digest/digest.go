@@ -0,0 +1,135 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// worstDeviceLimit caps the worst devices listed in a digest
+const worstDeviceLimit = 10
+
+// Build summarizes outages between start and end for the given properties, or the
+// whole fleet when propertyIDs is empty, along with the properties red right now
+func Build(ctx context.Context, postgres storage.Store, redis storage.StatusStore, propertyIDs []int64, start, end time.Time) (*models.Digest, error) {
+	if propertyIDs == nil {
+		propertyIDs = []int64{}
+	}
+
+	properties, err := postgres.ListProperties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	included := make(map[int64]bool, len(propertyIDs))
+	for _, id := range propertyIDs {
+		included[id] = true
+	}
+
+	d := &models.Digest{PeriodStart: start, PeriodEnd: end}
+
+	d.Properties, err = postgres.GetPropertyOutageSummaries(ctx, propertyIDs, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize property outages: %w", err)
+	}
+	for _, p := range d.Properties {
+		d.OutageCount += p.OutageCount
+		d.DowntimeMinutes += p.DowntimeMinutes
+	}
+
+	d.WorstDevices, err = postgres.GetWorstDevices(ctx, propertyIDs, start, end, worstDeviceLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize device outages: %w", err)
+	}
+
+	statuses, err := redis.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load property statuses: %w", err)
+	}
+	for _, p := range properties {
+		if len(included) > 0 && !included[p.ID] {
+			continue
+		}
+		d.PropertyCount++
+		status, ok := statuses[p.ID]
+		if !ok || status.Status != "red" {
+			continue
+		}
+		d.CurrentReds = append(d.CurrentReds, models.RedProperty{
+			PropertyID:   p.ID,
+			PropertyName: p.Name,
+			OfflineCount: status.OfflineCount,
+			TotalCount:   status.TotalCount,
+			Since:        status.Since,
+		})
+	}
+	sort.Slice(d.CurrentReds, func(i, j int) bool {
+		return d.CurrentReds[i].Since.Before(d.CurrentReds[j].Since)
+	})
+
+	return d, nil
+}
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"minutes": formatMinutes,
+}).Parse(`ETS NOC {{.Title}} digest
+{{.Digest.PeriodStart.Format "Jan 2 15:04"}} to {{.Digest.PeriodEnd.Format "Jan 2 15:04 MST"}}
+
+Properties:     {{.Digest.PropertyCount}}
+Outages:        {{.Digest.OutageCount}}
+Total downtime: {{minutes .Digest.DowntimeMinutes}}
+
+Currently red:
+{{- range .Digest.CurrentReds}}
+  - {{.PropertyName}}: {{.OfflineCount}} of {{.TotalCount}} devices offline{{if not .Since.IsZero}} since {{.Since.Format "Jan 2 15:04 MST"}}{{end}}
+{{- else}}
+  none
+{{- end}}
+
+Properties with downtime:
+{{- range .Digest.Properties}}
+  - {{.PropertyName}}: {{.OutageCount}} outage(s), {{minutes .DowntimeMinutes}} down
+{{- else}}
+  none
+{{- end}}
+
+Worst devices:
+{{- range .Digest.WorstDevices}}
+  - {{.DeviceName}} at {{.PropertyName}}: {{.OutageCount}} outage(s), {{minutes .DowntimeMinutes}} offline
+{{- else}}
+  none
+{{- end}}
+`))
+
+// Render returns the subject and plain text body of a digest email
+func Render(frequency string, d *models.Digest) (string, string, error) {
+	title := "daily"
+	if frequency == "weekly" {
+		title = "weekly"
+	}
+
+	var body bytes.Buffer
+	if err := digestTemplate.Execute(&body, struct {
+		Title  string
+		Digest *models.Digest
+	}{title, d}); err != nil {
+		return "", "", fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	subject := fmt.Sprintf("[ETS NOC] %s digest: %d outage(s), %d red now",
+		title, d.OutageCount, len(d.CurrentReds))
+	return subject, body.String(), nil
+}
+
+func formatMinutes(m float64) string {
+	d := time.Duration(m * float64(time.Minute)).Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,136 @@
+package digest
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const scheduleInterval = 5 * time.Minute
+
+// Scheduler sends digest emails to subscribed users once their send time passes
+type Scheduler struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	notifier *notifier.Notifier
+	stopChan chan struct{}
+}
+
+func NewScheduler(postgres storage.Store, redis storage.StatusStore, notifier *notifier.Notifier) *Scheduler {
+	return &Scheduler{
+		postgres: postgres,
+		redis:    redis,
+		notifier: notifier,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Start(ctx context.Context) error {
+	slog.Info("Digest scheduler started")
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stopChan:
+			slog.Info("Digest scheduler stopped")
+			return nil
+		case <-ticker.C:
+			s.sendDue(ctx, time.Now())
+		}
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stopChan)
+}
+
+func (s *Scheduler) sendDue(ctx context.Context, now time.Time) {
+	subscriptions, err := s.postgres.ListDigestSubscriptions(ctx)
+	if err != nil {
+		slog.Error("Failed to list digest subscriptions", "error", err)
+		return
+	}
+
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if !sub.Enabled {
+			continue
+		}
+		start, end, ok := DuePeriod(sub, now)
+		if !ok {
+			continue
+		}
+		if err := s.send(ctx, sub, start, end); err != nil {
+			slog.Error("Failed to send digest", "digest_id", sub.ID, "error", err)
+			continue
+		}
+		if err := s.postgres.MarkDigestSent(ctx, sub.ID, now); err != nil {
+			slog.Error("Failed to mark digest sent", "digest_id", sub.ID, "error", err)
+		}
+	}
+}
+
+func (s *Scheduler) send(ctx context.Context, sub *models.DigestSubscription, start, end time.Time) error {
+	user, err := s.postgres.GetUser(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.Active || user.Email == "" {
+		return nil
+	}
+
+	channel, err := s.postgres.GetNotificationChannel(ctx, sub.NotificationChannelID)
+	if err != nil {
+		return err
+	}
+
+	d, err := Build(ctx, s.postgres, s.redis, sub.PropertyIDs, start, end)
+	if err != nil {
+		return err
+	}
+	subject, body, err := Render(sub.Frequency, d)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.SendEmail(ctx, channel, []string{user.Email}, subject, body)
+}
+
+// DuePeriod returns the reporting period for a subscription whose most recent send
+// time has passed without a digest being sent. Daily digests cover the previous 24
+// hours; weekly digests are sent on Mondays and cover the previous 7 days.
+func DuePeriod(sub *models.DigestSubscription, now time.Time) (time.Time, time.Time, bool) {
+	loc, err := time.LoadLocation(sub.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), sub.SendHour, 0, 0, 0, loc)
+	days := 1
+	if sub.Frequency == "weekly" {
+		days = 7
+		offset := (int(scheduled.Weekday()) + 6) % 7 // days since Monday
+		scheduled = scheduled.AddDate(0, 0, -offset)
+	}
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -days)
+	}
+
+	if sub.LastSentAt != nil && !sub.LastSentAt.Before(scheduled) {
+		return time.Time{}, time.Time{}, false
+	}
+	// Don't send a backlog of digests for a subscription created after its send time
+	if sub.LastSentAt == nil && sub.CreatedAt.After(scheduled) {
+		return time.Time{}, time.Time{}, false
+	}
+	return scheduled.AddDate(0, 0, -days), scheduled, true
+}
@@ -0,0 +1,122 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// uploadTimeout bounds each request of a resumable upload, long enough for one part
+// over a slow link
+const uploadTimeout = 5 * time.Minute
+
+// StartUpload opens a resumable upload session and returns its URL. The session is
+// started through a signed URL, so it needs no more access than download links.
+func (c *Client) StartUpload(ctx context.Context, objectName, contentType string, size int64) (string, error) {
+	opts := &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      http.MethodPost,
+		Expires:     time.Now().Add(15 * time.Minute),
+		ContentType: contentType,
+		Headers:     []string{"x-goog-resumable:start"},
+	}
+	signedURL, err := c.client.Bucket(c.bucketName).SignedURL(objectName, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign upload URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, signedURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("x-goog-resumable", "start")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to start GCS upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to start GCS upload: %s", responseError(resp))
+	}
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return "", fmt.Errorf("failed to start GCS upload: no session URL returned")
+	}
+	return session, nil
+}
+
+// UploadPart sends a part of a resumable upload and returns how many bytes GCS has
+// persisted, which it reports in the Range header of its 308 response
+func (c *Client) UploadPart(ctx context.Context, session string, offset int64, data []byte, size int64) (int64, error) {
+	end := offset + int64(len(data))
+	total := "*"
+	if end == size {
+		total = strconv.FormatInt(size, 10)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", offset, end-1, total))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write to GCS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return size, nil
+	case http.StatusPermanentRedirect:
+		// "bytes=0-N" covers what is persisted; no header means nothing is
+		persisted := resp.Header.Get("Range")
+		if persisted == "" {
+			return 0, nil
+		}
+		_, last, ok := strings.Cut(strings.TrimPrefix(persisted, "bytes="), "-")
+		n, err := strconv.ParseInt(last, 10, 64)
+		if !ok || err != nil {
+			return 0, fmt.Errorf("invalid Range from GCS: %q", persisted)
+		}
+		return n + 1, nil
+	default:
+		return 0, fmt.Errorf("failed to write to GCS: %s", responseError(resp))
+	}
+}
+
+// AbortUpload cancels a resumable upload; GCS answers 499 once it is cancelled
+func (c *Client) AbortUpload(ctx context.Context, session string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, session, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to cancel GCS upload: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 499 && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to cancel GCS upload: %s", responseError(resp))
+	}
+	return nil
+}
+
+// responseError describes a failed response by its status and the start of its body
+func responseError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Sprintf("HTTP %d: %s", resp.StatusCode, msg)
+	}
+	return fmt.Sprintf("HTTP %d", resp.StatusCode)
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
 )
 
 type Client struct {
 	client     *storage.Client
 	bucketName string
+	// http sends the parts of resumable uploads, whose session URLs need no credentials
+	http *http.Client
 }
 
 func NewClient(ctx context.Context, bucketName string) (*Client, error) {
@@ -23,13 +27,29 @@ func NewClient(ctx context.Context, bucketName string) (*Client, error) {
 	return &Client{
 		client:     client,
 		bucketName: bucketName,
+		http:       &http.Client{Timeout: uploadTimeout},
 	}, nil
 }
 
+// Kind identifies the store in attachment records
+func (c *Client) Kind() string {
+	return "gcs"
+}
+
 func (c *Client) Close() error {
 	return c.client.Close()
 }
 
+// Ping checks the bucket can be reached by listing at most one object, which needs
+// no more access than the attachments themselves
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.client.Bucket(c.bucketName).Objects(ctx, nil).Next()
+	if err != nil && err != iterator.Done {
+		return fmt.Errorf("failed to reach GCS bucket: %w", err)
+	}
+	return nil
+}
+
 // UploadFile uploads a file to GCS
 func (c *Client) UploadFile(ctx context.Context, objectName string, reader io.Reader, contentType string) error {
 	bucket := c.client.Bucket(c.bucketName)
@@ -78,6 +98,28 @@ func (c *Client) DeleteFile(ctx context.Context, objectName string) error {
 	return nil
 }
 
+// ListFiles calls fn for each object whose name starts with prefix
+func (c *Client) ListFiles(ctx context.Context, prefix string, fn func(name string, size int64, updated time.Time) error) error {
+	query := &storage.Query{Prefix: prefix}
+	if err := query.SetAttrSelection([]string{"Name", "Size", "Updated"}); err != nil {
+		return err
+	}
+
+	it := c.client.Bucket(c.bucketName).Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list GCS objects: %w", err)
+		}
+		if err := fn(attrs.Name, attrs.Size, attrs.Updated); err != nil {
+			return err
+		}
+	}
+}
+
 // GetFileMetadata retrieves metadata for a file
 func (c *Client) GetFileMetadata(ctx context.Context, objectName string) (*storage.ObjectAttrs, error) {
 	bucket := c.client.Bucket(c.bucketName)
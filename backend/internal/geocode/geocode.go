@@ -0,0 +1,48 @@
+// Package geocode turns property addresses into coordinates for the map view. The
+// provider is chosen by configuration, so a deployment can use the free OpenStreetMap
+// service, Google or its own Nominatim server.
+package geocode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrNotFound is returned when a provider has no match for an address
+var ErrNotFound = errors.New("address not found")
+
+// requestTimeout bounds a single geocoding request
+const requestTimeout = 10 * time.Second
+
+// Location is a point in WGS 84 degrees
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Geocoder looks up the location of a postal address
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (*Location, error)
+}
+
+// New returns the geocoder for a provider: nominatim or google. baseURL overrides the
+// provider's public endpoint and apiKey is required by Google. An empty provider
+// turns geocoding off and returns nil.
+func New(provider, baseURL, apiKey string) (Geocoder, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch provider {
+	case "":
+		return nil, nil
+	case "nominatim":
+		return NewNominatim(baseURL, client), nil
+	case "google":
+		if apiKey == "" {
+			return nil, fmt.Errorf("the google geocoder needs an API key")
+		}
+		return NewGoogle(baseURL, apiKey, client), nil
+	}
+	return nil, fmt.Errorf("unknown geocoder %q", provider)
+}
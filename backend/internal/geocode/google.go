@@ -0,0 +1,84 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultGoogleURL is the Google Maps Platform API host
+const DefaultGoogleURL = "https://maps.googleapis.com"
+
+// Google geocodes with the Google Maps Geocoding API
+type Google struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func NewGoogle(baseURL, apiKey string, client *http.Client) *Google {
+	if baseURL == "" {
+		baseURL = DefaultGoogleURL
+	}
+	return &Google{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, http: client}
+}
+
+// Geocode returns the location of the first result for address
+func (g *Google) Geocode(ctx context.Context, address string) (*Location, error) {
+	query := url.Values{"address": {address}, "key": {g.apiKey}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/maps/api/geocode/json?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.http.Do(req)
+	if err != nil {
+		// The URL carries the API key, so it is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to reach Google: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("google returned HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Results      []struct {
+			Geometry struct {
+				Location struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"location"`
+			} `json:"geometry"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode Google response: %w", err)
+	}
+
+	switch body.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return nil, ErrNotFound
+	default:
+		if body.ErrorMessage != "" {
+			return nil, fmt.Errorf("google geocoding failed: %s: %s", body.Status, body.ErrorMessage)
+		}
+		return nil, fmt.Errorf("google geocoding failed: %s", body.Status)
+	}
+	if len(body.Results) == 0 {
+		return nil, ErrNotFound
+	}
+
+	location := body.Results[0].Geometry.Location
+	return &Location{Latitude: location.Lat, Longitude: location.Lng}, nil
+}
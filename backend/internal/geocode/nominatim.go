@@ -0,0 +1,71 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultNominatimURL is the public OpenStreetMap Nominatim service. Its usage policy
+// allows about one request a second, which saving properties stays well within.
+const DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+// Nominatim geocodes with an OpenStreetMap Nominatim server
+type Nominatim struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewNominatim(baseURL string, client *http.Client) *Nominatim {
+	if baseURL == "" {
+		baseURL = DefaultNominatimURL
+	}
+	return &Nominatim{baseURL: strings.TrimRight(baseURL, "/"), http: client}
+}
+
+// Geocode returns the location of the best match for address
+func (n *Nominatim) Geocode(ctx context.Context, address string) (*Location, error) {
+	query := url.Values{"q": {address}, "format": {"jsonv2"}, "limit": {"1"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Nominatim rejects requests that don't identify the application
+	req.Header.Set("User-Agent", "ets-noc")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Nominatim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nominatim returned HTTP %d", resp.StatusCode)
+	}
+
+	// Coordinates come back as strings
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode Nominatim response: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude %q from Nominatim", results[0].Lat)
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude %q from Nominatim", results[0].Lon)
+	}
+	return &Location{Latitude: lat, Longitude: lon}, nil
+}
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Setup makes a JSON logger on stdout the default for slog and the standard log
+// package. levelName sets the minimum level (debug, info, warn or error; default info)
+// and every entry carries the service name.
+func Setup(service, levelName string) {
+	level := slog.LevelInfo
+	if levelName != "" {
+		if err := level.UnmarshalText([]byte(strings.ToUpper(levelName))); err != nil {
+			level = slog.LevelInfo
+			defer slog.Warn("Invalid log level, using info", "value", levelName)
+		}
+	}
+
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler).With("service", service))
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics shared by the API and the worker. Each binary serves the ones it
+// records, plus Go runtime and process metrics, on /metrics.
+var (
+	CheckDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ets_noc_check_duration_seconds",
+		Help:    "Time taken to run a device check, by check type and result.",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"check_type", "status"})
+
+	ChecksPerCycle = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ets_noc_checks_per_cycle",
+		Help:    "Checks queued per one-second scheduler cycle.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	CheckQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ets_noc_check_queue_length",
+		Help: "Checks waiting or running on the shared check queue.",
+	})
+
+	ScheduledDevices = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ets_noc_scheduled_devices",
+		Help: "Devices scheduled for checks by this worker.",
+	})
+
+	StoreErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ets_noc_store_errors_total",
+		Help: "Failed Redis commands and Postgres queries.",
+	}, []string{"store"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ets_noc_http_request_duration_seconds",
+		Help:    "API request latency, by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Handler serves the default registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -4,54 +4,165 @@ import "time"
 
 // Property represents a physical property location
 type Property struct {
+	ID                 int64    `json:"id"`
+	Name               string   `json:"name"`
+	Address            string   `json:"address"`
+	Subnet             string   `json:"subnet"`
+	Notes              string   `json:"notes"`
+	ISPCompanyName     string   `json:"isp_company_name"`
+	ISPAccountInfo     string   `json:"isp_account_info"`
+	PfSenseHost        string   `json:"pfsense_host"`
+	PfSensePort        int      `json:"pfsense_port"`
+	PfSenseUsername    string   `json:"pfsense_username"`
+	PfSensePassword    string   `json:"pfsense_password,omitempty"` // write-only; cleared before responses
+	PfSensePasswordSet bool     `json:"pfsense_password_set"`
+	GroupID            *int64   `json:"group_id"` // property group, nil when ungrouped
+	Tags               []string `json:"tags"`
+	Latitude           *float64 `json:"latitude"` // nil until geocoded or set by hand
+	Longitude          *float64 `json:"longitude"`
+	// CheckInterval, Retries and Timeout override the global defaults for the property's
+	// devices that don't set their own; 0 uses the global default
+	CheckInterval int       `json:"check_interval"`
+	Retries       int       `json:"retries"`
+	Timeout       int       `json:"timeout"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Region is the top of the portfolio hierarchy: region, then property group, then property
+type Region struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PropertyGroup is a set of properties within a region, such as one manager's portfolio
+type PropertyGroup struct {
+	ID          int64     `json:"id"`
+	RegionID    int64     `json:"region_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StatusPage publishes the status of a property group's properties to their owners,
+// who reach it with its token instead of signing in
+type StatusPage struct {
 	ID              int64     `json:"id"`
-	Name            string    `json:"name"`
-	Address         string    `json:"address"`
-	Subnet          string    `json:"subnet"`
-	Notes           string    `json:"notes"`
-	ISPCompanyName  string    `json:"isp_company_name"`
-	ISPAccountInfo  string    `json:"isp_account_info"`
-	PfSenseHost     string    `json:"pfsense_host"`
-	PfSensePort     int       `json:"pfsense_port"`
-	PfSenseUsername string    `json:"pfsense_username"`
-	PfSensePassword string    `json:"pfsense_password,omitempty"` // omitempty for security
+	PropertyGroupID int64     `json:"property_group_id"`
+	Title           string    `json:"title"`           // shown instead of the group name when set
+	Message         string    `json:"message"`         // optional notice shown above the properties
+	Token           string    `json:"token,omitempty"` // only returned when the token is created or rotated
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
+// PublicStatusPage is what a status page shows: customer-safe wording only, without
+// devices, addresses or internal notes
+type PublicStatusPage struct {
+	Title       string                 `json:"title"`
+	Message     string                 `json:"message,omitempty"`
+	Status      string                 `json:"status"` // worst property status: green, yellow or red
+	Summary     string                 `json:"summary"`
+	Properties  []PublicPropertyStatus `json:"properties"`
+	Incidents   []PublicIncident       `json:"incidents"`
+	GeneratedAt time.Time              `json:"generated_at"`
+}
+
+// PublicPropertyStatus is one property on a status page
+type PublicPropertyStatus struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"` // green, yellow, red, or unknown before the first check
+	Label       string     `json:"label"`
+	Maintenance bool       `json:"maintenance"`
+	Since       *time.Time `json:"since"`
+}
+
+// PublicIncident is a current outage or maintenance at a property on a status page
+type PublicIncident struct {
+	Property  string    `json:"property"`
+	Status    string    `json:"status"` // red, or maintenance
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// PropertyBadge lets a property's status badge be embedded in pages outside the NOC
+// with its token instead of a sign-in
+type PropertyBadge struct {
+	ID         int64     `json:"id"`
+	PropertyID int64     `json:"property_id"`
+	Token      string    `json:"token,omitempty"` // only returned when the token is created or rotated
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 // PropertyWithStatus includes computed status
 type PropertyWithStatus struct {
 	Property
-	Status          string `json:"status"`
-	OnlineCount     int    `json:"online_count"`
-	OfflineCount    int    `json:"offline_count"`
-	TotalCount      int    `json:"total_count"`
-	CriticalOffline bool   `json:"critical_offline"`
-	LastCheck       string `json:"last_check"`
+	Status           string `json:"status"`
+	OnlineCount      int    `json:"online_count"`
+	OfflineCount     int    `json:"offline_count"`
+	UnreachableCount int    `json:"unreachable_count"`
+	TotalCount       int    `json:"total_count"`
+	CriticalOffline  bool   `json:"critical_offline"`
+	Maintenance      bool   `json:"maintenance"`
+	Flapping         bool   `json:"flapping"`
+	Acknowledged     bool   `json:"acknowledged"`
+	Silenced         bool   `json:"silenced"`
+	LastCheck        string `json:"last_check"`
 }
 
 // PropertyStatus represents the computed rollup status
 type PropertyStatus struct {
-	PropertyID      int64     `json:"property_id"`
-	Status          string    `json:"status"` // red, yellow, green
-	OnlineCount     int       `json:"online_count"`
-	OfflineCount    int       `json:"offline_count"`
-	TotalCount      int       `json:"total_count"`
-	CriticalOffline bool      `json:"critical_offline"`
-	LastCheck       time.Time `json:"last_check"`
+	PropertyID       int64     `json:"property_id"`
+	Status           string    `json:"status"` // red, yellow, green
+	OnlineCount      int       `json:"online_count"`
+	OfflineCount     int       `json:"offline_count"`
+	UnreachableCount int       `json:"unreachable_count"`
+	TotalCount       int       `json:"total_count"`
+	CriticalOffline  bool      `json:"critical_offline"`
+	Maintenance      bool      `json:"maintenance"`
+	Flapping         bool      `json:"flapping"` // every offline device is flapping or in maintenance
+	LastCheck        time.Time `json:"last_check"`
+	Since            time.Time `json:"since"` // when the property entered its current status
 }
 
 // Contact represents a contact for a property
 type Contact struct {
-	ID         int64     `json:"id"`
-	PropertyID int64     `json:"property_id"`
-	Name       string    `json:"name"`
-	Phone      string    `json:"phone"`
-	Email      string    `json:"email"`
-	Role       string    `json:"role"`
-	Notes      string    `json:"notes"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         int64  `json:"id"`
+	PropertyID int64  `json:"property_id"`
+	Name       string `json:"name"`
+	Phone      string `json:"phone"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	Notes      string `json:"notes"`
+	// EscalationOrder puts the contact on the call list sent with red alerts, 1
+	// first; 0 leaves them off
+	EscalationOrder int       `json:"escalation_order"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Circuit is an ISP circuit serving a property. When the device it terminates on goes
+// down, the property's down alerts name the circuit and the provider's support number.
+type Circuit struct {
+	ID                int64     `json:"id"`
+	PropertyID        int64     `json:"property_id"`
+	Provider          string    `json:"provider"`
+	CircuitID         string    `json:"circuit_id"` // the provider's circuit identifier
+	AccountNumber     string    `json:"account_number"`
+	SupportPhone      string    `json:"support_phone"` // provider NOC or repair line
+	BandwidthDownMbps int       `json:"bandwidth_down_mbps"`
+	BandwidthUpMbps   int       `json:"bandwidth_up_mbps"`
+	StaticIPs         []string  `json:"static_ips"` // addresses or CIDR blocks
+	DeviceID          *int64    `json:"device_id"`  // WAN device the circuit terminates on, such as the modem or router
+	Notes             string    `json:"notes"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // Attachment represents a file attachment for a property
@@ -60,58 +171,443 @@ type Attachment struct {
 	PropertyID  int64     `json:"property_id"`
 	Filename    string    `json:"filename"`
 	Description string    `json:"description"`
-	StorageType string    `json:"storage_type"` // gcs or google_drive
+	StorageType string    `json:"storage_type"` // gcs, s3, local or google_drive
 	StoragePath string    `json:"storage_path"`
 	FileSize    int64     `json:"file_size"`
 	MimeType    string    `json:"mime_type"`
+	Tags        []string  `json:"tags"`
 	UploadedBy  string    `json:"uploaded_by"`
 	CreatedAt   time.Time `json:"created_at"`
+	// PropertyName is filled in when attachments are read back, for search results
+	PropertyName string `json:"property_name,omitempty"`
+}
+
+// AttachmentUpdate changes an attachment's description and tags
+type AttachmentUpdate struct {
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// AttachmentUpload is a chunked upload of an attachment in progress. Parts are sent
+// in order, each ChunkSize bytes but the last, starting at Received; once Received
+// reaches FileSize it is completed into an attachment.
+type AttachmentUpload struct {
+	ID          int64    `json:"id"`
+	PropertyID  int64    `json:"property_id"`
+	Filename    string   `json:"filename"`
+	Description string   `json:"description"`
+	MimeType    string   `json:"mime_type"`
+	Tags        []string `json:"tags"`
+	FileSize    int64    `json:"file_size"`
+	Received    int64    `json:"received"`
+	ChunkSize   int64    `json:"chunk_size"`
+	StorageType string   `json:"storage_type"`
+	StoragePath string   `json:"-"`
+	// Session is the blob store's handle on the upload, a credential for GCS
+	Session    string    `json:"-"`
+	UploadedBy string    `json:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// StartAttachmentUploadRequest begins a chunked upload of a file of Size bytes
+type StartAttachmentUploadRequest struct {
+	Filename    string   `json:"filename" binding:"required"`
+	Description string   `json:"description"`
+	MimeType    string   `json:"mime_type"`
+	Tags        []string `json:"tags"`
+	Size        int64    `json:"size" binding:"required"`
+}
+
+// DriveAttachmentRequest links a Google Drive file picked in the browser. AccessToken
+// is the picker's token, used only to look the file up.
+type DriveAttachmentRequest struct {
+	FileID      string   `json:"file_id" binding:"required"`
+	AccessToken string   `json:"access_token" binding:"required"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// OrphanedFile is an attachment file in blob storage that no attachment or chunked
+// upload refers to
+type OrphanedFile struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrphanSweep reports a pass over the attachment files in blob storage. A dry run
+// only lists the orphans; otherwise Deleted and Failed count the attempts to remove
+// them.
+type OrphanSweep struct {
+	DryRun        bool           `json:"dry_run"`
+	Scanned       int            `json:"scanned"`
+	Orphans       []OrphanedFile `json:"orphans"`
+	OrphanedBytes int64          `json:"orphaned_bytes"`
+	Deleted       int            `json:"deleted"`
+	Failed        int            `json:"failed"`
 }
 
 // Device represents a network device to monitor
 type Device struct {
-	ID            int64    `json:"id"`
-	PropertyID    int64    `json:"property_id"`
-	Name          string   `json:"name"`
-	Hostname      string   `json:"hostname"`
-	DeviceType    string   `json:"device_type"`
-	IsCritical    bool     `json:"is_critical"`
-	CheckInterval int      `json:"check_interval"`
-	Retries       int      `json:"retries"`
-	Timeout       int      `json:"timeout"`
-	Description   string   `json:"description"`
-	Tags          []string `json:"tags"`
-	Active        bool     `json:"active"`
+	ID               int64    `json:"id"`
+	PropertyID       int64    `json:"property_id"`
+	Name             string   `json:"name"`
+	Hostname         string   `json:"hostname"`
+	DeviceType       string   `json:"device_type"`
+	IsCritical       bool     `json:"is_critical"`
+	CheckInterval    int      `json:"check_interval"`
+	Retries          int      `json:"retries"`
+	Timeout          int      `json:"timeout"`
+	Description      string   `json:"description"`
+	Tags             []string `json:"tags"`
+	Active           bool     `json:"active"`
+	CheckType        string   `json:"check_type"`        // icmp, tcp, vpn (hostname is the tunnel, e.g. ipsec:con1), carp (hostname is a firewall host) or ups (hostname is ups@host)
+	Port             int      `json:"port"`              // TCP port for tcp checks
+	FailureThreshold int      `json:"failure_threshold"` // consecutive failed checks before hard offline
+	ParentDeviceID   *int64   `json:"parent_device_id"`  // upstream device (switch/router) this device depends on
+	MACAddress       string   `json:"mac_address"`       // lowercase, set by pfSense sync
+	ProbeID          *int64   `json:"probe_id"`          // remote probe that checks this device instead of the worker
+	// Asset details, kept for inventory and warranty tracking; dates are whole days in UTC
+	SerialNumber    string     `json:"serial_number"`
+	Manufacturer    string     `json:"manufacturer"`
+	Model           string     `json:"model"`
+	FirmwareVersion string     `json:"firmware_version"`
+	PurchaseDate    *time.Time `json:"purchase_date"`
+	WarrantyExpires *time.Time `json:"warranty_expires"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// DeviceWarranty is a device in the warranty report. DaysLeft is negative once the
+// warranty has lapsed.
+type DeviceWarranty struct {
+	Device
+	PropertyName string `json:"property_name"`
+	DaysLeft     int    `json:"days_left"`
+}
+
+// FirmwareGroup is one model and firmware version in the firmware fleet report, with the
+// devices running it
+type FirmwareGroup struct {
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model"`
+	Version      string `json:"version"`
+	// MinimumVersion is the configured minimum for the model, empty when there is none
+	MinimumVersion string           `json:"minimum_version,omitempty"`
+	BelowMinimum   bool             `json:"below_minimum"`
+	Count          int              `json:"count"`
+	Devices        []FirmwareDevice `json:"devices"`
+}
+
+// FirmwareDevice is a device listed in a firmware fleet report group
+type FirmwareDevice struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	PropertyID   int64  `json:"property_id"`
+	PropertyName string `json:"property_name"`
+}
+
+// DeviceAudit is the result of checking every device for address conflicts
+type DeviceAudit struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Issues      []DeviceAuditIssue `json:"issues"`
+}
+
+// Device audit issue kinds
+const (
+	AuditDuplicateTarget = "duplicate_target"
+	AuditOutsideSubnet   = "outside_subnet"
+	AuditDuplicateMAC    = "duplicate_mac"
+)
+
+// DeviceAuditIssue is one conflict found by the device audit: devices at a property
+// checking the same target, a device whose private address is outside its property's
+// subnet, or devices anywhere sharing a MAC address
+type DeviceAuditIssue struct {
+	Kind string `json:"kind"`
+	// Value is the shared target or MAC, or the address outside the subnet
+	Value      string        `json:"value"`
+	PropertyID *int64        `json:"property_id,omitempty"` // unset for duplicate MACs
+	Detail     string        `json:"detail"`
+	Devices    []AuditDevice `json:"devices"`
+}
+
+// AuditDevice is a device named in a device audit issue
+type AuditDevice struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Hostname     string `json:"hostname"`
+	MACAddress   string `json:"mac_address,omitempty"`
+	PropertyID   int64  `json:"property_id"`
+	PropertyName string `json:"property_name"`
+}
+
+// DeviceTemplate holds check settings shared by a kind of device, such as a standard
+// guest WAP, so they can be stamped onto devices instead of entered for each one
+type DeviceTemplate struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	DeviceType    string    `json:"device_type"`
+	IsCritical    bool      `json:"is_critical"`
+	CheckInterval int       `json:"check_interval"`
+	Retries       int       `json:"retries"`
+	Timeout       int       `json:"timeout"`
+	Tags          []string  `json:"tags"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// DeviceOperation is one item of a bulk device request
+type DeviceOperation struct {
+	Op         string  `json:"op"`                    // create, update or delete
+	ID         int64   `json:"id,omitempty"`          // device to update or delete
+	Device     *Device `json:"device,omitempty"`      // full device for create and update
+	TemplateID int64   `json:"template_id,omitempty"` // device template stamped onto the device
+}
+
+// DeviceOperationResult is the outcome of one bulk device operation. Device is the
+// stored device after a create or update; Error is set on the items that failed.
+type DeviceOperationResult struct {
+	Index  int     `json:"index"`
+	Op     string  `json:"op"`
+	ID     int64   `json:"id,omitempty"`
+	Device *Device `json:"device,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// BulkDeviceResponse reports a bulk device request. Applied is false when any
+// operation failed, in which case none of them were applied.
+type BulkDeviceResponse struct {
+	Applied bool                    `json:"applied"`
+	Error   string                  `json:"error,omitempty"`
+	Results []DeviceOperationResult `json:"results"`
+}
+
+// DeviceImportRow is one CSV row of a device import and what it does. Action is create,
+// update or unchanged; Error is set on rows that failed validation.
+type DeviceImportRow struct {
+	Line     int    `json:"line"`
+	Action   string `json:"action,omitempty"`
+	DeviceID int64  `json:"device_id,omitempty"` // existing device for updates, new device once created
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DeviceImportResult reports a device CSV import or its dry run
+type DeviceImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Applied   bool              `json:"applied"`
+	Error     string            `json:"error,omitempty"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Rows      []DeviceImportRow `json:"rows"`
+}
+
 // DeviceStatus represents the current status of a device
 type DeviceStatus struct {
-	DeviceID     int64     `json:"device_id"`
-	Status       string    `json:"status"` // online or offline
-	ResponseTime float64   `json:"response_time"`
-	LastCheck    time.Time `json:"last_check"`
-	Message      string    `json:"message"`
+	DeviceID            int64      `json:"device_id"`
+	Status              string     `json:"status"` // online, offline or unreachable (parent offline)
+	ResponseTime        float64    `json:"response_time"`
+	LastCheck           time.Time  `json:"last_check"`
+	Message             string     `json:"message"`
+	StateType           string     `json:"state_type"` // soft (unconfirmed failure) or hard
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Maintenance         bool       `json:"maintenance"`   // checked during an active maintenance window
+	Since               time.Time  `json:"since"`         // when the device entered its current confirmed status
+	Flapping            bool       `json:"flapping"`      // changing state too often; its alerts are held back
+	UPS                 *UPSStatus `json:"ups,omitempty"` // set by UPS checks
+}
+
+// UPSStatus is the battery state reported by a UPS check
+type UPSStatus struct {
+	OnBattery      bool     `json:"on_battery"`
+	LowBattery     bool     `json:"low_battery"`
+	ChargePercent  *float64 `json:"charge_percent"`
+	RuntimeSeconds *int     `json:"runtime_seconds"`
+	BatteryHealth  string   `json:"battery_health"` // ok or replace
+	Flags          string   `json:"flags"`          // raw NUT ups.status, e.g. "OB DISCHRG"
+}
+
+// DeviceStatusChange is published by the worker when a device's status or state type changes
+type DeviceStatusChange struct {
+	PropertyID     int64        `json:"property_id"`
+	DeviceID       int64        `json:"device_id"`
+	DeviceName     string       `json:"device_name"`
+	PreviousStatus string       `json:"previous_status"` // empty on first check
+	Status         DeviceStatus `json:"status"`
+}
+
+// StatusEvent records a device or property state transition
+type StatusEvent struct {
+	ID               int64     `json:"id"`
+	EntityType       string    `json:"entity_type"` // device, property
+	PropertyID       int64     `json:"property_id"`
+	DeviceID         *int64    `json:"device_id"`
+	FromStatus       string    `json:"from_status"`
+	ToStatus         string    `json:"to_status"`
+	OccurredAt       time.Time `json:"occurred_at"`
+	PreviousDuration *int64    `json:"previous_duration"` // seconds spent in from_status, nil if unknown
+	Message          string    `json:"message"`
+}
+
+// ActivityEvent is an entry in the activity feed: a status change, incident,
+// acknowledgement, notification delivery or sync with pfSense
+type ActivityEvent struct {
+	ID           string    `json:"id"`   // source and row ID, e.g. status_event:42
+	Kind         string    `json:"kind"` // status_change, incident, acknowledgement, notification, sync
+	Action       string    `json:"action"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	PropertyID   int64     `json:"property_id"`
+	PropertyName string    `json:"property_name"`
+	DeviceID     *int64    `json:"device_id"`
+	DeviceName   string    `json:"device_name,omitempty"`
+	Summary      string    `json:"summary"`
+	Detail       string    `json:"detail,omitempty"`
+	Actor        string    `json:"actor,omitempty"`
+	Success      *bool     `json:"success,omitempty"` // notification deliveries and DHCP changes
+	Source       string    `json:"-"`
+	SourceID     int64     `json:"-"`
+}
+
+// ActivityFeed is a page of the activity feed, newest first. NextCursor fetches the
+// following page and is empty on the last one.
+type ActivityFeed struct {
+	Events     []ActivityEvent `json:"events"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 }
 
 // DeviceHistory represents historical status data point
 type DeviceHistory struct {
 	Timestamp    int64   `json:"timestamp"`
 	Status       string  `json:"status"`
+	StateType    string  `json:"state_type,omitempty"`
 	ResponseTime float64 `json:"response_time"`
 	Message      string  `json:"message,omitempty"`
+	Maintenance  bool    `json:"maintenance,omitempty"`
+}
+
+// DeviceHistoryBucket aggregates a device's raw checks over one interval of a
+// downsampled history. Soft failures count as online, as in rollups.
+type DeviceHistoryBucket struct {
+	Timestamp         int64    `json:"timestamp"`     // start of the bucket
+	ResponseTime      *float64 `json:"response_time"` // aggregate of successful checks; nil when none succeeded
+	Checks            int      `json:"checks"`
+	OnlineChecks      int      `json:"online_checks"`
+	OfflineChecks     int      `json:"offline_checks"`
+	UnreachableChecks int      `json:"unreachable_checks"`
+}
+
+// DeviceHistoryRollup aggregates a device's checks over an hour or a day. Soft
+// failures count as online; offline and unreachable checks during maintenance are
+// also counted in MaintenanceChecks.
+type DeviceHistoryRollup struct {
+	DeviceID          int64     `json:"device_id"`
+	Resolution        string    `json:"resolution"` // hour, day
+	BucketStart       time.Time `json:"bucket_start"`
+	Checks            int       `json:"checks"`
+	OnlineChecks      int       `json:"online_checks"`
+	OfflineChecks     int       `json:"offline_checks"`
+	UnreachableChecks int       `json:"unreachable_checks"`
+	MaintenanceChecks int       `json:"maintenance_checks"`
+	AvgResponseTime   *float64  `json:"avg_response_time"` // nil when no check in the bucket succeeded
+	MinResponseTime   *float64  `json:"min_response_time"`
+	MaxResponseTime   *float64  `json:"max_response_time"`
+}
+
+// UptimeReport summarizes availability over a period. A device is down when it is
+// hard offline or unreachable outside a maintenance window; each check's state is
+// assumed to hold until the next check.
+type UptimeReport struct {
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnd        time.Time `json:"period_end"`
+	UptimePercent    *float64  `json:"uptime_percent"` // nil when there is no history in the period
+	DowntimeMinutes  float64   `json:"downtime_minutes"`
+	MonitoredMinutes float64   `json:"monitored_minutes"`
+	OutageCount      int       `json:"outage_count"`
+	Checks           int       `json:"checks"`
+}
+
+// DeviceUptime is the uptime report for a single device
+type DeviceUptime struct {
+	DeviceID   int64  `json:"device_id"`
+	DeviceName string `json:"device_name"`
+	UptimeReport
+}
+
+// PropertyUptime combines the uptime of every device at a property, weighted by
+// monitored time, with a per-device breakdown
+type PropertyUptime struct {
+	PropertyID int64 `json:"property_id"`
+	UptimeReport
+	Devices []DeviceUptime `json:"devices"`
+}
+
+// AvailabilityCalendar is a device's or property's uptime per day or hour, for a
+// calendar heatmap. It is built from status changes: a device is down while it isn't
+// online and a property while it is red.
+type AvailabilityCalendar struct {
+	EntityType    string               `json:"entity_type"` // device or property
+	EntityID      int64                `json:"entity_id"`
+	Resolution    string               `json:"resolution"` // day or hour
+	Timezone      string               `json:"timezone"`
+	Start         time.Time            `json:"start"`
+	End           time.Time            `json:"end"`
+	UptimePercent *float64             `json:"uptime_percent"` // over the whole range
+	Buckets       []AvailabilityBucket `json:"buckets"`
+}
+
+// AvailabilityBucket is one day or hour of an availability calendar
+type AvailabilityBucket struct {
+	Start            time.Time `json:"start"`
+	Date             string    `json:"date"`           // YYYY-MM-DD in the calendar's timezone
+	UptimePercent    *float64  `json:"uptime_percent"` // nil before monitoring began or in the future
+	DowntimeMinutes  float64   `json:"downtime_minutes"`
+	MonitoredMinutes float64   `json:"monitored_minutes"`
+	OutageCount      int       `json:"outage_count"` // outages that started in the bucket
+}
+
+// PropertyTopology is a property's devices as a graph for a site map. Edges point
+// from the upstream device to the device behind it.
+type PropertyTopology struct {
+	PropertyID int64          `json:"property_id"`
+	Nodes      []TopologyNode `json:"nodes"`
+	Edges      []TopologyEdge `json:"edges"`
+}
+
+// TopologyNode is a device on a topology map with its current status
+type TopologyNode struct {
+	DeviceID    int64  `json:"device_id"`
+	Name        string `json:"name"`
+	Hostname    string `json:"hostname"`
+	DeviceType  string `json:"device_type"`
+	IsCritical  bool   `json:"is_critical"`
+	Active      bool   `json:"active"`
+	Status      string `json:"status"` // online, offline, unreachable or unknown
+	Maintenance bool   `json:"maintenance"`
+	Network     string `json:"network,omitempty"` // subnet the device's address falls in, empty for hostnames
+}
+
+// TopologyEdge links an upstream device to a device that depends on it
+type TopologyEdge struct {
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	Kind string `json:"kind"` // dependency (parent_device_id) or subnet (inferred from the router on the device's subnet)
 }
 
 // NotificationChannel represents a notification destination
 type NotificationChannel struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Type        string    `json:"type"` // slack, email
-	Config      string    `json:"config"` // JSON config
-	Enabled     bool      `json:"enabled"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`   // slack, email, pagerduty, sms
+	Config       string    `json:"config"` // JSON config
+	Enabled      bool      `json:"enabled"`
+	SystemAlerts bool      `json:"system_alerts"` // also receives NOC alerts such as workers down
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // PropertyNotification links properties to notification channels
@@ -122,6 +618,40 @@ type PropertyNotification struct {
 	Enabled               bool  `json:"enabled"`
 	NotifyOnRed           bool  `json:"notify_on_red"`
 	NotifyOnRecovery      bool  `json:"notify_on_recovery"`
+	// Active hours as HH:MM in Timezone; outside them notifications are held back
+	// unless BypassCritical is set and a critical device is offline. Empty means always.
+	ActiveFrom     string `json:"active_from"`
+	ActiveTo       string `json:"active_to"`
+	Timezone       string `json:"timezone"`
+	BypassCritical bool   `json:"bypass_critical"`
+}
+
+// NotificationTestResult is the outcome of a test-fire through a notification channel
+type NotificationTestResult struct {
+	NotificationChannelID int64  `json:"notification_channel_id"`
+	Success               bool   `json:"success"`
+	Error                 string `json:"error,omitempty"`
+	DurationMs            int64  `json:"duration_ms"`
+}
+
+// NotificationRule routes property down alerts to a channel based on which devices
+// are down. Empty match fields match anything; a rule matches when at least one down
+// device satisfies both Tags (any of) and DeviceTypes (any of).
+type NotificationRule struct {
+	ID                    int64     `json:"id"`
+	Name                  string    `json:"name"`
+	Priority              int       `json:"priority"` // lower is evaluated first
+	Enabled               bool      `json:"enabled"`
+	PropertyID            *int64    `json:"property_id"` // nil matches every property
+	Tags                  []string  `json:"tags"`
+	DeviceTypes           []string  `json:"device_types"`
+	PropertyTags          []string  `json:"property_tags"` // the property must have one; empty matches every property
+	Severity              string    `json:"severity"`      // critical, warning; empty matches both
+	NotificationChannelID int64     `json:"notification_channel_id"`
+	NotifyOnRecovery      bool      `json:"notify_on_recovery"`
+	StopProcessing        bool      `json:"stop_processing"` // skip lower priority rules once matched
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 // NotificationEvent tracks notification history
@@ -138,31 +668,98 @@ type NotificationEvent struct {
 
 // User represents a system user
 type User struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"` // admin, user
-	Active    bool      `json:"active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	Password    string    `json:"-"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone"` // E.164, used for on-call SMS
+	Role        string    `json:"role"`  // admin, user, viewer
+	Active      bool      `json:"active"`
+	TOTPEnabled bool      `json:"totp_enabled"` // password logins also need an authenticator code
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserSession is a signed-in session. Its ID is carried in the session token, so
+// revoking the session signs that token out.
+type UserSession struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `json:"current"` // the session making the request
+}
+
+// UserTOTP is a user's two-factor state. Secret is set from enrollment on, before the
+// first code is verified and Enabled is set; LastStep is the time step of the last
+// accepted code, so a code can't be replayed.
+type UserTOTP struct {
+	UserID   int64
+	Enabled  bool
+	Secret   string
+	LastStep int64
 }
 
 // Settings represents system-wide settings
 type Settings struct {
-	ID                     int64  `json:"id"`
-	MaxConcurrentPings     int    `json:"max_concurrent_pings"`
-	DefaultCheckInterval   int    `json:"default_check_interval"`
-	DefaultRetries         int    `json:"default_retries"`
-	DefaultTimeout         int    `json:"default_timeout"`
-	HistoryRetentionDays   int    `json:"history_retention_days"`
-	NotificationCooldown   int    `json:"notification_cooldown"`
+	ID                   int64 `json:"id"`
+	MaxConcurrentPings   int   `json:"max_concurrent_pings"`
+	DefaultCheckInterval int   `json:"default_check_interval"`
+	DefaultRetries       int   `json:"default_retries"`
+	DefaultTimeout       int   `json:"default_timeout"`
+	HistoryRetentionDays int   `json:"history_retention_days"`
+	NotificationCooldown int   `json:"notification_cooldown"`
+	// MaxAttachmentSizeMB caps attachment uploads; files over 50MB must use chunked
+	// uploads
+	MaxAttachmentSizeMB int `json:"max_attachment_size_mb"`
+	// OAuthAllowedDomains are the email domains allowed to sign in with Google or OIDC
+	OAuthAllowedDomains []string `json:"oauth_allowed_domains"`
+	// OAuthDefaultRole is the role given to users created by their first Google or OIDC
+	// sign-in when no OIDC role mapping applies
+	OAuthDefaultRole string `json:"oauth_default_role"`
+	// OIDC configures single sign-on through an OpenID Connect provider
+	OIDC *OIDCSettings `json:"oidc"`
+	// FirmwareMinimums maps a device model, such as U6-Pro or pfSense, to the lowest
+	// firmware version the fleet report accepts for it
+	FirmwareMinimums map[string]string `json:"firmware_minimums"`
+	// SubnetBase is where automatic property subnets start: a property without its own
+	// subnet gets the /24 as many /24s after it as its ID, skipping ones already in use
+	SubnetBase string `json:"subnet_base"`
+}
+
+// OIDCSettings configures sign-in through a generic OpenID Connect provider such as
+// Azure AD or Okta. ClientSecret is write-only; leaving it empty keeps the stored one.
+type OIDCSettings struct {
+	Enabled bool `json:"enabled"`
+	// Name labels the sign-in button, e.g. "Okta"
+	Name            string   `json:"name"`
+	Issuer          string   `json:"issuer"`
+	ClientID        string   `json:"client_id"`
+	ClientSecret    string   `json:"client_secret,omitempty"`
+	ClientSecretSet bool     `json:"client_secret_set"`
+	Scopes          []string `json:"scopes"`
+	// RoleClaim names the ID token claim, such as groups or roles, whose values are
+	// looked up in RoleMapping to pick the user's role
+	RoleClaim   string            `json:"role_claim"`
+	RoleMapping map[string]string `json:"role_mapping"`
 }
 
-// LoginRequest represents login credentials
+// LoginRequest represents login credentials. TOTPCode is an authenticator code or an
+// unused recovery code, required for users with two-factor authentication enabled.
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// ChangePasswordRequest changes the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 // LoginResponse contains JWT token
@@ -174,6 +771,8 @@ type LoginResponse struct {
 // DashboardResponse contains all properties with status
 type DashboardResponse struct {
 	Properties []PropertyWithStatus `json:"properties"`
+	Groups     []GroupStatus        `json:"groups"`
+	Workers    *WorkerHealth        `json:"workers,omitempty"`
 	Summary    struct {
 		TotalProperties int `json:"total_properties"`
 		RedCount        int `json:"red_count"`
@@ -182,7 +781,451 @@ type DashboardResponse struct {
 	} `json:"summary"`
 }
 
+// PropertyMap is the map view of the properties. Properties without coordinates
+// can't be placed, so they are only counted.
+type PropertyMap struct {
+	Properties []PropertyWithStatus `json:"properties"`
+	Unlocated  int                  `json:"unlocated"`
+}
+
+// GroupStatus rolls up the statuses of a property group's properties. Status is the
+// worst of them; GroupID 0 collects the ungrouped properties.
+type GroupStatus struct {
+	GroupID         int64  `json:"group_id"`
+	Name            string `json:"name"`
+	RegionID        int64  `json:"region_id"`
+	RegionName      string `json:"region_name"`
+	Status          string `json:"status"`
+	TotalProperties int    `json:"total_properties"`
+	RedCount        int    `json:"red_count"`
+	YellowCount     int    `json:"yellow_count"`
+	GreenCount      int    `json:"green_count"`
+}
+
+// DashboardUpdate is a message pushed to live dashboard clients: a full snapshot
+// on connect, then one property status per change
+type DashboardUpdate struct {
+	Type      string             `json:"type"` // snapshot, property_status
+	Dashboard *DashboardResponse `json:"dashboard,omitempty"`
+	Status    *PropertyStatus    `json:"status,omitempty"`
+}
+
 // ErrorResponse represents an API error
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// MaintenanceWindow is a scheduled period during which a property or device is
+// still checked but status changes don't trigger notifications
+type MaintenanceWindow struct {
+	ID         int64     `json:"id"`
+	PropertyID *int64    `json:"property_id"`
+	DeviceID   *int64    `json:"device_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Reason     string    `json:"reason"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Acknowledgement marks an active property or device alert as being handled. It stops
+// further down notifications and is cleared automatically on recovery.
+type Acknowledgement struct {
+	ID             int64      `json:"id"`
+	EntityType     string     `json:"entity_type"` // property, device
+	PropertyID     int64      `json:"property_id"`
+	DeviceID       *int64     `json:"device_id"`
+	AcknowledgedBy string     `json:"acknowledged_by"`
+	Comment        string     `json:"comment"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ClearedAt      *time.Time `json:"cleared_at"`
+}
+
+// Silence mutes notifications for a device, a property, or every device with a tag
+// until it expires
+type Silence struct {
+	ID         int64     `json:"id"`
+	ScopeType  string    `json:"scope_type"` // device, property, tag
+	PropertyID *int64    `json:"property_id"`
+	DeviceID   *int64    `json:"device_id"`
+	Tag        string    `json:"tag"`
+	Reason     string    `json:"reason"`
+	CreatedBy  string    `json:"created_by"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OnCallSchedule rotates on-call duty through an ordered list of users. Each member
+// covers RotationDays starting at RotationStart, with handoffs at the same local
+// time in Timezone.
+type OnCallSchedule struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Timezone      string    `json:"timezone"`
+	RotationStart time.Time `json:"rotation_start"`
+	RotationDays  int       `json:"rotation_days"`
+	MemberIDs     []int64   `json:"member_ids"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// OnCallOverride puts a user on call for a schedule in place of the rotation
+type OnCallOverride struct {
+	ID         int64     `json:"id"`
+	ScheduleID int64     `json:"schedule_id"`
+	UserID     int64     `json:"user_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OnCallShift is who is on call for a schedule at a point in time
+type OnCallShift struct {
+	ScheduleID   int64     `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name"`
+	User         *User     `json:"user"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	Override     bool      `json:"override"`
+}
+
+// DigestSubscription sends a user a periodic summary email for a set of properties,
+// or the whole fleet when PropertyIDs is empty, through an email channel's SMTP settings
+type DigestSubscription struct {
+	ID                    int64      `json:"id"`
+	UserID                int64      `json:"user_id"`
+	Frequency             string     `json:"frequency"` // daily, weekly (sent on Mondays)
+	PropertyIDs           []int64    `json:"property_ids"`
+	NotificationChannelID int64      `json:"notification_channel_id"`
+	SendHour              int        `json:"send_hour"` // 0-23 in Timezone
+	Timezone              string     `json:"timezone"`
+	Enabled               bool       `json:"enabled"`
+	LastSentAt            *time.Time `json:"last_sent_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// PropertyOutageSummary is a property's red time over a reporting period
+type PropertyOutageSummary struct {
+	PropertyID      int64   `json:"property_id"`
+	PropertyName    string  `json:"property_name"`
+	OutageCount     int     `json:"outage_count"`
+	DowntimeMinutes float64 `json:"downtime_minutes"`
+}
+
+// DeviceOutageSummary is a device's offline time over a reporting period
+type DeviceOutageSummary struct {
+	DeviceID        int64   `json:"device_id"`
+	DeviceName      string  `json:"device_name"`
+	PropertyID      int64   `json:"property_id"`
+	PropertyName    string  `json:"property_name"`
+	OutageCount     int     `json:"outage_count"`
+	DowntimeMinutes float64 `json:"downtime_minutes"`
+}
+
+// Digest summarizes outages over a period for a digest email
+type Digest struct {
+	PeriodStart     time.Time               `json:"period_start"`
+	PeriodEnd       time.Time               `json:"period_end"`
+	PropertyCount   int                     `json:"property_count"`
+	OutageCount     int                     `json:"outage_count"`
+	DowntimeMinutes float64                 `json:"downtime_minutes"`
+	Properties      []PropertyOutageSummary `json:"properties"`
+	WorstDevices    []DeviceOutageSummary   `json:"worst_devices"`
+	CurrentReds     []RedProperty           `json:"current_reds"`
+}
+
+// FleetStats summarizes the whole fleet for operations reviews. Uptime, outages and
+// latency cover the period; the other counts are as of GeneratedAt.
+type FleetStats struct {
+	PeriodStart     time.Time               `json:"period_start"`
+	PeriodEnd       time.Time               `json:"period_end"`
+	GeneratedAt     time.Time               `json:"generated_at"`
+	PropertyCount   int                     `json:"property_count"`
+	DeviceCount     int                     `json:"device_count"` // active devices
+	DevicesByType   map[string]int          `json:"devices_by_type"`
+	Devices         DeviceStatusCounts      `json:"devices"`
+	Properties      PropertyStatusCounts    `json:"properties"`
+	Incidents       IncidentCounts          `json:"incidents"`
+	WorstProperties []PropertyUptimeSummary `json:"worst_properties"`
+	LatencyTrend    []LatencyPoint          `json:"latency_trend"`
+}
+
+// DeviceStatusCounts counts active devices by current status
+type DeviceStatusCounts struct {
+	Up          int `json:"up"`
+	Down        int `json:"down"`
+	Unreachable int `json:"unreachable"` // behind an offline parent
+	Unknown     int `json:"unknown"`     // not checked yet
+}
+
+// PropertyStatusCounts counts properties by current rollup status
+type PropertyStatusCounts struct {
+	Red    int `json:"red"`
+	Yellow int `json:"yellow"`
+	Green  int `json:"green"`
+}
+
+// IncidentCounts counts property outages: open ones are red properties whose alerts
+// aren't held back by maintenance or flapping
+type IncidentCounts struct {
+	Open         int `json:"open"`
+	Acknowledged int `json:"acknowledged"` // open and acknowledged
+	Opened       int `json:"opened"`       // started during the period
+}
+
+// PropertyUptimeSummary is a property's availability over a period, taking the time
+// it was red as down
+type PropertyUptimeSummary struct {
+	PropertyID      int64   `json:"property_id"`
+	PropertyName    string  `json:"property_name"`
+	UptimePercent   float64 `json:"uptime_percent"`
+	DowntimeMinutes float64 `json:"downtime_minutes"`
+	OutageCount     int     `json:"outage_count"`
+}
+
+// LatencyPoint is the fleet's mean response time for successful checks over one day
+type LatencyPoint struct {
+	Day             time.Time `json:"day"`
+	AvgResponseTime *float64  `json:"avg_response_time"` // nil when no check succeeded
+	Checks          int       `json:"checks"`
+}
+
+// RedProperty is a property that is red when a report is built
+type RedProperty struct {
+	PropertyID   int64     `json:"property_id"`
+	PropertyName string    `json:"property_name"`
+	OfflineCount int       `json:"offline_count"`
+	TotalCount   int       `json:"total_count"`
+	Since        time.Time `json:"since"`
+}
+
+// ConfigBackup is a stored copy of a property's pfSense config.xml
+type ConfigBackup struct {
+	ID          int64     `json:"id"`
+	PropertyID  int64     `json:"property_id"`
+	StoragePath string    `json:"storage_path"`
+	FileSize    int64     `json:"file_size"`
+	SHA256      string    `json:"sha256"`
+	Trigger     string    `json:"trigger"` // scheduled or manual
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Firewall is an additional pfSense node at a property, such as the members of a CARP
+// HA pair. The property's own pfSense settings remain the address used for syncing.
+type Firewall struct {
+	ID                int64     `json:"id"`
+	PropertyID        int64     `json:"property_id"`
+	Name              string    `json:"name"`
+	Host              string    `json:"host"`
+	Port              int       `json:"port"`
+	Username          string    `json:"username"`
+	Password          string    `json:"password,omitempty"`  // write-only; never returned
+	ExpectedCARPState string    `json:"expected_carp_state"` // master, backup or empty for any consistent state
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TrafficSample is a snapshot of an interface's cumulative counters on a property's pfSense
+type TrafficSample struct {
+	PropertyID  int64     `json:"property_id"`
+	Interface   string    `json:"interface"`
+	CollectedAt time.Time `json:"collected_at"`
+	RxBytes     int64     `json:"rx_bytes"`
+	TxBytes     int64     `json:"tx_bytes"`
+	RxPackets   int64     `json:"rx_packets"`
+	TxPackets   int64     `json:"tx_packets"`
+}
+
+// TrafficPoint is the average throughput of an interface between two samples, in bits
+// and packets per second
+type TrafficPoint struct {
+	Time  time.Time `json:"time"`
+	RxBps float64   `json:"rx_bps"`
+	TxBps float64   `json:"tx_bps"`
+	RxPps float64   `json:"rx_pps"`
+	TxPps float64   `json:"tx_pps"`
+}
+
+// InterfaceTraffic is the throughput series for one interface
+type InterfaceTraffic struct {
+	Interface string         `json:"interface"`
+	Points    []TrafficPoint `json:"points"`
+}
+
+// DHCPMappingChange is the audit record of a static mapping pushed to a property's pfSense.
+// Failed pushes are recorded too, with Error set.
+type DHCPMappingChange struct {
+	ID               int64     `json:"id"`
+	PropertyID       int64     `json:"property_id"`
+	DeviceID         *int64    `json:"device_id"`
+	Action           string    `json:"action"` // set or delete
+	Interface        string    `json:"interface"`
+	MACAddress       string    `json:"mac_address"`
+	IPAddress        string    `json:"ip_address"`
+	Hostname         string    `json:"hostname"`
+	PreviousIP       string    `json:"previous_ip_address"`
+	PreviousHostname string    `json:"previous_hostname"`
+	Success          bool      `json:"success"`
+	Error            string    `json:"error,omitempty"`
+	CreatedBy        string    `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UniFiController is the UniFi Network controller that manages a property's access points
+type UniFiController struct {
+	ID          int64     `json:"id"`
+	PropertyID  int64     `json:"property_id"`
+	URL         string    `json:"url"` // e.g. https://unifi.example.com:8443 or a UniFi OS console
+	Username    string    `json:"username"`
+	Password    string    `json:"password,omitempty"` // write-only; never returned
+	PasswordSet bool      `json:"password_set"`
+	Site        string    `json:"site"` // controller site name, "default" unless renamed
+	VerifyTLS   bool      `json:"verify_tls"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AccessPoint is the state of a UniFi access point as reported by its controller
+type AccessPoint struct {
+	MAC        string             `json:"mac"`
+	Name       string             `json:"name"`
+	Model      string             `json:"model"`
+	IPAddr     string             `json:"ip_addr"`
+	State      string             `json:"state"` // connected, disconnected, upgrading, ...
+	Clients    int                `json:"clients"`
+	Uptime     int64              `json:"uptime"` // seconds
+	Version    string             `json:"version"`
+	Radios     []AccessPointRadio `json:"radios"`
+	DeviceID   *int64             `json:"device_id"` // registered device with the same MAC
+	DeviceName string             `json:"device_name,omitempty"`
+}
+
+// AccessPointRadio is one radio of an access point
+type AccessPointRadio struct {
+	Band         string `json:"band"` // 2.4GHz, 5GHz or 6GHz
+	Channel      int    `json:"channel"`
+	Utilization  int    `json:"utilization"` // channel utilization percent
+	Clients      int    `json:"clients"`
+	Satisfaction int    `json:"satisfaction"` // controller's experience score, -1 when unknown
+}
+
+// WiFiSnapshot is the latest poll of a property's UniFi controller
+type WiFiSnapshot struct {
+	PropertyID   int64         `json:"property_id"`
+	CollectedAt  time.Time     `json:"collected_at"`
+	Error        string        `json:"error,omitempty"`
+	AccessPoints []AccessPoint `json:"access_points"`
+}
+
+// Probe is a remote agent running inside a property's network that checks the devices
+// assigned to it and reports the results to the API
+type Probe struct {
+	ID         int64      `json:"id"`
+	PropertyID int64      `json:"property_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"` // only returned when the probe is created
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Webhook posts signed domain events, such as a property being created or an
+// incident opening, to an external system's URL
+type Webhook struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // write-only; only returned when the webhook is created
+	SecretSet bool      `json:"secret_set"`
+	Events    []string  `json:"events"` // event types to send; empty sends all of them
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery records one delivery of an event to a webhook, including its retries.
+// A replay is a new delivery of the same payload, with ReplayOf set.
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  int64     `json:"webhook_id"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Payload    string    `json:"payload"`
+	Success    bool      `json:"success"`
+	StatusCode int       `json:"status_code"` // 0 when no response was received
+	Error      string    `json:"error,omitempty"`
+	Attempts   int       `json:"attempts"`
+	DurationMs int64     `json:"duration_ms"`
+	ReplayOf   *int64    `json:"replay_of"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProbeResult is a check result reported by a remote probe, queued for the worker
+type ProbeResult struct {
+	ProbeID int64        `json:"probe_id"`
+	Status  DeviceStatus `json:"status"`
+}
+
+// QueuedCheck is a device check waiting on the worker check queue
+type QueuedCheck struct {
+	ID       string    `json:"-"`
+	Device   Device    `json:"device"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// WorkerHeartbeat is written by each worker's scheduler every few seconds, so a
+// stalled or crashed worker stops updating it
+type WorkerHeartbeat struct {
+	WorkerID  string    `json:"worker_id"`
+	Leader    bool      `json:"leader"`
+	Shards    int       `json:"shards"`
+	Devices   int       `json:"devices"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Stale     bool      `json:"stale"`
+}
+
+// WorkerHealth summarizes the worker heartbeats for /health and the dashboard
+type WorkerHealth struct {
+	Status        string            `json:"status"` // ok, or stale when no worker has sent a recent heartbeat
+	LastHeartbeat *time.Time        `json:"last_heartbeat,omitempty"`
+	StaleAfter    int               `json:"stale_after"` // seconds
+	Workers       []WorkerHeartbeat `json:"workers"`
+}
+
+// ConfigDocument is a full configuration export. IDs are those of the exporting
+// system and only link entries within the document; an import assigns new ones.
+type ConfigDocument struct {
+	Version              int                   `json:"version"`
+	ExportedAt           time.Time             `json:"exported_at"`
+	Settings             *Settings             `json:"settings,omitempty"`
+	NotificationChannels []NotificationChannel `json:"notification_channels"`
+	NotificationRules    []NotificationRule    `json:"notification_rules"`
+	Properties           []ConfigProperty      `json:"properties"`
+}
+
+// ConfigProperty is a property in a configuration export with what belongs to it
+type ConfigProperty struct {
+	Property
+	Devices       []Device               `json:"devices"`
+	Contacts      []Contact              `json:"contacts"`
+	Circuits      []Circuit              `json:"circuits"`
+	Notifications []PropertyNotification `json:"notifications"`
+}
+
+// ConfigImportResult reports a configuration import or its dry run. Counts are keyed
+// by section, e.g. properties or devices. Conflicts lists the entries that already
+// existed, matched by name (devices by hostname, then name).
+type ConfigImportResult struct {
+	DryRun    bool           `json:"dry_run"`
+	Applied   bool           `json:"applied"`
+	Strategy  string         `json:"strategy"`
+	Error     string         `json:"error,omitempty"`
+	Created   map[string]int `json:"created"`
+	Updated   map[string]int `json:"updated"`
+	Skipped   map[string]int `json:"skipped"`
+	Conflicts []string       `json:"conflicts"`
+}
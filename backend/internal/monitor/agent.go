@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const (
+	agentRefreshInterval = time.Minute
+	agentFlushInterval   = 5 * time.Second
+	// Results held while the API is unreachable; the oldest are dropped beyond this
+	agentMaxPending = 10000
+)
+
+// ProbeAgent runs on-site for a property that the worker can't reach. It fetches the
+// devices assigned to its probe from the API, checks them on their own intervals with
+// the worker's checks and reports the results back in batches.
+type ProbeAgent struct {
+	baseURL  string
+	token    string
+	http     *http.Client
+	schedule *schedule
+	sem      chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	pending []models.DeviceStatus
+}
+
+func NewProbeAgent(baseURL, token string, maxConcurrent int) *ProbeAgent {
+	return &ProbeAgent{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		token:    token,
+		http:     &http.Client{Timeout: 30 * time.Second},
+		schedule: newSchedule(),
+		sem:      make(chan struct{}, maxConcurrent),
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (a *ProbeAgent) Start(ctx context.Context) error {
+	slog.Info("Probe agent started", "noc_url", a.baseURL)
+
+	if err := a.refreshDevices(ctx); err != nil {
+		slog.Error("Failed to load devices", "error", err)
+	}
+
+	dispatchTicker := time.NewTicker(time.Second)
+	defer dispatchTicker.Stop()
+	refreshTicker := time.NewTicker(agentRefreshInterval)
+	defer refreshTicker.Stop()
+	flushTicker := time.NewTicker(agentFlushInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.wg.Wait()
+			return ctx.Err()
+		case <-a.stopChan:
+			a.wg.Wait()
+			a.flush(context.Background())
+			return nil
+		case <-refreshTicker.C:
+			if err := a.refreshDevices(ctx); err != nil {
+				slog.Error("Failed to load devices", "error", err)
+			}
+		case <-dispatchTicker.C:
+			a.dispatchDue(ctx)
+		case <-flushTicker.C:
+			a.flush(ctx)
+		}
+	}
+}
+
+func (a *ProbeAgent) Stop() {
+	close(a.stopChan)
+}
+
+func (a *ProbeAgent) refreshDevices(ctx context.Context) error {
+	var devices []models.Device
+	if err := a.do(ctx, http.MethodGet, "/api/v1/probe/devices", nil, &devices); err != nil {
+		return err
+	}
+	a.schedule.sync(devices, time.Now())
+	slog.Info("Scheduled devices", "devices", a.schedule.size())
+	return nil
+}
+
+func (a *ProbeAgent) dispatchDue(ctx context.Context) {
+	for _, device := range a.schedule.due(time.Now()) {
+		a.wg.Add(1)
+		go func(d models.Device) {
+			defer a.wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case a.sem <- struct{}{}:
+			}
+			defer func() { <-a.sem }()
+
+			status := CheckDevice(ctx, &d)
+			a.schedule.complete(d.ID, time.Now())
+
+			a.mu.Lock()
+			a.pending = append(a.pending, *status)
+			if len(a.pending) > agentMaxPending {
+				a.pending = a.pending[len(a.pending)-agentMaxPending:]
+			}
+			a.mu.Unlock()
+		}(device)
+	}
+}
+
+// flush reports pending results; they are kept for the next flush if the API is down
+func (a *ProbeAgent) flush(ctx context.Context) {
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := a.do(ctx, http.MethodPost, "/api/v1/probe/results", batch, nil); err != nil {
+		slog.Error("Failed to report results", "results", len(batch), "error", err)
+		a.mu.Lock()
+		a.pending = append(batch, a.pending...)
+		if len(a.pending) > agentMaxPending {
+			a.pending = a.pending[len(a.pending)-agentMaxPending:]
+		}
+		a.mu.Unlock()
+	}
+}
+
+// do sends an authenticated request to the API and decodes the JSON response into out
+func (a *ProbeAgent) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
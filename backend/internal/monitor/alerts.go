@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"context"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// alertSuppression holds the acknowledgements and silences in effect for one
+// detector pass
+type alertSuppression struct {
+	acks     []models.Acknowledgement
+	silences []models.Silence
+}
+
+func (a *alertSuppression) propertyAcknowledged(propertyID int64) bool {
+	for _, ack := range a.acks {
+		if ack.EntityType == "property" && ack.PropertyID == propertyID {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *alertSuppression) propertySilenced(propertyID int64) bool {
+	for _, s := range a.silences {
+		if s.ScopeType == "property" && s.PropertyID != nil && *s.PropertyID == propertyID {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceSuppressed reports whether a device is acknowledged or covered by a silence
+func (a *alertSuppression) deviceSuppressed(d *models.Device) bool {
+	for _, ack := range a.acks {
+		if ack.EntityType == "device" && ack.DeviceID != nil && *ack.DeviceID == d.ID {
+			return true
+		}
+	}
+	for i := range a.silences {
+		if silenceMatches(&a.silences[i], d) {
+			return true
+		}
+	}
+	return false
+}
+
+// silenceMatches reports whether a silence covers a device
+func silenceMatches(s *models.Silence, d *models.Device) bool {
+	switch s.ScopeType {
+	case "device":
+		return s.DeviceID != nil && *s.DeviceID == d.ID
+	case "property":
+		return s.PropertyID != nil && *s.PropertyID == d.PropertyID
+	case "tag":
+		for _, tag := range d.Tags {
+			if tag == s.Tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// downDevicesSuppressed reports whether every down device at a property is
+// acknowledged or silenced, so the property alert carries no new information
+func (td *TransitionDetector) downDevicesSuppressed(ctx context.Context, propertyID int64, suppression *alertSuppression) (bool, error) {
+	devices, err := td.postgres.ListDevicesForProperty(ctx, propertyID)
+	if err != nil {
+		return false, err
+	}
+
+	down := 0
+	for i := range devices {
+		d := &devices[i]
+		if !d.Active {
+			continue
+		}
+		status, err := td.redis.GetDeviceStatus(ctx, d.ID)
+		if err != nil || deviceUp(status) {
+			continue
+		}
+		down++
+		if !suppression.deviceSuppressed(d) {
+			return false, nil
+		}
+	}
+	return down > 0, nil
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+)
+
+// carpCheckDevice checks the CARP state of the property firewall whose host matches the
+// device hostname. The device is offline when the node isn't in its expected state
+// (for example a primary that failed over to BACKUP) or its VIPs disagree.
+func (p *Pinger) carpCheckDevice(ctx context.Context, device *models.Device) *models.DeviceStatus {
+	status := &models.DeviceStatus{
+		DeviceID:  device.ID,
+		LastCheck: time.Now(),
+		Status:    "offline",
+	}
+
+	firewalls, err := p.postgres.ListFirewallsForProperty(ctx, device.PropertyID)
+	if err != nil {
+		status.Message = fmt.Sprintf("Failed to load firewalls: %v", err)
+		return status
+	}
+
+	var firewall *models.Firewall
+	for i := range firewalls {
+		if strings.EqualFold(firewalls[i].Host, device.Hostname) {
+			firewall = &firewalls[i]
+			break
+		}
+	}
+	if firewall == nil {
+		status.Message = fmt.Sprintf("No firewall with host %s at this property", device.Hostname)
+		return status
+	}
+
+	start := time.Now()
+	client := pfsense.NewClient(firewall.Host, firewall.Port, firewall.Username, firewall.Password)
+	vips, err := client.GetCARPStatus(ctx)
+	if err != nil {
+		status.Message = fmt.Sprintf("Failed to read CARP status from %s: %v", firewall.Name, err)
+		return status
+	}
+
+	ok, detail := pfsense.EvaluateCARP(vips, firewall.ExpectedCARPState)
+	status.Message = detail
+	if ok {
+		status.Status = "online"
+		status.ResponseTime = float64(time.Since(start).Milliseconds())
+	}
+	return status
+}
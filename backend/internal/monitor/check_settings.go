@@ -0,0 +1,37 @@
+package monitor
+
+import "github.com/etswifi/ets-noc/internal/models"
+
+// Built-in check settings, used when neither the device, its property nor the global
+// settings set one
+const (
+	builtinCheckInterval = 60    // seconds
+	builtinRetries       = 3     // attempts
+	builtinTimeout       = 10000 // milliseconds
+)
+
+// ResolveCheckSettings fills in the check interval, retries and timeout a device leaves
+// at 0 (inherit) from its property's overrides, then from the global defaults. property
+// and settings may be nil.
+func ResolveCheckSettings(d *models.Device, property *models.Property, settings *models.Settings) {
+	var p models.Property
+	if property != nil {
+		p = *property
+	}
+	var s models.Settings
+	if settings != nil {
+		s = *settings
+	}
+	d.CheckInterval = firstPositive(d.CheckInterval, p.CheckInterval, s.DefaultCheckInterval, builtinCheckInterval)
+	d.Retries = firstPositive(d.Retries, p.Retries, s.DefaultRetries, builtinRetries)
+	d.Timeout = firstPositive(d.Timeout, p.Timeout, s.DefaultTimeout, builtinTimeout)
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
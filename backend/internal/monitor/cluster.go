@@ -0,0 +1,312 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	clusterInterval = 5 * time.Second
+	// A worker that stops renewing for this long is considered gone and its shards
+	// and leadership are taken over
+	clusterLeaseTTL = 30 * time.Second
+	leaderLease     = "leader"
+)
+
+// Cluster partitions properties between worker replicas. Properties are hashed into
+// storage.WorkerShards shards and each live worker holds a Redis lease on an even
+// share of them, so every device is checked by exactly one worker and a crashed
+// worker's shards are picked up once its leases expire. One worker also holds the
+// leader lease and runs the fleet-wide jobs.
+//
+// A nil *Cluster owns every shard and is always leader, for a single worker.
+type Cluster struct {
+	redis    storage.StatusStore
+	workerID string
+	stopChan chan struct{}
+	// runMu keeps a rebalance from racing the release of leases on Stop
+	runMu sync.Mutex
+
+	mu sync.Mutex
+	// owned maps each held shard to when its lease runs out if not renewed
+	owned       map[int]time.Time
+	leaderUntil time.Time
+	version     uint64
+}
+
+func NewCluster(redis storage.StatusStore, workerID string) *Cluster {
+	return &Cluster{
+		redis:    redis,
+		workerID: workerID,
+		stopChan: make(chan struct{}),
+		owned:    make(map[int]time.Time),
+	}
+}
+
+func (c *Cluster) Start(ctx context.Context) error {
+	slog.Info("Joining cluster", "worker_id", c.workerID)
+
+	c.rebalance(ctx)
+
+	ticker := time.NewTicker(clusterInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.stopChan:
+			return nil
+		case <-ticker.C:
+			c.rebalance(ctx)
+		}
+	}
+}
+
+// Stop releases this worker's leases so the others take over without waiting for
+// them to expire
+func (c *Cluster) Stop() {
+	close(c.stopChan)
+
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+	c.leave(context.Background())
+	slog.Info("Cluster membership stopped", "worker_id", c.workerID)
+}
+
+// OwnsProperty reports whether this worker checks the property's devices
+func (c *Cluster) OwnsProperty(propertyID int64) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.owned[storage.ShardForProperty(propertyID)])
+}
+
+// Shards returns the shards this worker currently holds
+func (c *Cluster) Shards() []int {
+	var shards []int
+	if c == nil {
+		for shard := 0; shard < storage.WorkerShards; shard++ {
+			shards = append(shards, shard)
+		}
+		return shards
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	for shard, until := range c.owned {
+		if now.Before(until) {
+			shards = append(shards, shard)
+		}
+	}
+	c.mu.Unlock()
+	sort.Ints(shards)
+	return shards
+}
+
+// IsLeader reports whether this worker should run the fleet-wide jobs
+func (c *Cluster) IsLeader() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.leaderUntil)
+}
+
+// Version changes whenever the set of held shards does, so the pinger knows to
+// reload its devices
+func (c *Cluster) Version() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// RunAsLeader runs fn whenever this worker holds leadership. fn's context is cancelled
+// when leadership is lost or the cluster stops, and fn must return once it is; it is
+// started again if leadership comes back.
+func (c *Cluster) RunAsLeader(ctx context.Context, fn func(ctx context.Context)) {
+	if c == nil {
+		fn(ctx)
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		if c.IsLeader() {
+			slog.Info("Elected leader, starting fleet-wide jobs", "worker_id", c.workerID)
+			leaderCtx, cancel := context.WithCancel(ctx)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				fn(leaderCtx)
+			}()
+
+			for c.IsLeader() && ctx.Err() == nil && !c.stopped() {
+				<-ticker.C
+			}
+			cancel()
+			<-done
+			slog.Info("Stopped fleet-wide jobs", "worker_id", c.workerID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cluster) stopped() bool {
+	select {
+	case <-c.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// rebalance renews held leases, then gives up or claims shards until this worker holds
+// its share of them
+func (c *Cluster) rebalance(ctx context.Context) {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+	if c.stopped() {
+		return
+	}
+
+	members, err := c.redis.HeartbeatWorker(ctx, c.workerID, clusterLeaseTTL)
+	if err != nil {
+		slog.Error("Failed to send worker heartbeat", "error", err)
+		return
+	}
+	target := (storage.WorkerShards + len(members) - 1) / len(members)
+
+	// Leases are good until TTL after the request that took them was sent
+	until := time.Now().Add(clusterLeaseTTL)
+
+	c.mu.Lock()
+	held := make([]int, 0, len(c.owned))
+	for shard := range c.owned {
+		held = append(held, shard)
+	}
+	c.mu.Unlock()
+	sort.Ints(held)
+
+	owned := make(map[int]time.Time, target)
+	for _, shard := range held {
+		if len(owned) >= target {
+			if err := c.redis.ReleaseLease(ctx, shardLease(shard), c.workerID); err != nil {
+				slog.Error("Failed to release shard", "shard", shard, "error", err)
+			}
+			continue
+		}
+		ok, err := c.redis.AcquireLease(ctx, shardLease(shard), c.workerID, clusterLeaseTTL)
+		if err != nil {
+			slog.Error("Failed to renew shard", "shard", shard, "error", err)
+			c.mu.Lock()
+			if expiry, held := c.owned[shard]; held {
+				owned[shard] = expiry
+			}
+			c.mu.Unlock()
+			continue
+		}
+		if ok {
+			owned[shard] = until
+		}
+	}
+
+	// Start from a worker-specific shard so joining workers don't all contend for
+	// the same free shards
+	start := int(workerHash(c.workerID) % storage.WorkerShards)
+	for i := 0; i < storage.WorkerShards && len(owned) < target; i++ {
+		shard := (start + i) % storage.WorkerShards
+		if _, ok := owned[shard]; ok {
+			continue
+		}
+		ok, err := c.redis.AcquireLease(ctx, shardLease(shard), c.workerID, clusterLeaseTTL)
+		if err != nil {
+			slog.Error("Failed to claim shard", "shard", shard, "error", err)
+			break
+		}
+		if ok {
+			owned[shard] = until
+		}
+	}
+
+	leader, err := c.redis.AcquireLease(ctx, leaderLease, c.workerID, clusterLeaseTTL)
+	if err != nil {
+		slog.Error("Failed to renew leadership", "error", err)
+	}
+
+	c.mu.Lock()
+	changed := len(owned) != len(c.owned)
+	for shard := range owned {
+		if _, ok := c.owned[shard]; !ok {
+			changed = true
+		}
+	}
+	c.owned = owned
+	if leader {
+		c.leaderUntil = until
+	} else if err == nil {
+		c.leaderUntil = time.Time{}
+	}
+	if changed {
+		c.version++
+	}
+	c.mu.Unlock()
+
+	if changed {
+		slog.Info("Shards rebalanced", "worker_id", c.workerID, "shards", len(owned), "total_shards", storage.WorkerShards, "workers", len(members))
+	}
+}
+
+// leave releases every lease held by this worker and removes it from the live set
+func (c *Cluster) leave(ctx context.Context) {
+	c.mu.Lock()
+	owned := c.owned
+	c.owned = make(map[int]time.Time)
+	c.leaderUntil = time.Time{}
+	c.version++
+	c.mu.Unlock()
+
+	for shard := range owned {
+		if err := c.redis.ReleaseLease(ctx, shardLease(shard), c.workerID); err != nil {
+			slog.Error("Failed to release shard", "shard", shard, "error", err)
+		}
+	}
+	if err := c.redis.ReleaseLease(ctx, leaderLease, c.workerID); err != nil {
+		slog.Error("Failed to release leadership", "error", err)
+	}
+	if err := c.redis.RemoveWorker(ctx, c.workerID); err != nil {
+		slog.Error("Failed to leave cluster", "error", err)
+	}
+}
+
+func shardLease(shard int) string {
+	return fmt.Sprintf("shard:%d", shard)
+}
+
+func workerHash(workerID string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(workerID))
+	return h.Sum32()
+}
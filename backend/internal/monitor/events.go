@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// deviceState is the status recorded in status_events. Soft failures are not
+// confirmed yet, so the device is still considered online.
+func deviceState(status *models.DeviceStatus) string {
+	if deviceUp(status) {
+		return StatusOnline
+	}
+	return status.Status
+}
+
+// previousDuration returns the whole seconds spent in a state, or nil if its start is unknown
+func previousDuration(since, now time.Time) *int64 {
+	if since.IsZero() {
+		return nil
+	}
+	seconds := int64(now.Sub(since) / time.Second)
+	return &seconds
+}
+
+// recordDeviceTransition carries the state start time forward onto the new status and
+// writes a status event when the device's confirmed state changed. The first status
+// seen for a device starts its state clock without an event.
+func (p *Pinger) recordDeviceTransition(ctx context.Context, d *models.Device, previous, current *models.DeviceStatus) {
+	if previous == nil {
+		current.Since = current.LastCheck
+		return
+	}
+
+	from, to := deviceState(previous), deviceState(current)
+	if from == to && !previous.Since.IsZero() {
+		current.Since = previous.Since
+		return
+	}
+	current.Since = current.LastCheck
+	if from == to {
+		return
+	}
+
+	if to == StatusOnline {
+		if err := p.postgres.ClearDeviceAcknowledgement(ctx, d.ID); err != nil {
+			slog.Error("Failed to clear acknowledgement", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+		}
+	}
+
+	deviceID := d.ID
+	event := &models.StatusEvent{
+		EntityType:       "device",
+		PropertyID:       d.PropertyID,
+		DeviceID:         &deviceID,
+		FromStatus:       from,
+		ToStatus:         to,
+		OccurredAt:       current.LastCheck,
+		PreviousDuration: previousDuration(previous.Since, current.LastCheck),
+		Message:          current.Message,
+	}
+	if err := p.postgres.CreateStatusEvent(ctx, event); err != nil {
+		slog.Error("Failed to record status event", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+	}
+}
+
+// recordPropertyTransition is the property rollup equivalent of recordDeviceTransition
+func (p *Pinger) recordPropertyTransition(ctx context.Context, previous, current *models.PropertyStatus) {
+	if previous == nil {
+		current.Since = current.LastCheck
+		return
+	}
+
+	if previous.Status == current.Status && !previous.Since.IsZero() {
+		current.Since = previous.Since
+		return
+	}
+	current.Since = current.LastCheck
+	if previous.Status == current.Status {
+		return
+	}
+
+	// Acknowledgements last until the property is fully healthy again, so a property
+	// bouncing between red and yellow doesn't re-alert
+	if current.Status == "green" {
+		if err := p.postgres.ClearPropertyAcknowledgement(ctx, current.PropertyID); err != nil {
+			slog.Error("Failed to clear acknowledgement", "property_id", current.PropertyID, "error", err)
+		}
+	}
+
+	event := &models.StatusEvent{
+		EntityType:       "property",
+		PropertyID:       current.PropertyID,
+		FromStatus:       previous.Status,
+		ToStatus:         current.Status,
+		OccurredAt:       current.LastCheck,
+		PreviousDuration: previousDuration(previous.Since, current.LastCheck),
+	}
+	if err := p.postgres.CreateStatusEvent(ctx, event); err != nil {
+		slog.Error("Failed to record status event", "property_id", current.PropertyID, "error", err)
+	}
+}
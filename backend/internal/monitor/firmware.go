@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	// Firmware changes only on upgrades, so hourly is plenty
+	firmwareCollectInterval = time.Hour
+	firmwareConcurrency     = 8
+)
+
+// pfSense firmware is recorded under this manufacturer and model when the device
+// doesn't have its own
+const (
+	pfSenseManufacturer = "Netgate"
+	pfSenseModel        = "pfSense"
+)
+
+// FirmwareCollector reads the running version from every property's pfSense and HA
+// firewall nodes and records it on the devices monitoring them. UniFi access point
+// firmware is recorded by the WiFi poller.
+type FirmwareCollector struct {
+	postgres storage.Store
+	stopChan chan struct{}
+}
+
+func NewFirmwareCollector(postgres storage.Store) *FirmwareCollector {
+	return &FirmwareCollector{
+		postgres: postgres,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (f *FirmwareCollector) Start(ctx context.Context) error {
+	slog.Info("Firmware collector started")
+
+	ticker := time.NewTicker(firmwareCollectInterval)
+	defer ticker.Stop()
+
+	f.collect(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.stopChan:
+			slog.Info("Firmware collector stopped")
+			return nil
+		case <-ticker.C:
+			f.collect(ctx)
+		}
+	}
+}
+
+func (f *FirmwareCollector) Stop() {
+	close(f.stopChan)
+}
+
+func (f *FirmwareCollector) collect(ctx context.Context) {
+	properties, err := f.postgres.ListProperties(ctx)
+	if err != nil {
+		slog.Error("Failed to list properties for firmware collection", "error", err)
+		return
+	}
+
+	sem := make(chan struct{}, firmwareConcurrency)
+	var wg sync.WaitGroup
+	for i := range properties {
+		property := &properties[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := f.collectProperty(ctx, property); err != nil {
+				slog.Error("Failed to collect firmware", "property_id", property.ID, "property", property.Name, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// collectProperty reads the version of the property's pfSense and each HA node whose
+// host a device at the property monitors. Properties without either are skipped.
+func (f *FirmwareCollector) collectProperty(ctx context.Context, property *models.Property) error {
+	firewalls, err := f.postgres.ListFirewallsForProperty(ctx, property.ID)
+	if err != nil {
+		return err
+	}
+	if property.PfSenseHost != "" && property.PfSenseUsername != "" && property.PfSensePassword != "" {
+		firewalls = append(firewalls, models.Firewall{
+			Name:     property.PfSenseHost,
+			Host:     property.PfSenseHost,
+			Port:     property.PfSensePort,
+			Username: property.PfSenseUsername,
+			Password: property.PfSensePassword,
+		})
+	}
+	if len(firewalls) == 0 {
+		return nil
+	}
+
+	devices, err := f.postgres.ListDevicesForProperty(ctx, property.ID)
+	if err != nil {
+		return err
+	}
+
+	polled := make(map[string]bool)
+	for _, firewall := range firewalls {
+		host := strings.ToLower(firewall.Host)
+		if polled[host] {
+			continue
+		}
+		polled[host] = true
+
+		var matched []*models.Device
+		for i := range devices {
+			if strings.EqualFold(devices[i].Hostname, firewall.Host) {
+				matched = append(matched, &devices[i])
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		client := pfsense.NewClient(firewall.Host, firewall.Port, firewall.Username, firewall.Password)
+		version, err := client.GetVersion(ctx)
+		if err != nil {
+			slog.Error("Failed to read pfSense version", "property_id", property.ID, "firewall", firewall.Name, "error", err)
+			continue
+		}
+		for _, device := range matched {
+			recordFirmware(ctx, f.postgres, device, pfSenseManufacturer, pfSenseModel, version)
+		}
+	}
+	return nil
+}
+
+// recordFirmware stores the firmware version an integration reported for a device when
+// it differs from the one on record
+func recordFirmware(ctx context.Context, store storage.DeviceStore, device *models.Device, manufacturer, model, version string) {
+	if version == "" || (version == device.FirmwareVersion &&
+		(device.Manufacturer != "" || manufacturer == "") && (device.Model != "" || model == "")) {
+		return
+	}
+	if err := store.SetDeviceFirmware(ctx, device.ID, manufacturer, model, version); err != nil {
+		slog.Error("Failed to record firmware version", "device_id", device.ID, "error", err)
+	}
+}
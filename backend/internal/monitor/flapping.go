@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// A device is flapping once it changes confirmed state flapStartChanges times within
+// flapWindow, and stops flapping when the window holds flapStopChanges or fewer
+const (
+	flapWindow       = 30 * time.Minute
+	flapStartChanges = 6
+	flapStopChanges  = 2
+)
+
+// applyFlapping tracks confirmed state changes and sets the flapping flag on the new
+// status. A device that starts flapping sends one notification; its alerts are then
+// held back until it has been stable long enough to drop below the stop threshold.
+func (p *Pinger) applyFlapping(ctx context.Context, d *models.Device, previous, current *models.DeviceStatus) {
+	if previous == nil {
+		return
+	}
+	wasFlapping := previous.Flapping
+	current.Flapping = wasFlapping
+
+	var changes int64
+	var err error
+	if deviceState(previous) != deviceState(current) {
+		changes, err = p.redis.RecordDeviceStateChange(ctx, d.ID, current.LastCheck, flapWindow)
+	} else if wasFlapping {
+		changes, err = p.redis.CountDeviceStateChanges(ctx, d.ID, current.LastCheck.Add(-flapWindow))
+	} else {
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to track state changes", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+		return
+	}
+
+	switch {
+	case !wasFlapping && changes >= flapStartChanges:
+		current.Flapping = true
+		slog.Warn("Device is flapping", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "state_changes", changes, "window", flapWindow.String())
+		p.detector.ProcessFlapping(ctx, d, current, int(changes), flapWindow)
+	case wasFlapping && changes <= flapStopChanges:
+		current.Flapping = false
+		slog.Info("Device has stopped flapping", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name)
+	}
+}
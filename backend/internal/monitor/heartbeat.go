@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	heartbeatInterval = 10 * time.Second
+	// DefaultWorkerStaleAfter is how long the fleet may go without a heartbeat before
+	// the workers are reported down
+	DefaultWorkerStaleAfter = 2 * time.Minute
+	// Heartbeats older than this are dropped, so workers that were scaled away vanish
+	workerHeartbeatRetention = 24 * time.Hour
+	watchdogInterval         = 30 * time.Second
+)
+
+// sendHeartbeat records that this worker's scheduler loop is running. It is sent from
+// the loop itself, so a hung scheduler stops it as surely as a crash.
+func (p *Pinger) sendHeartbeat(ctx context.Context) {
+	hb := &models.WorkerHeartbeat{
+		WorkerID:  p.consumer,
+		Leader:    p.cluster.IsLeader(),
+		Shards:    len(p.cluster.Shards()),
+		Devices:   p.schedule.size(),
+		StartedAt: p.startedAt,
+		LastSeen:  time.Now(),
+	}
+	if err := p.redis.SetWorkerHeartbeat(ctx, hb); err != nil {
+		slog.Error("Failed to send worker heartbeat", "error", err)
+	}
+}
+
+// CheckWorkerHealth summarizes the worker heartbeats. The fleet is ok while at least
+// one worker has sent a heartbeat within staleAfter.
+func CheckWorkerHealth(ctx context.Context, redis storage.StatusStore, staleAfter time.Duration) (*models.WorkerHealth, error) {
+	heartbeats, err := redis.GetWorkerHeartbeats(ctx, workerHeartbeatRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &models.WorkerHealth{
+		Status:     "stale",
+		StaleAfter: int(staleAfter / time.Second),
+		Workers:    make([]models.WorkerHeartbeat, 0, len(heartbeats)),
+	}
+	now := time.Now()
+	for _, hb := range heartbeats {
+		hb.Stale = now.Sub(hb.LastSeen) > staleAfter
+		if !hb.Stale {
+			health.Status = "ok"
+		}
+		if health.LastHeartbeat == nil || hb.LastSeen.After(*health.LastHeartbeat) {
+			lastSeen := hb.LastSeen
+			health.LastHeartbeat = &lastSeen
+		}
+		health.Workers = append(health.Workers, hb)
+	}
+	return health, nil
+}
+
+// WorkerWatchdog runs in the API, independently of the workers, and alerts the system
+// alert channels when every worker has stopped sending heartbeats, and again once one
+// is back. The alert state is kept in Redis so only one API replica sends each alert.
+type WorkerWatchdog struct {
+	redis      storage.StatusStore
+	notifier   *notifier.Notifier
+	staleAfter time.Duration
+	stopChan   chan struct{}
+}
+
+func NewWorkerWatchdog(redis storage.StatusStore, notifier *notifier.Notifier, staleAfter time.Duration) *WorkerWatchdog {
+	return &WorkerWatchdog{
+		redis:      redis,
+		notifier:   notifier,
+		staleAfter: staleAfter,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+func (w *WorkerWatchdog) Start(ctx context.Context) error {
+	slog.Info("Worker watchdog started", "stale_after", w.staleAfter.String())
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.stopChan:
+			slog.Info("Worker watchdog stopped")
+			return nil
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+func (w *WorkerWatchdog) Stop() {
+	close(w.stopChan)
+}
+
+func (w *WorkerWatchdog) check(ctx context.Context) {
+	health, err := CheckWorkerHealth(ctx, w.redis, w.staleAfter)
+	if err != nil {
+		slog.Error("Failed to check worker heartbeats", "error", err)
+		return
+	}
+
+	if health.Status == "ok" {
+		recovered, err := w.redis.MarkWorkersUp(ctx)
+		if err != nil {
+			slog.Error("Failed to record worker alert state", "error", err)
+			return
+		}
+		if recovered {
+			detail := fmt.Sprintf("%d worker(s) sending heartbeats", countLive(health))
+			slog.Info("Workers are sending heartbeats again", "workers", countLive(health))
+			if err := w.notifier.NotifySystem(ctx, notifier.EventWorkerRecovery, detail); err != nil {
+				slog.Error("Failed to send worker recovery alert", "error", err)
+			}
+		}
+		return
+	}
+
+	down, err := w.redis.MarkWorkersDown(ctx)
+	if err != nil {
+		slog.Error("Failed to record worker alert state", "error", err)
+		return
+	}
+	if down {
+		detail := "no worker is running"
+		if health.LastHeartbeat != nil {
+			detail = fmt.Sprintf("last heartbeat %s ago", time.Since(*health.LastHeartbeat).Round(time.Second))
+		}
+		slog.Error("Workers have stopped sending heartbeats", "detail", detail)
+		if err := w.notifier.NotifySystem(ctx, notifier.EventWorkerDown, detail); err != nil {
+			slog.Error("Failed to send worker down alert", "error", err)
+		}
+	}
+}
+
+func countLive(health *models.WorkerHealth) int {
+	live := 0
+	for _, hb := range health.Workers {
+		if !hb.Stale {
+			live++
+		}
+	}
+	return live
+}
@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	historyAggregateInterval = 5 * time.Minute
+	// Re-aggregate a little past the current hour so late writes to the previous
+	// hour are still picked up
+	historyAggregateLookback = 2 * time.Hour
+)
+
+// HistoryAggregator periodically rolls raw device history in Postgres up into
+// hourly and daily buckets for long-range queries
+type HistoryAggregator struct {
+	postgres storage.Store
+	stopChan chan struct{}
+}
+
+func NewHistoryAggregator(postgres storage.Store) *HistoryAggregator {
+	return &HistoryAggregator{
+		postgres: postgres,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (h *HistoryAggregator) Start(ctx context.Context) error {
+	slog.Info("History aggregator started")
+
+	// Catch up on the last day in case the worker was down
+	h.aggregate(ctx, time.Now().Add(-24*time.Hour))
+
+	ticker := time.NewTicker(historyAggregateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-h.stopChan:
+			slog.Info("History aggregator stopped")
+			return nil
+		case <-ticker.C:
+			h.aggregate(ctx, time.Now().Add(-historyAggregateLookback))
+		}
+	}
+}
+
+func (h *HistoryAggregator) Stop() {
+	close(h.stopChan)
+}
+
+func (h *HistoryAggregator) aggregate(ctx context.Context, since time.Time) {
+	if err := h.postgres.RollupDeviceHistory(ctx, since); err != nil {
+		slog.Error("Failed to roll up device history", "error", err)
+	}
+}
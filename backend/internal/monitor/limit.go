@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// MaxConcurrentChecks is the highest max_concurrent_pings a worker accepts. The check
+// semaphore is sized to it, and the slots above the configured limit are held by the
+// pinger, so the limit can change without replacing the channel under running checks.
+const MaxConcurrentChecks = 2000
+
+// DefaultMaxConcurrentChecks is the limit used until max_concurrent_pings is set
+const DefaultMaxConcurrentChecks = 150
+
+// reloadSettings picks up a changed max_concurrent_pings from the settings table
+func (p *Pinger) reloadSettings(ctx context.Context) {
+	settings, err := p.postgres.GetSettings(ctx)
+	if err != nil {
+		slog.Error("Failed to reload settings", "error", err)
+		return
+	}
+	if settings.MaxConcurrentPings > 0 {
+		p.setConcurrency(settings.MaxConcurrentPings)
+	}
+}
+
+// setConcurrency changes how many checks may run at once. A lower limit takes effect
+// as running checks finish.
+func (p *Pinger) setConcurrency(n int) {
+	n = clampConcurrency(n)
+
+	p.limitMu.Lock()
+	changed := n != p.maxConcurrent
+	p.maxConcurrent = n
+	p.limitMu.Unlock()
+
+	if changed {
+		slog.Info("Max concurrent pings changed", "max_concurrent_pings", n)
+		select {
+		case p.limitChanged <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// runLimiter takes or gives back held slots until the free slots match the limit
+func (p *Pinger) runLimiter(ctx context.Context) {
+	for {
+		p.limitMu.Lock()
+		want := cap(p.sem) - p.maxConcurrent
+		for p.reserved > want {
+			<-p.sem
+			p.reserved--
+		}
+		short := p.reserved < want
+		p.limitMu.Unlock()
+
+		if !short {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stopChan:
+				return
+			case <-p.limitChanged:
+			}
+			continue
+		}
+
+		// Wait for a running check to finish and keep its slot
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopChan:
+			return
+		case <-p.limitChanged:
+		case p.sem <- struct{}{}:
+			p.limitMu.Lock()
+			p.reserved++
+			p.limitMu.Unlock()
+		}
+	}
+}
+
+func clampConcurrency(n int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > MaxConcurrentChecks {
+		return MaxConcurrentChecks
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// maintenanceSet holds the active and upcoming maintenance windows loaded by the scheduler
+type maintenanceSet []models.MaintenanceWindow
+
+func windowActive(w *models.MaintenanceWindow, now time.Time) bool {
+	return !now.Before(w.StartsAt) && now.Before(w.EndsAt)
+}
+
+// propertyInMaintenance reports whether a property-wide window is active
+func (m maintenanceSet) propertyInMaintenance(propertyID int64, now time.Time) bool {
+	for i := range m {
+		w := &m[i]
+		if w.PropertyID != nil && *w.PropertyID == propertyID && windowActive(w, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceInMaintenance reports whether the device, or its property, is in an active window
+func (m maintenanceSet) deviceInMaintenance(d *models.Device, now time.Time) bool {
+	for i := range m {
+		w := &m[i]
+		if !windowActive(w, now) {
+			continue
+		}
+		if w.DeviceID != nil && *w.DeviceID == d.ID {
+			return true
+		}
+		if w.PropertyID != nil && *w.PropertyID == d.PropertyID {
+			return true
+		}
+	}
+	return false
+}
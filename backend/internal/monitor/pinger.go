@@ -3,52 +3,186 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
-	probing "github.com/prometheus-community/pro-bing"
+	"github.com/etswifi/ets-noc/internal/metrics"
 	"github.com/etswifi/ets-noc/internal/models"
 	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/webhook"
+	probing "github.com/prometheus-community/pro-bing"
 )
 
 type Pinger struct {
-	postgres     *storage.PostgresStore
-	redis        *storage.RedisStore
+	postgres storage.Store
+	redis    storage.StatusStore
+	detector *TransitionDetector
+	webhooks EventPublisher
+	cluster  *Cluster
+	consumer string
+	schedule *schedule
+	now      func() time.Time
+	sem      chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+
+	// limitMu guards maxConcurrent and reserved, the semaphore slots held back to
+	// enforce it
+	limitMu       sync.Mutex
 	maxConcurrent int
-	stopChan     chan struct{}
-	wg           sync.WaitGroup
+	reserved      int
+	limitChanged  chan struct{}
+
+	mu                sync.Mutex
+	devicesByProperty map[int64][]models.Device
+	dirtyProperties   map[int64]bool
+	maintenance       maintenanceSet
+	vpnStatus         *vpnStatusCache
+	// localDevices are scheduled here; probedDevices are checked by remote probes
+	localDevices  map[int64]models.Device
+	probedDevices map[int64]models.Device
+	startedAt     time.Time
+	// clusterVersion is the shard assignment the device roster was loaded for
+	clusterVersion uint64
+	// queueFull is set while the check queue is at its limit
+	queueFull bool
+}
+
+// PingerOption configures a Pinger
+type PingerOption func(*Pinger)
+
+// WithMaxConcurrent caps the checks run at once, clamped to 1..MaxConcurrentChecks.
+// Without it the pinger starts at DefaultMaxConcurrentChecks until settings say otherwise.
+func WithMaxConcurrent(n int) PingerOption {
+	return func(p *Pinger) {
+		p.maxConcurrent = n
+	}
+}
+
+// WithCluster limits the pinger to the properties the cluster assigns to this worker.
+// Without it every property is checked here.
+func WithCluster(cluster *Cluster) PingerOption {
+	return func(p *Pinger) {
+		p.cluster = cluster
+	}
+}
+
+// WithSchedulerClock sets the clock the check schedule and property rollups run on
+func WithSchedulerClock(now func() time.Time) PingerOption {
+	return func(p *Pinger) {
+		p.now = now
+	}
 }
 
-func NewPinger(postgres *storage.PostgresStore, redis *storage.RedisStore, maxConcurrent int) *Pinger {
-	return &Pinger{
-		postgres:     postgres,
-		redis:        redis,
-		maxConcurrent: maxConcurrent,
-		stopChan:     make(chan struct{}),
+// NewPinger creates a pinger that raises alerts through notifier and publishes status
+// changes and incidents to webhooks
+func NewPinger(postgres storage.Store, redis storage.StatusStore, notifier Notifier, webhooks EventPublisher, opts ...PingerOption) *Pinger {
+	p := &Pinger{
+		postgres:          postgres,
+		redis:             redis,
+		detector:          NewTransitionDetector(postgres, redis, notifier, webhooks),
+		webhooks:          webhooks,
+		maxConcurrent:     DefaultMaxConcurrentChecks,
+		limitChanged:      make(chan struct{}, 1),
+		schedule:          newSchedule(),
+		now:               time.Now,
+		stopChan:          make(chan struct{}),
+		devicesByProperty: make(map[int64][]models.Device),
+		dirtyProperties:   make(map[int64]bool),
+		vpnStatus:         newVPNStatusCache(),
+		localDevices:      make(map[int64]models.Device),
+		probedDevices:     make(map[int64]models.Device),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.consumer = consumerName(p.cluster)
+	p.maxConcurrent = clampConcurrency(p.maxConcurrent)
+	p.reserved = MaxConcurrentChecks - p.maxConcurrent
+	p.sem = make(chan struct{}, MaxConcurrentChecks)
+	for i := 0; i < p.reserved; i++ {
+		p.sem <- struct{}{}
 	}
+	return p
 }
 
+// Start runs the check scheduler and an executor for the shared check queue. Each
+// device is queued on its own check_interval; the device roster is reloaded every 30
+// seconds and property statuses are rolled up every 5 seconds for properties with new
+// results. Results from executors and remote probes are picked up every second. The
+// roster is also reloaded as soon as the cluster moves shards to or from this worker,
+// and max_concurrent_pings is reloaded with it, so neither needs a restart. A
+// heartbeat is written every 10 seconds for the API's worker watchdog.
 func (p *Pinger) Start(ctx context.Context) error {
-	log.Printf("Pinger started with max concurrent pings: %d", p.maxConcurrent)
+	slog.Info("Pinger started", "max_concurrent_pings", p.maxConcurrent)
+	p.startedAt = time.Now()
 
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	if err := p.redis.EnsureCheckQueue(ctx); err != nil {
+		return fmt.Errorf("failed to create check queue: %w", err)
+	}
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		p.runLimiter(ctx)
+	}()
+	go func() {
+		defer p.wg.Done()
+		p.runExecutor(ctx)
+	}()
+
+	if err := p.refreshDevices(ctx); err != nil {
+		slog.Error("Failed to load devices", "error", err)
+	}
+
+	dispatchTicker := time.NewTicker(time.Second)
+	defer dispatchTicker.Stop()
+	refreshTicker := time.NewTicker(30 * time.Second)
+	defer refreshTicker.Stop()
+	rollupTicker := time.NewTicker(5 * time.Second)
+	defer rollupTicker.Stop()
+	probeTicker := time.NewTicker(time.Second)
+	defer probeTicker.Stop()
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+	p.sendHeartbeat(ctx)
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Pinger stopping...")
+			slog.Info("Pinger stopping")
 			p.wg.Wait()
 			return ctx.Err()
 		case <-p.stopChan:
-			log.Println("Pinger stopped")
+			slog.Info("Pinger stopped")
 			p.wg.Wait()
+			if err := p.redis.RemoveWorkerHeartbeat(context.Background(), p.consumer); err != nil {
+				slog.Error("Failed to remove worker heartbeat", "error", err)
+			}
 			return nil
-		case <-ticker.C:
-			if err := p.checkDevices(ctx); err != nil {
-				log.Printf("Error checking devices: %v", err)
+		case <-refreshTicker.C:
+			p.reloadSettings(ctx)
+			if err := p.refreshDevices(ctx); err != nil {
+				slog.Error("Failed to load devices", "error", err)
+			}
+			p.markSilentProbedDevices(ctx)
+			p.requeueStaleChecks()
+			p.claimStaleChecks(ctx)
+		case <-dispatchTicker.C:
+			if p.cluster.Version() != p.clusterVersion {
+				if err := p.refreshDevices(ctx); err != nil {
+					slog.Error("Failed to load devices", "error", err)
+				}
 			}
+			p.dispatchDue(ctx)
+		case <-rollupTicker.C:
+			p.updatePropertyStatuses(ctx)
+		case <-probeTicker.C:
+			p.processCheckResults(ctx)
+			p.processProbeResults(ctx)
+		case <-heartbeatTicker.C:
+			p.sendHeartbeat(ctx)
 		}
 	}
 }
@@ -57,73 +191,282 @@ func (p *Pinger) Stop() {
 	close(p.stopChan)
 }
 
-func (p *Pinger) checkDevices(ctx context.Context) error {
-	devices, err := p.postgres.ListActiveDevices(ctx)
+// refreshDevices reloads the active devices of this worker's properties into the
+// schedule and marks every property for a status rollup
+func (p *Pinger) refreshDevices(ctx context.Context) error {
+	version := p.cluster.Version()
+	all, err := p.postgres.ListActiveDevices(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list devices: %w", err)
 	}
+	p.clusterVersion = version
 
-	if len(devices) == 0 {
-		return nil
+	// Check settings devices inherit are resolved here, so a changed property override
+	// or global default applies on the next reload
+	settings, err := p.postgres.GetSettings(ctx)
+	if err != nil {
+		slog.Error("Failed to load settings for check defaults", "error", err)
+	}
+	properties, err := p.postgres.ListProperties(ctx)
+	if err != nil {
+		slog.Error("Failed to load properties for check overrides", "error", err)
+	}
+	propertiesByID := make(map[int64]*models.Property, len(properties))
+	for i := range properties {
+		propertiesByID[properties[i].ID] = &properties[i]
 	}
 
-	log.Printf("Checking %d devices", len(devices))
+	devices := make([]models.Device, 0, len(all))
+	for _, device := range all {
+		if p.cluster.OwnsProperty(device.PropertyID) {
+			ResolveCheckSettings(&device, propertiesByID[device.PropertyID], settings)
+			devices = append(devices, device)
+		}
+	}
 
-	// Create semaphore for concurrency control
-	sem := make(chan struct{}, p.maxConcurrent)
-	var wg sync.WaitGroup
+	// Devices assigned to a remote probe are rolled up but not checked here
+	local := make([]models.Device, 0, len(devices))
+	localByID := make(map[int64]models.Device, len(devices))
+	probed := make(map[int64]models.Device)
+	for _, device := range devices {
+		if device.ProbeID != nil {
+			probed[device.ID] = device
+		} else {
+			local = append(local, device)
+			localByID[device.ID] = device
+		}
+	}
+	now := p.now()
+	p.schedule.sync(local, now)
+
+	windows, err := p.postgres.ListCurrentMaintenanceWindows(ctx, now)
+	if err != nil {
+		slog.Error("Failed to load maintenance windows", "error", err)
+	}
 
-	// Group devices by property for status computation
 	devicesByProperty := make(map[int64][]models.Device)
 	for _, device := range devices {
 		devicesByProperty[device.PropertyID] = append(devicesByProperty[device.PropertyID], device)
 	}
 
-	// Check each device
-	for _, device := range devices {
-		wg.Add(1)
-		go func(d models.Device) {
-			defer wg.Done()
+	p.mu.Lock()
+	p.devicesByProperty = devicesByProperty
+	p.localDevices = localByID
+	p.probedDevices = probed
+	if err == nil {
+		p.maintenance = windows
+	}
+	for propertyID := range devicesByProperty {
+		p.dirtyProperties[propertyID] = true
+	}
+	p.mu.Unlock()
 
-			select {
-			case <-ctx.Done():
-				return
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
+	metrics.ScheduledDevices.Set(float64(p.schedule.size()))
+	slog.Info("Scheduled devices", "devices", p.schedule.size(), "properties", len(devicesByProperty), "probed_devices", len(probed))
+	return nil
+}
 
-				status := p.pingDevice(ctx, &d)
-				if err := p.redis.SetDeviceStatus(ctx, status); err != nil {
-					log.Printf("Failed to set device status for %s: %v", d.Name, err)
-				}
+// checkResult is a check outcome waiting to be recorded for a device
+type checkResult struct {
+	device models.Device
+	status *models.DeviceStatus
+}
 
-				// Store history
-				if err := p.redis.AddDeviceHistory(ctx, d.ID, status.Status, status.ResponseTime, status.Message); err != nil {
-					log.Printf("Failed to add device history for %s: %v", d.Name, err)
-				}
+// recordStatuses applies state tracking to a batch of check results, whether run here
+// or by remote probes, then stores the statuses and change events in one Redis
+// pipeline and the history in one insert, and marks the properties for rollup.
+// Devices are tracked in parallel; results for the same device are applied in order.
+func (p *Pinger) recordStatuses(ctx context.Context, results []checkResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	byDevice := make(map[int64][]*checkResult)
+	var order, ids []int64
+	for i := range results {
+		d := &results[i].device
+		if _, seen := byDevice[d.ID]; !seen {
+			order = append(order, d.ID)
+			ids = append(ids, d.ID)
+			if d.ParentDeviceID != nil {
+				ids = append(ids, *d.ParentDeviceID)
 			}
-		}(device)
+		}
+		byDevice[d.ID] = append(byDevice[d.ID], &results[i])
+	}
+
+	// A missing previous status is treated as a first check
+	known, err := p.redis.GetDeviceStatuses(ctx, ids)
+	if err != nil {
+		slog.Error("Failed to get previous device statuses", "error", err)
+		known = make(map[int64]*models.DeviceStatus)
 	}
 
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	writes := make([]storage.DeviceStatusWrite, 0, len(results))
+	for _, id := range order {
+		group := byDevice[id]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			previous := known[id]
+			for _, r := range group {
+				write := p.trackStatus(ctx, &r.device, previous, known, r.status)
+				mu.Lock()
+				writes = append(writes, write)
+				mu.Unlock()
+				previous = r.status
+			}
+		}()
+	}
 	wg.Wait()
 
-	// Compute property statuses
-	statusComputer := NewStatusComputer(p.postgres, p.redis)
-	for propertyID, propertyDevices := range devicesByProperty {
-		propertyStatus, err := statusComputer.ComputePropertyStatus(ctx, propertyID, propertyDevices)
-		if err != nil {
-			log.Printf("Failed to compute property status for property %d: %v", propertyID, err)
-			continue
+	if err := p.redis.WriteDeviceStatuses(ctx, writes); err != nil {
+		slog.Error("Failed to write device statuses", "devices", len(order), "error", err)
+	}
+	for _, w := range writes {
+		if w.Change != nil {
+			p.webhooks.Publish(ctx, webhook.EventDeviceStatusChanged, w.Change)
+		}
+	}
+
+	history := make([]*models.DeviceStatus, len(writes))
+	for i, w := range writes {
+		history[i] = w.Status
+	}
+	if err := p.postgres.AddDeviceHistoryBatch(ctx, history); err != nil {
+		slog.Error("Failed to add device history", "devices", len(order), "error", err)
+	}
+
+	p.mu.Lock()
+	for i := range results {
+		p.dirtyProperties[results[i].device.PropertyID] = true
+	}
+	p.mu.Unlock()
+}
+
+// trackStatus applies state tracking to one check result given the device's previous
+// status and the statuses known before the batch, and returns the write to store it
+func (p *Pinger) trackStatus(ctx context.Context, d *models.Device, previous *models.DeviceStatus, known map[int64]*models.DeviceStatus, status *models.DeviceStatus) storage.DeviceStatusWrite {
+	p.mu.Lock()
+	status.Maintenance = p.maintenance.deviceInMaintenance(d, status.LastCheck)
+	p.mu.Unlock()
+
+	applyStateType(previous, status, failureThreshold(d))
+	if d.ParentDeviceID != nil {
+		applyParentState(status, known[*d.ParentDeviceID])
+	}
+
+	p.recordDeviceTransition(ctx, d, previous, status)
+	p.applyFlapping(ctx, d, previous, status)
+	p.applyPowerState(ctx, d, status)
+
+	write := storage.DeviceStatusWrite{Status: status, TTL: statusTTL(d)}
+	if deviceStatusChanged(previous, status) {
+		write.Change = &models.DeviceStatusChange{
+			PropertyID: d.PropertyID,
+			DeviceID:   d.ID,
+			DeviceName: d.Name,
+			Status:     *status,
+		}
+		if previous != nil {
+			write.Change.PreviousStatus = previous.Status
 		}
+	}
+	return write
+}
+
+// statusTTL keeps a device status alive for at least three check intervals
+func statusTTL(d *models.Device) time.Duration {
+	ttl := 3 * checkInterval(d)
+	if ttl < 10*time.Minute {
+		return 10 * time.Minute
+	}
+	return ttl
+}
+
+// updatePropertyStatuses recomputes the rollup for properties with new check results
+func (p *Pinger) updatePropertyStatuses(ctx context.Context) {
+	p.mu.Lock()
+	dirty := p.dirtyProperties
+	p.dirtyProperties = make(map[int64]bool)
+	devicesByProperty := p.devicesByProperty
+	maintenance := p.maintenance
+	p.mu.Unlock()
+
+	if len(dirty) == 0 {
+		return
+	}
+
+	// Previous statuses are needed to detect red/recovery transitions
+	previousStatuses, err := p.redis.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		slog.Error("Failed to get previous property statuses", "error", err)
+		previousStatuses = make(map[int64]*models.PropertyStatus)
+	}
+
+	dirtyDevices := make(map[int64][]models.Device, len(dirty))
+	for propertyID := range dirty {
+		if propertyDevices, ok := devicesByProperty[propertyID]; ok {
+			dirtyDevices[propertyID] = propertyDevices
+		}
+	}
+
+	// Device statuses for every dirty property are read in one round trip
+	statusComputer := NewStatusComputer(p.postgres, p.redis, WithClock(p.now))
+	computed, err := statusComputer.ComputePropertyStatuses(ctx, dirtyDevices)
+	if err != nil {
+		slog.Error("Failed to compute property statuses", "properties", len(dirtyDevices), "error", err)
+		// Retry on the next rollup
+		p.mu.Lock()
+		for propertyID := range dirty {
+			p.dirtyProperties[propertyID] = true
+		}
+		p.mu.Unlock()
+		return
+	}
+
+	currentStatuses := make(map[int64]*models.PropertyStatus)
+	for propertyID, propertyStatus := range computed {
+		if maintenance.propertyInMaintenance(propertyID, propertyStatus.LastCheck) {
+			propertyStatus.Maintenance = true
+		}
+		p.recordPropertyTransition(ctx, previousStatuses[propertyID], propertyStatus)
 
 		if err := p.redis.SetPropertyStatus(ctx, propertyStatus); err != nil {
-			log.Printf("Failed to set property status for property %d: %v", propertyID, err)
+			slog.Error("Failed to set property status", "property_id", propertyID, "error", err)
+			continue
+		}
+		currentStatuses[propertyID] = propertyStatus
+
+		if propertyStatusChanged(previousStatuses[propertyID], propertyStatus) {
+			if err := p.redis.PublishPropertyStatus(ctx, propertyStatus); err != nil {
+				slog.Error("Failed to publish property status", "property_id", propertyID, "error", err)
+			}
 		}
 	}
 
-	return nil
+	p.detector.Process(ctx, previousStatuses, currentStatuses)
+}
+
+// runCheck performs the check configured by the device's check_type
+func (p *Pinger) runCheck(ctx context.Context, d *models.Device) *models.DeviceStatus {
+	switch d.CheckType {
+	case "tcp":
+		return tcpCheckDevice(ctx, d)
+	case "vpn":
+		return p.vpnCheckDevice(ctx, d)
+	case "carp":
+		return p.carpCheckDevice(ctx, d)
+	case "ups":
+		return upsCheckDevice(ctx, d)
+	default:
+		return pingDevice(ctx, d)
+	}
 }
 
-func (p *Pinger) pingDevice(ctx context.Context, device *models.Device) *models.DeviceStatus {
+func pingDevice(ctx context.Context, device *models.Device) *models.DeviceStatus {
 	status := &models.DeviceStatus{
 		DeviceID:  device.ID,
 		LastCheck: time.Now(),
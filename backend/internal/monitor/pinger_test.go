@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/webhook"
+)
+
+// recordingNotifier keeps the property alerts it is asked to send
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Transition
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, propertyID int64, eventType string, status *models.PropertyStatus) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, Transition{PropertyID: propertyID, EventType: eventType, Current: status})
+	return nil
+}
+
+func (n *recordingNotifier) NotifyFlapping(ctx context.Context, device *models.Device, changes int, window time.Duration) error {
+	return nil
+}
+
+func (n *recordingNotifier) NotifyPower(ctx context.Context, device *models.Device, eventType, detail string) error {
+	return nil
+}
+
+func (n *recordingNotifier) sent() []Transition {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Transition(nil), n.alerts...)
+}
+
+// recordingPublisher counts the events published by type
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events map[string]int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, eventType string, data any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.events == nil {
+		p.events = make(map[string]int)
+	}
+	p.events[eventType]++
+}
+
+func (p *recordingPublisher) count(eventType string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.events[eventType]
+}
+
+func TestSchedulerAlertsWhenPropertyGoesDown(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewFakeStore()
+	statuses := storage.NewMemoryStore()
+	defer statuses.Close()
+
+	property := &models.Property{Name: "Harbor Inn"}
+	if err := store.CreateProperty(ctx, property); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateDevice(ctx, &models.Device{PropertyID: property.ID, Name: "lobby-ap", Hostname: "10.0.0.2", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	alerts := &recordingNotifier{}
+	events := &recordingPublisher{}
+	p := NewPinger(store, statuses, alerts, events, WithSchedulerClock(func() time.Time { return clock }))
+
+	if err := p.refreshDevices(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// First checks are spread across the interval, so none is due straight away
+	p.dispatchDue(ctx)
+	if n, _ := statuses.CheckQueueLength(ctx); n != 0 {
+		t.Fatalf("%d checks queued at start, want 0", n)
+	}
+	clock = clock.Add(time.Minute)
+	p.dispatchDue(ctx)
+	checks, err := statuses.ReadChecks(ctx, "test", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("%d checks queued after a minute, want the router and the AP", len(checks))
+	}
+
+	// Every check fails; the property is only down once the failures are confirmed
+	for round := 1; round <= defaultFailureThreshold; round++ {
+		clock = clock.Add(time.Minute)
+		results := make([]checkResult, len(checks))
+		for i, check := range checks {
+			results[i] = checkResult{device: check.Device, status: &models.DeviceStatus{
+				DeviceID:  check.Device.ID,
+				Status:    StatusOffline,
+				LastCheck: clock,
+				Message:   "timeout",
+			}}
+		}
+		p.recordStatuses(ctx, results)
+		p.updatePropertyStatuses(ctx)
+
+		if round < defaultFailureThreshold && len(alerts.sent()) != 0 {
+			t.Fatalf("alerted after %d soft failures", round)
+		}
+	}
+
+	current, err := statuses.GetAllPropertyStatuses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := current[property.ID]; status == nil || status.Status != "red" || !status.LastCheck.Equal(clock) {
+		t.Fatalf("property status = %+v, want red as of %v", status, clock)
+	}
+
+	sent := alerts.sent()
+	if len(sent) != 1 || sent[0].PropertyID != property.ID || sent[0].EventType != notifier.EventPropertyDown {
+		t.Fatalf("alerts = %+v, want one %s for property %d", sent, notifier.EventPropertyDown, property.ID)
+	}
+	if n := events.count(webhook.EventIncidentOpened); n != 1 {
+		t.Fatalf("%d incidents opened, want 1", n)
+	}
+	if events.count(webhook.EventDeviceStatusChanged) == 0 {
+		t.Fatal("no device status changes published")
+	}
+}
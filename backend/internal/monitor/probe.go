@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// probeResultBatch is the most results taken from the queue per tick
+const probeResultBatch = 1000
+
+// ProbeCheckTypes are the check types a remote probe can run. vpn and carp checks need
+// firewall credentials from the database and stay with the central worker.
+var ProbeCheckTypes = map[string]bool{"icmp": true, "tcp": true, "ups": true}
+
+// CheckDevice runs a single check the way the worker would, for remote probes
+func CheckDevice(ctx context.Context, d *models.Device) *models.DeviceStatus {
+	switch d.CheckType {
+	case "tcp":
+		return tcpCheckDevice(ctx, d)
+	case "ups":
+		return upsCheckDevice(ctx, d)
+	case "", "icmp":
+		return pingDevice(ctx, d)
+	default:
+		return &models.DeviceStatus{
+			DeviceID:  d.ID,
+			LastCheck: time.Now(),
+			Status:    "offline",
+			Message:   fmt.Sprintf("%s checks can't run on a remote probe", d.CheckType),
+		}
+	}
+}
+
+// processProbeResults records queued results from remote probes for this worker's
+// shards as one batch. Results for the same device are applied in order; results for
+// devices no longer assigned to the reporting probe are dropped.
+func (p *Pinger) processProbeResults(ctx context.Context) {
+	var results []models.ProbeResult
+	for _, shard := range p.cluster.Shards() {
+		batch, err := p.redis.PopProbeResults(ctx, shard, probeResultBatch)
+		if err != nil {
+			slog.Error("Failed to read probe results", "error", err)
+			return
+		}
+		results = append(results, batch...)
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	probed := p.probedDevices
+	p.mu.Unlock()
+
+	batch := make([]checkResult, 0, len(results))
+	for i := range results {
+		d, ok := probed[results[i].Status.DeviceID]
+		if !ok || d.ProbeID == nil || *d.ProbeID != results[i].ProbeID {
+			continue
+		}
+		batch = append(batch, checkResult{device: d, status: probeStatus(&results[i].Status)})
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.recordStatuses(ctx, batch)
+	}()
+}
+
+// probeStatus keeps only the check outcome of a reported status; state tracking is
+// always done by the worker
+func probeStatus(reported *models.DeviceStatus) *models.DeviceStatus {
+	status := &models.DeviceStatus{
+		DeviceID:     reported.DeviceID,
+		Status:       reported.Status,
+		ResponseTime: reported.ResponseTime,
+		LastCheck:    reported.LastCheck,
+		Message:      reported.Message,
+		UPS:          reported.UPS,
+	}
+	if status.Status != "online" {
+		status.Status = "offline"
+	}
+	// Don't trust a probe clock that is missing or ahead of ours
+	if now := time.Now(); status.LastCheck.IsZero() || status.LastCheck.After(now) {
+		status.LastCheck = now
+	}
+	return status
+}
+
+// markSilentProbedDevices takes probed devices offline when their probe hasn't
+// reported them for three check intervals, so a dead probe or lost uplink alerts
+// like any other outage
+func (p *Pinger) markSilentProbedDevices(ctx context.Context) {
+	p.mu.Lock()
+	probed := p.probedDevices
+	p.mu.Unlock()
+
+	now := time.Now()
+	var silent []checkResult
+	for _, d := range probed {
+		limit := 3 * checkInterval(&d)
+		if now.Sub(p.startedAt) < limit {
+			continue
+		}
+		message := fmt.Sprintf("No results from remote probe for %s", limit)
+
+		// Once marked silent, keep counting a failure every interval so the device
+		// reaches hard offline at its usual pace
+		status, _ := p.redis.GetDeviceStatus(ctx, d.ID)
+		if status != nil {
+			wait := limit
+			if status.Message == message {
+				wait = checkInterval(&d)
+			}
+			if now.Sub(status.LastCheck) < wait {
+				continue
+			}
+		}
+		silent = append(silent, checkResult{device: d, status: &models.DeviceStatus{
+			DeviceID:  d.ID,
+			LastCheck: now,
+			Status:    "offline",
+			Message:   message,
+		}})
+	}
+	p.recordStatuses(ctx, silent)
+}
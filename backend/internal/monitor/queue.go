@@ -0,0 +1,229 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/metrics"
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const (
+	// checkQueueLimit is the most checks left waiting or running on the shared queue;
+	// schedulers hold due checks back while executors catch up
+	checkQueueLimit = 20000
+	// checkClaimIdle is how long a check may stay unacknowledged before another
+	// executor takes it over
+	checkClaimIdle   = 90 * time.Second
+	checkClaimBatch  = 100
+	checkResultBatch = 1000
+)
+
+// Checks flow through Redis in three steps. The worker owning a property's shard
+// queues its devices' due checks on a stream; executors on every worker read the
+// stream through a consumer group, run the checks and push the results back to the
+// owning shard; the owner then records each result. An executor only reads as many
+// checks as it has free slots, and checks left unacknowledged by a crashed executor
+// are claimed by another one.
+
+// consumerName identifies this worker's executor in the check queue consumer group
+func consumerName(cluster *Cluster) string {
+	if cluster != nil {
+		return cluster.workerID
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "worker"
+}
+
+// checkTypeLabel returns the check type for metrics, with icmp for the default
+func checkTypeLabel(d *models.Device) string {
+	if d.CheckType == "" {
+		return "icmp"
+	}
+	return d.CheckType
+}
+
+// staleCheckAge is how long a check may go without a result before the scheduler
+// queues it again and executors drop the old copy
+func staleCheckAge(d *models.Device) time.Duration {
+	return checkInterval(d) + 2*time.Minute
+}
+
+// dispatchDue queues checks for every device whose next check time has passed,
+// unless the queue is already full
+func (p *Pinger) dispatchDue(ctx context.Context) {
+	length, err := p.redis.CheckQueueLength(ctx)
+	if err != nil {
+		slog.Error("Failed to read check queue length", "error", err)
+		return
+	}
+	metrics.CheckQueueLength.Set(float64(length))
+	if length >= checkQueueLimit {
+		if !p.queueFull {
+			slog.Warn("Check queue is full, holding back due checks", "queued_checks", length)
+			p.queueFull = true
+		}
+		return
+	}
+	p.queueFull = false
+
+	now := p.now()
+	var devices []models.Device
+	for _, device := range p.schedule.due(now) {
+		// The property's shard may have moved since the roster was loaded
+		if !p.cluster.OwnsProperty(device.PropertyID) {
+			p.schedule.complete(device.ID, now)
+			continue
+		}
+		devices = append(devices, device)
+	}
+
+	if len(devices) > 0 {
+		metrics.ChecksPerCycle.Observe(float64(len(devices)))
+	}
+	if err := p.redis.EnqueueChecks(ctx, devices, now); err != nil {
+		slog.Error("Failed to queue checks", "checks", len(devices), "error", err)
+		for _, device := range devices {
+			p.schedule.complete(device.ID, now)
+		}
+	}
+}
+
+// requeueStaleChecks queues again checks whose result never came back
+func (p *Pinger) requeueStaleChecks() {
+	if n := p.schedule.requeueStale(p.now(), staleCheckAge); n > 0 {
+		slog.Warn("Requeued checks with no result", "checks", n)
+	}
+}
+
+// runExecutor runs checks from the shared queue, reading only as many as there are
+// free check slots
+func (p *Pinger) runExecutor(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopChan:
+			return
+		case p.sem <- struct{}{}:
+		}
+
+		count := int64(1 + cap(p.sem) - len(p.sem))
+		checks, err := p.redis.ReadChecks(ctx, p.consumer, count, time.Second)
+		if err != nil || len(checks) == 0 {
+			<-p.sem
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Failed to read check queue", "error", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for i := range checks {
+			// The first slot is already held
+			if i > 0 {
+				p.sem <- struct{}{}
+			}
+			p.wg.Add(1)
+			go func(check models.QueuedCheck) {
+				defer p.wg.Done()
+				defer func() { <-p.sem }()
+				p.executeCheck(ctx, &check)
+			}(checks[i])
+		}
+	}
+}
+
+// claimStaleChecks takes over checks another executor read but never finished
+func (p *Pinger) claimStaleChecks(ctx context.Context) {
+	checks, err := p.redis.ClaimStaleChecks(ctx, p.consumer, checkClaimIdle, checkClaimBatch)
+	if err != nil {
+		slog.Error("Failed to claim stale checks", "error", err)
+		return
+	}
+	if len(checks) > 0 {
+		slog.Warn("Claimed unfinished checks", "checks", len(checks))
+	}
+
+	for i := range checks {
+		p.wg.Add(1)
+		go func(check models.QueuedCheck) {
+			defer p.wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stopChan:
+				return
+			case p.sem <- struct{}{}:
+			}
+			defer func() { <-p.sem }()
+
+			p.executeCheck(ctx, &check)
+		}(checks[i])
+	}
+}
+
+// executeCheck runs a queued check and hands the result to the property's owner. The
+// check is acknowledged once the result is queued, or dropped if the scheduler has
+// already queued it again.
+func (p *Pinger) executeCheck(ctx context.Context, check *models.QueuedCheck) {
+	d := &check.Device
+	if time.Since(check.QueuedAt) < staleCheckAge(d) {
+		start := time.Now()
+		status := p.runCheck(ctx, d)
+		metrics.CheckDuration.WithLabelValues(checkTypeLabel(d), status.Status).Observe(time.Since(start).Seconds())
+		if err := p.redis.PushCheckResult(ctx, d.PropertyID, status); err != nil {
+			slog.Error("Failed to queue check result", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+			return
+		}
+	}
+	if err := p.redis.AckCheck(ctx, check.ID); err != nil {
+		slog.Error("Failed to acknowledge check", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+	}
+}
+
+// processCheckResults records executor results for this worker's shards as one batch
+// and puts the devices back on the schedule
+func (p *Pinger) processCheckResults(ctx context.Context) {
+	var statuses []models.DeviceStatus
+	for _, shard := range p.cluster.Shards() {
+		batch, err := p.redis.PopCheckResults(ctx, shard, checkResultBatch)
+		if err != nil {
+			slog.Error("Failed to read check results", "error", err)
+			return
+		}
+		statuses = append(statuses, batch...)
+	}
+	if len(statuses) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	local := p.localDevices
+	p.mu.Unlock()
+
+	results := make([]checkResult, 0, len(statuses))
+	for i := range statuses {
+		// Devices no longer scheduled here, e.g. after a shard moved, are dropped
+		d, ok := local[statuses[i].DeviceID]
+		if !ok {
+			continue
+		}
+		results = append(results, checkResult{device: d, status: &statuses[i]})
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.recordStatuses(ctx, results)
+		now := p.now()
+		for _, r := range results {
+			p.schedule.complete(r.device.ID, now)
+		}
+	}()
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const retentionInterval = 24 * time.Hour
+
+// RetentionCleaner prunes history older than the history_retention_days setting once
+// a day: raw device history and hourly rollups in Postgres, notification events,
+// webhook deliveries, and the legacy per-device history in Redis. Daily rollups are
+// kept. Chunked attachment uploads abandoned for a week are dropped too.
+type RetentionCleaner struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	stopChan chan struct{}
+}
+
+func NewRetentionCleaner(postgres storage.Store, redis storage.StatusStore) *RetentionCleaner {
+	return &RetentionCleaner{
+		postgres: postgres,
+		redis:    redis,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (r *RetentionCleaner) Start(ctx context.Context) error {
+	slog.Info("Retention cleaner started")
+
+	r.cleanup(ctx)
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.stopChan:
+			slog.Info("Retention cleaner stopped")
+			return nil
+		case <-ticker.C:
+			r.cleanup(ctx)
+		}
+	}
+}
+
+func (r *RetentionCleaner) Stop() {
+	close(r.stopChan)
+}
+
+func (r *RetentionCleaner) cleanup(ctx context.Context) {
+	settings, err := r.postgres.GetSettings(ctx)
+	if err != nil {
+		slog.Error("Failed to load settings for retention cleanup", "error", err)
+		return
+	}
+	// Raw history must outlive the rollup lookback, so never prune the last day
+	days := settings.HistoryRetentionDays
+	if days < 1 {
+		days = 1
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	history, err := r.postgres.DeleteDeviceHistoryBefore(ctx, cutoff)
+	if err != nil {
+		slog.Error("Failed to prune device history", "error", err)
+	}
+	events, err := r.postgres.DeleteNotificationEventsBefore(ctx, cutoff)
+	if err != nil {
+		slog.Error("Failed to prune notification events", "error", err)
+	}
+	sessions, err := r.postgres.DeleteSessionsBefore(ctx, cutoff)
+	if err != nil {
+		slog.Error("Failed to prune expired sessions", "error", err)
+	}
+	deliveries, err := r.postgres.DeleteWebhookDeliveriesBefore(ctx, cutoff)
+	if err != nil {
+		slog.Error("Failed to prune webhook deliveries", "error", err)
+	}
+	// Uploads go by how long their blob store keeps the session, not the setting
+	uploads, err := r.postgres.DeleteAttachmentUploadsBefore(ctx, time.Now().Add(-storage.UploadSessionLifetime))
+	if err != nil {
+		slog.Error("Failed to prune abandoned attachment uploads", "error", err)
+	}
+	if err := r.redis.CleanupOldHistory(ctx, days); err != nil {
+		slog.Error("Failed to prune Redis device history", "error", err)
+	}
+
+	slog.Info("Retention cleanup finished", "retention_days", days,
+		"device_history_rows", history, "notification_events", events, "sessions", sessions,
+		"webhook_deliveries", deliveries, "attachment_uploads", uploads)
+}
@@ -0,0 +1,172 @@
+package monitor
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const (
+	defaultCheckInterval = 60 * time.Second
+	minCheckInterval     = 5 * time.Second
+)
+
+// scheduledCheck is a device waiting in the check queue
+type scheduledCheck struct {
+	device    models.Device
+	nextCheck time.Time
+	inFlight  bool
+	// dispatched is when the check last went in flight
+	dispatched time.Time
+	index      int // position in the heap, -1 when not queued
+}
+
+// checkQueue is a min-heap of scheduled checks ordered by next check time
+type checkQueue []*scheduledCheck
+
+func (q checkQueue) Len() int           { return len(q) }
+func (q checkQueue) Less(i, j int) bool { return q[i].nextCheck.Before(q[j].nextCheck) }
+func (q checkQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *checkQueue) Push(x interface{}) {
+	item := x.(*scheduledCheck)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *checkQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*q = old[:n-1]
+	return item
+}
+
+// schedule tracks when each active device is next due for a check
+type schedule struct {
+	mu      sync.Mutex
+	queue   checkQueue
+	entries map[int64]*scheduledCheck
+}
+
+func newSchedule() *schedule {
+	return &schedule{
+		entries: make(map[int64]*scheduledCheck),
+	}
+}
+
+// checkInterval returns the effective interval for a device
+func checkInterval(d *models.Device) time.Duration {
+	if d.CheckInterval <= 0 {
+		return defaultCheckInterval
+	}
+	interval := time.Duration(d.CheckInterval) * time.Second
+	if interval < minCheckInterval {
+		return minCheckInterval
+	}
+	return interval
+}
+
+// sync reconciles the schedule with the current device roster. New devices are
+// spread across their first interval so a fresh start doesn't ping everything at once.
+func (s *schedule) sync(devices []models.Device, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int64]bool, len(devices))
+	for _, d := range devices {
+		seen[d.ID] = true
+		interval := checkInterval(&d)
+
+		entry, ok := s.entries[d.ID]
+		if !ok {
+			offset := time.Duration(d.ID%int64(interval/time.Second)) * time.Second
+			entry = &scheduledCheck{device: d, nextCheck: now.Add(offset), index: -1}
+			s.entries[d.ID] = entry
+			heap.Push(&s.queue, entry)
+			continue
+		}
+
+		entry.device = d
+		// Pull the next check forward if the interval was shortened
+		if latest := now.Add(interval); entry.index >= 0 && entry.nextCheck.After(latest) {
+			entry.nextCheck = latest
+			heap.Fix(&s.queue, entry.index)
+		}
+	}
+
+	for id, entry := range s.entries {
+		if seen[id] {
+			continue
+		}
+		if entry.index >= 0 {
+			heap.Remove(&s.queue, entry.index)
+		}
+		delete(s.entries, id)
+	}
+}
+
+// due pops every device whose next check time has passed and marks it in flight
+func (s *schedule) due(now time.Time) []models.Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var devices []models.Device
+	for s.queue.Len() > 0 && !s.queue[0].nextCheck.After(now) {
+		entry := heap.Pop(&s.queue).(*scheduledCheck)
+		entry.inFlight = true
+		entry.dispatched = now
+		devices = append(devices, entry.device)
+	}
+	return devices
+}
+
+// complete requeues a device one interval after its check finished. Devices removed
+// from the roster while in flight are dropped.
+func (s *schedule) complete(deviceID int64, finished time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[deviceID]
+	if !ok || !entry.inFlight {
+		return
+	}
+	entry.inFlight = false
+	entry.nextCheck = finished.Add(checkInterval(&entry.device))
+	heap.Push(&s.queue, entry)
+}
+
+// requeueStale puts back checks that have been in flight longer than maxAge allows,
+// e.g. because their result was lost, so they run again right away. It returns the
+// number of checks requeued.
+func (s *schedule) requeueStale(now time.Time, maxAge func(d *models.Device) time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requeued := 0
+	for _, entry := range s.entries {
+		if !entry.inFlight || now.Sub(entry.dispatched) < maxAge(&entry.device) {
+			continue
+		}
+		entry.inFlight = false
+		entry.nextCheck = now
+		heap.Push(&s.queue, entry)
+		requeued++
+	}
+	return requeued
+}
+
+// size returns the number of scheduled devices
+func (s *schedule) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
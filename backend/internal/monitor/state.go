@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// State types, following Nagios soft/hard semantics
+const (
+	StateSoft = "soft"
+	StateHard = "hard"
+)
+
+// Device status values
+const (
+	StatusOnline      = "online"
+	StatusOffline     = "offline"
+	StatusUnreachable = "unreachable"
+)
+
+const defaultFailureThreshold = 3
+
+// failureThreshold returns how many consecutive failed checks confirm a device offline
+func failureThreshold(d *models.Device) int {
+	if d.FailureThreshold <= 0 {
+		return defaultFailureThreshold
+	}
+	return d.FailureThreshold
+}
+
+// applyStateType sets the soft/hard state on a fresh check result based on the
+// previous status. A failed check only becomes a hard offline once threshold
+// consecutive failures have been seen; a successful check is always hard online.
+func applyStateType(previous, current *models.DeviceStatus, threshold int) {
+	if current.Status == StatusOnline {
+		current.StateType = StateHard
+		current.ConsecutiveFailures = 0
+		return
+	}
+
+	failures := 1
+	if previous != nil && previous.Status != StatusOnline {
+		failures = previous.ConsecutiveFailures + 1
+	}
+	current.ConsecutiveFailures = failures
+
+	// Once hard offline, stay hard until the device recovers
+	wasHardOffline := previous != nil && previous.Status != StatusOnline && previous.StateType != StateSoft
+	if failures >= threshold || wasHardOffline {
+		current.StateType = StateHard
+		return
+	}
+
+	current.StateType = StateSoft
+	current.Message = fmt.Sprintf("Soft failure %d/%d: %s", failures, threshold, current.Message)
+}
+
+// deviceUp reports whether a device counts as up for property rollups. Soft
+// failures have not been confirmed yet and don't count against the property.
+func deviceUp(status *models.DeviceStatus) bool {
+	return status.Status == StatusOnline || status.StateType == StateSoft
+}
+
+// applyParentState marks a hard-offline device unreachable when its parent device is
+// down, so a single upstream outage doesn't count every downstream device as offline
+func applyParentState(current, parent *models.DeviceStatus) {
+	if current.Status != StatusOffline || current.StateType != StateHard || parent == nil {
+		return
+	}
+	if deviceUp(parent) {
+		return
+	}
+	current.Status = StatusUnreachable
+	current.Message = fmt.Sprintf("Parent device %d is down: %s", parent.DeviceID, current.Message)
+}
+
+// deviceStatusChanged reports whether a check moved the device to a new status or
+// state type. Response time and message changes alone are not transitions.
+func deviceStatusChanged(previous, current *models.DeviceStatus) bool {
+	if previous == nil {
+		return true
+	}
+	return previous.Status != current.Status ||
+		previous.StateType != current.StateType ||
+		previous.Maintenance != current.Maintenance ||
+		previous.Flapping != current.Flapping
+}
@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/etswifi/ets-noc/internal/models"
@@ -9,61 +10,130 @@ import (
 )
 
 type StatusComputer struct {
-	postgres *storage.PostgresStore
-	redis    *storage.RedisStore
+	postgres storage.Store
+	redis    storage.StatusStore
+	now      func() time.Time
 }
 
-func NewStatusComputer(postgres *storage.PostgresStore, redis *storage.RedisStore) *StatusComputer {
-	return &StatusComputer{
+// StatusComputerOption configures a StatusComputer
+type StatusComputerOption func(*StatusComputer)
+
+// WithClock sets the clock that stamps LastCheck on computed statuses
+func WithClock(now func() time.Time) StatusComputerOption {
+	return func(sc *StatusComputer) {
+		sc.now = now
+	}
+}
+
+func NewStatusComputer(postgres storage.Store, redis storage.StatusStore, opts ...StatusComputerOption) *StatusComputer {
+	sc := &StatusComputer{
 		postgres: postgres,
 		redis:    redis,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(sc)
 	}
+	return sc
 }
 
 // ComputePropertyStatus computes the rollup status for a property based on device statuses
 func (sc *StatusComputer) ComputePropertyStatus(ctx context.Context, propertyID int64, devices []models.Device) (*models.PropertyStatus, error) {
+	statuses, err := sc.ComputePropertyStatuses(ctx, map[int64][]models.Device{propertyID: devices})
+	if err != nil {
+		return nil, err
+	}
+	return statuses[propertyID], nil
+}
+
+// ComputePropertyStatuses computes the rollup status for several properties, reading
+// every device status they need, parents in other properties included, in one round trip
+func (sc *StatusComputer) ComputePropertyStatuses(ctx context.Context, devicesByProperty map[int64][]models.Device) (map[int64]*models.PropertyStatus, error) {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, devices := range devicesByProperty {
+		for _, d := range devices {
+			for _, id := range []int64{d.ID, parentID(&d)} {
+				if id != 0 && !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	deviceStatuses, err := sc.redis.GetDeviceStatuses(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device statuses: %w", err)
+	}
+
+	now := sc.now()
+	statuses := make(map[int64]*models.PropertyStatus, len(devicesByProperty))
+	for propertyID, devices := range devicesByProperty {
+		statuses[propertyID] = computePropertyStatus(propertyID, devices, deviceStatuses, now)
+	}
+	return statuses, nil
+}
+
+// parentID returns a device's parent ID, or 0 when it has none
+func parentID(d *models.Device) int64 {
+	if d.ParentDeviceID == nil {
+		return 0
+	}
+	return *d.ParentDeviceID
+}
+
+// computePropertyStatus rolls up a property from the given device statuses as of now
+func computePropertyStatus(propertyID int64, devices []models.Device, deviceStatuses map[int64]*models.DeviceStatus, now time.Time) *models.PropertyStatus {
 	if len(devices) == 0 {
 		return &models.PropertyStatus{
 			PropertyID: propertyID,
 			Status:     "green",
-			LastCheck:  time.Now(),
-		}, nil
-	}
-
-	// Get all device statuses
-	deviceStatuses := make(map[int64]*models.DeviceStatus)
-	for _, d := range devices {
-		status, err := sc.redis.GetDeviceStatus(ctx, d.ID)
-		if err == nil && status != nil {
-			deviceStatuses[d.ID] = status
+			LastCheck:  now,
 		}
 	}
 
-	online, offline := 0, 0
+	online, offline, unreachable := 0, 0, 0
+	offlineInMaintenance, offlineFlapping := 0, 0
 	criticalOffline := false
 
 	for _, device := range devices {
-		if status, ok := deviceStatuses[device.ID]; ok && status.Status == "online" {
+		status, ok := deviceStatuses[device.ID]
+		switch {
+		case ok && deviceUp(status):
 			online++
-		} else {
+		case ok && (status.Status == StatusUnreachable || parentDown(&device, deviceStatuses)):
+			// Downstream of a failed device; suppressed from offline counts
+			unreachable++
+		default:
 			offline++
 			if device.IsCritical {
 				criticalOffline = true
 			}
+			if ok && status.Maintenance {
+				offlineInMaintenance++
+			} else if ok && status.Flapping {
+				offlineFlapping++
+			}
 		}
 	}
 
 	propertyStatus := &models.PropertyStatus{
-		PropertyID:      propertyID,
-		OnlineCount:     online,
-		OfflineCount:    offline,
-		TotalCount:      len(devices),
-		CriticalOffline: criticalOffline,
-		LastCheck:       time.Now(),
+		PropertyID:       propertyID,
+		OnlineCount:      online,
+		OfflineCount:     offline,
+		UnreachableCount: unreachable,
+		TotalCount:       len(devices),
+		CriticalOffline:  criticalOffline,
+		LastCheck:        now,
+		// Failures entirely explained by devices under maintenance
+		Maintenance: offline > 0 && offlineInMaintenance == offline,
+		// Failures explained by flapping devices, possibly alongside maintenance
+		Flapping: offlineFlapping > 0 && offlineInMaintenance+offlineFlapping == offline,
 	}
 
 	// Status logic: red > yellow > green
-	if offline == len(devices) || criticalOffline {
+	if offline+unreachable == len(devices) || criticalOffline {
 		propertyStatus.Status = "red"
 	} else if offline > 0 {
 		propertyStatus.Status = "yellow"
@@ -71,7 +141,20 @@ func (sc *StatusComputer) ComputePropertyStatus(ctx context.Context, propertyID
 		propertyStatus.Status = "green"
 	}
 
-	return propertyStatus, nil
+	return propertyStatus
+}
+
+// parentDown reports whether a device's parent is currently down
+func parentDown(device *models.Device, statuses map[int64]*models.DeviceStatus) bool {
+	if device.ParentDeviceID == nil {
+		return false
+	}
+
+	parent, ok := statuses[*device.ParentDeviceID]
+	if !ok {
+		return false
+	}
+	return !deviceUp(parent)
 }
 
 // ComputeAllPropertyStatuses computes status for all properties
@@ -81,17 +164,21 @@ func (sc *StatusComputer) ComputeAllPropertyStatuses(ctx context.Context) error
 		return err
 	}
 
+	devicesByProperty := make(map[int64][]models.Device, len(properties))
 	for _, property := range properties {
 		devices, err := sc.postgres.ListDevicesForProperty(ctx, property.ID)
 		if err != nil {
 			continue
 		}
+		devicesByProperty[property.ID] = devices
+	}
 
-		propertyStatus, err := sc.ComputePropertyStatus(ctx, property.ID, devices)
-		if err != nil {
-			continue
-		}
+	statuses, err := sc.ComputePropertyStatuses(ctx, devicesByProperty)
+	if err != nil {
+		return err
+	}
 
+	for _, propertyStatus := range statuses {
 		if err := sc.redis.SetPropertyStatus(ctx, propertyStatus); err != nil {
 			continue
 		}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const reconcileInterval = 10 * time.Minute
+
+// StatusReconciler prunes the aggregate status hashes every ten minutes: statuses that
+// have expired, and those of devices that were deleted or deactivated and properties
+// that were deleted, which would otherwise keep showing their last state
+type StatusReconciler struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	stopChan chan struct{}
+}
+
+func NewStatusReconciler(postgres storage.Store, redis storage.StatusStore) *StatusReconciler {
+	return &StatusReconciler{
+		postgres: postgres,
+		redis:    redis,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (r *StatusReconciler) Start(ctx context.Context) error {
+	slog.Info("Status reconciler started")
+
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	r.reconcile(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.stopChan:
+			slog.Info("Status reconciler stopped")
+			return nil
+		case <-ticker.C:
+			r.reconcile(ctx)
+		}
+	}
+}
+
+func (r *StatusReconciler) Stop() {
+	close(r.stopChan)
+}
+
+func (r *StatusReconciler) reconcile(ctx context.Context) {
+	devices, err := r.postgres.ListActiveDevices(ctx)
+	if err != nil {
+		slog.Error("Failed to list devices for status reconciliation", "error", err)
+		return
+	}
+	properties, err := r.postgres.ListProperties(ctx)
+	if err != nil {
+		slog.Error("Failed to list properties for status reconciliation", "error", err)
+		return
+	}
+
+	deviceIDs := make([]int64, len(devices))
+	for i, d := range devices {
+		deviceIDs[i] = d.ID
+	}
+	propertyIDs := make([]int64, len(properties))
+	for i, p := range properties {
+		propertyIDs[i] = p.ID
+	}
+
+	removedDevices, removedProperties, err := r.redis.PruneStatuses(ctx, deviceIDs, propertyIDs)
+	if err != nil {
+		slog.Error("Failed to prune stale statuses", "error", err)
+		return
+	}
+	if removedDevices > 0 || removedProperties > 0 {
+		slog.Info("Pruned stale statuses", "devices", removedDevices, "properties", removedProperties)
+	}
+}
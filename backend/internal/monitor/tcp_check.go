@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// tcpCheckDevice attempts a TCP connect to the device's configured port, retrying up
+// to device.Retries times. Response time is the duration of the successful connect.
+func tcpCheckDevice(ctx context.Context, device *models.Device) *models.DeviceStatus {
+	status := &models.DeviceStatus{
+		DeviceID:  device.ID,
+		LastCheck: time.Now(),
+	}
+
+	if device.Port <= 0 || device.Port > 65535 {
+		status.Status = "offline"
+		status.Message = fmt.Sprintf("Invalid TCP port %d", device.Port)
+		return status
+	}
+
+	attempts := device.Retries
+	if attempts <= 0 {
+		attempts = 1
+	}
+	timeout := time.Duration(device.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	addr := net.JoinHostPort(device.Hostname, strconv.Itoa(device.Port))
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			elapsed := time.Since(start)
+			conn.Close()
+			status.Status = "online"
+			status.ResponseTime = float64(elapsed.Milliseconds())
+			status.Message = "OK"
+			return status
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	status.Status = "offline"
+	status.Message = fmt.Sprintf("TCP connect to port %d failed: %v", device.Port, lastErr)
+	return status
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	trafficCollectInterval = 5 * time.Minute
+	trafficRetention       = 90 * 24 * time.Hour
+	// Firewalls polled at once; each poll is an SSH session lasting a second or two
+	trafficConcurrency = 8
+)
+
+// TrafficCollector samples interface counters from every property's pfSense so
+// throughput can be graphed over time
+type TrafficCollector struct {
+	postgres storage.Store
+	stopChan chan struct{}
+}
+
+func NewTrafficCollector(postgres storage.Store) *TrafficCollector {
+	return &TrafficCollector{
+		postgres: postgres,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (t *TrafficCollector) Start(ctx context.Context) error {
+	slog.Info("Traffic collector started")
+
+	ticker := time.NewTicker(trafficCollectInterval)
+	defer ticker.Stop()
+
+	t.collect(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.stopChan:
+			slog.Info("Traffic collector stopped")
+			return nil
+		case <-ticker.C:
+			t.collect(ctx)
+		}
+	}
+}
+
+func (t *TrafficCollector) Stop() {
+	close(t.stopChan)
+}
+
+func (t *TrafficCollector) collect(ctx context.Context) {
+	properties, err := t.postgres.ListProperties(ctx)
+	if err != nil {
+		slog.Error("Failed to list properties for traffic collection", "error", err)
+		return
+	}
+
+	sem := make(chan struct{}, trafficConcurrency)
+	var wg sync.WaitGroup
+	for i := range properties {
+		property := &properties[i]
+		if property.PfSenseHost == "" || property.PfSenseUsername == "" || property.PfSensePassword == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := t.collectProperty(ctx, property); err != nil {
+				slog.Error("Failed to collect traffic", "property_id", property.ID, "property", property.Name, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := t.postgres.DeleteTrafficSamplesBefore(ctx, time.Now().Add(-trafficRetention)); err != nil {
+		slog.Error("Failed to prune traffic samples", "error", err)
+	}
+}
+
+func (t *TrafficCollector) collectProperty(ctx context.Context, property *models.Property) error {
+	client := pfsense.NewClient(property.PfSenseHost, property.PfSensePort, property.PfSenseUsername, property.PfSensePassword)
+	counters, err := client.GetInterfaceCounters(ctx)
+	if err != nil {
+		return err
+	}
+
+	samples := make([]models.TrafficSample, 0, len(counters))
+	for _, c := range counters {
+		samples = append(samples, models.TrafficSample{
+			Interface: c.Interface,
+			RxBytes:   c.RxBytes,
+			TxBytes:   c.TxBytes,
+			RxPackets: c.RxPackets,
+			TxPackets: c.TxPackets,
+		})
+	}
+	return t.postgres.InsertTrafficSamples(ctx, property.ID, time.Now(), samples)
+}
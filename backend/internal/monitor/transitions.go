@@ -0,0 +1,276 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/webhook"
+)
+
+// Transition is a property status change that should be notified
+type Transition struct {
+	PropertyID int64
+	EventType  string
+	Previous   *models.PropertyStatus
+	Current    *models.PropertyStatus
+}
+
+// Notifier sends the alerts the monitor raises; *notifier.Notifier is the production
+// implementation
+type Notifier interface {
+	Notify(ctx context.Context, propertyID int64, eventType string, status *models.PropertyStatus) error
+	NotifyFlapping(ctx context.Context, device *models.Device, changes int, window time.Duration) error
+	NotifyPower(ctx context.Context, device *models.Device, eventType, detail string) error
+}
+
+// EventPublisher delivers monitor events to subscribers; *webhook.Dispatcher is the
+// production implementation
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType string, data any)
+}
+
+// TransitionDetector compares successive property statuses and hands
+// down/recovery events to the notifier, and incidents to the webhooks
+type TransitionDetector struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	notifier Notifier
+	webhooks EventPublisher
+}
+
+func NewTransitionDetector(postgres storage.Store, redis storage.StatusStore, notifier Notifier, webhooks EventPublisher) *TransitionDetector {
+	return &TransitionDetector{
+		postgres: postgres,
+		redis:    redis,
+		notifier: notifier,
+		webhooks: webhooks,
+	}
+}
+
+// DetectTransition returns the event type for a status change, or "" if the change
+// is not notifiable. A property with no previous status is treated as not red.
+// Changes during maintenance or while devices are flapping are suppressed, and a
+// property that went red in either state alerts once it ends if it is still red.
+func DetectTransition(previous, current *models.PropertyStatus) string {
+	if alertsHeld(current) {
+		return ""
+	}
+
+	wasRed := previous != nil && previous.Status == "red" && !alertsHeld(previous)
+	isRed := current.Status == "red"
+
+	switch {
+	case isRed && !wasRed:
+		return notifier.EventPropertyDown
+	case wasRed && !isRed:
+		return notifier.EventPropertyRecovery
+	default:
+		return ""
+	}
+}
+
+// alertsHeld reports whether a status is explained by maintenance or flapping devices
+func alertsHeld(status *models.PropertyStatus) bool {
+	return status.Maintenance || status.Flapping
+}
+
+// Detect returns all notifiable transitions between two sets of property statuses
+func (td *TransitionDetector) Detect(previous, current map[int64]*models.PropertyStatus) []Transition {
+	var transitions []Transition
+	for propertyID, status := range current {
+		eventType := DetectTransition(previous[propertyID], status)
+		if eventType == "" {
+			continue
+		}
+		transitions = append(transitions, Transition{
+			PropertyID: propertyID,
+			EventType:  eventType,
+			Previous:   previous[propertyID],
+			Current:    status,
+		})
+	}
+	return transitions
+}
+
+// Process detects transitions and sends notifications for those outside the cooldown
+// window. Webhooks get every transition as an incident opening or resolving, whether
+// or not it was acknowledged, silenced or in cooldown.
+func (td *TransitionDetector) Process(ctx context.Context, previous, current map[int64]*models.PropertyStatus) {
+	transitions := append(td.Detect(previous, current), td.heldRecoveries(ctx, previous, current)...)
+	for _, t := range transitions {
+		td.publishIncident(ctx, t)
+	}
+	if td.notifier == nil || len(transitions) == 0 {
+		return
+	}
+
+	settings, err := td.postgres.GetSettings(ctx)
+	if err != nil {
+		slog.Error("Failed to load settings for notifications", "error", err)
+		return
+	}
+
+	suppression, err := td.loadSuppression(ctx)
+	if err != nil {
+		slog.Error("Failed to load acknowledgements and silences", "error", err)
+		suppression = &alertSuppression{}
+	}
+
+	for _, t := range transitions {
+		slog.Info("Property status changed", "property_id", t.PropertyID, "from", statusName(t.Previous), "to", t.Current.Status)
+
+		if td.suppressed(ctx, t, suppression) {
+			slog.Info("Skipping notification, acknowledged or silenced", "property_id", t.PropertyID, "event", t.EventType)
+			continue
+		}
+
+		shouldNotify, err := td.redis.ShouldNotify(ctx, t.PropertyID, t.EventType, settings.NotificationCooldown)
+		if err != nil {
+			slog.Error("Failed to check notification cooldown", "property_id", t.PropertyID, "error", err)
+			continue
+		}
+		if !shouldNotify {
+			slog.Info("Skipping notification, in cooldown", "property_id", t.PropertyID, "event", t.EventType)
+			continue
+		}
+
+		if err := td.notifier.Notify(ctx, t.PropertyID, t.EventType, t.Current); err != nil {
+			slog.Error("Failed to send notification", "property_id", t.PropertyID, "event", t.EventType, "error", err)
+		}
+	}
+}
+
+// publishIncident sends a down transition to the webhooks as incident.opened and a
+// recovery as incident.resolved
+func (td *TransitionDetector) publishIncident(ctx context.Context, t Transition) {
+	eventType := webhook.EventIncidentOpened
+	if t.EventType == notifier.EventPropertyRecovery {
+		eventType = webhook.EventIncidentResolved
+	}
+	td.webhooks.Publish(ctx, eventType, webhook.IncidentData{PropertyID: t.PropertyID, Status: t.Current})
+}
+
+// heldRecoveries returns recoveries for properties that were alerted as down, then
+// went quiet under maintenance or flapping and are now healthy. DetectTransition
+// can't see these because the red status before recovery was held.
+func (td *TransitionDetector) heldRecoveries(ctx context.Context, previous, current map[int64]*models.PropertyStatus) []Transition {
+	var transitions []Transition
+	for propertyID, status := range current {
+		prev := previous[propertyID]
+		if prev == nil || prev.Status != "red" || !alertsHeld(prev) || status.Status == "red" || alertsHeld(status) {
+			continue
+		}
+		incident, err := td.redis.GetPropertyIncident(ctx, propertyID)
+		if err != nil || incident == "" {
+			continue
+		}
+		transitions = append(transitions, Transition{
+			PropertyID: propertyID,
+			EventType:  notifier.EventPropertyRecovery,
+			Previous:   prev,
+			Current:    status,
+		})
+	}
+	return transitions
+}
+
+// ProcessFlapping sends the one-off notification for a device that started flapping,
+// unless it is in maintenance, acknowledged or silenced
+func (td *TransitionDetector) ProcessFlapping(ctx context.Context, d *models.Device, status *models.DeviceStatus, changes int, window time.Duration) {
+	if td.notifier == nil || status.Maintenance {
+		return
+	}
+
+	suppression, err := td.loadSuppression(ctx)
+	if err != nil {
+		slog.Error("Failed to load acknowledgements and silences", "error", err)
+	} else if suppression.deviceSuppressed(d) || suppression.propertySilenced(d.PropertyID) {
+		slog.Info("Skipping flapping notification, acknowledged or silenced", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name)
+		return
+	}
+
+	if err := td.notifier.NotifyFlapping(ctx, d, changes, window); err != nil {
+		slog.Error("Failed to send flapping notification", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+	}
+}
+
+// ProcessPower sends a property's on-battery or power-restored notification, unless the
+// UPS is in maintenance or the property or device is acknowledged or silenced
+func (td *TransitionDetector) ProcessPower(ctx context.Context, d *models.Device, status *models.DeviceStatus, eventType string) {
+	if td.notifier == nil || status.Maintenance {
+		return
+	}
+
+	suppression, err := td.loadSuppression(ctx)
+	if err != nil {
+		slog.Error("Failed to load acknowledgements and silences", "error", err)
+	} else if suppression.deviceSuppressed(d) || suppression.propertySilenced(d.PropertyID) {
+		slog.Info("Skipping notification, acknowledged or silenced", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "event", eventType)
+		return
+	}
+
+	if err := td.notifier.NotifyPower(ctx, d, eventType, status.Message); err != nil {
+		slog.Error("Failed to send notification", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "event", eventType, "error", err)
+	}
+}
+
+func (td *TransitionDetector) loadSuppression(ctx context.Context) (*alertSuppression, error) {
+	acks, err := td.postgres.ListActiveAcknowledgements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	silences, err := td.postgres.ListActiveSilences(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &alertSuppression{acks: acks, silences: silences}, nil
+}
+
+// suppressed reports whether a transition's notification is muted. A property silence
+// mutes everything; an acknowledgement, or every down device being acknowledged or
+// silenced, mutes down notifications only so the recovery still goes out.
+func (td *TransitionDetector) suppressed(ctx context.Context, t Transition, suppression *alertSuppression) bool {
+	if suppression.propertySilenced(t.PropertyID) {
+		return true
+	}
+	if t.EventType != notifier.EventPropertyDown {
+		return false
+	}
+	if suppression.propertyAcknowledged(t.PropertyID) {
+		return true
+	}
+
+	suppressed, err := td.downDevicesSuppressed(ctx, t.PropertyID, suppression)
+	if err != nil {
+		slog.Error("Failed to check device silences", "property_id", t.PropertyID, "error", err)
+		return false
+	}
+	return suppressed
+}
+
+func statusName(status *models.PropertyStatus) string {
+	if status == nil {
+		return "unknown"
+	}
+	return status.Status
+}
+
+// propertyStatusChanged reports whether anything shown on the dashboard differs
+// between two statuses; LastCheck alone doesn't count
+func propertyStatusChanged(previous, current *models.PropertyStatus) bool {
+	if previous == nil {
+		return true
+	}
+	return previous.Status != current.Status ||
+		previous.OnlineCount != current.OnlineCount ||
+		previous.OfflineCount != current.OfflineCount ||
+		previous.UnreachableCount != current.UnreachableCount ||
+		previous.TotalCount != current.TotalCount ||
+		previous.CriticalOffline != current.CriticalOffline ||
+		previous.Maintenance != current.Maintenance ||
+		previous.Flapping != current.Flapping
+}
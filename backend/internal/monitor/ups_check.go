@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/nut"
+)
+
+// upsCheckDevice reads a UPS from a NUT server, with the device hostname as
+// "<ups>@<host>" and port as the upsd port (3493 if unset). The device is online while
+// the UPS reports, including on battery; a low battery takes it offline since the
+// UPS is about to cut power.
+func upsCheckDevice(ctx context.Context, device *models.Device) *models.DeviceStatus {
+	status := &models.DeviceStatus{
+		DeviceID:  device.ID,
+		LastCheck: time.Now(),
+		Status:    "offline",
+	}
+
+	name, host, err := nut.ParseTarget(device.Hostname)
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+	timeout := time.Duration(device.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	start := time.Now()
+	reading, err := nut.GetStatus(ctx, host, device.Port, name, timeout)
+	if err != nil {
+		status.Message = fmt.Sprintf("Failed to read UPS %s: %v", name, err)
+		return status
+	}
+
+	status.UPS = &models.UPSStatus{
+		OnBattery:      reading.OnBattery,
+		LowBattery:     reading.LowBattery,
+		ChargePercent:  reading.ChargePercent,
+		RuntimeSeconds: reading.RuntimeSeconds,
+		BatteryHealth:  reading.BatteryHealth(),
+		Flags:          reading.Flags,
+	}
+	status.Message = upsSummary(status.UPS)
+	status.ResponseTime = float64(time.Since(start).Milliseconds())
+	if !reading.LowBattery {
+		status.Status = "online"
+	}
+	return status
+}
+
+// upsSummary describes a UPS state, e.g. "On battery, 84% charge, 23m remaining"
+func upsSummary(ups *models.UPSStatus) string {
+	msg := "On line power"
+	if ups.OnBattery {
+		msg = "On battery"
+	}
+	if ups.LowBattery {
+		msg += ", LOW BATTERY"
+	}
+	if ups.ChargePercent != nil {
+		msg += fmt.Sprintf(", %.0f%% charge", *ups.ChargePercent)
+	}
+	if ups.RuntimeSeconds != nil {
+		msg += fmt.Sprintf(", %dm remaining", *ups.RuntimeSeconds/60)
+	}
+	if ups.BatteryHealth == "replace" {
+		msg += ", battery needs replacing"
+	}
+	return msg
+}
+
+// applyPowerState tracks which UPS devices at a property are on battery and sends the
+// power alerts when the property's first UPS switches to battery and when its last one
+// returns to line power. A UPS that can't be read leaves the state unchanged.
+func (p *Pinger) applyPowerState(ctx context.Context, d *models.Device, current *models.DeviceStatus) {
+	if current.UPS == nil {
+		return
+	}
+
+	before, after, err := p.redis.SetDeviceOnBattery(ctx, d.PropertyID, d.ID, current.UPS.OnBattery)
+	if err != nil {
+		slog.Error("Failed to track power state", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name, "error", err)
+		return
+	}
+
+	switch {
+	case before == 0 && after > 0:
+		slog.Warn("Property switched to battery power", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name)
+		p.detector.ProcessPower(ctx, d, current, notifier.EventPowerOnBattery)
+	case before > 0 && after == 0:
+		slog.Info("Property is back on line power", "property_id", d.PropertyID, "device_id", d.ID, "device", d.Name)
+		p.detector.ProcessPower(ctx, d, current, notifier.EventPowerRestored)
+	}
+}
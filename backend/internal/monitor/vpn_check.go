@@ -0,0 +1,105 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/pfsense"
+)
+
+// vpnStatusTTL is how long a property's tunnel list is reused, so VPN devices on the
+// same firewall share one SSH session per round of checks
+const vpnStatusTTL = 30 * time.Second
+
+// vpnStatusCache holds the latest tunnel list per property
+type vpnStatusCache struct {
+	mu      sync.Mutex
+	entries map[int64]*vpnStatusEntry
+}
+
+type vpnStatusEntry struct {
+	mu      sync.Mutex
+	fetched time.Time
+	tunnels []pfsense.VPNTunnel
+	err     error
+}
+
+func newVPNStatusCache() *vpnStatusCache {
+	return &vpnStatusCache{entries: make(map[int64]*vpnStatusEntry)}
+}
+
+// get returns the property's tunnels, fetching them from pfSense if the cached copy
+// is stale. Concurrent callers for the same property wait for a single fetch.
+func (c *vpnStatusCache) get(ctx context.Context, property *models.Property) ([]pfsense.VPNTunnel, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[property.ID]
+	if !ok {
+		entry = &vpnStatusEntry{}
+		c.entries[property.ID] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if time.Since(entry.fetched) < vpnStatusTTL {
+		return entry.tunnels, entry.err
+	}
+
+	client := pfsense.NewClient(property.PfSenseHost, property.PfSensePort, property.PfSenseUsername, property.PfSensePassword)
+	entry.tunnels, entry.err = client.GetVPNStatus(ctx)
+	entry.fetched = time.Now()
+	return entry.tunnels, entry.err
+}
+
+// vpnCheckDevice reports a VPN pseudo-device online when the tunnel named by its
+// hostname ("ipsec:con1", "openvpn:client1") is up on the property's pfSense. The
+// firewall being unreachable counts as the tunnel being down.
+func (p *Pinger) vpnCheckDevice(ctx context.Context, device *models.Device) *models.DeviceStatus {
+	status := &models.DeviceStatus{
+		DeviceID:  device.ID,
+		LastCheck: time.Now(),
+		Status:    "offline",
+	}
+
+	kind, name, err := pfsense.ParseVPNKey(device.Hostname)
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+
+	property, err := p.postgres.GetProperty(ctx, device.PropertyID)
+	if err != nil {
+		status.Message = fmt.Sprintf("Failed to load property: %v", err)
+		return status
+	}
+	if property.PfSenseHost == "" || property.PfSenseUsername == "" || property.PfSensePassword == "" {
+		status.Message = "pfSense credentials not configured for this property"
+		return status
+	}
+
+	start := time.Now()
+	tunnels, err := p.vpnStatus.get(ctx, property)
+	if err != nil {
+		status.Message = fmt.Sprintf("Failed to read VPN status from pfSense: %v", err)
+		return status
+	}
+
+	for _, t := range tunnels {
+		if t.Type != kind || !strings.EqualFold(t.Name, name) {
+			continue
+		}
+		status.Message = t.Detail
+		if t.Up {
+			status.Status = "online"
+			status.ResponseTime = float64(time.Since(start).Milliseconds())
+		}
+		return status
+	}
+
+	status.Message = fmt.Sprintf("Tunnel %s not configured on pfSense", device.Hostname)
+	return status
+}
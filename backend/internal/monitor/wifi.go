@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/unifi"
+)
+
+const (
+	wifiPollInterval = time.Minute
+	// Snapshots outlive a few missed polls, then expire so stale telemetry isn't shown
+	wifiSnapshotTTL = 5 * time.Minute
+	wifiConcurrency = 8
+	// uniFiManufacturer is recorded on access points that don't have a manufacturer
+	uniFiManufacturer = "Ubiquiti"
+)
+
+// WiFiPoller polls each property's UniFi controller for access point status, client
+// counts and channel utilization, and stores the latest snapshot in Redis
+type WiFiPoller struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	stopChan chan struct{}
+}
+
+func NewWiFiPoller(postgres storage.Store, redis storage.StatusStore) *WiFiPoller {
+	return &WiFiPoller{
+		postgres: postgres,
+		redis:    redis,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (w *WiFiPoller) Start(ctx context.Context) error {
+	slog.Info("WiFi poller started")
+
+	ticker := time.NewTicker(wifiPollInterval)
+	defer ticker.Stop()
+
+	w.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.stopChan:
+			slog.Info("WiFi poller stopped")
+			return nil
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+func (w *WiFiPoller) Stop() {
+	close(w.stopChan)
+}
+
+func (w *WiFiPoller) poll(ctx context.Context) {
+	controllers, err := w.postgres.ListUniFiControllers(ctx)
+	if err != nil {
+		slog.Error("Failed to list UniFi controllers", "error", err)
+		return
+	}
+
+	sem := make(chan struct{}, wifiConcurrency)
+	var wg sync.WaitGroup
+	for i := range controllers {
+		controller := &controllers[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.pollController(ctx, controller)
+		}()
+	}
+	wg.Wait()
+}
+
+// pollController stores a snapshot for one property. A failed poll is stored with its
+// error so the API can tell an unreachable controller from one that was never polled.
+func (w *WiFiPoller) pollController(ctx context.Context, controller *models.UniFiController) {
+	snapshot := &models.WiFiSnapshot{
+		PropertyID:   controller.PropertyID,
+		CollectedAt:  time.Now(),
+		AccessPoints: []models.AccessPoint{},
+	}
+
+	client := unifi.NewClient(controller.URL, controller.Username, controller.Password, controller.Site, controller.VerifyTLS)
+	aps, err := client.GetAccessPoints(ctx)
+	if err != nil {
+		slog.Error("Failed to poll UniFi controller", "property_id", controller.PropertyID, "error", err)
+		snapshot.Error = err.Error()
+	} else {
+		if err := w.matchDevices(ctx, controller.PropertyID, aps); err != nil {
+			slog.Error("Failed to match access points", "property_id", controller.PropertyID, "error", err)
+		}
+		snapshot.AccessPoints = aps
+	}
+
+	if err := w.redis.SetWiFiSnapshot(ctx, snapshot, wifiSnapshotTTL); err != nil {
+		slog.Error("Failed to store WiFi snapshot", "property_id", controller.PropertyID, "error", err)
+	}
+}
+
+// matchDevices links access points to the property's registered devices by MAC and
+// records the firmware each access point reports on its device
+func (w *WiFiPoller) matchDevices(ctx context.Context, propertyID int64, aps []models.AccessPoint) error {
+	devices, err := w.postgres.ListDevicesForProperty(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+	byMAC := make(map[string]*models.Device)
+	for i := range devices {
+		if devices[i].MACAddress != "" {
+			byMAC[devices[i].MACAddress] = &devices[i]
+		}
+	}
+	for i := range aps {
+		if device, ok := byMAC[aps[i].MAC]; ok {
+			aps[i].DeviceID = &device.ID
+			aps[i].DeviceName = device.Name
+			recordFirmware(ctx, w.postgres, device, uniFiManufacturer, aps[i].Model, aps[i].Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// channelSecretFields are the config fields of each channel type that hold
+// credentials: a webhook URL or key that posts on the channel's behalf, or a password
+var channelSecretFields = map[string][]string{
+	"slack":     {"webhook_url"},
+	"email":     {"password"},
+	"pagerduty": {"routing_key"},
+	"sms":       {"auth_token"},
+}
+
+// RedactChannelConfig returns a channel config with its secret fields removed. A
+// config of a type without known fields is dropped whole.
+func RedactChannelConfig(channelType, config string) (string, error) {
+	fields, ok := channelSecretFields[channelType]
+	if !ok {
+		return "{}", nil
+	}
+	values, err := parseChannelConfig(config)
+	if err != nil {
+		return "", err
+	}
+	for _, field := range fields {
+		delete(values, field)
+	}
+	return marshalChannelConfig(values)
+}
+
+// MergeChannelSecrets fills the secret fields config leaves out or empty from stored,
+// the config already saved for the channel, so a redacted config can be written back
+// without losing credentials
+func MergeChannelSecrets(channelType, config, stored string) (string, error) {
+	values, err := parseChannelConfig(config)
+	if err != nil {
+		return "", err
+	}
+	current, err := parseChannelConfig(stored)
+	if err != nil {
+		return "", err
+	}
+	merged := false
+	for _, field := range channelSecretFields[channelType] {
+		if value, ok := values[field]; ok && string(value) != `""` && string(value) != "null" {
+			continue
+		}
+		if value, ok := current[field]; ok {
+			values[field] = value
+			merged = true
+		}
+	}
+	if !merged {
+		return config, nil
+	}
+	return marshalChannelConfig(values)
+}
+
+func parseChannelConfig(config string) (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+	if config == "" {
+		return values, nil
+	}
+	if err := json.Unmarshal([]byte(config), &values); err != nil {
+		return nil, fmt.Errorf("invalid channel config: %w", err)
+	}
+	if values == nil {
+		values = make(map[string]json.RawMessage)
+	}
+	return values, nil
+}
+
+func marshalChannelConfig(values map[string]json.RawMessage) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,44 @@
+package notifier
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRedactAndMergeChannelSecrets(t *testing.T) {
+	stored := `{"host":"smtp.example.com","port":587,"username":"noc","password":"smtp-secret","from":"noc@example.com","to":["ops@example.com"]}`
+
+	redacted, err := RedactChannelConfig("email", stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(redacted), &values); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := values["password"]; ok || values["host"] != "smtp.example.com" {
+		t.Fatalf("redacted config = %s", redacted)
+	}
+
+	merged, err := MergeChannelSecrets("email", redacted, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want map[string]any
+	json.Unmarshal([]byte(merged), &got)
+	json.Unmarshal([]byte(stored), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged config = %s, want %s", merged, stored)
+	}
+
+	// A secret given in the document replaces the stored one
+	replaced, err := MergeChannelSecrets("slack", `{"webhook_url":"https://hooks.example.com/new"}`, `{"webhook_url":"https://hooks.example.com/old"}`)
+	if err != nil || replaced != `{"webhook_url":"https://hooks.example.com/new"}` {
+		t.Fatalf("replaced config = %s, %v", replaced, err)
+	}
+
+	if redacted, _ := RedactChannelConfig("teams", `{"url":"https://example.com/secret"}`); redacted != "{}" {
+		t.Fatalf("unknown channel type kept its config: %s", redacted)
+	}
+}
@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// loadCircuits returns the property's ISP circuits likely behind a down event: those
+// whose WAN device is down, or, when every device at the property is down, also the
+// circuits not tied to a device
+func (n *Notifier) loadCircuits(ctx context.Context, propertyID int64, event *Event) ([]models.Circuit, error) {
+	circuits, err := n.postgres.ListCircuitsForProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	down := make(map[int64]bool, len(event.DownDevices))
+	for _, d := range event.DownDevices {
+		down[d.Device.ID] = true
+	}
+	allDown := event.Status.TotalCount > 0 && event.Status.OnlineCount == 0
+
+	var affected []models.Circuit
+	for _, circuit := range circuits {
+		if circuit.DeviceID != nil && down[*circuit.DeviceID] || circuit.DeviceID == nil && allDown {
+			affected = append(affected, circuit)
+		}
+	}
+	return affected, nil
+}
+
+// CircuitList describes the affected circuits on one line, e.g.
+// "Spectrum 12.KXFN.123456 (NOC 800-555-0100)"
+func (e *Event) CircuitList() string {
+	parts := make([]string, len(e.Circuits))
+	for i, circuit := range e.Circuits {
+		parts[i] = circuitLine(circuit)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// circuitLine names a circuit's provider with its circuit ID and support number when set
+func circuitLine(circuit models.Circuit) string {
+	line := circuit.Provider
+	if circuit.CircuitID != "" {
+		line += " " + circuit.CircuitID
+	}
+	if circuit.SupportPhone != "" {
+		line += fmt.Sprintf(" (NOC %s)", circuit.SupportPhone)
+	}
+	return line
+}
@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// alertMaxContacts caps the contacts sent with a property down alert
+const alertMaxContacts = 3
+
+// loadContacts returns the first contacts to call at a property, those with an
+// escalation order, lowest first
+func (n *Notifier) loadContacts(ctx context.Context, propertyID int64) ([]models.Contact, error) {
+	contacts, err := n.postgres.ListContactsForProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var escalation []models.Contact
+	for _, contact := range contacts {
+		if contact.EscalationOrder > 0 {
+			escalation = append(escalation, contact)
+		}
+	}
+	// Contacts come sorted by name, which breaks ties
+	sort.SliceStable(escalation, func(i, j int) bool {
+		return escalation[i].EscalationOrder < escalation[j].EscalationOrder
+	})
+	if len(escalation) > alertMaxContacts {
+		escalation = escalation[:alertMaxContacts]
+	}
+	return escalation, nil
+}
+
+// ContactList describes the contacts to call on one line, e.g.
+// "Jane Smith (GM, 555-0100), Joe Brown (Maintenance, 555-0101)"
+func (e *Event) ContactList() string {
+	parts := make([]string, len(e.Contacts))
+	for i, contact := range e.Contacts {
+		parts[i] = contactLine(contact)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// contactLine names a contact with their role and phone number when they have them
+func contactLine(contact models.Contact) string {
+	var details []string
+	for _, detail := range []string{contact.Role, contact.Phone} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) == 0 {
+		return contact.Name
+	}
+	return fmt.Sprintf("%s (%s)", contact.Name, strings.Join(details, ", "))
+}
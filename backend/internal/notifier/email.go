@@ -0,0 +1,293 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// EmailConfig is the JSON config stored on an email notification channel
+type EmailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	TLS      string   `json:"tls,omitempty"` // starttls (default), tls, none
+}
+
+var emailSubjectTemplates = map[string]*template.Template{
+	EventPropertyDown:     template.Must(template.New("down_subject").Parse(`[ETS NOC] DOWN: {{.Property.Name}}`)),
+	EventPropertyRecovery: template.Must(template.New("recovery_subject").Parse(`[ETS NOC] RECOVERED: {{.Property.Name}}`)),
+	EventTest:             template.Must(template.New("test_subject").Parse(`[ETS NOC] TEST: {{.Property.Name}}`)),
+	EventDeviceFlapping:   template.Must(template.New("flapping_subject").Parse(`[ETS NOC] FLAPPING: {{.Device.Name}} at {{.Property.Name}}`)),
+	EventPowerOnBattery:   template.Must(template.New("on_battery_subject").Parse(`[ETS NOC] ON BATTERY: {{.Property.Name}}`)),
+	EventPowerRestored:    template.Must(template.New("power_restored_subject").Parse(`[ETS NOC] POWER RESTORED: {{.Property.Name}}`)),
+	EventWorkerDown:       template.Must(template.New("worker_down_subject").Parse(`[ETS NOC] WORKERS DOWN`)),
+	EventWorkerRecovery:   template.Must(template.New("worker_recovery_subject").Parse(`[ETS NOC] WORKERS RECOVERED`)),
+}
+
+var emailBodyTemplates = map[string]*template.Template{
+	EventPropertyDown: template.Must(template.New("down_body").Parse(`Property {{.Property.Name}} is DOWN.
+
+Devices online:  {{.Status.OnlineCount}}
+Devices offline: {{.Status.OfflineCount}}
+Total devices:   {{.Status.TotalCount}}
+{{- if .Status.CriticalOffline}}
+
+A critical device is offline.
+{{- end}}
+{{- if .DownDevices}}
+
+Down devices:
+{{- range .DownDevices}}
+  - {{.Device.Name}} ({{.Device.DeviceType}}, {{.Device.Hostname}}) {{.Status.Status}} for {{.DownFor}}
+{{- end}}
+{{- end}}
+{{- if .Property.Address}}
+
+Address: {{.Property.Address}}
+{{- end}}
+{{- if .Circuits}}
+
+ISP circuits that may be down:
+{{- range .Circuits}}
+  - {{.Provider}}{{if .CircuitID}} circuit {{.CircuitID}}{{end}}{{if .SupportPhone}}, NOC {{.SupportPhone}}{{end}}{{if .AccountNumber}}, account {{.AccountNumber}}{{end}}
+{{- end}}
+{{- end}}
+{{- if .Contacts}}
+
+Contacts to call, in order:
+{{- range .Contacts}}
+  - {{.Name}}{{if .Role}} ({{.Role}}){{end}}{{if .Phone}}: {{.Phone}}{{end}}
+{{- end}}
+{{- end}}
+
+Detected at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventPropertyRecovery: template.Must(template.New("recovery_body").Parse(`Property {{.Property.Name}} has recovered.
+
+Devices online:  {{.Status.OnlineCount}}
+Devices offline: {{.Status.OfflineCount}}
+Total devices:   {{.Status.TotalCount}}
+{{- if .Property.Address}}
+
+Address: {{.Property.Address}}
+{{- end}}
+
+Recovered at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventTest: template.Must(template.New("test_body").Parse(`This is a test notification from {{.Property.Name}}.
+
+If you received it, this notification channel is configured correctly.
+
+Sent at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventDeviceFlapping: template.Must(template.New("flapping_body").Parse(`Device {{.Device.Name}} ({{.Device.Hostname}}) at {{.Property.Name}} is flapping: {{.Detail}}.
+
+Down and recovery alerts caused by this device are held until it stabilizes.
+{{- if .Property.Address}}
+
+Address: {{.Property.Address}}
+{{- end}}
+
+Detected at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventPowerOnBattery: template.Must(template.New("on_battery_body").Parse(`Property {{.Property.Name}} has switched to battery power, which usually means a building power outage.
+
+UPS {{.Device.Name}} ({{.Device.Hostname}}): {{.Detail}}
+{{- if .Property.Address}}
+
+Address: {{.Property.Address}}
+{{- end}}
+
+Detected at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventPowerRestored: template.Must(template.New("power_restored_body").Parse(`Property {{.Property.Name}} is back on line power.
+
+UPS {{.Device.Name}} ({{.Device.Hostname}}): {{.Detail}}
+{{- if .Property.Address}}
+
+Address: {{.Property.Address}}
+{{- end}}
+
+Restored at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventWorkerDown: template.Must(template.New("worker_down_body").Parse(`The NOC workers have stopped checking devices: {{.Detail}}.
+
+Device and property statuses on the dashboard are stale and no down alerts will be sent until a worker is running again.
+
+Detected at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+	EventWorkerRecovery: template.Must(template.New("worker_recovery_body").Parse(`The NOC workers are checking devices again: {{.Detail}}.
+
+Recovered at {{.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+`)),
+}
+
+// EmailSender delivers events over SMTP
+type EmailSender struct {
+	dialTimeout time.Duration
+}
+
+func NewEmailSender() *EmailSender {
+	return &EmailSender{
+		dialTimeout: 10 * time.Second,
+	}
+}
+
+func (s *EmailSender) Send(ctx context.Context, channel *models.NotificationChannel, event *Event) error {
+	var cfg EmailConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return fmt.Errorf("invalid email config: %w", err)
+	}
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("email config requires host, from and to")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+
+	subject, body, err := renderEmail(event)
+	if err != nil {
+		return err
+	}
+
+	return s.sendMail(ctx, &cfg, buildEmailMessage(&cfg, subject, body))
+}
+
+// SendMessage sends a plain text email through a channel's SMTP settings to the given
+// recipients instead of the channel's own list
+func (s *EmailSender) SendMessage(ctx context.Context, channel *models.NotificationChannel, to []string, subject, body string) error {
+	var cfg EmailConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return fmt.Errorf("invalid email config: %w", err)
+	}
+	if cfg.Host == "" || cfg.From == "" {
+		return fmt.Errorf("email config requires host and from")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no email recipients")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	cfg.To = to
+
+	return s.sendMail(ctx, &cfg, buildEmailMessage(&cfg, subject, body))
+}
+
+func renderEmail(event *Event) (string, string, error) {
+	subjectTmpl, ok := emailSubjectTemplates[event.Type]
+	if !ok {
+		return "", "", fmt.Errorf("no email template for event type %s", event.Type)
+	}
+	bodyTmpl := emailBodyTemplates[event.Type]
+
+	var subject, body bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, event); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := bodyTmpl.Execute(&body, event); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return subject.String(), body.String(), nil
+}
+
+// headerReplacer drops line breaks from header values, so a property or device name
+// can't end the header and start another
+var headerReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+func buildEmailMessage(cfg *EmailConfig, subject, body string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", headerReplacer.Replace(cfg.From))
+	fmt.Fprintf(&msg, "To: %s\r\n", headerReplacer.Replace(strings.Join(cfg.To, ", ")))
+	// Encoded so names outside ASCII make a valid header; plain ASCII is left as is
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerReplacer.Replace(subject)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+func (s *EmailSender) sendMail(ctx context.Context, cfg *EmailConfig, msg []byte) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{Timeout: s.dialTimeout}
+
+	var conn net.Conn
+	var err error
+	if cfg.TLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer client.Close()
+
+	if cfg.TLS == "" || cfg.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP auth failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildEmailMessageHeaders(t *testing.T) {
+	cfg := &EmailConfig{From: "noc@example.com", To: []string{"ops@example.com"}}
+
+	msg := string(buildEmailMessage(cfg, "Lobby\r\nBcc: x@evil\r\n\r\nforged", "body"))
+	headers, _, _ := strings.Cut(msg, "\r\n\r\n")
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Fatalf("subject started a new header:\n%s", headers)
+	}
+	if !strings.Contains(headers, "Subject: Lobby Bcc: x@evil  forged\r\n") {
+		t.Fatalf("subject wasn't kept on one line:\n%s", headers)
+	}
+
+	msg = string(buildEmailMessage(cfg, "Café down", "body"))
+	if !strings.Contains(msg, "Subject: =?utf-8?q?Caf=C3=A9_down?=\r\n") {
+		t.Fatalf("non-ASCII subject wasn't encoded:\n%s", msg)
+	}
+}
@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// NotifyFlapping sends a single flapping notice for a device to the channels that would
+// receive its property's down alerts. PagerDuty is skipped since there is no recovery
+// to resolve the incident it would open.
+func (n *Notifier) NotifyFlapping(ctx context.Context, device *models.Device, changes int, window time.Duration) error {
+	property, err := n.postgres.GetProperty(ctx, device.PropertyID)
+	if err != nil {
+		return err
+	}
+
+	status, err := n.redis.GetPropertyStatus(ctx, property.ID)
+	if err != nil {
+		status = &models.PropertyStatus{PropertyID: property.ID}
+	}
+
+	links, err := n.postgres.ListPropertyNotifications(ctx, property.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list property notifications: %w", err)
+	}
+
+	event := &Event{
+		Type:      EventDeviceFlapping,
+		Property:  property,
+		Status:    status,
+		Timestamp: time.Now(),
+		Device:    device,
+		Detail:    fmt.Sprintf("%d state changes in %s", changes, window),
+	}
+
+	var channelIDs []int64
+	for i := range links {
+		link := &links[i]
+		if link.Enabled && link.NotifyOnRed && !inQuietHours(link, event) {
+			channelIDs = append(channelIDs, link.NotificationChannelID)
+		}
+	}
+
+	rules, err := n.postgres.ListNotificationRulesForProperty(ctx, property.ID)
+	if err != nil {
+		slog.Error("Failed to load notification rules", "property_id", property.ID, "error", err)
+	} else {
+		routed, _ := matchRules(rules, property.Tags, event.Severity(), []models.Device{*device})
+		channelIDs = append(channelIDs, routed...)
+	}
+
+	for _, channel := range n.enabledChannels(ctx, channelIDs) {
+		if channel.Type == "pagerduty" {
+			continue
+		}
+		n.deliver(ctx, channel, event)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// slackMaxDevices caps the devices listed in a Slack alert; email lists them all
+const slackMaxDevices = 15
+
+// DownDevice is an offline or unreachable device listed in a property down alert
+type DownDevice struct {
+	Device *models.Device
+	Status *models.DeviceStatus
+	// Duration is how long the device had been down when the alert was built
+	Duration time.Duration
+}
+
+// DownFor returns the down duration rounded for display
+func (d DownDevice) DownFor() string {
+	if d.Duration <= 0 {
+		return "unknown"
+	}
+	if d.Duration < time.Minute {
+		return d.Duration.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(d.Duration.Round(time.Minute).String(), "0s")
+}
+
+// loadDownDevices returns the property's active devices that are offline or
+// unreachable after confirmation, longest down first
+func (n *Notifier) loadDownDevices(ctx context.Context, propertyID int64, at time.Time) ([]DownDevice, error) {
+	devices, err := n.postgres.ListDevicesForProperty(ctx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var down []DownDevice
+	for i := range devices {
+		d := &devices[i]
+		if !d.Active {
+			continue
+		}
+		status, err := n.redis.GetDeviceStatus(ctx, d.ID)
+		if err != nil || status.Status == "online" || status.StateType == "soft" {
+			continue
+		}
+		entry := DownDevice{Device: d, Status: status}
+		if !status.Since.IsZero() {
+			entry.Duration = at.Sub(status.Since)
+		}
+		down = append(down, entry)
+	}
+
+	sort.SliceStable(down, func(i, j int) bool {
+		return down[i].Duration > down[j].Duration
+	})
+	return down, nil
+}
+
+// DeviceList describes up to max down devices on one line, e.g.
+// "Lobby AP (wap, 12m), Core Router (router, 14m) and 3 more"
+func (e *Event) DeviceList(max int) string {
+	if len(e.DownDevices) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for i, d := range e.DownDevices {
+		if i == max {
+			break
+		}
+		detail := d.DownFor()
+		if d.Device.DeviceType != "" {
+			detail = d.Device.DeviceType + ", " + detail
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", d.Device.Name, detail))
+	}
+
+	list := strings.Join(parts, ", ")
+	if extra := len(e.DownDevices) - len(parts); extra > 0 {
+		list += fmt.Sprintf(" and %d more", extra)
+	}
+	return list
+}
+
+// downModels returns the down devices for rule matching
+func (e *Event) downModels() []models.Device {
+	devices := make([]models.Device, len(e.DownDevices))
+	for i, d := range e.DownDevices {
+		devices[i] = *d.Device
+	}
+	return devices
+}
@@ -0,0 +1,296 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Event types recorded in notification_events
+const (
+	EventPropertyDown     = "property_down"
+	EventPropertyRecovery = "property_recovery"
+	EventDeviceFlapping   = "device_flapping"
+	EventPowerOnBattery   = "power_on_battery"
+	EventPowerRestored    = "power_restored"
+	EventTest             = "test"
+	EventWorkerDown       = "worker_down"
+	EventWorkerRecovery   = "worker_recovery"
+)
+
+// Event describes a property status transition to be delivered to notification channels
+type Event struct {
+	Type      string
+	Property  *models.Property
+	Status    *models.PropertyStatus
+	Timestamp time.Time
+	// IncidentKey identifies the outage; a down event and the recovery that ends it
+	// share the same key
+	IncidentKey string
+	// DownDevices lists the devices behind a down event
+	DownDevices []DownDevice
+	// Contacts are who to call at the property about a down event, in escalation order
+	Contacts []models.Contact
+	// Circuits are the ISP circuits likely behind a down event
+	Circuits []models.Circuit
+	// Device and Detail are set for device events such as flapping and power changes
+	Device *models.Device
+	Detail string
+}
+
+// Sender delivers an event to a single notification channel
+type Sender interface {
+	Send(ctx context.Context, channel *models.NotificationChannel, event *Event) error
+}
+
+// eventFilter is implemented by senders that only deliver some event types on a channel
+type eventFilter interface {
+	Accepts(channel *models.NotificationChannel, event *Event) bool
+}
+
+type Notifier struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	senders  map[string]Sender
+}
+
+func NewNotifier(postgres storage.Store, redis storage.StatusStore) *Notifier {
+	return &Notifier{
+		postgres: postgres,
+		redis:    redis,
+		senders: map[string]Sender{
+			"slack":     NewSlackSender(),
+			"email":     NewEmailSender(),
+			"pagerduty": NewPagerDutySender(),
+			"sms":       NewSMSSender(redis),
+		},
+	}
+}
+
+// Notify sends a property event to every channel linked to the property, honoring
+// the per-link notify flags, and to any channels selected by notification rules. Each
+// channel gets one message per event; down events list every down device. Cooldown is
+// enforced by the caller; the notification time is recorded here once at least one
+// channel was attempted.
+func (n *Notifier) Notify(ctx context.Context, propertyID int64, eventType string, status *models.PropertyStatus) error {
+	property, err := n.postgres.GetProperty(ctx, propertyID)
+	if err != nil {
+		return err
+	}
+
+	links, err := n.postgres.ListPropertyNotifications(ctx, propertyID)
+	if err != nil {
+		return fmt.Errorf("failed to list property notifications: %w", err)
+	}
+
+	event := &Event{
+		Type:        eventType,
+		Property:    property,
+		Status:      status,
+		Timestamp:   time.Now(),
+		IncidentKey: n.incidentKey(ctx, propertyID, eventType, status),
+	}
+
+	if eventType == EventPropertyDown {
+		event.DownDevices, err = n.loadDownDevices(ctx, propertyID, event.Timestamp)
+		if err != nil {
+			slog.Error("Failed to load down devices", "property_id", propertyID, "error", err)
+		}
+		event.Contacts, err = n.loadContacts(ctx, propertyID)
+		if err != nil {
+			slog.Error("Failed to load escalation contacts", "property_id", propertyID, "error", err)
+		}
+		event.Circuits, err = n.loadCircuits(ctx, propertyID, event)
+		if err != nil {
+			slog.Error("Failed to load ISP circuits", "property_id", propertyID, "error", err)
+		}
+	}
+
+	sent := make(map[int64]bool)
+	for _, link := range links {
+		if !link.Enabled {
+			continue
+		}
+		if eventType == EventPropertyDown && !link.NotifyOnRed {
+			continue
+		}
+
+		channel, err := n.postgres.GetNotificationChannel(ctx, link.NotificationChannelID)
+		if err != nil {
+			slog.Error("Failed to load notification channel", "channel_id", link.NotificationChannelID, "error", err)
+			continue
+		}
+		if !channel.Enabled {
+			continue
+		}
+		// PagerDuty always gets the recovery so the incident it opened is resolved
+		if eventType == EventPropertyRecovery && !link.NotifyOnRecovery && channel.Type != "pagerduty" {
+			continue
+		}
+		// PagerDuty still gets recoveries so an incident opened before quiet hours resolves
+		if inQuietHours(&link, event) && !(eventType == EventPropertyRecovery && channel.Type == "pagerduty") {
+			slog.Info("Holding notification for quiet hours", "property_id", propertyID, "event", eventType, "channel", channel.Name)
+			continue
+		}
+
+		n.deliver(ctx, channel, event)
+		sent[channel.ID] = true
+	}
+
+	for _, channel := range n.routedChannels(ctx, event) {
+		if sent[channel.ID] {
+			continue
+		}
+		n.deliver(ctx, channel, event)
+		sent[channel.ID] = true
+	}
+
+	if eventType == EventPropertyRecovery {
+		if err := n.redis.ClearPropertyIncident(ctx, propertyID); err != nil {
+			slog.Error("Failed to clear incident", "property_id", propertyID, "error", err)
+		}
+	}
+
+	if len(sent) == 0 {
+		return nil
+	}
+
+	return n.redis.SetLastNotification(ctx, propertyID, eventType)
+}
+
+// incidentKey opens a new incident on a down event, keyed by property and the time it
+// went red, and looks up the open incident on recovery
+func (n *Notifier) incidentKey(ctx context.Context, propertyID int64, eventType string, status *models.PropertyStatus) string {
+	if eventType == EventPropertyDown {
+		since := status.Since
+		if since.IsZero() {
+			since = status.LastCheck
+		}
+		key := fmt.Sprintf("property-%d-%d", propertyID, since.Unix())
+		if err := n.redis.SetPropertyIncident(ctx, propertyID, key); err != nil {
+			slog.Error("Failed to store incident", "property_id", propertyID, "error", err)
+		}
+		return key
+	}
+
+	key, err := n.redis.GetPropertyIncident(ctx, propertyID)
+	if err != nil {
+		slog.Error("Failed to load incident", "property_id", propertyID, "error", err)
+	}
+	if key == "" {
+		key = fmt.Sprintf("property-%d", propertyID)
+	}
+	return key
+}
+
+// deliver sends an event through a channel and records the outcome in notification_events.
+// Failed sends are queued for retry. Events the channel's sender filters out are
+// skipped without a record.
+func (n *Notifier) deliver(ctx context.Context, channel *models.NotificationChannel, event *Event) {
+	if !n.accepts(channel, event) {
+		return
+	}
+	n.deliverAttempt(ctx, channel, event, 1)
+}
+
+func (n *Notifier) deliverAttempt(ctx context.Context, channel *models.NotificationChannel, event *Event, attempt int) {
+	record := &models.NotificationEvent{
+		PropertyID:            event.Property.ID,
+		NotificationChannelID: channel.ID,
+		EventType:             event.Type,
+		Message:               event.Summary(),
+	}
+
+	if err := n.send(ctx, channel, event); err != nil {
+		record.Error = err.Error()
+		if isPermanent(err) {
+			record.Error += " (not retried)"
+		} else if attempt < retryMaxAttempts {
+			delay := retryDelay(attempt)
+			if err := n.scheduleRetry(ctx, channel.ID, event, attempt, delay); err != nil {
+				slog.Error("Failed to queue notification retry", "property_id", event.Property.ID, "channel", channel.Name, "error", err)
+			} else {
+				record.Error += fmt.Sprintf(" (attempt %d of %d, retrying in %s)", attempt, retryMaxAttempts, delay)
+			}
+		} else if attempt > 1 {
+			record.Error += fmt.Sprintf(" (gave up after %d attempts)", attempt)
+		}
+	} else {
+		record.Success = true
+	}
+
+	if !record.Success {
+		slog.Error("Failed to send notification", "property_id", event.Property.ID, "event", event.Type, "channel", channel.Name, "error", record.Error)
+	}
+
+	if err := n.postgres.CreateNotificationEvent(ctx, record); err != nil {
+		slog.Error("Failed to record notification event", "property_id", event.Property.ID, "error", err)
+	}
+}
+
+// send routes a channel to the current on-call user if configured and hands the event
+// to the sender for its type
+func (n *Notifier) send(ctx context.Context, channel *models.NotificationChannel, event *Event) error {
+	sender, ok := n.senders[channel.Type]
+	if !ok {
+		return fmt.Errorf("unsupported channel type: %s", channel.Type)
+	}
+
+	routed, err := n.withOnCallRecipients(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	return sender.Send(ctx, routed, event)
+}
+
+// accepts reports whether the channel's sender takes this type of event
+func (n *Notifier) accepts(channel *models.NotificationChannel, event *Event) bool {
+	filter, ok := n.senders[channel.Type].(eventFilter)
+	return !ok || filter.Accepts(channel, event)
+}
+
+// SendEmail sends a plain text message to the given addresses using an email
+// channel's SMTP settings, for reports that aren't tied to a property event
+func (n *Notifier) SendEmail(ctx context.Context, channel *models.NotificationChannel, to []string, subject, body string) error {
+	sender, ok := n.senders["email"].(*EmailSender)
+	if !ok || channel.Type != "email" {
+		return fmt.Errorf("channel %s is not an email channel", channel.Name)
+	}
+	return sender.SendMessage(ctx, channel, to, subject, body)
+}
+
+// Summary returns a one-line plain text description of the event
+func (e *Event) Summary() string {
+	switch e.Type {
+	case EventPropertyDown:
+		msg := fmt.Sprintf("%s is DOWN: %d of %d devices offline",
+			e.Property.Name, e.Status.OfflineCount, e.Status.TotalCount)
+		if e.Status.CriticalOffline {
+			msg += " (critical device offline)"
+		}
+		return msg
+	case EventPropertyRecovery:
+		return fmt.Sprintf("%s has recovered: %d of %d devices online",
+			e.Property.Name, e.Status.OnlineCount, e.Status.TotalCount)
+	case EventDeviceFlapping:
+		return fmt.Sprintf("%s at %s is flapping (%s); alerts are held until it stabilizes",
+			e.Device.Name, e.Property.Name, e.Detail)
+	case EventPowerOnBattery:
+		return fmt.Sprintf("%s is on BATTERY power: %s reports %s", e.Property.Name, e.Device.Name, e.Detail)
+	case EventPowerRestored:
+		return fmt.Sprintf("%s is back on line power: %s reports %s", e.Property.Name, e.Device.Name, e.Detail)
+	case EventTest:
+		return fmt.Sprintf("Test notification from %s; this channel is working", e.Property.Name)
+	case EventWorkerDown:
+		return fmt.Sprintf("%s workers have STOPPED checking devices: %s", e.Property.Name, e.Detail)
+	case EventWorkerRecovery:
+		return fmt.Sprintf("%s workers are checking devices again: %s", e.Property.Name, e.Detail)
+	default:
+		return fmt.Sprintf("%s: %s", e.Property.Name, e.Type)
+	}
+}
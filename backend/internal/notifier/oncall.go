@@ -0,0 +1,51 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/oncall"
+)
+
+// withOnCallRecipients returns the channel with its "to" list replaced by whoever is
+// currently on call, when an email or sms channel config sets oncall_schedule_id.
+// Other channels are returned unchanged.
+func (n *Notifier) withOnCallRecipients(ctx context.Context, channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	if channel.Type != "email" && channel.Type != "sms" {
+		return channel, nil
+	}
+
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", channel.Type, err)
+	}
+	scheduleID, ok := cfg["oncall_schedule_id"].(float64)
+	if !ok || scheduleID <= 0 {
+		return channel, nil
+	}
+
+	shift, err := oncall.Resolve(ctx, n.postgres, int64(scheduleID), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve on-call user: %w", err)
+	}
+
+	recipient := shift.User.Email
+	if channel.Type == "sms" {
+		recipient = shift.User.Phone
+	}
+	if recipient == "" {
+		return nil, fmt.Errorf("on-call user %s has no %s contact", shift.User.Username, channel.Type)
+	}
+	cfg["to"] = []string{recipient}
+
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	routed := *channel
+	routed.Config = string(config)
+	return &routed, nil
+}
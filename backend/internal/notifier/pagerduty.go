@@ -0,0 +1,187 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyConfig is the JSON config stored on a pagerduty notification channel
+type PagerDutyConfig struct {
+	RoutingKey string `json:"routing_key"`        // Events API v2 integration key
+	Severity   string `json:"severity,omitempty"` // critical (default), error, warning or info
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // trigger, resolve
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// PagerDutySender triggers a PagerDuty incident when a property goes down and
+// resolves it on recovery, using the event's incident key as the dedup key
+type PagerDutySender struct {
+	httpClient *http.Client
+	eventsURL  string
+}
+
+func NewPagerDutySender() *PagerDutySender {
+	return &PagerDutySender{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		eventsURL:  pagerDutyEventsURL,
+	}
+}
+
+func (p *PagerDutySender) Send(ctx context.Context, channel *models.NotificationChannel, event *Event) error {
+	var cfg PagerDutyConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return fmt.Errorf("invalid pagerduty config: %w", err)
+	}
+	if cfg.RoutingKey == "" {
+		return fmt.Errorf("pagerduty config missing routing_key")
+	}
+
+	pdEvent, err := buildPagerDutyEvent(&cfg, event)
+	if err != nil {
+		return err
+	}
+	if err := p.post(ctx, pdEvent); err != nil {
+		return err
+	}
+
+	// Close the test incident straight away so nobody gets paged twice
+	if event.Type == EventTest {
+		return p.post(ctx, &pagerDutyEvent{
+			RoutingKey:  pdEvent.RoutingKey,
+			EventAction: "resolve",
+			DedupKey:    pdEvent.DedupKey,
+		})
+	}
+	return nil
+}
+
+func (p *PagerDutySender) post(ctx context.Context, pdEvent *pagerDutyEvent) error {
+	payload, err := json.Marshal(pdEvent)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.eventsURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to pagerduty: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pagerduty returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func buildPagerDutyEvent(cfg *PagerDutyConfig, event *Event) (*pagerDutyEvent, error) {
+	pdEvent := &pagerDutyEvent{
+		RoutingKey: cfg.RoutingKey,
+		DedupKey:   "ets-noc-" + event.IncidentKey,
+	}
+
+	switch event.Type {
+	case EventPropertyDown:
+		severity := cfg.Severity
+		if severity == "" {
+			severity = "critical"
+		}
+		pdEvent.EventAction = "trigger"
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:   event.Summary(),
+			Source:    event.Property.Name,
+			Severity:  severity,
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+			Component: "property",
+			Group:     event.Property.Address,
+			CustomDetails: map[string]interface{}{
+				"property_id":      event.Property.ID,
+				"online_count":     event.Status.OnlineCount,
+				"offline_count":    event.Status.OfflineCount,
+				"total_count":      event.Status.TotalCount,
+				"critical_offline": event.Status.CriticalOffline,
+				"down_devices":     event.DeviceList(len(event.DownDevices)),
+				"contacts":         event.ContactList(),
+				"circuits":         event.CircuitList(),
+			},
+		}
+	case EventPropertyRecovery:
+		pdEvent.EventAction = "resolve"
+	case EventPowerOnBattery:
+		pdEvent.EventAction = "trigger"
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:   event.Summary(),
+			Source:    event.Property.Name,
+			Severity:  "warning",
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+			Component: "power",
+			Group:     event.Property.Address,
+			CustomDetails: map[string]interface{}{
+				"property_id": event.Property.ID,
+				"device_id":   event.Device.ID,
+				"ups":         event.Device.Name,
+				"detail":      event.Detail,
+			},
+		}
+	case EventPowerRestored:
+		pdEvent.EventAction = "resolve"
+	case EventWorkerDown:
+		pdEvent.EventAction = "trigger"
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:   event.Summary(),
+			Source:    "ets-noc",
+			Severity:  "critical",
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+			Component: "worker",
+			CustomDetails: map[string]interface{}{
+				"detail": event.Detail,
+			},
+		}
+	case EventWorkerRecovery:
+		pdEvent.EventAction = "resolve"
+	case EventTest:
+		pdEvent.EventAction = "trigger"
+		pdEvent.Payload = &pagerDutyPayload{
+			Summary:   event.Summary(),
+			Source:    "ets-noc",
+			Severity:  "info",
+			Timestamp: event.Timestamp.Format(time.RFC3339),
+			Component: "test",
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event type for pagerduty: %s", event.Type)
+	}
+
+	return pdEvent, nil
+}
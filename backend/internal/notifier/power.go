@@ -0,0 +1,79 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// NotifyPower sends a property's on-battery or power-restored notice, reported by the
+// UPS device that changed. On-battery notices go to the channels that receive the
+// property's down alerts and restored notices to those that receive its recoveries.
+// PagerDuty opens an incident on battery and always gets the restore that resolves it.
+func (n *Notifier) NotifyPower(ctx context.Context, device *models.Device, eventType, detail string) error {
+	property, err := n.postgres.GetProperty(ctx, device.PropertyID)
+	if err != nil {
+		return err
+	}
+
+	status, err := n.redis.GetPropertyStatus(ctx, property.ID)
+	if err != nil {
+		status = &models.PropertyStatus{PropertyID: property.ID}
+	}
+
+	links, err := n.postgres.ListPropertyNotifications(ctx, property.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list property notifications: %w", err)
+	}
+
+	event := &Event{
+		Type:        eventType,
+		Property:    property,
+		Status:      status,
+		Timestamp:   time.Now(),
+		IncidentKey: fmt.Sprintf("property-%d-power", property.ID),
+		Device:      device,
+		Detail:      detail,
+	}
+
+	var channelIDs []int64
+	for i := range links {
+		link := &links[i]
+		if !link.Enabled {
+			continue
+		}
+		if eventType == EventPowerOnBattery && !link.NotifyOnRed {
+			continue
+		}
+		channelIDs = append(channelIDs, link.NotificationChannelID)
+	}
+
+	rules, err := n.postgres.ListNotificationRulesForProperty(ctx, property.ID)
+	if err != nil {
+		slog.Error("Failed to load notification rules", "property_id", property.ID, "error", err)
+	} else {
+		routed, _ := matchRules(rules, property.Tags, event.Severity(), []models.Device{*device})
+		channelIDs = append(channelIDs, routed...)
+	}
+
+	linkByChannel := make(map[int64]*models.PropertyNotification, len(links))
+	for i := range links {
+		linkByChannel[links[i].NotificationChannelID] = &links[i]
+	}
+
+	for _, channel := range n.enabledChannels(ctx, channelIDs) {
+		link := linkByChannel[channel.ID]
+		if eventType == EventPowerRestored && channel.Type != "pagerduty" && link != nil && !link.NotifyOnRecovery {
+			continue
+		}
+		if link != nil && inQuietHours(link, event) && !(eventType == EventPowerRestored && channel.Type == "pagerduty") {
+			slog.Info("Holding notification for quiet hours", "property_id", property.ID, "event", eventType, "channel", channel.Name)
+			continue
+		}
+		n.deliver(ctx, channel, event)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+const clockLayout = "15:04"
+
+// ValidateActiveHours checks a link's active hours. Both ends must be set or both
+// empty, and the timezone must be known; an empty timezone is treated as UTC.
+func ValidateActiveHours(from, to, timezone string) error {
+	if (from == "") != (to == "") {
+		return fmt.Errorf("active_from and active_to must both be set or both be empty")
+	}
+	if from != "" {
+		if _, err := time.Parse(clockLayout, from); err != nil {
+			return fmt.Errorf("active_from must be HH:MM")
+		}
+		if _, err := time.Parse(clockLayout, to); err != nil {
+			return fmt.Errorf("active_to must be HH:MM")
+		}
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", timezone)
+		}
+	}
+	return nil
+}
+
+// withinActiveHours reports whether t falls inside the link's active hours. Windows
+// may cross midnight (22:00-06:00); a link without hours, or with an unparseable
+// configuration, is always active.
+func withinActiveHours(link *models.PropertyNotification, t time.Time) bool {
+	if link.ActiveFrom == "" || link.ActiveTo == "" || link.ActiveFrom == link.ActiveTo {
+		return true
+	}
+	from, err := time.Parse(clockLayout, link.ActiveFrom)
+	if err != nil {
+		return true
+	}
+	to, err := time.Parse(clockLayout, link.ActiveTo)
+	if err != nil {
+		return true
+	}
+
+	loc := time.UTC
+	if link.Timezone != "" {
+		if l, err := time.LoadLocation(link.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	start := from.Hour()*60 + from.Minute()
+	end := to.Hour()*60 + to.Minute()
+
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// inQuietHours reports whether a link's quiet hours hold back an event. Critical
+// alerts pass when the link allows it.
+func inQuietHours(link *models.PropertyNotification, event *Event) bool {
+	if withinActiveHours(link, event.Timestamp) {
+		return false
+	}
+	return !(link.BypassCritical && event.Severity() == SeverityCritical)
+}
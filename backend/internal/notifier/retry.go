@@ -0,0 +1,160 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	retryMaxAttempts  = 5
+	retryBaseDelay    = 30 * time.Second
+	retryMaxDelay     = 30 * time.Minute
+	retryPollInterval = 15 * time.Second
+	retryBatchSize    = 50
+)
+
+// retryJob is a failed delivery waiting in the Redis retry queue
+type retryJob struct {
+	ChannelID int64  `json:"channel_id"`
+	Attempt   int    `json:"attempt"` // attempts made so far
+	Event     *Event `json:"event"`
+	QueuedAt  int64  `json:"queued_at"` // keeps otherwise identical jobs distinct in the queue
+}
+
+// permanentError marks a send failure that retrying won't fix, such as a channel
+// that is over its rate limit
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// retryDelay doubles the wait after each failed attempt, up to retryMaxDelay
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+func (n *Notifier) scheduleRetry(ctx context.Context, channelID int64, event *Event, attempt int, delay time.Duration) error {
+	// The queue is outside the database's encryption at rest, so the property's
+	// decrypted pfSense password is left out; no sender uses it
+	queued := *event
+	if event.Property != nil {
+		property := *event.Property
+		property.PfSensePassword = ""
+		queued.Property = &property
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(&retryJob{
+		ChannelID: channelID,
+		Attempt:   attempt,
+		Event:     &queued,
+		QueuedAt:  now.UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	return n.redis.ScheduleNotificationRetry(ctx, string(payload), now.Add(delay))
+}
+
+// processRetries redelivers every due retry. Jobs whose channel was deleted or
+// disabled in the meantime, and down alerts for outages that have ended, are dropped.
+func (n *Notifier) processRetries(ctx context.Context) {
+	for {
+		payloads, err := n.redis.ClaimDueNotificationRetries(ctx, time.Now(), retryBatchSize)
+		if err != nil {
+			slog.Error("Failed to claim notification retries", "error", err)
+		}
+
+		for _, payload := range payloads {
+			var job retryJob
+			if err := json.Unmarshal([]byte(payload), &job); err != nil || job.Event == nil || job.Event.Property == nil {
+				slog.Warn("Dropping malformed notification retry", "error", err)
+				continue
+			}
+
+			if n.staleRetry(ctx, job.Event) {
+				slog.Info("Dropping notification retry, incident has ended", "property_id", job.Event.Property.ID, "event", job.Event.Type)
+				continue
+			}
+
+			channel, err := n.postgres.GetNotificationChannel(ctx, job.ChannelID)
+			if err != nil || !channel.Enabled {
+				slog.Warn("Dropping notification retry, channel unavailable", "channel_id", job.ChannelID)
+				continue
+			}
+
+			n.deliverAttempt(ctx, channel, job.Event, job.Attempt+1)
+		}
+
+		if err != nil || len(payloads) < retryBatchSize {
+			return
+		}
+	}
+}
+
+// staleRetry reports whether a down event belongs to an incident that is no longer open
+func (n *Notifier) staleRetry(ctx context.Context, event *Event) bool {
+	if event.Type != EventPropertyDown {
+		return false
+	}
+	current, err := n.redis.GetPropertyIncident(ctx, event.Property.ID)
+	return err == nil && current != event.IncidentKey
+}
+
+// RetryQueue redelivers failed notifications from the Redis retry queue with
+// exponential backoff
+type RetryQueue struct {
+	notifier *Notifier
+	stopChan chan struct{}
+}
+
+func NewRetryQueue(notifier *Notifier) *RetryQueue {
+	return &RetryQueue{
+		notifier: notifier,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (q *RetryQueue) Start(ctx context.Context) error {
+	slog.Info("Notification retry queue started")
+
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-q.stopChan:
+			slog.Info("Notification retry queue stopped")
+			return nil
+		case <-ticker.C:
+			q.notifier.processRetries(ctx)
+		}
+	}
+}
+
+func (q *RetryQueue) Stop() {
+	close(q.stopChan)
+}
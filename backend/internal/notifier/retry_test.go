@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+func TestScheduleRetryLeavesOutPfSensePassword(t *testing.T) {
+	ctx := context.Background()
+	redis := storage.NewMemoryStore()
+	defer redis.Close()
+	n := NewNotifier(nil, redis)
+
+	property := &models.Property{ID: 7, Name: "Harbor Inn", PfSensePassword: "router-secret"}
+	event := &Event{Type: EventPropertyDown, Property: property}
+	if err := n.scheduleRetry(ctx, 1, event, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	payloads, err := redis.ClaimDueNotificationRetries(ctx, time.Now().Add(time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 1 {
+		t.Fatalf("%d retries queued, want 1", len(payloads))
+	}
+	if strings.Contains(payloads[0], "router-secret") {
+		t.Fatalf("retry job carries the pfSense password: %s", payloads[0])
+	}
+	if property.PfSensePassword != "router-secret" {
+		t.Fatal("scheduling a retry cleared the caller's property password")
+	}
+}
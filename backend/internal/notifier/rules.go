@@ -0,0 +1,152 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Severity levels matched by notification rules
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// Severity is critical when a critical device is offline, or for device events when
+// the device is critical, and warning otherwise
+func (e *Event) Severity() string {
+	if e.Device != nil {
+		if e.Device.IsCritical {
+			return SeverityCritical
+		}
+		return SeverityWarning
+	}
+	if e.Status != nil && e.Status.CriticalOffline {
+		return SeverityCritical
+	}
+	return SeverityWarning
+}
+
+// routedChannels returns the channels selected by notification rules for an event.
+// Down events evaluate the rules against the event's down devices and remember
+// which channels should hear the recovery; recovery events go to those channels.
+func (n *Notifier) routedChannels(ctx context.Context, event *Event) []*models.NotificationChannel {
+	propertyID := event.Property.ID
+
+	if event.Type == EventPropertyRecovery {
+		ids, err := n.redis.GetIncidentChannels(ctx, propertyID)
+		if err != nil {
+			slog.Error("Failed to load incident channels", "property_id", propertyID, "error", err)
+			return nil
+		}
+		return n.enabledChannels(ctx, ids)
+	}
+
+	rules, err := n.postgres.ListNotificationRulesForProperty(ctx, propertyID)
+	if err != nil {
+		slog.Error("Failed to load notification rules", "property_id", propertyID, "error", err)
+		return nil
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	channelIDs, recoveryIDs := matchRules(rules, event.Property.Tags, event.Severity(), event.downModels())
+	channels := n.enabledChannels(ctx, channelIDs)
+	// PagerDuty always gets the recovery so the incident it opened is resolved
+	for _, channel := range channels {
+		if channel.Type == "pagerduty" {
+			recoveryIDs = append(recoveryIDs, channel.ID)
+		}
+	}
+	if err := n.redis.AddIncidentChannels(ctx, propertyID, recoveryIDs); err != nil {
+		slog.Error("Failed to store incident channels", "property_id", propertyID, "error", err)
+	}
+
+	return channels
+}
+
+// matchRules evaluates rules in order for a property with the given tags and returns
+// the channels they select and the subset that should also hear the recovery
+func matchRules(rules []models.NotificationRule, propertyTags []string, severity string, down []models.Device) (channelIDs, recoveryIDs []int64) {
+	for i := range rules {
+		rule := &rules[i]
+		if !ruleMatches(rule, propertyTags, severity, down) {
+			continue
+		}
+		channelIDs = append(channelIDs, rule.NotificationChannelID)
+		if rule.NotifyOnRecovery {
+			recoveryIDs = append(recoveryIDs, rule.NotificationChannelID)
+		}
+		if rule.StopProcessing {
+			break
+		}
+	}
+	return channelIDs, recoveryIDs
+}
+
+// ruleMatches reports whether a rule applies to an event of the given severity, at a
+// property with the given tags, with the given devices down. Tags and device types
+// compare case-insensitively since synced devices use "Router" where manually added
+// ones use "router".
+func ruleMatches(rule *models.NotificationRule, propertyTags []string, severity string, down []models.Device) bool {
+	if rule.Severity != "" && rule.Severity != severity {
+		return false
+	}
+	if len(rule.PropertyTags) > 0 && !anyString(rule.PropertyTags, propertyTags) {
+		return false
+	}
+	for i := range down {
+		d := &down[i]
+		if len(rule.DeviceTypes) > 0 && !containsString(rule.DeviceTypes, d.DeviceType) {
+			continue
+		}
+		if len(rule.Tags) > 0 && !anyString(rule.Tags, d.Tags) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// enabledChannels loads the given channels once each, skipping disabled ones
+func (n *Notifier) enabledChannels(ctx context.Context, ids []int64) []*models.NotificationChannel {
+	seen := make(map[int64]bool, len(ids))
+	var channels []*models.NotificationChannel
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		channel, err := n.postgres.GetNotificationChannel(ctx, id)
+		if err != nil {
+			slog.Error("Failed to load notification channel", "channel_id", id, "error", err)
+			continue
+		}
+		if channel.Enabled {
+			channels = append(channels, channel)
+		}
+	}
+	return channels
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyString(values, candidates []string) bool {
+	for _, c := range candidates {
+		if containsString(values, c) {
+			return true
+		}
+	}
+	return false
+}
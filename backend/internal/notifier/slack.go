@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// SlackConfig is the JSON config stored on a slack notification channel
+type SlackConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Channel    string `json:"channel,omitempty"`
+	Username   string `json:"username,omitempty"`
+	IconEmoji  string `json:"icon_emoji,omitempty"`
+}
+
+type slackAttachment struct {
+	Color    string `json:"color"`
+	Fallback string `json:"fallback"`
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+	Ts       int64  `json:"ts"`
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+// SlackSender posts events to a Slack incoming webhook
+type SlackSender struct {
+	httpClient *http.Client
+}
+
+func NewSlackSender() *SlackSender {
+	return &SlackSender{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *SlackSender) Send(ctx context.Context, channel *models.NotificationChannel, event *Event) error {
+	var cfg SlackConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return fmt.Errorf("invalid slack config: %w", err)
+	}
+	if cfg.WebhookURL == "" {
+		return fmt.Errorf("slack config missing webhook_url")
+	}
+
+	payload, err := json.Marshal(buildSlackMessage(&cfg, event))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+func buildSlackMessage(cfg *SlackConfig, event *Event) *slackMessage {
+	color, title := "#808080", event.Property.Name
+	switch event.Type {
+	case EventPropertyDown:
+		color = "#d32f2f"
+		title = fmt.Sprintf(":red_circle: %s is DOWN", event.Property.Name)
+	case EventPropertyRecovery:
+		color = "#388e3c"
+		title = fmt.Sprintf(":large_green_circle: %s has recovered", event.Property.Name)
+	case EventTest:
+		color = "#1976d2"
+		title = ":white_check_mark: Test notification"
+	case EventDeviceFlapping:
+		color = "#f57c00"
+		title = fmt.Sprintf(":warning: %s at %s is flapping", event.Device.Name, event.Property.Name)
+	case EventPowerOnBattery:
+		color = "#d32f2f"
+		title = fmt.Sprintf(":battery: %s is on battery power", event.Property.Name)
+	case EventPowerRestored:
+		color = "#388e3c"
+		title = fmt.Sprintf(":electric_plug: %s is back on line power", event.Property.Name)
+	case EventWorkerDown:
+		color = "#d32f2f"
+		title = ":rotating_light: NOC workers are down; device statuses are stale"
+	case EventWorkerRecovery:
+		color = "#388e3c"
+		title = ":white_check_mark: NOC workers are back"
+	}
+
+	text := fmt.Sprintf("%d online, %d offline, %d total",
+		event.Status.OnlineCount, event.Status.OfflineCount, event.Status.TotalCount)
+	if event.Type == EventWorkerDown || event.Type == EventWorkerRecovery {
+		text = event.Detail
+	}
+	if event.Status.CriticalOffline {
+		text += "\nA critical device is offline"
+	}
+	for i, d := range event.DownDevices {
+		if i == slackMaxDevices {
+			text += fmt.Sprintf("\n…and %d more", len(event.DownDevices)-i)
+			break
+		}
+		text += fmt.Sprintf("\n• %s (%s) %s for %s", d.Device.Name, d.Device.DeviceType, d.Status.Status, d.DownFor())
+	}
+	if event.Property.Address != "" {
+		text += "\n" + event.Property.Address
+	}
+	for _, circuit := range event.Circuits {
+		text += "\n:globe_with_meridians: ISP " + circuitLine(circuit)
+	}
+	for i, contact := range event.Contacts {
+		text += fmt.Sprintf("\n:telephone_receiver: %d. %s", i+1, contactLine(contact))
+	}
+
+	return &slackMessage{
+		Channel:   cfg.Channel,
+		Username:  cfg.Username,
+		IconEmoji: cfg.IconEmoji,
+		Text:      event.Summary(),
+		Attachments: []slackAttachment{{
+			Color:    color,
+			Fallback: event.Summary(),
+			Title:    title,
+			Text:     text,
+			Ts:       event.Timestamp.Unix(),
+		}},
+	}
+}
@@ -0,0 +1,165 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	twilioAPIBase        = "https://api.twilio.com/2010-04-01"
+	defaultSMSMaxPerHour = 10
+	smsMaxBodyLength     = 320
+	smsRateLimitWindow   = time.Hour
+)
+
+// SMSConfig is the JSON config stored on an sms notification channel
+type SMSConfig struct {
+	AccountSID string   `json:"account_sid"`
+	AuthToken  string   `json:"auth_token"`
+	From       string   `json:"from"` // Twilio number in E.164 format
+	To         []string `json:"to"`
+	MaxPerHour int      `json:"max_per_hour,omitempty"` // messages per channel per hour, default 10
+	Events     []string `json:"events,omitempty"`       // event types to text, default critical down events
+}
+
+// defaultSMSEvents are the event types an sms channel sends when its config doesn't
+// list any: a property or the workers going down
+var defaultSMSEvents = []string{EventPropertyDown, EventWorkerDown}
+
+// SMSSender sends events as text messages through Twilio. Each channel is rate
+// limited so a flapping property can't run up the SMS bill.
+type SMSSender struct {
+	redis      storage.StatusStore
+	httpClient *http.Client
+	apiBase    string
+}
+
+func NewSMSSender(redis storage.StatusStore) *SMSSender {
+	return &SMSSender{
+		redis:      redis,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		apiBase:    twilioAPIBase,
+	}
+}
+
+// Accepts limits the channel to its configured event types. Test sends always go
+// through so a new channel can be checked.
+func (s *SMSSender) Accepts(channel *models.NotificationChannel, event *Event) bool {
+	if event.Type == EventTest {
+		return true
+	}
+	var cfg SMSConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		// Let Send report the broken config
+		return true
+	}
+	events := cfg.Events
+	if len(events) == 0 {
+		events = defaultSMSEvents
+	}
+	return slices.Contains(events, event.Type)
+}
+
+func (s *SMSSender) Send(ctx context.Context, channel *models.NotificationChannel, event *Event) error {
+	var cfg SMSConfig
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return fmt.Errorf("invalid sms config: %w", err)
+	}
+	if cfg.AccountSID == "" || cfg.AuthToken == "" || cfg.From == "" {
+		return fmt.Errorf("sms config requires account_sid, auth_token and from")
+	}
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("sms config has no recipients")
+	}
+
+	limit := cfg.MaxPerHour
+	if limit <= 0 {
+		limit = defaultSMSMaxPerHour
+	}
+	// Every recipient counts against the limit since each is billed separately
+	allowed, err := s.redis.AllowNotification(ctx, channel.ID, len(cfg.To), limit, smsRateLimitWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check sms rate limit: %w", err)
+	}
+	if !allowed {
+		// Retrying would only send the alert late, after the window has moved on
+		return permanent(fmt.Errorf("sms rate limit of %d messages per hour reached", limit))
+	}
+
+	body := smsBody(event)
+	var failed []string
+	for _, to := range cfg.To {
+		if err := s.sendMessage(ctx, &cfg, to, body); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", to, err))
+		}
+	}
+	// A retry resends to every number, so it is only worth it when nobody got the alert
+	if len(failed) == len(cfg.To) {
+		return fmt.Errorf("failed to send sms to %s", strings.Join(failed, "; "))
+	}
+	if len(failed) > 0 {
+		slog.Warn("Failed to send sms to some recipients", "channel", channel.Name, "sent", len(cfg.To)-len(failed), "failed", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+func (s *SMSSender) sendMessage(ctx context.Context, cfg *SMSConfig, to, body string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", cfg.From)
+	form.Set("Body", body)
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", s.apiBase, url.PathEscape(cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(cfg.AccountSID, cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("twilio returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+func smsBody(event *Event) string {
+	body := "[ETS NOC] " + event.Summary()
+	// The circuit and who to call go before the devices, which are cut first when the
+	// body is long
+	if list := event.CircuitList(); list != "" {
+		body += ". ISP: " + list
+	}
+	if list := event.ContactList(); list != "" {
+		body += ". Call: " + list
+	}
+	if list := event.DeviceList(3); list != "" {
+		body += ". Down: " + list
+	}
+	// Cut on a character so a multi-byte name isn't split into invalid UTF-8
+	if utf8.RuneCountInString(body) > smsMaxBodyLength {
+		body = string([]rune(body)[:smsMaxBodyLength])
+	}
+	return body
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+func TestSMSSenderDelivery(t *testing.T) {
+	ctx := context.Background()
+	redis := storage.NewMemoryStore()
+	defer redis.Close()
+
+	// Twilio rejects one of the two numbers
+	twilio := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("To") == "+15550009999" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer twilio.Close()
+
+	sender := NewSMSSender(redis)
+	sender.apiBase = twilio.URL
+	channel := &models.NotificationChannel{
+		ID:     1,
+		Name:   "on-call phones",
+		Type:   "sms",
+		Config: `{"account_sid": "AC1", "auth_token": "token", "from": "+15550001111", "to": ["+15550002222", "+15550009999"], "max_per_hour": 3}`,
+	}
+	event := &Event{Type: EventPropertyDown, Property: &models.Property{Name: "Harbor Inn"}, Status: &models.PropertyStatus{}}
+
+	if !sender.Accepts(channel, event) || !sender.Accepts(channel, &Event{Type: EventTest}) {
+		t.Fatal("down and test events were filtered out")
+	}
+	if sender.Accepts(channel, &Event{Type: EventPropertyRecovery}) {
+		t.Fatal("recovery accepted without being listed in events")
+	}
+
+	if err := sender.Send(ctx, channel, event); err != nil {
+		t.Fatalf("partial delivery failed the send: %v", err)
+	}
+
+	// Two of the three messages this hour are used, so two more recipients are over the limit
+	err := sender.Send(ctx, channel, event)
+	if err == nil || !isPermanent(err) {
+		t.Fatalf("send over the rate limit returned %v, want a permanent error", err)
+	}
+}
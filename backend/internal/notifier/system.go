@@ -0,0 +1,57 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// NotifySystem sends an alert about the NOC itself to every enabled channel marked for
+// system alerts. It belongs to no property, so like test sends it is not recorded in
+// notification_events or retried; it returns an error if no channel received it.
+func (n *Notifier) NotifySystem(ctx context.Context, eventType, detail string) error {
+	channels, err := n.postgres.ListNotificationChannels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list notification channels: %w", err)
+	}
+
+	status := "green"
+	if eventType == EventWorkerDown {
+		status = "red"
+	}
+	now := time.Now()
+	event := &Event{
+		Type:      eventType,
+		Property:  &models.Property{Name: "ETS NOC"},
+		Status:    &models.PropertyStatus{Status: status, LastCheck: now},
+		Timestamp: now,
+		// Down and recovery share the key so PagerDuty resolves the incident
+		IncidentKey: "system-workers",
+		Detail:      detail,
+	}
+
+	sent, failed := 0, 0
+	for i := range channels {
+		channel := &channels[i]
+		if !channel.Enabled || !channel.SystemAlerts || !n.accepts(channel, event) {
+			continue
+		}
+		if err := n.send(ctx, channel, event); err != nil {
+			slog.Error("Failed to send system alert", "event", eventType, "channel", channel.Name, "error", err)
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	if sent == 0 && failed > 0 {
+		return fmt.Errorf("system alert failed on all %d channels", failed)
+	}
+	if sent == 0 {
+		slog.Warn("No notification channel receives system alerts", "event", eventType)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// SendTest sends a synthetic test event through a channel, including on-call routing,
+// and returns the delivery error if any. Test sends are not recorded in
+// notification_events since they belong to no property.
+func (n *Notifier) SendTest(ctx context.Context, channel *models.NotificationChannel) error {
+	now := time.Now()
+	event := &Event{
+		Type:        EventTest,
+		Property:    &models.Property{Name: "ETS NOC"},
+		Status:      &models.PropertyStatus{Status: "green", LastCheck: now},
+		Timestamp:   now,
+		IncidentKey: fmt.Sprintf("test-%d-%d", channel.ID, now.Unix()),
+	}
+	return n.send(ctx, channel, event)
+}
@@ -0,0 +1,131 @@
+package nut
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the port upsd listens on
+const DefaultPort = 3493
+
+// Status is the battery state of a UPS derived from its NUT variables
+type Status struct {
+	// Flags are the ups.status flags, e.g. "OL CHRG" or "OB DISCHRG LB"
+	Flags          string
+	OnBattery      bool
+	LowBattery     bool
+	ReplaceBattery bool
+	// ChargePercent and RuntimeSeconds are nil when the UPS doesn't report them
+	ChargePercent  *float64
+	RuntimeSeconds *int
+	// SelfTestResult is the outcome of the last battery self-test, if reported
+	SelfTestResult string
+}
+
+// BatteryHealth is "replace" when the UPS asks for a new battery or failed its last
+// self-test, and "ok" otherwise
+func (s *Status) BatteryHealth() string {
+	if s.ReplaceBattery || strings.Contains(strings.ToLower(s.SelfTestResult), "fail") {
+		return "replace"
+	}
+	return "ok"
+}
+
+// ParseTarget splits a "ups@host" device hostname into the UPS name and host
+func ParseTarget(target string) (string, string, error) {
+	ups, host, ok := strings.Cut(target, "@")
+	if !ok || ups == "" || host == "" {
+		return "", "", fmt.Errorf("UPS hostname must be <ups>@<host>, e.g. ups@10.0.0.5")
+	}
+	return ups, host, nil
+}
+
+// GetStatus reads the variables of a UPS from a NUT server. A port of 0 uses DefaultPort.
+func GetStatus(ctx context.Context, host string, port int, ups string, timeout time.Duration) (*Status, error) {
+	vars, err := ListVars(ctx, host, port, ups, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStatus(vars)
+}
+
+// ListVars returns every variable upsd reports for a UPS
+func ListVars(ctx context.Context, host string, port int, ups string, timeout time.Duration) (map[string]string, error) {
+	if port == 0 {
+		port = DefaultPort
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to upsd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := fmt.Fprintf(conn, "LIST VAR %s\n", ups); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "ERR "):
+			return nil, fmt.Errorf("upsd error: %s", strings.TrimPrefix(line, "ERR "))
+		case strings.HasPrefix(line, "END LIST VAR"):
+			fmt.Fprint(conn, "LOGOUT\n")
+			return vars, nil
+		case strings.HasPrefix(line, "VAR "):
+			// VAR <ups> <name> "<value>"
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) == 4 {
+				vars[fields[2]] = unquote(fields[3])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read variables: %w", err)
+	}
+	return nil, fmt.Errorf("upsd closed the connection before listing variables")
+}
+
+// unquote strips the quotes around a variable value and its backslash escapes
+func unquote(value string) string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value)
+}
+
+// ParseStatus derives the battery state from NUT variables
+func ParseStatus(vars map[string]string) (*Status, error) {
+	flags, ok := vars["ups.status"]
+	if !ok {
+		return nil, fmt.Errorf("UPS does not report ups.status")
+	}
+
+	status := &Status{Flags: flags, SelfTestResult: vars["ups.test.result"]}
+	for _, flag := range strings.Fields(flags) {
+		switch flag {
+		case "OB":
+			status.OnBattery = true
+		case "LB":
+			status.LowBattery = true
+		case "RB":
+			status.ReplaceBattery = true
+		}
+	}
+
+	if v, err := strconv.ParseFloat(vars["battery.charge"], 64); err == nil {
+		status.ChargePercent = &v
+	}
+	if v, err := strconv.ParseFloat(vars["battery.runtime"], 64); err == nil {
+		runtime := int(v)
+		status.RuntimeSeconds = &runtime
+	}
+	return status, nil
+}
@@ -0,0 +1,300 @@
+// Package oidc signs users in through an OpenID Connect provider such as Azure AD or
+// Okta: issuer discovery, the authorization code endpoints and ID token verification
+// against the provider's published keys
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// discoveryTTL is how long a provider's discovery document is cached
+	discoveryTTL = time.Hour
+	// keyRefreshInterval limits how often the signing keys are refetched for a token
+	// signed with an unknown key
+	keyRefreshInterval = time.Minute
+	// clockSkew is the leeway allowed on ID token timestamps
+	clockSkew = time.Minute
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+var (
+	providersMu sync.Mutex
+	providers   = make(map[string]*Provider)
+)
+
+// Provider is an OpenID Connect provider's discovery document along with its signing
+// keys
+type Provider struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+	JWKSURL     string `json:"jwks_uri"`
+
+	discoveredAt time.Time
+
+	keysMu        sync.Mutex
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// Discover returns the provider for an issuer URL from its
+// /.well-known/openid-configuration document, cached for an hour
+func Discover(ctx context.Context, issuer string) (*Provider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	providersMu.Lock()
+	cached := providers[issuer]
+	providersMu.Unlock()
+	if cached != nil && time.Since(cached.discoveredAt) < discoveryTTL {
+		return cached, nil
+	}
+
+	p := &Provider{}
+	if err := getJSON(ctx, issuer+"/.well-known/openid-configuration", "", p); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, not %q", p.Issuer, issuer)
+	}
+	if p.AuthURL == "" || p.TokenURL == "" || p.JWKSURL == "" {
+		return nil, fmt.Errorf("discovery document is missing endpoints")
+	}
+	p.discoveredAt = time.Now()
+
+	providersMu.Lock()
+	providers[issuer] = p
+	providersMu.Unlock()
+	return p, nil
+}
+
+// Endpoint returns the provider's endpoints for an oauth2.Config
+func (p *Provider) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{AuthURL: p.AuthURL, TokenURL: p.TokenURL}
+}
+
+// Claims are the claims of an ID token or userinfo response
+type Claims map[string]interface{}
+
+// String returns a string claim, or "" if it is missing or not a string
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim that may be a single string or a list of them, as group and
+// role claims are
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, expiry and nonce and
+// returns its claims
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, clientID, nonce string) (Claims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce does not match")
+	}
+	return Claims(claims), nil
+}
+
+// UserInfo fetches the signed-in user's claims from the userinfo endpoint
+func (p *Provider) UserInfo(ctx context.Context, token *oauth2.Token) (Claims, error) {
+	if p.UserInfoURL == "" {
+		return nil, fmt.Errorf("provider has no userinfo endpoint")
+	}
+	claims := Claims{}
+	if err := getJSON(ctx, p.UserInfoURL, token.AccessToken, &claims); err != nil {
+		return nil, fmt.Errorf("userinfo failed: %w", err)
+	}
+	return claims, nil
+}
+
+// key returns the signing key with the given ID, refetching the key set when the ID is
+// unknown since providers rotate keys. An empty ID is accepted when the set holds
+// a single key.
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+
+	if key := lookupKey(p.keys, kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := fetchKeys(ctx, p.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key := lookupKey(p.keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func lookupKey(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return keys[kid]
+}
+
+// jwk is one JSON Web Key, RSA or EC
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys loads a JSON Web Key Set, keeping the signing keys it understands
+func fetchKeys(ctx context.Context, url string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, url, "", &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable signing keys at %s", url)
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// getJSON fetches a JSON document, with a bearer token if one is given
+func getJSON(ctx context.Context, url, bearer string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
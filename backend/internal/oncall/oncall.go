@@ -0,0 +1,102 @@
+// Package oncall works out who is on call for a rotation schedule
+package oncall
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// Assignment is the user covering a schedule for a period
+type Assignment struct {
+	UserID   int64
+	StartsAt time.Time
+	EndsAt   time.Time
+	Override bool
+}
+
+// Current returns the assignment in effect at the given time. An active override wins
+// over the rotation; ok is false when the schedule has no members and no override.
+func Current(schedule *models.OnCallSchedule, overrides []models.OnCallOverride, at time.Time) (Assignment, bool) {
+	for _, o := range overrides {
+		if !at.Before(o.StartsAt) && at.Before(o.EndsAt) {
+			return Assignment{UserID: o.UserID, StartsAt: o.StartsAt, EndsAt: o.EndsAt, Override: true}, true
+		}
+	}
+
+	if len(schedule.MemberIDs) == 0 {
+		return Assignment{}, false
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	days := schedule.RotationDays
+	if days <= 0 {
+		days = 7
+	}
+
+	// Handoffs fall on the same local wall-clock time, so step in calendar days
+	// rather than fixed 24h periods to stay correct across DST changes
+	start := schedule.RotationStart.In(loc)
+	handoff := func(n int) time.Time { return start.AddDate(0, 0, n*days) }
+
+	n := int(at.Sub(start).Hours() / 24 / float64(days))
+	for handoff(n).After(at) {
+		n--
+	}
+	for !handoff(n + 1).After(at) {
+		n++
+	}
+
+	index := n % len(schedule.MemberIDs)
+	if index < 0 {
+		index += len(schedule.MemberIDs)
+	}
+
+	return Assignment{
+		UserID:   schedule.MemberIDs[index],
+		StartsAt: handoff(n),
+		EndsAt:   handoff(n + 1),
+	}, true
+}
+
+// Resolve loads a schedule and returns who is on call for it at the given time
+func Resolve(ctx context.Context, postgres storage.Store, scheduleID int64, at time.Time) (*models.OnCallShift, error) {
+	schedule, err := postgres.GetOnCallSchedule(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveSchedule(ctx, postgres, schedule, at)
+}
+
+// ResolveSchedule returns who is on call for an already loaded schedule
+func ResolveSchedule(ctx context.Context, postgres storage.Store, schedule *models.OnCallSchedule, at time.Time) (*models.OnCallShift, error) {
+	overrides, err := postgres.ListOnCallOverrides(ctx, schedule.ID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, ok := Current(schedule, overrides, at)
+	if !ok {
+		return nil, fmt.Errorf("on-call schedule %q has no members", schedule.Name)
+	}
+
+	user, err := postgres.GetUser(ctx, assignment.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load on-call user %d: %w", assignment.UserID, err)
+	}
+
+	return &models.OnCallShift{
+		ScheduleID:   schedule.ID,
+		ScheduleName: schedule.Name,
+		User:         user,
+		StartsAt:     assignment.StartsAt,
+		EndsAt:       assignment.EndsAt,
+		Override:     assignment.Override,
+	}, nil
+}
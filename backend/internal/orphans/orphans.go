@@ -0,0 +1,69 @@
+// Package orphans finds attachment files left in blob storage with no database row,
+// by failed uploads and deleted properties, and removes them
+package orphans
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const (
+	// Prefix holds every attachment file; config backups live elsewhere
+	Prefix = "properties/"
+	// GracePeriod spares recent files, since an upload writes its file before the row
+	// that refers to it
+	GracePeriod = 24 * time.Hour
+)
+
+// Sweep lists the attachment files in blobs that no attachment or chunked upload
+// refers to and, unless dryRun is set, deletes them. A file that fails to delete is
+// counted and logged, and the sweep carries on.
+func Sweep(ctx context.Context, postgres storage.Store, blobs storage.BlobStore, dryRun bool) (*models.OrphanSweep, error) {
+	cutoff := time.Now().Add(-GracePeriod)
+	sweep := &models.OrphanSweep{DryRun: dryRun, Orphans: make([]models.OrphanedFile, 0)}
+	var candidates []models.OrphanedFile
+	err := blobs.ListFiles(ctx, Prefix, func(name string, size int64, updated time.Time) error {
+		sweep.Scanned++
+		if updated.Before(cutoff) {
+			candidates = append(candidates, models.OrphanedFile{Name: name, Size: size, UpdatedAt: updated})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are read after the files are listed, so a file and row written in between
+	// aren't mistaken for an orphan
+	paths, err := postgres.ListAttachmentStoragePaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	known := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		known[path] = true
+	}
+
+	for _, file := range candidates {
+		if known[file.Name] {
+			continue
+		}
+		sweep.Orphans = append(sweep.Orphans, file)
+		sweep.OrphanedBytes += file.Size
+		if dryRun {
+			continue
+		}
+		if err := blobs.DeleteFile(ctx, file.Name); err != nil {
+			slog.Error("Failed to delete orphaned file", "name", file.Name, "error", err)
+			sweep.Failed++
+			continue
+		}
+		sweep.Deleted++
+	}
+	return sweep, nil
+}
@@ -0,0 +1,65 @@
+package orphans
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+const reportInterval = 24 * time.Hour
+
+// Reporter logs the orphaned attachment files once a day, starting at launch. It only
+// reports them; an admin deletes them through the API.
+type Reporter struct {
+	postgres storage.Store
+	blobs    storage.BlobStore
+	stopChan chan struct{}
+}
+
+func NewReporter(postgres storage.Store, blobs storage.BlobStore) *Reporter {
+	return &Reporter{
+		postgres: postgres,
+		blobs:    blobs,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (r *Reporter) Start(ctx context.Context) error {
+	slog.Info("Orphaned file reporter started")
+
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	r.report(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.stopChan:
+			slog.Info("Orphaned file reporter stopped")
+			return nil
+		case <-ticker.C:
+			r.report(ctx)
+		}
+	}
+}
+
+func (r *Reporter) Stop() {
+	close(r.stopChan)
+}
+
+func (r *Reporter) report(ctx context.Context) {
+	sweep, err := Sweep(ctx, r.postgres, r.blobs, true)
+	if err != nil {
+		slog.Error("Failed to look for orphaned attachment files", "error", err)
+		return
+	}
+	if len(sweep.Orphans) == 0 {
+		slog.Info("No orphaned attachment files", "scanned", sweep.Scanned)
+		return
+	}
+	slog.Warn("Found orphaned attachment files; delete them with POST /api/v1/attachments/orphans/cleanup",
+		"scanned", sweep.Scanned, "orphans", len(sweep.Orphans), "bytes", sweep.OrphanedBytes)
+}
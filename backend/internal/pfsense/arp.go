@@ -0,0 +1,49 @@
+package pfsense
+
+import (
+	"bufio"
+	"context"
+	"strings"
+)
+
+// ARPEntry is a resolved neighbor in the firewall's ARP table
+type ARPEntry struct {
+	IPAddr    string `json:"ip_addr"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"` // physical interface, e.g. igb1 or igb1.20
+	Permanent bool   `json:"permanent"` // the firewall's own addresses
+}
+
+// GetARPTable returns the firewall's ARP table. Incomplete entries are skipped.
+func (c *Client) GetARPTable(ctx context.Context) ([]ARPEntry, error) {
+	output, err := c.runCommand(ctx, "arp -an")
+	if err != nil {
+		return nil, err
+	}
+	return parseARP(output), nil
+}
+
+// parseARP parses FreeBSD `arp -an` lines such as
+// "? (10.0.0.5) at aa:bb:cc:dd:ee:ff on igb1 expires in 1198 seconds [ethernet]"
+func parseARP(data string) []ARPEntry {
+	var entries []ARPEntry
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[2] != "at" || fields[4] != "on" {
+			continue
+		}
+		ip := strings.Trim(fields[1], "()")
+		mac := strings.ToLower(fields[3])
+		if ip == "" || mac == "(incomplete)" {
+			continue
+		}
+		entries = append(entries, ARPEntry{
+			IPAddr:    ip,
+			MAC:       mac,
+			Interface: fields[5],
+			Permanent: strings.Contains(scanner.Text(), "permanent"),
+		})
+	}
+	return entries
+}
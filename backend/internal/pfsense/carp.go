@@ -0,0 +1,115 @@
+package pfsense
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CARPVIP is the state of one CARP virtual IP group on a firewall
+type CARPVIP struct {
+	Interface string `json:"interface"`
+	VHID      int    `json:"vhid"`
+	State     string `json:"state"` // MASTER, BACKUP or INIT
+	AdvSkew   int    `json:"advskew"`
+}
+
+// GetCARPStatus returns the CARP state of every VIP on the firewall. A firewall
+// without CARP VIPs returns an empty list.
+func (c *Client) GetCARPStatus(ctx context.Context) ([]CARPVIP, error) {
+	output, err := c.runCommand(ctx, "ifconfig -a")
+	if err != nil {
+		return nil, err
+	}
+	return parseCARP(output), nil
+}
+
+// parseCARP reads the "carp: MASTER vhid 1 advbase 1 advskew 0" lines of ifconfig
+// output, attributing each to the interface block it appears in
+func parseCARP(data string) []CARPVIP {
+	var vips []CARPVIP
+	var iface string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			if name, _, ok := strings.Cut(line, ":"); ok {
+				iface = name
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "carp:" {
+			continue
+		}
+		vip := CARPVIP{Interface: iface, State: fields[1]}
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				continue
+			}
+			switch fields[i] {
+			case "vhid":
+				vip.VHID = value
+			case "advskew":
+				vip.AdvSkew = value
+			}
+		}
+		vips = append(vips, vip)
+	}
+	return vips
+}
+
+// EvaluateCARP checks VIP states against the state a node is expected to hold
+// ("master" or "backup"). With no expectation the node only has to be consistent: every
+// VIP in the same state and none stuck in INIT. Returns whether the node is healthy and
+// a summary.
+func EvaluateCARP(vips []CARPVIP, expected string) (bool, string) {
+	if len(vips) == 0 {
+		return false, "no CARP VIPs found"
+	}
+
+	counts := make(map[string]int)
+	for _, vip := range vips {
+		counts[vip.State]++
+	}
+
+	want := strings.ToUpper(expected)
+	if want == "" {
+		if counts["INIT"] > 0 {
+			return false, fmt.Sprintf("%d of %d VIPs in INIT", counts["INIT"], len(vips))
+		}
+		if len(counts) > 1 {
+			return false, fmt.Sprintf("split CARP state: %s", carpSummary(counts))
+		}
+		return true, fmt.Sprintf("%d VIPs %s", len(vips), vips[0].State)
+	}
+
+	if counts[want] == len(vips) {
+		return true, fmt.Sprintf("%d VIPs %s", len(vips), want)
+	}
+	var wrong []string
+	for _, vip := range vips {
+		if vip.State != want {
+			wrong = append(wrong, fmt.Sprintf("%s vhid %d %s", vip.Interface, vip.VHID, vip.State))
+		}
+	}
+	return false, fmt.Sprintf("%d of %d VIPs not in expected state %s: %s", len(wrong), len(vips), want, strings.Join(wrong, ", "))
+}
+
+func carpSummary(counts map[string]int) string {
+	states := make([]string, 0, len(counts))
+	for state := range counts {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	parts := make([]string, 0, len(states))
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[state], state))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -16,6 +16,10 @@ type DHCPStaticMapping struct {
 	Hostname string
 	IPAddr   string
 	MAC      string
+	// Interface is the dhcpd scope the mapping belongs to (lan, opt1, ...) and
+	// InterfaceDescr its description from the interfaces section, if set
+	Interface      string
+	InterfaceDescr string
 }
 
 type Client struct {
@@ -101,20 +105,93 @@ func parseStaticMappings(output string) []DHCPStaticMapping {
 
 // Alternative method using XML parsing (more robust)
 type ConfigXML struct {
+	// Interfaces and DHCPd hold one child element per interface (lan, opt1, ...)
+	Interfaces struct {
+		Items []struct {
+			XMLName xml.Name
+			Descr   string `xml:"descr"`
+		} `xml:",any"`
+	} `xml:"interfaces"`
 	DHCPd struct {
-		LAN struct {
+		Items []struct {
+			XMLName    xml.Name
 			StaticMaps []struct {
 				MAC      string `xml:"mac"`
 				IPAddr   string `xml:"ipaddr"`
 				Hostname string `xml:"hostname"`
 			} `xml:"staticmap"`
-		} `xml:"lan"`
+		} `xml:",any"`
 	} `xml:"dhcpd"`
 }
 
-// GetDHCPStaticMappingsXML fetches DHCP static mappings using XML parsing
+// GetDHCPStaticMappingsXML fetches DHCP static mappings for every DHCP scope using
+// XML parsing
 func (c *Client) GetDHCPStaticMappingsXML(ctx context.Context) ([]DHCPStaticMapping, error) {
-	config := &ssh.ClientConfig{
+	xmlContent, err := c.GetConfigXML(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg ConfigXML
+	if err := xml.Unmarshal(xmlContent, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	return staticMappingsFromConfig(&cfg), nil
+}
+
+func staticMappingsFromConfig(cfg *ConfigXML) []DHCPStaticMapping {
+	descrs := make(map[string]string)
+	for _, iface := range cfg.Interfaces.Items {
+		descrs[iface.XMLName.Local] = iface.Descr
+	}
+
+	var mappings []DHCPStaticMapping
+	for _, scope := range cfg.DHCPd.Items {
+		name := scope.XMLName.Local
+		for _, sm := range scope.StaticMaps {
+			mappings = append(mappings, DHCPStaticMapping{
+				Hostname:       sm.Hostname,
+				IPAddr:         sm.IPAddr,
+				MAC:            sm.MAC,
+				Interface:      name,
+				InterfaceDescr: descrs[name],
+			})
+		}
+	}
+	return mappings
+}
+
+// GetConfigXML returns the raw config.xml
+func (c *Client) GetConfigXML(ctx context.Context) ([]byte, error) {
+	outputStr, err := c.runShellCommand(ctx, "cat /cf/conf/config.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract XML from output (it's between the command echo and the next prompt)
+	// Look for XML declaration
+	xmlStart := strings.Index(outputStr, "<?xml")
+	if xmlStart == -1 {
+		return nil, fmt.Errorf("no XML found in output")
+	}
+
+	// Find the end of XML (look for closing pfsense tag)
+	xmlEnd := strings.Index(outputStr[xmlStart:], "</pfsense>")
+	if xmlEnd == -1 {
+		return nil, fmt.Errorf("incomplete XML in output")
+	}
+	xmlEnd += xmlStart + len("</pfsense>")
+
+	return []byte(outputStr[xmlStart:xmlEnd]), nil
+}
+
+func (c *Client) addr() string {
+	return net.JoinHostPort(c.host, strconv.Itoa(c.port))
+}
+
+func (c *Client) sshConfig() *ssh.ClientConfig {
+	return &ssh.ClientConfig{
 		User: c.username,
 		Auth: []ssh.AuthMethod{
 			ssh.Password(c.password),
@@ -122,52 +199,86 @@ func (c *Client) GetDHCPStaticMappingsXML(ctx context.Context) ([]DHCPStaticMapp
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 		Timeout:         10 * time.Second,
 	}
+}
 
-	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
-	client, err := ssh.Dial("tcp", addr, config)
+// Markers around command output; the command line splits them in two so an echoed
+// command can't be mistaken for the output
+const (
+	outputBegin = "__ETS_NOC_BEGIN__"
+	outputEnd   = "__ETS_NOC_END__"
+)
+
+// runCommand runs a shell command on the firewall and returns only its output
+func (c *Client) runCommand(ctx context.Context, command string) (string, error) {
+	output, err := c.runShellCommand(ctx, wrapCommand(command))
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
+		return "", err
+	}
+	return extractOutput(output, command)
+}
+
+// wrapCommand surrounds a command with the output markers
+func wrapCommand(command string) string {
+	return fmt.Sprintf(`echo "%s""%s"; %s; echo "%s""%s"`,
+		outputBegin[:6], outputBegin[6:], command, outputEnd[:6], outputEnd[6:])
+}
+
+// extractOutput returns what a wrapped command printed between the markers
+func extractOutput(output, command string) (string, error) {
+	start := strings.Index(output, outputBegin)
+	end := strings.Index(output, outputEnd)
+	if start == -1 || end == -1 || end < start {
+		return "", fmt.Errorf("no output found for %q", command)
+	}
+	return output[start+len(outputBegin) : end], nil
+}
+
+// runShellCommand goes through the pfSense console menu to reach a shell, runs a
+// command and returns everything the session printed, menu included
+func (c *Client) runShellCommand(ctx context.Context, command string) (string, error) {
+	client, err := ssh.Dial("tcp", c.addr(), c.sshConfig())
+	if err != nil {
+		return "", fmt.Errorf("failed to dial: %w", err)
 	}
 	defer client.Close()
 
 	session, err := client.NewSession()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+		return "", fmt.Errorf("failed to create session: %w", err)
 	}
 	defer session.Close()
 
 	// Create pipes for stdin/stdout to handle interactive menu
 	stdin, err := session.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+		return "", fmt.Errorf("failed to get stdin pipe: %w", err)
 	}
 
 	stdout, err := session.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+		return "", fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 
 	// Start shell
 	if err := session.Shell(); err != nil {
-		return nil, fmt.Errorf("failed to start shell: %w", err)
+		return "", fmt.Errorf("failed to start shell: %w", err)
 	}
 
 	// Send "8" to select shell option from pfSense menu
 	if _, err := stdin.Write([]byte("8\n")); err != nil {
-		return nil, fmt.Errorf("failed to send menu option: %w", err)
+		return "", fmt.Errorf("failed to send menu option: %w", err)
 	}
 
 	// Wait a moment for shell to be ready
 	time.Sleep(500 * time.Millisecond)
 
-	// Send command to get config.xml
-	if _, err := stdin.Write([]byte("cat /cf/conf/config.xml\n")); err != nil {
-		return nil, fmt.Errorf("failed to send command: %w", err)
+	if _, err := stdin.Write([]byte(command + "\n")); err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
 	}
 
 	// Send exit command
 	if _, err := stdin.Write([]byte("exit\n")); err != nil {
-		return nil, fmt.Errorf("failed to send exit: %w", err)
+		return "", fmt.Errorf("failed to send exit: %w", err)
 	}
 
 	// Read all output
@@ -188,39 +299,7 @@ func (c *Client) GetDHCPStaticMappingsXML(ctx context.Context) ([]DHCPStaticMapp
 	// Wait for session to finish
 	session.Wait()
 
-	outputStr := string(output)
-
-	// Extract XML from output (it's between the command echo and the next prompt)
-	// Look for XML declaration
-	xmlStart := strings.Index(outputStr, "<?xml")
-	if xmlStart == -1 {
-		return nil, fmt.Errorf("no XML found in output")
-	}
-
-	// Find the end of XML (look for closing pfsense tag)
-	xmlEnd := strings.Index(outputStr[xmlStart:], "</pfsense>")
-	if xmlEnd == -1 {
-		return nil, fmt.Errorf("incomplete XML in output")
-	}
-	xmlEnd += xmlStart + len("</pfsense>")
-
-	xmlContent := outputStr[xmlStart:xmlEnd]
-
-	var cfg ConfigXML
-	if err := xml.Unmarshal([]byte(xmlContent), &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse XML: %w", err)
-	}
-
-	var mappings []DHCPStaticMapping
-	for _, sm := range cfg.DHCPd.LAN.StaticMaps {
-		mappings = append(mappings, DHCPStaticMapping{
-			Hostname: sm.Hostname,
-			IPAddr:   sm.IPAddr,
-			MAC:      sm.MAC,
-		})
-	}
-
-	return mappings, nil
+	return string(output), nil
 }
 
 // DetermineDeviceType returns the device type based on IP address
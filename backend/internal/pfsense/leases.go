@@ -0,0 +1,124 @@
+package pfsense
+
+import (
+	"bufio"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dhcpdLeasesPath is where pfSense's ISC dhcpd keeps its lease database
+const dhcpdLeasesPath = "/var/dhcpd/var/db/dhcpd.leases"
+
+// DHCPLease is a dynamic lease handed out by pfSense's DHCP server
+type DHCPLease struct {
+	IPAddr   string     `json:"ip_addr"`
+	MAC      string     `json:"mac"`
+	Hostname string     `json:"hostname"`
+	State    string     `json:"state"` // active, free, expired, ...
+	Starts   *time.Time `json:"starts"`
+	Ends     *time.Time `json:"ends"` // nil for leases that never expire
+}
+
+// Active reports whether the lease is currently held by a client
+func (l *DHCPLease) Active(now time.Time) bool {
+	return l.State == "active" && (l.Ends == nil || l.Ends.After(now))
+}
+
+// GetDHCPLeases returns the most recent lease for each IP address from the ISC dhcpd
+// lease database. Kea-based installs (pfSense Plus 23.09+ with Kea enabled) are not
+// supported.
+func (c *Client) GetDHCPLeases(ctx context.Context) ([]DHCPLease, error) {
+	output, err := c.runCommand(ctx, "cat "+dhcpdLeasesPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseLeases(output), nil
+}
+
+// parseLeases parses dhcpd.leases. The file is append-only, so later entries for an
+// address replace earlier ones.
+func parseLeases(data string) []DHCPLease {
+	byIP := make(map[string]DHCPLease)
+
+	var current *DHCPLease
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "lease ") && strings.HasSuffix(line, "{"):
+			fields := strings.Fields(line)
+			current = &DHCPLease{IPAddr: fields[1]}
+		case line == "}":
+			if current != nil {
+				byIP[current.IPAddr] = *current
+			}
+			current = nil
+		case current != nil:
+			parseLeaseStatement(current, strings.TrimSuffix(line, ";"))
+		}
+	}
+
+	leases := make([]DHCPLease, 0, len(byIP))
+	for _, lease := range byIP {
+		leases = append(leases, lease)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return ipLess(leases[i].IPAddr, leases[j].IPAddr)
+	})
+	return leases
+}
+
+func parseLeaseStatement(lease *DHCPLease, stmt string) {
+	fields := strings.Fields(stmt)
+	if len(fields) < 2 {
+		return
+	}
+
+	switch fields[0] {
+	case "starts":
+		lease.Starts = parseLeaseTime(fields[1:])
+	case "ends":
+		lease.Ends = parseLeaseTime(fields[1:])
+	case "binding":
+		if len(fields) >= 3 && fields[1] == "state" {
+			lease.State = fields[2]
+		}
+	case "hardware":
+		if len(fields) >= 3 {
+			lease.MAC = strings.ToLower(fields[2])
+		}
+	case "client-hostname":
+		lease.Hostname = strings.Trim(strings.Join(fields[1:], " "), `"`)
+	}
+}
+
+// parseLeaseTime parses "<weekday> YYYY/MM/DD HH:MM:SS" in UTC; "never" returns nil
+func parseLeaseTime(fields []string) *time.Time {
+	if len(fields) < 3 {
+		return nil
+	}
+	t, err := time.Parse("2006/01/02 15:04:05", fields[1]+" "+fields[2])
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// ipLess orders dotted IPv4 addresses numerically, falling back to string order
+func ipLess(a, b string) bool {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	if len(pa) != 4 || len(pb) != 4 {
+		return a < b
+	}
+	for i := 0; i < 4; i++ {
+		if len(pa[i]) != len(pb[i]) {
+			return len(pa[i]) < len(pb[i])
+		}
+		if pa[i] != pb[i] {
+			return pa[i] < pb[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package pfsense
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Static mapping write actions
+const (
+	StaticMapSet    = "set"
+	StaticMapDelete = "delete"
+)
+
+// StaticMappingChange is a static mapping to create, update or remove in one DHCP scope.
+// Mappings are keyed by MAC; setting a MAC that is already mapped updates it in place.
+type StaticMappingChange struct {
+	Action      string
+	Interface   string
+	MAC         string
+	IPAddr      string
+	Hostname    string
+	Description string
+	// Note is recorded as the pfSense config history entry
+	Note string
+}
+
+// staticMapScript applies a StaticMappingChange through pfSense's own config functions,
+// so the write is locked, recorded in the config history and the DHCP server reloaded.
+// __REQUEST__ is replaced with the base64 encoded JSON change.
+const staticMapScript = `<?php
+require_once("config.inc");
+require_once("util.inc");
+require_once("services.inc");
+global $config;
+$req = json_decode(base64_decode('__REQUEST__'), true);
+$if = $req['interface'];
+if (!is_array($config['dhcpd'][$if] ?? null)) {
+	echo "ERROR: DHCP server is not configured on {$if}\n";
+	exit(1);
+}
+$maps = $config['dhcpd'][$if]['staticmap'] ?? array();
+if (!is_array($maps)) {
+	$maps = array();
+}
+$found = -1;
+foreach ($maps as $i => $m) {
+	if (strcasecmp($m['mac'] ?? '', $req['mac']) == 0) {
+		$found = $i;
+		continue;
+	}
+	if ($req['action'] == 'set' && ($m['ipaddr'] ?? '') == $req['ipaddr']) {
+		echo "ERROR: {$req['ipaddr']} is already mapped to {$m['mac']}\n";
+		exit(1);
+	}
+}
+$prev = $found >= 0 ? $maps[$found] : null;
+if ($req['action'] == 'delete') {
+	if ($found < 0) {
+		echo "ERROR: no static mapping for {$req['mac']} on {$if}\n";
+		exit(1);
+	}
+	array_splice($maps, $found, 1);
+} else {
+	$m = $found >= 0 ? $maps[$found] : array();
+	$m['mac'] = $req['mac'];
+	$m['ipaddr'] = $req['ipaddr'];
+	$m['hostname'] = $req['hostname'];
+	if ($req['descr'] != '') {
+		$m['descr'] = $req['descr'];
+	}
+	if ($found >= 0) {
+		$maps[$found] = $m;
+	} else {
+		$maps[] = $m;
+	}
+}
+$config['dhcpd'][$if]['staticmap'] = array_values($maps);
+write_config($req['note']);
+services_dhcpd_configure();
+echo "PREVIOUS: " . json_encode($prev) . "\n";
+echo "OK\n";
+`
+
+// ApplyStaticMapping writes a static mapping change to config.xml and reloads the DHCP
+// server. It returns the mapping as it was before the change, or nil if there was none.
+func (c *Client) ApplyStaticMapping(ctx context.Context, change StaticMappingChange) (*DHCPStaticMapping, error) {
+	request, err := json.Marshal(map[string]string{
+		"action":    change.Action,
+		"interface": change.Interface,
+		"mac":       change.MAC,
+		"ipaddr":    change.IPAddr,
+		"hostname":  change.Hostname,
+		"descr":     change.Description,
+		"note":      change.Note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	script := strings.Replace(staticMapScript, "__REQUEST__", base64.StdEncoding.EncodeToString(request), 1)
+
+	output, err := c.runPHP(ctx, script)
+	if err != nil {
+		return nil, err
+	}
+	return parseStaticMapResult(output, change.Interface)
+}
+
+// parseStaticMapResult reads the PREVIOUS/OK lines printed by staticMapScript
+func parseStaticMapResult(output, iface string) (*DHCPStaticMapping, error) {
+	var previous *DHCPStaticMapping
+	ok := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "ERROR: "):
+			return nil, fmt.Errorf("%s", strings.TrimPrefix(line, "ERROR: "))
+		case strings.HasPrefix(line, "PREVIOUS: "):
+			var prev *struct {
+				MAC      string `json:"mac"`
+				IPAddr   string `json:"ipaddr"`
+				Hostname string `json:"hostname"`
+			}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "PREVIOUS: ")), &prev); err != nil {
+				return nil, fmt.Errorf("failed to parse previous mapping: %w", err)
+			}
+			if prev != nil {
+				previous = &DHCPStaticMapping{
+					Hostname:  prev.Hostname,
+					IPAddr:    prev.IPAddr,
+					MAC:       prev.MAC,
+					Interface: iface,
+				}
+			}
+		case line == "OK":
+			ok = true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("static mapping change was not confirmed by pfSense")
+	}
+	return previous, nil
+}
+
+// runPHP uploads a PHP script to a temporary file in short lines, since the console
+// shell rejects very long input lines, then runs it and returns its output
+func (c *Client) runPHP(ctx context.Context, script string) (string, error) {
+	path := fmt.Sprintf("/tmp/ets-noc-%d", time.Now().UnixNano())
+	encoded := base64.StdEncoding.EncodeToString([]byte(script))
+
+	var lines []string
+	for len(encoded) > 0 {
+		n := min(64, len(encoded))
+		lines = append(lines, fmt.Sprintf("echo '%s' >> %s.b64", encoded[:n], path))
+		encoded = encoded[n:]
+	}
+	run := fmt.Sprintf("openssl base64 -d -in %[1]s.b64 -out %[1]s.php && /usr/local/bin/php -q %[1]s.php; rm -f %[1]s.b64 %[1]s.php", path)
+	lines = append(lines, wrapCommand(run))
+
+	output, err := c.runShellCommand(ctx, strings.Join(lines, "\n"))
+	if err != nil {
+		return "", err
+	}
+	return extractOutput(output, "php script")
+}
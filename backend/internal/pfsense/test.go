@@ -0,0 +1,65 @@
+package pfsense
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ConnectionTest is the result of checking that a firewall can be reached and logged in to
+type ConnectionTest struct {
+	Reachable     bool   `json:"reachable"`
+	Authenticated bool   `json:"authenticated"`
+	Version       string `json:"version,omitempty"`
+	LatencyMs     int64  `json:"latency_ms"`
+	Error         string `json:"error,omitempty"`
+}
+
+// TestConnection checks TCP reachability of the SSH port, then SSH login, then reads the
+// pfSense version. It stops at the first step that fails and reports why.
+func (c *Client) TestConnection(ctx context.Context) *ConnectionTest {
+	result := &ConnectionTest{}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr())
+	if err != nil {
+		result.Error = "unreachable: " + err.Error()
+		return result
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Reachable = true
+	conn.Close()
+
+	client, err := ssh.Dial("tcp", c.addr(), c.sshConfig())
+	if err != nil {
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			result.Error = "authentication failed: check the username and password"
+		} else {
+			result.Error = "SSH handshake failed: " + err.Error()
+		}
+		return result
+	}
+	client.Close()
+	result.Authenticated = true
+
+	version, err := c.GetVersion(ctx)
+	if err != nil {
+		result.Error = "logged in but could not read the pfSense version: " + err.Error()
+		return result
+	}
+	result.Version = version
+	return result
+}
+
+// GetVersion returns the running pfSense version, such as 2.7.2-RELEASE
+func (c *Client) GetVersion(ctx context.Context) (string, error) {
+	output, err := c.runCommand(ctx, "cat /etc/version")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
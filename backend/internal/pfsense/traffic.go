@@ -0,0 +1,80 @@
+package pfsense
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// InterfaceCounters are the cumulative byte and packet counters of a network interface
+type InterfaceCounters struct {
+	Interface string `json:"interface"`
+	RxBytes   int64  `json:"rx_bytes"`
+	TxBytes   int64  `json:"tx_bytes"`
+	RxPackets int64  `json:"rx_packets"`
+	TxPackets int64  `json:"tx_packets"`
+	RxErrors  int64  `json:"rx_errors"`
+	TxErrors  int64  `json:"tx_errors"`
+}
+
+// Pseudo-interfaces whose counters aren't interesting for bandwidth
+var skipInterfaces = map[string]bool{"lo0": true, "pflog0": true, "pfsync0": true, "enc0": true}
+
+// GetInterfaceCounters returns the counters of every physical and VLAN interface
+func (c *Client) GetInterfaceCounters(ctx context.Context) ([]InterfaceCounters, error) {
+	output, err := c.runCommand(ctx, "netstat -i -b -n -W -f link")
+	if err != nil {
+		return nil, err
+	}
+	return parseNetstat(output), nil
+}
+
+// parseNetstat parses `netstat -ibn -f link` output. The address column is empty for
+// some interfaces, so the counters are read from the end of each line:
+// Ipkts Ierrs Idrop Ibytes Opkts Oerrs Obytes Coll
+func parseNetstat(data string) []InterfaceCounters {
+	var counters []InterfaceCounters
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[0] == "Name" {
+			continue
+		}
+		name := strings.TrimSuffix(fields[0], "*") // down interfaces are marked with *
+		if skipInterfaces[name] || seen[name] {
+			continue
+		}
+
+		tail := fields[len(fields)-8:]
+		values := make([]int64, len(tail))
+		valid := true
+		for i, f := range tail {
+			v, err := strconv.ParseInt(f, 10, 64)
+			if err != nil {
+				// Counters the driver doesn't report are shown as "-"
+				if f != "-" {
+					valid = false
+				}
+				continue
+			}
+			values[i] = v
+		}
+		if !valid {
+			continue
+		}
+
+		seen[name] = true
+		counters = append(counters, InterfaceCounters{
+			Interface: name,
+			RxPackets: values[0],
+			RxErrors:  values[1],
+			RxBytes:   values[3],
+			TxPackets: values[4],
+			TxErrors:  values[5],
+			TxBytes:   values[6],
+		})
+	}
+	return counters
+}
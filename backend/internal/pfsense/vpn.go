@@ -0,0 +1,155 @@
+package pfsense
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VPN tunnel types
+const (
+	VPNTypeIPsec   = "ipsec"
+	VPNTypeOpenVPN = "openvpn"
+)
+
+// vpnStatusScript prints configured IPsec connections, active IPsec SAs and the state
+// of every OpenVPN instance, each under a "== section" header. It runs under sh
+// because the pfSense console shell is tcsh.
+const vpnStatusScript = `sh -c '` +
+	`echo "== ipsec-conns"; swanctl --list-conns 2>/dev/null; ` +
+	`echo "== ipsec-sas"; swanctl --list-sas --noblock 2>/dev/null; ` +
+	`for s in /var/etc/openvpn/*/sock; do [ -S "$s" ] || continue; ` +
+	`echo "== openvpn $(basename $(dirname $s))"; printf "state\nquit\n" | nc -U -w 2 "$s"; done'`
+
+// VPNTunnel is the state of an IPsec connection or OpenVPN instance
+type VPNTunnel struct {
+	Type   string `json:"type"` // ipsec or openvpn
+	Name   string `json:"name"` // IPsec connection (con1) or OpenVPN instance (client1, server2)
+	Up     bool   `json:"up"`
+	Detail string `json:"detail"`
+}
+
+// Key identifies the tunnel as "<type>:<name>", the form used as a VPN device's hostname
+func (t *VPNTunnel) Key() string {
+	return t.Type + ":" + t.Name
+}
+
+// GetVPNStatus returns the state of every configured IPsec connection and OpenVPN
+// instance. An IPsec tunnel is up when its IKE SA is established with at least one
+// child SA installed; an OpenVPN instance when its management state is CONNECTED.
+func (c *Client) GetVPNStatus(ctx context.Context) ([]VPNTunnel, error) {
+	output, err := c.runCommand(ctx, vpnStatusScript)
+	if err != nil {
+		return nil, err
+	}
+	return parseVPNStatus(output), nil
+}
+
+func parseVPNStatus(data string) []VPNTunnel {
+	tunnels := make(map[string]*VPNTunnel)
+	tunnel := func(kind, name string) *VPNTunnel {
+		key := kind + ":" + name
+		if t, ok := tunnels[key]; ok {
+			return t
+		}
+		t := &VPNTunnel{Type: kind, Name: name, Detail: "not established"}
+		tunnels[key] = t
+		return t
+	}
+
+	var section string
+	var currentSA *VPNTunnel
+	var children, installed int
+
+	finishSA := func() {
+		if currentSA != nil {
+			currentSA.Up = currentSA.Up && installed > 0
+			currentSA.Detail = fmt.Sprintf("%s, %d of %d child SAs installed", currentSA.Detail, installed, children)
+		}
+		currentSA, children, installed = nil, 0, 0
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "== ") {
+			finishSA()
+			section = strings.TrimPrefix(line, "== ")
+			continue
+		}
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		switch {
+		case section == "ipsec-conns":
+			// "con1: IKEv2, no reauthentication, rekeying every 25920s"
+			if name, _, ok := strings.Cut(trimmed, ":"); ok && !indented && !strings.Contains(name, " ") {
+				tunnel(VPNTypeIPsec, name)
+			}
+		case section == "ipsec-sas":
+			name, rest, ok := strings.Cut(trimmed, ":")
+			if !ok || strings.Contains(name, " ") {
+				continue
+			}
+			if !indented {
+				// "con1: #3, ESTABLISHED, IKEv2, ..."
+				finishSA()
+				currentSA = tunnel(VPNTypeIPsec, name)
+				state := saState(rest, 1)
+				currentSA.Up = state == "ESTABLISHED"
+				currentSA.Detail = "IKE " + state
+			} else if currentSA != nil && strings.Contains(rest, "reqid") {
+				// "  con1_1: #5, reqid 1, INSTALLED, TUNNEL, ..."
+				children++
+				if saState(rest, 2) == "INSTALLED" {
+					installed++
+				}
+			}
+		case strings.HasPrefix(section, "openvpn "):
+			// "1697040000,CONNECTED,SUCCESS,10.8.0.2,203.0.113.5,1194,,"
+			fields := strings.Split(trimmed, ",")
+			if len(fields) < 2 || strings.HasPrefix(trimmed, ">") || trimmed == "END" {
+				continue
+			}
+			t := tunnel(VPNTypeOpenVPN, strings.TrimPrefix(section, "openvpn "))
+			t.Up = fields[1] == "CONNECTED"
+			t.Detail = strings.ToLower(fields[1])
+			if t.Up && len(fields) > 4 && fields[4] != "" {
+				t.Detail = "connected to " + fields[4]
+			}
+		}
+	}
+	finishSA()
+
+	result := make([]VPNTunnel, 0, len(tunnels))
+	for _, t := range tunnels {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key() < result[j].Key()
+	})
+	return result
+}
+
+// saState returns the comma separated field at index i of a swanctl SA line
+func saState(rest string, i int) string {
+	fields := strings.Split(rest, ",")
+	if len(fields) <= i {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+// ParseVPNKey splits a "<type>:<name>" tunnel key
+func ParseVPNKey(key string) (string, string, error) {
+	kind, name, ok := strings.Cut(key, ":")
+	if !ok || name == "" || (kind != VPNTypeIPsec && kind != VPNTypeOpenVPN) {
+		return "", "", fmt.Errorf("VPN tunnel must be given as ipsec:<connection> or openvpn:<instance>, got %q", key)
+	}
+	return kind, name, nil
+}
@@ -0,0 +1,203 @@
+// Package s3 keeps attachments and config backups in an S3 bucket, on AWS or on an
+// S3-compatible server such as MinIO. It speaks the few REST calls the NOC needs,
+// signed with Signature Version 4, rather than pulling in an SDK.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 60 * time.Second
+
+// Config locates a bucket and the keys to reach it. Endpoint is the server's base
+// URL for S3-compatible servers, which are addressed path-style; empty means AWS in
+// Region, addressed virtual-host style.
+type Config struct {
+	Bucket          string
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Client reads and writes the objects of one bucket
+type Client struct {
+	bucket    string
+	base      *url.URL
+	pathStyle bool
+	signer    *signer
+	http      *http.Client
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("an S3 bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access keys are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint, pathStyle := cfg.Endpoint, true
+	if endpoint == "" {
+		endpoint, pathStyle = "https://"+cfg.Bucket+".s3."+cfg.Region+".amazonaws.com", false
+	}
+	base, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+
+	return &Client{
+		bucket:    cfg.Bucket,
+		base:      base,
+		pathStyle: pathStyle,
+		signer:    &signer{accessKeyID: cfg.AccessKeyID, secretAccessKey: cfg.SecretAccessKey, region: cfg.Region},
+		http:      &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Kind identifies the store in attachment records
+func (c *Client) Kind() string {
+	return "s3"
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+// objectURL returns the URL of an object, or of the bucket for an empty name
+func (c *Client) objectURL(objectName string) *url.URL {
+	u := *c.base
+	if c.pathStyle {
+		u.Path += "/" + c.bucket
+	}
+	if objectName != "" || !c.pathStyle {
+		u.Path += "/" + objectName
+	}
+	u.RawPath = escapePath(u.Path)
+	return &u
+}
+
+// Ping checks the bucket exists and the keys can reach it
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodHead, c.objectURL(""), nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to reach S3 bucket: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// UploadFile uploads a file. The body is read into memory first, since a signed PUT
+// needs its length and checksum; attachments are capped at 50MB.
+func (c *Client) UploadFile(ctx context.Context, objectName string, reader io.Reader, contentType string) error {
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	resp, err := c.do(ctx, http.MethodPut, c.objectURL(objectName), body, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to write to S3: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetSignedURL generates a presigned URL for downloading a file, valid for up to
+// seven days
+func (c *Client) GetSignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, error) {
+	return c.signer.presign(c.objectURL(objectName), expiration, time.Now()), nil
+}
+
+// DeleteFile deletes a file; S3 reports success for one that is already gone
+func (c *Client) DeleteFile(ctx context.Context, objectName string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.objectURL(objectName), nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete from S3: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListFiles calls fn for each object whose key starts with prefix, a page of up to
+// 1000 at a time
+func (c *Client) ListFiles(ctx context.Context, prefix string, fn func(name string, size int64, updated time.Time) error) error {
+	var continuation string
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuation != "" {
+			query.Set("continuation-token", continuation)
+		}
+		u := c.objectURL("")
+		u.RawQuery = canonicalQuery(query)
+
+		resp, err := c.do(ctx, http.MethodGet, u, nil, "")
+		if err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+		var page struct {
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+			Contents              []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read S3 object list: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if err := fn(object.Key, object.Size, object.LastModified); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		continuation = page.NextContinuationToken
+	}
+}
+
+// do sends a signed request and turns S3 error responses into errors
+func (c *Client) do(ctx context.Context, method string, u *url.URL, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.signer.sign(req, hashHex(body), time.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		// HEAD responses have no body to explain the status
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3Err) == nil && s3Err.Code != "" {
+			return nil, fmt.Errorf("S3 returned HTTP %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+		}
+		return nil, fmt.Errorf("S3 returned HTTP %d", resp.StatusCode)
+	}
+	return resp, nil
+}
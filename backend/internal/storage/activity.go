@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Activity feed kinds
+const (
+	ActivityStatusChange   = "status_change"
+	ActivityIncident       = "incident"
+	ActivityAcknowledgment = "acknowledgement"
+	ActivityNotification   = "notification"
+	ActivitySync           = "sync"
+)
+
+// ActivityKinds lists the kinds of activity feed entries
+var ActivityKinds = []string{ActivityStatusChange, ActivityIncident, ActivityAcknowledgment, ActivityNotification, ActivitySync}
+
+// ActivityCursor is the position of an entry in the activity feed, which is ordered
+// newest first, then by source and ID so entries at the same time keep their order
+type ActivityCursor struct {
+	OccurredAt time.Time
+	Source     string
+	ID         int64
+}
+
+// ActivityFilter selects activity feed entries. Since keeps entries after that time
+// and Before those after the entry it names, in feed order.
+type ActivityFilter struct {
+	PropertyID int64
+	Kinds      []string // all kinds when empty
+	Since      time.Time
+	Before     *ActivityCursor
+	Limit      int
+}
+
+// activitySource is a table the activity feed reads from. Each query selects the same
+// columns: id, time, property ID and name, device ID and name, three text fields, the
+// actor and the outcome, which toEvent turns into an entry.
+type activitySource struct {
+	name     string
+	kinds    []string
+	query    string
+	table    string // alias the property filter applies to
+	timeExpr string
+	toEvent  func(e *models.ActivityEvent, a, b, c string)
+}
+
+// activitySources are in feed order for entries at the same time
+var activitySources = []activitySource{
+	{
+		name:  "status_event",
+		kinds: []string{ActivityStatusChange, ActivityIncident},
+		query: `SELECT e.id, e.occurred_at, e.property_id, COALESCE(p.name, ''), e.device_id, COALESCE(d.name, ''),
+			e.entity_type, e.from_status, e.to_status, COALESCE(e.message, ''), '', NULL::boolean
+			FROM status_events e
+			LEFT JOIN properties p ON p.id = e.property_id
+			LEFT JOIN devices d ON d.id = e.device_id`,
+		table:    "e",
+		timeExpr: "e.occurred_at",
+		toEvent: func(e *models.ActivityEvent, entityType, from, to string) {
+			switch {
+			case entityType == "device":
+				e.Kind, e.Action = ActivityStatusChange, to
+				e.Summary = fmt.Sprintf("%s is %s, was %s", e.DeviceName, to, from)
+			case to == "red":
+				e.Kind, e.Action = ActivityIncident, "opened"
+				e.Summary = fmt.Sprintf("%s is down", e.PropertyName)
+			case from == "red":
+				e.Kind, e.Action = ActivityIncident, "resolved"
+				e.Summary = fmt.Sprintf("%s recovered to %s", e.PropertyName, to)
+			default:
+				e.Kind, e.Action = ActivityStatusChange, to
+				e.Summary = fmt.Sprintf("%s is %s, was %s", e.PropertyName, to, from)
+			}
+		},
+	},
+	{
+		name:  "acknowledgement",
+		kinds: []string{ActivityAcknowledgment},
+		query: `SELECT a.id, a.created_at, a.property_id, COALESCE(p.name, ''), a.device_id, COALESCE(d.name, ''),
+			a.entity_type, '', '', COALESCE(a.comment, ''), COALESCE(a.acknowledged_by, ''), NULL::boolean
+			FROM acknowledgements a
+			LEFT JOIN properties p ON p.id = a.property_id
+			LEFT JOIN devices d ON d.id = a.device_id`,
+		table:    "a",
+		timeExpr: "a.created_at",
+		toEvent: func(e *models.ActivityEvent, entityType, _, _ string) {
+			e.Kind, e.Action = ActivityAcknowledgment, "acknowledged"
+			name := e.PropertyName
+			if entityType == "device" {
+				name = e.DeviceName
+			}
+			e.Summary = fmt.Sprintf("%s acknowledged %s", e.Actor, name)
+		},
+	},
+	{
+		name:  "notification_event",
+		kinds: []string{ActivityNotification},
+		query: `SELECT n.id, n.created_at, n.property_id, COALESCE(p.name, ''), NULL::bigint, '',
+			n.event_type, COALESCE(ch.name, ''), '', COALESCE(n.error, ''), '', n.success
+			FROM notification_events n
+			LEFT JOIN properties p ON p.id = n.property_id
+			LEFT JOIN notification_channels ch ON ch.id = n.notification_channel_id`,
+		table:    "n",
+		timeExpr: "n.created_at",
+		toEvent: func(e *models.ActivityEvent, eventType, channel, _ string) {
+			e.Kind, e.Action = ActivityNotification, eventType
+			if e.Success != nil && *e.Success {
+				e.Summary = fmt.Sprintf("Sent %s for %s to %s", eventType, e.PropertyName, channel)
+			} else {
+				e.Summary = fmt.Sprintf("Failed to send %s for %s to %s", eventType, e.PropertyName, channel)
+			}
+		},
+	},
+	{
+		name:  "config_backup",
+		kinds: []string{ActivitySync},
+		query: `SELECT b.id, b.created_at, b.property_id, COALESCE(p.name, ''), NULL::bigint, '',
+			b.trigger, '', '', '', COALESCE(b.created_by, ''), NULL::boolean
+			FROM config_backups b
+			LEFT JOIN properties p ON p.id = b.property_id`,
+		table:    "b",
+		timeExpr: "b.created_at",
+		toEvent: func(e *models.ActivityEvent, trigger, _, _ string) {
+			e.Kind, e.Action = ActivitySync, "config_backup"
+			e.Summary = fmt.Sprintf("Backed up the pfSense config of %s (%s)", e.PropertyName, trigger)
+		},
+	},
+	{
+		name:  "dhcp_mapping_change",
+		kinds: []string{ActivitySync},
+		query: `SELECT m.id, m.created_at, m.property_id, COALESCE(p.name, ''), m.device_id, COALESCE(d.name, ''),
+			m.action, m.ip_address, m.hostname, COALESCE(m.error, ''), COALESCE(m.created_by, ''), m.success
+			FROM dhcp_mapping_changes m
+			LEFT JOIN properties p ON p.id = m.property_id
+			LEFT JOIN devices d ON d.id = m.device_id`,
+		table:    "m",
+		timeExpr: "m.created_at",
+		toEvent: func(e *models.ActivityEvent, action, ip, hostname string) {
+			e.Kind, e.Action = ActivitySync, "dhcp_mapping_"+action
+			verb := "Set"
+			if action == "delete" {
+				verb = "Removed"
+			}
+			e.Summary = strings.Join(strings.Fields(fmt.Sprintf("%s DHCP mapping %s %s at %s", verb, ip, hostname, e.PropertyName)), " ")
+			if e.Success != nil && !*e.Success {
+				e.Summary = "Failed: " + e.Summary
+			}
+		},
+	},
+}
+
+// IsActivitySource reports whether name is a source an activity cursor can point into
+func IsActivitySource(name string) bool {
+	return slices.ContainsFunc(activitySources, func(src activitySource) bool { return src.name == name })
+}
+
+// ListActivity returns the newest activity feed entries matching filter, merged from
+// status events, acknowledgements, notification deliveries, config backups and DHCP
+// mapping changes
+func (s *PostgresStore) ListActivity(ctx context.Context, filter ActivityFilter) ([]models.ActivityEvent, error) {
+	return s.listActivity(ctx, filter, func(expr string) string { return expr })
+}
+
+// listActivity runs ListActivity with timeText applied to the times it compares
+func (s *PostgresStore) listActivity(ctx context.Context, filter ActivityFilter, timeText func(string) string) ([]models.ActivityEvent, error) {
+	sourceRank := -1
+	if filter.Before != nil {
+		sourceRank = slices.IndexFunc(activitySources, func(src activitySource) bool { return src.name == filter.Before.Source })
+		if sourceRank < 0 {
+			return nil, fmt.Errorf("unknown activity source %q", filter.Before.Source)
+		}
+	}
+
+	var events []models.ActivityEvent
+	for rank, src := range activitySources {
+		wanted := src.kinds
+		if len(filter.Kinds) > 0 {
+			wanted = slices.DeleteFunc(slices.Clone(src.kinds), func(k string) bool { return !slices.Contains(filter.Kinds, k) })
+		}
+		if len(wanted) == 0 {
+			continue
+		}
+
+		var conditions []string
+		var args []interface{}
+		add := func(cond string, values ...interface{}) {
+			placeholders := make([]interface{}, len(values))
+			for i, v := range values {
+				args = append(args, v)
+				placeholders[i] = len(args)
+			}
+			conditions = append(conditions, fmt.Sprintf(cond, placeholders...))
+		}
+
+		if filter.PropertyID != 0 {
+			add(src.table+".property_id = $%d", filter.PropertyID)
+		}
+		// add formats its condition, so percent signs from timeText are escaped
+		ts := timeText(src.timeExpr)
+		escaped := strings.ReplaceAll(ts, "%", "%%")
+		param := strings.Replace(strings.ReplaceAll(timeText("?"), "%", "%%"), "?", "$%d", 1)
+		if !filter.Since.IsZero() {
+			add(escaped+" > "+param, filter.Since)
+		}
+		// Entries after the cursor: earlier ones, or at the same time, later sources and
+		// lower IDs of the cursor's own source
+		if c := filter.Before; c != nil {
+			switch {
+			case rank < sourceRank:
+				add(escaped+" < "+param, c.OccurredAt)
+			case rank == sourceRank:
+				add("("+escaped+" < "+param+" OR ("+escaped+" = "+param+" AND "+src.table+".id < $%d))", c.OccurredAt, c.OccurredAt, c.ID)
+			default:
+				add(escaped+" <= "+param, c.OccurredAt)
+			}
+		}
+		if src.name == "status_event" && len(wanted) == 1 {
+			incident := "(e.entity_type = 'property' AND (e.from_status = 'red' OR e.to_status = 'red'))"
+			if wanted[0] == ActivityIncident {
+				conditions = append(conditions, incident)
+			} else {
+				conditions = append(conditions, "NOT "+incident)
+			}
+		}
+
+		query := src.query
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		query += fmt.Sprintf(" ORDER BY %s DESC, %s.id DESC", ts, src.table)
+		if filter.Limit > 0 {
+			args = append(args, filter.Limit)
+			query += fmt.Sprintf(" LIMIT $%d", len(args))
+		}
+
+		sourceEvents, err := s.queryActivity(ctx, src, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s activity: %w", src.name, err)
+		}
+		events = append(events, sourceEvents...)
+	}
+
+	ranks := make(map[string]int, len(activitySources))
+	for i, src := range activitySources {
+		ranks[src.name] = i
+	}
+	slices.SortStableFunc(events, func(a, b models.ActivityEvent) int {
+		if c := b.OccurredAt.Compare(a.OccurredAt); c != 0 {
+			return c
+		}
+		if c := ranks[a.Source] - ranks[b.Source]; c != 0 {
+			return c
+		}
+		return int(b.SourceID - a.SourceID)
+	})
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	if events == nil {
+		events = make([]models.ActivityEvent, 0)
+	}
+	return events, nil
+}
+
+func (s *PostgresStore) queryActivity(ctx context.Context, src activitySource, query string, args ...interface{}) ([]models.ActivityEvent, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ActivityEvent
+	for rows.Next() {
+		e := models.ActivityEvent{Source: src.name}
+		var a, b, c string
+		var success sql.NullBool
+		if err := rows.Scan(&e.SourceID, &e.OccurredAt, &e.PropertyID, &e.PropertyName, &e.DeviceID, &e.DeviceName,
+			&a, &b, &c, &e.Detail, &e.Actor, &success); err != nil {
+			return nil, err
+		}
+		if success.Valid {
+			e.Success = &success.Bool
+		}
+		e.ID = fmt.Sprintf("%s:%d", src.name, e.SourceID)
+		src.toEvent(&e, a, b, c)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Acknowledgements
+const acknowledgementColumns = `id, entity_type, property_id, device_id, acknowledged_by, comment, created_at, cleared_at`
+
+func (s *PostgresStore) CreateAcknowledgement(ctx context.Context, a *models.Acknowledgement) error {
+	query := `
+		INSERT INTO acknowledgements (entity_type, property_id, device_id, acknowledged_by, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, a.EntityType, a.PropertyID, a.DeviceID, a.AcknowledgedBy, a.Comment).
+		Scan(&a.ID, &a.CreatedAt)
+}
+
+// ListActiveAcknowledgements returns acknowledgements that have not been cleared
+func (s *PostgresStore) ListActiveAcknowledgements(ctx context.Context) ([]models.Acknowledgement, error) {
+	query := `SELECT ` + acknowledgementColumns + ` FROM acknowledgements WHERE cleared_at IS NULL ORDER BY created_at DESC`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acks := make([]models.Acknowledgement, 0)
+	for rows.Next() {
+		var a models.Acknowledgement
+		if err := rows.Scan(&a.ID, &a.EntityType, &a.PropertyID, &a.DeviceID, &a.AcknowledgedBy, &a.Comment,
+			&a.CreatedAt, &a.ClearedAt); err != nil {
+			return nil, err
+		}
+		acks = append(acks, a)
+	}
+	return acks, rows.Err()
+}
+
+// ClearPropertyAcknowledgement clears the active property-level acknowledgement, if any
+func (s *PostgresStore) ClearPropertyAcknowledgement(ctx context.Context, propertyID int64) error {
+	query := `UPDATE acknowledgements SET cleared_at = NOW()
+		WHERE entity_type = 'property' AND property_id = $1 AND cleared_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, propertyID)
+	return err
+}
+
+// ClearDeviceAcknowledgement clears the active acknowledgement for a device, if any
+func (s *PostgresStore) ClearDeviceAcknowledgement(ctx context.Context, deviceID int64) error {
+	query := `UPDATE acknowledgements SET cleared_at = NOW()
+		WHERE entity_type = 'device' AND device_id = $1 AND cleared_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, deviceID)
+	return err
+}
+
+// Silences
+const silenceColumns = `id, scope_type, property_id, device_id, tag, reason, created_by, expires_at, created_at`
+
+func (s *PostgresStore) CreateSilence(ctx context.Context, silence *models.Silence) error {
+	query := `
+		INSERT INTO silences (scope_type, property_id, device_id, tag, reason, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, silence.ScopeType, silence.PropertyID, silence.DeviceID, silence.Tag,
+		silence.Reason, silence.CreatedBy, silence.ExpiresAt).Scan(&silence.ID, &silence.CreatedAt)
+}
+
+// ListActiveSilences returns silences that have not expired at the given time
+func (s *PostgresStore) ListActiveSilences(ctx context.Context, at time.Time) ([]models.Silence, error) {
+	query := `SELECT ` + silenceColumns + ` FROM silences WHERE expires_at > $1 ORDER BY expires_at`
+	rows, err := s.db.QueryContext(ctx, query, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	silences := make([]models.Silence, 0)
+	for rows.Next() {
+		var silence models.Silence
+		if err := rows.Scan(&silence.ID, &silence.ScopeType, &silence.PropertyID, &silence.DeviceID, &silence.Tag,
+			&silence.Reason, &silence.CreatedBy, &silence.ExpiresAt, &silence.CreatedAt); err != nil {
+			return nil, err
+		}
+		silences = append(silences, silence)
+	}
+	return silences, rows.Err()
+}
+
+// ExpireSilence ends a silence immediately
+func (s *PostgresStore) ExpireSilence(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE silences SET expires_at = NOW() WHERE id = $1 AND expires_at > NOW()`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("active silence not found")
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// arrayElement are the element types of the array columns and unnest parameters
+type arrayElement interface {
+	string | int64 | float64 | bool
+}
+
+// array passes a slice as a Postgres array parameter. It is written in array syntax,
+// which pgx sends as text for the server to parse and SQLite stores as is; a nil slice
+// is NULL.
+func array[T arrayElement](elements []T) driver.Valuer {
+	return arrayValue[T](elements)
+}
+
+// scanArray reads an array column, in the syntax Postgres returns it and SQLite
+// stores it, into dest. NULL leaves dest nil.
+func scanArray[T arrayElement](dest *[]T) *arrayScanner[T] {
+	return &arrayScanner[T]{dest: dest}
+}
+
+type arrayValue[T arrayElement] []T
+
+func (a arrayValue[T]) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b := []byte{'{'}
+	for i, element := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		switch v := any(element).(type) {
+		case string:
+			// Quoting every string keeps empty ones, NULL and delimiters literal
+			b = append(b, '"')
+			b = append(b, arrayEscaper.Replace(v)...)
+			b = append(b, '"')
+		case int64:
+			b = strconv.AppendInt(b, v, 10)
+		case float64:
+			b = strconv.AppendFloat(b, v, 'f', -1, 64)
+		case bool:
+			if v {
+				b = append(b, 't')
+			} else {
+				b = append(b, 'f')
+			}
+		}
+	}
+	return string(append(b, '}')), nil
+}
+
+var arrayEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+type arrayScanner[T arrayElement] struct {
+	dest *[]T
+}
+
+func (s *arrayScanner[T]) Scan(src interface{}) error {
+	var text string
+	switch src := src.(type) {
+	case nil:
+		*s.dest = nil
+		return nil
+	case string:
+		text = src
+	case []byte:
+		text = string(src)
+	default:
+		return fmt.Errorf("cannot scan %T into an array", src)
+	}
+
+	elements, err := parseArray(text)
+	if err != nil {
+		return err
+	}
+	values := make([]T, len(elements))
+	for i, element := range elements {
+		if err := parseArrayElement(element, &values[i]); err != nil {
+			return fmt.Errorf("array element %d: %w", i+1, err)
+		}
+	}
+	*s.dest = values
+	return nil
+}
+
+// parseArray splits a one-dimensional array literal such as {a,"b c"} into its
+// unquoted elements. NULL elements are rejected since the slices can't hold them.
+func parseArray(text string) ([]string, error) {
+	if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+		return nil, fmt.Errorf("invalid array %q", text)
+	}
+	body := text[1 : len(text)-1]
+	elements := []string{}
+	if body == "" {
+		return elements, nil
+	}
+
+	for i := 0; ; {
+		var element strings.Builder
+		quoted := i < len(body) && body[i] == '"'
+		if quoted {
+			i++
+		}
+		for ; i < len(body); i++ {
+			c := body[i]
+			if c == '\\' {
+				i++
+				if i == len(body) {
+					return nil, fmt.Errorf("invalid array %q", text)
+				}
+				element.WriteByte(body[i])
+				continue
+			}
+			if quoted && c == '"' {
+				break
+			}
+			if !quoted && c == ',' {
+				break
+			}
+			if !quoted && (c == '{' || c == '}' || c == '"') {
+				return nil, fmt.Errorf("unsupported array %q; only one dimension is read", text)
+			}
+			element.WriteByte(c)
+		}
+		if quoted {
+			if i == len(body) {
+				return nil, fmt.Errorf("invalid array %q", text)
+			}
+			i++
+		} else if strings.EqualFold(element.String(), "NULL") {
+			return nil, fmt.Errorf("array %q has a NULL element", text)
+		}
+		elements = append(elements, element.String())
+
+		if i == len(body) {
+			return elements, nil
+		}
+		if body[i] != ',' {
+			return nil, fmt.Errorf("invalid array %q", text)
+		}
+		i++
+	}
+}
+
+func parseArrayElement[T arrayElement](text string, dest *T) error {
+	var err error
+	switch d := any(dest).(type) {
+	case *string:
+		*d = text
+	case *int64:
+		*d, err = strconv.ParseInt(text, 10, 64)
+	case *float64:
+		*d, err = strconv.ParseFloat(text, 64)
+	case *bool:
+		*d, err = strconv.ParseBool(text)
+	}
+	return err
+}
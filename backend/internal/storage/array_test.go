@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArrayRoundTrip(t *testing.T) {
+	tags := []string{"plain", "with space", "comma,inside", `quote"and\slash`, "", "NULL", "{braces}"}
+	value, err := array(tags).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if err := scanArray(&got).Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Fatalf("round trip = %q, want %q", got, tags)
+	}
+
+	ids := []int64{1, -2, 3000000000}
+	value, _ = array(ids).Value()
+	var gotIDs []int64
+	if err := scanArray(&gotIDs).Scan([]byte(value.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotIDs, ids) {
+		t.Fatalf("round trip = %v, want %v", gotIDs, ids)
+	}
+}
+
+func TestArrayNullAndEmpty(t *testing.T) {
+	if value, _ := array([]string(nil)).Value(); value != nil {
+		t.Fatalf("nil slice = %v, want NULL", value)
+	}
+	if value, _ := array([]bool{}).Value(); value != "{}" {
+		t.Fatalf("empty slice = %v, want {}", value)
+	}
+
+	got := []string{"stale"}
+	if err := scanArray(&got).Scan(nil); err != nil || got != nil {
+		t.Fatalf("NULL scanned to %q, %v", got, err)
+	}
+	if err := scanArray(&got).Scan("{}"); err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("{} scanned to %#v, %v", got, err)
+	}
+}
+
+func TestScanArrayPostgresOutput(t *testing.T) {
+	// Postgres only quotes the elements that need it
+	var tags []string
+	if err := scanArray(&tags).Scan(`{lobby,"pool deck","",ap\\1}`); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"lobby", "pool deck", "", `ap\1`}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("scanned %q, want %q", tags, want)
+	}
+
+	var flags []bool
+	if err := scanArray(&flags).Scan("{t,f}"); err != nil || !reflect.DeepEqual(flags, []bool{true, false}) {
+		t.Fatalf("scanned %v, %v", flags, err)
+	}
+
+	for _, bad := range []string{"", "lobby", "{a,NULL}", "{{1,2},{3,4}}", `{"open}`} {
+		if err := scanArray(&tags).Scan(bad); err == nil {
+			t.Errorf("scanning %q succeeded", bad)
+		}
+	}
+}
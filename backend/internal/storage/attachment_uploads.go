@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Chunked attachment uploads
+const attachmentUploadColumns = `id, property_id, filename, description, mime_type, tags, file_size, received,
+	storage_type, storage_path, session, uploaded_by, created_at, updated_at`
+
+func scanAttachmentUpload(row rowScanner, u *models.AttachmentUpload) error {
+	return row.Scan(&u.ID, &u.PropertyID, &u.Filename, &u.Description, &u.MimeType, scanArray(&u.Tags), &u.FileSize,
+		&u.Received, &u.StorageType, &u.StoragePath, &u.Session, &u.UploadedBy, &u.CreatedAt, &u.UpdatedAt)
+}
+
+// CreateAttachmentUpload stores an upload in progress. The session is sealed with the
+// secrets key, since a GCS session URL accepts data without credentials.
+func (s *PostgresStore) CreateAttachmentUpload(ctx context.Context, u *models.AttachmentUpload) error {
+	if u.Tags == nil {
+		u.Tags = []string{}
+	}
+	session, err := s.sealSecret(u.Session)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO attachment_uploads (property_id, filename, description, mime_type, tags, file_size, received,
+			storage_type, storage_path, session, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, u.PropertyID, u.Filename, u.Description, u.MimeType, array(u.Tags),
+		u.FileSize, u.Received, u.StorageType, u.StoragePath, session, u.UploadedBy).Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+}
+
+func (s *PostgresStore) GetAttachmentUpload(ctx context.Context, id int64) (*models.AttachmentUpload, error) {
+	u := &models.AttachmentUpload{}
+	query := `SELECT ` + attachmentUploadColumns + ` FROM attachment_uploads WHERE id = $1`
+	err := scanAttachmentUpload(s.db.QueryRowContext(ctx, query, id), u)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment upload not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openSecret(&u.Session); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ListAttachmentUploadsForProperty returns a property's uploads in progress
+func (s *PostgresStore) ListAttachmentUploadsForProperty(ctx context.Context, propertyID int64) ([]models.AttachmentUpload, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+attachmentUploadColumns+`
+		FROM attachment_uploads WHERE property_id = $1 ORDER BY id`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := make([]models.AttachmentUpload, 0)
+	for rows.Next() {
+		var u models.AttachmentUpload
+		if err := scanAttachmentUpload(rows, &u); err != nil {
+			return nil, err
+		}
+		if err := s.openSecret(&u.Session); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// UpdateAttachmentUploadReceived records how much of an upload the blob store holds
+func (s *PostgresStore) UpdateAttachmentUploadReceived(ctx context.Context, id, received int64) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE attachment_uploads SET received = $1, updated_at = NOW() WHERE id = $2", received, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("attachment upload not found")
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteAttachmentUpload(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM attachment_uploads WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("attachment upload not found")
+	}
+	return nil
+}
+
+// CompleteAttachmentUpload creates the attachment a finished upload made and removes
+// the upload, together
+func (s *PostgresStore) CompleteAttachmentUpload(ctx context.Context, uploadID int64, a *models.Attachment) error {
+	return s.inTx(ctx, func(tx *PostgresStore) error {
+		if err := tx.DeleteAttachmentUpload(ctx, uploadID); err != nil {
+			return err
+		}
+		return tx.CreateAttachment(ctx, a)
+	})
+}
+
+// DeleteAttachmentUploadsBefore removes uploads started before the cutoff, which
+// their blob store has given up on
+func (s *PostgresStore) DeleteAttachmentUploadsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.deleteInBatches(ctx, `
+		DELETE FROM attachment_uploads WHERE id IN (
+			SELECT id FROM attachment_uploads WHERE created_at < $1 LIMIT $2)`, cutoff)
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Property badges
+const propertyBadgeColumns = `id, property_id, created_at, updated_at`
+
+func scanPropertyBadge(row rowScanner, b *models.PropertyBadge) error {
+	return row.Scan(&b.ID, &b.PropertyID, &b.CreatedAt, &b.UpdatedAt)
+}
+
+// CreatePropertyBadge stores a property's badge with the SHA-256 hash of its token; the
+// token itself is never stored
+func (s *PostgresStore) CreatePropertyBadge(ctx context.Context, b *models.PropertyBadge, tokenHash string) error {
+	query := `
+		INSERT INTO property_badges (property_id, token_hash)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, b.PropertyID, tokenHash).Scan(&b.ID, &b.CreatedAt, &b.UpdatedAt)
+}
+
+func (s *PostgresStore) GetPropertyBadge(ctx context.Context, propertyID int64) (*models.PropertyBadge, error) {
+	b := &models.PropertyBadge{}
+	query := `SELECT ` + propertyBadgeColumns + ` FROM property_badges WHERE property_id = $1`
+	err := scanPropertyBadge(s.db.QueryRowContext(ctx, query, propertyID), b)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("property badge not found")
+	}
+	return b, err
+}
+
+func (s *PostgresStore) GetPropertyBadgeByTokenHash(ctx context.Context, tokenHash string) (*models.PropertyBadge, error) {
+	b := &models.PropertyBadge{}
+	query := `SELECT ` + propertyBadgeColumns + ` FROM property_badges WHERE token_hash = $1`
+	err := scanPropertyBadge(s.db.QueryRowContext(ctx, query, tokenHash), b)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("property badge not found")
+	}
+	return b, err
+}
+
+// UpdatePropertyBadgeToken replaces a badge's token hash
+func (s *PostgresStore) UpdatePropertyBadgeToken(ctx context.Context, b *models.PropertyBadge, tokenHash string) error {
+	query := `
+		UPDATE property_badges SET token_hash = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING property_id, created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, tokenHash, b.ID).Scan(&b.PropertyID, &b.CreatedAt, &b.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("property badge not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeletePropertyBadge(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM property_badges WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("property badge not found")
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/gcs"
+	"github.com/etswifi/ets-noc/internal/s3"
+)
+
+// BlobStore keeps attachment and pfSense config backup files
+type BlobStore interface {
+	// Kind is recorded as an attachment's storage type: gcs, s3 or local
+	Kind() string
+	UploadFile(ctx context.Context, objectName string, reader io.Reader, contentType string) error
+	// GetSignedURL returns a link that downloads the file without signing in, valid
+	// for expiration
+	GetSignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, error)
+	DeleteFile(ctx context.Context, objectName string) error
+	// ListFiles calls fn for each file whose name starts with prefix, stopping at the
+	// first error fn returns
+	ListFiles(ctx context.Context, prefix string, fn func(name string, size int64, updated time.Time) error) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// ChunkedBlobStore is a BlobStore that can also take a file in parts sent over
+// several requests, so a large upload over a poor link can resume where it broke off
+type ChunkedBlobStore interface {
+	BlobStore
+	// StartUpload begins an upload of size bytes and returns the session its parts are
+	// sent to
+	StartUpload(ctx context.Context, objectName, contentType string, size int64) (string, error)
+	// UploadPart sends the part of the file starting at offset and returns how many
+	// bytes the store now holds, which may be fewer than were sent. Every part but the
+	// last is UploadChunkSize bytes, and the part that brings the file to size
+	// finishes it.
+	UploadPart(ctx context.Context, session string, offset int64, data []byte, size int64) (int64, error)
+	// AbortUpload discards an unfinished upload
+	AbortUpload(ctx context.Context, session string) error
+}
+
+const (
+	// UploadChunkSize is the size of each part of a chunked upload but the last, a
+	// multiple of the 256KiB GCS requires
+	UploadChunkSize = 8 << 20
+	// UploadSessionLifetime is how long a chunked upload can take; GCS forgets
+	// resumable upload sessions after a week
+	UploadSessionLifetime = 7 * 24 * time.Hour
+)
+
+var (
+	_ BlobStore        = (*gcs.Client)(nil)
+	_ BlobStore        = (*s3.Client)(nil)
+	_ ChunkedBlobStore = (*gcs.Client)(nil)
+	_ ChunkedBlobStore = (*LocalBlobStore)(nil)
+)
+
+// BlobOptions choose and configure a blob store. An empty Kind picks gcs when
+// GCSBucket is set.
+type BlobOptions struct {
+	Kind      string // gcs, s3 or local
+	GCSBucket string
+	S3        s3.Config
+	Dir       string // for local
+}
+
+// OpenBlobStore returns the blob store opts describe, or nil when none is configured,
+// which turns attachments and config backups off
+func OpenBlobStore(ctx context.Context, opts BlobOptions) (BlobStore, error) {
+	kind := opts.Kind
+	if kind == "" && opts.GCSBucket != "" {
+		kind = "gcs"
+	}
+
+	var blobs BlobStore
+	var err error
+	switch kind {
+	case "":
+		return nil, nil
+	case "gcs":
+		blobs, err = gcs.NewClient(ctx, opts.GCSBucket)
+	case "s3":
+		blobs, err = s3.NewClient(opts.S3)
+	case "local":
+		blobs, err = NewLocalBlobStore(opts.Dir)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return blobs, nil
+}
+
+// LocalBlobPath is where the API serves files from a LocalBlobStore
+const LocalBlobPath = "/api/v1/blobs/"
+
+// LocalBlobStore keeps files in a directory, for installs without cloud storage. The
+// API serves them itself under LocalBlobPath, behind links signed with a key made at
+// startup, so links stop working when the API restarts and only suit a single API
+// instance.
+type LocalBlobStore struct {
+	dir string
+	key []byte
+}
+
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("a directory is required for local blob storage")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{dir: dir, key: key}, nil
+}
+
+func (s *LocalBlobStore) Kind() string {
+	return "local"
+}
+
+// Path returns the file an object is kept in. Object names are cleaned first, so they
+// can't reach outside the directory.
+func (s *LocalBlobStore) Path(objectName string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+objectName)))
+}
+
+// UploadFile writes to a temporary file that is renamed into place, so a failed upload
+// leaves nothing behind
+func (s *LocalBlobStore) UploadFile(ctx context.Context, objectName string, reader io.Reader, contentType string) error {
+	dest := s.Path(objectName)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// GetSignedURL returns a link to the file under LocalBlobPath, relative to the API
+func (s *LocalBlobStore) GetSignedURL(ctx context.Context, objectName string, expiration time.Duration) (string, error) {
+	if _, err := os.Stat(s.Path(objectName)); err != nil {
+		return "", fmt.Errorf("failed to find file: %w", err)
+	}
+	expires := strconv.FormatInt(time.Now().Add(expiration).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.sign(objectName, expires)}}
+	return LocalBlobPath + (&url.URL{Path: objectName}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Verify reports whether a link's expiry and signature are valid for an object
+func (s *LocalBlobStore) Verify(objectName, expires, signature string) bool {
+	at, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > at {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(objectName, expires)))
+}
+
+func (s *LocalBlobStore) sign(objectName, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(objectName + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// uploadPath returns the file a chunked upload is assembled in. Local sessions are
+// the object names themselves.
+func (s *LocalBlobStore) uploadPath(session string) string {
+	sum := sha256.Sum256([]byte(session))
+	return filepath.Join(s.dir, ".uploads", hex.EncodeToString(sum[:]))
+}
+
+// StartUpload creates the file the upload is assembled in, first removing those of
+// uploads abandoned longer ago than UploadSessionLifetime
+func (s *LocalBlobStore) StartUpload(ctx context.Context, objectName, contentType string, size int64) (string, error) {
+	dir := filepath.Join(s.dir, ".uploads")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > UploadSessionLifetime {
+				os.Remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	f, err := os.Create(s.uploadPath(objectName))
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	f.Close()
+	return objectName, nil
+}
+
+// UploadPart writes a part at its offset, dropping anything after it left by an
+// earlier attempt, and moves the file into place once it is complete
+func (s *LocalBlobStore) UploadPart(ctx context.Context, session string, offset int64, data []byte, size int64) (int64, error) {
+	partial := s.uploadPath(session)
+	f, err := os.OpenFile(partial, os.O_WRONLY, 0)
+	if err != nil {
+		// A retried last part finds the file already finished
+		if info, statErr := os.Stat(s.Path(session)); statErr == nil && info.Size() == size {
+			return size, nil
+		}
+		return 0, fmt.Errorf("failed to open upload: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("failed to open upload: %w", err)
+	}
+	if offset > info.Size() {
+		f.Close()
+		return info.Size(), fmt.Errorf("upload only has %d bytes, can't write at %d", info.Size(), offset)
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+	if _, err := f.WriteAt(data, offset); err != nil {
+		f.Close()
+		return offset, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return offset, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	received := offset + int64(len(data))
+	if received < size {
+		return received, nil
+	}
+	dest := s.Path(session)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+		return offset, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Rename(partial, dest); err != nil {
+		return offset, fmt.Errorf("failed to write file: %w", err)
+	}
+	return received, nil
+}
+
+// AbortUpload removes the file an upload was being assembled in
+func (s *LocalBlobStore) AbortUpload(ctx context.Context, session string) error {
+	if err := os.Remove(s.uploadPath(session)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+// DeleteFile removes a file; one that is already gone is not an error
+func (s *LocalBlobStore) DeleteFile(ctx context.Context, objectName string) error {
+	if err := os.Remove(s.Path(objectName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// ListFiles walks the directory holding prefix, skipping the dot files and
+// directories that temporary and partial uploads are kept in
+func (s *LocalBlobStore) ListFiles(ctx context.Context, prefix string, fn func(name string, size int64, updated time.Time) error) error {
+	root := s.Path(prefix[:strings.LastIndex(prefix, "/")+1])
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && file != root {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(name, info.Size(), info.ModTime())
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	return nil
+}
+
+// Ping checks the directory can be written to
+func (s *LocalBlobStore) Ping(ctx context.Context) error {
+	f, err := os.CreateTemp(s.dir, ".ping-*")
+	if err != nil {
+		return fmt.Errorf("failed to write to blob directory: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func (s *LocalBlobStore) Close() error {
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Circuits
+const circuitColumns = `id, property_id, provider, circuit_id, account_number, support_phone, bandwidth_down_mbps,
+	bandwidth_up_mbps, static_ips, device_id, notes, created_at, updated_at`
+
+func scanCircuit(row rowScanner, c *models.Circuit) error {
+	return row.Scan(&c.ID, &c.PropertyID, &c.Provider, &c.CircuitID, &c.AccountNumber, &c.SupportPhone,
+		&c.BandwidthDownMbps, &c.BandwidthUpMbps, scanArray(&c.StaticIPs), &c.DeviceID, &c.Notes, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (s *PostgresStore) CreateCircuit(ctx context.Context, c *models.Circuit) error {
+	query := `
+		INSERT INTO circuits (property_id, provider, circuit_id, account_number, support_phone, bandwidth_down_mbps,
+			bandwidth_up_mbps, static_ips, device_id, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, c.PropertyID, c.Provider, c.CircuitID, c.AccountNumber, c.SupportPhone,
+		c.BandwidthDownMbps, c.BandwidthUpMbps, array(c.StaticIPs), c.DeviceID, c.Notes).
+		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (s *PostgresStore) GetCircuit(ctx context.Context, id int64) (*models.Circuit, error) {
+	c := &models.Circuit{}
+	query := `SELECT ` + circuitColumns + ` FROM circuits WHERE id = $1`
+	err := scanCircuit(s.db.QueryRowContext(ctx, query, id), c)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("circuit not found")
+	}
+	return c, err
+}
+
+func (s *PostgresStore) ListCircuitsForProperty(ctx context.Context, propertyID int64) ([]models.Circuit, error) {
+	query := `SELECT ` + circuitColumns + ` FROM circuits WHERE property_id = $1 ORDER BY provider, circuit_id`
+	rows, err := s.db.QueryContext(ctx, query, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	circuits := make([]models.Circuit, 0)
+	for rows.Next() {
+		var c models.Circuit
+		if err := scanCircuit(rows, &c); err != nil {
+			return nil, err
+		}
+		circuits = append(circuits, c)
+	}
+	return circuits, rows.Err()
+}
+
+func (s *PostgresStore) UpdateCircuit(ctx context.Context, c *models.Circuit) error {
+	query := `
+		UPDATE circuits
+		SET provider = $1, circuit_id = $2, account_number = $3, support_phone = $4, bandwidth_down_mbps = $5,
+			bandwidth_up_mbps = $6, static_ips = $7, device_id = $8, notes = $9, updated_at = NOW()
+		WHERE id = $10
+		RETURNING property_id, created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, c.Provider, c.CircuitID, c.AccountNumber, c.SupportPhone,
+		c.BandwidthDownMbps, c.BandwidthUpMbps, array(c.StaticIPs), c.DeviceID, c.Notes, c.ID).
+		Scan(&c.PropertyID, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("circuit not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteCircuit(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM circuits WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("circuit not found")
+	}
+	return nil
+}
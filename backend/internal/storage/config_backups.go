@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Config Backups
+const configBackupColumns = `id, property_id, storage_path, file_size, sha256, trigger, created_by, created_at`
+
+func scanConfigBackup(row rowScanner, b *models.ConfigBackup) error {
+	return row.Scan(&b.ID, &b.PropertyID, &b.StoragePath, &b.FileSize, &b.SHA256, &b.Trigger, &b.CreatedBy, &b.CreatedAt)
+}
+
+func (s *PostgresStore) CreateConfigBackup(ctx context.Context, b *models.ConfigBackup) error {
+	query := `
+		INSERT INTO config_backups (property_id, storage_path, file_size, sha256, trigger, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, b.PropertyID, b.StoragePath, b.FileSize, b.SHA256, b.Trigger, b.CreatedBy).
+		Scan(&b.ID, &b.CreatedAt)
+}
+
+func (s *PostgresStore) GetConfigBackup(ctx context.Context, id int64) (*models.ConfigBackup, error) {
+	b := &models.ConfigBackup{}
+	query := `SELECT ` + configBackupColumns + ` FROM config_backups WHERE id = $1`
+	err := scanConfigBackup(s.db.QueryRowContext(ctx, query, id), b)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("config backup not found")
+	}
+	return b, err
+}
+
+// GetLatestConfigBackup returns the newest backup for a property, or nil if it has none
+func (s *PostgresStore) GetLatestConfigBackup(ctx context.Context, propertyID int64) (*models.ConfigBackup, error) {
+	b := &models.ConfigBackup{}
+	query := `SELECT ` + configBackupColumns + ` FROM config_backups WHERE property_id = $1 ORDER BY created_at DESC LIMIT 1`
+	err := scanConfigBackup(s.db.QueryRowContext(ctx, query, propertyID), b)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (s *PostgresStore) ListConfigBackupsForProperty(ctx context.Context, propertyID int64) ([]models.ConfigBackup, error) {
+	query := `SELECT ` + configBackupColumns + ` FROM config_backups WHERE property_id = $1 ORDER BY created_at DESC`
+	rows, err := s.db.QueryContext(ctx, query, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backups := make([]models.ConfigBackup, 0)
+	for rows.Next() {
+		var b models.ConfigBackup
+		if err := scanConfigBackup(rows, &b); err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	return backups, rows.Err()
+}
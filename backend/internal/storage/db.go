@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// database runs PostgresStore's queries. Queries are written for Postgres; rebind
+// adapts them and their arguments when the connection is another database, and is
+// nil for Postgres.
+type database struct {
+	*sql.DB
+	// tx is set on the copy of a store handed to an InTx callback
+	tx     *sql.Tx
+	rebind func(query string, args []any) (string, []any)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction when there is one and the pool otherwise
+func (d *database) conn() queryer {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.DB
+}
+
+func (d *database) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if d.rebind != nil {
+		query, args = d.rebind(query, args)
+	}
+	return d.conn().ExecContext(ctx, query, args...)
+}
+
+func (d *database) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if d.rebind != nil {
+		query, args = d.rebind(query, args)
+	}
+	return d.conn().QueryContext(ctx, query, args...)
+}
+
+func (d *database) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if d.rebind != nil {
+		query, args = d.rebind(query, args)
+	}
+	return d.conn().QueryRowContext(ctx, query, args...)
+}
+
+// inTx runs fn with a copy of s whose queries run in one transaction, committing it
+// if fn returns nil and rolling it back otherwise
+func (s *PostgresStore) inTx(ctx context.Context, fn func(tx *PostgresStore) error) error {
+	if s.db.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	txStore := &PostgresStore{db: &database{DB: s.db.DB, tx: tx, rebind: s.db.rebind}, secrets: s.secrets}
+	if err := fn(txStore); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// InTx runs fn in a transaction, committed only if fn returns nil. Nested calls join
+// the outer transaction.
+func (s *PostgresStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.inTx(ctx, func(tx *PostgresStore) error { return fn(tx) })
+}
+
+// InTx runs fn in a transaction, committed only if fn returns nil
+func (s *SQLiteStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return s.inTx(ctx, func(tx *PostgresStore) error { return fn(&SQLiteStore{tx}) })
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Device Templates
+const deviceTemplateColumns = `id, name, description, device_type, is_critical, check_interval, retries,
+	timeout, tags, created_at, updated_at`
+
+func scanDeviceTemplate(row rowScanner, t *models.DeviceTemplate) error {
+	return row.Scan(&t.ID, &t.Name, &t.Description, &t.DeviceType, &t.IsCritical, &t.CheckInterval,
+		&t.Retries, &t.Timeout, scanArray(&t.Tags), &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (s *PostgresStore) CreateDeviceTemplate(ctx context.Context, t *models.DeviceTemplate) error {
+	query := `
+		INSERT INTO device_templates (name, description, device_type, is_critical, check_interval, retries,
+			timeout, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, t.Name, t.Description, t.DeviceType, t.IsCritical, t.CheckInterval,
+		t.Retries, t.Timeout, array(t.Tags)).
+		Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (s *PostgresStore) GetDeviceTemplate(ctx context.Context, id int64) (*models.DeviceTemplate, error) {
+	t := &models.DeviceTemplate{}
+	query := `SELECT ` + deviceTemplateColumns + ` FROM device_templates WHERE id = $1`
+	err := scanDeviceTemplate(s.db.QueryRowContext(ctx, query, id), t)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("device template not found")
+	}
+	return t, err
+}
+
+func (s *PostgresStore) ListDeviceTemplates(ctx context.Context) ([]models.DeviceTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+deviceTemplateColumns+` FROM device_templates ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]models.DeviceTemplate, 0)
+	for rows.Next() {
+		var t models.DeviceTemplate
+		if err := scanDeviceTemplate(rows, &t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *PostgresStore) UpdateDeviceTemplate(ctx context.Context, t *models.DeviceTemplate) error {
+	query := `
+		UPDATE device_templates
+		SET name = $1, description = $2, device_type = $3, is_critical = $4, check_interval = $5, retries = $6,
+			timeout = $7, tags = $8, updated_at = NOW()
+		WHERE id = $9
+		RETURNING created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, t.Name, t.Description, t.DeviceType, t.IsCritical, t.CheckInterval,
+		t.Retries, t.Timeout, array(t.Tags), t.ID).
+		Scan(&t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("device template not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteDeviceTemplate(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM device_templates WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("device template not found")
+	}
+	return nil
+}
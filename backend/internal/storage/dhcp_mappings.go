@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// DHCP Mapping Changes
+const dhcpMappingChangeColumns = `id, property_id, device_id, action, interface, mac_address, ip_address, hostname,
+	previous_ip_address, previous_hostname, success, error, created_by, created_at`
+
+func scanDHCPMappingChange(row rowScanner, ch *models.DHCPMappingChange) error {
+	return row.Scan(&ch.ID, &ch.PropertyID, &ch.DeviceID, &ch.Action, &ch.Interface, &ch.MACAddress, &ch.IPAddress,
+		&ch.Hostname, &ch.PreviousIP, &ch.PreviousHostname, &ch.Success, &ch.Error, &ch.CreatedBy, &ch.CreatedAt)
+}
+
+func (s *PostgresStore) CreateDHCPMappingChange(ctx context.Context, ch *models.DHCPMappingChange) error {
+	query := `
+		INSERT INTO dhcp_mapping_changes (property_id, device_id, action, interface, mac_address, ip_address, hostname,
+			previous_ip_address, previous_hostname, success, error, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, ch.PropertyID, ch.DeviceID, ch.Action, ch.Interface, ch.MACAddress,
+		ch.IPAddress, ch.Hostname, ch.PreviousIP, ch.PreviousHostname, ch.Success, ch.Error, ch.CreatedBy).
+		Scan(&ch.ID, &ch.CreatedAt)
+}
+
+// ListDHCPMappingChanges returns a property's static mapping changes, newest first
+func (s *PostgresStore) ListDHCPMappingChanges(ctx context.Context, propertyID int64) ([]models.DHCPMappingChange, error) {
+	query := `SELECT ` + dhcpMappingChangeColumns + ` FROM dhcp_mapping_changes WHERE property_id = $1 ORDER BY created_at DESC`
+	rows, err := s.db.QueryContext(ctx, query, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]models.DHCPMappingChange, 0)
+	for rows.Next() {
+		var ch models.DHCPMappingChange
+		if err := scanDHCPMappingChange(rows, &ch); err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	return changes, rows.Err()
+}
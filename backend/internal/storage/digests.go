@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Digest Subscriptions
+const digestSubscriptionColumns = `id, user_id, frequency, property_ids, notification_channel_id, send_hour, timezone,
+	enabled, last_sent_at, created_at, updated_at`
+
+func scanDigestSubscription(row rowScanner, d *models.DigestSubscription) error {
+	return row.Scan(&d.ID, &d.UserID, &d.Frequency, scanArray(&d.PropertyIDs), &d.NotificationChannelID,
+		&d.SendHour, &d.Timezone, &d.Enabled, &d.LastSentAt, &d.CreatedAt, &d.UpdatedAt)
+}
+
+func (s *PostgresStore) queryDigestSubscriptions(ctx context.Context, query string, args ...interface{}) ([]models.DigestSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]models.DigestSubscription, 0)
+	for rows.Next() {
+		var d models.DigestSubscription
+		if err := scanDigestSubscription(rows, &d); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, d)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (s *PostgresStore) CreateDigestSubscription(ctx context.Context, d *models.DigestSubscription) error {
+	query := `
+		INSERT INTO digest_subscriptions (user_id, frequency, property_ids, notification_channel_id, send_hour, timezone, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, d.UserID, d.Frequency, array(d.PropertyIDs), d.NotificationChannelID,
+		d.SendHour, d.Timezone, d.Enabled).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+}
+
+func (s *PostgresStore) GetDigestSubscription(ctx context.Context, id int64) (*models.DigestSubscription, error) {
+	d := &models.DigestSubscription{}
+	query := `SELECT ` + digestSubscriptionColumns + ` FROM digest_subscriptions WHERE id = $1`
+	err := scanDigestSubscription(s.db.QueryRowContext(ctx, query, id), d)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("digest subscription not found")
+	}
+	return d, err
+}
+
+func (s *PostgresStore) ListDigestSubscriptions(ctx context.Context) ([]models.DigestSubscription, error) {
+	return s.queryDigestSubscriptions(ctx, `SELECT `+digestSubscriptionColumns+` FROM digest_subscriptions ORDER BY id`)
+}
+
+func (s *PostgresStore) ListDigestSubscriptionsForUser(ctx context.Context, userID int64) ([]models.DigestSubscription, error) {
+	query := `SELECT ` + digestSubscriptionColumns + ` FROM digest_subscriptions WHERE user_id = $1 ORDER BY id`
+	return s.queryDigestSubscriptions(ctx, query, userID)
+}
+
+func (s *PostgresStore) UpdateDigestSubscription(ctx context.Context, d *models.DigestSubscription) error {
+	query := `
+		UPDATE digest_subscriptions
+		SET frequency = $1, property_ids = $2, notification_channel_id = $3, send_hour = $4, timezone = $5,
+			enabled = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING user_id, last_sent_at, created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, d.Frequency, array(d.PropertyIDs), d.NotificationChannelID, d.SendHour,
+		d.Timezone, d.Enabled, d.ID).Scan(&d.UserID, &d.LastSentAt, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("digest subscription not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) MarkDigestSent(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE digest_subscriptions SET last_sent_at = $1 WHERE id = $2", at, id)
+	return err
+}
+
+func (s *PostgresStore) DeleteDigestSubscription(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM digest_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("digest subscription not found")
+	}
+	return nil
+}
+
+// Outage Summaries
+
+// GetPropertyOutageSummaries returns red-status outages per property between start and
+// end from status_events, for the given properties or all when propertyIDs is empty.
+// An outage already in progress at start counts toward downtime but not the outage count.
+func (s *PostgresStore) GetPropertyOutageSummaries(ctx context.Context, propertyIDs []int64, start, end time.Time) ([]models.PropertyOutageSummary, error) {
+	query := `
+		WITH ev AS (
+			SELECT property_id, to_status, occurred_at,
+				LEAD(occurred_at) OVER (PARTITION BY property_id ORDER BY occurred_at, id) AS next_at
+			FROM status_events
+			WHERE entity_type = 'property' AND occurred_at < $2
+				AND (COALESCE(cardinality($3::bigint[]), 0) = 0 OR property_id = ANY($3))
+		)
+		SELECT ev.property_id, p.name,
+			COUNT(*) FILTER (WHERE ev.occurred_at >= $1),
+			COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(COALESCE(ev.next_at, $2), $2) - GREATEST(ev.occurred_at, $1)))), 0)
+		FROM ev
+		JOIN properties p ON p.id = ev.property_id
+		WHERE ev.to_status = 'red' AND COALESCE(ev.next_at, $2) > $1
+		GROUP BY ev.property_id, p.name
+		ORDER BY 4 DESC`
+	rows, err := s.db.QueryContext(ctx, query, start, end, array(propertyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.PropertyOutageSummary, 0)
+	for rows.Next() {
+		var o models.PropertyOutageSummary
+		var seconds float64
+		if err := rows.Scan(&o.PropertyID, &o.PropertyName, &o.OutageCount, &seconds); err != nil {
+			return nil, err
+		}
+		o.DowntimeMinutes = seconds / 60
+		summaries = append(summaries, o)
+	}
+	return summaries, rows.Err()
+}
+
+// GetWorstDevices returns the devices with the most offline time between start and
+// end, for the given properties or all when propertyIDs is empty
+func (s *PostgresStore) GetWorstDevices(ctx context.Context, propertyIDs []int64, start, end time.Time, limit int) ([]models.DeviceOutageSummary, error) {
+	query := `
+		WITH ev AS (
+			SELECT device_id, to_status, occurred_at,
+				LEAD(occurred_at) OVER (PARTITION BY device_id ORDER BY occurred_at, id) AS next_at
+			FROM status_events
+			WHERE entity_type = 'device' AND occurred_at < $2
+				AND (COALESCE(cardinality($3::bigint[]), 0) = 0 OR property_id = ANY($3))
+		)
+		SELECT ev.device_id, d.name, p.id, p.name,
+			COUNT(*) FILTER (WHERE ev.occurred_at >= $1),
+			COALESCE(SUM(EXTRACT(EPOCH FROM (LEAST(COALESCE(ev.next_at, $2), $2) - GREATEST(ev.occurred_at, $1)))), 0)
+		FROM ev
+		JOIN devices d ON d.id = ev.device_id
+		JOIN properties p ON p.id = d.property_id
+		WHERE ev.to_status = 'offline' AND COALESCE(ev.next_at, $2) > $1
+		GROUP BY ev.device_id, d.name, p.id, p.name
+		ORDER BY 6 DESC
+		LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, start, end, array(propertyIDs), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.DeviceOutageSummary, 0)
+	for rows.Next() {
+		var o models.DeviceOutageSummary
+		var seconds float64
+		if err := rows.Scan(&o.DeviceID, &o.DeviceName, &o.PropertyID, &o.PropertyName, &o.OutageCount, &seconds); err != nil {
+			return nil, err
+		}
+		o.DowntimeMinutes = seconds / 60
+		summaries = append(summaries, o)
+	}
+	return summaries, rows.Err()
+}
@@ -0,0 +1,785 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// FakeStore is an in-memory Store for handler and scheduler tests, needing neither a
+// database nor cgo. It keeps users, SSO identities, sessions, settings, regions,
+// properties, devices, maintenance windows, silences, acknowledgements, check history
+// and status events, with the ordering and not-found errors PostgresStore gives them. The other Store
+// methods aren't implemented and panic if called. InTx runs its function directly,
+// so a failed transaction isn't rolled back.
+type FakeStore struct {
+	Store // nil; only reached by methods the fake doesn't implement
+
+	mu               sync.Mutex
+	nextID           int64
+	users            map[int64]models.User
+	ssoIdentities    map[ssoIdentity]int64
+	sessions         map[int64]models.UserSession
+	settings         *models.Settings
+	regions          map[int64]models.Region
+	properties       map[int64]models.Property
+	devices          map[int64]models.Device
+	windows          map[int64]models.MaintenanceWindow
+	silences         map[int64]models.Silence
+	acknowledgements map[int64]models.Acknowledgement
+	history          map[int64][]models.DeviceStatus
+	statusEvents     []models.StatusEvent
+}
+
+var _ Store = (*FakeStore)(nil)
+
+// NewFakeStore creates an empty store with the default settings
+func NewFakeStore() *FakeStore {
+	return &FakeStore{
+		users:            make(map[int64]models.User),
+		ssoIdentities:    make(map[ssoIdentity]int64),
+		sessions:         make(map[int64]models.UserSession),
+		settings:         defaultSettings(),
+		regions:          make(map[int64]models.Region),
+		properties:       make(map[int64]models.Property),
+		devices:          make(map[int64]models.Device),
+		windows:          make(map[int64]models.MaintenanceWindow),
+		silences:         make(map[int64]models.Silence),
+		acknowledgements: make(map[int64]models.Acknowledgement),
+		history:          make(map[int64][]models.DeviceStatus),
+	}
+}
+
+// ssoIdentity keys the users linked to single sign-on identities
+type ssoIdentity struct {
+	issuer, subject string
+}
+
+// id returns the next row ID; IDs are unique across tables. Callers hold mu.
+func (f *FakeStore) id() int64 {
+	f.nextID++
+	return f.nextID
+}
+
+// page returns the part of items opts asks for
+func page[T any](items []T, opts ListOptions) []T {
+	if opts.Limit <= 0 {
+		return items
+	}
+	start := min(opts.Offset, len(items))
+	return items[start:min(start+opts.Limit, len(items))]
+}
+
+// sortedValues returns a map's values ordered by cmpFn
+func sortedValues[T any](m map[int64]T, cmpFn func(a, b T) int) []T {
+	values := make([]T, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, cmpFn)
+	return values
+}
+
+func (f *FakeStore) EnableSecretEncryption(encodedKey string) error {
+	return nil
+}
+
+func (f *FakeStore) EncryptExistingSecrets(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (f *FakeStore) Migrate(ctx context.Context) error {
+	return nil
+}
+
+func (f *FakeStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	return fn(f)
+}
+
+func (f *FakeStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (f *FakeStore) Close() error {
+	return nil
+}
+
+// Users and sessions
+
+func (f *FakeStore) CreateUser(ctx context.Context, u *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.users {
+		if existing.Username == u.Username {
+			return fmt.Errorf("username %q already exists", u.Username)
+		}
+	}
+	u.ID = f.id()
+	u.CreatedAt = time.Now()
+	u.UpdatedAt = u.CreatedAt
+	f.users[u.ID] = *u
+	return nil
+}
+
+func (f *FakeStore) GetUser(ctx context.Context, id int64) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &u, nil
+}
+
+func (f *FakeStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+// CreateUserFromOAuth adds an active user named by email. The fake doesn't set a
+// password, so the user can't sign in with one.
+func (f *FakeStore) CreateUserFromOAuth(ctx context.Context, email, name, role string) (*models.User, error) {
+	u := &models.User{Username: email, Email: email, Role: role, Active: true}
+	if err := f.CreateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (f *FakeStore) GetUserBySSOIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[f.ssoIdentities[ssoIdentity{issuer, subject}]]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &u, nil
+}
+
+func (f *FakeStore) LinkSSOIdentity(ctx context.Context, userID int64, issuer, subject string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := ssoIdentity{issuer, subject}
+	if _, ok := f.ssoIdentities[key]; ok {
+		return fmt.Errorf("SSO identity already linked")
+	}
+	f.ssoIdentities[key] = userID
+	return nil
+}
+
+func (f *FakeStore) ListUsers(ctx context.Context) ([]models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.users, func(a, b models.User) int { return cmp.Compare(a.Username, b.Username) }), nil
+}
+
+func (f *FakeStore) UpdateUser(ctx context.Context, u *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.users[u.ID]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	existing.Username, existing.Email, existing.Phone = u.Username, u.Email, u.Phone
+	existing.Role, existing.Active = u.Role, u.Active
+	existing.UpdatedAt = time.Now()
+	f.users[u.ID] = existing
+	*u = existing
+	return nil
+}
+
+func (f *FakeStore) UpdateUserPassword(ctx context.Context, userID int64, hashedPassword string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u, ok := f.users[userID]
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	u.Password = hashedPassword
+	u.UpdatedAt = time.Now()
+	f.users[userID] = u
+	return nil
+}
+
+func (f *FakeStore) DeleteUser(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.users[id]; !ok {
+		return fmt.Errorf("user not found")
+	}
+	delete(f.users, id)
+	for identity, userID := range f.ssoIdentities {
+		if userID == id {
+			delete(f.ssoIdentities, identity)
+		}
+	}
+	for sessionID, session := range f.sessions {
+		if session.UserID == id {
+			delete(f.sessions, sessionID)
+		}
+	}
+	return nil
+}
+
+func (f *FakeStore) CreateSession(ctx context.Context, session *models.UserSession) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	session.ID = f.id()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	f.sessions[session.ID] = *session
+	return nil
+}
+
+func (f *FakeStore) GetSession(ctx context.Context, id int64) (*models.UserSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("session not found")
+	}
+	return &session, nil
+}
+
+func (f *FakeStore) ListActiveSessions(ctx context.Context, userID int64) ([]models.UserSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	var sessions []models.UserSession
+	for _, session := range f.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b models.UserSession) int {
+		return cmp.Or(b.LastSeenAt.Compare(a.LastSeenAt), cmp.Compare(b.ID, a.ID))
+	})
+	return sessions, nil
+}
+
+func (f *FakeStore) TouchSession(ctx context.Context, id int64, ipAddress string, at time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if session, ok := f.sessions[id]; ok {
+		session.LastSeenAt = at
+		session.IPAddress = ipAddress
+		f.sessions[id] = session
+	}
+	return nil
+}
+
+func (f *FakeStore) RevokeSession(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	session, ok := f.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return fmt.Errorf("session not found")
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	f.sessions[id] = session
+	return nil
+}
+
+func (f *FakeStore) RevokeUserSessions(ctx context.Context, userID, keepID int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	var revoked int64
+	for id, session := range f.sessions {
+		if session.UserID == userID && id != keepID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			f.sessions[id] = session
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (f *FakeStore) GetSettings(ctx context.Context) (*models.Settings, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	settings := *f.settings
+	return &settings, nil
+}
+
+func (f *FakeStore) UpdateSettings(ctx context.Context, settings *models.Settings) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	settings.ID = settingsID
+	saved := *settings
+	f.settings = &saved
+	return nil
+}
+
+// Regions and properties
+
+func (f *FakeStore) CreateRegion(ctx context.Context, r *models.Region) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r.ID = f.id()
+	r.CreatedAt = time.Now()
+	r.UpdatedAt = r.CreatedAt
+	f.regions[r.ID] = *r
+	return nil
+}
+
+func (f *FakeStore) GetRegion(ctx context.Context, id int64) (*models.Region, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, ok := f.regions[id]
+	if !ok {
+		return nil, fmt.Errorf("region not found")
+	}
+	return &r, nil
+}
+
+func (f *FakeStore) ListRegions(ctx context.Context) ([]models.Region, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.regions, func(a, b models.Region) int { return cmp.Compare(a.Name, b.Name) }), nil
+}
+
+func (f *FakeStore) UpdateRegion(ctx context.Context, r *models.Region) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.regions[r.ID]
+	if !ok {
+		return fmt.Errorf("region not found")
+	}
+	existing.Name, existing.Description = r.Name, r.Description
+	existing.UpdatedAt = time.Now()
+	f.regions[r.ID] = existing
+	*r = existing
+	return nil
+}
+
+func (f *FakeStore) DeleteRegion(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.regions[id]; !ok {
+		return fmt.Errorf("region not found")
+	}
+	delete(f.regions, id)
+	return nil
+}
+
+// CreateProperty stores a property with its router device and an automatic subnet,
+// as PostgresStore does
+func (f *FakeStore) CreateProperty(ctx context.Context, p *models.Property) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	p.ID = f.id()
+
+	subnet, err := netip.ParsePrefix(p.Subnet)
+	if p.Subnet == "" {
+		var base netip.Addr
+		base, err = netip.ParseAddr(f.settings.SubnetBase)
+		if err == nil {
+			var taken []netip.Prefix
+			for _, other := range f.properties {
+				if prefix, err := netip.ParsePrefix(other.Subnet); err == nil {
+					taken = append(taken, prefix.Masked())
+				}
+			}
+			subnet, err = autoSubnet(base, p.ID, taken)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	subnet = subnet.Masked()
+	p.Subnet = subnet.String()
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	f.properties[p.ID] = *p
+
+	router := models.Device{
+		ID:          f.id(),
+		PropertyID:  p.ID,
+		Name:        fmt.Sprintf("%s-router", p.Name),
+		Hostname:    subnet.Addr().Next().String(),
+		DeviceType:  "Router",
+		Tags:        []string{"Router"},
+		IsCritical:  true,
+		Active:      true,
+		Description: "Auto-created router device",
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.CreatedAt,
+	}
+	f.devices[router.ID] = router
+	return nil
+}
+
+func (f *FakeStore) GetProperty(ctx context.Context, id int64) (*models.Property, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.properties[id]
+	if !ok {
+		return nil, fmt.Errorf("property not found")
+	}
+	p.PfSensePasswordSet = p.PfSensePassword != ""
+	return &p, nil
+}
+
+func (f *FakeStore) ListProperties(ctx context.Context) ([]models.Property, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.properties, func(a, b models.Property) int { return cmp.Compare(a.Name, b.Name) }), nil
+}
+
+// ListPropertiesPage filters, orders and pages like PostgresStore. Property groups
+// aren't kept, so a RegionID filter is an error.
+func (f *FakeStore) ListPropertiesPage(ctx context.Context, filter PropertyFilter) ([]models.Property, int, error) {
+	if filter.RegionID != 0 {
+		return nil, 0, fmt.Errorf("FakeStore can't filter properties by region")
+	}
+	field, desc := strings.CutPrefix(filter.Sort, "-")
+	if _, ok := PropertySorts[field]; field != "" && !ok {
+		return nil, 0, fmt.Errorf("invalid sort field %q", field)
+	}
+	byField := func(a, b models.Property) int {
+		var c int
+		switch field {
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case "updated_at":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			c = cmp.Compare(a.Name, b.Name)
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matching []models.Property
+	for _, p := range sortedValues(f.properties, byField) {
+		if filter.GroupID != 0 && (p.GroupID == nil || *p.GroupID != filter.GroupID) {
+			continue
+		}
+		if filter.Tag != "" && !slices.Contains(p.Tags, filter.Tag) {
+			continue
+		}
+		matching = append(matching, p)
+	}
+	return page(matching, filter.ListOptions), len(matching), nil
+}
+
+func (f *FakeStore) UpdateProperty(ctx context.Context, p *models.Property) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.properties[p.ID]
+	if !ok {
+		return fmt.Errorf("property not found")
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	// An empty password or subnet keeps the stored one
+	if p.PfSensePassword == "" {
+		p.PfSensePassword = existing.PfSensePassword
+	}
+	if p.Subnet == "" {
+		p.Subnet = existing.Subnet
+	}
+	p.CreatedAt = existing.CreatedAt
+	p.UpdatedAt = time.Now()
+	f.properties[p.ID] = *p
+	p.PfSensePasswordSet = p.PfSensePassword != ""
+	return nil
+}
+
+// DeleteProperty removes a property with its devices, as the schema cascades
+func (f *FakeStore) DeleteProperty(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.properties[id]; !ok {
+		return fmt.Errorf("property not found")
+	}
+	delete(f.properties, id)
+	for deviceID, d := range f.devices {
+		if d.PropertyID == id {
+			delete(f.devices, deviceID)
+			delete(f.history, deviceID)
+		}
+	}
+	return nil
+}
+
+// Attachments, uploads, digests, firewalls and webhooks aren't kept; these report none
+// so deleting a property and publishing events work
+
+func (f *FakeStore) ListAttachmentsForProperty(ctx context.Context, propertyID int64) ([]models.Attachment, error) {
+	return nil, nil
+}
+
+func (f *FakeStore) ListAttachmentUploadsForProperty(ctx context.Context, propertyID int64) ([]models.AttachmentUpload, error) {
+	return nil, nil
+}
+
+func (f *FakeStore) ListDigestSubscriptions(ctx context.Context) ([]models.DigestSubscription, error) {
+	return nil, nil
+}
+
+func (f *FakeStore) ListFirewallsForProperty(ctx context.Context, propertyID int64) ([]models.Firewall, error) {
+	return nil, nil
+}
+
+func (f *FakeStore) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return nil, nil
+}
+
+// Devices
+
+func (f *FakeStore) CreateDevice(ctx context.Context, d *models.Device) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.properties[d.PropertyID]; !ok {
+		return fmt.Errorf("property not found")
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	d.ID = f.id()
+	d.CreatedAt = time.Now()
+	d.UpdatedAt = d.CreatedAt
+	f.devices[d.ID] = *d
+	return nil
+}
+
+func (f *FakeStore) GetDevice(ctx context.Context, id int64) (*models.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.devices[id]
+	if !ok {
+		return nil, fmt.Errorf("device not found")
+	}
+	return &d, nil
+}
+
+// listDevices returns the devices keep accepts, ordered by name
+func (f *FakeStore) listDevices(keep func(d *models.Device) bool) []models.Device {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	devices := []models.Device{}
+	for _, d := range f.devices {
+		if keep(&d) {
+			devices = append(devices, d)
+		}
+	}
+	slices.SortFunc(devices, func(a, b models.Device) int { return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID)) })
+	return devices
+}
+
+func (f *FakeStore) ListDevices(ctx context.Context) ([]models.Device, error) {
+	return f.listDevices(func(d *models.Device) bool { return true }), nil
+}
+
+func (f *FakeStore) ListDevicesForProperty(ctx context.Context, propertyID int64) ([]models.Device, error) {
+	return f.listDevices(func(d *models.Device) bool { return d.PropertyID == propertyID }), nil
+}
+
+func (f *FakeStore) ListActiveDevices(ctx context.Context) ([]models.Device, error) {
+	return f.listDevices(func(d *models.Device) bool { return d.Active }), nil
+}
+
+func (f *FakeStore) UpdateDevice(ctx context.Context, d *models.Device) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.devices[d.ID]
+	if !ok {
+		return fmt.Errorf("device not found")
+	}
+	if d.Tags == nil {
+		d.Tags = []string{}
+	}
+	d.CreatedAt = existing.CreatedAt
+	d.UpdatedAt = time.Now()
+	f.devices[d.ID] = *d
+	return nil
+}
+
+func (f *FakeStore) DeleteDevice(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.devices[id]; !ok {
+		return fmt.Errorf("device not found")
+	}
+	delete(f.devices, id)
+	delete(f.history, id)
+	return nil
+}
+
+// Alerts and maintenance
+
+func (f *FakeStore) CreateAcknowledgement(ctx context.Context, a *models.Acknowledgement) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a.ID = f.id()
+	a.CreatedAt = time.Now()
+	f.acknowledgements[a.ID] = *a
+	return nil
+}
+
+func (f *FakeStore) ListActiveAcknowledgements(ctx context.Context) ([]models.Acknowledgement, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var active []models.Acknowledgement
+	for _, a := range f.acknowledgements {
+		if a.ClearedAt == nil {
+			active = append(active, a)
+		}
+	}
+	slices.SortFunc(active, func(a, b models.Acknowledgement) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return active, nil
+}
+
+// clearAcknowledgements clears the uncleared acknowledgements match accepts
+func (f *FakeStore) clearAcknowledgements(match func(a *models.Acknowledgement) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for id, a := range f.acknowledgements {
+		if a.ClearedAt == nil && match(&a) {
+			a.ClearedAt = &now
+			f.acknowledgements[id] = a
+		}
+	}
+}
+
+func (f *FakeStore) ClearPropertyAcknowledgement(ctx context.Context, propertyID int64) error {
+	f.clearAcknowledgements(func(a *models.Acknowledgement) bool {
+		return a.EntityType == "property" && a.PropertyID == propertyID
+	})
+	return nil
+}
+
+func (f *FakeStore) ClearDeviceAcknowledgement(ctx context.Context, deviceID int64) error {
+	f.clearAcknowledgements(func(a *models.Acknowledgement) bool {
+		return a.EntityType == "device" && a.DeviceID != nil && *a.DeviceID == deviceID
+	})
+	return nil
+}
+
+func (f *FakeStore) CreateSilence(ctx context.Context, silence *models.Silence) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	silence.ID = f.id()
+	silence.CreatedAt = time.Now()
+	f.silences[silence.ID] = *silence
+	return nil
+}
+
+func (f *FakeStore) ListActiveSilences(ctx context.Context, at time.Time) ([]models.Silence, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var active []models.Silence
+	for _, silence := range f.silences {
+		if silence.ExpiresAt.After(at) {
+			active = append(active, silence)
+		}
+	}
+	slices.SortFunc(active, func(a, b models.Silence) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return active, nil
+}
+
+func (f *FakeStore) CreateMaintenanceWindow(ctx context.Context, w *models.MaintenanceWindow) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.ID = f.id()
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+	f.windows[w.ID] = *w
+	return nil
+}
+
+func (f *FakeStore) ListMaintenanceWindows(ctx context.Context) ([]models.MaintenanceWindow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.windows, func(a, b models.MaintenanceWindow) int { return b.StartsAt.Compare(a.StartsAt) }), nil
+}
+
+func (f *FakeStore) ListCurrentMaintenanceWindows(ctx context.Context, at time.Time) ([]models.MaintenanceWindow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var current []models.MaintenanceWindow
+	for _, w := range f.windows {
+		if w.EndsAt.After(at) {
+			current = append(current, w)
+		}
+	}
+	slices.SortFunc(current, func(a, b models.MaintenanceWindow) int { return a.StartsAt.Compare(b.StartsAt) })
+	return current, nil
+}
+
+// History
+
+func (f *FakeStore) AddDeviceHistory(ctx context.Context, status *models.DeviceStatus) error {
+	return f.AddDeviceHistoryBatch(ctx, []*models.DeviceStatus{status})
+}
+
+func (f *FakeStore) AddDeviceHistoryBatch(ctx context.Context, statuses []*models.DeviceStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, status := range statuses {
+		f.history[status.DeviceID] = append(f.history[status.DeviceID], *status)
+	}
+	return nil
+}
+
+func (f *FakeStore) GetDeviceHistory(ctx context.Context, deviceID int64, startTime, endTime time.Time) ([]models.DeviceHistory, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	history := []models.DeviceHistory{}
+	for _, status := range f.history[deviceID] {
+		if !status.LastCheck.Before(startTime) && !status.LastCheck.After(endTime) {
+			history = append(history, models.DeviceHistory{
+				Timestamp:    status.LastCheck.Unix(),
+				Status:       status.Status,
+				StateType:    status.StateType,
+				ResponseTime: status.ResponseTime,
+				Message:      status.Message,
+				Maintenance:  status.Maintenance,
+			})
+		}
+	}
+	return history, nil
+}
+
+func (f *FakeStore) CreateStatusEvent(ctx context.Context, e *models.StatusEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e.ID = f.id()
+	f.statusEvents = append(f.statusEvents, *e)
+	return nil
+}
+
+// StatusEvents returns the status events recorded so far, oldest first
+func (f *FakeStore) StatusEvents() []models.StatusEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.statusEvents)
+}
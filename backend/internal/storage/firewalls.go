@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Firewalls
+const firewallColumns = `id, property_id, name, host, port, username, password, expected_carp_state, created_at, updated_at`
+
+func (s *PostgresStore) scanFirewall(row rowScanner, f *models.Firewall) error {
+	if err := row.Scan(&f.ID, &f.PropertyID, &f.Name, &f.Host, &f.Port, &f.Username, &f.Password,
+		&f.ExpectedCARPState, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return err
+	}
+	return s.openSecret(&f.Password)
+}
+
+func (s *PostgresStore) CreateFirewall(ctx context.Context, f *models.Firewall) error {
+	password, err := s.sealSecret(f.Password)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO firewalls (property_id, name, host, port, username, password, expected_carp_state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, f.PropertyID, f.Name, f.Host, f.Port, f.Username, password,
+		f.ExpectedCARPState).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+}
+
+func (s *PostgresStore) GetFirewall(ctx context.Context, id int64) (*models.Firewall, error) {
+	f := &models.Firewall{}
+	query := `SELECT ` + firewallColumns + ` FROM firewalls WHERE id = $1`
+	err := s.scanFirewall(s.db.QueryRowContext(ctx, query, id), f)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("firewall not found")
+	}
+	return f, err
+}
+
+func (s *PostgresStore) ListFirewallsForProperty(ctx context.Context, propertyID int64) ([]models.Firewall, error) {
+	query := `SELECT ` + firewallColumns + ` FROM firewalls WHERE property_id = $1 ORDER BY name`
+	rows, err := s.db.QueryContext(ctx, query, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	firewalls := make([]models.Firewall, 0)
+	for rows.Next() {
+		var f models.Firewall
+		if err := s.scanFirewall(rows, &f); err != nil {
+			return nil, err
+		}
+		firewalls = append(firewalls, f)
+	}
+	return firewalls, rows.Err()
+}
+
+// UpdateFirewall saves a firewall; an empty password keeps the stored one
+func (s *PostgresStore) UpdateFirewall(ctx context.Context, f *models.Firewall) error {
+	password, err := s.sealSecret(f.Password)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE firewalls
+		SET name = $1, host = $2, port = $3, username = $4, password = COALESCE(NULLIF($5, ''), password),
+			expected_carp_state = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING property_id, created_at, updated_at`
+	err = s.db.QueryRowContext(ctx, query, f.Name, f.Host, f.Port, f.Username, password,
+		f.ExpectedCARPState, f.ID).Scan(&f.PropertyID, &f.CreatedAt, &f.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("firewall not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteFirewall(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM firewalls WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("firewall not found")
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Regions
+const regionColumns = `id, name, description, created_at, updated_at`
+
+func (s *PostgresStore) CreateRegion(ctx context.Context, r *models.Region) error {
+	query := `
+		INSERT INTO regions (name, description)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, r.Name, r.Description).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (s *PostgresStore) GetRegion(ctx context.Context, id int64) (*models.Region, error) {
+	r := &models.Region{}
+	query := `SELECT ` + regionColumns + ` FROM regions WHERE id = $1`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("region not found")
+	}
+	return r, err
+}
+
+func (s *PostgresStore) ListRegions(ctx context.Context) ([]models.Region, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+regionColumns+` FROM regions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	regions := make([]models.Region, 0)
+	for rows.Next() {
+		var r models.Region
+		if err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		regions = append(regions, r)
+	}
+	return regions, rows.Err()
+}
+
+func (s *PostgresStore) UpdateRegion(ctx context.Context, r *models.Region) error {
+	query := `
+		UPDATE regions
+		SET name = $1, description = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, r.Name, r.Description, r.ID).Scan(&r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("region not found")
+	}
+	return err
+}
+
+// DeleteRegion deletes a region with its groups; their properties become ungrouped
+func (s *PostgresStore) DeleteRegion(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM regions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("region not found")
+	}
+	return nil
+}
+
+// Property Groups
+const propertyGroupColumns = `id, region_id, name, description, created_at, updated_at`
+
+func (s *PostgresStore) CreatePropertyGroup(ctx context.Context, g *models.PropertyGroup) error {
+	query := `
+		INSERT INTO property_groups (region_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, g.RegionID, g.Name, g.Description).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+}
+
+func (s *PostgresStore) GetPropertyGroup(ctx context.Context, id int64) (*models.PropertyGroup, error) {
+	g := &models.PropertyGroup{}
+	query := `SELECT ` + propertyGroupColumns + ` FROM property_groups WHERE id = $1`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&g.ID, &g.RegionID, &g.Name, &g.Description, &g.CreatedAt, &g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("property group not found")
+	}
+	return g, err
+}
+
+// ListPropertyGroups returns the groups of a region, or every group when regionID is 0
+func (s *PostgresStore) ListPropertyGroups(ctx context.Context, regionID int64) ([]models.PropertyGroup, error) {
+	query := `SELECT ` + propertyGroupColumns + ` FROM property_groups`
+	var args []interface{}
+	if regionID != 0 {
+		query += ` WHERE region_id = $1`
+		args = append(args, regionID)
+	}
+	rows, err := s.db.QueryContext(ctx, query+` ORDER BY name, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]models.PropertyGroup, 0)
+	for rows.Next() {
+		var g models.PropertyGroup
+		if err := rows.Scan(&g.ID, &g.RegionID, &g.Name, &g.Description, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (s *PostgresStore) UpdatePropertyGroup(ctx context.Context, g *models.PropertyGroup) error {
+	query := `
+		UPDATE property_groups
+		SET region_id = $1, name = $2, description = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, g.RegionID, g.Name, g.Description, g.ID).Scan(&g.CreatedAt, &g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("property group not found")
+	}
+	return err
+}
+
+// DeletePropertyGroup deletes a group; its properties become ungrouped
+func (s *PostgresStore) DeletePropertyGroup(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM property_groups WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("property group not found")
+	}
+	return nil
+}
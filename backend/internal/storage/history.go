@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Device History
+func (s *PostgresStore) AddDeviceHistory(ctx context.Context, status *models.DeviceStatus) error {
+	query := `
+		INSERT INTO device_history (device_id, checked_at, status, state_type, response_time, message, maintenance)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := s.db.ExecContext(ctx, query, status.DeviceID, status.LastCheck, status.Status, status.StateType,
+		status.ResponseTime, status.Message, status.Maintenance)
+	return err
+}
+
+// AddDeviceHistoryBatch stores the history of a whole check run in one insert
+func (s *PostgresStore) AddDeviceHistoryBatch(ctx context.Context, statuses []*models.DeviceStatus) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	deviceIDs := make([]int64, len(statuses))
+	checkedAt := make([]string, len(statuses))
+	states := make([]string, len(statuses))
+	stateTypes := make([]string, len(statuses))
+	responseTimes := make([]float64, len(statuses))
+	messages := make([]string, len(statuses))
+	maintenance := make([]bool, len(statuses))
+	for i, status := range statuses {
+		deviceIDs[i] = status.DeviceID
+		checkedAt[i] = status.LastCheck.Format(time.RFC3339Nano)
+		states[i] = status.Status
+		stateTypes[i] = status.StateType
+		responseTimes[i] = status.ResponseTime
+		messages[i] = status.Message
+		maintenance[i] = status.Maintenance
+	}
+
+	query := `
+		INSERT INTO device_history (device_id, checked_at, status, state_type, response_time, message, maintenance)
+		SELECT * FROM unnest($1::bigint[], $2::timestamptz[], $3::text[], $4::text[], $5::double precision[], $6::text[], $7::boolean[])`
+	_, err := s.db.ExecContext(ctx, query, array(deviceIDs), array(checkedAt), array(states),
+		array(stateTypes), array(responseTimes), array(messages), array(maintenance))
+	return err
+}
+
+func (s *PostgresStore) queryDeviceHistory(ctx context.Context, query string, args ...interface{}) ([]models.DeviceHistory, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]models.DeviceHistory, 0)
+	for rows.Next() {
+		var h models.DeviceHistory
+		var checkedAt time.Time
+		if err := rows.Scan(&checkedAt, &h.Status, &h.StateType, &h.ResponseTime, &h.Message, &h.Maintenance); err != nil {
+			return nil, err
+		}
+		h.Timestamp = checkedAt.Unix()
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+func (s *PostgresStore) GetDeviceHistory(ctx context.Context, deviceID int64, startTime, endTime time.Time) ([]models.DeviceHistory, error) {
+	query := `
+		SELECT checked_at, status, state_type, response_time, message, maintenance
+		FROM device_history
+		WHERE device_id = $1 AND checked_at BETWEEN $2 AND $3
+		ORDER BY checked_at`
+	return s.queryDeviceHistory(ctx, query, deviceID, startTime, endTime)
+}
+
+// HistoryAggregates are the response time aggregates GetDeviceHistoryBuckets can take,
+// with the Postgres expression for each
+var HistoryAggregates = map[string]string{
+	"avg": "AVG(response_time)",
+	"min": "MIN(response_time)",
+	"max": "MAX(response_time)",
+	"p95": "percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time)",
+}
+
+// GetDeviceHistoryBuckets downsamples a device's raw history in [startTime, endTime]
+// into buckets of interval aligned to the Unix epoch, aggregating the response times of
+// successful checks with one of HistoryAggregates. Empty buckets are omitted.
+func (s *PostgresStore) GetDeviceHistoryBuckets(ctx context.Context, deviceID int64, interval time.Duration, aggregate string, startTime, endTime time.Time) ([]models.DeviceHistoryBucket, error) {
+	expr, ok := HistoryAggregates[aggregate]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregate %q", aggregate)
+	}
+	query := `
+		SELECT (floor(EXTRACT(EPOCH FROM checked_at) / $4::bigint) * $4::bigint)::bigint,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'online' OR state_type = 'soft'),
+			COUNT(*) FILTER (WHERE status = 'offline' AND state_type <> 'soft'),
+			COUNT(*) FILTER (WHERE status = 'unreachable'),
+			` + expr + ` FILTER (WHERE status = 'online')
+		FROM device_history
+		WHERE device_id = $1 AND checked_at BETWEEN $2 AND $3
+		GROUP BY 1
+		ORDER BY 1`
+	rows, err := s.db.QueryContext(ctx, query, deviceID, startTime, endTime, int64(interval.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]models.DeviceHistoryBucket, 0)
+	for rows.Next() {
+		var b models.DeviceHistoryBucket
+		if err := rows.Scan(&b.Timestamp, &b.Checks, &b.OnlineChecks, &b.OfflineChecks, &b.UnreachableChecks, &b.ResponseTime); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// GetDeviceErrors returns the most recent offline checks for a device, newest first
+func (s *PostgresStore) GetDeviceErrors(ctx context.Context, deviceID int64, limit int) ([]models.DeviceHistory, error) {
+	query := `
+		SELECT checked_at, status, state_type, response_time, message, maintenance
+		FROM device_history
+		WHERE device_id = $1 AND status = 'offline'
+		ORDER BY checked_at DESC
+		LIMIT $2`
+	return s.queryDeviceHistory(ctx, query, deviceID, limit)
+}
+
+// RollupDeviceHistory (re)computes hourly rollups from raw history and daily rollups
+// from hourly ones for every bucket at or after since. Buckets are upserted, so
+// re-running over the same range is safe.
+func (s *PostgresStore) RollupDeviceHistory(ctx context.Context, since time.Time) error {
+	hourly := `
+		INSERT INTO device_history_rollups (device_id, resolution, bucket_start, checks, online_checks,
+			offline_checks, unreachable_checks, maintenance_checks, avg_response_time, min_response_time, max_response_time)
+		SELECT device_id, 'hour', date_trunc('hour', checked_at),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'online' OR state_type = 'soft'),
+			COUNT(*) FILTER (WHERE status = 'offline' AND state_type <> 'soft'),
+			COUNT(*) FILTER (WHERE status = 'unreachable'),
+			COUNT(*) FILTER (WHERE maintenance AND status <> 'online' AND state_type <> 'soft'),
+			AVG(response_time) FILTER (WHERE status = 'online'),
+			MIN(response_time) FILTER (WHERE status = 'online'),
+			MAX(response_time) FILTER (WHERE status = 'online')
+		FROM device_history
+		WHERE checked_at >= date_trunc('hour', $1::timestamptz)
+		GROUP BY device_id, date_trunc('hour', checked_at)
+		ON CONFLICT (device_id, resolution, bucket_start) DO UPDATE SET
+			checks = EXCLUDED.checks,
+			online_checks = EXCLUDED.online_checks,
+			offline_checks = EXCLUDED.offline_checks,
+			unreachable_checks = EXCLUDED.unreachable_checks,
+			maintenance_checks = EXCLUDED.maintenance_checks,
+			avg_response_time = EXCLUDED.avg_response_time,
+			min_response_time = EXCLUDED.min_response_time,
+			max_response_time = EXCLUDED.max_response_time`
+	if _, err := s.db.ExecContext(ctx, hourly, since); err != nil {
+		return err
+	}
+
+	// Daily average is weighted by the number of successful checks in each hour
+	daily := `
+		INSERT INTO device_history_rollups (device_id, resolution, bucket_start, checks, online_checks,
+			offline_checks, unreachable_checks, maintenance_checks, avg_response_time, min_response_time, max_response_time)
+		SELECT device_id, 'day', date_trunc('day', bucket_start),
+			SUM(checks), SUM(online_checks), SUM(offline_checks), SUM(unreachable_checks), SUM(maintenance_checks),
+			SUM(avg_response_time * online_checks) / NULLIF(SUM(online_checks) FILTER (WHERE avg_response_time IS NOT NULL), 0),
+			MIN(min_response_time),
+			MAX(max_response_time)
+		FROM device_history_rollups
+		WHERE resolution = 'hour' AND bucket_start >= date_trunc('day', $1::timestamptz)
+		GROUP BY device_id, date_trunc('day', bucket_start)
+		ON CONFLICT (device_id, resolution, bucket_start) DO UPDATE SET
+			checks = EXCLUDED.checks,
+			online_checks = EXCLUDED.online_checks,
+			offline_checks = EXCLUDED.offline_checks,
+			unreachable_checks = EXCLUDED.unreachable_checks,
+			maintenance_checks = EXCLUDED.maintenance_checks,
+			avg_response_time = EXCLUDED.avg_response_time,
+			min_response_time = EXCLUDED.min_response_time,
+			max_response_time = EXCLUDED.max_response_time`
+	_, err := s.db.ExecContext(ctx, daily, since)
+	return err
+}
+
+// GetDeviceHistoryRollups returns a device's rollups at the given resolution with
+// bucket_start in [startTime, endTime]
+func (s *PostgresStore) GetDeviceHistoryRollups(ctx context.Context, deviceID int64, resolution string, startTime, endTime time.Time) ([]models.DeviceHistoryRollup, error) {
+	query := `
+		SELECT device_id, resolution, bucket_start, checks, online_checks, offline_checks, unreachable_checks,
+			maintenance_checks, avg_response_time, min_response_time, max_response_time
+		FROM device_history_rollups
+		WHERE device_id = $1 AND resolution = $2 AND bucket_start BETWEEN $3 AND $4
+		ORDER BY bucket_start`
+	rows, err := s.db.QueryContext(ctx, query, deviceID, resolution, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := make([]models.DeviceHistoryRollup, 0)
+	for rows.Next() {
+		var r models.DeviceHistoryRollup
+		if err := rows.Scan(&r.DeviceID, &r.Resolution, &r.BucketStart, &r.Checks, &r.OnlineChecks, &r.OfflineChecks,
+			&r.UnreachableChecks, &r.MaintenanceChecks, &r.AvgResponseTime, &r.MinResponseTime, &r.MaxResponseTime); err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, r)
+	}
+	return rollups, rows.Err()
+}
+
+// GetLatencyTrend returns the mean response time of successful checks across all
+// devices for each day with rollups in [startTime, endTime), weighted by each device's
+// successful checks
+func (s *PostgresStore) GetLatencyTrend(ctx context.Context, startTime, endTime time.Time) ([]models.LatencyPoint, error) {
+	query := `
+		SELECT bucket_start,
+			SUM(avg_response_time * online_checks) / NULLIF(SUM(online_checks) FILTER (WHERE avg_response_time IS NOT NULL), 0),
+			SUM(checks)
+		FROM device_history_rollups
+		WHERE resolution = 'day' AND bucket_start >= $1 AND bucket_start < $2
+		GROUP BY bucket_start
+		ORDER BY bucket_start`
+	rows, err := s.db.QueryContext(ctx, query, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]models.LatencyPoint, 0)
+	for rows.Next() {
+		var p models.LatencyPoint
+		if err := rows.Scan(&p.Day, &p.AvgResponseTime, &p.Checks); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// GetDeviceUptime computes availability for each device over [startTime, endTime)
+// from raw history. Each check's state is held until the next check (or endTime for
+// the last one); an outage is a run of down checks. Devices without history in the
+// period are omitted.
+func (s *PostgresStore) GetDeviceUptime(ctx context.Context, deviceIDs []int64, startTime, endTime time.Time) (map[int64]*models.UptimeReport, error) {
+	query := `
+		WITH checks AS (
+			SELECT device_id, checked_at,
+				(status <> 'online' AND state_type <> 'soft' AND NOT maintenance) AS down,
+				LEAD(checked_at) OVER (PARTITION BY device_id ORDER BY checked_at) AS next_at
+			FROM device_history
+			WHERE device_id = ANY($1) AND checked_at >= $2 AND checked_at < $3
+		), spans AS (
+			SELECT device_id, down,
+				LAG(down) OVER (PARTITION BY device_id ORDER BY checked_at) AS prev_down,
+				EXTRACT(EPOCH FROM (COALESCE(next_at, LEAST($3::timestamptz, NOW())) - checked_at)) AS seconds
+			FROM checks
+		)
+		SELECT device_id,
+			COUNT(*),
+			COALESCE(SUM(seconds), 0),
+			COALESCE(SUM(seconds) FILTER (WHERE down), 0),
+			COUNT(*) FILTER (WHERE down AND NOT COALESCE(prev_down, false))
+		FROM spans
+		GROUP BY device_id`
+	rows, err := s.db.QueryContext(ctx, query, array(deviceIDs), startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make(map[int64]*models.UptimeReport)
+	for rows.Next() {
+		var deviceID int64
+		var monitoredSeconds, downSeconds float64
+		r := &models.UptimeReport{PeriodStart: startTime, PeriodEnd: endTime}
+		if err := rows.Scan(&deviceID, &r.Checks, &monitoredSeconds, &downSeconds, &r.OutageCount); err != nil {
+			return nil, err
+		}
+		r.MonitoredMinutes = monitoredSeconds / 60
+		r.DowntimeMinutes = downSeconds / 60
+		if monitoredSeconds > 0 {
+			uptime := 100 * (monitoredSeconds - downSeconds) / monitoredSeconds
+			r.UptimePercent = &uptime
+		}
+		reports[deviceID] = r
+	}
+	return reports, rows.Err()
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/etswifi/ets-noc/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+)
+
+// Error counting for the /metrics endpoint. Postgres connections are wrapped so every
+// failed query or exec is counted without touching each store method; Redis uses a
+// client hook. Missing keys and rows are not errors.
+
+type countingConnector struct {
+	connector driver.Connector
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		countPostgresError(err)
+		return nil, err
+	}
+	return &countingConn{conn.(pgxConn)}, nil
+}
+
+func (c *countingConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// pgxConn is the set of driver interfaces pgx's database/sql connections implement.
+// NamedValueChecker lets arguments through to pgx unconverted.
+type pgxConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.NamedValueChecker
+}
+
+type countingConn struct {
+	pgxConn
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.pgxConn.QueryContext(ctx, query, args)
+	countPostgresError(err)
+	return rows, err
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.pgxConn.ExecContext(ctx, query, args)
+	countPostgresError(err)
+	return result, err
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.pgxConn.BeginTx(ctx, opts)
+	countPostgresError(err)
+	return tx, err
+}
+
+func countPostgresError(err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) && !errors.Is(err, driver.ErrBadConn) {
+		metrics.StoreErrors.WithLabelValues("postgres").Inc()
+	}
+}
+
+func newCountingConnector(config *pgx.ConnConfig) driver.Connector {
+	return &countingConnector{connector: stdlib.GetConnector(*config)}
+}
+
+type countingHook struct{}
+
+func (countingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (countingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		countRedisError(err)
+		return err
+	}
+}
+
+func (countingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		countRedisError(err)
+		return err
+	}
+}
+
+func countRedisError(err error) {
+	if err != nil && err != redis.Nil {
+		metrics.StoreErrors.WithLabelValues("redis").Inc()
+	}
+}
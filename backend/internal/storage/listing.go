@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// ListOptions pages and orders a listing. Sort names one of the listing's sort fields,
+// prefixed with "-" for descending, and is empty for the default order. A zero Limit
+// returns every row; Offset only applies with a Limit.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Sort   string
+}
+
+// Sort fields accepted by each listing, mapped to the columns they order by
+var (
+	DeviceSorts = map[string]string{
+		"name":             "name",
+		"hostname":         "hostname",
+		"type":             "device_type",
+		"property_id":      "property_id",
+		"warranty_expires": "warranty_expires",
+		"created_at":       "created_at",
+		"updated_at":       "updated_at",
+	}
+	PropertySorts = map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	}
+	ContactSorts = map[string]string{
+		"name":             "name",
+		"role":             "role",
+		"escalation_order": "escalation_order",
+		"created_at":       "created_at",
+	}
+	AttachmentSorts = map[string]string{
+		"filename":    "filename",
+		"file_size":   "file_size",
+		"property_id": "property_id",
+		"created_at":  "created_at",
+	}
+	NotificationEventSorts = map[string]string{
+		"created_at": "created_at",
+		"event_type": "event_type",
+	}
+)
+
+// DeviceFilter narrows a device listing. Zero values are ignored.
+type DeviceFilter struct {
+	ListOptions
+	PropertyID   int64
+	DeviceType   string
+	Tag          string
+	Active       *bool
+	Manufacturer string
+	Model        string
+}
+
+// PropertyFilter narrows a property listing to a group, a region or a tag. Zero values
+// are ignored.
+type PropertyFilter struct {
+	ListOptions
+	GroupID  int64
+	RegionID int64
+	Tag      string
+}
+
+// ContactFilter narrows a contact listing to one property
+type ContactFilter struct {
+	ListOptions
+	PropertyID int64
+}
+
+// AttachmentFilter narrows an attachment search. Query is split into words, each of
+// which must appear, in any case, in the filename, description, tags or property
+// name. Zero values are ignored.
+type AttachmentFilter struct {
+	ListOptions
+	Query      string
+	Tag        string
+	PropertyID int64
+}
+
+// NotificationEventFilter narrows a notification event listing. Zero values are
+// ignored.
+type NotificationEventFilter struct {
+	ListOptions
+	PropertyID int64
+	ChannelID  int64
+	EventType  string
+	Success    *bool
+	Start      time.Time // inclusive
+	End        time.Time // exclusive
+}
+
+// listQuery collects the conditions shared by a listing's count and page queries
+type listQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (q *listQuery) add(condition string, arg interface{}) {
+	q.args = append(q.args, arg)
+	q.conditions = append(q.conditions, fmt.Sprintf(condition, len(q.args)))
+}
+
+func (q *listQuery) where() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// orderBy returns the ORDER BY clause for opts.Sort, or fallback when it is empty.
+// Ties are broken by id so pages don't overlap.
+func (o ListOptions) orderBy(sorts map[string]string, fallback string) (string, error) {
+	if o.Sort == "" {
+		return " ORDER BY " + fallback, nil
+	}
+	field, direction := o.Sort, "ASC"
+	if strings.HasPrefix(field, "-") {
+		field, direction = field[1:], "DESC"
+	}
+	column, ok := sorts[field]
+	if !ok {
+		return "", fmt.Errorf("invalid sort field %q", field)
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction), nil
+}
+
+// listPage counts the rows matching q in table and returns the query for the
+// requested page of them, with its arguments
+func (s *PostgresStore) listPage(ctx context.Context, table, columns string, q listQuery, opts ListOptions, sorts map[string]string, fallback string) (string, []interface{}, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table+q.where(), q.args...).Scan(&total); err != nil {
+		return "", nil, 0, err
+	}
+
+	order, err := opts.orderBy(sorts, fallback)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	query := `SELECT ` + columns + ` FROM ` + table + q.where() + order
+	args := q.args
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit, opts.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+	return query, args, total, nil
+}
+
+// ListDevicesPage returns a page of the devices matching filter and how many match
+// in total
+func (s *PostgresStore) ListDevicesPage(ctx context.Context, filter DeviceFilter) ([]models.Device, int, error) {
+	var q listQuery
+	if filter.PropertyID != 0 {
+		q.add("property_id = $%d", filter.PropertyID)
+	}
+	if filter.DeviceType != "" {
+		q.add("device_type = $%d", filter.DeviceType)
+	}
+	if filter.Tag != "" {
+		q.add("array_position(tags, $%d) IS NOT NULL", filter.Tag)
+	}
+	if filter.Active != nil {
+		q.add("active = $%d", *filter.Active)
+	}
+	if filter.Manufacturer != "" {
+		q.add("LOWER(manufacturer) = LOWER($%d)", filter.Manufacturer)
+	}
+	if filter.Model != "" {
+		q.add("LOWER(model) = LOWER($%d)", filter.Model)
+	}
+
+	query, args, total, err := s.listPage(ctx, "devices", deviceColumns, q, filter.ListOptions, DeviceSorts, "name, id")
+	if err != nil {
+		return nil, 0, err
+	}
+	devices, err := s.queryDevices(ctx, query, args...)
+	return devices, total, err
+}
+
+// ListPropertiesPage returns a page of the properties matching filter and how many
+// match in total
+func (s *PostgresStore) ListPropertiesPage(ctx context.Context, filter PropertyFilter) ([]models.Property, int, error) {
+	var q listQuery
+	if filter.GroupID != 0 {
+		q.add("group_id = $%d", filter.GroupID)
+	}
+	if filter.RegionID != 0 {
+		q.add("group_id IN (SELECT id FROM property_groups WHERE region_id = $%d)", filter.RegionID)
+	}
+	if filter.Tag != "" {
+		q.add("array_position(tags, $%d) IS NOT NULL", filter.Tag)
+	}
+
+	query, args, total, err := s.listPage(ctx, "properties", propertyColumns, q, filter.ListOptions, PropertySorts, "name, id")
+	if err != nil {
+		return nil, 0, err
+	}
+	properties, err := s.queryProperties(ctx, query, args...)
+	return properties, total, err
+}
+
+// ListContactsPage returns a page of a property's contacts and how many it has in
+// total
+func (s *PostgresStore) ListContactsPage(ctx context.Context, filter ContactFilter) ([]models.Contact, int, error) {
+	var q listQuery
+	q.add("property_id = $%d", filter.PropertyID)
+
+	query, args, total, err := s.listPage(ctx, "contacts", contactColumns, q, filter.ListOptions, ContactSorts, "name, id")
+	if err != nil {
+		return nil, 0, err
+	}
+	contacts, err := s.queryContacts(ctx, query, args...)
+	return contacts, total, err
+}
+
+// attachmentSearchText is what an attachment search matches words against
+const attachmentSearchText = `LOWER(filename || ' ' || COALESCE(description, '') || ' ' || array_to_string(tags, ' ') || ' ' ||
+	COALESCE((SELECT name FROM properties WHERE properties.id = attachments.property_id), ''))`
+
+// SearchAttachments returns a page of the attachments across all properties matching
+// filter, newest first by default, and how many match in total
+func (s *PostgresStore) SearchAttachments(ctx context.Context, filter AttachmentFilter) ([]models.Attachment, int, error) {
+	var q listQuery
+	for _, word := range strings.Fields(strings.ToLower(filter.Query)) {
+		q.add(attachmentSearchText+` LIKE $%d ESCAPE '\'`, "%"+likeEscaper.Replace(word)+"%")
+	}
+	if filter.Tag != "" {
+		q.add("array_position(tags, $%d) IS NOT NULL", filter.Tag)
+	}
+	if filter.PropertyID != 0 {
+		q.add("property_id = $%d", filter.PropertyID)
+	}
+
+	query, args, total, err := s.listPage(ctx, "attachments", attachmentColumns, q, filter.ListOptions, AttachmentSorts, "created_at DESC, id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+	attachments, err := s.queryAttachments(ctx, query, args...)
+	return attachments, total, err
+}
+
+// likeEscaper escapes the LIKE wildcards in a search word
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ListNotificationEventsPage returns a page of the notification events matching
+// filter, newest first by default, and how many match in total
+func (s *PostgresStore) ListNotificationEventsPage(ctx context.Context, filter NotificationEventFilter) ([]models.NotificationEvent, int, error) {
+	var q listQuery
+	if filter.PropertyID != 0 {
+		q.add("property_id = $%d", filter.PropertyID)
+	}
+	if filter.EventType != "" {
+		q.add("event_type = $%d", filter.EventType)
+	}
+	if filter.Success != nil {
+		q.add("success = $%d", *filter.Success)
+	}
+	if filter.ChannelID != 0 {
+		q.add("notification_channel_id = $%d", filter.ChannelID)
+	}
+	if !filter.Start.IsZero() {
+		q.add("created_at >= $%d", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		q.add("created_at < $%d", filter.End)
+	}
+
+	query, args, total, err := s.listPage(ctx, "notification_events", notificationEventColumns, q, filter.ListOptions,
+		NotificationEventSorts, "created_at DESC, id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+	events, err := s.queryNotificationEvents(ctx, query, args...)
+	return events, total, err
+}
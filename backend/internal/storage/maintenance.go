@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Maintenance Windows
+const maintenanceWindowColumns = `id, property_id, device_id, starts_at, ends_at, reason, created_by, created_at, updated_at`
+
+func scanMaintenanceWindow(row rowScanner, w *models.MaintenanceWindow) error {
+	return row.Scan(&w.ID, &w.PropertyID, &w.DeviceID, &w.StartsAt, &w.EndsAt, &w.Reason, &w.CreatedBy,
+		&w.CreatedAt, &w.UpdatedAt)
+}
+
+func (s *PostgresStore) queryMaintenanceWindows(ctx context.Context, query string, args ...interface{}) ([]models.MaintenanceWindow, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make([]models.MaintenanceWindow, 0)
+	for rows.Next() {
+		var w models.MaintenanceWindow
+		if err := scanMaintenanceWindow(rows, &w); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+func (s *PostgresStore) CreateMaintenanceWindow(ctx context.Context, w *models.MaintenanceWindow) error {
+	query := `
+		INSERT INTO maintenance_windows (property_id, device_id, starts_at, ends_at, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, w.PropertyID, w.DeviceID, w.StartsAt, w.EndsAt, w.Reason, w.CreatedBy).
+		Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (s *PostgresStore) GetMaintenanceWindow(ctx context.Context, id int64) (*models.MaintenanceWindow, error) {
+	w := &models.MaintenanceWindow{}
+	query := `SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows WHERE id = $1`
+	err := scanMaintenanceWindow(s.db.QueryRowContext(ctx, query, id), w)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("maintenance window not found")
+	}
+	return w, err
+}
+
+func (s *PostgresStore) ListMaintenanceWindows(ctx context.Context) ([]models.MaintenanceWindow, error) {
+	return s.queryMaintenanceWindows(ctx, `SELECT `+maintenanceWindowColumns+` FROM maintenance_windows ORDER BY starts_at DESC`)
+}
+
+func (s *PostgresStore) ListMaintenanceWindowsForProperty(ctx context.Context, propertyID int64) ([]models.MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows
+		WHERE property_id = $1 OR device_id IN (SELECT id FROM devices WHERE property_id = $1)
+		ORDER BY starts_at DESC`
+	return s.queryMaintenanceWindows(ctx, query, propertyID)
+}
+
+// ListCurrentMaintenanceWindows returns windows that are active at or will start after the given time
+func (s *PostgresStore) ListCurrentMaintenanceWindows(ctx context.Context, at time.Time) ([]models.MaintenanceWindow, error) {
+	query := `SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows WHERE ends_at > $1 ORDER BY starts_at`
+	return s.queryMaintenanceWindows(ctx, query, at)
+}
+
+func (s *PostgresStore) UpdateMaintenanceWindow(ctx context.Context, w *models.MaintenanceWindow) error {
+	query := `
+		UPDATE maintenance_windows
+		SET property_id = $1, device_id = $2, starts_at = $3, ends_at = $4, reason = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING created_by, created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, w.PropertyID, w.DeviceID, w.StartsAt, w.EndsAt, w.Reason, w.ID).
+		Scan(&w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("maintenance window not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteMaintenanceWindow(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM maintenance_windows WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("maintenance window not found")
+	}
+	return nil
+}
@@ -0,0 +1,1035 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// memorySweepInterval is how often expired entries are dropped from a MemoryStore
+const memorySweepInterval = time.Minute
+
+// MemoryStore keeps live state in process for small deployments without Redis. It
+// follows RedisStore's behavior, including expiry, but nothing survives a restart and
+// the state can't be shared, so the API and the worker have to run in one process.
+// Statuses are held as the JSON Redis would store so callers never share values.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	// values holds what RedisStore keeps as plain keys, under the same key names
+	values            map[string]memoryValue
+	deviceStatuses    map[int64]string
+	propertyStatuses  map[int64]string
+	lastNotifications map[int64]map[string]time.Time
+	probeResults      map[int][]models.ProbeResult
+	checkResults      map[int][]models.DeviceStatus
+	checks            []memoryCheck
+	nextCheckID       int64
+	// checkQueued is closed and replaced whenever checks are queued, to wake readers
+	checkQueued      chan struct{}
+	members          map[string]time.Time
+	heartbeats       map[string]models.WorkerHeartbeat
+	alertState       string
+	onBattery        map[int64]*memorySet
+	stateChanges     map[int64]*memoryWindow
+	retries          map[string]int64
+	incidentChannels map[int64]map[int64]bool
+	subscribers      map[string]map[chan string]bool
+
+	stop chan struct{}
+}
+
+type memoryValue struct {
+	data    string
+	expires time.Time // zero when the value doesn't expire
+}
+
+// memoryCheck is a queued check; consumer is set once an executor has read it
+type memoryCheck struct {
+	check       models.QueuedCheck
+	consumer    string
+	deliveredAt time.Time
+}
+
+type memorySet struct {
+	members map[int64]bool
+	expires time.Time
+}
+
+// memoryWindow holds the times of recent state changes, pruned to the flap window
+type memoryWindow struct {
+	times   []time.Time
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-process store and starts sweeping expired entries
+// until it is closed
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{
+		values:            make(map[string]memoryValue),
+		deviceStatuses:    make(map[int64]string),
+		propertyStatuses:  make(map[int64]string),
+		lastNotifications: make(map[int64]map[string]time.Time),
+		probeResults:      make(map[int][]models.ProbeResult),
+		checkResults:      make(map[int][]models.DeviceStatus),
+		checkQueued:       make(chan struct{}),
+		members:           make(map[string]time.Time),
+		heartbeats:        make(map[string]models.WorkerHeartbeat),
+		onBattery:         make(map[int64]*memorySet),
+		stateChanges:      make(map[int64]*memoryWindow),
+		retries:           make(map[string]int64),
+		incidentChannels:  make(map[int64]map[int64]bool),
+		subscribers:       make(map[string]map[chan string]bool),
+		stop:              make(chan struct{}),
+	}
+	go m.sweep()
+	return m
+}
+
+// Ping always succeeds, since the state is in process
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	close(m.stop)
+	return nil
+}
+
+// sweep drops expired entries so the store only holds live state
+func (m *MemoryStore) sweep() {
+	ticker := time.NewTicker(memorySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			m.mu.Lock()
+			for key, v := range m.values {
+				if v.expired(now) {
+					delete(m.values, key)
+				}
+			}
+			// A status leaves the aggregate views when its own entry expires
+			for id := range m.deviceStatuses {
+				if _, ok := m.values[deviceStatusKey(id)]; !ok {
+					delete(m.deviceStatuses, id)
+				}
+			}
+			for id := range m.propertyStatuses {
+				if _, ok := m.values[propertyStatusKey(id)]; !ok {
+					delete(m.propertyStatuses, id)
+				}
+			}
+			for id, set := range m.onBattery {
+				if now.After(set.expires) {
+					delete(m.onBattery, id)
+				}
+			}
+			for id, window := range m.stateChanges {
+				if now.After(window.expires) {
+					delete(m.stateChanges, id)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (v memoryValue) expired(now time.Time) bool {
+	return !v.expires.IsZero() && now.After(v.expires)
+}
+
+// get returns a live value; the caller holds m.mu
+func (m *MemoryStore) get(key string) (string, bool) {
+	v, ok := m.values[key]
+	if !ok || v.expired(time.Now()) {
+		return "", false
+	}
+	return v.data, true
+}
+
+// set stores a value that expires after ttl, or never when ttl is 0; the caller holds m.mu
+func (m *MemoryStore) set(key, data string, ttl time.Duration) {
+	v := memoryValue{data: data}
+	if ttl > 0 {
+		v.expires = time.Now().Add(ttl)
+	}
+	m.values[key] = v
+}
+
+// publish hands a message to every subscriber of a channel, dropping it for
+// subscribers that are too far behind; the caller holds m.mu
+func (m *MemoryStore) publish(channel, data string) {
+	for messages := range m.subscribers[channel] {
+		select {
+		case messages <- data:
+		default:
+		}
+	}
+}
+
+// Device Status Operations
+func (m *MemoryStore) SetDeviceStatus(ctx context.Context, status *models.DeviceStatus, ttl time.Duration) error {
+	return m.WriteDeviceStatuses(ctx, []DeviceStatusWrite{{Status: status, TTL: ttl}})
+}
+
+// WriteDeviceStatuses stores a batch of device statuses and publishes their change
+// events, in the order given
+func (m *MemoryStore) WriteDeviceStatuses(ctx context.Context, writes []DeviceStatusWrite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range writes {
+		data, err := json.Marshal(w.Status)
+		if err != nil {
+			return err
+		}
+		m.set(deviceStatusKey(w.Status.DeviceID), string(data), w.TTL)
+		m.deviceStatuses[w.Status.DeviceID] = string(data)
+
+		if w.Change != nil {
+			change, err := json.Marshal(w.Change)
+			if err != nil {
+				return err
+			}
+			m.publish(deviceStatusChannel(), string(change))
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetDeviceStatus(ctx context.Context, deviceID int64) (*models.DeviceStatus, error) {
+	m.mu.Lock()
+	data, ok := m.get(deviceStatusKey(deviceID))
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("device status not found")
+	}
+
+	var status models.DeviceStatus
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetDeviceStatuses returns the stored statuses of the given devices; devices without
+// a status are left out
+func (m *MemoryStore) GetDeviceStatuses(ctx context.Context, deviceIDs []int64) (map[int64]*models.DeviceStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make(map[int64]*models.DeviceStatus, len(deviceIDs))
+	for _, id := range deviceIDs {
+		data, ok := m.get(deviceStatusKey(id))
+		if !ok {
+			continue
+		}
+		var status models.DeviceStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			continue
+		}
+		statuses[id] = &status
+	}
+	return statuses, nil
+}
+
+func (m *MemoryStore) GetAllDeviceStatuses(ctx context.Context) (map[int64]*models.DeviceStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make(map[int64]*models.DeviceStatus, len(m.deviceStatuses))
+	for id, data := range m.deviceStatuses {
+		if _, ok := m.get(deviceStatusKey(id)); !ok {
+			continue
+		}
+		var status models.DeviceStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			continue
+		}
+		statuses[id] = &status
+	}
+	return statuses, nil
+}
+
+// Property Status Operations
+func (m *MemoryStore) SetPropertyStatus(ctx context.Context, status *models.PropertyStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(propertyStatusKey(status.PropertyID), string(data), propertyStatusTTL)
+	m.propertyStatuses[status.PropertyID] = string(data)
+	return nil
+}
+
+func (m *MemoryStore) GetPropertyStatus(ctx context.Context, propertyID int64) (*models.PropertyStatus, error) {
+	m.mu.Lock()
+	data, ok := m.get(propertyStatusKey(propertyID))
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("property status not found")
+	}
+
+	var status models.PropertyStatus
+	if err := json.Unmarshal([]byte(data), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (m *MemoryStore) GetAllPropertyStatuses(ctx context.Context) (map[int64]*models.PropertyStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make(map[int64]*models.PropertyStatus, len(m.propertyStatuses))
+	for id, data := range m.propertyStatuses {
+		if _, ok := m.get(propertyStatusKey(id)); !ok {
+			continue
+		}
+		var status models.PropertyStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			continue
+		}
+		statuses[id] = &status
+	}
+	return statuses, nil
+}
+
+// DeleteDeviceStatuses removes the statuses and flap history of deleted devices
+func (m *MemoryStore) DeleteDeviceStatuses(ctx context.Context, deviceIDs []int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range deviceIDs {
+		delete(m.values, deviceStatusKey(id))
+		delete(m.deviceStatuses, id)
+		delete(m.stateChanges, id)
+	}
+	return nil
+}
+
+// DeletePropertyState removes everything kept for a deleted property: its status,
+// notification cooldowns, open incident, UPS power state and WiFi snapshot
+func (m *MemoryStore) DeletePropertyState(ctx context.Context, propertyID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, propertyStatusKey(propertyID))
+	delete(m.values, propertyIncidentKey(propertyID))
+	delete(m.values, propertyWiFiKey(propertyID))
+	delete(m.propertyStatuses, propertyID)
+	delete(m.lastNotifications, propertyID)
+	delete(m.incidentChannels, propertyID)
+	delete(m.onBattery, propertyID)
+	return nil
+}
+
+// PruneStatuses drops statuses that have expired or whose device or property isn't
+// among those given, returning how many device and property statuses it removed
+func (m *MemoryStore) PruneStatuses(ctx context.Context, deviceIDs, propertyIDs []int64) (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	devices := pruneMemoryStatuses(m, m.deviceStatuses, deviceIDs, deviceStatusKey)
+	properties := pruneMemoryStatuses(m, m.propertyStatuses, propertyIDs, propertyStatusKey)
+	return devices, properties, nil
+}
+
+// pruneMemoryStatuses reconciles one aggregate view; the caller holds m.mu
+func pruneMemoryStatuses(m *MemoryStore, statuses map[int64]string, keep []int64, statusKey func(int64) string) int {
+	kept := make(map[int64]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+	removed := 0
+	for id := range statuses {
+		if _, ok := m.get(statusKey(id)); ok && kept[id] {
+			continue
+		}
+		delete(statuses, id)
+		if !kept[id] {
+			delete(m.values, statusKey(id))
+		}
+		removed++
+	}
+	return removed
+}
+
+// PublishPropertyStatus announces a property status change to live dashboard subscribers
+func (m *MemoryStore) PublishPropertyStatus(ctx context.Context, status *models.PropertyStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publish(propertyStatusChannel(), string(data))
+	return nil
+}
+
+// SubscribePropertyStatus streams published property status changes until ctx is
+// cancelled, at which point the returned channel is closed
+func (m *MemoryStore) SubscribePropertyStatus(ctx context.Context) (<-chan *models.PropertyStatus, error) {
+	return subscribeMemory[models.PropertyStatus](ctx, m, propertyStatusChannel())
+}
+
+// PublishDeviceStatusChange announces a device status transition to live subscribers
+func (m *MemoryStore) PublishDeviceStatusChange(ctx context.Context, change *models.DeviceStatusChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publish(deviceStatusChannel(), string(data))
+	return nil
+}
+
+// SubscribeDeviceStatusChanges streams published device status transitions until ctx
+// is cancelled, at which point the returned channel is closed
+func (m *MemoryStore) SubscribeDeviceStatusChanges(ctx context.Context) (<-chan *models.DeviceStatusChange, error) {
+	return subscribeMemory[models.DeviceStatusChange](ctx, m, deviceStatusChannel())
+}
+
+// subscribeMemory subscribes to an in-process channel and decodes each message as T
+func subscribeMemory[T any](ctx context.Context, m *MemoryStore, channel string) (<-chan *T, error) {
+	messages := make(chan string, 100)
+	m.mu.Lock()
+	if m.subscribers[channel] == nil {
+		m.subscribers[channel] = make(map[chan string]bool)
+	}
+	m.subscribers[channel][messages] = true
+	m.mu.Unlock()
+
+	updates := make(chan *T, 16)
+	go func() {
+		defer close(updates)
+		defer func() {
+			m.mu.Lock()
+			delete(m.subscribers[channel], messages)
+			m.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data := <-messages:
+				var v T
+				if err := json.Unmarshal([]byte(data), &v); err != nil {
+					continue
+				}
+				select {
+				case updates <- &v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// WiFi Snapshot Operations
+func (m *MemoryStore) SetWiFiSnapshot(ctx context.Context, snapshot *models.WiFiSnapshot, ttl time.Duration) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(propertyWiFiKey(snapshot.PropertyID), string(data), ttl)
+	return nil
+}
+
+func (m *MemoryStore) GetWiFiSnapshot(ctx context.Context, propertyID int64) (*models.WiFiSnapshot, error) {
+	m.mu.Lock()
+	data, ok := m.get(propertyWiFiKey(propertyID))
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("wifi snapshot not found")
+	}
+
+	var snapshot models.WiFiSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (m *MemoryStore) SetDeviceAudit(ctx context.Context, audit *models.DeviceAudit, ttl time.Duration) error {
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(deviceAuditKey(), string(data), ttl)
+	return nil
+}
+
+func (m *MemoryStore) GetDeviceAudit(ctx context.Context) (*models.DeviceAudit, error) {
+	m.mu.Lock()
+	data, ok := m.get(deviceAuditKey())
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("device audit not found")
+	}
+
+	var audit models.DeviceAudit
+	if err := json.Unmarshal([]byte(data), &audit); err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
+// Notification Cooldown Operations
+func (m *MemoryStore) SetLastNotification(ctx context.Context, propertyID int64, eventType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lastNotifications[propertyID] == nil {
+		m.lastNotifications[propertyID] = make(map[string]time.Time)
+	}
+	m.lastNotifications[propertyID][eventType] = time.Unix(time.Now().Unix(), 0)
+	return nil
+}
+
+func (m *MemoryStore) GetLastNotification(ctx context.Context, propertyID int64, eventType string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastNotifications[propertyID][eventType], nil
+}
+
+func (m *MemoryStore) ShouldNotify(ctx context.Context, propertyID int64, eventType string, cooldownSeconds int) (bool, error) {
+	lastNotification, err := m.GetLastNotification(ctx, propertyID, eventType)
+	if err != nil {
+		return false, err
+	}
+
+	if lastNotification.IsZero() {
+		return true, nil
+	}
+
+	elapsed := time.Since(lastNotification)
+	return elapsed.Seconds() >= float64(cooldownSeconds), nil
+}
+
+// AllowNotification counts n messages against a channel's fixed-window rate limit and
+// reports whether they fit. Messages that don't fit are not counted.
+func (m *MemoryStore) AllowNotification(ctx context.Context, channelID int64, n, limit int, window time.Duration) (bool, error) {
+	key := notificationRateKey(channelID, window)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, _ := m.get(key)
+	count, _ := strconv.Atoi(data)
+	if count+n > limit {
+		return false, nil
+	}
+	m.set(key, strconv.Itoa(count+n), window)
+	return true, nil
+}
+
+// TakeRateLimitToken takes a token from the bucket under key, which holds limit
+// tokens and refills at limit per period. It reports whether there was one and, if
+// not, how long until there will be.
+func (m *MemoryStore) TakeRateLimitToken(ctx context.Context, key string, limit int, per time.Duration) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	tokens := float64(limit)
+	if data, ok := m.get(rateLimitKey(key)); ok {
+		var last int64
+		if _, err := fmt.Sscanf(data, "%g %d", &tokens, &last); err == nil {
+			elapsed := now.Sub(time.UnixMilli(last))
+			tokens = min(float64(limit), tokens+float64(limit)*elapsed.Seconds()/per.Seconds())
+		}
+	}
+
+	allowed := tokens >= 1
+	var wait time.Duration
+	if allowed {
+		tokens--
+	} else {
+		wait = time.Duration((1 - tokens) / float64(limit) * float64(per))
+	}
+	m.set(rateLimitKey(key), fmt.Sprintf("%g %d", tokens, now.UnixMilli()), per)
+	return allowed, wait, nil
+}
+
+// Response Cache Operations
+
+func (m *MemoryStore) SetCachedResponse(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(responseCacheKey(key), string(data), ttl)
+	return nil
+}
+
+// GetCachedResponse returns a cached response body, or nil when there isn't one
+func (m *MemoryStore) GetCachedResponse(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.get(responseCacheKey(key))
+	if !ok {
+		return nil, nil
+	}
+	return []byte(data), nil
+}
+
+// Probe Result Operations
+
+// PushProbeResults queues results reported by a remote probe for the property's shard
+func (m *MemoryStore) PushProbeResults(ctx context.Context, propertyID int64, results []models.ProbeResult) error {
+	shard := ShardForProperty(propertyID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probeResults[shard] = append(m.probeResults[shard], results...)
+	return nil
+}
+
+// PopProbeResults removes and returns up to limit queued probe results for a shard,
+// oldest first
+func (m *MemoryStore) PopProbeResults(ctx context.Context, shard int, limit int64) ([]models.ProbeResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued := m.probeResults[shard]
+	n := min(int(limit), len(queued))
+	results := append([]models.ProbeResult(nil), queued[:n]...)
+	m.probeResults[shard] = queued[n:]
+	return results, nil
+}
+
+// Check Queue Operations
+
+// EnsureCheckQueue is a no-op; the in-process queue always exists
+func (m *MemoryStore) EnsureCheckQueue(ctx context.Context) error {
+	return nil
+}
+
+// EnqueueChecks adds due device checks to the queue and wakes waiting executors
+func (m *MemoryStore) EnqueueChecks(ctx context.Context, devices []models.Device, queuedAt time.Time) error {
+	if len(devices) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range devices {
+		m.nextCheckID++
+		m.checks = append(m.checks, memoryCheck{check: models.QueuedCheck{
+			ID:       fmt.Sprintf("%d-0", m.nextCheckID),
+			Device:   devices[i],
+			QueuedAt: queuedAt,
+		}})
+	}
+	close(m.checkQueued)
+	m.checkQueued = make(chan struct{})
+	return nil
+}
+
+// CheckQueueLength returns the number of checks waiting or being run
+func (m *MemoryStore) CheckQueueLength(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.checks)), nil
+}
+
+// ReadChecks takes up to count new checks for an executor, waiting up to block for
+// one to arrive. Checks stay pending for the executor until acknowledged.
+func (m *MemoryStore) ReadChecks(ctx context.Context, consumer string, count int64, block time.Duration) ([]models.QueuedCheck, error) {
+	timer := time.NewTimer(block)
+	defer timer.Stop()
+
+	for {
+		m.mu.Lock()
+		var checks []models.QueuedCheck
+		now := time.Now()
+		for i := range m.checks {
+			if int64(len(checks)) >= count {
+				break
+			}
+			if m.checks[i].consumer != "" {
+				continue
+			}
+			m.checks[i].consumer = consumer
+			m.checks[i].deliveredAt = now
+			checks = append(checks, m.checks[i].check)
+		}
+		queued := m.checkQueued
+		m.mu.Unlock()
+
+		if len(checks) > 0 {
+			return checks, nil
+		}
+		select {
+		case <-queued:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ClaimStaleChecks takes over up to count checks left pending by an executor for
+// longer than minIdle
+func (m *MemoryStore) ClaimStaleChecks(ctx context.Context, consumer string, minIdle time.Duration, count int64) ([]models.QueuedCheck, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var checks []models.QueuedCheck
+	now := time.Now()
+	for i := range m.checks {
+		if int64(len(checks)) >= count {
+			break
+		}
+		if m.checks[i].consumer == "" || now.Sub(m.checks[i].deliveredAt) < minIdle {
+			continue
+		}
+		m.checks[i].consumer = consumer
+		m.checks[i].deliveredAt = now
+		checks = append(checks, m.checks[i].check)
+	}
+	return checks, nil
+}
+
+// AckCheck marks a check done and removes it from the queue
+func (m *MemoryStore) AckCheck(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.checks {
+		if m.checks[i].check.ID == id {
+			m.checks = append(m.checks[:i], m.checks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// PushCheckResult queues an executor's check result for the property's shard
+func (m *MemoryStore) PushCheckResult(ctx context.Context, propertyID int64, status *models.DeviceStatus) error {
+	shard := ShardForProperty(propertyID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkResults[shard] = append(m.checkResults[shard], *status)
+	return nil
+}
+
+// PopCheckResults removes and returns up to limit queued check results for a shard,
+// oldest first
+func (m *MemoryStore) PopCheckResults(ctx context.Context, shard int, limit int64) ([]models.DeviceStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued := m.checkResults[shard]
+	n := min(int(limit), len(queued))
+	statuses := append([]models.DeviceStatus(nil), queued[:n]...)
+	m.checkResults[shard] = queued[n:]
+	return statuses, nil
+}
+
+// Worker Coordination Operations
+
+// AcquireLease takes or renews a named lease for owner and reports whether owner
+// holds it. A lease not renewed within ttl is free to take.
+func (m *MemoryStore) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if held, ok := m.get(workerLeaseKey(name)); ok && held != owner {
+		return false, nil
+	}
+	m.set(workerLeaseKey(name), owner, ttl)
+	return true, nil
+}
+
+// ReleaseLease gives up a lease held by owner
+func (m *MemoryStore) ReleaseLease(ctx context.Context, name, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if held, ok := m.get(workerLeaseKey(name)); ok && held == owner {
+		delete(m.values, workerLeaseKey(name))
+	}
+	return nil
+}
+
+// HeartbeatWorker records that a worker is alive and returns the workers that have
+// sent a heartbeat within ttl, including this one
+func (m *MemoryStore) HeartbeatWorker(ctx context.Context, workerID string, ttl time.Duration) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.members[workerID] = now
+	members := make([]string, 0, len(m.members))
+	for id, seen := range m.members {
+		if seen.Before(now.Add(-ttl)) {
+			delete(m.members, id)
+			continue
+		}
+		members = append(members, id)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, b := m.members[members[i]], m.members[members[j]]
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return members[i] < members[j]
+	})
+	return members, nil
+}
+
+// RemoveWorker drops a worker from the live set when it shuts down
+func (m *MemoryStore) RemoveWorker(ctx context.Context, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.members, workerID)
+	return nil
+}
+
+// Worker Heartbeat Operations
+
+// SetWorkerHeartbeat records a worker's latest heartbeat
+func (m *MemoryStore) SetWorkerHeartbeat(ctx context.Context, hb *models.WorkerHeartbeat) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.heartbeats[hb.WorkerID] = *hb
+	return nil
+}
+
+// RemoveWorkerHeartbeat drops a worker's heartbeat when it shuts down cleanly
+func (m *MemoryStore) RemoveWorkerHeartbeat(ctx context.Context, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.heartbeats, workerID)
+	return nil
+}
+
+// GetWorkerHeartbeats returns the heartbeats sent within maxAge, newest first, and
+// deletes older ones
+func (m *MemoryStore) GetWorkerHeartbeats(ctx context.Context, maxAge time.Duration) ([]models.WorkerHeartbeat, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	var heartbeats []models.WorkerHeartbeat
+	for workerID, hb := range m.heartbeats {
+		if hb.LastSeen.Before(cutoff) {
+			delete(m.heartbeats, workerID)
+			continue
+		}
+		heartbeats = append(heartbeats, hb)
+	}
+
+	sort.Slice(heartbeats, func(i, j int) bool {
+		return heartbeats[i].LastSeen.After(heartbeats[j].LastSeen)
+	})
+	return heartbeats, nil
+}
+
+// MarkWorkersDown records that no worker is sending heartbeats and reports whether
+// they were up until now. A fleet that was never seen up stays in its initial state.
+func (m *MemoryStore) MarkWorkersDown(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.alertState != "up" {
+		return false, nil
+	}
+	m.alertState = "down"
+	return true, nil
+}
+
+// MarkWorkersUp records that workers are sending heartbeats and reports whether they
+// were marked down before
+func (m *MemoryStore) MarkWorkersUp(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous := m.alertState
+	m.alertState = "up"
+	return previous == "down", nil
+}
+
+// Power State Operations
+
+// SetDeviceOnBattery records whether a UPS device is on battery and returns how many of
+// the property's UPS devices were on battery before and after. The set expires a day
+// after its last update.
+func (m *MemoryStore) SetDeviceOnBattery(ctx context.Context, propertyID, deviceID int64, onBattery bool) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	set, ok := m.onBattery[propertyID]
+	if !ok || now.After(set.expires) {
+		set = &memorySet{members: make(map[int64]bool)}
+		m.onBattery[propertyID] = set
+	}
+	set.expires = now.Add(24 * time.Hour)
+
+	before := int64(len(set.members))
+	if onBattery {
+		set.members[deviceID] = true
+	} else {
+		delete(set.members, deviceID)
+	}
+	return before, int64(len(set.members)), nil
+}
+
+// Flap Detection Operations
+
+// RecordDeviceStateChange notes a confirmed state change for flap detection. Entries
+// older than the window are dropped and the count within the window is returned.
+func (m *MemoryStore) RecordDeviceStateChange(ctx context.Context, deviceID int64, at time.Time, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.stateChanges[deviceID]
+	if !ok || time.Now().After(w.expires) {
+		w = &memoryWindow{}
+		m.stateChanges[deviceID] = w
+	}
+
+	w.times = append(w.times, at)
+	cutoff := at.Add(-window).Unix()
+	kept := w.times[:0]
+	for _, t := range w.times {
+		if t.Unix() >= cutoff {
+			kept = append(kept, t)
+		}
+	}
+	w.times = kept
+	w.expires = time.Now().Add(window)
+	return int64(len(w.times)), nil
+}
+
+// CountDeviceStateChanges returns the number of state changes recorded since the given time
+func (m *MemoryStore) CountDeviceStateChanges(ctx context.Context, deviceID int64, since time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w, ok := m.stateChanges[deviceID]
+	if !ok || time.Now().After(w.expires) {
+		return 0, nil
+	}
+	var count int64
+	for _, t := range w.times {
+		if t.Unix() >= since.Unix() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Notification Retry Operations
+
+// ScheduleNotificationRetry queues a failed delivery to be retried at the given time
+func (m *MemoryStore) ScheduleNotificationRetry(ctx context.Context, payload string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[payload] = at.Unix()
+	return nil
+}
+
+// ClaimDueNotificationRetries removes and returns up to limit retries that are due,
+// earliest first
+func (m *MemoryStore) ClaimDueNotificationRetries(ctx context.Context, now time.Time, limit int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []string
+	for payload, at := range m.retries {
+		if at <= now.Unix() {
+			due = append(due, payload)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if m.retries[due[i]] != m.retries[due[j]] {
+			return m.retries[due[i]] < m.retries[due[j]]
+		}
+		return due[i] < due[j]
+	})
+	if int64(len(due)) > limit {
+		due = due[:limit]
+	}
+	for _, payload := range due {
+		delete(m.retries, payload)
+	}
+	return due, nil
+}
+
+// Incident Operations
+
+// SetPropertyIncident stores the key of the property's open incident
+func (m *MemoryStore) SetPropertyIncident(ctx context.Context, propertyID int64, incidentKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(propertyIncidentKey(propertyID), incidentKey, 0)
+	return nil
+}
+
+// GetPropertyIncident returns the open incident key for a property, or "" if none
+func (m *MemoryStore) GetPropertyIncident(ctx context.Context, propertyID int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, _ := m.get(propertyIncidentKey(propertyID))
+	return key, nil
+}
+
+// AddIncidentChannels records channels that were routed the property's down alert by a
+// rule and should receive the recovery
+func (m *MemoryStore) AddIncidentChannels(ctx context.Context, propertyID int64, channelIDs []int64) error {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.incidentChannels[propertyID] == nil {
+		m.incidentChannels[propertyID] = make(map[int64]bool)
+	}
+	for _, id := range channelIDs {
+		m.incidentChannels[propertyID][id] = true
+	}
+	return nil
+}
+
+// GetIncidentChannels returns the channels recorded for the property's open incident
+func (m *MemoryStore) GetIncidentChannels(ctx context.Context, propertyID int64) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]int64, 0, len(m.incidentChannels[propertyID]))
+	for id := range m.incidentChannels[propertyID] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *MemoryStore) ClearPropertyIncident(ctx context.Context, propertyID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, propertyIncidentKey(propertyID))
+	delete(m.incidentChannels, propertyID)
+	return nil
+}
+
+// Cleanup Operations
+
+// CleanupOldHistory is a no-op; the legacy Redis history never existed in process
+func (m *MemoryStore) CleanupOldHistory(ctx context.Context, retentionDays int) error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Schema migrations live in migrations/<dialect> as numbered goose SQL files. Each
+// Postgres migration has a SQLite counterpart with the same version.
+//
+//go:embed migrations
+var migrations embed.FS
+
+// Migrate applies any pending schema migrations. Replicas starting together wait on a
+// Postgres advisory lock so each migration runs once.
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return err
+	}
+	return migrate(ctx, s.db.DB, goose.DialectPostgres, "migrations/postgres", goose.WithSessionLocker(locker))
+}
+
+// Migrate applies any pending schema migrations
+func (s *SQLiteStore) Migrate(ctx context.Context) error {
+	return migrate(ctx, s.db.DB, goose.DialectSQLite3, "migrations/sqlite")
+}
+
+func migrate(ctx context.Context, db *sql.DB, dialect goose.Dialect, dir string, opts ...goose.ProviderOption) error {
+	fsys, err := fs.Sub(migrations, dir)
+	if err != nil {
+		return err
+	}
+	provider, err := goose.NewProvider(dialect, db, fsys, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	results, err := provider.Up(ctx)
+	for _, r := range results {
+		if r.Error == nil {
+			slog.Info("Applied migration", "version", r.Source.Version, "file", r.Source.Path, "duration_ms", r.Duration.Milliseconds())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	version, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Database schema up to date", "version", version)
+	return nil
+}
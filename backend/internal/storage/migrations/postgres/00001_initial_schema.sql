@@ -0,0 +1,405 @@
+-- +goose Up
+-- ETS NOC (Network Operations Center) database schema as of the first migration.
+-- Written to be idempotent so it also baselines databases created from schema.sql
+-- before migrations were introduced.
+
+-- Properties table
+CREATE TABLE IF NOT EXISTS properties (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    address TEXT,
+    notes TEXT,
+    isp_company_name VARCHAR(255),
+    isp_account_info TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Contacts table
+CREATE TABLE IF NOT EXISTS contacts (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(50),
+    email VARCHAR(255),
+    role VARCHAR(100),
+    notes TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Attachments table
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    description TEXT,
+    storage_type VARCHAR(20) NOT NULL CHECK (storage_type IN ('gcs', 'google_drive')),
+    storage_path TEXT NOT NULL,
+    file_size BIGINT,
+    mime_type VARCHAR(100),
+    uploaded_by VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Devices table
+CREATE TABLE IF NOT EXISTS devices (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    hostname VARCHAR(255) NOT NULL,
+    device_type VARCHAR(50),
+    is_critical BOOLEAN DEFAULT false,
+    check_interval INT DEFAULT 60,
+    retries INT DEFAULT 3,
+    timeout INT DEFAULT 10000,
+    description TEXT DEFAULT '',
+    tags TEXT[] DEFAULT '{}',
+    active BOOLEAN DEFAULT true,
+    check_type VARCHAR(20) NOT NULL DEFAULT 'icmp' CHECK (check_type IN ('icmp', 'tcp', 'vpn', 'carp', 'ups')),
+    port INT NOT NULL DEFAULT 0,
+    failure_threshold INT NOT NULL DEFAULT 3,
+    parent_device_id BIGINT REFERENCES devices(id) ON DELETE SET NULL,
+    mac_address VARCHAR(17) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Notification channels table
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL CHECK (type IN ('slack', 'email', 'pagerduty', 'sms')),
+    config TEXT NOT NULL,
+    enabled BOOLEAN DEFAULT true,
+    system_alerts BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Property notifications junction table
+CREATE TABLE IF NOT EXISTS property_notifications (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    notification_channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    enabled BOOLEAN DEFAULT true,
+    notify_on_red BOOLEAN DEFAULT true,
+    notify_on_recovery BOOLEAN DEFAULT true,
+    active_from VARCHAR(5) NOT NULL DEFAULT '',
+    active_to VARCHAR(5) NOT NULL DEFAULT '',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    bypass_critical BOOLEAN NOT NULL DEFAULT false,
+    UNIQUE(property_id, notification_channel_id)
+);
+
+-- Notification routing rules (evaluated by priority in addition to property links)
+CREATE TABLE IF NOT EXISTS notification_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 100,
+    enabled BOOLEAN DEFAULT true,
+    property_id BIGINT REFERENCES properties(id) ON DELETE CASCADE,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    device_types TEXT[] NOT NULL DEFAULT '{}',
+    severity VARCHAR(20) NOT NULL DEFAULT '' CHECK (severity IN ('', 'critical', 'warning')),
+    notification_channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    notify_on_recovery BOOLEAN DEFAULT true,
+    stop_processing BOOLEAN DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Notification events log table
+CREATE TABLE IF NOT EXISTS notification_events (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    notification_channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    success BOOLEAN DEFAULT false,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50) DEFAULT '',
+    role VARCHAR(50) NOT NULL CHECK (role IN ('admin', 'user')),
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Settings table
+CREATE TABLE IF NOT EXISTS settings (
+    id BIGSERIAL PRIMARY KEY,
+    max_concurrent_pings INT DEFAULT 150,
+    default_check_interval INT DEFAULT 60,
+    default_retries INT DEFAULT 3,
+    default_timeout INT DEFAULT 10000,
+    history_retention_days INT DEFAULT 90,
+    notification_cooldown INT DEFAULT 300
+);
+
+-- Maintenance windows table
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT REFERENCES properties(id) ON DELETE CASCADE,
+    device_id BIGINT REFERENCES devices(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason TEXT DEFAULT '',
+    created_by VARCHAR(255) DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (property_id IS NOT NULL OR device_id IS NOT NULL),
+    CHECK (ends_at > starts_at)
+);
+
+-- Device history table (one row per check, written by the worker)
+CREATE TABLE IF NOT EXISTS device_history (
+    id BIGSERIAL PRIMARY KEY,
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    checked_at TIMESTAMPTZ NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    state_type VARCHAR(10) NOT NULL DEFAULT 'hard',
+    response_time DOUBLE PRECISION NOT NULL DEFAULT 0,
+    message TEXT DEFAULT '',
+    maintenance BOOLEAN NOT NULL DEFAULT false
+);
+
+-- Device history rollups (hourly from device_history, daily from hourly)
+CREATE TABLE IF NOT EXISTS device_history_rollups (
+    device_id BIGINT NOT NULL REFERENCES devices(id) ON DELETE CASCADE,
+    resolution VARCHAR(10) NOT NULL CHECK (resolution IN ('hour', 'day')),
+    bucket_start TIMESTAMPTZ NOT NULL,
+    checks INT NOT NULL DEFAULT 0,
+    online_checks INT NOT NULL DEFAULT 0,
+    offline_checks INT NOT NULL DEFAULT 0,
+    unreachable_checks INT NOT NULL DEFAULT 0,
+    maintenance_checks INT NOT NULL DEFAULT 0,
+    avg_response_time DOUBLE PRECISION,
+    min_response_time DOUBLE PRECISION,
+    max_response_time DOUBLE PRECISION,
+    PRIMARY KEY (device_id, resolution, bucket_start)
+);
+
+-- Status events table (device and property state transitions)
+CREATE TABLE IF NOT EXISTS status_events (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('device', 'property')),
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    device_id BIGINT REFERENCES devices(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    previous_duration BIGINT,
+    message TEXT DEFAULT ''
+);
+
+-- Alert acknowledgements (cleared by the worker on recovery)
+CREATE TABLE IF NOT EXISTS acknowledgements (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('device', 'property')),
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    device_id BIGINT REFERENCES devices(id) ON DELETE CASCADE,
+    acknowledged_by VARCHAR(255) DEFAULT '',
+    comment TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    cleared_at TIMESTAMPTZ
+);
+
+-- Notification silences
+CREATE TABLE IF NOT EXISTS silences (
+    id BIGSERIAL PRIMARY KEY,
+    scope_type VARCHAR(20) NOT NULL CHECK (scope_type IN ('device', 'property', 'tag')),
+    property_id BIGINT REFERENCES properties(id) ON DELETE CASCADE,
+    device_id BIGINT REFERENCES devices(id) ON DELETE CASCADE,
+    tag VARCHAR(255) DEFAULT '',
+    reason TEXT DEFAULT '',
+    created_by VARCHAR(255) DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- On-call schedules (member_ids is the rotation order)
+CREATE TABLE IF NOT EXISTS oncall_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    rotation_start TIMESTAMPTZ NOT NULL,
+    rotation_days INT NOT NULL DEFAULT 7 CHECK (rotation_days > 0),
+    member_ids BIGINT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- On-call overrides
+CREATE TABLE IF NOT EXISTS oncall_overrides (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL REFERENCES oncall_schedules(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+-- Digest email subscriptions (empty property_ids means fleet-wide)
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+    property_ids BIGINT[] NOT NULL DEFAULT '{}',
+    notification_channel_id BIGINT NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+    send_hour INT NOT NULL DEFAULT 8 CHECK (send_hour BETWEEN 0 AND 23),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN DEFAULT true,
+    last_sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Additional pfSense nodes per property (CARP HA pairs)
+CREATE TABLE IF NOT EXISTS firewalls (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    host VARCHAR(255) NOT NULL,
+    port INT NOT NULL DEFAULT 22,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    password VARCHAR(255) NOT NULL DEFAULT '',
+    expected_carp_state VARCHAR(10) NOT NULL DEFAULT '' CHECK (expected_carp_state IN ('', 'master', 'backup')),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- pfSense interface counters, sampled every few minutes
+CREATE TABLE IF NOT EXISTS interface_traffic (
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    interface VARCHAR(64) NOT NULL,
+    collected_at TIMESTAMPTZ NOT NULL,
+    rx_bytes BIGINT NOT NULL,
+    tx_bytes BIGINT NOT NULL,
+    rx_packets BIGINT NOT NULL,
+    tx_packets BIGINT NOT NULL,
+    PRIMARY KEY (property_id, interface, collected_at)
+);
+
+-- Audit trail of DHCP static mappings pushed to pfSense
+CREATE TABLE IF NOT EXISTS dhcp_mapping_changes (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    device_id BIGINT REFERENCES devices(id) ON DELETE SET NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('set', 'delete')),
+    interface VARCHAR(64) NOT NULL,
+    mac_address VARCHAR(17) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    hostname VARCHAR(255) NOT NULL DEFAULT '',
+    previous_ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    previous_hostname VARCHAR(255) NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- UniFi Network controller per property, polled for access point telemetry
+CREATE TABLE IF NOT EXISTS unifi_controllers (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL UNIQUE REFERENCES properties(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    password TEXT NOT NULL DEFAULT '',
+    site VARCHAR(100) NOT NULL DEFAULT 'default',
+    verify_tls BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Remote probe agents that check devices from inside a property's network
+CREATE TABLE IF NOT EXISTS probes (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_seen_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- pfSense config.xml backups stored in GCS
+CREATE TABLE IF NOT EXISTS config_backups (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    storage_path VARCHAR(1000) NOT NULL,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    sha256 VARCHAR(64) NOT NULL,
+    trigger VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (trigger IN ('scheduled', 'manual')),
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Columns added after initial release (for existing databases)
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS check_type VARCHAR(20) NOT NULL DEFAULT 'icmp';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS port INT NOT NULL DEFAULT 0;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS failure_threshold INT NOT NULL DEFAULT 3;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS parent_device_id BIGINT REFERENCES devices(id) ON DELETE SET NULL;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS mac_address VARCHAR(17) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS probe_id BIGINT REFERENCES probes(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(50) DEFAULT '';
+ALTER TABLE property_notifications ADD COLUMN IF NOT EXISTS active_from VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE property_notifications ADD COLUMN IF NOT EXISTS active_to VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE property_notifications ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE property_notifications ADD COLUMN IF NOT EXISTS bypass_critical BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE notification_channels ADD COLUMN IF NOT EXISTS system_alerts BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE devices DROP CONSTRAINT IF EXISTS devices_check_type_check;
+ALTER TABLE devices ADD CONSTRAINT devices_check_type_check CHECK (check_type IN ('icmp', 'tcp', 'vpn', 'carp', 'ups'));
+ALTER TABLE notification_channels DROP CONSTRAINT IF EXISTS notification_channels_type_check;
+ALTER TABLE notification_channels ADD CONSTRAINT notification_channels_type_check CHECK (type IN ('slack', 'email', 'pagerduty', 'sms'));
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_devices_property_id ON devices(property_id);
+CREATE INDEX IF NOT EXISTS idx_devices_hostname ON devices(hostname);
+CREATE INDEX IF NOT EXISTS idx_devices_active ON devices(active);
+CREATE INDEX IF NOT EXISTS idx_devices_critical ON devices(is_critical);
+CREATE INDEX IF NOT EXISTS idx_devices_parent_device_id ON devices(parent_device_id);
+CREATE INDEX IF NOT EXISTS idx_contacts_property_id ON contacts(property_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_property_id ON attachments(property_id);
+CREATE INDEX IF NOT EXISTS idx_property_notifications_property_id ON property_notifications(property_id);
+CREATE INDEX IF NOT EXISTS idx_notification_rules_priority ON notification_rules(priority) WHERE enabled;
+CREATE INDEX IF NOT EXISTS idx_notification_events_property_id ON notification_events(property_id);
+CREATE INDEX IF NOT EXISTS idx_notification_events_created_at ON notification_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_ends_at ON maintenance_windows(ends_at);
+CREATE INDEX IF NOT EXISTS idx_device_history_device_checked_at ON device_history(device_id, checked_at);
+CREATE INDEX IF NOT EXISTS idx_device_history_checked_at ON device_history(checked_at);
+CREATE INDEX IF NOT EXISTS idx_status_events_property_occurred_at ON status_events(property_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_status_events_device_occurred_at ON status_events(device_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_status_events_occurred_at ON status_events(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_acknowledgements_active ON acknowledgements(property_id) WHERE cleared_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_silences_expires_at ON silences(expires_at);
+CREATE INDEX IF NOT EXISTS idx_oncall_overrides_schedule_id ON oncall_overrides(schedule_id, ends_at);
+CREATE INDEX IF NOT EXISTS idx_digest_subscriptions_user_id ON digest_subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_config_backups_property_created_at ON config_backups(property_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_firewalls_property_id ON firewalls(property_id);
+CREATE INDEX IF NOT EXISTS idx_interface_traffic_collected_at ON interface_traffic(collected_at);
+CREATE INDEX IF NOT EXISTS idx_dhcp_mapping_changes_property_created_at ON dhcp_mapping_changes(property_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_probes_property_id ON probes(property_id);
+CREATE INDEX IF NOT EXISTS idx_devices_probe_id ON devices(probe_id);
+
+-- Insert default settings
+INSERT INTO settings (id, max_concurrent_pings, default_check_interval, default_retries, default_timeout, history_retention_days, notification_cooldown)
+VALUES (1, 150, 60, 3, 10000, 90, 300)
+ON CONFLICT (id) DO NOTHING;
+
+-- Insert default admin user (password: changeme)
+-- Password hash for "changeme" using bcrypt
+INSERT INTO users (username, password, email, role, active)
+VALUES ('admin', '$2a$10$YVZxZIYXXXXXXXXXXXXXXeN5xN5xN5xN5xN5xN5xN5xN5xN5xN5xN', 'admin@etsusa.com', 'admin', true)
+ON CONFLICT (username) DO NOTHING;
+
+-- Note: You should change the admin password after first login!
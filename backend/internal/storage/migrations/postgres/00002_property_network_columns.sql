@@ -0,0 +1,15 @@
+-- +goose Up
+-- Columns the code has used since pfSense sync and subnet allocation but that were
+-- only ever added by hand
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS subnet VARCHAR(18) NOT NULL DEFAULT '';
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS pfsense_host VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS pfsense_port INT NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS pfsense_username VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS pfsense_password TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE properties DROP COLUMN IF EXISTS pfsense_password;
+ALTER TABLE properties DROP COLUMN IF EXISTS pfsense_username;
+ALTER TABLE properties DROP COLUMN IF EXISTS pfsense_port;
+ALTER TABLE properties DROP COLUMN IF EXISTS pfsense_host;
+ALTER TABLE properties DROP COLUMN IF EXISTS subnet;
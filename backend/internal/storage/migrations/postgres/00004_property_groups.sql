@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS regions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS property_groups (
+    id BIGSERIAL PRIMARY KEY,
+    region_id BIGINT NOT NULL REFERENCES regions(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (region_id, name)
+);
+
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS group_id BIGINT REFERENCES property_groups(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_properties_group_id ON properties(group_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_properties_group_id;
+ALTER TABLE properties DROP COLUMN IF EXISTS group_id;
+DROP TABLE IF EXISTS property_groups;
+DROP TABLE IF EXISTS regions;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE notification_rules ADD COLUMN IF NOT EXISTS property_tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE notification_rules DROP COLUMN IF EXISTS property_tags;
+ALTER TABLE properties DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'user', 'viewer'));
+
+-- +goose Down
+-- Viewers can't be expressed without the role, so they are disabled rather than
+-- given write access
+UPDATE users SET role = 'user', active = false WHERE role = 'viewer';
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'user'));
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Google sign-in is limited to these email domains; new users it creates get the default role
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oauth_allowed_domains TEXT[] NOT NULL DEFAULT '{etsusa.com}';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oauth_default_role VARCHAR(20) NOT NULL DEFAULT 'user';
+
+-- +goose Down
+ALTER TABLE settings DROP COLUMN IF EXISTS oauth_default_role;
+ALTER TABLE settings DROP COLUMN IF EXISTS oauth_allowed_domains;
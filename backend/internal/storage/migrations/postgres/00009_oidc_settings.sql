@@ -0,0 +1,20 @@
+-- +goose Up
+-- Single sign-on through a generic OpenID Connect provider
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_name VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_issuer TEXT NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_client_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_client_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_scopes TEXT[] NOT NULL DEFAULT '{openid,email,profile}';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_role_claim VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS oidc_role_mapping TEXT NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_role_mapping;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_role_claim;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_scopes;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_client_secret;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_client_id;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_issuer;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_name;
+ALTER TABLE settings DROP COLUMN IF EXISTS oidc_enabled;
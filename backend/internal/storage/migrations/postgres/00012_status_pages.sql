@@ -0,0 +1,14 @@
+-- +goose Up
+-- Public status pages for property groups, reached with a token instead of a sign-in
+CREATE TABLE IF NOT EXISTS status_pages (
+    id BIGSERIAL PRIMARY KEY,
+    property_group_id BIGINT NOT NULL UNIQUE REFERENCES property_groups(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS status_pages;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Embeddable status badges for properties, reached with a token instead of a sign-in
+CREATE TABLE IF NOT EXISTS property_badges (
+    id BIGSERIAL PRIMARY KEY,
+    property_id BIGINT NOT NULL UNIQUE REFERENCES properties(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS property_badges;
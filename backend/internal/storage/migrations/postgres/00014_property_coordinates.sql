@@ -0,0 +1,8 @@
+-- +goose Up
+-- Property locations for the map view, geocoded from the address or set by hand
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+-- +goose Down
+ALTER TABLE properties DROP COLUMN IF EXISTS longitude;
+ALTER TABLE properties DROP COLUMN IF EXISTS latitude;
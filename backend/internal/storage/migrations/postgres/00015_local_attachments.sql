@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE attachments DROP CONSTRAINT IF EXISTS attachments_storage_type_check;
+ALTER TABLE attachments ADD CONSTRAINT attachments_storage_type_check CHECK (storage_type IN ('gcs', 'local', 'google_drive'));
+
+-- +goose Down
+-- Files on local disk can't be reached without the setting, so their records go
+DELETE FROM attachments WHERE storage_type = 'local';
+ALTER TABLE attachments DROP CONSTRAINT IF EXISTS attachments_storage_type_check;
+ALTER TABLE attachments ADD CONSTRAINT attachments_storage_type_check CHECK (storage_type IN ('gcs', 'google_drive'));
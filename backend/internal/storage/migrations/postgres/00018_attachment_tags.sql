@@ -0,0 +1,8 @@
+-- +goose Up
+-- Tags for finding attachments across properties
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE attachment_uploads ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE attachment_uploads DROP COLUMN IF EXISTS tags;
+ALTER TABLE attachments DROP COLUMN IF EXISTS tags;
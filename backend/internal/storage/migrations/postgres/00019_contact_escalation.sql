@@ -0,0 +1,6 @@
+-- +goose Up
+-- Order contacts are called in when a property goes red; 0 leaves a contact out
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS escalation_order INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS escalation_order;
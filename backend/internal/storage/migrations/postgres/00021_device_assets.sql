@@ -0,0 +1,19 @@
+-- +goose Up
+-- Asset details for inventory and warranty tracking
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS serial_number VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS manufacturer VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS model VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS firmware_version VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS purchase_date DATE;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS warranty_expires DATE;
+
+CREATE INDEX IF NOT EXISTS idx_devices_warranty_expires ON devices(warranty_expires);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_devices_warranty_expires;
+ALTER TABLE devices DROP COLUMN IF EXISTS warranty_expires;
+ALTER TABLE devices DROP COLUMN IF EXISTS purchase_date;
+ALTER TABLE devices DROP COLUMN IF EXISTS firmware_version;
+ALTER TABLE devices DROP COLUMN IF EXISTS model;
+ALTER TABLE devices DROP COLUMN IF EXISTS manufacturer;
+ALTER TABLE devices DROP COLUMN IF EXISTS serial_number;
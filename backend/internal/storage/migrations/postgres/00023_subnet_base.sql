@@ -0,0 +1,7 @@
+-- +goose Up
+-- Start of the automatic property subnet scheme, so it can be moved clear of acquired
+-- sites' addressing
+ALTER TABLE settings ADD COLUMN IF NOT EXISTS subnet_base VARCHAR(15) NOT NULL DEFAULT '10.99.0.0';
+
+-- +goose Down
+ALTER TABLE settings DROP COLUMN IF EXISTS subnet_base;
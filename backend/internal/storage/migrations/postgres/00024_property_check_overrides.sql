@@ -0,0 +1,18 @@
+-- +goose Up
+-- Check settings cascade from the global defaults to the property to the device, with 0
+-- meaning "inherit". Devices still on the stock defaults now inherit them, so a property
+-- override or a changed global default reaches them.
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS check_interval INT NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS retries INT NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS timeout INT NOT NULL DEFAULT 0;
+UPDATE devices SET check_interval = 0 WHERE check_interval = 60 OR check_interval IS NULL;
+UPDATE devices SET retries = 0 WHERE retries = 3 OR retries IS NULL;
+UPDATE devices SET timeout = 0 WHERE timeout = 10000 OR timeout IS NULL;
+
+-- +goose Down
+UPDATE devices SET check_interval = 60 WHERE check_interval = 0;
+UPDATE devices SET retries = 3 WHERE retries = 0;
+UPDATE devices SET timeout = 10000 WHERE timeout = 0;
+ALTER TABLE properties DROP COLUMN IF EXISTS timeout;
+ALTER TABLE properties DROP COLUMN IF EXISTS retries;
+ALTER TABLE properties DROP COLUMN IF EXISTS check_interval;
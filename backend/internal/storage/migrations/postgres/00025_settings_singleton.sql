@@ -0,0 +1,17 @@
+-- +goose Up
+-- Settings are a single row with id 1: keep the oldest row if an older install has no
+-- row 1, drop any others and make sure the row exists
+UPDATE settings SET id = 1
+WHERE id = (SELECT MIN(id) FROM settings)
+  AND NOT EXISTS (SELECT 1 FROM settings WHERE id = 1);
+
+DELETE FROM settings WHERE id <> 1;
+
+INSERT INTO settings (id, max_concurrent_pings, default_check_interval, default_retries, default_timeout, history_retention_days, notification_cooldown)
+VALUES (1, 150, 60, 3, 10000, 90, 300)
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE settings ADD CONSTRAINT settings_singleton CHECK (id = 1);
+
+-- +goose Down
+ALTER TABLE settings DROP CONSTRAINT IF EXISTS settings_singleton;
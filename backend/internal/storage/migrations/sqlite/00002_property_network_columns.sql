@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE properties ADD COLUMN subnet VARCHAR(18) NOT NULL DEFAULT '';
+ALTER TABLE properties ADD COLUMN pfsense_host VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE properties ADD COLUMN pfsense_port INT NOT NULL DEFAULT 0;
+ALTER TABLE properties ADD COLUMN pfsense_username VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE properties ADD COLUMN pfsense_password TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE properties DROP COLUMN pfsense_password;
+ALTER TABLE properties DROP COLUMN pfsense_username;
+ALTER TABLE properties DROP COLUMN pfsense_port;
+ALTER TABLE properties DROP COLUMN pfsense_host;
+ALTER TABLE properties DROP COLUMN subnet;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS device_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    device_type VARCHAR(50) NOT NULL DEFAULT '',
+    is_critical BOOLEAN NOT NULL DEFAULT false,
+    check_interval INTEGER NOT NULL DEFAULT 60,
+    retries INTEGER NOT NULL DEFAULT 3,
+    timeout INTEGER NOT NULL DEFAULT 10000,
+    tags TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS device_templates;
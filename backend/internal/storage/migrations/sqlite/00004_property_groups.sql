@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS regions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS property_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    region_id INTEGER NOT NULL REFERENCES regions(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (region_id, name)
+);
+
+ALTER TABLE properties ADD COLUMN group_id INTEGER REFERENCES property_groups(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_properties_group_id ON properties(group_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_properties_group_id;
+ALTER TABLE properties DROP COLUMN group_id;
+DROP TABLE IF EXISTS property_groups;
+DROP TABLE IF EXISTS regions;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE properties ADD COLUMN tags TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE notification_rules ADD COLUMN property_tags TEXT NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE notification_rules DROP COLUMN property_tags;
+ALTER TABLE properties DROP COLUMN tags;
@@ -0,0 +1,51 @@
+-- SQLite can't alter a CHECK constraint, so users is rebuilt. Foreign keys are off
+-- while the old table is dropped so the rows referencing it aren't cascaded away;
+-- that setting can't change inside a transaction.
+-- +goose NO TRANSACTION
+
+-- +goose Up
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50) DEFAULT '',
+    role VARCHAR(50) NOT NULL CHECK (role IN ('admin', 'user', 'viewer')),
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO users_new (id, username, password, email, phone, role, active, created_at, updated_at)
+SELECT id, username, password, email, phone, role, active, created_at, updated_at FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+COMMIT;
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50) DEFAULT '',
+    role VARCHAR(50) NOT NULL CHECK (role IN ('admin', 'user')),
+    active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- Viewers are disabled rather than given write access
+INSERT INTO users_new (id, username, password, email, phone, role, active, created_at, updated_at)
+SELECT id, username, password, email, phone,
+    CASE WHEN role = 'viewer' THEN 'user' ELSE role END,
+    CASE WHEN role = 'viewer' THEN false ELSE active END,
+    created_at, updated_at
+FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+COMMIT;
+PRAGMA foreign_keys = ON;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Property locations for the map view, geocoded from the address or set by hand
+ALTER TABLE properties ADD COLUMN latitude REAL;
+ALTER TABLE properties ADD COLUMN longitude REAL;
+
+-- +goose Down
+ALTER TABLE properties DROP COLUMN longitude;
+ALTER TABLE properties DROP COLUMN latitude;
@@ -0,0 +1,50 @@
+-- SQLite can't alter a CHECK constraint, so attachments is rebuilt
+-- +goose NO TRANSACTION
+
+-- +goose Up
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE attachments_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    description TEXT,
+    storage_type VARCHAR(20) NOT NULL CHECK (storage_type IN ('gcs', 's3', 'local', 'google_drive')),
+    storage_path TEXT NOT NULL,
+    file_size INTEGER,
+    mime_type VARCHAR(100),
+    uploaded_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO attachments_new (id, property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by, created_at)
+SELECT id, property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by, created_at FROM attachments;
+DROP TABLE attachments;
+ALTER TABLE attachments_new RENAME TO attachments;
+CREATE INDEX IF NOT EXISTS idx_attachments_property_id ON attachments(property_id);
+COMMIT;
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+BEGIN;
+CREATE TABLE attachments_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    description TEXT,
+    storage_type VARCHAR(20) NOT NULL CHECK (storage_type IN ('gcs', 'local', 'google_drive')),
+    storage_path TEXT NOT NULL,
+    file_size INTEGER,
+    mime_type VARCHAR(100),
+    uploaded_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+-- Files in S3 can't be reached without the setting, so their records go
+INSERT INTO attachments_new (id, property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by, created_at)
+SELECT id, property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by, created_at FROM attachments
+WHERE storage_type <> 's3';
+DROP TABLE attachments;
+ALTER TABLE attachments_new RENAME TO attachments;
+CREATE INDEX IF NOT EXISTS idx_attachments_property_id ON attachments(property_id);
+COMMIT;
+PRAGMA foreign_keys = ON;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Chunked uploads of large attachments that are still in progress, and the largest
+-- attachment that may be uploaded
+CREATE TABLE IF NOT EXISTS attachment_uploads (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    mime_type VARCHAR(100) NOT NULL DEFAULT '',
+    file_size INTEGER NOT NULL,
+    received INTEGER NOT NULL DEFAULT 0,
+    storage_type VARCHAR(20) NOT NULL,
+    storage_path TEXT NOT NULL,
+    session TEXT NOT NULL,
+    uploaded_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_uploads_created_at ON attachment_uploads(created_at);
+
+ALTER TABLE settings ADD COLUMN max_attachment_size_mb INTEGER NOT NULL DEFAULT 500;
+
+-- +goose Down
+ALTER TABLE settings DROP COLUMN max_attachment_size_mb;
+DROP TABLE IF EXISTS attachment_uploads;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Tags for finding attachments across properties
+ALTER TABLE attachments ADD COLUMN tags TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE attachment_uploads ADD COLUMN tags TEXT NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE attachment_uploads DROP COLUMN tags;
+ALTER TABLE attachments DROP COLUMN tags;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- ISP circuits, several per property, each optionally tied to the WAN device it terminates on
+CREATE TABLE IF NOT EXISTS circuits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    provider VARCHAR(255) NOT NULL,
+    circuit_id VARCHAR(255) NOT NULL DEFAULT '',
+    account_number TEXT NOT NULL DEFAULT '',
+    support_phone VARCHAR(50) NOT NULL DEFAULT '',
+    bandwidth_down_mbps INT NOT NULL DEFAULT 0,
+    bandwidth_up_mbps INT NOT NULL DEFAULT 0,
+    static_ips TEXT NOT NULL DEFAULT '{}',
+    device_id INTEGER REFERENCES devices(id) ON DELETE SET NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_circuits_property_id ON circuits(property_id);
+CREATE INDEX IF NOT EXISTS idx_circuits_device_id ON circuits(device_id);
+
+-- Carry each property's free-text ISP details over as its first circuit
+INSERT INTO circuits (property_id, provider, account_number)
+SELECT id, isp_company_name, COALESCE(isp_account_info, '')
+FROM properties
+WHERE COALESCE(isp_company_name, '') <> '';
+
+-- +goose Down
+DROP TABLE IF EXISTS circuits;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Asset details for inventory and warranty tracking
+ALTER TABLE devices ADD COLUMN serial_number VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN manufacturer VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN model VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN firmware_version VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE devices ADD COLUMN purchase_date TIMESTAMP;
+ALTER TABLE devices ADD COLUMN warranty_expires TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_devices_warranty_expires ON devices(warranty_expires);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_devices_warranty_expires;
+ALTER TABLE devices DROP COLUMN warranty_expires;
+ALTER TABLE devices DROP COLUMN purchase_date;
+ALTER TABLE devices DROP COLUMN firmware_version;
+ALTER TABLE devices DROP COLUMN model;
+ALTER TABLE devices DROP COLUMN manufacturer;
+ALTER TABLE devices DROP COLUMN serial_number;
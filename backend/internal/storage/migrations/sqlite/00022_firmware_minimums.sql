@@ -0,0 +1,6 @@
+-- +goose Up
+-- Lowest accepted firmware version per device model, for the firmware fleet report
+ALTER TABLE settings ADD COLUMN firmware_minimums TEXT NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE settings DROP COLUMN firmware_minimums;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Single sign-on identities, by the provider's issuer and the subject it names the user
+-- with, so SSO sign-in finds its account by identity rather than by username
+CREATE TABLE IF NOT EXISTS sso_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sso_identities_user_id ON sso_identities(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS sso_identities;
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Notification Rules
+const notificationRuleColumns = `id, name, priority, enabled, property_id, tags, device_types, property_tags,
+	severity, notification_channel_id, notify_on_recovery, stop_processing, created_at, updated_at`
+
+func scanNotificationRule(row rowScanner, r *models.NotificationRule) error {
+	return row.Scan(&r.ID, &r.Name, &r.Priority, &r.Enabled, &r.PropertyID, scanArray(&r.Tags),
+		scanArray(&r.DeviceTypes), scanArray(&r.PropertyTags), &r.Severity, &r.NotificationChannelID, &r.NotifyOnRecovery,
+		&r.StopProcessing, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (s *PostgresStore) queryNotificationRules(ctx context.Context, query string, args ...interface{}) ([]models.NotificationRule, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.NotificationRule, 0)
+	for rows.Next() {
+		var r models.NotificationRule
+		if err := scanNotificationRule(rows, &r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (s *PostgresStore) CreateNotificationRule(ctx context.Context, r *models.NotificationRule) error {
+	query := `
+		INSERT INTO notification_rules (name, priority, enabled, property_id, tags, device_types, property_tags,
+			severity, notification_channel_id, notify_on_recovery, stop_processing)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, r.Name, r.Priority, r.Enabled, r.PropertyID, array(r.Tags),
+		array(r.DeviceTypes), array(r.PropertyTags), r.Severity, r.NotificationChannelID, r.NotifyOnRecovery,
+		r.StopProcessing).
+		Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (s *PostgresStore) GetNotificationRule(ctx context.Context, id int64) (*models.NotificationRule, error) {
+	r := &models.NotificationRule{}
+	query := `SELECT ` + notificationRuleColumns + ` FROM notification_rules WHERE id = $1`
+	err := scanNotificationRule(s.db.QueryRowContext(ctx, query, id), r)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification rule not found")
+	}
+	return r, err
+}
+
+// ListNotificationRules returns all rules in evaluation order
+func (s *PostgresStore) ListNotificationRules(ctx context.Context) ([]models.NotificationRule, error) {
+	return s.queryNotificationRules(ctx, `SELECT `+notificationRuleColumns+` FROM notification_rules ORDER BY priority, id`)
+}
+
+// ListNotificationRulesForProperty returns the enabled rules that apply to a property,
+// in evaluation order
+func (s *PostgresStore) ListNotificationRulesForProperty(ctx context.Context, propertyID int64) ([]models.NotificationRule, error) {
+	query := `SELECT ` + notificationRuleColumns + ` FROM notification_rules
+		WHERE enabled AND (property_id IS NULL OR property_id = $1)
+		ORDER BY priority, id`
+	return s.queryNotificationRules(ctx, query, propertyID)
+}
+
+func (s *PostgresStore) UpdateNotificationRule(ctx context.Context, r *models.NotificationRule) error {
+	query := `
+		UPDATE notification_rules
+		SET name = $1, priority = $2, enabled = $3, property_id = $4, tags = $5, device_types = $6, property_tags = $7,
+			severity = $8, notification_channel_id = $9, notify_on_recovery = $10, stop_processing = $11, updated_at = NOW()
+		WHERE id = $12
+		RETURNING created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, r.Name, r.Priority, r.Enabled, r.PropertyID, array(r.Tags),
+		array(r.DeviceTypes), array(r.PropertyTags), r.Severity, r.NotificationChannelID, r.NotifyOnRecovery,
+		r.StopProcessing, r.ID).
+		Scan(&r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("notification rule not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteNotificationRule(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM notification_rules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("notification rule not found")
+	}
+	return nil
+}
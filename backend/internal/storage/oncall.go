@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// On-Call Schedules
+const oncallScheduleColumns = `id, name, timezone, rotation_start, rotation_days, member_ids, created_at, updated_at`
+
+func scanOnCallSchedule(row rowScanner, sch *models.OnCallSchedule) error {
+	return row.Scan(&sch.ID, &sch.Name, &sch.Timezone, &sch.RotationStart, &sch.RotationDays,
+		scanArray(&sch.MemberIDs), &sch.CreatedAt, &sch.UpdatedAt)
+}
+
+func (s *PostgresStore) CreateOnCallSchedule(ctx context.Context, sch *models.OnCallSchedule) error {
+	query := `
+		INSERT INTO oncall_schedules (name, timezone, rotation_start, rotation_days, member_ids)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, sch.Name, sch.Timezone, sch.RotationStart, sch.RotationDays,
+		array(sch.MemberIDs)).Scan(&sch.ID, &sch.CreatedAt, &sch.UpdatedAt)
+}
+
+func (s *PostgresStore) GetOnCallSchedule(ctx context.Context, id int64) (*models.OnCallSchedule, error) {
+	sch := &models.OnCallSchedule{}
+	query := `SELECT ` + oncallScheduleColumns + ` FROM oncall_schedules WHERE id = $1`
+	err := scanOnCallSchedule(s.db.QueryRowContext(ctx, query, id), sch)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("on-call schedule not found")
+	}
+	return sch, err
+}
+
+func (s *PostgresStore) ListOnCallSchedules(ctx context.Context) ([]models.OnCallSchedule, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+oncallScheduleColumns+` FROM oncall_schedules ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]models.OnCallSchedule, 0)
+	for rows.Next() {
+		var sch models.OnCallSchedule
+		if err := scanOnCallSchedule(rows, &sch); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, sch)
+	}
+	return schedules, rows.Err()
+}
+
+func (s *PostgresStore) UpdateOnCallSchedule(ctx context.Context, sch *models.OnCallSchedule) error {
+	query := `
+		UPDATE oncall_schedules
+		SET name = $1, timezone = $2, rotation_start = $3, rotation_days = $4, member_ids = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, sch.Name, sch.Timezone, sch.RotationStart, sch.RotationDays,
+		array(sch.MemberIDs), sch.ID).Scan(&sch.CreatedAt, &sch.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("on-call schedule not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteOnCallSchedule(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM oncall_schedules WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("on-call schedule not found")
+	}
+	return nil
+}
+
+// On-Call Overrides
+func (s *PostgresStore) CreateOnCallOverride(ctx context.Context, o *models.OnCallOverride) error {
+	query := `
+		INSERT INTO oncall_overrides (schedule_id, user_id, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, o.ScheduleID, o.UserID, o.StartsAt, o.EndsAt, o.Reason).
+		Scan(&o.ID, &o.CreatedAt)
+}
+
+// ListOnCallOverrides returns a schedule's overrides that end after the given time
+func (s *PostgresStore) ListOnCallOverrides(ctx context.Context, scheduleID int64, after time.Time) ([]models.OnCallOverride, error) {
+	query := `
+		SELECT id, schedule_id, user_id, starts_at, ends_at, reason, created_at
+		FROM oncall_overrides
+		WHERE schedule_id = $1 AND ends_at > $2
+		ORDER BY starts_at`
+	rows, err := s.db.QueryContext(ctx, query, scheduleID, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make([]models.OnCallOverride, 0)
+	for rows.Next() {
+		var o models.OnCallOverride
+		if err := rows.Scan(&o.ID, &o.ScheduleID, &o.UserID, &o.StartsAt, &o.EndsAt, &o.Reason, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+func (s *PostgresStore) DeleteOnCallOverride(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM oncall_overrides WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("on-call override not found")
+	}
+	return nil
+}
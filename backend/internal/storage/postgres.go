@@ -2,36 +2,49 @@ package storage
 
 import (
 	"context"
+	"crypto/cipher"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/netip"
+	"strings"
 	"time"
 
-	"github.com/lib/pq"
-	_ "github.com/lib/pq"
 	"github.com/etswifi/ets-noc/internal/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type PostgresStore struct {
-	db *sql.DB
+	db      *database
+	secrets cipher.AEAD // nil when secret encryption is disabled
 }
 
-func NewPostgresStore(connStr string) (*PostgresStore, error) {
-	db, err := sql.Open("postgres", connStr)
+// NewPostgresStore connects to Postgres through pgx, with the pool and TLS settings in
+// opts
+func NewPostgresStore(connStr string, opts PostgresOptions) (*PostgresStore, error) {
+	config, err := opts.connConfig(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
 	}
+	db := sql.OpenDB(newCountingConnector(config))
+	db.SetMaxOpenConns(opts.maxOpenConns())
+	db.SetMaxIdleConns(opts.maxIdleConns())
+	db.SetConnMaxLifetime(opts.connMaxLifetime())
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
 
-	if err := db.Ping(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping postgres: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	return &PostgresStore{db: &database{DB: db}}, nil
+}
 
-	return &PostgresStore{db: db}, nil
+// Ping checks the database can be reached
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
 }
 
 func (s *PostgresStore) Close() error {
@@ -39,41 +52,50 @@ func (s *PostgresStore) Close() error {
 }
 
 // Properties
+
+// CreateProperty stores a property with the router device every property starts with.
+// A property without a subnet is given one from the subnet_base setting.
 func (s *PostgresStore) CreateProperty(ctx context.Context, p *models.Property) error {
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
 	query := `
-		INSERT INTO properties (name, address, notes, isp_company_name, isp_account_info)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO properties (name, address, notes, isp_company_name, isp_account_info, group_id, tags, latitude, longitude,
+			check_interval, retries, timeout)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at`
-	err := s.db.QueryRowContext(ctx, query, p.Name, p.Address, p.Notes, p.ISPCompanyName, p.ISPAccountInfo).
+	err := s.db.QueryRowContext(ctx, query, p.Name, p.Address, p.Notes, p.ISPCompanyName, p.ISPAccountInfo, p.GroupID,
+		array(p.Tags), p.Latitude, p.Longitude, p.CheckInterval, p.Retries, p.Timeout).
 		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return err
 	}
 
-	// Auto-calculate subnet based on property ID: 10.(99 + floor(ID/256)).(ID%256).0/24
-	subnetQuery := `
-		UPDATE properties
-		SET subnet = '10.' || (99 + (id / 256))::text || '.' || (id % 256)::text || '.0/24'
-		WHERE id = $1
-		RETURNING subnet`
-	if err := s.db.QueryRowContext(ctx, subnetQuery, p.ID).Scan(&p.Subnet); err != nil {
+	// A property without a subnet gets the next automatic one; see assignSubnet
+	subnet, err := netip.ParsePrefix(p.Subnet)
+	if p.Subnet == "" {
+		subnet, err = s.assignSubnet(ctx, p.ID)
+	}
+	if err != nil {
+		return err
+	}
+	subnet = subnet.Masked()
+	p.Subnet = subnet.String()
+	if _, err := s.db.ExecContext(ctx, "UPDATE properties SET subnet = $1 WHERE id = $2", p.Subnet, p.ID); err != nil {
 		return err
 	}
 
-	// Auto-create router device at .1
-	routerIP := fmt.Sprintf("10.%d.%d.1", 99+(p.ID/256), p.ID%256)
+	// Auto-create router device at the subnet's first address
+	routerIP := subnet.Addr().Next().String()
 	routerDevice := &models.Device{
-		PropertyID:    p.ID,
-		Name:          fmt.Sprintf("%s-router", p.Name),
-		Hostname:      routerIP,
-		DeviceType:    "Router",
-		Tags:          []string{"Router"},
-		IsCritical:    true,
-		Active:        true,
-		CheckInterval: 60,
-		Retries:       3,
-		Timeout:       10000,
-		Description:   "Auto-created router device",
+		PropertyID:  p.ID,
+		Name:        fmt.Sprintf("%s-router", p.Name),
+		Hostname:    routerIP,
+		DeviceType:  "Router",
+		Tags:        []string{"Router"},
+		IsCritical:  true,
+		Active:      true,
+		Description: "Auto-created router device",
 	}
 
 	routerQuery := `
@@ -82,7 +104,7 @@ func (s *PostgresStore) CreateProperty(ctx context.Context, p *models.Property)
 		RETURNING id, created_at, updated_at`
 	err = s.db.QueryRowContext(ctx, routerQuery, routerDevice.PropertyID, routerDevice.Name, routerDevice.Hostname,
 		routerDevice.DeviceType, routerDevice.IsCritical, routerDevice.CheckInterval, routerDevice.Retries,
-		routerDevice.Timeout, routerDevice.Description, pq.Array(routerDevice.Tags), routerDevice.Active).
+		routerDevice.Timeout, routerDevice.Description, array(routerDevice.Tags), routerDevice.Active).
 		Scan(&routerDevice.ID, &routerDevice.CreatedAt, &routerDevice.UpdatedAt)
 
 	return err
@@ -91,23 +113,35 @@ func (s *PostgresStore) CreateProperty(ctx context.Context, p *models.Property)
 func (s *PostgresStore) GetProperty(ctx context.Context, id int64) (*models.Property, error) {
 	p := &models.Property{}
 	query := `SELECT id, name, address, subnet, notes, isp_company_name, isp_account_info,
-		pfsense_host, pfsense_port, pfsense_username, pfsense_password, created_at, updated_at
+		pfsense_host, pfsense_port, pfsense_username, pfsense_password, group_id, tags, latitude, longitude,
+		check_interval, retries, timeout, created_at, updated_at
 		FROM properties WHERE id = $1`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&p.ID, &p.Name, &p.Address, &p.Subnet, &p.Notes, &p.ISPCompanyName, &p.ISPAccountInfo,
-		&p.PfSenseHost, &p.PfSensePort, &p.PfSenseUsername, &p.PfSensePassword,
-		&p.CreatedAt, &p.UpdatedAt)
+		&p.PfSenseHost, &p.PfSensePort, &p.PfSenseUsername, &p.PfSensePassword, &p.GroupID, scanArray(&p.Tags),
+		&p.Latitude, &p.Longitude, &p.CheckInterval, &p.Retries, &p.Timeout, &p.CreatedAt, &p.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("property not found")
 	}
-	return p, err
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openSecret(&p.PfSensePassword); err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
+const propertyColumns = `id, name, address, subnet, notes, isp_company_name, isp_account_info,
+	pfsense_host, pfsense_port, pfsense_username, pfsense_password, group_id, tags, latitude, longitude,
+	check_interval, retries, timeout, created_at, updated_at`
+
 func (s *PostgresStore) ListProperties(ctx context.Context) ([]models.Property, error) {
-	query := `SELECT id, name, address, subnet, notes, isp_company_name, isp_account_info,
-		pfsense_host, pfsense_port, pfsense_username, pfsense_password, created_at, updated_at
-		FROM properties ORDER BY name`
-	rows, err := s.db.QueryContext(ctx, query)
+	return s.queryProperties(ctx, `SELECT `+propertyColumns+` FROM properties ORDER BY name`)
+}
+
+func (s *PostgresStore) queryProperties(ctx context.Context, query string, args ...interface{}) ([]models.Property, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -117,8 +151,11 @@ func (s *PostgresStore) ListProperties(ctx context.Context) ([]models.Property,
 	for rows.Next() {
 		var p models.Property
 		if err := rows.Scan(&p.ID, &p.Name, &p.Address, &p.Subnet, &p.Notes, &p.ISPCompanyName, &p.ISPAccountInfo,
-			&p.PfSenseHost, &p.PfSensePort, &p.PfSenseUsername, &p.PfSensePassword,
-			&p.CreatedAt, &p.UpdatedAt); err != nil {
+			&p.PfSenseHost, &p.PfSensePort, &p.PfSenseUsername, &p.PfSensePassword, &p.GroupID, scanArray(&p.Tags),
+			&p.Latitude, &p.Longitude, &p.CheckInterval, &p.Retries, &p.Timeout, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := s.openSecret(&p.PfSensePassword); err != nil {
 			return nil, err
 		}
 		properties = append(properties, p)
@@ -126,16 +163,29 @@ func (s *PostgresStore) ListProperties(ctx context.Context) ([]models.Property,
 	return properties, rows.Err()
 }
 
+// UpdateProperty saves a property. An empty pfSense password or subnet keeps the
+// stored one.
 func (s *PostgresStore) UpdateProperty(ctx context.Context, p *models.Property) error {
+	password, err := s.sealSecret(p.PfSensePassword)
+	if err != nil {
+		return err
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
 	query := `
 		UPDATE properties
 		SET name = $1, address = $2, notes = $3, isp_company_name = $4, isp_account_info = $5,
-		    pfsense_host = $6, pfsense_port = $7, pfsense_username = $8, pfsense_password = $9, updated_at = NOW()
-		WHERE id = $10
-		RETURNING updated_at`
+		    pfsense_host = $6, pfsense_port = $7, pfsense_username = $8,
+		    pfsense_password = COALESCE(NULLIF($9, ''), pfsense_password), group_id = $10, tags = $11,
+		    latitude = $12, longitude = $13, subnet = COALESCE(NULLIF($14, ''), subnet),
+		    check_interval = $15, retries = $16, timeout = $17, updated_at = NOW()
+		WHERE id = $18
+		RETURNING updated_at, pfsense_password <> '', subnet`
 	return s.db.QueryRowContext(ctx, query, p.Name, p.Address, p.Notes, p.ISPCompanyName, p.ISPAccountInfo,
-		p.PfSenseHost, p.PfSensePort, p.PfSenseUsername, p.PfSensePassword, p.ID).
-		Scan(&p.UpdatedAt)
+		p.PfSenseHost, p.PfSensePort, p.PfSenseUsername, password, p.GroupID, array(p.Tags),
+		p.Latitude, p.Longitude, p.Subnet, p.CheckInterval, p.Retries, p.Timeout, p.ID).
+		Scan(&p.UpdatedAt, &p.PfSensePasswordSet, &p.Subnet)
 }
 
 func (s *PostgresStore) DeleteProperty(ctx context.Context, id int64) error {
@@ -156,29 +206,33 @@ func (s *PostgresStore) DeleteProperty(ctx context.Context, id int64) error {
 // Contacts
 func (s *PostgresStore) CreateContact(ctx context.Context, c *models.Contact) error {
 	query := `
-		INSERT INTO contacts (property_id, name, phone, email, role, notes)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO contacts (property_id, name, phone, email, role, notes, escalation_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, created_at, updated_at`
-	return s.db.QueryRowContext(ctx, query, c.PropertyID, c.Name, c.Phone, c.Email, c.Role, c.Notes).
+	return s.db.QueryRowContext(ctx, query, c.PropertyID, c.Name, c.Phone, c.Email, c.Role, c.Notes, c.EscalationOrder).
 		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
 }
 
 func (s *PostgresStore) GetContact(ctx context.Context, id int64) (*models.Contact, error) {
 	c := &models.Contact{}
-	query := `SELECT id, property_id, name, phone, email, role, notes, created_at, updated_at
+	query := `SELECT id, property_id, name, phone, email, role, notes, escalation_order, created_at, updated_at
 		FROM contacts WHERE id = $1`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&c.ID, &c.PropertyID, &c.Name, &c.Phone, &c.Email, &c.Role, &c.Notes, &c.CreatedAt, &c.UpdatedAt)
+		&c.ID, &c.PropertyID, &c.Name, &c.Phone, &c.Email, &c.Role, &c.Notes, &c.EscalationOrder, &c.CreatedAt, &c.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("contact not found")
 	}
 	return c, err
 }
 
+const contactColumns = `id, property_id, name, phone, email, role, notes, escalation_order, created_at, updated_at`
+
 func (s *PostgresStore) ListContactsForProperty(ctx context.Context, propertyID int64) ([]models.Contact, error) {
-	query := `SELECT id, property_id, name, phone, email, role, notes, created_at, updated_at
-		FROM contacts WHERE property_id = $1 ORDER BY name`
-	rows, err := s.db.QueryContext(ctx, query, propertyID)
+	return s.queryContacts(ctx, `SELECT `+contactColumns+` FROM contacts WHERE property_id = $1 ORDER BY name`, propertyID)
+}
+
+func (s *PostgresStore) queryContacts(ctx context.Context, query string, args ...interface{}) ([]models.Contact, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +242,7 @@ func (s *PostgresStore) ListContactsForProperty(ctx context.Context, propertyID
 	for rows.Next() {
 		var c models.Contact
 		if err := rows.Scan(&c.ID, &c.PropertyID, &c.Name, &c.Phone, &c.Email, &c.Role, &c.Notes,
-			&c.CreatedAt, &c.UpdatedAt); err != nil {
+			&c.EscalationOrder, &c.CreatedAt, &c.UpdatedAt); err != nil {
 			return nil, err
 		}
 		contacts = append(contacts, c)
@@ -199,10 +253,10 @@ func (s *PostgresStore) ListContactsForProperty(ctx context.Context, propertyID
 func (s *PostgresStore) UpdateContact(ctx context.Context, c *models.Contact) error {
 	query := `
 		UPDATE contacts
-		SET name = $1, phone = $2, email = $3, role = $4, notes = $5, updated_at = NOW()
-		WHERE id = $6
+		SET name = $1, phone = $2, email = $3, role = $4, notes = $5, escalation_order = $6, updated_at = NOW()
+		WHERE id = $7
 		RETURNING updated_at`
-	return s.db.QueryRowContext(ctx, query, c.Name, c.Phone, c.Email, c.Role, c.Notes, c.ID).
+	return s.db.QueryRowContext(ctx, query, c.Name, c.Phone, c.Email, c.Role, c.Notes, c.EscalationOrder, c.ID).
 		Scan(&c.UpdatedAt)
 }
 
@@ -222,32 +276,39 @@ func (s *PostgresStore) DeleteContact(ctx context.Context, id int64) error {
 }
 
 // Attachments
+const attachmentColumns = `id, property_id, filename, description, storage_type, storage_path, file_size, mime_type,
+	tags, uploaded_by, created_at,
+	COALESCE((SELECT name FROM properties WHERE properties.id = attachments.property_id), '')`
+
 func (s *PostgresStore) CreateAttachment(ctx context.Context, a *models.Attachment) error {
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
 	query := `
-		INSERT INTO attachments (property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO attachments (property_id, filename, description, storage_type, storage_path, file_size, mime_type, tags, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id, created_at`
 	return s.db.QueryRowContext(ctx, query, a.PropertyID, a.Filename, a.Description, a.StorageType,
-		a.StoragePath, a.FileSize, a.MimeType, a.UploadedBy).Scan(&a.ID, &a.CreatedAt)
+		a.StoragePath, a.FileSize, a.MimeType, array(a.Tags), a.UploadedBy).Scan(&a.ID, &a.CreatedAt)
 }
 
 func (s *PostgresStore) GetAttachment(ctx context.Context, id int64) (*models.Attachment, error) {
-	a := &models.Attachment{}
-	query := `SELECT id, property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by, created_at
-		FROM attachments WHERE id = $1`
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&a.ID, &a.PropertyID, &a.Filename, &a.Description, &a.StorageType, &a.StoragePath,
-		&a.FileSize, &a.MimeType, &a.UploadedBy, &a.CreatedAt)
-	if err == sql.ErrNoRows {
+	attachments, err := s.queryAttachments(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
 		return nil, fmt.Errorf("attachment not found")
 	}
-	return a, err
+	return &attachments[0], nil
 }
 
 func (s *PostgresStore) ListAttachmentsForProperty(ctx context.Context, propertyID int64) ([]models.Attachment, error) {
-	query := `SELECT id, property_id, filename, description, storage_type, storage_path, file_size, mime_type, uploaded_by, created_at
-		FROM attachments WHERE property_id = $1 ORDER BY created_at DESC`
-	rows, err := s.db.QueryContext(ctx, query, propertyID)
+	return s.queryAttachments(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE property_id = $1 ORDER BY created_at DESC`, propertyID)
+}
+
+func (s *PostgresStore) queryAttachments(ctx context.Context, query string, args ...interface{}) ([]models.Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -257,16 +318,26 @@ func (s *PostgresStore) ListAttachmentsForProperty(ctx context.Context, property
 	for rows.Next() {
 		var a models.Attachment
 		if err := rows.Scan(&a.ID, &a.PropertyID, &a.Filename, &a.Description, &a.StorageType,
-			&a.StoragePath, &a.FileSize, &a.MimeType, &a.UploadedBy, &a.CreatedAt); err != nil {
+			&a.StoragePath, &a.FileSize, &a.MimeType, scanArray(&a.Tags), &a.UploadedBy, &a.CreatedAt,
+			&a.PropertyName); err != nil {
 			return nil, err
 		}
+		if a.Tags == nil {
+			a.Tags = []string{}
+		}
 		attachments = append(attachments, a)
 	}
 	return attachments, rows.Err()
 }
 
-func (s *PostgresStore) DeleteAttachment(ctx context.Context, id int64) error {
-	result, err := s.db.ExecContext(ctx, "DELETE FROM attachments WHERE id = $1", id)
+// UpdateAttachment saves an attachment's description and tags; the file itself can't
+// be changed
+func (s *PostgresStore) UpdateAttachment(ctx context.Context, a *models.Attachment) error {
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
+	result, err := s.db.ExecContext(ctx, "UPDATE attachments SET description = $1, tags = $2 WHERE id = $3",
+		a.Description, array(a.Tags), a.ID)
 	if err != nil {
 		return err
 	}
@@ -280,56 +351,62 @@ func (s *PostgresStore) DeleteAttachment(ctx context.Context, id int64) error {
 	return nil
 }
 
-// Devices
-func (s *PostgresStore) CreateDevice(ctx context.Context, d *models.Device) error {
-	query := `
-		INSERT INTO devices (property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout, description, tags, active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, created_at, updated_at`
-	return s.db.QueryRowContext(ctx, query, d.PropertyID, d.Name, d.Hostname, d.DeviceType, d.IsCritical,
-		d.CheckInterval, d.Retries, d.Timeout, d.Description, pq.Array(d.Tags), d.Active).
-		Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
-}
-
-func (s *PostgresStore) GetDevice(ctx context.Context, id int64) (*models.Device, error) {
-	d := &models.Device{}
-	query := `SELECT id, property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout, description, tags, active, created_at, updated_at
-		FROM devices WHERE id = $1`
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&d.ID, &d.PropertyID, &d.Name, &d.Hostname, &d.DeviceType, &d.IsCritical, &d.CheckInterval,
-		&d.Retries, &d.Timeout, &d.Description, pq.Array(&d.Tags), &d.Active, &d.CreatedAt, &d.UpdatedAt)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("device not found")
+func (s *PostgresStore) DeleteAttachment(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM attachments WHERE id = $1", id)
+	if err != nil {
+		return err
 	}
-	return d, err
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("attachment not found")
+	}
+	return nil
 }
 
-func (s *PostgresStore) ListDevices(ctx context.Context) ([]models.Device, error) {
-	query := `SELECT id, property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout, description, tags, active, created_at, updated_at
-		FROM devices ORDER BY name`
-	rows, err := s.db.QueryContext(ctx, query)
+// ListAttachmentStoragePaths returns the blob object names of every attachment and
+// chunked upload in progress
+func (s *PostgresStore) ListAttachmentStoragePaths(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT storage_path FROM attachments WHERE storage_type <> 'google_drive'
+		UNION SELECT storage_path FROM attachment_uploads`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	devices := make([]models.Device, 0)
+	paths := make([]string, 0)
 	for rows.Next() {
-		var d models.Device
-		if err := rows.Scan(&d.ID, &d.PropertyID, &d.Name, &d.Hostname, &d.DeviceType, &d.IsCritical,
-			&d.CheckInterval, &d.Retries, &d.Timeout, &d.Description, pq.Array(&d.Tags), &d.Active,
-			&d.CreatedAt, &d.UpdatedAt); err != nil {
+		var path string
+		if err := rows.Scan(&path); err != nil {
 			return nil, err
 		}
-		devices = append(devices, d)
+		paths = append(paths, path)
 	}
-	return devices, rows.Err()
+	return paths, rows.Err()
 }
 
-func (s *PostgresStore) ListDevicesForProperty(ctx context.Context, propertyID int64) ([]models.Device, error) {
-	query := `SELECT id, property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout, description, tags, active, created_at, updated_at
-		FROM devices WHERE property_id = $1 ORDER BY name`
-	rows, err := s.db.QueryContext(ctx, query, propertyID)
+// Devices
+const deviceColumns = `id, property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout,
+	description, tags, active, check_type, port, failure_threshold, parent_device_id, mac_address, probe_id,
+	serial_number, manufacturer, model, firmware_version, purchase_date, warranty_expires, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDevice(row rowScanner, d *models.Device) error {
+	return row.Scan(&d.ID, &d.PropertyID, &d.Name, &d.Hostname, &d.DeviceType, &d.IsCritical,
+		&d.CheckInterval, &d.Retries, &d.Timeout, &d.Description, scanArray(&d.Tags), &d.Active,
+		&d.CheckType, &d.Port, &d.FailureThreshold, &d.ParentDeviceID, &d.MACAddress, &d.ProbeID,
+		&d.SerialNumber, &d.Manufacturer, &d.Model, &d.FirmwareVersion, &d.PurchaseDate, &d.WarrantyExpires,
+		&d.CreatedAt, &d.UpdatedAt)
+}
+
+func (s *PostgresStore) queryDevices(ctx context.Context, query string, args ...interface{}) ([]models.Device, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -338,9 +415,7 @@ func (s *PostgresStore) ListDevicesForProperty(ctx context.Context, propertyID i
 	devices := make([]models.Device, 0)
 	for rows.Next() {
 		var d models.Device
-		if err := rows.Scan(&d.ID, &d.PropertyID, &d.Name, &d.Hostname, &d.DeviceType, &d.IsCritical,
-			&d.CheckInterval, &d.Retries, &d.Timeout, &d.Description, pq.Array(&d.Tags), &d.Active,
-			&d.CreatedAt, &d.UpdatedAt); err != nil {
+		if err := scanDevice(rows, &d); err != nil {
 			return nil, err
 		}
 		devices = append(devices, d)
@@ -348,37 +423,120 @@ func (s *PostgresStore) ListDevicesForProperty(ctx context.Context, propertyID i
 	return devices, rows.Err()
 }
 
+// applyDeviceDefaults fills in check settings that were left unset and normalizes
+// the MAC address
+func applyDeviceDefaults(d *models.Device) {
+	d.MACAddress = strings.ToLower(strings.TrimSpace(d.MACAddress))
+	if d.CheckType == "" {
+		d.CheckType = "icmp"
+	}
+	if d.FailureThreshold <= 0 {
+		d.FailureThreshold = 3
+	}
+}
+
+func (s *PostgresStore) CreateDevice(ctx context.Context, d *models.Device) error {
+	applyDeviceDefaults(d)
+	query := `
+		INSERT INTO devices (property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout, description, tags, active,
+		                     check_type, port, failure_threshold, parent_device_id, mac_address, probe_id,
+		                     serial_number, manufacturer, model, firmware_version, purchase_date, warranty_expires)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, d.PropertyID, d.Name, d.Hostname, d.DeviceType, d.IsCritical,
+		d.CheckInterval, d.Retries, d.Timeout, d.Description, array(d.Tags), d.Active, d.CheckType, d.Port,
+		d.FailureThreshold, d.ParentDeviceID, d.MACAddress, d.ProbeID,
+		d.SerialNumber, d.Manufacturer, d.Model, d.FirmwareVersion, d.PurchaseDate, d.WarrantyExpires).
+		Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+}
+
+func (s *PostgresStore) GetDevice(ctx context.Context, id int64) (*models.Device, error) {
+	d := &models.Device{}
+	query := `SELECT ` + deviceColumns + ` FROM devices WHERE id = $1`
+	err := scanDevice(s.db.QueryRowContext(ctx, query, id), d)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("device not found")
+	}
+	return d, err
+}
+
+func (s *PostgresStore) ListDevices(ctx context.Context) ([]models.Device, error) {
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices ORDER BY name`)
+}
+
+func (s *PostgresStore) ListDevicesForProperty(ctx context.Context, propertyID int64) ([]models.Device, error) {
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices WHERE property_id = $1 ORDER BY name`, propertyID)
+}
+
 func (s *PostgresStore) ListActiveDevices(ctx context.Context) ([]models.Device, error) {
-	query := `SELECT id, property_id, name, hostname, device_type, is_critical, check_interval, retries, timeout, description, tags, active, created_at, updated_at
-		FROM devices WHERE active = true ORDER BY name`
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices WHERE active = true ORDER BY name`)
+}
+
+// ListDevicesWarrantyExpiring returns the devices whose warranty ends from one time up
+// to another, soonest first, at one property or all when propertyID is zero. A zero
+// from includes warranties that have already lapsed.
+func (s *PostgresStore) ListDevicesWarrantyExpiring(ctx context.Context, from, before time.Time, propertyID int64) ([]models.Device, error) {
+	var q listQuery
+	q.add("warranty_expires < $%d", before)
+	if !from.IsZero() {
+		q.add("warranty_expires >= $%d", from)
 	}
-	defer rows.Close()
+	if propertyID != 0 {
+		q.add("property_id = $%d", propertyID)
+	}
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices`+q.where()+` ORDER BY warranty_expires, name`, q.args...)
+}
 
-	devices := make([]models.Device, 0)
-	for rows.Next() {
-		var d models.Device
-		if err := rows.Scan(&d.ID, &d.PropertyID, &d.Name, &d.Hostname, &d.DeviceType, &d.IsCritical,
-			&d.CheckInterval, &d.Retries, &d.Timeout, &d.Description, pq.Array(&d.Tags), &d.Active,
-			&d.CreatedAt, &d.UpdatedAt); err != nil {
-			return nil, err
-		}
-		devices = append(devices, d)
+// ListDevicesByMAC returns the devices anywhere in the fleet with a MAC address
+func (s *PostgresStore) ListDevicesByMAC(ctx context.Context, mac string) ([]models.Device, error) {
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices WHERE mac_address = $1 ORDER BY id`, mac)
+}
+
+// ListDevicesWithFirmware returns the devices with a known firmware version, at one
+// property or all when propertyID is 0
+func (s *PostgresStore) ListDevicesWithFirmware(ctx context.Context, propertyID int64) ([]models.Device, error) {
+	var q listQuery
+	q.add("firmware_version <> $%d", "")
+	if propertyID != 0 {
+		q.add("property_id = $%d", propertyID)
 	}
-	return devices, rows.Err()
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices`+q.where()+` ORDER BY manufacturer, model, firmware_version, name`, q.args...)
+}
+
+// SetDeviceFirmware records the firmware version an integration reported for a device,
+// filling in its manufacturer and model only where they are blank
+func (s *PostgresStore) SetDeviceFirmware(ctx context.Context, id int64, manufacturer, model, version string) error {
+	query := `
+		UPDATE devices
+		SET firmware_version = $2,
+		    manufacturer = CASE WHEN manufacturer = '' THEN $3 ELSE manufacturer END,
+		    model = CASE WHEN model = '' THEN $4 ELSE model END,
+		    updated_at = NOW()
+		WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id, version, manufacturer, model)
+	return err
+}
+
+// ListDevicesForProbe returns the active devices assigned to a remote probe
+func (s *PostgresStore) ListDevicesForProbe(ctx context.Context, probeID int64) ([]models.Device, error) {
+	return s.queryDevices(ctx, `SELECT `+deviceColumns+` FROM devices WHERE probe_id = $1 AND active = true ORDER BY name`, probeID)
 }
 
 func (s *PostgresStore) UpdateDevice(ctx context.Context, d *models.Device) error {
+	applyDeviceDefaults(d)
 	query := `
 		UPDATE devices
 		SET property_id = $1, name = $2, hostname = $3, device_type = $4, is_critical = $5,
-		    check_interval = $6, retries = $7, timeout = $8, description = $9, tags = $10, active = $11, updated_at = NOW()
-		WHERE id = $12
+		    check_interval = $6, retries = $7, timeout = $8, description = $9, tags = $10, active = $11,
+		    check_type = $12, port = $13, failure_threshold = $14, parent_device_id = $15, mac_address = $16, probe_id = $17,
+		    serial_number = $18, manufacturer = $19, model = $20, firmware_version = $21, purchase_date = $22,
+		    warranty_expires = $23, updated_at = NOW()
+		WHERE id = $24
 		RETURNING updated_at`
 	return s.db.QueryRowContext(ctx, query, d.PropertyID, d.Name, d.Hostname, d.DeviceType, d.IsCritical,
-		d.CheckInterval, d.Retries, d.Timeout, d.Description, pq.Array(d.Tags), d.Active, d.ID).
+		d.CheckInterval, d.Retries, d.Timeout, d.Description, array(d.Tags), d.Active, d.CheckType, d.Port,
+		d.FailureThreshold, d.ParentDeviceID, d.MACAddress, d.ProbeID,
+		d.SerialNumber, d.Manufacturer, d.Model, d.FirmwareVersion, d.PurchaseDate, d.WarrantyExpires, d.ID).
 		Scan(&d.UpdatedAt)
 }
 
@@ -399,28 +557,38 @@ func (s *PostgresStore) DeleteDevice(ctx context.Context, id int64) error {
 
 // Notification Channels
 func (s *PostgresStore) CreateNotificationChannel(ctx context.Context, nc *models.NotificationChannel) error {
+	config, err := s.sealSecret(nc.Config)
+	if err != nil {
+		return err
+	}
 	query := `
-		INSERT INTO notification_channels (name, type, config, enabled)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO notification_channels (name, type, config, enabled, system_alerts)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at`
-	return s.db.QueryRowContext(ctx, query, nc.Name, nc.Type, nc.Config, nc.Enabled).
+	return s.db.QueryRowContext(ctx, query, nc.Name, nc.Type, config, nc.Enabled, nc.SystemAlerts).
 		Scan(&nc.ID, &nc.CreatedAt, &nc.UpdatedAt)
 }
 
 func (s *PostgresStore) GetNotificationChannel(ctx context.Context, id int64) (*models.NotificationChannel, error) {
 	nc := &models.NotificationChannel{}
-	query := `SELECT id, name, type, config, enabled, created_at, updated_at
+	query := `SELECT id, name, type, config, enabled, system_alerts, created_at, updated_at
 		FROM notification_channels WHERE id = $1`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&nc.ID, &nc.Name, &nc.Type, &nc.Config, &nc.Enabled, &nc.CreatedAt, &nc.UpdatedAt)
+		&nc.ID, &nc.Name, &nc.Type, &nc.Config, &nc.Enabled, &nc.SystemAlerts, &nc.CreatedAt, &nc.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("notification channel not found")
 	}
-	return nc, err
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openSecret(&nc.Config); err != nil {
+		return nil, err
+	}
+	return nc, nil
 }
 
 func (s *PostgresStore) ListNotificationChannels(ctx context.Context) ([]models.NotificationChannel, error) {
-	query := `SELECT id, name, type, config, enabled, created_at, updated_at
+	query := `SELECT id, name, type, config, enabled, system_alerts, created_at, updated_at
 		FROM notification_channels ORDER BY name`
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
@@ -432,7 +600,10 @@ func (s *PostgresStore) ListNotificationChannels(ctx context.Context) ([]models.
 	for rows.Next() {
 		var nc models.NotificationChannel
 		if err := rows.Scan(&nc.ID, &nc.Name, &nc.Type, &nc.Config, &nc.Enabled,
-			&nc.CreatedAt, &nc.UpdatedAt); err != nil {
+			&nc.SystemAlerts, &nc.CreatedAt, &nc.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := s.openSecret(&nc.Config); err != nil {
 			return nil, err
 		}
 		channels = append(channels, nc)
@@ -441,12 +612,16 @@ func (s *PostgresStore) ListNotificationChannels(ctx context.Context) ([]models.
 }
 
 func (s *PostgresStore) UpdateNotificationChannel(ctx context.Context, nc *models.NotificationChannel) error {
+	config, err := s.sealSecret(nc.Config)
+	if err != nil {
+		return err
+	}
 	query := `
 		UPDATE notification_channels
-		SET name = $1, type = $2, config = $3, enabled = $4, updated_at = NOW()
-		WHERE id = $5
+		SET name = $1, type = $2, config = $3, enabled = $4, system_alerts = $5, updated_at = NOW()
+		WHERE id = $6
 		RETURNING updated_at`
-	return s.db.QueryRowContext(ctx, query, nc.Name, nc.Type, nc.Config, nc.Enabled, nc.ID).
+	return s.db.QueryRowContext(ctx, query, nc.Name, nc.Type, config, nc.Enabled, nc.SystemAlerts, nc.ID).
 		Scan(&nc.UpdatedAt)
 }
 
@@ -468,15 +643,17 @@ func (s *PostgresStore) DeleteNotificationChannel(ctx context.Context, id int64)
 // Property Notifications
 func (s *PostgresStore) CreatePropertyNotification(ctx context.Context, pn *models.PropertyNotification) error {
 	query := `
-		INSERT INTO property_notifications (property_id, notification_channel_id, enabled, notify_on_red, notify_on_recovery)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO property_notifications (property_id, notification_channel_id, enabled, notify_on_red, notify_on_recovery,
+			active_from, active_to, timezone, bypass_critical)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id`
 	return s.db.QueryRowContext(ctx, query, pn.PropertyID, pn.NotificationChannelID, pn.Enabled,
-		pn.NotifyOnRed, pn.NotifyOnRecovery).Scan(&pn.ID)
+		pn.NotifyOnRed, pn.NotifyOnRecovery, pn.ActiveFrom, pn.ActiveTo, pn.Timezone, pn.BypassCritical).Scan(&pn.ID)
 }
 
 func (s *PostgresStore) ListPropertyNotifications(ctx context.Context, propertyID int64) ([]models.PropertyNotification, error) {
-	query := `SELECT id, property_id, notification_channel_id, enabled, notify_on_red, notify_on_recovery
+	query := `SELECT id, property_id, notification_channel_id, enabled, notify_on_red, notify_on_recovery,
+		active_from, active_to, timezone, bypass_critical
 		FROM property_notifications WHERE property_id = $1`
 	rows, err := s.db.QueryContext(ctx, query, propertyID)
 	if err != nil {
@@ -488,7 +665,8 @@ func (s *PostgresStore) ListPropertyNotifications(ctx context.Context, propertyI
 	for rows.Next() {
 		var pn models.PropertyNotification
 		if err := rows.Scan(&pn.ID, &pn.PropertyID, &pn.NotificationChannelID, &pn.Enabled,
-			&pn.NotifyOnRed, &pn.NotifyOnRecovery); err != nil {
+			&pn.NotifyOnRed, &pn.NotifyOnRecovery, &pn.ActiveFrom, &pn.ActiveTo, &pn.Timezone,
+			&pn.BypassCritical); err != nil {
 			return nil, err
 		}
 		notifications = append(notifications, pn)
@@ -499,9 +677,11 @@ func (s *PostgresStore) ListPropertyNotifications(ctx context.Context, propertyI
 func (s *PostgresStore) UpdatePropertyNotification(ctx context.Context, pn *models.PropertyNotification) error {
 	query := `
 		UPDATE property_notifications
-		SET enabled = $1, notify_on_red = $2, notify_on_recovery = $3
-		WHERE id = $4`
-	_, err := s.db.ExecContext(ctx, query, pn.Enabled, pn.NotifyOnRed, pn.NotifyOnRecovery, pn.ID)
+		SET enabled = $1, notify_on_red = $2, notify_on_recovery = $3,
+			active_from = $4, active_to = $5, timezone = $6, bypass_critical = $7
+		WHERE id = $8`
+	_, err := s.db.ExecContext(ctx, query, pn.Enabled, pn.NotifyOnRed, pn.NotifyOnRecovery,
+		pn.ActiveFrom, pn.ActiveTo, pn.Timezone, pn.BypassCritical, pn.ID)
 	return err
 }
 
@@ -530,10 +710,15 @@ func (s *PostgresStore) CreateNotificationEvent(ctx context.Context, ne *models.
 		ne.Message, ne.Success, ne.Error).Scan(&ne.ID, &ne.CreatedAt)
 }
 
+const notificationEventColumns = `id, property_id, notification_channel_id, event_type, message, success, error, created_at`
+
 func (s *PostgresStore) ListNotificationEvents(ctx context.Context, propertyID int64, limit int) ([]models.NotificationEvent, error) {
-	query := `SELECT id, property_id, notification_channel_id, event_type, message, success, error, created_at
-		FROM notification_events WHERE property_id = $1 ORDER BY created_at DESC LIMIT $2`
-	rows, err := s.db.QueryContext(ctx, query, propertyID, limit)
+	return s.queryNotificationEvents(ctx, `SELECT `+notificationEventColumns+`
+		FROM notification_events WHERE property_id = $1 ORDER BY created_at DESC LIMIT $2`, propertyID, limit)
+}
+
+func (s *PostgresStore) queryNotificationEvents(ctx context.Context, query string, args ...interface{}) ([]models.NotificationEvent, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -554,19 +739,20 @@ func (s *PostgresStore) ListNotificationEvents(ctx context.Context, propertyID i
 // Users
 func (s *PostgresStore) CreateUser(ctx context.Context, u *models.User) error {
 	query := `
-		INSERT INTO users (username, password, email, role, active)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO users (username, password, email, phone, role, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, updated_at`
-	return s.db.QueryRowContext(ctx, query, u.Username, u.Password, u.Email, u.Role, u.Active).
+	return s.db.QueryRowContext(ctx, query, u.Username, u.Password, u.Email, u.Phone, u.Role, u.Active).
 		Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
 }
 
 func (s *PostgresStore) GetUser(ctx context.Context, id int64) (*models.User, error) {
 	u := &models.User{}
-	query := `SELECT id, username, password, email, role, active, created_at, updated_at
+	query := `SELECT id, username, password, email, phone, role, active, totp_enabled, created_at, updated_at
 		FROM users WHERE id = $1`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&u.ID, &u.Username, &u.Password, &u.Email, &u.Role, &u.Active, &u.CreatedAt, &u.UpdatedAt)
+		&u.ID, &u.Username, &u.Password, &u.Email, &u.Phone, &u.Role, &u.Active, &u.TOTPEnabled,
+		&u.CreatedAt, &u.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -575,17 +761,18 @@ func (s *PostgresStore) GetUser(ctx context.Context, id int64) (*models.User, er
 
 func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	u := &models.User{}
-	query := `SELECT id, username, password, email, role, active, created_at, updated_at
+	query := `SELECT id, username, password, email, phone, role, active, totp_enabled, created_at, updated_at
 		FROM users WHERE username = $1`
 	err := s.db.QueryRowContext(ctx, query, username).Scan(
-		&u.ID, &u.Username, &u.Password, &u.Email, &u.Role, &u.Active, &u.CreatedAt, &u.UpdatedAt)
+		&u.ID, &u.Username, &u.Password, &u.Email, &u.Phone, &u.Role, &u.Active, &u.TOTPEnabled,
+		&u.CreatedAt, &u.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
 	return u, err
 }
 
-func (s *PostgresStore) CreateUserFromOAuth(ctx context.Context, email, name string) (*models.User, error) {
+func (s *PostgresStore) CreateUserFromOAuth(ctx context.Context, email, name, role string) (*models.User, error) {
 	// For OAuth users, we set a random password they can't use
 	// They can only login via OAuth
 	randomPassword := fmt.Sprintf("oauth_%d_%s", time.Now().UnixNano(), email)
@@ -598,7 +785,7 @@ func (s *PostgresStore) CreateUserFromOAuth(ctx context.Context, email, name str
 		Username: email,
 		Password: string(hashedPassword),
 		Email:    email,
-		Role:     "user",
+		Role:     role,
 		Active:   true,
 	}
 
@@ -611,8 +798,30 @@ func (s *PostgresStore) CreateUserFromOAuth(ctx context.Context, email, name str
 	return u, err
 }
 
+// GetUserBySSOIdentity returns the user a single sign-on identity is linked to
+func (s *PostgresStore) GetUserBySSOIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	u := &models.User{}
+	query := `SELECT id, username, password, email, phone, role, active, totp_enabled, created_at, updated_at
+		FROM users WHERE id = (SELECT user_id FROM sso_identities WHERE issuer = $1 AND subject = $2)`
+	err := s.db.QueryRowContext(ctx, query, issuer, subject).Scan(
+		&u.ID, &u.Username, &u.Password, &u.Email, &u.Phone, &u.Role, &u.Active, &u.TOTPEnabled,
+		&u.CreatedAt, &u.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	return u, err
+}
+
+// LinkSSOIdentity links a single sign-on identity to a user, so later sign-ins with it
+// find the user whatever their username
+func (s *PostgresStore) LinkSSOIdentity(ctx context.Context, userID int64, issuer, subject string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO sso_identities (issuer, subject, user_id) VALUES ($1, $2, $3)`, issuer, subject, userID)
+	return err
+}
+
 func (s *PostgresStore) ListUsers(ctx context.Context) ([]models.User, error) {
-	query := `SELECT id, username, password, email, role, active, created_at, updated_at
+	query := `SELECT id, username, password, email, phone, role, active, totp_enabled, created_at, updated_at
 		FROM users ORDER BY username`
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
@@ -623,8 +832,8 @@ func (s *PostgresStore) ListUsers(ctx context.Context) ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Username, &u.Password, &u.Email, &u.Role, &u.Active,
-			&u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Username, &u.Password, &u.Email, &u.Phone, &u.Role, &u.Active,
+			&u.TOTPEnabled, &u.CreatedAt, &u.UpdatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
@@ -635,10 +844,10 @@ func (s *PostgresStore) ListUsers(ctx context.Context) ([]models.User, error) {
 func (s *PostgresStore) UpdateUser(ctx context.Context, u *models.User) error {
 	query := `
 		UPDATE users
-		SET username = $1, email = $2, role = $3, active = $4, updated_at = NOW()
-		WHERE id = $5
+		SET username = $1, email = $2, phone = $3, role = $4, active = $5, updated_at = NOW()
+		WHERE id = $6
 		RETURNING updated_at`
-	return s.db.QueryRowContext(ctx, query, u.Username, u.Email, u.Role, u.Active, u.ID).
+	return s.db.QueryRowContext(ctx, query, u.Username, u.Email, u.Phone, u.Role, u.Active, u.ID).
 		Scan(&u.UpdatedAt)
 }
 
@@ -664,39 +873,146 @@ func (s *PostgresStore) DeleteUser(ctx context.Context, id int64) error {
 }
 
 // Settings
+
+// settingsID is the id of the one settings row
+const settingsID = 1
+
+// defaultSettings are the settings before any have been saved
+func defaultSettings() *models.Settings {
+	return &models.Settings{
+		ID:                   settingsID,
+		MaxConcurrentPings:   150,
+		DefaultCheckInterval: 60,
+		DefaultRetries:       3,
+		DefaultTimeout:       10000,
+		HistoryRetentionDays: 90,
+		NotificationCooldown: 300,
+		MaxAttachmentSizeMB:  500,
+		OAuthAllowedDomains:  []string{"etsusa.com"},
+		OAuthDefaultRole:     "user",
+		OIDC: &models.OIDCSettings{
+			Scopes:      []string{"openid", "email", "profile"},
+			RoleMapping: map[string]string{},
+		},
+		FirmwareMinimums: map[string]string{},
+		SubnetBase:       DefaultSubnetBase,
+	}
+}
+
+// GetSettings returns the settings row, or the defaults when none has been saved
 func (s *PostgresStore) GetSettings(ctx context.Context) (*models.Settings, error) {
 	settings := &models.Settings{}
+	oidc := &models.OIDCSettings{}
+	var roleMapping, firmwareMinimums string
 	query := `SELECT id, max_concurrent_pings, default_check_interval, default_retries,
-		default_timeout, history_retention_days, notification_cooldown
-		FROM settings LIMIT 1`
-	err := s.db.QueryRowContext(ctx, query).Scan(
+		default_timeout, history_retention_days, notification_cooldown, max_attachment_size_mb,
+		oauth_allowed_domains, oauth_default_role,
+		oidc_enabled, oidc_name, oidc_issuer, oidc_client_id, oidc_client_secret,
+		oidc_scopes, oidc_role_claim, oidc_role_mapping, firmware_minimums, subnet_base
+		FROM settings WHERE id = $1`
+	err := s.db.QueryRowContext(ctx, query, settingsID).Scan(
 		&settings.ID, &settings.MaxConcurrentPings, &settings.DefaultCheckInterval,
 		&settings.DefaultRetries, &settings.DefaultTimeout, &settings.HistoryRetentionDays,
-		&settings.NotificationCooldown)
+		&settings.NotificationCooldown, &settings.MaxAttachmentSizeMB,
+		scanArray(&settings.OAuthAllowedDomains), &settings.OAuthDefaultRole,
+		&oidc.Enabled, &oidc.Name, &oidc.Issuer, &oidc.ClientID, &oidc.ClientSecret,
+		scanArray(&oidc.Scopes), &oidc.RoleClaim, &roleMapping, &firmwareMinimums, &settings.SubnetBase)
 	if err == sql.ErrNoRows {
-		// Return defaults
-		return &models.Settings{
-			MaxConcurrentPings:   150,
-			DefaultCheckInterval: 60,
-			DefaultRetries:       3,
-			DefaultTimeout:       10000,
-			HistoryRetentionDays: 90,
-			NotificationCooldown: 300,
-		}, nil
+		return defaultSettings(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if settings.OAuthAllowedDomains == nil {
+		settings.OAuthAllowedDomains = []string{}
+	}
+	if err := s.openSecret(&oidc.ClientSecret); err != nil {
+		return nil, err
+	}
+	oidc.ClientSecretSet = oidc.ClientSecret != ""
+	if oidc.Scopes == nil {
+		oidc.Scopes = []string{}
+	}
+	if err := unmarshalConfig(roleMapping, &oidc.RoleMapping); err != nil {
+		return nil, fmt.Errorf("invalid OIDC role mapping: %w", err)
+	}
+	if oidc.RoleMapping == nil {
+		oidc.RoleMapping = map[string]string{}
 	}
-	return settings, err
+	settings.OIDC = oidc
+	if err := unmarshalConfig(firmwareMinimums, &settings.FirmwareMinimums); err != nil {
+		return nil, fmt.Errorf("invalid firmware minimums: %w", err)
+	}
+	if settings.FirmwareMinimums == nil {
+		settings.FirmwareMinimums = map[string]string{}
+	}
+	return settings, nil
 }
 
+// UpdateSettings saves the settings, creating the settings row if there is none. An
+// empty OIDC client secret keeps the stored one, and settings without OIDC leave it
+// unchanged.
 func (s *PostgresStore) UpdateSettings(ctx context.Context, settings *models.Settings) error {
+	settings.ID = settingsID
+	if settings.OAuthAllowedDomains == nil {
+		settings.OAuthAllowedDomains = []string{}
+	}
+	if settings.FirmwareMinimums == nil {
+		settings.FirmwareMinimums = map[string]string{}
+	}
+	firmwareMinimums, err := json.Marshal(settings.FirmwareMinimums)
+	if err != nil {
+		return err
+	}
 	query := `
-		UPDATE settings
-		SET max_concurrent_pings = $1, default_check_interval = $2, default_retries = $3,
-		    default_timeout = $4, history_retention_days = $5, notification_cooldown = $6
-		WHERE id = $7`
-	_, err := s.db.ExecContext(ctx, query, settings.MaxConcurrentPings, settings.DefaultCheckInterval,
+		INSERT INTO settings (id, max_concurrent_pings, default_check_interval, default_retries,
+		    default_timeout, history_retention_days, notification_cooldown, oauth_allowed_domains,
+		    oauth_default_role, max_attachment_size_mb, firmware_minimums, subnet_base)
+		VALUES ($12, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE
+		SET max_concurrent_pings = EXCLUDED.max_concurrent_pings,
+		    default_check_interval = EXCLUDED.default_check_interval,
+		    default_retries = EXCLUDED.default_retries,
+		    default_timeout = EXCLUDED.default_timeout,
+		    history_retention_days = EXCLUDED.history_retention_days,
+		    notification_cooldown = EXCLUDED.notification_cooldown,
+		    oauth_allowed_domains = EXCLUDED.oauth_allowed_domains,
+		    oauth_default_role = EXCLUDED.oauth_default_role,
+		    max_attachment_size_mb = EXCLUDED.max_attachment_size_mb,
+		    firmware_minimums = EXCLUDED.firmware_minimums,
+		    subnet_base = EXCLUDED.subnet_base`
+	_, err = s.db.ExecContext(ctx, query, settings.MaxConcurrentPings, settings.DefaultCheckInterval,
 		settings.DefaultRetries, settings.DefaultTimeout, settings.HistoryRetentionDays,
-		settings.NotificationCooldown, settings.ID)
-	return err
+		settings.NotificationCooldown, array(settings.OAuthAllowedDomains), settings.OAuthDefaultRole,
+		settings.MaxAttachmentSizeMB, string(firmwareMinimums), settings.SubnetBase, settings.ID)
+	if err != nil || settings.OIDC == nil {
+		return err
+	}
+
+	oidc := settings.OIDC
+	if oidc.Scopes == nil {
+		oidc.Scopes = []string{}
+	}
+	if oidc.RoleMapping == nil {
+		oidc.RoleMapping = map[string]string{}
+	}
+	roleMapping, err := json.Marshal(oidc.RoleMapping)
+	if err != nil {
+		return err
+	}
+	secret, err := s.sealSecret(oidc.ClientSecret)
+	if err != nil {
+		return err
+	}
+	query = `
+		UPDATE settings
+		SET oidc_enabled = $1, oidc_name = $2, oidc_issuer = $3, oidc_client_id = $4,
+		    oidc_client_secret = COALESCE(NULLIF($5, ''), oidc_client_secret),
+		    oidc_scopes = $6, oidc_role_claim = $7, oidc_role_mapping = $8
+		WHERE id = $9
+		RETURNING oidc_client_secret <> ''`
+	return s.db.QueryRowContext(ctx, query, oidc.Enabled, oidc.Name, oidc.Issuer, oidc.ClientID, secret,
+		array(oidc.Scopes), oidc.RoleClaim, string(roleMapping), settings.ID).Scan(&oidc.ClientSecretSet)
 }
 
 // Helper to unmarshal JSON config
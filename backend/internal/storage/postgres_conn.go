@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Connection pool defaults, used for options left unset
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 5 * time.Minute
+)
+
+// SSLModes are the sslmode values Postgres clients accept
+var SSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// PostgresOptions tune the connection pool and TLS. Pool settings left at 0, or nil,
+// take the defaults above; TLS settings left empty keep whatever the connection URL
+// sets.
+type PostgresOptions struct {
+	// QueryTimeout is set as each connection's statement_timeout; 0 for no limit
+	QueryTimeout time.Duration
+	MaxOpenConns int
+	// MaxIdleConns may be 0 to close every connection once it is returned; more than
+	// MaxOpenConns is lowered to it
+	MaxIdleConns    *int
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle for longer; 0 keeps them
+	ConnMaxIdleTime time.Duration
+
+	SSLMode     string
+	SSLRootCert string // CA certificate file the server's certificate is verified against
+	SSLCert     string // client certificate file
+	SSLKey      string // client key file
+}
+
+// connConfig parses a connection URL or keyword/value string with the TLS options
+// applied over it
+func (o PostgresOptions) connConfig(connStr string) (*pgx.ConnConfig, error) {
+	tls := [][2]string{
+		{"sslmode", o.SSLMode},
+		{"sslrootcert", o.SSLRootCert},
+		{"sslcert", o.SSLCert},
+		{"sslkey", o.SSLKey},
+	}
+	if strings.HasPrefix(connStr, "postgres://") || strings.HasPrefix(connStr, "postgresql://") {
+		u, err := url.Parse(connStr)
+		if err != nil {
+			// The error would echo the URL, password and all
+			return nil, fmt.Errorf("invalid connection URL")
+		}
+		query := u.Query()
+		for _, param := range tls {
+			if param[1] != "" {
+				query.Set(param[0], param[1])
+			}
+		}
+		u.RawQuery = query.Encode()
+		connStr = u.String()
+	} else {
+		// Later keywords win, so these override any already in the string
+		for _, param := range tls {
+			if param[1] != "" {
+				connStr += fmt.Sprintf(" %s='%s'", param[0], strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(param[1]))
+			}
+		}
+	}
+
+	config, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	if o.QueryTimeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(o.QueryTimeout.Milliseconds(), 10)
+	}
+	return config, nil
+}
+
+func (o PostgresOptions) maxOpenConns() int {
+	if o.MaxOpenConns > 0 {
+		return o.MaxOpenConns
+	}
+	return DefaultMaxOpenConns
+}
+
+func (o PostgresOptions) maxIdleConns() int {
+	idle := DefaultMaxIdleConns
+	if o.MaxIdleConns != nil {
+		idle = max(*o.MaxIdleConns, 0)
+	}
+	return min(idle, o.maxOpenConns())
+}
+
+func (o PostgresOptions) connMaxLifetime() time.Duration {
+	if o.ConnMaxLifetime > 0 {
+		return o.ConnMaxLifetime
+	}
+	return DefaultConnMaxLifetime
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Probes
+const probeColumns = `id, property_id, name, last_seen_at, created_at`
+
+func scanProbe(row rowScanner, p *models.Probe) error {
+	return row.Scan(&p.ID, &p.PropertyID, &p.Name, &p.LastSeenAt, &p.CreatedAt)
+}
+
+// CreateProbe stores a probe with the SHA-256 hash of its token; the token itself is
+// never stored
+func (s *PostgresStore) CreateProbe(ctx context.Context, p *models.Probe, tokenHash string) error {
+	query := `
+		INSERT INTO probes (property_id, name, token_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, p.PropertyID, p.Name, tokenHash).Scan(&p.ID, &p.CreatedAt)
+}
+
+func (s *PostgresStore) GetProbe(ctx context.Context, id int64) (*models.Probe, error) {
+	p := &models.Probe{}
+	query := `SELECT ` + probeColumns + ` FROM probes WHERE id = $1`
+	err := scanProbe(s.db.QueryRowContext(ctx, query, id), p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("probe not found")
+	}
+	return p, err
+}
+
+func (s *PostgresStore) GetProbeByTokenHash(ctx context.Context, tokenHash string) (*models.Probe, error) {
+	p := &models.Probe{}
+	query := `SELECT ` + probeColumns + ` FROM probes WHERE token_hash = $1`
+	err := scanProbe(s.db.QueryRowContext(ctx, query, tokenHash), p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("probe not found")
+	}
+	return p, err
+}
+
+func (s *PostgresStore) ListProbes(ctx context.Context) ([]models.Probe, error) {
+	query := `SELECT ` + probeColumns + ` FROM probes ORDER BY property_id, name`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	probes := make([]models.Probe, 0)
+	for rows.Next() {
+		var p models.Probe
+		if err := scanProbe(rows, &p); err != nil {
+			return nil, err
+		}
+		probes = append(probes, p)
+	}
+	return probes, rows.Err()
+}
+
+// TouchProbe records that a probe has just reported
+func (s *PostgresStore) TouchProbe(ctx context.Context, id int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE probes SET last_seen_at = $1 WHERE id = $2", at, id)
+	return err
+}
+
+func (s *PostgresStore) DeleteProbe(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM probes WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("probe not found")
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/etswifi/ets-noc/internal/models"
@@ -21,6 +23,7 @@ func NewRedisStore(addr string, password string, db int) (*RedisStore, error) {
 		Password: password,
 		DB:       db,
 	})
+	client.AddHook(countingHook{})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -32,6 +35,11 @@ func NewRedisStore(addr string, password string, db int) (*RedisStore, error) {
 	return &RedisStore{client: client}, nil
 }
 
+// Ping checks Redis can be reached
+func (r *RedisStore) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (r *RedisStore) Close() error {
 	return r.client.Close()
 }
@@ -41,14 +49,16 @@ func deviceStatusKey(deviceID int64) string {
 	return fmt.Sprintf("device:status:%d", deviceID)
 }
 
-func deviceHistoryKey(deviceID int64) string {
-	return fmt.Sprintf("device:history:%d", deviceID)
-}
-
 func allDeviceStatusKey() string {
 	return "all_device_status"
 }
 
+// allDeviceStatusExpiryKey scores each member of the all-devices hash with when its own
+// status key expires, since hash members can't expire themselves
+func allDeviceStatusExpiryKey() string {
+	return "all_device_status:expires"
+}
+
 // Property Status Keys
 func propertyStatusKey(propertyID int64) string {
 	return fmt.Sprintf("property:status:%d", propertyID)
@@ -58,12 +68,98 @@ func allPropertyStatusKey() string {
 	return "all_property_status"
 }
 
+func allPropertyStatusExpiryKey() string {
+	return "all_property_status:expires"
+}
+
 func propertyLastNotificationKey(propertyID int64) string {
 	return fmt.Sprintf("property:last_notification:%d", propertyID)
 }
 
+func deviceFlapKey(deviceID int64) string {
+	return fmt.Sprintf("device:flap:%d", deviceID)
+}
+
+func propertyIncidentKey(propertyID int64) string {
+	return fmt.Sprintf("property:incident:%d", propertyID)
+}
+
+func propertyIncidentChannelsKey(propertyID int64) string {
+	return fmt.Sprintf("property:incident:%d:channels", propertyID)
+}
+
+func propertyOnBatteryKey(propertyID int64) string {
+	return fmt.Sprintf("property:on_battery:%d", propertyID)
+}
+
+func probeResultsKey(shard int) string {
+	return fmt.Sprintf("probe:results:%d", shard)
+}
+
+func propertyWiFiKey(propertyID int64) string {
+	return fmt.Sprintf("property:wifi:%d", propertyID)
+}
+
+func deviceAuditKey() string {
+	return "audit:devices"
+}
+
+func checkQueueStream() string {
+	return "check:queue"
+}
+
+func checkQueueGroup() string {
+	return "executors"
+}
+
+func checkResultsKey(shard int) string {
+	return fmt.Sprintf("check:results:%d", shard)
+}
+
+func workerMembersKey() string {
+	return "worker:members"
+}
+
+func workerHeartbeatsKey() string {
+	return "worker:heartbeats"
+}
+
+func workerAlertStateKey() string {
+	return "worker:alert_state"
+}
+
+func workerLeaseKey(name string) string {
+	return "worker:lease:" + name
+}
+
+func notificationRetryKey() string {
+	return "notification:retry"
+}
+
+func rateLimitKey(key string) string {
+	return "ratelimit:" + key
+}
+
+func responseCacheKey(key string) string {
+	return "cache:" + key
+}
+
+func notificationRateKey(channelID int64, window time.Duration) string {
+	bucket := time.Now().Unix() / int64(window/time.Second)
+	return fmt.Sprintf("notification:rate:%d:%d", channelID, bucket)
+}
+
+// Pub/Sub Channels
+func propertyStatusChannel() string {
+	return "property_status_updates"
+}
+
+func deviceStatusChannel() string {
+	return "device_status_updates"
+}
+
 // Device Status Operations
-func (r *RedisStore) SetDeviceStatus(ctx context.Context, status *models.DeviceStatus) error {
+func (r *RedisStore) SetDeviceStatus(ctx context.Context, status *models.DeviceStatus, ttl time.Duration) error {
 	data, err := json.Marshal(status)
 	if err != nil {
 		return err
@@ -72,15 +168,71 @@ func (r *RedisStore) SetDeviceStatus(ctx context.Context, status *models.DeviceS
 	pipe := r.client.Pipeline()
 
 	// Store individual device status
-	pipe.Set(ctx, deviceStatusKey(status.DeviceID), data, 10*time.Minute)
+	pipe.Set(ctx, deviceStatusKey(status.DeviceID), data, ttl)
 
 	// Add to all devices hash for quick lookup
-	pipe.HSet(ctx, allDeviceStatusKey(), strconv.FormatInt(status.DeviceID, 10), data)
+	member := strconv.FormatInt(status.DeviceID, 10)
+	pipe.HSet(ctx, allDeviceStatusKey(), member, data)
+	trackStatusExpiry(ctx, pipe, allDeviceStatusExpiryKey(), member, ttl)
 
 	_, err = pipe.Exec(ctx)
 	return err
 }
 
+// trackStatusExpiry records when an aggregate hash member's own status key expires. A
+// status stored without a TTL is untracked and never expires from the hash.
+func trackStatusExpiry(ctx context.Context, pipe redis.Pipeliner, key, member string, ttl time.Duration) {
+	if ttl <= 0 {
+		pipe.ZRem(ctx, key, member)
+		return
+	}
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().Add(ttl).Unix()), Member: member})
+}
+
+// expiredStatusRange selects the members of an expiry set whose status has expired
+func expiredStatusRange() *redis.ZRangeBy {
+	return &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(time.Now().Unix(), 10)}
+}
+
+// DeviceStatusWrite is a device status to store, with the change event to publish
+// when the device changed status
+type DeviceStatusWrite struct {
+	Status *models.DeviceStatus
+	TTL    time.Duration
+	Change *models.DeviceStatusChange
+}
+
+// WriteDeviceStatuses stores a batch of device statuses and publishes their change
+// events in a single pipeline, in the order given
+func (r *RedisStore) WriteDeviceStatuses(ctx context.Context, writes []DeviceStatusWrite) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, w := range writes {
+		data, err := json.Marshal(w.Status)
+		if err != nil {
+			return err
+		}
+		member := strconv.FormatInt(w.Status.DeviceID, 10)
+		pipe.Set(ctx, deviceStatusKey(w.Status.DeviceID), data, w.TTL)
+		pipe.HSet(ctx, allDeviceStatusKey(), member, data)
+		trackStatusExpiry(ctx, pipe, allDeviceStatusExpiryKey(), member, w.TTL)
+
+		if w.Change != nil {
+			change, err := json.Marshal(w.Change)
+			if err != nil {
+				return err
+			}
+			pipe.Publish(ctx, deviceStatusChannel(), change)
+		}
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
 func (r *RedisStore) GetDeviceStatus(ctx context.Context, deviceID int64) (*models.DeviceStatus, error) {
 	data, err := r.client.Get(ctx, deviceStatusKey(deviceID)).Result()
 	if err == redis.Nil {
@@ -97,106 +249,79 @@ func (r *RedisStore) GetDeviceStatus(ctx context.Context, deviceID int64) (*mode
 	return &status, nil
 }
 
-func (r *RedisStore) GetAllDeviceStatuses(ctx context.Context) (map[int64]*models.DeviceStatus, error) {
-	data, err := r.client.HGetAll(ctx, allDeviceStatusKey()).Result()
+// GetDeviceStatuses returns the stored statuses of the given devices in one round
+// trip; devices without a status are left out
+func (r *RedisStore) GetDeviceStatuses(ctx context.Context, deviceIDs []int64) (map[int64]*models.DeviceStatus, error) {
+	statuses := make(map[int64]*models.DeviceStatus, len(deviceIDs))
+	if len(deviceIDs) == 0 {
+		return statuses, nil
+	}
+
+	keys := make([]string, len(deviceIDs))
+	for i, id := range deviceIDs {
+		keys[i] = deviceStatusKey(id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
 	if err != nil {
 		return nil, err
 	}
 
-	statuses := make(map[int64]*models.DeviceStatus)
-	for deviceIDStr, statusJSON := range data {
-		deviceID, err := strconv.ParseInt(deviceIDStr, 10, 64)
-		if err != nil {
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
 			continue
 		}
-
 		var status models.DeviceStatus
-		if err := json.Unmarshal([]byte(statusJSON), &status); err != nil {
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
 			continue
 		}
-		statuses[deviceID] = &status
+		statuses[deviceIDs[i]] = &status
 	}
 	return statuses, nil
 }
 
-// Device History Operations
-func (r *RedisStore) AddDeviceHistory(ctx context.Context, deviceID int64, status string, responseTime float64, message string) error {
-	timestamp := time.Now().Unix()
-	history := models.DeviceHistory{
-		Timestamp:    timestamp,
-		Status:       status,
-		ResponseTime: responseTime,
-		Message:      message,
-	}
-
-	data, err := json.Marshal(history)
-	if err != nil {
-		return err
-	}
-
-	// Use sorted set with timestamp as score for time-series data
-	err = r.client.ZAdd(ctx, deviceHistoryKey(deviceID), redis.Z{
-		Score:  float64(timestamp),
-		Member: data,
-	}).Err()
-	if err != nil {
-		return err
-	}
-
-	// Keep only last 90 days
-	ninetyDaysAgo := time.Now().AddDate(0, 0, -90).Unix()
-	return r.client.ZRemRangeByScore(ctx, deviceHistoryKey(deviceID), "0", strconv.FormatInt(ninetyDaysAgo, 10)).Err()
-}
-
-func (r *RedisStore) GetDeviceHistory(ctx context.Context, deviceID int64, startTime, endTime time.Time) ([]models.DeviceHistory, error) {
-	data, err := r.client.ZRangeByScore(ctx, deviceHistoryKey(deviceID), &redis.ZRangeBy{
-		Min: strconv.FormatInt(startTime.Unix(), 10),
-		Max: strconv.FormatInt(endTime.Unix(), 10),
-	}).Result()
+// GetAllDeviceStatuses returns every device status in the all-devices hash, leaving out
+// those whose own key has expired but haven't been pruned yet
+func (r *RedisStore) GetAllDeviceStatuses(ctx context.Context) (map[int64]*models.DeviceStatus, error) {
+	data, expired, err := r.getAggregate(ctx, allDeviceStatusKey(), allDeviceStatusExpiryKey())
 	if err != nil {
 		return nil, err
 	}
 
-	var history []models.DeviceHistory
-	for _, item := range data {
-		var h models.DeviceHistory
-		if err := json.Unmarshal([]byte(item), &h); err != nil {
+	statuses := make(map[int64]*models.DeviceStatus)
+	for deviceIDStr, statusJSON := range data {
+		if expired[deviceIDStr] {
+			continue
+		}
+		deviceID, err := strconv.ParseInt(deviceIDStr, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var status models.DeviceStatus
+		if err := json.Unmarshal([]byte(statusJSON), &status); err != nil {
 			continue
 		}
-		history = append(history, h)
+		statuses[deviceID] = &status
 	}
-	return history, nil
+	return statuses, nil
 }
 
-func (r *RedisStore) GetDeviceErrors(ctx context.Context, deviceID int64, limit int) ([]models.DeviceHistory, error) {
-	// Get recent history (last 7 days to ensure we have enough errors)
-	endTime := time.Now()
-	startTime := endTime.AddDate(0, 0, -7)
-
-	data, err := r.client.ZRevRangeByScore(ctx, deviceHistoryKey(deviceID), &redis.ZRangeBy{
-		Min:   strconv.FormatInt(startTime.Unix(), 10),
-		Max:   strconv.FormatInt(endTime.Unix(), 10),
-		Count: int64(limit * 10), // Get more than needed to filter offline only
-	}).Result()
-	if err != nil {
-		return nil, err
+// getAggregate reads an aggregate status hash with the set of its members that have
+// expired
+func (r *RedisStore) getAggregate(ctx context.Context, key, expiryKey string) (map[string]string, map[string]bool, error) {
+	pipe := r.client.Pipeline()
+	all := pipe.HGetAll(ctx, key)
+	expiredMembers := pipe.ZRangeByScore(ctx, expiryKey, expiredStatusRange())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, nil, err
 	}
 
-	var errors []models.DeviceHistory
-	for _, item := range data {
-		var h models.DeviceHistory
-		if err := json.Unmarshal([]byte(item), &h); err != nil {
-			continue
-		}
-		// Only include offline statuses
-		if h.Status == "offline" {
-			errors = append(errors, h)
-			if len(errors) >= limit {
-				break
-			}
-		}
+	expired := make(map[string]bool, len(expiredMembers.Val()))
+	for _, member := range expiredMembers.Val() {
+		expired[member] = true
 	}
-	return errors, nil
+	return all.Val(), expired, nil
 }
 
 // Property Status Operations
@@ -209,10 +334,12 @@ func (r *RedisStore) SetPropertyStatus(ctx context.Context, status *models.Prope
 	pipe := r.client.Pipeline()
 
 	// Store individual property status
-	pipe.Set(ctx, propertyStatusKey(status.PropertyID), data, 10*time.Minute)
+	pipe.Set(ctx, propertyStatusKey(status.PropertyID), data, propertyStatusTTL)
 
 	// Add to all properties hash for quick lookup
-	pipe.HSet(ctx, allPropertyStatusKey(), strconv.FormatInt(status.PropertyID, 10), data)
+	member := strconv.FormatInt(status.PropertyID, 10)
+	pipe.HSet(ctx, allPropertyStatusKey(), member, data)
+	trackStatusExpiry(ctx, pipe, allPropertyStatusExpiryKey(), member, propertyStatusTTL)
 
 	_, err = pipe.Exec(ctx)
 	return err
@@ -234,14 +361,19 @@ func (r *RedisStore) GetPropertyStatus(ctx context.Context, propertyID int64) (*
 	return &status, nil
 }
 
+// GetAllPropertyStatuses returns every property status in the all-properties hash,
+// leaving out those whose own key has expired but haven't been pruned yet
 func (r *RedisStore) GetAllPropertyStatuses(ctx context.Context) (map[int64]*models.PropertyStatus, error) {
-	data, err := r.client.HGetAll(ctx, allPropertyStatusKey()).Result()
+	data, expired, err := r.getAggregate(ctx, allPropertyStatusKey(), allPropertyStatusExpiryKey())
 	if err != nil {
 		return nil, err
 	}
 
 	statuses := make(map[int64]*models.PropertyStatus)
 	for propertyIDStr, statusJSON := range data {
+		if expired[propertyIDStr] {
+			continue
+		}
 		propertyID, err := strconv.ParseInt(propertyIDStr, 10, 64)
 		if err != nil {
 			continue
@@ -256,6 +388,258 @@ func (r *RedisStore) GetAllPropertyStatuses(ctx context.Context) (map[int64]*mod
 	return statuses, nil
 }
 
+// DeleteDeviceStatuses removes the statuses and flap history of deleted devices, from
+// their own keys and the all-devices hash, so they don't linger on dashboards
+func (r *RedisStore) DeleteDeviceStatuses(ctx context.Context, deviceIDs []int64) error {
+	if len(deviceIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, 2*len(deviceIDs))
+	fields := make([]string, len(deviceIDs))
+	for i, id := range deviceIDs {
+		keys = append(keys, deviceStatusKey(id), deviceFlapKey(id))
+		fields[i] = strconv.FormatInt(id, 10)
+	}
+
+	members := make([]interface{}, len(fields))
+	for i, field := range fields {
+		members[i] = field
+	}
+
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, keys...)
+	pipe.HDel(ctx, allDeviceStatusKey(), fields...)
+	pipe.ZRem(ctx, allDeviceStatusExpiryKey(), members...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeletePropertyState removes everything kept for a deleted property: its status,
+// notification cooldowns, open incident, UPS power state and WiFi snapshot
+func (r *RedisStore) DeletePropertyState(ctx context.Context, propertyID int64) error {
+	pipe := r.client.Pipeline()
+	pipe.Del(ctx, propertyStatusKey(propertyID), propertyLastNotificationKey(propertyID),
+		propertyIncidentKey(propertyID), propertyIncidentChannelsKey(propertyID),
+		propertyOnBatteryKey(propertyID), propertyWiFiKey(propertyID))
+	member := strconv.FormatInt(propertyID, 10)
+	pipe.HDel(ctx, allPropertyStatusKey(), member)
+	pipe.ZRem(ctx, allPropertyStatusExpiryKey(), member)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// pruneExpiredScript drops members of an aggregate status hash (KEYS[1]) whose expiry
+// in KEYS[2] is at or before ARGV[1]. Each is checked again here, so a status written
+// since the members were listed is kept.
+var pruneExpiredScript = redis.NewScript(`
+local removed = 0
+for i = 2, #ARGV do
+	local expires = tonumber(redis.call("ZSCORE", KEYS[2], ARGV[i]))
+	if expires ~= nil and expires <= tonumber(ARGV[1]) then
+		removed = removed + redis.call("HDEL", KEYS[1], ARGV[i])
+		redis.call("ZREM", KEYS[2], ARGV[i])
+	end
+end
+return removed`)
+
+// PruneStatuses reconciles the aggregate status hashes with the devices and properties
+// that should have a status: members whose own status has expired are dropped, and so
+// are members, with their statuses, whose device or property isn't among those given.
+// It returns how many device and property statuses it removed.
+func (r *RedisStore) PruneStatuses(ctx context.Context, deviceIDs, propertyIDs []int64) (int, int, error) {
+	devices, err := r.pruneAggregate(ctx, allDeviceStatusKey(), allDeviceStatusExpiryKey(), deviceIDs, deviceStatusKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	properties, err := r.pruneAggregate(ctx, allPropertyStatusKey(), allPropertyStatusExpiryKey(), propertyIDs, propertyStatusKey)
+	if err != nil {
+		return devices, 0, err
+	}
+	return devices, properties, nil
+}
+
+func (r *RedisStore) pruneAggregate(ctx context.Context, key, expiryKey string, keep []int64, statusKey func(int64) string) (int, error) {
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[strconv.FormatInt(id, 10)] = true
+	}
+
+	pipe := r.client.Pipeline()
+	fields := pipe.HKeys(ctx, key)
+	tracked := pipe.ZRange(ctx, expiryKey, 0, -1)
+	expired := pipe.ZRangeByScore(ctx, expiryKey, expiredStatusRange())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	// Members of deleted or inactive devices and properties go outright
+	unknown := make(map[string]bool)
+	for _, members := range [][]string{fields.Val(), tracked.Val()} {
+		for _, member := range members {
+			if !kept[member] {
+				unknown[member] = true
+			}
+		}
+	}
+	removed := 0
+	if len(unknown) > 0 {
+		members := make([]string, 0, len(unknown))
+		scored := make([]interface{}, 0, len(unknown))
+		keys := make([]string, 0, len(unknown))
+		for member := range unknown {
+			members = append(members, member)
+			scored = append(scored, member)
+			if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+				keys = append(keys, statusKey(id))
+			}
+		}
+		pipe := r.client.Pipeline()
+		deleted := pipe.HDel(ctx, key, members...)
+		pipe.ZRem(ctx, expiryKey, scored...)
+		if len(keys) > 0 {
+			pipe.Del(ctx, keys...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return 0, err
+		}
+		removed += int(deleted.Val())
+	}
+
+	args := []interface{}{time.Now().Unix()}
+	for _, member := range expired.Val() {
+		if !unknown[member] {
+			args = append(args, member)
+		}
+	}
+	if len(args) == 1 {
+		return removed, nil
+	}
+	n, err := pruneExpiredScript.Run(ctx, r.client, []string{key, expiryKey}, args...).Int()
+	if err != nil {
+		return removed, err
+	}
+	return removed + n, nil
+}
+
+// PublishPropertyStatus announces a property status change to live dashboard subscribers
+func (r *RedisStore) PublishPropertyStatus(ctx context.Context, status *models.PropertyStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, propertyStatusChannel(), data).Err()
+}
+
+// SubscribePropertyStatus streams published property status changes until ctx is
+// cancelled, at which point the returned channel is closed
+func (r *RedisStore) SubscribePropertyStatus(ctx context.Context) (<-chan *models.PropertyStatus, error) {
+	return subscribeJSON[models.PropertyStatus](ctx, r.client, propertyStatusChannel())
+}
+
+// PublishDeviceStatusChange announces a device status transition to live subscribers
+func (r *RedisStore) PublishDeviceStatusChange(ctx context.Context, change *models.DeviceStatusChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, deviceStatusChannel(), data).Err()
+}
+
+// SubscribeDeviceStatusChanges streams published device status transitions until ctx
+// is cancelled, at which point the returned channel is closed
+func (r *RedisStore) SubscribeDeviceStatusChanges(ctx context.Context) (<-chan *models.DeviceStatusChange, error) {
+	return subscribeJSON[models.DeviceStatusChange](ctx, r.client, deviceStatusChannel())
+}
+
+// subscribeJSON subscribes to a pub/sub channel and decodes each message as T.
+// Undecodable messages are dropped.
+func subscribeJSON[T any](ctx context.Context, client *redis.Client, channel string) (<-chan *T, error) {
+	pubsub := client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", channel, err)
+	}
+
+	updates := make(chan *T, 16)
+	go func() {
+		defer close(updates)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var v T
+				if err := json.Unmarshal([]byte(msg.Payload), &v); err != nil {
+					continue
+				}
+				select {
+				case updates <- &v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+// WiFi Snapshot Operations
+func (r *RedisStore) SetWiFiSnapshot(ctx context.Context, snapshot *models.WiFiSnapshot, ttl time.Duration) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, propertyWiFiKey(snapshot.PropertyID), data, ttl).Err()
+}
+
+func (r *RedisStore) GetWiFiSnapshot(ctx context.Context, propertyID int64) (*models.WiFiSnapshot, error) {
+	data, err := r.client.Get(ctx, propertyWiFiKey(propertyID)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("wifi snapshot not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot models.WiFiSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// SetDeviceAudit stores the latest device audit
+func (r *RedisStore) SetDeviceAudit(ctx context.Context, audit *models.DeviceAudit, ttl time.Duration) error {
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, deviceAuditKey(), data, ttl).Err()
+}
+
+func (r *RedisStore) GetDeviceAudit(ctx context.Context) (*models.DeviceAudit, error) {
+	data, err := r.client.Get(ctx, deviceAuditKey()).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("device audit not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var audit models.DeviceAudit
+	if err := json.Unmarshal([]byte(data), &audit); err != nil {
+		return nil, err
+	}
+	return &audit, nil
+}
+
 // Notification Cooldown Operations
 func (r *RedisStore) SetLastNotification(ctx context.Context, propertyID int64, eventType string) error {
 	key := propertyLastNotificationKey(propertyID)
@@ -294,20 +678,539 @@ func (r *RedisStore) ShouldNotify(ctx context.Context, propertyID int64, eventTy
 	return elapsed.Seconds() >= float64(cooldownSeconds), nil
 }
 
-// Cleanup Operations
-func (r *RedisStore) CleanupOldHistory(ctx context.Context, retentionDays int) error {
-	cutoff := time.Now().AddDate(0, 0, -retentionDays).Unix()
+// AllowNotification counts n messages against a channel's fixed-window rate limit and
+// reports whether they fit. Messages that don't fit are not counted.
+func (r *RedisStore) AllowNotification(ctx context.Context, channelID int64, n, limit int, window time.Duration) (bool, error) {
+	key := notificationRateKey(channelID, window)
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, int64(n))
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	if incr.Val() > int64(limit) {
+		return false, r.client.DecrBy(ctx, key, int64(n)).Err()
+	}
+	return true, nil
+}
 
-	// Get all device history keys
-	keys, err := r.client.Keys(ctx, "device:history:*").Result()
+// takeTokenScript refills a token bucket for the time since it was last used and takes
+// a token if there is one. It returns 1 or 0 and, when no token was left, the
+// milliseconds until there will be.
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(bucket[1]) or capacity
+local at = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - at) * capacity / per)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * per / capacity)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "at", now)
+redis.call("PEXPIRE", KEYS[1], per)
+return {allowed, wait}`)
+
+// TakeRateLimitToken takes a token from the bucket under key, which holds limit
+// tokens and refills at limit per period. It reports whether there was one and, if
+// not, how long until there will be.
+func (r *RedisStore) TakeRateLimitToken(ctx context.Context, key string, limit int, per time.Duration) (bool, time.Duration, error) {
+	result, err := takeTokenScript.Run(ctx, r.client, []string{rateLimitKey(key)}, limit, per.Milliseconds(), time.Now().UnixMilli()).Int64Slice()
 	if err != nil {
-		return err
+		return false, 0, err
 	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, nil
+}
+
+// Response Cache Operations
+
+// SetCachedResponse stores an assembled API response body for ttl, so requests for the
+// same thing from other API replicas can reuse it
+func (r *RedisStore) SetCachedResponse(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, responseCacheKey(key), data, ttl).Err()
+}
+
+// GetCachedResponse returns a cached response body, or nil when there isn't one
+func (r *RedisStore) GetCachedResponse(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, responseCacheKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Probe Result Operations
 
-	for _, key := range keys {
-		if err := r.client.ZRemRangeByScore(ctx, key, "0", strconv.FormatInt(cutoff, 10)).Err(); err != nil {
+// PushProbeResults queues results reported by a remote probe for the worker that owns
+// the probe's property
+func (r *RedisStore) PushProbeResults(ctx context.Context, propertyID int64, results []models.ProbeResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, len(results))
+	for i := range results {
+		data, err := json.Marshal(&results[i])
+		if err != nil {
 			return err
 		}
+		values = append(values, data)
+	}
+	return r.client.RPush(ctx, probeResultsKey(ShardForProperty(propertyID)), values...).Err()
+}
+
+// PopProbeResults removes and returns up to limit queued probe results for a shard,
+// oldest first
+func (r *RedisStore) PopProbeResults(ctx context.Context, shard int, limit int64) ([]models.ProbeResult, error) {
+	pipe := r.client.TxPipeline()
+	items := pipe.LRange(ctx, probeResultsKey(shard), 0, limit-1)
+	pipe.LTrim(ctx, probeResultsKey(shard), limit, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ProbeResult, 0, len(items.Val()))
+	for _, item := range items.Val() {
+		var result models.ProbeResult
+		if err := json.Unmarshal([]byte(item), &result); err != nil {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Check Queue Operations
+
+// EnsureCheckQueue creates the check stream and its executor consumer group
+func (r *RedisStore) EnsureCheckQueue(ctx context.Context) error {
+	err := r.client.XGroupCreateMkStream(ctx, checkQueueStream(), checkQueueGroup(), "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
 	}
 	return nil
 }
+
+// EnqueueChecks adds due device checks to the check stream
+func (r *RedisStore) EnqueueChecks(ctx context.Context, devices []models.Device, queuedAt time.Time) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for i := range devices {
+		data, err := json.Marshal(&models.QueuedCheck{Device: devices[i], QueuedAt: queuedAt})
+		if err != nil {
+			return err
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: checkQueueStream(),
+			Values: map[string]interface{}{"check": data},
+		})
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// CheckQueueLength returns the number of checks waiting or being run
+func (r *RedisStore) CheckQueueLength(ctx context.Context) (int64, error) {
+	return r.client.XLen(ctx, checkQueueStream()).Result()
+}
+
+// ReadChecks takes up to count new checks for an executor, waiting up to block for
+// one to arrive. Checks stay pending for the executor until acknowledged.
+func (r *RedisStore) ReadChecks(ctx context.Context, consumer string, count int64, block time.Duration) ([]models.QueuedCheck, error) {
+	streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    checkQueueGroup(),
+		Consumer: consumer,
+		Streams:  []string{checkQueueStream(), ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []models.QueuedCheck
+	for _, stream := range streams {
+		checks = append(checks, r.decodeChecks(ctx, stream.Messages)...)
+	}
+	return checks, nil
+}
+
+// ClaimStaleChecks takes over up to count checks left pending by an executor for
+// longer than minIdle, e.g. because it crashed mid-check
+func (r *RedisStore) ClaimStaleChecks(ctx context.Context, consumer string, minIdle time.Duration, count int64) ([]models.QueuedCheck, error) {
+	messages, _, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   checkQueueStream(),
+		Group:    checkQueueGroup(),
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return r.decodeChecks(ctx, messages), nil
+}
+
+// decodeChecks parses stream messages into checks; undecodable messages are dropped
+// from the stream
+func (r *RedisStore) decodeChecks(ctx context.Context, messages []redis.XMessage) []models.QueuedCheck {
+	checks := make([]models.QueuedCheck, 0, len(messages))
+	for _, message := range messages {
+		var check models.QueuedCheck
+		data, _ := message.Values["check"].(string)
+		if err := json.Unmarshal([]byte(data), &check); err != nil {
+			r.AckCheck(ctx, message.ID)
+			continue
+		}
+		check.ID = message.ID
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// AckCheck marks a check done and removes it from the stream
+func (r *RedisStore) AckCheck(ctx context.Context, id string) error {
+	pipe := r.client.TxPipeline()
+	pipe.XAck(ctx, checkQueueStream(), checkQueueGroup(), id)
+	pipe.XDel(ctx, checkQueueStream(), id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PushCheckResult queues an executor's check result for the worker that owns the
+// device's property
+func (r *RedisStore) PushCheckResult(ctx context.Context, propertyID int64, status *models.DeviceStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return r.client.RPush(ctx, checkResultsKey(ShardForProperty(propertyID)), data).Err()
+}
+
+// PopCheckResults removes and returns up to limit queued check results for a shard,
+// oldest first
+func (r *RedisStore) PopCheckResults(ctx context.Context, shard int, limit int64) ([]models.DeviceStatus, error) {
+	pipe := r.client.TxPipeline()
+	items := pipe.LRange(ctx, checkResultsKey(shard), 0, limit-1)
+	pipe.LTrim(ctx, checkResultsKey(shard), limit, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.DeviceStatus, 0, len(items.Val()))
+	for _, item := range items.Val() {
+		var status models.DeviceStatus
+		if err := json.Unmarshal([]byte(item), &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Worker Coordination Operations
+
+// WorkerShards is the number of partitions properties are spread across for workers.
+// Changing it reassigns properties, so every worker must use the same value.
+const WorkerShards = 64
+
+// ShardForProperty returns the partition a property's devices are checked by
+func ShardForProperty(propertyID int64) int {
+	return int(propertyID % WorkerShards)
+}
+
+// acquireLeaseScript takes a free lease or extends one the owner already holds
+var acquireLeaseScript = redis.NewScript(`
+local owner = redis.call("GET", KEYS[1])
+if owner == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0`)
+
+// releaseLeaseScript drops a lease only if the owner still holds it
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// AcquireLease takes or renews a named lease for owner and reports whether owner
+// holds it. A lease not renewed within ttl is free for any worker to take.
+func (r *RedisStore) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	held, err := acquireLeaseScript.Run(ctx, r.client, []string{workerLeaseKey(name)}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return held == 1, nil
+}
+
+// ReleaseLease gives up a lease held by owner so another worker can take it at once
+func (r *RedisStore) ReleaseLease(ctx context.Context, name, owner string) error {
+	return releaseLeaseScript.Run(ctx, r.client, []string{workerLeaseKey(name)}, owner).Err()
+}
+
+// HeartbeatWorker records that a worker is alive and returns the workers that have
+// sent a heartbeat within ttl, including this one. Workers that stopped are dropped.
+func (r *RedisStore) HeartbeatWorker(ctx context.Context, workerID string, ttl time.Duration) ([]string, error) {
+	now := time.Now()
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, workerMembersKey(), redis.Z{Score: float64(now.UnixMilli()), Member: workerID})
+	pipe.ZRemRangeByScore(ctx, workerMembersKey(), "-inf", fmt.Sprintf("(%d", now.Add(-ttl).UnixMilli()))
+	members := pipe.ZRange(ctx, workerMembersKey(), 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return members.Val(), nil
+}
+
+// RemoveWorker drops a worker from the live set when it shuts down
+func (r *RedisStore) RemoveWorker(ctx context.Context, workerID string) error {
+	return r.client.ZRem(ctx, workerMembersKey(), workerID).Err()
+}
+
+// Worker Heartbeat Operations
+
+// SetWorkerHeartbeat records a worker's latest heartbeat
+func (r *RedisStore) SetWorkerHeartbeat(ctx context.Context, hb *models.WorkerHeartbeat) error {
+	data, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, workerHeartbeatsKey(), hb.WorkerID, data).Err()
+}
+
+// RemoveWorkerHeartbeat drops a worker's heartbeat when it shuts down cleanly
+func (r *RedisStore) RemoveWorkerHeartbeat(ctx context.Context, workerID string) error {
+	return r.client.HDel(ctx, workerHeartbeatsKey(), workerID).Err()
+}
+
+// GetWorkerHeartbeats returns the heartbeats sent within maxAge, newest first, and
+// deletes older ones so workers that were scaled away eventually disappear
+func (r *RedisStore) GetWorkerHeartbeats(ctx context.Context, maxAge time.Duration) ([]models.WorkerHeartbeat, error) {
+	data, err := r.client.HGetAll(ctx, workerHeartbeatsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var heartbeats []models.WorkerHeartbeat
+	var expired []string
+	for workerID, value := range data {
+		var hb models.WorkerHeartbeat
+		if err := json.Unmarshal([]byte(value), &hb); err != nil || hb.LastSeen.Before(cutoff) {
+			expired = append(expired, workerID)
+			continue
+		}
+		heartbeats = append(heartbeats, hb)
+	}
+	if len(expired) > 0 {
+		if err := r.client.HDel(ctx, workerHeartbeatsKey(), expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(heartbeats, func(i, j int) bool {
+		return heartbeats[i].LastSeen.After(heartbeats[j].LastSeen)
+	})
+	return heartbeats, nil
+}
+
+// markWorkersDownScript moves the dead-man state from up to down, so only one API
+// replica sends the alert
+var markWorkersDownScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == "up" then
+	redis.call("SET", KEYS[1], "down")
+	return 1
+end
+return 0`)
+
+// MarkWorkersDown records that no worker is sending heartbeats and reports whether
+// they were up until now. A fleet that was never seen up stays in its initial state.
+func (r *RedisStore) MarkWorkersDown(ctx context.Context) (bool, error) {
+	changed, err := markWorkersDownScript.Run(ctx, r.client, []string{workerAlertStateKey()}).Int()
+	if err != nil {
+		return false, err
+	}
+	return changed == 1, nil
+}
+
+// MarkWorkersUp records that workers are sending heartbeats and reports whether they
+// were marked down before
+func (r *RedisStore) MarkWorkersUp(ctx context.Context) (bool, error) {
+	previous, err := r.client.GetSet(ctx, workerAlertStateKey(), "up").Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return previous == "down", nil
+}
+
+// Power State Operations
+
+// SetDeviceOnBattery records whether a UPS device is on battery and returns how many of
+// the property's UPS devices were on battery before and after. The set expires a day
+// after its last update so a deleted UPS can't hold a property on battery forever.
+func (r *RedisStore) SetDeviceOnBattery(ctx context.Context, propertyID, deviceID int64, onBattery bool) (int64, int64, error) {
+	key := propertyOnBatteryKey(propertyID)
+	member := strconv.FormatInt(deviceID, 10)
+
+	pipe := r.client.TxPipeline()
+	var changed *redis.IntCmd
+	if onBattery {
+		changed = pipe.SAdd(ctx, key, member)
+	} else {
+		changed = pipe.SRem(ctx, key, member)
+	}
+	count := pipe.SCard(ctx, key)
+	pipe.Expire(ctx, key, 24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	after := count.Val()
+	before := after - changed.Val()
+	if !onBattery {
+		before = after + changed.Val()
+	}
+	return before, after, nil
+}
+
+// Flap Detection Operations
+
+// RecordDeviceStateChange notes a confirmed state change for flap detection. Entries
+// older than the window are dropped and the count within the window is returned.
+func (r *RedisStore) RecordDeviceStateChange(ctx context.Context, deviceID int64, at time.Time, window time.Duration) (int64, error) {
+	key := deviceFlapKey(deviceID)
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.Unix()), Member: at.UnixNano()})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", at.Add(-window).Unix()))
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// CountDeviceStateChanges returns the number of state changes recorded since the given time
+func (r *RedisStore) CountDeviceStateChanges(ctx context.Context, deviceID int64, since time.Time) (int64, error) {
+	return r.client.ZCount(ctx, deviceFlapKey(deviceID), fmt.Sprintf("%d", since.Unix()), "+inf").Result()
+}
+
+// Notification Retry Operations
+
+// ScheduleNotificationRetry queues a failed delivery to be retried at the given time
+func (r *RedisStore) ScheduleNotificationRetry(ctx context.Context, payload string, at time.Time) error {
+	return r.client.ZAdd(ctx, notificationRetryKey(), redis.Z{Score: float64(at.Unix()), Member: payload}).Err()
+}
+
+// ClaimDueNotificationRetries removes and returns up to limit retries that are due.
+// Each entry is claimed with ZREM so concurrent workers never retry the same delivery.
+func (r *RedisStore) ClaimDueNotificationRetries(ctx context.Context, now time.Time, limit int64) ([]string, error) {
+	due, err := r.client.ZRangeByScore(ctx, notificationRetryKey(), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]string, 0, len(due))
+	for _, payload := range due {
+		removed, err := r.client.ZRem(ctx, notificationRetryKey(), payload).Result()
+		if err != nil {
+			return claimed, err
+		}
+		if removed == 1 {
+			claimed = append(claimed, payload)
+		}
+	}
+	return claimed, nil
+}
+
+// Incident Operations
+
+// SetPropertyIncident stores the key of the property's open incident so the recovery
+// notification can refer back to the incident it resolves
+func (r *RedisStore) SetPropertyIncident(ctx context.Context, propertyID int64, incidentKey string) error {
+	return r.client.Set(ctx, propertyIncidentKey(propertyID), incidentKey, 0).Err()
+}
+
+// GetPropertyIncident returns the open incident key for a property, or "" if none
+func (r *RedisStore) GetPropertyIncident(ctx context.Context, propertyID int64) (string, error) {
+	key, err := r.client.Get(ctx, propertyIncidentKey(propertyID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return key, err
+}
+
+// AddIncidentChannels records channels that were routed the property's down alert by a
+// rule and should receive the recovery
+func (r *RedisStore) AddIncidentChannels(ctx context.Context, propertyID int64, channelIDs []int64) error {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(channelIDs))
+	for i, id := range channelIDs {
+		members[i] = id
+	}
+	return r.client.SAdd(ctx, propertyIncidentChannelsKey(propertyID), members...).Err()
+}
+
+// GetIncidentChannels returns the channels recorded for the property's open incident
+func (r *RedisStore) GetIncidentChannels(ctx context.Context, propertyID int64) ([]int64, error) {
+	members, err := r.client.SMembers(ctx, propertyIncidentChannelsKey(propertyID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *RedisStore) ClearPropertyIncident(ctx context.Context, propertyID int64) error {
+	return r.client.Del(ctx, propertyIncidentKey(propertyID), propertyIncidentChannelsKey(propertyID)).Err()
+}
+
+// Cleanup Operations
+
+// CleanupOldHistory prunes the per-device history sorted sets written before history
+// moved to Postgres. Keys are walked with SCAN so a large keyspace doesn't block Redis.
+func (r *RedisStore) CleanupOldHistory(ctx context.Context, retentionDays int) error {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays).Unix()
+
+	iter := r.client.Scan(ctx, 0, "device:history:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.ZRemRangeByScore(ctx, iter.Val(), "0", strconv.FormatInt(cutoff, 10)).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
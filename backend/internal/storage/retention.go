@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// retentionBatchSize caps the rows removed per statement so pruning a large backlog
+// doesn't hold long locks on tables the worker writes to constantly
+const retentionBatchSize = 10000
+
+// deleteInBatches runs a DELETE ... LIMIT-style query, which must take the cutoff as
+// $1 and the batch size as $2, until it removes fewer rows than a full batch
+func (s *PostgresStore) deleteInBatches(ctx context.Context, query string, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		result, err := s.db.ExecContext(ctx, query, cutoff, retentionBatchSize)
+		if err != nil {
+			return total, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += rows
+		if rows < retentionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// DeleteDeviceHistoryBefore removes raw device history and hourly rollups older than
+// the cutoff. Daily rollups are kept for long-range uptime reports.
+func (s *PostgresStore) DeleteDeviceHistoryBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	raw, err := s.deleteInBatches(ctx, `
+		DELETE FROM device_history WHERE ctid IN (
+			SELECT ctid FROM device_history WHERE checked_at < $1 LIMIT $2)`, cutoff)
+	if err != nil {
+		return raw, err
+	}
+	hourly, err := s.deleteInBatches(ctx, `
+		DELETE FROM device_history_rollups WHERE ctid IN (
+			SELECT ctid FROM device_history_rollups
+			WHERE resolution = 'hour' AND bucket_start < $1 LIMIT $2)`, cutoff)
+	return raw + hourly, err
+}
+
+// DeleteNotificationEventsBefore removes notification events older than the cutoff
+func (s *PostgresStore) DeleteNotificationEventsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.deleteInBatches(ctx, `
+		DELETE FROM notification_events WHERE id IN (
+			SELECT id FROM notification_events WHERE created_at < $1 LIMIT $2)`, cutoff)
+}
+
+// DeleteSessionsBefore removes session records that expired before the cutoff
+func (s *PostgresStore) DeleteSessionsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.deleteInBatches(ctx, `
+		DELETE FROM user_sessions WHERE id IN (
+			SELECT id FROM user_sessions WHERE expires_at < $1 LIMIT $2)`, cutoff)
+}
+
+// DeleteWebhookDeliveriesBefore removes webhook delivery records older than the cutoff
+func (s *PostgresStore) DeleteWebhookDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.deleteInBatches(ctx, `
+		DELETE FROM webhook_deliveries WHERE id IN (
+			SELECT id FROM webhook_deliveries WHERE created_at < $1 LIMIT $2)`, cutoff)
+}
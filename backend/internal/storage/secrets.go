@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks a column value sealed with the secrets key. Values without it
+// are legacy plaintext and are read as-is until they are next written.
+const encryptedPrefix = "enc:v1:"
+
+// EnableSecretEncryption turns on AES-256-GCM encryption of secret columns (pfSense
+// passwords, notification channel configs, two-factor secrets, webhook signing secrets
+// and the OIDC client secret). The key is 32 bytes, base64 encoded. Without it secrets are stored in
+// plaintext and encrypted values can't be read.
+func (s *PostgresStore) EnableSecretEncryption(encodedKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil {
+		return fmt.Errorf("secrets key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("secrets key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	s.secrets = aead
+	return nil
+}
+
+// sealSecret encrypts a value for storage. Empty values and values already sealed with
+// the key are stored as-is; anything else that merely starts with the prefix is
+// encrypted like any other value.
+func (s *PostgresStore) sealSecret(value string) (string, error) {
+	if s.secrets == nil || value == "" {
+		return value, nil
+	}
+	if strings.HasPrefix(value, encryptedPrefix) {
+		if _, err := s.decryptSecret(value); err == nil {
+			return value, nil
+		}
+	}
+
+	nonce := make([]byte, s.secrets.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := s.secrets.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openSecret decrypts a stored value in place
+func (s *PostgresStore) openSecret(value *string) error {
+	if !strings.HasPrefix(*value, encryptedPrefix) {
+		return nil
+	}
+	if s.secrets == nil {
+		return fmt.Errorf("secret is encrypted but no SECRETS_KEY is configured")
+	}
+
+	plain, err := s.decryptSecret(*value)
+	if err != nil {
+		return err
+	}
+	*value = plain
+	return nil
+}
+
+// decryptSecret opens a value sealed with the secrets key, which must be configured
+func (s *PostgresStore) decryptSecret(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < s.secrets.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+	nonce, ciphertext := sealed[:s.secrets.NonceSize()], sealed[s.secrets.NonceSize():]
+	plain, err := s.secrets.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret (wrong SECRETS_KEY?)")
+	}
+	return string(plain), nil
+}
+
+// EncryptExistingSecrets seals secret columns still stored in plaintext. It is a no-op
+// without a secrets key and safe to run on every start.
+func (s *PostgresStore) EncryptExistingSecrets(ctx context.Context) (int, error) {
+	if s.secrets == nil {
+		return 0, nil
+	}
+
+	columns := []struct{ table, column string }{
+		{"properties", "pfsense_password"},
+		{"firewalls", "password"},
+		{"unifi_controllers", "password"},
+		{"notification_channels", "config"},
+		{"users", "totp_secret"},
+		{"webhooks", "secret"},
+		{"settings", "oidc_client_secret"},
+	}
+
+	total := 0
+	for _, col := range columns {
+		query := fmt.Sprintf(`SELECT id, %s FROM %s WHERE %s <> '' AND %s NOT LIKE '%s%%'`,
+			col.column, col.table, col.column, col.column, encryptedPrefix)
+		rows, err := s.db.QueryContext(ctx, query)
+		if err != nil {
+			return total, err
+		}
+
+		plain := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var value string
+			if err := rows.Scan(&id, &value); err != nil {
+				rows.Close()
+				return total, err
+			}
+			plain[id] = value
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+
+		update := fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE id = $2`, col.table, col.column)
+		for id, value := range plain {
+			sealed, err := s.sealSecret(value)
+			if err != nil {
+				return total, err
+			}
+			if _, err := s.db.ExecContext(ctx, update, sealed, id); err != nil {
+				return total, err
+			}
+			total++
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSealSecretEncryptsPrefixedPlaintext(t *testing.T) {
+	s := &PostgresStore{}
+	if err := s.EnableSecretEncryption(strings.Repeat("A", 43) + "="); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := s.sealSecret("router-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := s.sealSecret(sealed); err != nil || again != sealed {
+		t.Fatalf("resealing a sealed value gave %q, %v; want it unchanged", again, err)
+	}
+
+	// A value that only looks sealed is still encrypted, and reads back as written
+	forged := encryptedPrefix + "not-really-encrypted"
+	stored, err := s.sealSecret(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == forged {
+		t.Fatal("value with the encrypted prefix was stored in plaintext")
+	}
+	if err := s.openSecret(&stored); err != nil || stored != forged {
+		t.Fatalf("opened %q, %v; want %q", stored, err, forged)
+	}
+}
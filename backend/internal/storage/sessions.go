@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Sessions
+const sessionColumns = `id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner, session *models.UserSession) error {
+	return row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt)
+}
+
+func (s *PostgresStore) CreateSession(ctx context.Context, session *models.UserSession) error {
+	query := `
+		INSERT INTO user_sessions (user_id, user_agent, ip_address, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), NOW(), $4)
+		RETURNING id, created_at, last_seen_at`
+	return s.db.QueryRowContext(ctx, query, session.UserID, session.UserAgent, session.IPAddress, session.ExpiresAt).
+		Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+}
+
+func (s *PostgresStore) GetSession(ctx context.Context, id int64) (*models.UserSession, error) {
+	session := &models.UserSession{}
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE id = $1`
+	err := scanSession(s.db.QueryRowContext(ctx, query, id), session)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found")
+	}
+	return session, err
+}
+
+// ListActiveSessions returns a user's sessions that are neither revoked nor expired,
+// most recently used first
+func (s *PostgresStore) ListActiveSessions(ctx context.Context, userID int64) ([]models.UserSession, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC, id DESC`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.UserSession, 0)
+	for rows.Next() {
+		var session models.UserSession
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession records that a session was used at a time from an address
+func (s *PostgresStore) TouchSession(ctx context.Context, id int64, ipAddress string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE user_sessions SET last_seen_at = $1, ip_address = $2 WHERE id = $3`,
+		at, ipAddress, id)
+	return err
+}
+
+// RevokeSession signs a session out. It fails if the session doesn't exist or is
+// already revoked.
+func (s *PostgresStore) RevokeSession(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE user_sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions signs out every session of a user except keepID, which may be 0,
+// and returns how many were revoked
+func (s *PostgresStore) RevokeUserSessions(ctx context.Context, userID, keepID int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is go-sqlite3 with the Postgres functions the shared queries call
+const sqliteDriver = "sqlite3_ets"
+
+// sqliteTimeFormat is how go-sqlite3 writes time.Time arguments. Times are stored in
+// UTC so they compare correctly as text.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// sqliteTimeFormats are the forms timestamps are read back in, including column
+// defaults from CURRENT_TIMESTAMP
+var sqliteTimeFormats = []string{
+	sqliteTimeFormat,
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("now", sqliteNow, false); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("array_position", sqliteArrayPosition, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("array_to_string", sqliteArrayToString, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("date_trunc", sqliteDateTrunc, true)
+		},
+	})
+}
+
+// SQLiteStore keeps everything PostgresStore does in a single SQLite file, for lab and
+// demo deployments and for exercising handlers without a live Postgres. It runs
+// PostgresStore's queries with placeholders and casts rewritten for SQLite; the few
+// queries SQLite can't express are reimplemented below.
+type SQLiteStore struct {
+	*PostgresStore
+}
+
+// NewSQLiteStore opens or creates the database at path; Migrate creates the schema.
+// Needs a cgo build (CGO_ENABLED=1).
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open(sqliteDriver, "file:"+path+"?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	return &SQLiteStore{&PostgresStore{db: &database{DB: db, rebind: sqliteRebind}}}, nil
+}
+
+var (
+	sqlitePlaceholder = regexp.MustCompile(`\$(\d+)`)
+	sqliteCast        = regexp.MustCompile(`::[a-z]+( precision)?(\[\])?`)
+	sqliteCTID        = regexp.MustCompile(`\bctid\b`)
+
+	// sqliteQueries caches rewritten queries, which are all constants
+	sqliteQueries sync.Map
+)
+
+// sqliteRebind rewrites a Postgres query for SQLite: $N placeholders become ?N, casts
+// are dropped and ctid becomes rowid. Times are passed in UTC.
+func sqliteRebind(query string, args []any) (string, []any) {
+	rewritten, ok := sqliteQueries.Load(query)
+	if !ok {
+		q := sqlitePlaceholder.ReplaceAllString(query, "?$1")
+		q = sqliteCast.ReplaceAllString(q, "")
+		q = sqliteCTID.ReplaceAllString(q, "rowid")
+		rewritten, _ = sqliteQueries.LoadOrStore(query, q)
+	}
+
+	return rewritten.(string), utcArgs(args)
+}
+
+// utcArgs converts time arguments to UTC
+func utcArgs(args []any) []any {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				args[i] = v.UTC()
+			}
+		}
+	}
+	return args
+}
+
+func sqliteNow() string {
+	return time.Now().UTC().Format(sqliteTimeFormat)
+}
+
+// sqliteDateTrunc implements Postgres date_trunc for the units the queries use
+func sqliteDateTrunc(unit, value string) (string, error) {
+	var t time.Time
+	var err error
+	for _, format := range sqliteTimeFormats {
+		if t, err = time.ParseInLocation(format, value, time.UTC); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("date_trunc: invalid timestamp %q", value)
+	}
+
+	t = t.UTC()
+	switch unit {
+	case "minute":
+		t = t.Truncate(time.Minute)
+	case "hour":
+		t = t.Truncate(time.Hour)
+	case "day":
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		return "", fmt.Errorf("date_trunc: unsupported unit %q", unit)
+	}
+	return t.Format(sqliteTimeFormat), nil
+}
+
+// sqliteArrayPosition implements Postgres array_position for the text arrays, which
+// SQLite stores in Postgres array syntax
+func sqliteArrayPosition(array, value string) (interface{}, error) {
+	elements, err := parseArray(array)
+	if err != nil {
+		return nil, err
+	}
+	for i, element := range elements {
+		if element == value {
+			return int64(i + 1), nil
+		}
+	}
+	return nil, nil
+}
+
+// sqliteArrayToString implements Postgres array_to_string for the text arrays
+func sqliteArrayToString(array, separator string) (string, error) {
+	elements, err := parseArray(array)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(elements, separator), nil
+}
+
+// sqliteIDs encodes IDs as a JSON array for json_each, which stands in for = ANY()
+func sqliteIDs(ids []int64) string {
+	if ids == nil {
+		ids = []int64{}
+	}
+	b, _ := json.Marshal(ids)
+	return string(b)
+}
+
+// sqliteHistoryBatch keeps each history insert under SQLite's bound parameter limit
+const sqliteHistoryBatch = 1000
+
+// AddDeviceHistoryBatch stores the history of a whole check run in as few inserts as
+// SQLite's parameter limit allows
+func (s *SQLiteStore) AddDeviceHistoryBatch(ctx context.Context, statuses []*models.DeviceStatus) error {
+	for len(statuses) > 0 {
+		batch := statuses[:min(len(statuses), sqliteHistoryBatch)]
+		statuses = statuses[len(batch):]
+
+		// Built per batch size, so it bypasses the rebind cache
+		query := `INSERT INTO device_history (device_id, checked_at, status, state_type, response_time, message, maintenance) VALUES ` +
+			strings.Repeat("(?, ?, ?, ?, ?, ?, ?), ", len(batch)-1) + "(?, ?, ?, ?, ?, ?, ?)"
+		args := make([]any, 0, len(batch)*7)
+		for _, status := range batch {
+			args = append(args, status.DeviceID, status.LastCheck, status.Status, status.StateType,
+				status.ResponseTime, status.Message, status.Maintenance)
+		}
+		if _, err := s.db.conn().ExecContext(ctx, query, utcArgs(args)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InsertTrafficSamples stores one collection round for a property
+func (s *SQLiteStore) InsertTrafficSamples(ctx context.Context, propertyID int64, collectedAt time.Time, samples []models.TrafficSample) error {
+	query := `
+		INSERT INTO interface_traffic (property_id, interface, collected_at, rx_bytes, tx_bytes, rx_packets, tx_packets)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING`
+	for _, sample := range samples {
+		_, err := s.db.ExecContext(ctx, query, propertyID, sample.Interface, collectedAt,
+			sample.RxBytes, sample.TxBytes, sample.RxPackets, sample.TxPackets)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListActivity compares times as normalized text, since column defaults from
+// CURRENT_TIMESTAMP are stored in a different form than time arguments
+func (s *SQLiteStore) ListActivity(ctx context.Context, filter ActivityFilter) ([]models.ActivityEvent, error) {
+	return s.listActivity(ctx, filter, func(expr string) string {
+		return "strftime('%Y-%m-%d %H:%M:%f', " + expr + ")"
+	})
+}
+
+// GetPropertyOutageSummaries returns red-status outages per property between start and
+// end from status_events, for the given properties or all when propertyIDs is empty
+func (s *SQLiteStore) GetPropertyOutageSummaries(ctx context.Context, propertyIDs []int64, start, end time.Time) ([]models.PropertyOutageSummary, error) {
+	query := `
+		WITH ev AS (
+			SELECT property_id, to_status, occurred_at,
+				LEAD(occurred_at) OVER (PARTITION BY property_id ORDER BY occurred_at, id) AS next_at
+			FROM status_events
+			WHERE entity_type = 'property' AND occurred_at < $2
+				AND (json_array_length($3) = 0 OR property_id IN (SELECT value FROM json_each($3)))
+		)
+		SELECT ev.property_id, p.name,
+			COUNT(*) FILTER (WHERE ev.occurred_at >= $1),
+			COALESCE(SUM((julianday(min(COALESCE(ev.next_at, $2), $2)) - julianday(max(ev.occurred_at, $1))) * 86400), 0)
+		FROM ev
+		JOIN properties p ON p.id = ev.property_id
+		WHERE ev.to_status = 'red' AND COALESCE(ev.next_at, $2) > $1
+		GROUP BY ev.property_id, p.name
+		ORDER BY 4 DESC`
+	rows, err := s.db.QueryContext(ctx, query, start, end, sqliteIDs(propertyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.PropertyOutageSummary, 0)
+	for rows.Next() {
+		var o models.PropertyOutageSummary
+		var seconds float64
+		if err := rows.Scan(&o.PropertyID, &o.PropertyName, &o.OutageCount, &seconds); err != nil {
+			return nil, err
+		}
+		o.DowntimeMinutes = seconds / 60
+		summaries = append(summaries, o)
+	}
+	return summaries, rows.Err()
+}
+
+// GetWorstDevices returns the devices with the most offline time between start and
+// end, for the given properties or all when propertyIDs is empty
+func (s *SQLiteStore) GetWorstDevices(ctx context.Context, propertyIDs []int64, start, end time.Time, limit int) ([]models.DeviceOutageSummary, error) {
+	query := `
+		WITH ev AS (
+			SELECT device_id, to_status, occurred_at,
+				LEAD(occurred_at) OVER (PARTITION BY device_id ORDER BY occurred_at, id) AS next_at
+			FROM status_events
+			WHERE entity_type = 'device' AND occurred_at < $2
+				AND (json_array_length($3) = 0 OR property_id IN (SELECT value FROM json_each($3)))
+		)
+		SELECT ev.device_id, d.name, p.id, p.name,
+			COUNT(*) FILTER (WHERE ev.occurred_at >= $1),
+			COALESCE(SUM((julianday(min(COALESCE(ev.next_at, $2), $2)) - julianday(max(ev.occurred_at, $1))) * 86400), 0)
+		FROM ev
+		JOIN devices d ON d.id = ev.device_id
+		JOIN properties p ON p.id = d.property_id
+		WHERE ev.to_status = 'offline' AND COALESCE(ev.next_at, $2) > $1
+		GROUP BY ev.device_id, d.name, p.id, p.name
+		ORDER BY 6 DESC
+		LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, start, end, sqliteIDs(propertyIDs), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]models.DeviceOutageSummary, 0)
+	for rows.Next() {
+		var o models.DeviceOutageSummary
+		var seconds float64
+		if err := rows.Scan(&o.DeviceID, &o.DeviceName, &o.PropertyID, &o.PropertyName, &o.OutageCount, &seconds); err != nil {
+			return nil, err
+		}
+		o.DowntimeMinutes = seconds / 60
+		summaries = append(summaries, o)
+	}
+	return summaries, rows.Err()
+}
+
+// GetDeviceUptime computes availability for each device over [startTime, endTime)
+// from raw history, the same way as PostgresStore.GetDeviceUptime
+func (s *SQLiteStore) GetDeviceUptime(ctx context.Context, deviceIDs []int64, startTime, endTime time.Time) (map[int64]*models.UptimeReport, error) {
+	query := `
+		WITH checks AS (
+			SELECT device_id, checked_at,
+				(status <> 'online' AND state_type <> 'soft' AND NOT maintenance) AS down,
+				LEAD(checked_at) OVER (PARTITION BY device_id ORDER BY checked_at) AS next_at
+			FROM device_history
+			WHERE device_id IN (SELECT value FROM json_each($1)) AND checked_at >= $2 AND checked_at < $3
+		), spans AS (
+			SELECT device_id, down,
+				LAG(down) OVER (PARTITION BY device_id ORDER BY checked_at) AS prev_down,
+				(julianday(COALESCE(next_at, min($3, now()))) - julianday(checked_at)) * 86400 AS seconds
+			FROM checks
+		)
+		SELECT device_id,
+			COUNT(*),
+			COALESCE(SUM(seconds), 0),
+			COALESCE(SUM(seconds) FILTER (WHERE down), 0),
+			COUNT(*) FILTER (WHERE down AND NOT COALESCE(prev_down, false))
+		FROM spans
+		GROUP BY device_id`
+	rows, err := s.db.QueryContext(ctx, query, sqliteIDs(deviceIDs), startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make(map[int64]*models.UptimeReport)
+	for rows.Next() {
+		var deviceID int64
+		var monitoredSeconds, downSeconds float64
+		r := &models.UptimeReport{PeriodStart: startTime, PeriodEnd: endTime}
+		if err := rows.Scan(&deviceID, &r.Checks, &monitoredSeconds, &downSeconds, &r.OutageCount); err != nil {
+			return nil, err
+		}
+		r.MonitoredMinutes = monitoredSeconds / 60
+		r.DowntimeMinutes = downSeconds / 60
+		if monitoredSeconds > 0 {
+			uptime := 100 * (monitoredSeconds - downSeconds) / monitoredSeconds
+			r.UptimePercent = &uptime
+		}
+		reports[deviceID] = r
+	}
+	return reports, rows.Err()
+}
+
+// GetDeviceHistoryBuckets downsamples a device's raw history the same way as
+// PostgresStore.GetDeviceHistoryBuckets. SQLite has no percentile aggregate, so the
+// buckets are built here from the raw checks.
+func (s *SQLiteStore) GetDeviceHistoryBuckets(ctx context.Context, deviceID int64, interval time.Duration, aggregate string, startTime, endTime time.Time) ([]models.DeviceHistoryBucket, error) {
+	if _, ok := HistoryAggregates[aggregate]; !ok {
+		return nil, fmt.Errorf("unknown aggregate %q", aggregate)
+	}
+	history, err := s.GetDeviceHistory(ctx, deviceID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	seconds := int64(interval.Seconds())
+	buckets := make([]models.DeviceHistoryBucket, 0)
+	var responseTimes []float64
+	flush := func() {
+		if len(buckets) == 0 || len(responseTimes) == 0 {
+			return
+		}
+		value := aggregateResponseTimes(aggregate, responseTimes)
+		buckets[len(buckets)-1].ResponseTime = &value
+		responseTimes = responseTimes[:0]
+	}
+	for _, h := range history {
+		start := h.Timestamp - h.Timestamp%seconds
+		if len(buckets) == 0 || buckets[len(buckets)-1].Timestamp != start {
+			flush()
+			buckets = append(buckets, models.DeviceHistoryBucket{Timestamp: start})
+		}
+		b := &buckets[len(buckets)-1]
+		b.Checks++
+		if h.Status == "online" || h.StateType == "soft" {
+			b.OnlineChecks++
+		}
+		if h.Status == "offline" && h.StateType != "soft" {
+			b.OfflineChecks++
+		}
+		if h.Status == "unreachable" {
+			b.UnreachableChecks++
+		}
+		if h.Status == "online" {
+			responseTimes = append(responseTimes, h.ResponseTime)
+		}
+	}
+	flush()
+	return buckets, nil
+}
+
+// aggregateResponseTimes computes one of HistoryAggregates over a bucket's response
+// times; p95 interpolates like Postgres percentile_cont
+func aggregateResponseTimes(aggregate string, values []float64) float64 {
+	switch aggregate {
+	case "min":
+		return slices.Min(values)
+	case "max":
+		return slices.Max(values)
+	case "p95":
+		sorted := slices.Clone(values)
+		slices.Sort(sorted)
+		pos := 0.95 * float64(len(sorted)-1)
+		lower := int(pos)
+		if lower+1 >= len(sorted) {
+			return sorted[lower]
+		}
+		return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
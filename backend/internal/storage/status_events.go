@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// StatusEventFilter narrows a status event listing. Zero values are ignored.
+type StatusEventFilter struct {
+	EntityType string
+	PropertyID int64
+	DeviceID   int64
+	ToStatus   string
+	Start      time.Time
+	End        time.Time
+	Limit      int
+}
+
+// Status Events
+func (s *PostgresStore) CreateStatusEvent(ctx context.Context, e *models.StatusEvent) error {
+	query := `
+		INSERT INTO status_events (entity_type, property_id, device_id, from_status, to_status, occurred_at, previous_duration, message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
+	return s.db.QueryRowContext(ctx, query, e.EntityType, e.PropertyID, e.DeviceID, e.FromStatus, e.ToStatus,
+		e.OccurredAt, e.PreviousDuration, e.Message).Scan(&e.ID)
+}
+
+// ListStatusEvents returns matching events, newest first
+func (s *PostgresStore) ListStatusEvents(ctx context.Context, filter StatusEventFilter) ([]models.StatusEvent, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.PropertyID != 0 {
+		add("property_id = $%d", filter.PropertyID)
+	}
+	if filter.DeviceID != 0 {
+		add("device_id = $%d", filter.DeviceID)
+	}
+	if filter.ToStatus != "" {
+		add("to_status = $%d", filter.ToStatus)
+	}
+	if !filter.Start.IsZero() {
+		add("occurred_at >= $%d", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		add("occurred_at <= $%d", filter.End)
+	}
+
+	query := `SELECT id, entity_type, property_id, device_id, from_status, to_status, occurred_at, previous_duration, message
+		FROM status_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY occurred_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]models.StatusEvent, 0)
+	for rows.Next() {
+		var e models.StatusEvent
+		if err := rows.Scan(&e.ID, &e.EntityType, &e.PropertyID, &e.DeviceID, &e.FromStatus, &e.ToStatus,
+			&e.OccurredAt, &e.PreviousDuration, &e.Message); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
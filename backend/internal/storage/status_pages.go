@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Status pages
+const statusPageColumns = `id, property_group_id, title, message, created_at, updated_at`
+
+func scanStatusPage(row rowScanner, p *models.StatusPage) error {
+	return row.Scan(&p.ID, &p.PropertyGroupID, &p.Title, &p.Message, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// CreateStatusPage stores a group's status page with the SHA-256 hash of its token; the
+// token itself is never stored
+func (s *PostgresStore) CreateStatusPage(ctx context.Context, p *models.StatusPage, tokenHash string) error {
+	query := `
+		INSERT INTO status_pages (property_group_id, token_hash, title, message)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, p.PropertyGroupID, tokenHash, p.Title, p.Message).
+		Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (s *PostgresStore) GetStatusPageForGroup(ctx context.Context, groupID int64) (*models.StatusPage, error) {
+	p := &models.StatusPage{}
+	query := `SELECT ` + statusPageColumns + ` FROM status_pages WHERE property_group_id = $1`
+	err := scanStatusPage(s.db.QueryRowContext(ctx, query, groupID), p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("status page not found")
+	}
+	return p, err
+}
+
+func (s *PostgresStore) GetStatusPageByTokenHash(ctx context.Context, tokenHash string) (*models.StatusPage, error) {
+	p := &models.StatusPage{}
+	query := `SELECT ` + statusPageColumns + ` FROM status_pages WHERE token_hash = $1`
+	err := scanStatusPage(s.db.QueryRowContext(ctx, query, tokenHash), p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("status page not found")
+	}
+	return p, err
+}
+
+// UpdateStatusPage saves a status page's title and message, and its token when
+// tokenHash is set
+func (s *PostgresStore) UpdateStatusPage(ctx context.Context, p *models.StatusPage, tokenHash string) error {
+	query := `
+		UPDATE status_pages
+		SET title = $1, message = $2, token_hash = COALESCE(NULLIF($3, ''), token_hash), updated_at = NOW()
+		WHERE id = $4
+		RETURNING property_group_id, created_at, updated_at`
+	err := s.db.QueryRowContext(ctx, query, p.Title, p.Message, tokenHash, p.ID).
+		Scan(&p.PropertyGroupID, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("status page not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteStatusPage(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM status_pages WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("status page not found")
+	}
+	return nil
+}
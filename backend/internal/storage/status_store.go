@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// StatusStore holds live state shared between the API and workers: current statuses,
+// the check queue, worker coordination and alert bookkeeping. RedisStore backs it for
+// normal deployments; MemoryStore keeps it in process for a single-binary deployment
+// that runs with Postgres only.
+type StatusStore interface {
+	Ping(ctx context.Context) error
+	Close() error
+
+	// Device and property statuses
+	SetDeviceStatus(ctx context.Context, status *models.DeviceStatus, ttl time.Duration) error
+	WriteDeviceStatuses(ctx context.Context, writes []DeviceStatusWrite) error
+	GetDeviceStatus(ctx context.Context, deviceID int64) (*models.DeviceStatus, error)
+	GetDeviceStatuses(ctx context.Context, deviceIDs []int64) (map[int64]*models.DeviceStatus, error)
+	GetAllDeviceStatuses(ctx context.Context) (map[int64]*models.DeviceStatus, error)
+	SetPropertyStatus(ctx context.Context, status *models.PropertyStatus) error
+	GetPropertyStatus(ctx context.Context, propertyID int64) (*models.PropertyStatus, error)
+	GetAllPropertyStatuses(ctx context.Context) (map[int64]*models.PropertyStatus, error)
+	DeleteDeviceStatuses(ctx context.Context, deviceIDs []int64) error
+	DeletePropertyState(ctx context.Context, propertyID int64) error
+	PruneStatuses(ctx context.Context, deviceIDs, propertyIDs []int64) (int, int, error)
+	PublishPropertyStatus(ctx context.Context, status *models.PropertyStatus) error
+	SubscribePropertyStatus(ctx context.Context) (<-chan *models.PropertyStatus, error)
+	PublishDeviceStatusChange(ctx context.Context, change *models.DeviceStatusChange) error
+	SubscribeDeviceStatusChanges(ctx context.Context) (<-chan *models.DeviceStatusChange, error)
+	SetWiFiSnapshot(ctx context.Context, snapshot *models.WiFiSnapshot, ttl time.Duration) error
+	GetWiFiSnapshot(ctx context.Context, propertyID int64) (*models.WiFiSnapshot, error)
+	SetDeviceAudit(ctx context.Context, audit *models.DeviceAudit, ttl time.Duration) error
+	GetDeviceAudit(ctx context.Context) (*models.DeviceAudit, error)
+
+	// Notification cooldowns and rate limits
+	SetLastNotification(ctx context.Context, propertyID int64, eventType string) error
+	GetLastNotification(ctx context.Context, propertyID int64, eventType string) (time.Time, error)
+	ShouldNotify(ctx context.Context, propertyID int64, eventType string, cooldownSeconds int) (bool, error)
+	AllowNotification(ctx context.Context, channelID int64, n, limit int, window time.Duration) (bool, error)
+
+	// Request rate limiting
+	TakeRateLimitToken(ctx context.Context, key string, limit int, per time.Duration) (bool, time.Duration, error)
+
+	// Response caching
+	SetCachedResponse(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	GetCachedResponse(ctx context.Context, key string) ([]byte, error)
+
+	// Check queue and results
+	PushProbeResults(ctx context.Context, propertyID int64, results []models.ProbeResult) error
+	PopProbeResults(ctx context.Context, shard int, limit int64) ([]models.ProbeResult, error)
+	EnsureCheckQueue(ctx context.Context) error
+	EnqueueChecks(ctx context.Context, devices []models.Device, queuedAt time.Time) error
+	CheckQueueLength(ctx context.Context) (int64, error)
+	ReadChecks(ctx context.Context, consumer string, count int64, block time.Duration) ([]models.QueuedCheck, error)
+	ClaimStaleChecks(ctx context.Context, consumer string, minIdle time.Duration, count int64) ([]models.QueuedCheck, error)
+	AckCheck(ctx context.Context, id string) error
+	PushCheckResult(ctx context.Context, propertyID int64, status *models.DeviceStatus) error
+	PopCheckResults(ctx context.Context, shard int, limit int64) ([]models.DeviceStatus, error)
+
+	// Worker coordination and heartbeats
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, owner string) error
+	HeartbeatWorker(ctx context.Context, workerID string, ttl time.Duration) ([]string, error)
+	RemoveWorker(ctx context.Context, workerID string) error
+	SetWorkerHeartbeat(ctx context.Context, hb *models.WorkerHeartbeat) error
+	RemoveWorkerHeartbeat(ctx context.Context, workerID string) error
+	GetWorkerHeartbeats(ctx context.Context, maxAge time.Duration) ([]models.WorkerHeartbeat, error)
+	MarkWorkersDown(ctx context.Context) (bool, error)
+	MarkWorkersUp(ctx context.Context) (bool, error)
+
+	// Power, flapping, retries and incidents
+	SetDeviceOnBattery(ctx context.Context, propertyID, deviceID int64, onBattery bool) (int64, int64, error)
+	RecordDeviceStateChange(ctx context.Context, deviceID int64, at time.Time, window time.Duration) (int64, error)
+	CountDeviceStateChanges(ctx context.Context, deviceID int64, since time.Time) (int64, error)
+	ScheduleNotificationRetry(ctx context.Context, payload string, at time.Time) error
+	ClaimDueNotificationRetries(ctx context.Context, now time.Time, limit int64) ([]string, error)
+	SetPropertyIncident(ctx context.Context, propertyID int64, incidentKey string) error
+	GetPropertyIncident(ctx context.Context, propertyID int64) (string, error)
+	AddIncidentChannels(ctx context.Context, propertyID int64, channelIDs []int64) error
+	GetIncidentChannels(ctx context.Context, propertyID int64) ([]int64, error)
+	ClearPropertyIncident(ctx context.Context, propertyID int64) error
+	CleanupOldHistory(ctx context.Context, retentionDays int) error
+}
+
+// propertyStatusTTL is how long a property status outlives the last time the worker
+// computed it
+const propertyStatusTTL = 10 * time.Minute
+
+var (
+	_ StatusStore = (*RedisStore)(nil)
+	_ StatusStore = (*MemoryStore)(nil)
+)
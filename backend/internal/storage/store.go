@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Store is the persistent storage used by the API and workers. PostgresStore backs it
+// in production; SQLiteStore backs it for lab and demo deployments and for exercising
+// handlers without a live Postgres.
+type Store interface {
+	PropertyStore
+	DeviceStore
+	NotificationStore
+	UserStore
+	AlertStore
+	HistoryStore
+	DigestStore
+	OnCallStore
+	FirewallStore
+	IntegrationStore
+	WebhookStore
+
+	// EnableSecretEncryption and EncryptExistingSecrets turn on encryption at rest
+	// for stored credentials; see secrets.go
+	EnableSecretEncryption(encodedKey string) error
+	EncryptExistingSecrets(ctx context.Context) (int, error)
+	Migrate(ctx context.Context) error
+	InTx(ctx context.Context, fn func(tx Store) error) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// PropertyStore stores properties, their regions and groups, group status pages,
+// status badges, contacts, ISP circuits, attachments and attachment uploads
+type PropertyStore interface {
+	CreateProperty(ctx context.Context, p *models.Property) error
+	GetProperty(ctx context.Context, id int64) (*models.Property, error)
+	ListProperties(ctx context.Context) ([]models.Property, error)
+	ListPropertiesPage(ctx context.Context, filter PropertyFilter) ([]models.Property, int, error)
+	UpdateProperty(ctx context.Context, p *models.Property) error
+	DeleteProperty(ctx context.Context, id int64) error
+	CreateRegion(ctx context.Context, r *models.Region) error
+	GetRegion(ctx context.Context, id int64) (*models.Region, error)
+	ListRegions(ctx context.Context) ([]models.Region, error)
+	UpdateRegion(ctx context.Context, r *models.Region) error
+	DeleteRegion(ctx context.Context, id int64) error
+	CreatePropertyGroup(ctx context.Context, g *models.PropertyGroup) error
+	GetPropertyGroup(ctx context.Context, id int64) (*models.PropertyGroup, error)
+	ListPropertyGroups(ctx context.Context, regionID int64) ([]models.PropertyGroup, error)
+	UpdatePropertyGroup(ctx context.Context, g *models.PropertyGroup) error
+	DeletePropertyGroup(ctx context.Context, id int64) error
+	CreateStatusPage(ctx context.Context, p *models.StatusPage, tokenHash string) error
+	GetStatusPageForGroup(ctx context.Context, groupID int64) (*models.StatusPage, error)
+	GetStatusPageByTokenHash(ctx context.Context, tokenHash string) (*models.StatusPage, error)
+	UpdateStatusPage(ctx context.Context, p *models.StatusPage, tokenHash string) error
+	DeleteStatusPage(ctx context.Context, id int64) error
+	CreatePropertyBadge(ctx context.Context, b *models.PropertyBadge, tokenHash string) error
+	GetPropertyBadge(ctx context.Context, propertyID int64) (*models.PropertyBadge, error)
+	GetPropertyBadgeByTokenHash(ctx context.Context, tokenHash string) (*models.PropertyBadge, error)
+	UpdatePropertyBadgeToken(ctx context.Context, b *models.PropertyBadge, tokenHash string) error
+	DeletePropertyBadge(ctx context.Context, id int64) error
+	CreateContact(ctx context.Context, c *models.Contact) error
+	GetContact(ctx context.Context, id int64) (*models.Contact, error)
+	ListContactsForProperty(ctx context.Context, propertyID int64) ([]models.Contact, error)
+	ListContactsPage(ctx context.Context, filter ContactFilter) ([]models.Contact, int, error)
+	UpdateContact(ctx context.Context, c *models.Contact) error
+	DeleteContact(ctx context.Context, id int64) error
+	CreateCircuit(ctx context.Context, c *models.Circuit) error
+	GetCircuit(ctx context.Context, id int64) (*models.Circuit, error)
+	ListCircuitsForProperty(ctx context.Context, propertyID int64) ([]models.Circuit, error)
+	UpdateCircuit(ctx context.Context, c *models.Circuit) error
+	DeleteCircuit(ctx context.Context, id int64) error
+	CreateAttachment(ctx context.Context, a *models.Attachment) error
+	GetAttachment(ctx context.Context, id int64) (*models.Attachment, error)
+	ListAttachmentsForProperty(ctx context.Context, propertyID int64) ([]models.Attachment, error)
+	UpdateAttachment(ctx context.Context, a *models.Attachment) error
+	SearchAttachments(ctx context.Context, filter AttachmentFilter) ([]models.Attachment, int, error)
+	DeleteAttachment(ctx context.Context, id int64) error
+	ListAttachmentStoragePaths(ctx context.Context) ([]string, error)
+	CreateAttachmentUpload(ctx context.Context, u *models.AttachmentUpload) error
+	GetAttachmentUpload(ctx context.Context, id int64) (*models.AttachmentUpload, error)
+	ListAttachmentUploadsForProperty(ctx context.Context, propertyID int64) ([]models.AttachmentUpload, error)
+	UpdateAttachmentUploadReceived(ctx context.Context, id, received int64) error
+	DeleteAttachmentUpload(ctx context.Context, id int64) error
+	CompleteAttachmentUpload(ctx context.Context, uploadID int64, a *models.Attachment) error
+}
+
+// DeviceStore stores devices and device templates
+type DeviceStore interface {
+	CreateDevice(ctx context.Context, d *models.Device) error
+	GetDevice(ctx context.Context, id int64) (*models.Device, error)
+	ListDevices(ctx context.Context) ([]models.Device, error)
+	ListDevicesPage(ctx context.Context, filter DeviceFilter) ([]models.Device, int, error)
+	ListDevicesForProperty(ctx context.Context, propertyID int64) ([]models.Device, error)
+	ListActiveDevices(ctx context.Context) ([]models.Device, error)
+	ListDevicesForProbe(ctx context.Context, probeID int64) ([]models.Device, error)
+	ListDevicesWarrantyExpiring(ctx context.Context, from, before time.Time, propertyID int64) ([]models.Device, error)
+	ListDevicesByMAC(ctx context.Context, mac string) ([]models.Device, error)
+	ListDevicesWithFirmware(ctx context.Context, propertyID int64) ([]models.Device, error)
+	SetDeviceFirmware(ctx context.Context, id int64, manufacturer, model, version string) error
+	UpdateDevice(ctx context.Context, d *models.Device) error
+	DeleteDevice(ctx context.Context, id int64) error
+	CreateDeviceTemplate(ctx context.Context, t *models.DeviceTemplate) error
+	GetDeviceTemplate(ctx context.Context, id int64) (*models.DeviceTemplate, error)
+	ListDeviceTemplates(ctx context.Context) ([]models.DeviceTemplate, error)
+	UpdateDeviceTemplate(ctx context.Context, t *models.DeviceTemplate) error
+	DeleteDeviceTemplate(ctx context.Context, id int64) error
+}
+
+// NotificationStore stores notification channels, property links, routing rules and the notification log
+type NotificationStore interface {
+	CreateNotificationChannel(ctx context.Context, nc *models.NotificationChannel) error
+	GetNotificationChannel(ctx context.Context, id int64) (*models.NotificationChannel, error)
+	ListNotificationChannels(ctx context.Context) ([]models.NotificationChannel, error)
+	UpdateNotificationChannel(ctx context.Context, nc *models.NotificationChannel) error
+	DeleteNotificationChannel(ctx context.Context, id int64) error
+	CreatePropertyNotification(ctx context.Context, pn *models.PropertyNotification) error
+	ListPropertyNotifications(ctx context.Context, propertyID int64) ([]models.PropertyNotification, error)
+	UpdatePropertyNotification(ctx context.Context, pn *models.PropertyNotification) error
+	DeletePropertyNotification(ctx context.Context, id int64) error
+	CreateNotificationEvent(ctx context.Context, ne *models.NotificationEvent) error
+	ListNotificationEvents(ctx context.Context, propertyID int64, limit int) ([]models.NotificationEvent, error)
+	ListNotificationEventsPage(ctx context.Context, filter NotificationEventFilter) ([]models.NotificationEvent, int, error)
+	CreateNotificationRule(ctx context.Context, r *models.NotificationRule) error
+	GetNotificationRule(ctx context.Context, id int64) (*models.NotificationRule, error)
+	ListNotificationRules(ctx context.Context) ([]models.NotificationRule, error)
+	ListNotificationRulesForProperty(ctx context.Context, propertyID int64) ([]models.NotificationRule, error)
+	UpdateNotificationRule(ctx context.Context, r *models.NotificationRule) error
+	DeleteNotificationRule(ctx context.Context, id int64) error
+}
+
+// UserStore stores users, their two-factor state and sessions, and global settings
+type UserStore interface {
+	CreateUser(ctx context.Context, u *models.User) error
+	GetUser(ctx context.Context, id int64) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	CreateUserFromOAuth(ctx context.Context, email, name, role string) (*models.User, error)
+	GetUserBySSOIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	LinkSSOIdentity(ctx context.Context, userID int64, issuer, subject string) error
+	ListUsers(ctx context.Context) ([]models.User, error)
+	UpdateUser(ctx context.Context, u *models.User) error
+	UpdateUserPassword(ctx context.Context, userID int64, hashedPassword string) error
+	DeleteUser(ctx context.Context, id int64) error
+	GetUserTOTP(ctx context.Context, userID int64) (*models.UserTOTP, error)
+	SetUserTOTPSecret(ctx context.Context, userID int64, secret string) error
+	EnableUserTOTP(ctx context.Context, userID, step int64) error
+	UseTOTPStep(ctx context.Context, userID, step int64) (bool, error)
+	DisableUserTOTP(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int64) (int, error)
+	CreateSession(ctx context.Context, session *models.UserSession) error
+	GetSession(ctx context.Context, id int64) (*models.UserSession, error)
+	ListActiveSessions(ctx context.Context, userID int64) ([]models.UserSession, error)
+	TouchSession(ctx context.Context, id int64, ipAddress string, at time.Time) error
+	RevokeSession(ctx context.Context, id int64) error
+	RevokeUserSessions(ctx context.Context, userID, keepID int64) (int64, error)
+	GetSettings(ctx context.Context) (*models.Settings, error)
+	UpdateSettings(ctx context.Context, settings *models.Settings) error
+}
+
+// AlertStore stores acknowledgements, silences and maintenance windows
+type AlertStore interface {
+	CreateAcknowledgement(ctx context.Context, a *models.Acknowledgement) error
+	ListActiveAcknowledgements(ctx context.Context) ([]models.Acknowledgement, error)
+	ClearPropertyAcknowledgement(ctx context.Context, propertyID int64) error
+	ClearDeviceAcknowledgement(ctx context.Context, deviceID int64) error
+	CreateSilence(ctx context.Context, silence *models.Silence) error
+	ListActiveSilences(ctx context.Context, at time.Time) ([]models.Silence, error)
+	ExpireSilence(ctx context.Context, id int64) error
+	CreateMaintenanceWindow(ctx context.Context, w *models.MaintenanceWindow) error
+	GetMaintenanceWindow(ctx context.Context, id int64) (*models.MaintenanceWindow, error)
+	ListMaintenanceWindows(ctx context.Context) ([]models.MaintenanceWindow, error)
+	ListMaintenanceWindowsForProperty(ctx context.Context, propertyID int64) ([]models.MaintenanceWindow, error)
+	ListCurrentMaintenanceWindows(ctx context.Context, at time.Time) ([]models.MaintenanceWindow, error)
+	UpdateMaintenanceWindow(ctx context.Context, w *models.MaintenanceWindow) error
+	DeleteMaintenanceWindow(ctx context.Context, id int64) error
+}
+
+// HistoryStore stores check history, rollups, status events and retention
+type HistoryStore interface {
+	AddDeviceHistory(ctx context.Context, status *models.DeviceStatus) error
+	AddDeviceHistoryBatch(ctx context.Context, statuses []*models.DeviceStatus) error
+	GetDeviceHistory(ctx context.Context, deviceID int64, startTime, endTime time.Time) ([]models.DeviceHistory, error)
+	GetDeviceErrors(ctx context.Context, deviceID int64, limit int) ([]models.DeviceHistory, error)
+	GetDeviceHistoryBuckets(ctx context.Context, deviceID int64, interval time.Duration, aggregate string, startTime, endTime time.Time) ([]models.DeviceHistoryBucket, error)
+	RollupDeviceHistory(ctx context.Context, since time.Time) error
+	GetDeviceHistoryRollups(ctx context.Context, deviceID int64, resolution string, startTime, endTime time.Time) ([]models.DeviceHistoryRollup, error)
+	GetDeviceUptime(ctx context.Context, deviceIDs []int64, startTime, endTime time.Time) (map[int64]*models.UptimeReport, error)
+	GetLatencyTrend(ctx context.Context, startTime, endTime time.Time) ([]models.LatencyPoint, error)
+	CreateStatusEvent(ctx context.Context, e *models.StatusEvent) error
+	ListStatusEvents(ctx context.Context, filter StatusEventFilter) ([]models.StatusEvent, error)
+	ListActivity(ctx context.Context, filter ActivityFilter) ([]models.ActivityEvent, error)
+	DeleteDeviceHistoryBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteNotificationEventsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteSessionsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteWebhookDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	DeleteAttachmentUploadsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// DigestStore stores digest subscriptions and the outage summaries they report
+type DigestStore interface {
+	CreateDigestSubscription(ctx context.Context, d *models.DigestSubscription) error
+	GetDigestSubscription(ctx context.Context, id int64) (*models.DigestSubscription, error)
+	ListDigestSubscriptions(ctx context.Context) ([]models.DigestSubscription, error)
+	ListDigestSubscriptionsForUser(ctx context.Context, userID int64) ([]models.DigestSubscription, error)
+	UpdateDigestSubscription(ctx context.Context, d *models.DigestSubscription) error
+	MarkDigestSent(ctx context.Context, id int64, at time.Time) error
+	DeleteDigestSubscription(ctx context.Context, id int64) error
+	GetPropertyOutageSummaries(ctx context.Context, propertyIDs []int64, start, end time.Time) ([]models.PropertyOutageSummary, error)
+	GetWorstDevices(ctx context.Context, propertyIDs []int64, start, end time.Time, limit int) ([]models.DeviceOutageSummary, error)
+}
+
+// OnCallStore stores on-call schedules and overrides
+type OnCallStore interface {
+	CreateOnCallSchedule(ctx context.Context, sch *models.OnCallSchedule) error
+	GetOnCallSchedule(ctx context.Context, id int64) (*models.OnCallSchedule, error)
+	ListOnCallSchedules(ctx context.Context) ([]models.OnCallSchedule, error)
+	UpdateOnCallSchedule(ctx context.Context, sch *models.OnCallSchedule) error
+	DeleteOnCallSchedule(ctx context.Context, id int64) error
+	CreateOnCallOverride(ctx context.Context, o *models.OnCallOverride) error
+	ListOnCallOverrides(ctx context.Context, scheduleID int64, after time.Time) ([]models.OnCallOverride, error)
+	DeleteOnCallOverride(ctx context.Context, id int64) error
+}
+
+// FirewallStore stores pfSense firewalls, config backups, DHCP mapping changes and traffic samples
+type FirewallStore interface {
+	CreateFirewall(ctx context.Context, f *models.Firewall) error
+	GetFirewall(ctx context.Context, id int64) (*models.Firewall, error)
+	ListFirewallsForProperty(ctx context.Context, propertyID int64) ([]models.Firewall, error)
+	UpdateFirewall(ctx context.Context, f *models.Firewall) error
+	DeleteFirewall(ctx context.Context, id int64) error
+	CreateConfigBackup(ctx context.Context, b *models.ConfigBackup) error
+	GetConfigBackup(ctx context.Context, id int64) (*models.ConfigBackup, error)
+	GetLatestConfigBackup(ctx context.Context, propertyID int64) (*models.ConfigBackup, error)
+	ListConfigBackupsForProperty(ctx context.Context, propertyID int64) ([]models.ConfigBackup, error)
+	CreateDHCPMappingChange(ctx context.Context, ch *models.DHCPMappingChange) error
+	ListDHCPMappingChanges(ctx context.Context, propertyID int64) ([]models.DHCPMappingChange, error)
+	InsertTrafficSamples(ctx context.Context, propertyID int64, collectedAt time.Time, samples []models.TrafficSample) error
+	ListTrafficSamples(ctx context.Context, propertyID int64, iface string, start, end time.Time) ([]models.TrafficSample, error)
+	DeleteTrafficSamplesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// IntegrationStore stores UniFi controllers and remote probes
+type IntegrationStore interface {
+	GetUniFiController(ctx context.Context, propertyID int64) (*models.UniFiController, error)
+	ListUniFiControllers(ctx context.Context) ([]models.UniFiController, error)
+	SaveUniFiController(ctx context.Context, u *models.UniFiController) error
+	DeleteUniFiController(ctx context.Context, propertyID int64) error
+	CreateProbe(ctx context.Context, p *models.Probe, tokenHash string) error
+	GetProbe(ctx context.Context, id int64) (*models.Probe, error)
+	GetProbeByTokenHash(ctx context.Context, tokenHash string) (*models.Probe, error)
+	ListProbes(ctx context.Context) ([]models.Probe, error)
+	TouchProbe(ctx context.Context, id int64, at time.Time) error
+	DeleteProbe(ctx context.Context, id int64) error
+}
+
+// WebhookStore stores outbound webhooks and their delivery log
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, w *models.Webhook) error
+	GetWebhook(ctx context.Context, id int64) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	UpdateWebhook(ctx context.Context, w *models.Webhook) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error)
+}
+
+var (
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*SQLiteStore)(nil)
+)
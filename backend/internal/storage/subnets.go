@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/netip"
+)
+
+// DefaultSubnetBase is where automatic property subnets start when the setting is unset:
+// property N gets the Nth /24 after it, 10.(99+N/256).(N%256).0/24
+const DefaultSubnetBase = "10.99.0.0"
+
+// assignSubnet gives a new property its automatic subnet: the /24 as many /24s after
+// the subnet_base setting as its ID, or the next one after that which overlaps no other
+// property's subnet
+func (s *PostgresStore) assignSubnet(ctx context.Context, propertyID int64) (netip.Prefix, error) {
+	baseText := DefaultSubnetBase
+	err := s.db.QueryRowContext(ctx, "SELECT subnet_base FROM settings LIMIT 1").Scan(&baseText)
+	if err != nil && err != sql.ErrNoRows {
+		return netip.Prefix{}, err
+	}
+	base, err := netip.ParseAddr(baseText)
+	if err != nil || !base.Is4() {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet_base %q", baseText)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT subnet FROM properties WHERE id <> $1 AND subnet <> ''", propertyID)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	defer rows.Close()
+	var taken []netip.Prefix
+	for rows.Next() {
+		var subnet string
+		if err := rows.Scan(&subnet); err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix, err := netip.ParsePrefix(subnet); err == nil {
+			taken = append(taken, prefix.Masked())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return autoSubnet(base, propertyID, taken)
+}
+
+// autoSubnet returns the first /24 from the id'th after base that overlaps none of taken
+func autoSubnet(base netip.Addr, id int64, taken []netip.Prefix) (netip.Prefix, error) {
+	b := base.As4()
+	start := uint64(b[0])<<24 | uint64(b[1])<<16 | uint64(b[2])<<8
+	for n := start + uint64(id)<<8; n <= 0xFFFFFF00; n += 1 << 8 {
+		prefix := netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), 0}), 24)
+		free := true
+		for _, t := range taken {
+			if t.Overlaps(prefix) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return prefix, nil
+		}
+	}
+	return netip.Prefix{}, fmt.Errorf("no free /24 subnet left after %s", base)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Two-factor authentication
+func (s *PostgresStore) GetUserTOTP(ctx context.Context, userID int64) (*models.UserTOTP, error) {
+	t := &models.UserTOTP{UserID: userID}
+	query := `SELECT totp_enabled, totp_secret, totp_last_step FROM users WHERE id = $1`
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&t.Enabled, &t.Secret, &t.LastStep)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openSecret(&t.Secret); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetUserTOTPSecret starts enrollment with a new secret. Two-factor stays off until
+// EnableUserTOTP.
+func (s *PostgresStore) SetUserTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	sealed, err := s.sealSecret(secret)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = false, totp_last_step = 0, updated_at = NOW() WHERE id = $2`
+	return s.execUserUpdate(ctx, query, sealed, userID)
+}
+
+// EnableUserTOTP turns on two-factor for a user whose enrollment code at step checked out
+func (s *PostgresStore) EnableUserTOTP(ctx context.Context, userID, step int64) error {
+	query := `UPDATE users SET totp_enabled = true, totp_last_step = $1, updated_at = NOW() WHERE id = $2 AND totp_secret <> ''`
+	return s.execUserUpdate(ctx, query, step, userID)
+}
+
+// UseTOTPStep records that a code for step was accepted. It reports false if a code
+// for that step or a later one was already used, so each code works once.
+func (s *PostgresStore) UseTOTPStep(ctx context.Context, userID, step int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`, step, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// DisableUserTOTP turns off two-factor for a user and drops their secret and recovery codes
+func (s *PostgresStore) DisableUserTOTP(ctx context.Context, userID int64) error {
+	query := `UPDATE users SET totp_enabled = false, totp_secret = '', totp_last_step = 0, updated_at = NOW() WHERE id = $1`
+	if err := s.execUserUpdate(ctx, query, userID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
+
+func (s *PostgresStore) execUserUpdate(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes swaps a user's recovery codes for new ones, given as hashes
+func (s *PostgresStore) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if _, err := s.db.ExecContext(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the user's unused recovery code with the given hash as used,
+// reporting false if there is no such code
+func (s *PostgresStore) UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	query := `UPDATE user_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := s.db.ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (s *PostgresStore) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
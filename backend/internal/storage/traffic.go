@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Interface Traffic
+
+// InsertTrafficSamples stores one collection round for a property
+func (s *PostgresStore) InsertTrafficSamples(ctx context.Context, propertyID int64, collectedAt time.Time, samples []models.TrafficSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	ifaces := make([]string, len(samples))
+	rxBytes := make([]int64, len(samples))
+	txBytes := make([]int64, len(samples))
+	rxPackets := make([]int64, len(samples))
+	txPackets := make([]int64, len(samples))
+	for i, sample := range samples {
+		ifaces[i] = sample.Interface
+		rxBytes[i] = sample.RxBytes
+		txBytes[i] = sample.TxBytes
+		rxPackets[i] = sample.RxPackets
+		txPackets[i] = sample.TxPackets
+	}
+
+	query := `
+		INSERT INTO interface_traffic (property_id, interface, collected_at, rx_bytes, tx_bytes, rx_packets, tx_packets)
+		SELECT $1, t.interface, $2, t.rx_bytes, t.tx_bytes, t.rx_packets, t.tx_packets
+		FROM unnest($3::text[], $4::bigint[], $5::bigint[], $6::bigint[], $7::bigint[])
+			AS t(interface, rx_bytes, tx_bytes, rx_packets, tx_packets)
+		ON CONFLICT DO NOTHING`
+	_, err := s.db.ExecContext(ctx, query, propertyID, collectedAt, array(ifaces), array(rxBytes),
+		array(txBytes), array(rxPackets), array(txPackets))
+	return err
+}
+
+// ListTrafficSamples returns a property's samples in a time range ordered by interface
+// and time, optionally for a single interface
+func (s *PostgresStore) ListTrafficSamples(ctx context.Context, propertyID int64, iface string, start, end time.Time) ([]models.TrafficSample, error) {
+	query := `
+		SELECT property_id, interface, collected_at, rx_bytes, tx_bytes, rx_packets, tx_packets
+		FROM interface_traffic
+		WHERE property_id = $1 AND collected_at >= $2 AND collected_at <= $3 AND ($4 = '' OR interface = $4)
+		ORDER BY interface, collected_at`
+	rows, err := s.db.QueryContext(ctx, query, propertyID, start, end, iface)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := make([]models.TrafficSample, 0)
+	for rows.Next() {
+		var t models.TrafficSample
+		if err := rows.Scan(&t.PropertyID, &t.Interface, &t.CollectedAt, &t.RxBytes, &t.TxBytes,
+			&t.RxPackets, &t.TxPackets); err != nil {
+			return nil, err
+		}
+		samples = append(samples, t)
+	}
+	return samples, rows.Err()
+}
+
+// DeleteTrafficSamplesBefore removes samples older than the cutoff
+func (s *PostgresStore) DeleteTrafficSamplesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM interface_traffic WHERE collected_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// UniFi Controllers
+const unifiControllerColumns = `id, property_id, url, username, password, site, verify_tls, created_at, updated_at`
+
+func (s *PostgresStore) scanUniFiController(row rowScanner, u *models.UniFiController) error {
+	if err := row.Scan(&u.ID, &u.PropertyID, &u.URL, &u.Username, &u.Password, &u.Site, &u.VerifyTLS,
+		&u.CreatedAt, &u.UpdatedAt); err != nil {
+		return err
+	}
+	u.PasswordSet = u.Password != ""
+	return s.openSecret(&u.Password)
+}
+
+func (s *PostgresStore) GetUniFiController(ctx context.Context, propertyID int64) (*models.UniFiController, error) {
+	u := &models.UniFiController{}
+	query := `SELECT ` + unifiControllerColumns + ` FROM unifi_controllers WHERE property_id = $1`
+	err := s.scanUniFiController(s.db.QueryRowContext(ctx, query, propertyID), u)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("unifi controller not found")
+	}
+	return u, err
+}
+
+func (s *PostgresStore) ListUniFiControllers(ctx context.Context) ([]models.UniFiController, error) {
+	query := `SELECT ` + unifiControllerColumns + ` FROM unifi_controllers ORDER BY property_id`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	controllers := make([]models.UniFiController, 0)
+	for rows.Next() {
+		var u models.UniFiController
+		if err := s.scanUniFiController(rows, &u); err != nil {
+			return nil, err
+		}
+		controllers = append(controllers, u)
+	}
+	return controllers, rows.Err()
+}
+
+// SaveUniFiController creates or replaces a property's controller; an empty password
+// keeps the stored one
+func (s *PostgresStore) SaveUniFiController(ctx context.Context, u *models.UniFiController) error {
+	password, err := s.sealSecret(u.Password)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO unifi_controllers (property_id, url, username, password, site, verify_tls)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (property_id) DO UPDATE
+		SET url = EXCLUDED.url, username = EXCLUDED.username,
+			password = COALESCE(NULLIF(EXCLUDED.password, ''), unifi_controllers.password),
+			site = EXCLUDED.site, verify_tls = EXCLUDED.verify_tls, updated_at = NOW()
+		RETURNING id, created_at, updated_at, password <> ''`
+	return s.db.QueryRowContext(ctx, query, u.PropertyID, u.URL, u.Username, password, u.Site, u.VerifyTLS).
+		Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt, &u.PasswordSet)
+}
+
+func (s *PostgresStore) DeleteUniFiController(ctx context.Context, propertyID int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM unifi_controllers WHERE property_id = $1", propertyID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("unifi controller not found")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Webhooks
+const webhookColumns = `id, name, url, secret, events, enabled, created_at, updated_at`
+
+func (s *PostgresStore) scanWebhook(row rowScanner, w *models.Webhook) error {
+	if err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, scanArray(&w.Events), &w.Enabled,
+		&w.CreatedAt, &w.UpdatedAt); err != nil {
+		return err
+	}
+	w.SecretSet = w.Secret != ""
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+	return s.openSecret(&w.Secret)
+}
+
+func (s *PostgresStore) CreateWebhook(ctx context.Context, w *models.Webhook) error {
+	secret, err := s.sealSecret(w.Secret)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO webhooks (name, url, secret, events, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	return s.db.QueryRowContext(ctx, query, w.Name, w.URL, secret, array(w.Events), w.Enabled).
+		Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (s *PostgresStore) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	w := &models.Webhook{}
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	err := s.scanWebhook(s.db.QueryRowContext(ctx, query, id), w)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook not found")
+	}
+	return w, err
+}
+
+func (s *PostgresStore) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks ORDER BY name`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var w models.Webhook
+		if err := s.scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook saves a webhook; an empty secret keeps the stored one
+func (s *PostgresStore) UpdateWebhook(ctx context.Context, w *models.Webhook) error {
+	secret, err := s.sealSecret(w.Secret)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE webhooks
+		SET name = $1, url = $2, secret = COALESCE(NULLIF($3, ''), secret), events = $4, enabled = $5,
+			updated_at = NOW()
+		WHERE id = $6
+		RETURNING secret <> '', created_at, updated_at`
+	err = s.db.QueryRowContext(ctx, query, w.Name, w.URL, secret, array(w.Events), w.Enabled, w.ID).
+		Scan(&w.SecretSet, &w.CreatedAt, &w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("webhook not found")
+	}
+	return err
+}
+
+func (s *PostgresStore) DeleteWebhook(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	return nil
+}
+
+// Webhook deliveries
+const webhookDeliveryColumns = `id, webhook_id, event_id, event_type, payload, success, status_code, error,
+	attempts, duration_ms, replay_of, created_at`
+
+func scanWebhookDelivery(row rowScanner, d *models.WebhookDelivery) error {
+	return row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Success, &d.StatusCode,
+		&d.Error, &d.Attempts, &d.DurationMs, &d.ReplayOf, &d.CreatedAt)
+}
+
+func (s *PostgresStore) CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, success, status_code, error,
+			attempts, duration_ms, replay_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, d.WebhookID, d.EventID, d.EventType, d.Payload, d.Success,
+		d.StatusCode, d.Error, d.Attempts, d.DurationMs, d.ReplayOf).Scan(&d.ID, &d.CreatedAt)
+}
+
+func (s *PostgresStore) GetWebhookDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	err := scanWebhookDelivery(s.db.QueryRowContext(ctx, query, id), d)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery not found")
+	}
+	return d, err
+}
+
+// ListWebhookDeliveries returns a webhook's most recent deliveries, newest first
+func (s *PostgresStore) ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE webhook_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`
+	rows, err := s.db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
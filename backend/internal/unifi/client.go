@@ -0,0 +1,240 @@
+package unifi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Client talks to a UniFi Network controller, either a standalone controller or one
+// hosted on a UniFi OS console (UDM, Cloud Key Gen2+), which serves the same API
+// under /proxy/network
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	site     string
+	http     *http.Client
+	// apiPrefix is set at login: "" for standalone controllers, "/proxy/network" for UniFi OS
+	apiPrefix string
+}
+
+func NewClient(baseURL, username, password, site string, verifyTLS bool) *Client {
+	jar, _ := cookiejar.New(nil)
+	if site == "" {
+		site = "default"
+	}
+	return &Client{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		site:     site,
+		http: &http.Client{
+			Timeout: 15 * time.Second,
+			Jar:     jar,
+			Transport: &http.Transport{
+				// Controllers ship with self-signed certificates
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: !verifyTLS},
+			},
+		},
+	}
+}
+
+// Login authenticates against the controller, trying the UniFi OS endpoint first and
+// falling back to the standalone controller endpoint
+func (c *Client) Login(ctx context.Context) error {
+	creds, err := json.Marshal(map[string]string{"username": c.username, "password": c.password})
+	if err != nil {
+		return err
+	}
+
+	status, err := c.post(ctx, "/api/auth/login", creds)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		c.apiPrefix = "/proxy/network"
+		return nil
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("login failed: HTTP %d", status)
+	}
+
+	status, err = c.post(ctx, "/api/login", creds)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("login failed: HTTP %d", status)
+	}
+	c.apiPrefix = ""
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach controller: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// get fetches a site API path and decodes the data array of the response envelope
+func (c *Client) get(ctx context.Context, path string, data interface{}) error {
+	endpoint := fmt.Sprintf("%s%s/api/s/%s%s", c.baseURL, c.apiPrefix, url.PathEscape(c.site), path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach controller: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("controller returned HTTP %d for %s", resp.StatusCode, path)
+	}
+
+	var envelope struct {
+		Meta struct {
+			RC  string `json:"rc"`
+			Msg string `json:"msg"`
+		} `json:"meta"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode controller response: %w", err)
+	}
+	if envelope.Meta.RC != "ok" {
+		return fmt.Errorf("controller error: %s", envelope.Meta.Msg)
+	}
+	return json.Unmarshal(envelope.Data, data)
+}
+
+// uniFiDevice is the subset of stat/device fields we use
+type uniFiDevice struct {
+	MAC     string `json:"mac"`
+	Name    string `json:"name"`
+	Model   string `json:"model"`
+	Type    string `json:"type"`
+	IP      string `json:"ip"`
+	State   int    `json:"state"`
+	NumSta  int    `json:"num_sta"`
+	Uptime  int64  `json:"uptime"`
+	Version string `json:"version"`
+	Radios  []struct {
+		Radio        string `json:"radio"`
+		Channel      int    `json:"channel"`
+		CUTotal      int    `json:"cu_total"`
+		NumSta       int    `json:"num_sta"`
+		Satisfaction *int   `json:"satisfaction"`
+	} `json:"radio_table_stats"`
+}
+
+// GetAccessPoints logs in and returns every access point adopted on the site
+func (c *Client) GetAccessPoints(ctx context.Context) ([]models.AccessPoint, error) {
+	if err := c.Login(ctx); err != nil {
+		return nil, err
+	}
+
+	var devices []uniFiDevice
+	if err := c.get(ctx, "/stat/device", &devices); err != nil {
+		return nil, err
+	}
+
+	aps := make([]models.AccessPoint, 0, len(devices))
+	for _, d := range devices {
+		if d.Type != "uap" {
+			continue
+		}
+		ap := models.AccessPoint{
+			MAC:     strings.ToLower(d.MAC),
+			Name:    d.Name,
+			Model:   d.Model,
+			IPAddr:  d.IP,
+			State:   deviceState(d.State),
+			Clients: d.NumSta,
+			Uptime:  d.Uptime,
+			Version: d.Version,
+			Radios:  make([]models.AccessPointRadio, 0, len(d.Radios)),
+		}
+		if ap.Name == "" {
+			ap.Name = ap.MAC
+		}
+		for _, r := range d.Radios {
+			radio := models.AccessPointRadio{
+				Band:         radioBand(r.Radio),
+				Channel:      r.Channel,
+				Utilization:  r.CUTotal,
+				Clients:      r.NumSta,
+				Satisfaction: -1,
+			}
+			if r.Satisfaction != nil {
+				radio.Satisfaction = *r.Satisfaction
+			}
+			ap.Radios = append(ap.Radios, radio)
+		}
+		aps = append(aps, ap)
+	}
+	return aps, nil
+}
+
+// deviceState names the controller's numeric device state
+func deviceState(state int) string {
+	switch state {
+	case 0:
+		return "disconnected"
+	case 1:
+		return "connected"
+	case 2:
+		return "pending"
+	case 4:
+		return "upgrading"
+	case 5:
+		return "provisioning"
+	case 6:
+		return "heartbeat_missed"
+	case 7:
+		return "adopting"
+	case 9:
+		return "adoption_failed"
+	case 10:
+		return "isolated"
+	default:
+		return fmt.Sprintf("unknown (%d)", state)
+	}
+}
+
+// radioBand maps the controller's radio names to a band
+func radioBand(radio string) string {
+	switch radio {
+	case "ng":
+		return "2.4GHz"
+	case "na":
+		return "5GHz"
+	case "6e":
+		return "6GHz"
+	default:
+		return radio
+	}
+}
@@ -0,0 +1,254 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+	"github.com/etswifi/ets-noc/internal/storage"
+)
+
+// webhookCacheTTL is how long the webhook list is reused before it is reloaded, so
+// changes made through another API replica reach this process within that time
+const webhookCacheTTL = 30 * time.Second
+
+// retryDelays are the waits before the second and later attempts of a published event
+var retryDelays = []time.Duration{5 * time.Second, 30 * time.Second}
+
+// Dispatcher delivers events to the webhooks subscribed to them. Published events are
+// delivered in the background and retried; tests and replays are delivered once,
+// while the caller waits. Every delivery is logged with its outcome.
+type Dispatcher struct {
+	postgres   storage.Store
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	webhooks []models.Webhook
+	loadedAt time.Time
+
+	// closed stops retries and new deliveries; wg tracks the ones running
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewDispatcher(postgres storage.Store) *Dispatcher {
+	return &Dispatcher{
+		postgres:   postgres,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		closed:     make(chan struct{}),
+	}
+}
+
+// Publish sends an event to every enabled webhook subscribed to it without waiting
+// for the deliveries. A nil dispatcher publishes nothing.
+func (d *Dispatcher) Publish(ctx context.Context, eventType string, data any) {
+	if d == nil || d.isClosed() {
+		return
+	}
+
+	hooks, err := d.subscribers(ctx, eventType)
+	if err != nil {
+		slog.Error("Failed to load webhooks", "event", eventType, "error", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	event, payload, err := newEvent(eventType, data)
+	if err != nil {
+		slog.Error("Failed to build webhook event", "event", eventType, "error", err)
+		return
+	}
+
+	// Checked again with the lock held so Close can't miss a delivery it should wait for
+	d.mu.Lock()
+	if d.isClosed() {
+		d.mu.Unlock()
+		return
+	}
+	d.wg.Add(len(hooks))
+	d.mu.Unlock()
+
+	// Deliveries outlive the request or check that published them
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		go func() {
+			defer d.wg.Done()
+			d.deliver(ctx, &hook, event.ID, eventType, payload, nil, 1+len(retryDelays))
+		}()
+	}
+}
+
+// Test sends a webhook.test event to a webhook, enabled or not, and returns the
+// logged delivery
+func (d *Dispatcher) Test(ctx context.Context, hook *models.Webhook) (*models.WebhookDelivery, error) {
+	event, payload, err := newEvent(EventTest, TestData{
+		WebhookID: hook.ID,
+		Message:   "Test event from ETS NOC",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.deliver(ctx, hook, event.ID, EventTest, payload, nil, 1)
+}
+
+// Replay sends a logged delivery's payload to its webhook again, as a new delivery
+// that records which one it replays. The event ID is unchanged.
+func (d *Dispatcher) Replay(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	hook, err := d.postgres.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	return d.deliver(ctx, hook, delivery.EventID, delivery.EventType, []byte(delivery.Payload), &delivery.ID, 1)
+}
+
+// Invalidate drops the cached webhook list so the next event reloads it
+func (d *Dispatcher) Invalidate() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.webhooks = nil
+	d.mu.Unlock()
+}
+
+// Close stops retrying and waits for the deliveries in flight to finish
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.closeOnce.Do(func() { close(d.closed) })
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) isClosed() bool {
+	select {
+	case <-d.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribers returns the webhooks that should receive an event type, from a list
+// reloaded every webhookCacheTTL
+func (d *Dispatcher) subscribers(ctx context.Context, eventType string) ([]models.Webhook, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.webhooks == nil || time.Since(d.loadedAt) > webhookCacheTTL {
+		hooks, err := d.postgres.ListWebhooks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		d.webhooks = hooks
+		d.loadedAt = time.Now()
+	}
+
+	var matched []models.Webhook
+	for _, hook := range d.webhooks {
+		if subscribed(&hook, eventType) {
+			matched = append(matched, hook)
+		}
+	}
+	return matched, nil
+}
+
+func newEvent(eventType string, data any) (*Event, []byte, error) {
+	id, err := newEventID()
+	if err != nil {
+		return nil, nil, err
+	}
+	event := &Event{ID: id, Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	return event, payload, nil
+}
+
+// deliver posts a payload to a webhook up to maxAttempts times, waiting retryDelays
+// between attempts, and logs the outcome as one delivery
+func (d *Dispatcher) deliver(ctx context.Context, hook *models.Webhook, eventID, eventType string, payload []byte, replayOf *int64, maxAttempts int) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		WebhookID: hook.ID,
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   string(payload),
+		ReplayOf:  replayOf,
+	}
+
+	var err error
+	start := time.Now()
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(retryDelays[attempt-2]):
+			case <-d.closed:
+			case <-ctx.Done():
+			}
+			if d.isClosed() || ctx.Err() != nil {
+				break
+			}
+		}
+
+		delivery.Attempts = attempt
+		delivery.StatusCode, err = d.send(ctx, hook, eventID, eventType, payload)
+		if err == nil {
+			delivery.Success = true
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+	}
+	delivery.DurationMs = time.Since(start).Milliseconds()
+
+	if !delivery.Success {
+		slog.Warn("Webhook delivery failed", "webhook_id", hook.ID, "event", eventType,
+			"event_id", eventID, "attempts", delivery.Attempts, "error", delivery.Error)
+	}
+	if err = d.postgres.CreateWebhookDelivery(ctx, delivery); err != nil {
+		slog.Error("Failed to log webhook delivery", "webhook_id", hook.ID, "event_id", eventID, "error", err)
+		return delivery, err
+	}
+	return delivery, nil
+}
+
+// send makes one delivery attempt and returns the response status, or 0 when none
+// was received. Any status other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, eventID, eventType string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ETS-NOC-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderEventID, eventID)
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, time.Now(), payload))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,119 @@
+// Package webhook posts signed domain events to the outbound webhooks external systems
+// subscribe with, logging each delivery so it can be inspected and replayed
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/etswifi/ets-noc/internal/models"
+)
+
+// Event types. A webhook subscribed to no events receives all of them.
+const (
+	EventPropertyCreated     = "property.created"
+	EventDeviceStatusChanged = "device.status_changed"
+	EventIncidentOpened      = "incident.opened"
+	EventIncidentResolved    = "incident.resolved"
+	EventSyncCompleted       = "sync.completed"
+	// EventTest is only sent by the test endpoint, whatever the webhook subscribes to
+	EventTest = "webhook.test"
+)
+
+// EventTypes lists the events a webhook can subscribe to
+var EventTypes = []string{
+	EventPropertyCreated,
+	EventDeviceStatusChanged,
+	EventIncidentOpened,
+	EventIncidentResolved,
+	EventSyncCompleted,
+}
+
+// Header names set on deliveries; the signature is left off when a webhook has no secret
+const (
+	HeaderEvent     = "X-ETS-Event"
+	HeaderEventID   = "X-ETS-Event-ID"
+	HeaderSignature = "X-ETS-Signature"
+)
+
+// Event is the JSON body posted to a webhook. ID is the same on every delivery and
+// replay of the event, so receivers can discard duplicates.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// IncidentData is the data of incident.opened and incident.resolved events
+type IncidentData struct {
+	PropertyID int64                  `json:"property_id"`
+	Status     *models.PropertyStatus `json:"status"`
+}
+
+// SyncData is the data of a sync.completed event, sent when a pfSense device sync is
+// applied to a property
+type SyncData struct {
+	PropertyID  int64    `json:"property_id"`
+	Created     int      `json:"created"`
+	Updated     int      `json:"updated"`
+	Deactivated int      `json:"deactivated"`
+	Unchanged   int      `json:"unchanged"`
+	Total       int      `json:"total"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// TestData is the data of a webhook.test event
+type TestData struct {
+	WebhookID int64  `json:"webhook_id"`
+	Message   string `json:"message"`
+}
+
+// ValidEventType reports whether a webhook can subscribe to an event type
+func ValidEventType(eventType string) bool {
+	return slices.Contains(EventTypes, eventType)
+}
+
+// subscribed reports whether an enabled webhook should receive an event type
+func subscribed(w *models.Webhook, eventType string) bool {
+	return w.Enabled && (len(w.Events) == 0 || slices.Contains(w.Events, eventType))
+}
+
+// NewSecret returns a random signing secret for a webhook
+func NewSecret() (string, error) {
+	return randomHex(32)
+}
+
+func newEventID() (string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	return "evt_" + id, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Sign returns the X-ETS-Signature header for a body sent at timestamp: t=<unix
+// seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>" keyed with the secret>.
+// Receivers recompute v1 and reject stale timestamps to stop replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
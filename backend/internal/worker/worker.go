@@ -0,0 +1,140 @@
+// Package worker runs device checks, notification retries and the fleet-wide leader
+// jobs, for the worker binary and for the API when it runs the worker in process
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/etswifi/ets-noc/internal/audit"
+	"github.com/etswifi/ets-noc/internal/backup"
+	"github.com/etswifi/ets-noc/internal/digest"
+	"github.com/etswifi/ets-noc/internal/monitor"
+	"github.com/etswifi/ets-noc/internal/notifier"
+	"github.com/etswifi/ets-noc/internal/orphans"
+	"github.com/etswifi/ets-noc/internal/storage"
+	"github.com/etswifi/ets-noc/internal/webhook"
+)
+
+type Worker struct {
+	postgres storage.Store
+	redis    storage.StatusStore
+	notify   *notifier.Notifier
+	blobs    storage.BlobStore
+	cluster  *monitor.Cluster
+	pinger   *monitor.Pinger
+	retries  *notifier.RetryQueue
+	webhooks *webhook.Dispatcher
+	wg       sync.WaitGroup
+}
+
+// New creates a worker that splits properties with the other workers sharing redis.
+// blobs may be nil, which disables pfSense config backups.
+func New(ctx context.Context, postgres storage.Store, redis storage.StatusStore, notify *notifier.Notifier, blobs storage.BlobStore, workerID string) *Worker {
+	cluster := monitor.NewCluster(redis, workerID)
+	webhooks := webhook.NewDispatcher(postgres)
+	opts := []monitor.PingerOption{monitor.WithCluster(cluster)}
+	settings, err := postgres.GetSettings(ctx)
+	if err == nil && settings.MaxConcurrentPings > 0 {
+		opts = append(opts, monitor.WithMaxConcurrent(settings.MaxConcurrentPings))
+	}
+
+	return &Worker{
+		postgres: postgres,
+		redis:    redis,
+		notify:   notify,
+		blobs:    blobs,
+		cluster:  cluster,
+		pinger:   monitor.NewPinger(postgres, redis, notify, webhooks, opts...),
+		retries:  notifier.NewRetryQueue(notify),
+		webhooks: webhooks,
+	}
+}
+
+// Start joins the cluster and runs checks until the worker is stopped or ctx is
+// cancelled. Notification retries run alongside, and fleet-wide jobs run while this
+// worker is the leader.
+func (w *Worker) Start(ctx context.Context) error {
+	w.wg.Add(3)
+	go func() {
+		defer w.wg.Done()
+		if err := w.cluster.Start(ctx); err != nil {
+			slog.Error("Cluster error", "error", err)
+		}
+	}()
+
+	// Retries are claimed atomically so every worker can run them
+	go func() {
+		defer w.wg.Done()
+		if err := w.retries.Start(ctx); err != nil {
+			slog.Error("Notification retry queue error", "error", err)
+		}
+	}()
+
+	// Fleet-wide jobs run on the leader only, and move to another worker if it dies
+	go func() {
+		defer w.wg.Done()
+		w.cluster.RunAsLeader(ctx, w.runLeaderJobs)
+	}()
+
+	return w.pinger.Start(ctx)
+}
+
+// Stop stops checks and retries and hands this worker's shards and leadership to the
+// others, waiting for the leader jobs and webhook deliveries in flight to finish
+func (w *Worker) Stop() {
+	w.pinger.Stop()
+	w.retries.Stop()
+	w.cluster.Stop()
+	w.wg.Wait()
+	w.webhooks.Close()
+}
+
+// runLeaderJobs runs the jobs that must only run on one worker until ctx is cancelled
+func (w *Worker) runLeaderJobs(ctx context.Context) {
+	var wg sync.WaitGroup
+	run := func(name string, start func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := start(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Leader job error", "job", name, "error", err)
+			}
+		}()
+	}
+
+	// Roll device history up into hourly/daily buckets
+	run("History aggregator", monitor.NewHistoryAggregator(w.postgres).Start)
+
+	// Drop expired statuses, and those of deleted and inactive devices, from the
+	// dashboard's aggregate views
+	run("Status reconciler", monitor.NewStatusReconciler(w.postgres, w.redis).Start)
+
+	// Prune history older than the retention setting once a day
+	run("Retention cleaner", monitor.NewRetentionCleaner(w.postgres, w.redis).Start)
+
+	// Sample pfSense interface counters for bandwidth graphs
+	run("Traffic collector", monitor.NewTrafficCollector(w.postgres).Start)
+
+	// Poll UniFi controllers for access point telemetry
+	run("WiFi poller", monitor.NewWiFiPoller(w.postgres, w.redis).Start)
+
+	// Record the firmware version running on pfSense firewalls
+	run("Firmware collector", monitor.NewFirmwareCollector(w.postgres).Start)
+
+	// Look for devices with conflicting addresses every hour
+	run("Device auditor", audit.NewReporter(w.postgres, w.redis).Start)
+
+	// Send daily and weekly digest emails
+	run("Digest scheduler", digest.NewScheduler(w.postgres, w.redis, w.notify).Start)
+
+	// Back up pfSense config.xml files to GCS, and report attachment files left
+	// behind with no attachment
+	if w.blobs != nil {
+		run("Config backup scheduler", backup.NewScheduler(w.postgres, w.blobs).Start)
+		run("Orphaned file reporter", orphans.NewReporter(w.postgres, w.blobs).Start)
+	}
+
+	wg.Wait()
+}
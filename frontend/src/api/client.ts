@@ -1,5 +1,22 @@
 const API_BASE_URL = import.meta.env.VITE_API_URL || ''
 
+// LoginError is a failed login; totpRequired means the password was right and the
+// account needs a two-factor code
+// AuthProviders lists the single sign-on options the login page offers
+export interface AuthProviders {
+  google: boolean
+  oidc?: { name: string }
+}
+
+export class LoginError extends Error {
+  totpRequired: boolean
+
+  constructor(message: string, totpRequired = false) {
+    super(message)
+    this.totpRequired = totpRequired
+  }
+}
+
 class ApiClient {
   private baseUrl: string
   private token: string | null = null
@@ -52,11 +69,50 @@ class ApiClient {
   }
 
   // Auth
-  async login(username: string, password: string) {
-    return this.request<{ token: string; user: any }>('/api/v1/auth/login', {
+  // Login doesn't go through request() so a 401 shows its error, or asks for a
+  // two-factor code, instead of redirecting back to the login page
+  async login(username: string, password: string, totpCode?: string) {
+    const response = await fetch(`${this.baseUrl}/api/v1/auth/login`, {
       method: 'POST',
-      body: JSON.stringify({ username, password }),
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ username, password, totp_code: totpCode }),
     })
+    const data = await response.json().catch(() => ({ error: 'Login failed' }))
+    if (!response.ok) {
+      throw new LoginError(data.error || 'Login failed', Boolean(data.totp_required))
+    }
+    return data as { token: string; user: any }
+  }
+
+  async getAuthProviders() {
+    const response = await fetch(`${this.baseUrl}/api/v1/auth/providers`)
+    if (!response.ok) {
+      return { google: false } as AuthProviders
+    }
+    return (await response.json()) as AuthProviders
+  }
+
+  // Revokes the session server-side; the token is cleared either way
+  async logout() {
+    if (this.token) {
+      await fetch(`${this.baseUrl}/api/v1/auth/logout`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${this.token}` },
+      }).catch(() => {})
+    }
+    this.clearToken()
+  }
+
+  async getSessions() {
+    return this.request<any[]>('/api/v1/auth/sessions')
+  }
+
+  async revokeSession(id: number) {
+    return this.request<any>(`/api/v1/auth/sessions/${id}`, { method: 'DELETE' })
+  }
+
+  async revokeOtherSessions() {
+    return this.request<{ revoked: number }>('/api/v1/auth/sessions', { method: 'DELETE' })
   }
 
   async getMe() {
@@ -68,6 +124,40 @@ class ApiClient {
     return this.request<any>('/api/v1/dashboard')
   }
 
+  async getMap() {
+    return this.request<any>('/api/v1/map')
+  }
+
+  // Activity feed, newest first; pass next_cursor from the previous page to continue
+  async getEvents(since?: string, cursor?: string, limit?: number) {
+    let url = '/api/v1/events'
+    const params = new URLSearchParams()
+    if (since) params.append('since', since)
+    if (cursor) params.append('cursor', cursor)
+    if (limit) params.append('limit', String(limit))
+    if (params.toString()) url += `?${params.toString()}`
+    return this.request<any>(url)
+  }
+
+  async getWorkerHealth() {
+    return this.request<any>('/api/v1/workers')
+  }
+
+  dashboardSocketUrl() {
+    const base = this.baseUrl || window.location.origin
+    const url = new URL('/api/v1/ws/dashboard', base)
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:'
+    url.searchParams.set('token', this.token || '')
+    return url.toString()
+  }
+
+  deviceStatusStreamUrl(propertyId: number) {
+    const url = new URL('/api/v1/stream/devices', this.baseUrl || window.location.origin)
+    url.searchParams.set('property_id', String(propertyId))
+    url.searchParams.set('token', this.token || '')
+    return url.toString()
+  }
+
   // Properties
   async getProperties() {
     return this.request<any[]>('/api/v1/properties')
@@ -105,12 +195,37 @@ class ApiClient {
     return this.request<any[]>(`/api/v1/properties/${id}/devices`)
   }
 
-  async syncDevicesFromPfSense(id: number) {
-    return this.request<any>(`/api/v1/properties/${id}/sync-devices`, {
+  async previewSyncDevices(id: number) {
+    return this.request<any>(`/api/v1/properties/${id}/sync-devices?dry_run=true`, {
       method: 'POST',
     })
   }
 
+  async syncDevicesFromPfSense(id: number, confirmToken?: string) {
+    const query = confirmToken ? `?confirm=${encodeURIComponent(confirmToken)}` : ''
+    return this.request<any>(`/api/v1/properties/${id}/sync-devices${query}`, {
+      method: 'POST',
+    })
+  }
+
+  async pushDHCPMapping(propertyId: number, data: any) {
+    return this.request<any>(`/api/v1/properties/${propertyId}/dhcp-mappings`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async deleteDHCPMapping(propertyId: number, mac: string, iface = 'lan') {
+    return this.request<any>(
+      `/api/v1/properties/${propertyId}/dhcp-mappings/${encodeURIComponent(mac)}?interface=${encodeURIComponent(iface)}`,
+      { method: 'DELETE' }
+    )
+  }
+
+  async getDHCPMappingChanges(propertyId: number) {
+    return this.request<any[]>(`/api/v1/properties/${propertyId}/dhcp-mappings/changes`)
+  }
+
   // Contacts
   async getContacts(propertyId: number) {
     return this.request<any[]>(`/api/v1/properties/${propertyId}/contacts`)
@@ -136,15 +251,41 @@ class ApiClient {
     })
   }
 
+  // ISP circuits
+  async getCircuits(propertyId: number) {
+    return this.request<any[]>(`/api/v1/properties/${propertyId}/circuits`)
+  }
+
+  async createCircuit(propertyId: number, data: any) {
+    return this.request<any>(`/api/v1/properties/${propertyId}/circuits`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async updateCircuit(id: number, data: any) {
+    return this.request<any>(`/api/v1/circuits/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async deleteCircuit(id: number) {
+    return this.request<any>(`/api/v1/circuits/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
   // Attachments
   async getAttachments(propertyId: number) {
     return this.request<any[]>(`/api/v1/properties/${propertyId}/attachments`)
   }
 
-  async uploadAttachment(propertyId: number, file: File, description: string) {
+  async uploadAttachment(propertyId: number, file: File, description: string, tags: string[] = []) {
     const formData = new FormData()
     formData.append('file', file)
     formData.append('description', description)
+    formData.append('tags', tags.join(','))
 
     const headers: HeadersInit = {}
     if (this.token) {
@@ -168,8 +309,85 @@ class ApiClient {
     return response.json()
   }
 
+  // Links a file chosen in the Google Drive picker, with the access token the picker
+  // signed in with
+  async linkDriveAttachment(
+    propertyId: number,
+    fileId: string,
+    accessToken: string,
+    description: string
+  ) {
+    return this.request<any>(`/api/v1/properties/${propertyId}/attachments/google-drive`, {
+      method: 'POST',
+      body: JSON.stringify({ file_id: fileId, access_token: accessToken, description }),
+    })
+  }
+
+  // Large files go up in parts, each retried after a dropped connection from where the
+  // server says the upload got to
+  async uploadAttachmentChunked(
+    propertyId: number,
+    file: File,
+    description: string,
+    onProgress?: (fraction: number) => void
+  ) {
+    let upload = await this.request<any>(`/api/v1/properties/${propertyId}/attachment-uploads`, {
+      method: 'POST',
+      body: JSON.stringify({
+        filename: file.name,
+        description,
+        mime_type: file.type,
+        size: file.size,
+      }),
+    })
+
+    let failures = 0
+    while (upload.received < upload.file_size) {
+      const part = file.slice(upload.received, upload.received + upload.chunk_size)
+      try {
+        upload = await this.request<any>(
+          `/api/v1/attachment-uploads/${upload.id}/parts?offset=${upload.received}`,
+          {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/octet-stream' },
+            body: part,
+          }
+        )
+        failures = 0
+        onProgress?.(upload.received / upload.file_size)
+      } catch (error) {
+        if (++failures > 5) throw error
+        await new Promise((resolve) => setTimeout(resolve, 2000 * failures))
+        upload = await this.request<any>(`/api/v1/attachment-uploads/${upload.id}`)
+      }
+    }
+
+    return this.request<any>(`/api/v1/attachment-uploads/${upload.id}/complete`, {
+      method: 'POST',
+    })
+  }
+
+  // Searches attachments across all properties by filename, description, tag and
+  // property name
+  async searchAttachments(q: string, tag?: string, propertyId?: number) {
+    const params = new URLSearchParams()
+    if (q) params.append('q', q)
+    if (tag) params.append('tag', tag)
+    if (propertyId) params.append('property_id', String(propertyId))
+    return this.request<any[]>(`/api/v1/attachments/search?${params.toString()}`)
+  }
+
+  async updateAttachment(id: number, description: string, tags: string[]) {
+    return this.request<any>(`/api/v1/attachments/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify({ description, tags }),
+    })
+  }
+
+  // Files in local storage come back as links relative to the API
   async getAttachmentDownloadUrl(id: number) {
-    return this.request<{ url: string }>(`/api/v1/attachments/${id}/download`)
+    const { url } = await this.request<{ url: string }>(`/api/v1/attachments/${id}/download`)
+    return { url: url.startsWith('/') ? `${this.baseUrl}${url}` : url }
   }
 
   async deleteAttachment(id: number) {
@@ -187,6 +405,23 @@ class ApiClient {
     return this.request<any>(`/api/v1/devices/${id}`)
   }
 
+  async getWarrantyReport(days = 90, propertyId?: number) {
+    const params = new URLSearchParams({ days: String(days) })
+    if (propertyId) params.set('property_id', String(propertyId))
+    return this.request<any[]>(`/api/v1/devices/warranty?${params}`)
+  }
+
+  async getFirmwareReport(propertyId?: number, belowMinimum = false) {
+    const params = new URLSearchParams()
+    if (propertyId) params.set('property_id', String(propertyId))
+    if (belowMinimum) params.set('below_minimum', 'true')
+    return this.request<any[]>(`/api/v1/devices/firmware?${params}`)
+  }
+
+  async getDeviceAudit(refresh = false) {
+    return this.request<any>(`/api/v1/audits/devices${refresh ? '?refresh=true' : ''}`)
+  }
+
   async createDevice(data: any) {
     return this.request<any>('/api/v1/devices', {
       method: 'POST',
@@ -211,11 +446,14 @@ class ApiClient {
     return this.request<any>(`/api/v1/devices/${id}/status`)
   }
 
-  async getDeviceHistory(id: number, start?: string, end?: string) {
+  // With an interval (e.g. '1h') the history comes back downsampled into buckets
+  async getDeviceHistory(id: number, start?: string, end?: string, interval?: string, aggregate?: string) {
     let url = `/api/v1/devices/${id}/history`
     const params = new URLSearchParams()
     if (start) params.append('start', start)
     if (end) params.append('end', end)
+    if (interval) params.append('interval', interval)
+    if (aggregate) params.append('aggregate', aggregate)
     if (params.toString()) url += `?${params.toString()}`
     return this.request<any[]>(url)
   }
@@ -1,6 +1,9 @@
 import { useState } from 'react'
 import { apiClient } from '../api/client'
 
+// Files over this go up in parts, which survive a flaky connection
+const CHUNKED_UPLOAD_THRESHOLD = 50 * 1024 * 1024
+
 interface AttachmentsListProps {
   attachments: any[]
   propertyId: number
@@ -10,6 +13,7 @@ interface AttachmentsListProps {
 export default function AttachmentsList({ attachments, propertyId, onUpdate }: AttachmentsListProps) {
   const [showUploadModal, setShowUploadModal] = useState(false)
   const [uploading, setUploading] = useState(false)
+  const [progress, setProgress] = useState<number | null>(null)
   const [file, setFile] = useState<File | null>(null)
   const [description, setDescription] = useState('')
 
@@ -19,7 +23,12 @@ export default function AttachmentsList({ attachments, propertyId, onUpdate }: A
 
     setUploading(true)
     try {
-      await apiClient.uploadAttachment(propertyId, file, description)
+      if (file.size > CHUNKED_UPLOAD_THRESHOLD) {
+        setProgress(0)
+        await apiClient.uploadAttachmentChunked(propertyId, file, description, setProgress)
+      } else {
+        await apiClient.uploadAttachment(propertyId, file, description)
+      }
       setShowUploadModal(false)
       setFile(null)
       setDescription('')
@@ -28,6 +37,7 @@ export default function AttachmentsList({ attachments, propertyId, onUpdate }: A
       alert(error.message)
     } finally {
       setUploading(false)
+      setProgress(null)
     }
   }
 
@@ -120,7 +130,7 @@ export default function AttachmentsList({ attachments, propertyId, onUpdate }: A
               <div className="space-y-4">
                 <div>
                   <label className="block text-sm font-medium text-gray-700 mb-1">
-                    File (Max 50MB)
+                    File
                   </label>
                   <input
                     type="file"
@@ -160,7 +170,11 @@ export default function AttachmentsList({ attachments, propertyId, onUpdate }: A
                   className="px-4 py-2 bg-blue-600 text-white rounded-md hover:bg-blue-700 disabled:bg-gray-400"
                   disabled={uploading}
                 >
-                  {uploading ? 'Uploading...' : 'Upload'}
+                  {uploading
+                    ? progress === null
+                      ? 'Uploading...'
+                      : `Uploading ${Math.round(progress * 100)}%`
+                    : 'Upload'}
                 </button>
               </div>
             </form>
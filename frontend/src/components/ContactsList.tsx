@@ -16,6 +16,7 @@ export default function ContactsList({ contacts, propertyId, onUpdate }: Contact
     email: '',
     role: '',
     notes: '',
+    escalation_order: 0,
   })
 
   const handleSubmit = async (e: React.FormEvent) => {
@@ -28,7 +29,7 @@ export default function ContactsList({ contacts, propertyId, onUpdate }: Contact
       }
       setShowAddModal(false)
       setEditingContact(null)
-      setFormData({ name: '', phone: '', email: '', role: '', notes: '' })
+      setFormData({ name: '', phone: '', email: '', role: '', notes: '', escalation_order: 0 })
       onUpdate()
     } catch (error: any) {
       alert(error.message)
@@ -53,6 +54,7 @@ export default function ContactsList({ contacts, propertyId, onUpdate }: Contact
       email: contact.email || '',
       role: contact.role || '',
       notes: contact.notes || '',
+      escalation_order: contact.escalation_order || 0,
     })
     setShowAddModal(true)
   }
@@ -81,6 +83,11 @@ export default function ContactsList({ contacts, propertyId, onUpdate }: Contact
                       {contact.role}
                     </span>
                   )}
+                  {contact.escalation_order > 0 && (
+                    <span className="text-xs bg-red-100 text-red-700 px-2 py-1 rounded-full">
+                      Call #{contact.escalation_order}
+                    </span>
+                  )}
                 </div>
                 {contact.phone && (
                   <div className="text-sm text-gray-600 mt-1">📞 {contact.phone}</div>
@@ -163,6 +170,23 @@ export default function ContactsList({ contacts, propertyId, onUpdate }: Contact
                     placeholder="e.g., Manager, IT Contact"
                   />
                 </div>
+                <div>
+                  <label className="block text-sm font-medium text-gray-700 mb-1">
+                    Escalation Order
+                  </label>
+                  <input
+                    type="number"
+                    min={0}
+                    value={formData.escalation_order}
+                    onChange={(e) =>
+                      setFormData({ ...formData, escalation_order: parseInt(e.target.value) || 0 })
+                    }
+                    className="w-full px-3 py-2 border border-gray-300 rounded-md"
+                  />
+                  <p className="text-xs text-gray-500 mt-1">
+                    Order to call this contact in when the property goes down; 0 leaves them out of alerts
+                  </p>
+                </div>
                 <div>
                   <label className="block text-sm font-medium text-gray-700 mb-1">Notes</label>
                   <textarea
@@ -179,7 +203,7 @@ export default function ContactsList({ contacts, propertyId, onUpdate }: Contact
                   onClick={() => {
                     setShowAddModal(false)
                     setEditingContact(null)
-                    setFormData({ name: '', phone: '', email: '', role: '', notes: '' })
+                    setFormData({ name: '', phone: '', email: '', role: '', notes: '', escalation_order: 0 })
                   }}
                   className="px-4 py-2 bg-gray-200 text-gray-700 rounded-md hover:bg-gray-300"
                 >
@@ -35,6 +35,12 @@ const periodDurations: Record<Period, number> = {
   '30d': 30 * 24 * 60 * 60 * 1000,
 }
 
+// Long periods are downsampled by the API instead of fetching every check
+const periodIntervals: Partial<Record<Period, string>> = {
+  '7d': '1h',
+  '30d': '4h',
+}
+
 export default function DeviceDetailModal({ device, onClose }: DeviceDetailModalProps) {
   const [status, setStatus] = useState<any>(null)
   const [history, setHistory] = useState<any[]>([])
@@ -59,7 +65,8 @@ export default function DeviceDetailModal({ device, onClose }: DeviceDetailModal
         apiClient.getDeviceHistory(
           device.id,
           start.toISOString(),
-          now.toISOString()
+          now.toISOString(),
+          periodIntervals[period]
         ),
         apiClient.getDeviceErrors(device.id, 10),
       ])
@@ -101,13 +108,20 @@ export default function DeviceDetailModal({ device, onClose }: DeviceDetailModal
       ...point,
       time: formatTime(point.timestamp),
       responseTime: point.response_time,
-      uptime: point.status === 'online' ? 100 : 0,
+      uptime: point.checks !== undefined
+        ? point.online_checks / point.checks * 100
+        : point.status === 'online' ? 100 : 0,
     }))
     .sort((a, b) => a.timestamp - b.timestamp)
 
-  // Calculate uptime percentage for the period
-  const uptimePercentage = history.length > 0
-    ? (history.filter(h => h.status === 'online').length / history.length * 100).toFixed(1)
+  // Calculate uptime percentage for the period; buckets carry their check counts
+  const totalChecks = history.reduce((sum, h) => sum + (h.checks ?? 1), 0)
+  const onlineChecks = history.reduce(
+    (sum, h) => sum + (h.checks !== undefined ? h.online_checks : h.status === 'online' ? 1 : 0),
+    0
+  )
+  const uptimePercentage = totalChecks > 0
+    ? (onlineChecks / totalChecks * 100).toFixed(1)
     : '0.0'
 
   return (
@@ -162,7 +176,7 @@ export default function DeviceDetailModal({ device, onClose }: DeviceDetailModal
                 </div>
                 <div className="bg-orange-100 dark:bg-orange-900/30 rounded-lg p-4">
                   <div className="text-2xl font-bold text-orange-600 dark:text-orange-400">
-                    {device.check_interval}s
+                    {device.check_interval ? `${device.check_interval}s` : 'Default'}
                   </div>
                   <div className="text-sm text-gray-600 dark:text-gray-400">Check Interval</div>
                 </div>
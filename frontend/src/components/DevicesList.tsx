@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react'
 import { apiClient } from '../api/client'
+import { useAuth } from '../contexts/AuthContext'
 import DeviceDetailModal from './DeviceDetailModal'
 
 interface DevicesListProps {
@@ -9,6 +10,7 @@ interface DevicesListProps {
 }
 
 export default function DevicesList({ devices, propertyId, onUpdate }: DevicesListProps) {
+  const { user } = useAuth()
   const [showAddModal, setShowAddModal] = useState(false)
   const [editingDevice, setEditingDevice] = useState<any>(null)
   const [selectedDevice, setSelectedDevice] = useState<any>(null)
@@ -18,17 +20,25 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
     hostname: '',
     device_type: 'wap',
     is_critical: false,
-    check_interval: 60,
-    retries: 3,
-    timeout: 10000,
+    check_interval: 0,
+    retries: 0,
+    timeout: 0,
   })
 
   useEffect(() => {
     loadDeviceStatuses()
-    const interval = setInterval(loadDeviceStatuses, 10000) // Refresh every 10s
-    return () => clearInterval(interval)
   }, [devices])
 
+  useEffect(() => {
+    // Live device status transitions for this property
+    const source = new EventSource(apiClient.deviceStatusStreamUrl(propertyId))
+    source.addEventListener('device_status', (event) => {
+      const change = JSON.parse((event as MessageEvent).data)
+      setDeviceStatuses((current) => ({ ...current, [change.device_id]: change.status }))
+    })
+    return () => source.close()
+  }, [propertyId])
+
   const loadDeviceStatuses = async () => {
     const statuses: Record<number, any> = {}
     await Promise.all(
@@ -48,7 +58,9 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
     e.preventDefault()
     try {
       if (editingDevice) {
+        // Fields the form doesn't edit, such as asset details, are sent back unchanged
         await apiClient.updateDevice(editingDevice.id, {
+          ...editingDevice,
           ...formData,
           property_id: propertyId,
         })
@@ -60,7 +72,7 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
       }
       setShowAddModal(false)
       setEditingDevice(null)
-      setFormData({ name: '', hostname: '', device_type: 'wap', is_critical: false, check_interval: 60, retries: 3, timeout: 10000 })
+      setFormData({ name: '', hostname: '', device_type: 'wap', is_critical: false, check_interval: 0, retries: 0, timeout: 0 })
       onUpdate()
     } catch (error: any) {
       alert(error.message)
@@ -77,6 +89,28 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
     }
   }
 
+  const handlePushDHCP = async (device: any) => {
+    const mac = device.mac_address || prompt(`MAC address for ${device.name}:`)
+    if (!mac) return
+    const iface = prompt('pfSense DHCP interface:', 'lan')
+    if (!iface) return
+    if (!confirm(`Push static mapping ${mac} -> ${device.hostname} for ${device.name} to pfSense (${iface})?`)) return
+    try {
+      const change = await apiClient.pushDHCPMapping(propertyId, {
+        device_id: device.id,
+        mac_address: mac,
+        interface: iface,
+      })
+      alert(
+        change.previous_ip_address
+          ? `Updated static mapping (was ${change.previous_ip_address})`
+          : 'Created static mapping'
+      )
+    } catch (error: any) {
+      alert(error.message)
+    }
+  }
+
   const openEditModal = (device: any) => {
     setEditingDevice(device)
     setFormData({
@@ -84,9 +118,9 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
       hostname: device.hostname,
       device_type: device.device_type,
       is_critical: device.is_critical,
-      check_interval: device.check_interval || 60,
-      retries: device.retries || 3,
-      timeout: device.timeout || 10000,
+      check_interval: device.check_interval || 0,
+      retries: device.retries || 0,
+      timeout: device.timeout || 0,
     })
     setShowAddModal(true)
   }
@@ -191,6 +225,14 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
                 >
                   Edit
                 </button>
+                {user?.role === 'admin' && (
+                  <button
+                    onClick={() => handlePushDHCP(device)}
+                    className="px-3 py-1 text-sm bg-gray-200 text-gray-700 rounded hover:bg-gray-300"
+                  >
+                    Push DHCP
+                  </button>
+                )}
                 <button
                   onClick={() => handleDelete(device.id)}
                   className="px-3 py-1 text-sm bg-red-600 text-white rounded hover:bg-red-700"
@@ -270,13 +312,13 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
                   </label>
                   <input
                     type="number"
-                    value={formData.check_interval}
-                    onChange={(e) => setFormData({ ...formData, check_interval: parseInt(e.target.value) || 60 })}
+                    value={formData.check_interval || ''}
+                    onChange={(e) => setFormData({ ...formData, check_interval: parseInt(e.target.value) || 0 })}
                     className="w-full px-3 py-2 border border-gray-300 rounded-md"
                     min="10"
-                    required
+                    placeholder="Inherited"
                   />
-                  <p className="text-xs text-gray-500 mt-1">How often to check device status (default: 60)</p>
+                  <p className="text-xs text-gray-500 mt-1">How often to check device status; leave blank to use the property or global default</p>
                 </div>
                 <div>
                   <label className="block text-sm font-medium text-gray-700 mb-1">
@@ -284,14 +326,14 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
                   </label>
                   <input
                     type="number"
-                    value={formData.retries}
-                    onChange={(e) => setFormData({ ...formData, retries: parseInt(e.target.value) || 3 })}
+                    value={formData.retries || ''}
+                    onChange={(e) => setFormData({ ...formData, retries: parseInt(e.target.value) || 0 })}
                     className="w-full px-3 py-2 border border-gray-300 rounded-md"
                     min="1"
                     max="10"
-                    required
+                    placeholder="Inherited"
                   />
-                  <p className="text-xs text-gray-500 mt-1">Number of retry attempts (default: 3)</p>
+                  <p className="text-xs text-gray-500 mt-1">Number of retry attempts; leave blank to inherit</p>
                 </div>
                 <div>
                   <label className="block text-sm font-medium text-gray-700 mb-1">
@@ -299,14 +341,14 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
                   </label>
                   <input
                     type="number"
-                    value={formData.timeout}
-                    onChange={(e) => setFormData({ ...formData, timeout: parseInt(e.target.value) || 10000 })}
+                    value={formData.timeout || ''}
+                    onChange={(e) => setFormData({ ...formData, timeout: parseInt(e.target.value) || 0 })}
                     className="w-full px-3 py-2 border border-gray-300 rounded-md"
                     min="1000"
                     max="60000"
-                    required
+                    placeholder="Inherited"
                   />
-                  <p className="text-xs text-gray-500 mt-1">Ping timeout in ms (default: 10000)</p>
+                  <p className="text-xs text-gray-500 mt-1">Ping timeout in ms; leave blank to inherit</p>
                 </div>
               </div>
               <div className="flex justify-end gap-2 mt-6">
@@ -315,7 +357,7 @@ export default function DevicesList({ devices, propertyId, onUpdate }: DevicesLi
                   onClick={() => {
                     setShowAddModal(false)
                     setEditingDevice(null)
-                    setFormData({ name: '', hostname: '', device_type: 'wap', is_critical: false, check_interval: 60, retries: 3, timeout: 10000 })
+                    setFormData({ name: '', hostname: '', device_type: 'wap', is_critical: false, check_interval: 0, retries: 0, timeout: 0 })
                   }}
                   className="px-4 py-2 bg-gray-200 text-gray-700 rounded-md hover:bg-gray-300"
                 >
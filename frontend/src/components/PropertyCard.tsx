@@ -45,11 +45,33 @@ export default function PropertyCard({ property, onClick }: PropertyCardProps) {
           <span className="text-gray-700 dark:text-gray-300">{property.total_count || 0}</span>
           <span className="text-gray-500 dark:text-gray-400"> devices</span>
         </div>
-        {property.critical_offline && (
-          <span className="text-xs bg-red-600 text-white px-2 py-1 rounded-full">
-            CRITICAL
-          </span>
-        )}
+        <div className="flex gap-1">
+          {property.maintenance && (
+            <span className="text-xs bg-blue-600 text-white px-2 py-1 rounded-full">
+              MAINTENANCE
+            </span>
+          )}
+          {property.flapping && (
+            <span className="text-xs bg-orange-500 text-white px-2 py-1 rounded-full">
+              FLAPPING
+            </span>
+          )}
+          {property.acknowledged && (
+            <span className="text-xs bg-gray-600 text-white px-2 py-1 rounded-full">
+              ACK
+            </span>
+          )}
+          {property.silenced && (
+            <span className="text-xs bg-gray-400 text-white px-2 py-1 rounded-full">
+              SILENCED
+            </span>
+          )}
+          {property.critical_offline && (
+            <span className="text-xs bg-red-600 text-white px-2 py-1 rounded-full">
+              CRITICAL
+            </span>
+          )}
+        </div>
       </div>
 
       {property.offline_count > 0 && (
@@ -25,6 +25,8 @@ export default function PropertyDetailModal({ property, onClose, onUpdate }: Pro
     notes: property.notes || '',
     isp_company_name: property.isp_company_name || '',
     isp_account_info: property.isp_account_info || '',
+    latitude: property.latitude ?? null,
+    longitude: property.longitude ?? null,
   })
 
   useEffect(() => {
@@ -76,7 +78,22 @@ export default function PropertyDetailModal({ property, onClose, onUpdate }: Pro
   const syncDevices = async () => {
     setSyncing(true)
     try {
-      await apiClient.syncDevicesFromPfSense(property.id)
+      const preview = await apiClient.previewSyncDevices(property.id)
+      const { create, update, deactivate, unchanged } = preview.plan
+      if (create.length + update.length + deactivate.length === 0) {
+        alert(`Devices are already in sync with pfSense (${unchanged} unchanged)`)
+        return
+      }
+      const lines = [
+        ...create.map((c: any) => `+ ${c.name} (${c.ip_addr})`),
+        ...update.map((c: any) => `~ ${c.name}: ${c.changes.join(', ')}`),
+        ...deactivate.map((c: any) => `- ${c.name} (${c.ip_addr})`),
+      ]
+      const summary = `Create ${create.length}, update ${update.length}, deactivate ${deactivate.length}, unchanged ${unchanged}`
+      if (!confirm(`${summary}\n\n${lines.slice(0, 20).join('\n')}${lines.length > 20 ? '\n...' : ''}\n\nApply these changes?`)) {
+        return
+      }
+      await apiClient.syncDevicesFromPfSense(property.id, preview.confirm_token)
       await loadDevices()
       alert('Devices synced successfully from pfSense')
     } catch (error) {
@@ -106,6 +123,8 @@ export default function PropertyDetailModal({ property, onClose, onUpdate }: Pro
       notes: property.notes || '',
       isp_company_name: property.isp_company_name || '',
       isp_account_info: property.isp_account_info || '',
+      latitude: property.latitude ?? null,
+      longitude: property.longitude ?? null,
     })
     setEditing(false)
   }
@@ -15,6 +15,9 @@ export default function PropertyModal({ property, onClose, onSuccess }: Property
     notes: '',
     isp_company_name: '',
     isp_account_info: '',
+    subnet: '',
+    latitude: null as number | null,
+    longitude: null as number | null,
   })
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState('')
@@ -27,6 +30,9 @@ export default function PropertyModal({ property, onClose, onSuccess }: Property
         notes: property.notes || '',
         isp_company_name: property.isp_company_name || '',
         isp_account_info: property.isp_account_info || '',
+        subnet: property.subnet || '',
+        latitude: property.latitude ?? null,
+        longitude: property.longitude ?? null,
       })
     }
   }, [property])
@@ -95,6 +101,19 @@ export default function PropertyModal({ property, onClose, onSuccess }: Property
                 />
               </div>
 
+              <div>
+                <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-1">
+                  Subnet
+                </label>
+                <input
+                  type="text"
+                  value={formData.subnet}
+                  onChange={(e) => setFormData({ ...formData, subnet: e.target.value })}
+                  className="w-full px-3 py-2 border border-gray-300 dark:border-gray-600 bg-white dark:bg-gray-700 text-gray-900 dark:text-white placeholder-gray-500 dark:placeholder-gray-400 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                  placeholder="Leave blank to assign automatically, e.g., 10.120.4.0/24"
+                />
+              </div>
+
               <div>
                 <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-1">
                   ISP Company
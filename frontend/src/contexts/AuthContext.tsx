@@ -6,13 +6,14 @@ interface User {
   username: string
   email: string
   role: string
+  totp_enabled: boolean
 }
 
 interface AuthContextType {
   user: User | null
   loading: boolean
-  login: (username: string, password: string) => Promise<void>
-  logout: () => void
+  login: (username: string, password: string, totpCode?: string) => Promise<void>
+  logout: () => Promise<void>
 }
 
 const AuthContext = createContext<AuthContextType | undefined>(undefined)
@@ -43,14 +44,14 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({ children
     }
   }
 
-  const login = async (username: string, password: string) => {
-    const response = await apiClient.login(username, password)
+  const login = async (username: string, password: string, totpCode?: string) => {
+    const response = await apiClient.login(username, password, totpCode)
     apiClient.setToken(response.token)
     setUser(response.user)
   }
 
-  const logout = () => {
-    apiClient.clearToken()
+  const logout = async () => {
+    await apiClient.logout()
     setUser(null)
     window.location.href = '/login'
   }
@@ -17,8 +17,47 @@ export default function DashboardPage() {
 
   useEffect(() => {
     loadDashboard()
-    const interval = setInterval(loadDashboard, 30000) // Refresh every 30 seconds
-    return () => clearInterval(interval)
+
+    // Live updates: snapshot on connect, then one message per property status change
+    let socket: WebSocket | null = null
+    let reconnectTimer: ReturnType<typeof setTimeout> | undefined
+    let closed = false
+
+    const connect = () => {
+      socket = new WebSocket(apiClient.dashboardSocketUrl())
+      socket.onmessage = (event) => {
+        const update = JSON.parse(event.data)
+        if (update.type === 'snapshot') {
+          setDashboard(update.dashboard)
+          setLoading(false)
+        } else if (update.type === 'property_status') {
+          setDashboard((current: any) => applyPropertyStatus(current, update.status))
+        }
+      }
+      socket.onclose = () => {
+        if (!closed) {
+          reconnectTimer = setTimeout(connect, 5000)
+        }
+      }
+    }
+    connect()
+
+    // Live updates only carry property changes, so poll worker heartbeats separately
+    const workerTimer = setInterval(async () => {
+      try {
+        const workers = await apiClient.getWorkerHealth()
+        setDashboard((current: any) => (current ? { ...current, workers } : current))
+      } catch (error) {
+        console.error('Failed to load worker health:', error)
+      }
+    }, 30000)
+
+    return () => {
+      closed = true
+      clearTimeout(reconnectTimer)
+      clearInterval(workerTimer)
+      socket?.close()
+    }
   }, [])
 
   const loadDashboard = async () => {
@@ -60,6 +99,18 @@ export default function DashboardPage() {
       <Header user={user} onRefresh={loadDashboard} onAddProperty={() => setShowPropertyModal(true)} />
 
       <div className="container mx-auto px-4 py-6">
+        {dashboard.workers && dashboard.workers.status !== 'ok' && (
+          <div className="bg-red-100 dark:bg-red-900/30 border border-red-300 dark:border-red-700 text-red-800 dark:text-red-300 rounded-lg p-4 mb-6">
+            <div className="font-semibold">Workers are not checking devices</div>
+            <div className="text-sm">
+              {dashboard.workers.last_heartbeat
+                ? `Last worker heartbeat ${new Date(dashboard.workers.last_heartbeat).toLocaleString()}. `
+                : 'No worker heartbeat has been received. '}
+              Statuses below may be out of date.
+            </div>
+          </div>
+        )}
+
         {/* Summary Cards */}
         <div className="grid grid-cols-1 md:grid-cols-4 gap-4 mb-6">
           <div className="bg-white dark:bg-gray-800 rounded-lg shadow p-4">
@@ -170,3 +221,38 @@ export default function DashboardPage() {
     </div>
   )
 }
+
+function applyPropertyStatus(dashboard: any, status: any) {
+  if (!dashboard) {
+    return dashboard
+  }
+
+  const properties = dashboard.properties.map((property: any) =>
+    property.id === status.property_id
+      ? {
+          ...property,
+          status: status.status,
+          online_count: status.online_count,
+          offline_count: status.offline_count,
+          unreachable_count: status.unreachable_count,
+          total_count: status.total_count,
+          critical_offline: status.critical_offline,
+          maintenance: status.maintenance,
+          flapping: status.flapping,
+          last_check: status.last_check,
+        }
+      : property
+  )
+
+  const count = (s: string) => properties.filter((p: any) => p.status === s).length
+  return {
+    ...dashboard,
+    properties,
+    summary: {
+      total_properties: properties.length,
+      red_count: count('red'),
+      yellow_count: count('yellow'),
+      green_count: count('green'),
+    },
+  }
+}